@@ -28,6 +28,7 @@ import (
 	"github.com/go-redis/redis"
 
 	"sync"
+	"time"
 
 	"github.com/Peripli/service-manager/pkg/query"
 
@@ -46,28 +47,29 @@ import (
 	"github.com/Peripli/service-manager/storage"
 )
 
-const osbVersion = "2.14"
-
 // Settings type to be loaded from the environment
 type Settings struct {
-	ServiceManagerTenantId     string   `mapstructure:"service_manager_tenant_id" description:"tenant id of the service manager"`
-	TokenIssuerURL             string   `mapstructure:"token_issuer_url" description:"url of the token issuer which to use for validating tokens"`
-	ClientID                   string   `mapstructure:"client_id" description:"id of the client from which the token must be issued"`
-	TokenBasicAuth             bool     `mapstructure:"token_basic_auth" description:"specifies if client credentials to the authorization server should be sent in the header as basic auth (true) or in the body (false)"`
-	ProtectedLabels            []string `mapstructure:"protected_labels" description:"defines labels which cannot be modified/added by REST API requests"`
-	OSBVersion                 string   `mapstructure:"-"`
-	MaxPageSize                int      `mapstructure:"max_page_size" description:"maximum number of items that could be returned in a single page"`
-	DefaultPageSize            int      `mapstructure:"default_page_size" description:"default number of items returned in a single page if not specified in request"`
-	EnableInstanceTransfer     bool     `mapstructure:"enable_instance_transfer" description:"whether service instance transfer is enabled or not"`
-	RateLimit                  string   `mapstructure:"rate_limit" description:"rate limiter configuration defined in format: rate<:path><,rate<:path>,...>"`
-	RateLimitingEnabled        bool     `mapstructure:"rate_limiting_enabled" description:"enable rate limiting"`
-	RateLimitExcludeClients    []string `mapstructure:"rate_limit_exclude_clients" description:"define client users that should be excluded from the rate limiter processing"`
-	RateLimitExcludePaths      []string `mapstructure:"rate_limit_exclude_paths" description:"define paths that should be excluded from the rate limiter processing"`
-	RateLimitUsageLogThreshold int64    `mapstructure:"rate_limiting_usage_log_threshold" description:"defines a threshold for log notification trigger about requests limit usage. Accepts value in range from 0 to 100 (percents)"`
-	DisabledQueryParameters    []string `mapstructure:"disabled_query_parameters" description:"which query parameters are not implemented by service manager and should be extended"`
-	OSBRSAPublicKey            string   `mapstructure:"osb_rsa_public_key"`
-	OSBRSAPrivateKey           string   `mapstructure:"osb_rsa_private_key"`
-	OSBSuccessorRSAPublicKey   string   `mapstructure:"osb_successor_rsa_public_key"`
+	ServiceManagerTenantId     string        `mapstructure:"service_manager_tenant_id" description:"tenant id of the service manager"`
+	TokenIssuerURL             string        `mapstructure:"token_issuer_url" description:"url of the token issuer which to use for validating tokens"`
+	ClientID                   string        `mapstructure:"client_id" description:"id of the client from which the token must be issued"`
+	TokenBasicAuth             bool          `mapstructure:"token_basic_auth" description:"specifies if client credentials to the authorization server should be sent in the header as basic auth (true) or in the body (false)"`
+	ProtectedLabels            []string      `mapstructure:"protected_labels" description:"defines labels which cannot be modified/added by REST API requests"`
+	OSBVersion                 string        `mapstructure:"osb_version" description:"the newest OSB API version negotiated with the brokers"`
+	MaxPageSize                int           `mapstructure:"max_page_size" description:"maximum number of items that could be returned in a single page"`
+	DefaultPageSize            int           `mapstructure:"default_page_size" description:"default number of items returned in a single page if not specified in request"`
+	MaxBatchSize               int           `mapstructure:"max_batch_size" description:"maximum number of items that could be applied in a single batch request"`
+	EnableInstanceTransfer     bool          `mapstructure:"enable_instance_transfer" description:"whether service instance transfer is enabled or not"`
+	RateLimit                  string        `mapstructure:"rate_limit" description:"rate limiter configuration defined in format: rate<:path><,rate<:path>,...>"`
+	RateLimitingEnabled        bool          `mapstructure:"rate_limiting_enabled" description:"enable rate limiting"`
+	RateLimitExcludeClients    []string      `mapstructure:"rate_limit_exclude_clients" description:"define client users that should be excluded from the rate limiter processing"`
+	RateLimitExcludePaths      []string      `mapstructure:"rate_limit_exclude_paths" description:"define paths that should be excluded from the rate limiter processing"`
+	RateLimitUsageLogThreshold int64         `mapstructure:"rate_limiting_usage_log_threshold" description:"defines a threshold for log notification trigger about requests limit usage. Accepts value in range from 0 to 100 (percents)"`
+	DisabledQueryParameters    []string      `mapstructure:"disabled_query_parameters" description:"which query parameters are not implemented by service manager and should be extended"`
+	OSBRSAPublicKey            string        `mapstructure:"osb_rsa_public_key"`
+	OSBRSAPrivateKey           string        `mapstructure:"osb_rsa_private_key"`
+	OSBSuccessorRSAPublicKey   string        `mapstructure:"osb_successor_rsa_public_key"`
+	EventsSettlePeriod         time.Duration `mapstructure:"events_settle_period" description:"the time after which the changes are returned by the events API, it should exceed the duration of the transactions storing them"`
+	OSBCatalogCacheEnabled     bool          `mapstructure:"osb_catalog_cache_enabled" description:"whether the catalogs filtered by the platform visibilities are cached until a notification for the platform"`
 }
 
 // DefaultSettings returns default values for API settings
@@ -77,15 +79,17 @@ func DefaultSettings() *Settings {
 		ClientID:                   "",
 		TokenBasicAuth:             true, // RFC 6749 section 2.3.1
 		ProtectedLabels:            []string{},
-		OSBVersion:                 osbVersion,
+		OSBVersion:                 osb.LatestAPIVersion,
 		MaxPageSize:                200,
 		DefaultPageSize:            50,
+		MaxBatchSize:               1000,
 		EnableInstanceTransfer:     false,
 		RateLimit:                  "10000-H,1000-M",
 		RateLimitingEnabled:        false,
 		RateLimitExcludeClients:    []string{},
 		RateLimitUsageLogThreshold: 10,
 		DisabledQueryParameters:    []string{},
+		EventsSettlePeriod:         5 * time.Second,
 	}
 }
 
@@ -94,6 +98,12 @@ func (s *Settings) Validate() error {
 	if (len(s.TokenIssuerURL)) == 0 {
 		return fmt.Errorf("validate Settings: APITokenIssuerURL missing")
 	}
+	if !osb.IsSupportedAPIVersion(s.OSBVersion) {
+		return fmt.Errorf("validate Settings: OSBVersion must be one of %v", osb.SupportedAPIVersions)
+	}
+	if s.EventsSettlePeriod < 0 {
+		return fmt.Errorf("validate Settings: EventsSettlePeriod must not be negative")
+	}
 	return validateRateLimiterConfiguration(s.RateLimit)
 }
 
@@ -107,6 +117,7 @@ type Options struct {
 	WaitGroup         *sync.WaitGroup
 	TenantLabelKey    string
 	Agents            *agents.Settings
+	BrokerCircuits    *operations.BrokerCircuits
 }
 
 // New returns the minimum set of REST APIs needed for the Service Manager
@@ -119,15 +130,14 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 	api := &web.API{
 		// Default controllers - more filters can be registered using the relevant API methods
 		Controllers: []web.Controller{
-			NewAsyncController(ctx, options, web.ServiceBrokersURL, types.ServiceBrokerType, false, func() types.Object {
-				return &types.ServiceBroker{}
-			}, false),
+			NewServiceBrokerController(ctx, options),
 			NewController(ctx, options, web.PlatformsURL, types.PlatformType, func() types.Object {
 				return &types.Platform{}
 			}, true),
 			NewController(ctx, options, web.VisibilitiesURL, types.VisibilityType, func() types.Object {
 				return &types.Visibility{}
 			}, false),
+			NewVisibilityBatchController(options),
 			NewTenantController(options.Repository),
 			NewServiceInstanceController(ctx, options),
 			NewServiceBindingController(ctx, options),
@@ -136,6 +146,8 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 			NewServiceOfferingController(ctx, options),
 			NewServicePlanController(ctx, options),
 			NewOperationsController(ctx, options),
+			NewEventsController(options),
+			NewAuditEventsController(ctx, options),
 			NewAgentsController(options.Agents),
 
 			&credentialsController{
@@ -158,6 +170,7 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 					}
 					return br.(*types.ServiceBroker), nil
 				},
+				BrokerCircuits: options.BrokerCircuits,
 			},
 			&configuration.Controller{
 				Environment: e,
@@ -171,6 +184,7 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 			&filters.SelectionCriteria{},
 			&filters.ServiceInstanceStripFilter{},
 			&filters.ServiceBindingStripFilter{},
+			&filters.PlatformStripFilter{},
 			filters.NewProtectedLabelsFilter(options.APISettings.ProtectedLabels),
 			&filters.ProtectedSMPlatformFilter{},
 			&filters.PlatformIDInstanceValidationFilter{},
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// AuditEventsController implements api.Controller by providing read-only access to the audit trail
+type AuditEventsController struct {
+	*BaseController
+}
+
+// NewAuditEventsController returns a new audit events controller
+func NewAuditEventsController(ctx context.Context, options *Options) *AuditEventsController {
+	return &AuditEventsController{
+		BaseController: NewController(ctx, options, web.AuditEventsURL, types.AuditEventType, func() types.Object {
+			return &types.AuditEvent{}
+		}, false),
+	}
+}
+
+func (c *AuditEventsController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}", web.AuditEventsURL, web.PathParamResourceID),
+			},
+			Handler: c.GetSingleObject,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.AuditEventsURL,
+			},
+			Handler: c.ListObjects,
+		},
+	}
+}
@@ -18,7 +18,6 @@ package api
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"github.com/tidwall/sjson"
 	"net/http"
@@ -51,6 +50,7 @@ type BaseController struct {
 	objectType      types.ObjectType
 	repository      storage.Repository
 	objectBlueprint func() types.Object
+	tenantLabelKey  string
 
 	DefaultPageSize int
 	MaxPageSize     int
@@ -75,9 +75,10 @@ func NewController(ctx context.Context, options *Options, resourceBaseURL string
 		resourceBaseURL:       resourceBaseURL,
 		objectBlueprint:       objectBlueprint,
 		objectType:            objectType,
+		tenantLabelKey:        options.TenantLabelKey,
 		DefaultPageSize:       options.APISettings.DefaultPageSize,
 		MaxPageSize:           options.APISettings.MaxPageSize,
-		scheduler:             operations.NewScheduler(ctx, options.Repository, options.OperationSettings, poolSize, options.WaitGroup),
+		scheduler:             operations.NewScheduler(ctx, options.Repository, options.OperationSettings, options.BrokerCircuits, poolSize, options.WaitGroup),
 		supportsCascadeDelete: supportsCascadeDelete,
 	}
 
@@ -93,6 +94,28 @@ func NewAsyncController(ctx context.Context, options *Options, resourceBaseURL s
 	return controller
 }
 
+// workerPoolProvider is implemented by the controllers which execute the operations of their resources in a worker pool
+type workerPoolProvider interface {
+	workerPool() (string, *operations.Scheduler)
+}
+
+// WorkerPools returns the schedulers of the provided resource controllers by the type of the resources whose
+// operations they execute
+func WorkerPools(controllers []web.Controller) map[string]*operations.Scheduler {
+	pools := make(map[string]*operations.Scheduler)
+	for _, controller := range controllers {
+		if resourceController, ok := controller.(workerPoolProvider); ok {
+			name, scheduler := resourceController.workerPool()
+			pools[name] = scheduler
+		}
+	}
+	return pools
+}
+
+func (c *BaseController) workerPool() (string, *operations.Scheduler) {
+	return c.objectType.String(), c.scheduler
+}
+
 // Routes returns the common set of routes for all objects
 func (c *BaseController) Routes() []web.Route {
 	return []web.Route{
@@ -103,6 +126,13 @@ func (c *BaseController) Routes() []web.Route {
 			},
 			Handler: c.CreateObject,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s%s", c.resourceBaseURL, web.StatsURL),
+			},
+			Handler: c.GetStats,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -117,6 +147,13 @@ func (c *BaseController) Routes() []web.Route {
 			},
 			Handler: c.GetOperation,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   fmt.Sprintf("%s/{%s}%s/{%s}", c.resourceBaseURL, web.PathParamResourceID, web.ResourceOperationsURL, web.PathParamID),
+			},
+			Handler: c.CancelOperation,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -162,6 +199,12 @@ func (c *BaseController) CreateObject(r *web.Request) (*web.Response, error) {
 		return nil, err
 	}
 
+	return c.createObject(r, result, "")
+}
+
+// createObject schedules the creation of the object, recording the provided description in the operation
+func (c *BaseController) createObject(r *web.Request, result types.Object, description string) (*web.Response, error) {
+	ctx := r.Context()
 	if result.GetID() == "" {
 		UUID, err := uuid.NewV4()
 		if err != nil {
@@ -193,6 +236,7 @@ func (c *BaseController) CreateObject(r *web.Request) (*web.Response, error) {
 			Labels:    make(map[string][]string),
 			Ready:     true,
 		},
+		Description:   description,
 		Type:          types.CREATE,
 		State:         types.IN_PROGRESS,
 		ResourceID:    result.GetID(),
@@ -353,6 +397,11 @@ func (c *BaseController) GetSingleObject(r *web.Request) (*web.Response, error)
 	ctx := r.Context()
 	log.C(ctx).Debugf("Getting %s with id %s", c.objectType, objectID)
 
+	shape, err := parseResponseShape(r, c.objectType, c.tenantLabelKey)
+	if err != nil {
+		return nil, err
+	}
+
 	byID := query.ByField(query.EqualsOperator, "id", objectID)
 	criteria := query.CriteriaForContext(ctx)
 	object, err := c.repository.Get(ctx, c.objectType, append(criteria, byID)...)
@@ -367,7 +416,15 @@ func (c *BaseController) GetSingleObject(r *web.Request) (*web.Response, error)
 	}
 
 	cleanObject(ctx, object.GetLastOperation())
-	return util.NewJSONResponse(http.StatusOK, object)
+	resp, err := util.NewJSONResponse(http.StatusOK, object)
+	if err != nil || shape.isDefault() {
+		return resp, err
+	}
+
+	if resp.Body, err = shape.apply(ctx, c.repository, resp.Body, []types.Object{object}, []string{""}); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
 // GetOperation handles the fetching of a single operation with the id specified for the specified resource
@@ -375,6 +432,42 @@ func (c *BaseController) GetOperation(r *web.Request) (*web.Response, error) {
 	return GetResourceOperation(r, c.repository, c.objectType)
 }
 
+// CancelOperation handles the cancellation of an in progress operation with the id specified for the specified resource
+func (c *BaseController) CancelOperation(r *web.Request) (*web.Response, error) {
+	objectID := r.PathParams[web.PathParamResourceID]
+	operationID := r.PathParams[web.PathParamID]
+
+	ctx := r.Context()
+	log.C(ctx).Debugf("Cancelling operation with id %s for object of type %s with id %s", operationID, c.objectType, objectID)
+
+	byOperationID := query.ByField(query.EqualsOperator, "id", operationID)
+	byObjectID := query.ByField(query.EqualsOperator, "resource_id", objectID)
+	byObjectType := query.ByField(query.EqualsOperator, "resource_type", c.objectType.String())
+	var err error
+	ctx, err = query.AddCriteria(ctx, byObjectID, byObjectType, byOperationID)
+	if err != nil {
+		return nil, err
+	}
+	criteria := query.CriteriaForContext(ctx)
+	operation, err := c.repository.Get(ctx, types.OperationType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+
+	if err := c.scheduler.CancelOperation(ctx, operation.(*types.Operation)); err != nil {
+		return nil, err
+	}
+
+	// the cancelled operation is fetched again as it is still used by the orphan mitigation
+	cancelledOperation, err := c.repository.Get(ctx, types.OperationType, byOperationID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+
+	cleanObject(ctx, cancelledOperation)
+	return util.NewJSONResponse(http.StatusOK, cancelledOperation)
+}
+
 func GetResourceOperation(r *web.Request, repository storage.Repository, objectType types.ObjectType) (*web.Response, error) {
 	objectID := r.PathParams[web.PathParamResourceID]
 	operationID := r.PathParams[web.PathParamID]
@@ -403,6 +496,16 @@ func GetResourceOperation(r *web.Request, repository storage.Repository, objectT
 func (c *BaseController) ListObjects(r *web.Request) (*web.Response, error) {
 	ctx := r.Context()
 
+	order, err := orderCriteria(r)
+	if err != nil {
+		return nil, err
+	}
+
+	shape, err := parseResponseShape(r, c.objectType, c.tenantLabelKey)
+	if err != nil {
+		return nil, err
+	}
+
 	criteria := query.CriteriaForContext(ctx)
 	count, err := c.repository.Count(ctx, c.objectType, criteria...)
 	if err != nil {
@@ -426,14 +529,16 @@ func (c *BaseController) ListObjects(r *web.Request) (*web.Response, error) {
 	}
 
 	rawToken := r.URL.Query().Get("token")
-	pagingSequence, err := c.parsePageToken(ctx, rawToken)
+	after, err := c.parsePageToken(ctx, rawToken, order)
 	if err != nil {
 		return nil, err
 	}
 
-	criteria = append(criteria, query.LimitResultBy(limit+pagingLimitOffset),
-		query.OrderResultBy("paging_sequence", query.AscOrder),
-		query.ByField(query.GreaterThanOperator, "paging_sequence", pagingSequence))
+	criteria = append(criteria, query.LimitResultBy(limit+pagingLimitOffset))
+	criteria = append(criteria, order...)
+	if after != nil {
+		criteria = append(criteria, query.ResultAfter(after...))
+	}
 
 	log.C(ctx).Debugf("Getting a page of %ss", c.objectType)
 	objectList, err := c.repository.List(ctx, c.objectType, criteria...)
@@ -448,12 +553,25 @@ func (c *BaseController) ListObjects(r *web.Request) (*web.Response, error) {
 		}
 	}
 
-	page := pageFromObjectList(ctx, objectList, count, limit)
+	page, err := pageFromObjectList(ctx, objectList, count, limit, order)
+	if err != nil {
+		return nil, err
+	}
 	resp, err := util.NewJSONResponse(http.StatusOK, page)
 	if err != nil {
 		return nil, err
 	}
 
+	if !shape.isDefault() {
+		paths := make([]string, 0, len(page.Items))
+		for i := range page.Items {
+			paths = append(paths, fmt.Sprintf("items.%d", i))
+		}
+		if resp.Body, err = shape.apply(ctx, c.repository, resp.Body, page.Items, paths); err != nil {
+			return nil, err
+		}
+	}
+
 	if page.Token != "" {
 		nextPageUrl := r.URL
 		q := nextPageUrl.Query()
@@ -656,40 +774,6 @@ func (c *BaseController) parseMaxItemsQuery(maxItems string) (int, error) {
 	return limit, nil
 }
 
-func (c *BaseController) parsePageToken(ctx context.Context, token string) (string, error) {
-	targetPageSequence := "0"
-	if token != "" {
-		base64DecodedTokenBytes, err := base64.StdEncoding.DecodeString(token)
-		if err != nil {
-			log.C(ctx).Infof("Invalid token provided: %v", err)
-			return "", &util.HTTPError{
-				ErrorType:   "TokenInvalid",
-				Description: "Invalid token provided.",
-				StatusCode:  http.StatusBadRequest,
-			}
-		}
-		targetPageSequence = string(base64DecodedTokenBytes)
-		pagingSequence, err := strconv.ParseInt(targetPageSequence, 10, 0)
-		if err != nil {
-			log.C(ctx).Infof("Invalid token provided: %v", err)
-			return "", &util.HTTPError{
-				ErrorType:   "TokenInvalid",
-				Description: "Invalid token provided.",
-				StatusCode:  http.StatusBadRequest,
-			}
-		}
-		if pagingSequence < 0 {
-			log.C(ctx).Infof("Invalid token provided: negative value")
-			return "", &util.HTTPError{
-				ErrorType:   "TokenInvalid",
-				Description: "Invalid token provided.",
-				StatusCode:  http.StatusBadRequest,
-			}
-		}
-	}
-	return targetPageSequence, nil
-}
-
 func (c *BaseController) prepareOperationContextByRequest(r *web.Request) *types.OperationContext {
 	var userInfo *types.UserInfo
 	userInfoFromContext := web.OriginatingIdentityFromContext(r.Context())
@@ -730,12 +814,7 @@ func (c *BaseController) prepareOperationContextByRequest(r *web.Request) *types
 	return operationContext
 }
 
-func generateTokenForItem(obj types.Object) string {
-	nextPageToken := obj.GetPagingSequence()
-	return base64.StdEncoding.EncodeToString([]byte(strconv.FormatInt(nextPageToken, 10)))
-}
-
-func pageFromObjectList(ctx context.Context, objectList types.ObjectList, count, limit int) *types.ObjectPage {
+func pageFromObjectList(ctx context.Context, objectList types.ObjectList, count, limit int, order []query.Criterion) (*types.ObjectPage, error) {
 	page := &types.ObjectPage{
 		ItemsCount: count,
 		Items:      make([]types.Object, 0, objectList.Len()),
@@ -747,9 +826,19 @@ func pageFromObjectList(ctx context.Context, objectList types.ObjectList, count,
 		page.Items = append(page.Items, obj)
 	}
 
-	if len(page.Items) > limit {
+	hasMoreItems := len(page.Items) > limit
+	if hasMoreItems {
 		page.Items = page.Items[:len(page.Items)-1]
-		page.Token = generateTokenForItem(page.Items[len(page.Items)-1])
 	}
-	return page
+	if len(page.Items) > 0 {
+		// the token is generated even for the last page in order to reject orders by fields which are not part of the items
+		token, err := generatePageToken(page.Items[len(page.Items)-1], order)
+		if err != nil {
+			return nil, err
+		}
+		if hasMoreItems {
+			page.Token = token
+		}
+	}
+	return page, nil
 }
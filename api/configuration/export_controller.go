@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/export"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// ExportController provides endpoints for exporting the platforms, service brokers and visibilities in a signed archive
+// and for importing such an archive
+type ExportController struct {
+	Exporter *export.Exporter
+}
+
+// ExportRequest is the body of an export request
+type ExportRequest struct {
+	Key string `json:"key"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (r *ExportRequest) Validate() error {
+	if r.Key == "" {
+		return errors.New("missing export key")
+	}
+	return nil
+}
+
+// ImportRequest is the body of an import request
+type ImportRequest struct {
+	Key     string          `json:"key"`
+	Archive *export.Archive `json:"archive"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (r *ImportRequest) Validate() error {
+	if r.Key == "" {
+		return errors.New("missing export key")
+	}
+	if r.Archive == nil {
+		return errors.New("missing archive")
+	}
+	return nil
+}
+
+func (c *ExportController) export(r *web.Request) (*web.Response, error) {
+	if err := assertGlobalAccess(r, "export and import the configuration"); err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	request := &ExportRequest{}
+	if err := util.BytesToObject(r.Body, request); err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Info("Exporting platforms, service brokers and visibilities...")
+	archive, err := c.Exporter.Export(ctx, request.Key)
+	if err != nil {
+		return nil, exportError(err)
+	}
+
+	return util.NewJSONResponse(http.StatusOK, archive)
+}
+
+func (c *ExportController) importArchive(r *web.Request) (*web.Response, error) {
+	if err := assertGlobalAccess(r, "export and import the configuration"); err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	request := &ImportRequest{}
+	if err := util.BytesToObject(r.Body, request); err != nil {
+		return nil, err
+	}
+
+	options := export.ImportOptions{
+		Mode:   export.FailOnConflicts,
+		DryRun: r.URL.Query().Get(web.QueryParamDryRun) == "true",
+	}
+	if mode := r.URL.Query().Get(web.QueryParamMode); mode != "" {
+		options.Mode = export.ConflictMode(mode)
+	}
+	if err := options.Mode.Validate(); err != nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: err.Error(),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	log.C(ctx).Infof("Importing archive in %s mode (dry run: %v)...", options.Mode, options.DryRun)
+	report, err := c.Exporter.Import(ctx, request.Archive, request.Key, options)
+	if err != nil {
+		if conflictErr, ok := err.(*export.ConflictError); ok {
+			return util.NewJSONResponse(http.StatusConflict, &conflictResponse{
+				HTTPError: util.HTTPError{
+					ErrorType:   "Conflict",
+					Description: conflictErr.Error(),
+					StatusCode:  http.StatusConflict,
+				},
+				Report: report,
+			})
+		}
+		return nil, exportError(err)
+	}
+
+	return util.NewJSONResponse(http.StatusOK, report)
+}
+
+// assertGlobalAccess verifies that the caller is allowed to manage the global resources, as the archives contain
+// the credentials of all platforms and service brokers and the encryption key protects the data of all tenants
+func assertGlobalAccess(r *web.Request, action string) error {
+	user, found := web.UserFromContext(r.Context())
+	if !found || user.AccessLevel != web.GlobalAccess {
+		return &util.HTTPError{
+			ErrorType:   "Forbidden",
+			Description: fmt.Sprintf("only users with global access are allowed to %s", action),
+			StatusCode:  http.StatusForbidden,
+		}
+	}
+	return nil
+}
+
+// conflictResponse is the body of the response of an import which fails because of conflicts
+type conflictResponse struct {
+	util.HTTPError
+	Report *export.Report `json:"report"`
+}
+
+func exportError(err error) error {
+	switch err {
+	case export.ErrInvalidKey, export.ErrInvalidSignature, export.ErrUnsupportedVersion:
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: err.Error(),
+			StatusCode:  http.StatusBadRequest,
+		}
+	default:
+		return err
+	}
+}
+
+// Routes provides endpoints for exporting and importing the platforms, service brokers and visibilities
+func (c *ExportController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.ExportURL,
+			},
+			Handler: c.export,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.ImportURL,
+			},
+			Handler: c.importArchive,
+		},
+	}
+}
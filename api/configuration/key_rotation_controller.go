@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package configuration
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// KeyRotationController provides endpoints for rotating the storage encryption key and obtaining the rotation progress
+type KeyRotationController struct {
+	KeyRotator *storage.KeyRotator
+}
+
+func (c *KeyRotationController) startKeyRotation(r *web.Request) (*web.Response, error) {
+	if err := assertGlobalAccess(r, "rotate the encryption key"); err != nil {
+		return nil, err
+	}
+	ctx := r.Context()
+	log.C(ctx).Info("Starting encryption key rotation...")
+
+	rotation, err := c.KeyRotator.Start(ctx)
+	if err != nil {
+		if err == storage.ErrKeyRotationInProgress {
+			return nil, &util.HTTPError{
+				ErrorType:   "Conflict",
+				Description: err.Error(),
+				StatusCode:  http.StatusConflict,
+			}
+		}
+		return nil, err
+	}
+
+	return util.NewJSONResponse(http.StatusAccepted, rotation)
+}
+
+func (c *KeyRotationController) getKeyRotation(r *web.Request) (*web.Response, error) {
+	if err := assertGlobalAccess(r, "rotate the encryption key"); err != nil {
+		return nil, err
+	}
+	ctx := r.Context()
+	log.C(ctx).Debug("Obtaining encryption key rotation progress...")
+
+	rotation, err := c.KeyRotator.Progress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rotation == nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: "encryption key was never rotated",
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+
+	return util.NewJSONResponse(http.StatusOK, rotation)
+}
+
+// Routes provides endpoints for rotating the encryption key and obtaining the progress of the rotation
+func (c *KeyRotationController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.KeyRotationURL,
+			},
+			Handler: c.startKeyRotation,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.KeyRotationURL,
+			},
+			Handler: c.getKeyRotation,
+		},
+	}
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// EventResources maps the resource names accepted by the events API to the types whose changes are recorded
+var EventResources = map[string]types.ObjectType{
+	"service_brokers":   types.ServiceBrokerType,
+	"service_plans":     types.ServicePlanType,
+	"visibilities":      types.VisibilityType,
+	"service_instances": types.ServiceInstanceType,
+	"service_bindings":  types.ServiceBindingType,
+}
+
+// Event is a single entry of the change feed
+type Event struct {
+	ID            string                      `json:"id"`
+	Revision      int64                       `json:"revision"`
+	Resource      string                      `json:"resource"`
+	Type          types.NotificationOperation `json:"type"`
+	CorrelationID string                      `json:"correlation_id,omitempty"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	Payload       json.RawMessage             `json:"payload"`
+}
+
+// EventsPage is the DTO for a given page of the change feed
+type EventsPage struct {
+	// Revision is the revision of the last event in the page and should be provided as since to fetch the next page
+	Revision   int64    `json:"revision"`
+	ItemsCount int      `json:"num_items"`
+	Items      []*Event `json:"items"`
+}
+
+// EventsController exposes the changes of the Service Manager resources ordered by their revision. The revisions are
+// assigned when the changes are stored, so a change can be committed after changes with higher revisions. To not
+// skip such changes, they are returned only after the settle period has passed since they were stored.
+type EventsController struct {
+	repository      storage.Repository
+	defaultPageSize int
+	maxPageSize     int
+	settlePeriod    time.Duration
+}
+
+// NewEventsController returns a new events controller
+func NewEventsController(options *Options) *EventsController {
+	return &EventsController{
+		repository:      options.Repository,
+		defaultPageSize: options.APISettings.DefaultPageSize,
+		maxPageSize:     options.APISettings.MaxPageSize,
+		settlePeriod:    options.APISettings.EventsSettlePeriod,
+	}
+}
+
+func (c *EventsController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.EventsURL,
+			},
+			Handler: c.ListEvents,
+		},
+	}
+}
+
+// ListEvents returns the settled events with revision greater than the provided one
+func (c *EventsController) ListEvents(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+
+	since, err := parseSinceQuery(r.URL.Query().Get("since"))
+	if err != nil {
+		return nil, err
+	}
+	limit, err := c.parseMaxItemsQuery(r.URL.Query().Get("max_items"))
+	if err != nil {
+		return nil, err
+	}
+
+	settledBefore := util.ToRFCNanoFormat(time.Now().Add(-c.settlePeriod))
+	criteria := []query.Criterion{
+		query.ByField(query.EqualsOperator, "platform_id", types.SMPlatform),
+		query.ByField(query.GreaterThanOperator, "revision", strconv.FormatInt(since, 10)),
+		query.ByField(query.LessThanOperator, "created_at", settledBefore),
+	}
+	// tenant users get only the changes of the resources labeled with their tenant
+	criteria = append(criteria, query.CriteriaForContext(ctx)...)
+	if resources := r.URL.Query().Get("types"); resources != "" {
+		objectTypes, err := parseEventResources(resources)
+		if err != nil {
+			return nil, err
+		}
+		criteria = append(criteria, query.ByField(query.InOperator, "resource", objectTypes...))
+	}
+
+	count, err := c.repository.Count(ctx, types.NotificationType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, "events")
+	}
+
+	page := &EventsPage{
+		Revision:   since,
+		ItemsCount: count,
+		Items:      make([]*Event, 0),
+	}
+	if limit == 0 {
+		return util.NewJSONResponse(http.StatusOK, page)
+	}
+
+	criteria = append(criteria,
+		query.OrderResultBy("revision", query.AscOrder),
+		query.LimitResultBy(limit))
+
+	log.C(ctx).Debugf("Getting a page of events after revision %d", since)
+	notifications, err := c.repository.List(ctx, types.NotificationType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, "events")
+	}
+
+	for i := 0; i < notifications.Len(); i++ {
+		notification := notifications.ItemAt(i).(*types.Notification)
+		page.Items = append(page.Items, &Event{
+			ID:            notification.ID,
+			Revision:      notification.Revision,
+			Resource:      eventResourceName(notification.Resource),
+			Type:          notification.Type,
+			CorrelationID: notification.CorrelationID,
+			CreatedAt:     notification.CreatedAt,
+			Payload:       notification.Payload,
+		})
+		page.Revision = notification.Revision
+	}
+
+	resp, err := util.NewJSONResponse(http.StatusOK, page)
+	if err != nil {
+		return nil, err
+	}
+
+	if count > len(page.Items) {
+		nextPageUrl := r.URL
+		q := nextPageUrl.Query()
+		q.Set("since", strconv.FormatInt(page.Revision, 10))
+		nextPageUrl.RawQuery = q.Encode()
+		resp.Header.Add("Link", fmt.Sprintf(`<%s>; rel="next"`, nextPageUrl))
+	}
+
+	return resp, nil
+}
+
+func (c *EventsController) parseMaxItemsQuery(maxItems string) (int, error) {
+	limit := c.defaultPageSize
+	var err error
+	if maxItems != "" {
+		limit, err = strconv.Atoi(maxItems)
+		if err != nil {
+			return -1, &util.HTTPError{
+				ErrorType:   "InvalidMaxItems",
+				Description: fmt.Sprintf("max_items should be integer: %v", err),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		if limit < 0 {
+			return -1, &util.HTTPError{
+				ErrorType:   "InvalidMaxItems",
+				Description: "max_items cannot be negative",
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		if limit > c.maxPageSize {
+			limit = c.maxPageSize
+		}
+	}
+	return limit, nil
+}
+
+func parseSinceQuery(since string) (int64, error) {
+	if since == "" {
+		return 0, nil
+	}
+	revision, err := strconv.ParseInt(since, 10, 64)
+	if err != nil || revision < 0 {
+		return -1, &util.HTTPError{
+			ErrorType:   "InvalidSince",
+			Description: "since should be a non-negative revision number",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return revision, nil
+}
+
+func parseEventResources(resources string) ([]string, error) {
+	objectTypes := make([]string, 0)
+	for _, resource := range strings.Split(resources, ",") {
+		resource = strings.TrimSpace(resource)
+		objectType, found := EventResources[resource]
+		if !found {
+			return nil, &util.HTTPError{
+				ErrorType:   "InvalidTypes",
+				Description: fmt.Sprintf("events are not supported for resource type %s", resource),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		objectTypes = append(objectTypes, objectType.String())
+	}
+	return objectTypes, nil
+}
+
+func eventResourceName(objectType types.ObjectType) string {
+	for name, eventObjectType := range EventResources {
+		if eventObjectType == objectType {
+			return name
+		}
+	}
+	return objectType.String()
+}
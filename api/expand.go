@@ -0,0 +1,341 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	expandServiceInstance = "service_instance"
+	expandServicePlan     = "service_plan"
+	expandServiceOffering = "service_offering"
+	expandBroker          = "broker"
+	expandPlatform        = "platform"
+)
+
+// relatedTypes are the types of the related resources which can be expanded
+var relatedTypes = map[string]types.ObjectType{
+	expandServiceInstance: types.ServiceInstanceType,
+	expandServicePlan:     types.ServicePlanType,
+	expandServiceOffering: types.ServiceOfferingType,
+	expandBroker:          types.ServiceBrokerType,
+	expandPlatform:        types.PlatformType,
+}
+
+// tenantScopedTypes are the related resources which tenants can list only with the criteria of their tenant
+var tenantScopedTypes = map[string]bool{
+	expandServiceInstance: true,
+	expandBroker:          true,
+	expandPlatform:        true,
+}
+
+// expansionPaths are the related resources which can be expanded for each resource type together with the path
+// of references which lead to them, e.g. the broker of an instance is the broker of the offering of its plan
+var expansionPaths = map[types.ObjectType]map[string][]string{
+	types.ServiceBindingType: {
+		expandServiceInstance: {expandServiceInstance},
+		expandServicePlan:     {expandServiceInstance, expandServicePlan},
+		expandServiceOffering: {expandServiceInstance, expandServicePlan, expandServiceOffering},
+		expandBroker:          {expandServiceInstance, expandServicePlan, expandServiceOffering, expandBroker},
+		expandPlatform:        {expandServiceInstance, expandPlatform},
+	},
+	types.ServiceInstanceType: {
+		expandServicePlan:     {expandServicePlan},
+		expandServiceOffering: {expandServicePlan, expandServiceOffering},
+		expandBroker:          {expandServicePlan, expandServiceOffering, expandBroker},
+		expandPlatform:        {expandPlatform},
+	},
+	types.VisibilityType: {
+		expandServicePlan:     {expandServicePlan},
+		expandServiceOffering: {expandServicePlan, expandServiceOffering},
+		expandBroker:          {expandServicePlan, expandServiceOffering, expandBroker},
+		expandPlatform:        {expandPlatform},
+	},
+	types.ServicePlanType: {
+		expandServiceOffering: {expandServiceOffering},
+		expandBroker:          {expandServiceOffering, expandBroker},
+	},
+	types.ServiceOfferingType: {
+		expandBroker: {expandBroker},
+	},
+}
+
+var fieldPattern = regexp.MustCompile(`^[a-zA-Z0-9_]+(\.[a-zA-Z0-9_]+)*$`)
+
+// responseShape is the form of the returned resources requested by the fields and expand query parameters
+type responseShape struct {
+	fields []string
+	expand []string
+
+	// tenantCriteria restrict the expanded tenant scoped resources to the ones of the tenant of the request
+	tenantCriteria []query.Criterion
+}
+
+func (s *responseShape) isDefault() bool {
+	return len(s.fields) == 0 && len(s.expand) == 0
+}
+
+// parseResponseShape parses the fields and expand query parameters of a request for resources of the provided type
+func parseResponseShape(r *web.Request, objectType types.ObjectType, tenantLabelKey string) (*responseShape, error) {
+	shape := &responseShape{
+		fields:         splitQueryParam(r.URL.Query().Get(web.QueryParamFields)),
+		expand:         splitQueryParam(r.URL.Query().Get(web.QueryParamExpand)),
+		tenantCriteria: tenantCriteria(r.Context(), tenantLabelKey),
+	}
+	for _, field := range shape.fields {
+		if !fieldPattern.MatchString(field) {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("field %s is invalid. Fields are json keys of the resource and nested keys are separated by dots", field)}
+		}
+	}
+	for _, related := range shape.expand {
+		if _, found := expansionPaths[objectType][related]; !found {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("%s cannot be expanded for %s. Supported values are: %s", related, objectType, supportedExpansions(objectType))}
+		}
+	}
+	return shape, nil
+}
+
+// tenantCriteria returns the criteria with which the multitenancy filters restrict the resources of a request
+// made with tenant access
+func tenantCriteria(ctx context.Context, tenantLabelKey string) []query.Criterion {
+	user, found := web.UserFromContext(ctx)
+	if tenantLabelKey == "" || !found || user.AccessLevel == web.GlobalAccess {
+		return nil
+	}
+	var criteria []query.Criterion
+	for _, criterion := range query.CriteriaForContext(ctx) {
+		if criterion.Type == query.LabelQuery && criterion.LeftOp == tenantLabelKey {
+			criteria = append(criteria, criterion)
+		}
+	}
+	return criteria
+}
+
+func splitQueryParam(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func supportedExpansions(objectType types.ObjectType) string {
+	names := make([]string, 0, len(expansionPaths[objectType]))
+	for name := range expansionPaths[objectType] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// apply expands the related resources of the objects and projects the requested fields in the json body
+// in which the objects are found at the provided paths
+func (s *responseShape) apply(ctx context.Context, repository storage.Repository, body []byte, objects []types.Object, paths []string) ([]byte, error) {
+	related, err := expandRelated(ctx, repository, objects, s.expand, s.tenantCriteria)
+	if err != nil {
+		return nil, err
+	}
+	for i, path := range paths {
+		for name, object := range related[i] {
+			if body, err = sjson.SetBytes(body, joinPath(path, name), object); err != nil {
+				return nil, err
+			}
+		}
+		if len(s.fields) == 0 {
+			continue
+		}
+		if body, err = projectFields(body, path, s.fields); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// expandRelated returns the requested related resources of each object by name. The related resources are fetched
+// with a single storage query per type of related resource regardless of the number of objects. The tenant scoped
+// related resources are fetched with the provided tenant criteria.
+func expandRelated(ctx context.Context, repository storage.Repository, objects []types.Object, expand []string, tenantCriteria []query.Criterion) ([]map[string]types.Object, error) {
+	related := make([]map[string]types.Object, len(objects))
+	for i := range related {
+		related[i] = make(map[string]types.Object)
+	}
+	if len(objects) == 0 || len(expand) == 0 {
+		return related, nil
+	}
+
+	paths := expansionPaths[objects[0].GetType()]
+	fetched := make(map[string]map[string]types.Object)
+	for _, name := range expand {
+		sources := objects
+		for _, hop := range paths[name] {
+			targets, err := fetchRelated(ctx, repository, hop, sources, fetched, tenantCriteria)
+			if err != nil {
+				return nil, err
+			}
+			sources = targets
+		}
+		for i, object := range sources {
+			if object != nil {
+				related[i][name] = object
+			}
+		}
+	}
+	return related, nil
+}
+
+// fetchRelated returns the related resources referenced by each of the sources or nil for the sources which do not
+// reference an existing resource accessible by the caller. Resources fetched for previous expansions are reused.
+func fetchRelated(ctx context.Context, repository storage.Repository, name string, sources []types.Object, fetched map[string]map[string]types.Object, tenantCriteria []query.Criterion) ([]types.Object, error) {
+	if fetched[name] == nil {
+		fetched[name] = make(map[string]types.Object)
+	}
+	byID := fetched[name]
+
+	var missingIDs []string
+	for _, source := range sources {
+		if source == nil {
+			continue
+		}
+		id := referencedID(source, name)
+		if _, found := byID[id]; !found && id != "" {
+			byID[id] = nil
+			missingIDs = append(missingIDs, id)
+		}
+	}
+	if len(missingIDs) != 0 {
+		if err := fetchByIDs(ctx, repository, name, missingIDs, byID, tenantCriteria); err != nil {
+			return nil, err
+		}
+	}
+
+	targets := make([]types.Object, len(sources))
+	for i, source := range sources {
+		if source != nil {
+			targets[i] = byID[referencedID(source, name)]
+		}
+	}
+	return targets, nil
+}
+
+// fetchByIDs fetches the related resources with the provided ids which are accessible by the caller in the same
+// way as when they are listed directly
+func fetchByIDs(ctx context.Context, repository storage.Repository, name string, ids []string, byID map[string]types.Object, tenantCriteria []query.Criterion) error {
+	objectType := relatedTypes[name]
+	criteria, visible, err := filters.VisibilityCriteria(ctx, repository, objectType)
+	if err != nil {
+		return util.HandleStorageError(err, objectType.String())
+	}
+	if !visible {
+		return nil
+	}
+	criteria = append(criteria, query.ByField(query.InOperator, "id", ids...))
+	if tenantScopedTypes[name] {
+		criteria = append(criteria, tenantCriteria...)
+	}
+	objectList, err := repository.List(ctx, objectType, criteria...)
+	if err != nil {
+		return util.HandleStorageError(err, objectType.String())
+	}
+	for i := 0; i < objectList.Len(); i++ {
+		object := objectList.ItemAt(i)
+		cleanObject(ctx, object)
+		byID[object.GetID()] = object
+	}
+	return nil
+}
+
+// referencedID returns the id of the related resource with the provided name which is referenced by the object
+func referencedID(object types.Object, name string) string {
+	switch o := object.(type) {
+	case *types.ServiceBinding:
+		if name == expandServiceInstance {
+			return o.ServiceInstanceID
+		}
+	case *types.ServiceInstance:
+		switch name {
+		case expandServicePlan:
+			return o.ServicePlanID
+		case expandPlatform:
+			return o.PlatformID
+		}
+	case *types.Visibility:
+		switch name {
+		case expandServicePlan:
+			return o.ServicePlanID
+		case expandPlatform:
+			return o.PlatformID
+		}
+	case *types.ServicePlan:
+		if name == expandServiceOffering {
+			return o.ServiceOfferingID
+		}
+	case *types.ServiceOffering:
+		if name == expandBroker {
+			return o.BrokerID
+		}
+	}
+	return ""
+}
+
+// projectFields keeps only the provided fields of the json object found at the provided path of the body
+func projectFields(body []byte, path string, fields []string) ([]byte, error) {
+	object := gjson.ParseBytes(body)
+	if path != "" {
+		object = object.Get(path)
+	}
+	projected := []byte("{}")
+	for _, field := range fields {
+		value := object.Get(field)
+		if !value.Exists() {
+			continue
+		}
+		var err error
+		if projected, err = sjson.SetRawBytes(projected, field, []byte(value.Raw)); err != nil {
+			return nil, err
+		}
+	}
+	if path == "" {
+		return projected, nil
+	}
+	return sjson.SetRawBytes(body, path, projected)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
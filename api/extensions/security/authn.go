@@ -15,6 +15,12 @@ import (
 
 // Register adds security configuration to the service manager builder
 func Register(ctx context.Context, cfg *config.Settings, smb *sm.ServiceManagerBuilder) error {
+	if cfg.ClientCertificates.Enabled {
+		if err := registerCertificateAuthentication(cfg, smb); err != nil {
+			return err
+		}
+	}
+
 	basicPlatformAuthenticator := &authenticators.Basic{
 		Repository:             smb.Storage,
 		BasicAuthenticatorFunc: authenticators.BasicPlatformAuthenticator,
@@ -67,9 +73,52 @@ func Register(ctx context.Context, cfg *config.Settings, smb *sm.ServiceManagerB
 		web.ConfigURL+"/**",
 		web.ProfileURL+"/**",
 		web.OperationsURL+"/**",
+		web.EventsURL+"/**",
+		web.AuditEventsURL+"/**",
+		web.TenantURL+"/*"+web.QuotasURL,
 	).
 		Method(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete).
 		WithAuthentication(bearerAuthenticator).Required()
 
 	return nil
 }
+
+// registerCertificateAuthentication registers the client certificate authenticator before the basic and bearer ones,
+// so that the requests with a client certificate are authenticated by it
+func registerCertificateAuthentication(cfg *config.Settings, smb *sm.ServiceManagerBuilder) error {
+	certificateAuthenticator, err := authenticators.NewCertificateAuthenticator(smb.Storage, cfg.ClientCertificates)
+	if err != nil {
+		return err
+	}
+
+	smb.Security().Path(
+		web.ServiceBrokersURL+"/**",
+		web.PlatformsURL+"/**",
+		web.ServiceOfferingsURL+"/**",
+		web.ServicePlansURL+"/**",
+		web.VisibilitiesURL+"/**",
+		web.NotificationsURL+"/**",
+		web.ServiceInstancesURL+"/**",
+		web.ServiceBindingsURL+"/**",
+		web.ConfigURL+"/**",
+		web.ProfileURL+"/**",
+		web.OperationsURL+"/**",
+		web.EventsURL+"/**",
+		web.AuditEventsURL+"/**",
+		web.TenantURL+"/*"+web.QuotasURL,
+	).
+		Method(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete).
+		WithAuthentication(certificateAuthenticator).Required()
+
+	smb.Security().
+		Path(web.BrokerPlatformCredentialsURL + "/**").
+		Method(http.MethodPut).
+		WithAuthentication(certificateAuthenticator).Required()
+
+	smb.Security().
+		Path(web.OSBURL+"/**").
+		Method(http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete).
+		WithAuthentication(certificateAuthenticator).Required()
+
+	return nil
+}
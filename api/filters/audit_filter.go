@@ -0,0 +1,360 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+// AuditFilterName is the name of the audit filter
+const AuditFilterName = "AuditFilter"
+
+const redacted = "[REDACTED]"
+
+// securedFields are the fields whose values are never recorded in the audit trail
+var securedFields = map[string]bool{
+	"credentials":       true,
+	"old_credentials":   true,
+	"password":          true,
+	"password_hash":     true,
+	"old_password_hash": true,
+	"client_key":        true,
+	"secret":            true,
+	"parameters":        true,
+	"context":           true,
+}
+
+// volatileFields are the fields whose changes are not recorded in the audit trail
+var volatileFields = map[string]bool{
+	"updated_at":      true,
+	"last_operation":  true,
+	"paging_sequence": true,
+}
+
+// auditedTypes are the resources whose state before the change is recorded in the audit trail
+var auditedTypes = map[types.ObjectType]bool{
+	types.PlatformType:                 true,
+	types.ServiceBrokerType:            true,
+	types.ServiceOfferingType:          true,
+	types.ServicePlanType:              true,
+	types.VisibilityType:               true,
+	types.ServiceInstanceType:          true,
+	types.ServiceBindingType:           true,
+	types.BrokerPlatformCredentialType: true,
+	types.OperationType:                true,
+}
+
+// AuditFilter records the actor, the changes and the outcome of the mutating API and OSB requests
+type AuditFilter struct {
+	repository     storage.Repository
+	tenantLabelKey string
+}
+
+// NewAuditFilter returns an AuditFilter storing the audit events in the repository. The tenant of the changed
+// resources is taken from their label with the specified key
+func NewAuditFilter(repository storage.Repository, tenantLabelKey string) *AuditFilter {
+	return &AuditFilter{
+		repository:     repository,
+		tenantLabelKey: tenantLabelKey,
+	}
+}
+
+func (f *AuditFilter) Name() string {
+	return AuditFilterName
+}
+
+func (f *AuditFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	resource, resourceID := auditedResource(req.URL.Path)
+	before := f.currentState(req, resource, resourceID)
+	body := req.Body
+
+	resp, err := next.Handle(req)
+
+	event := &types.AuditEvent{
+		Resource:      resource,
+		ResourceID:    resourceID,
+		Action:        auditAction(req.Method),
+		Method:        req.Method,
+		Path:          req.URL.Path,
+		CorrelationID: log.CorrelationIDFromContext(req.Context()),
+		AccessLevel:   web.NoAccess.String(),
+	}
+	if user, found := web.UserFromContext(req.Context()); found {
+		event.Actor = user.Name
+		event.AccessLevel = user.AccessLevel.String()
+	}
+
+	var after map[string]interface{}
+	switch {
+	case err != nil:
+		event.Outcome = types.AuditFailure
+		event.StatusCode = http.StatusInternalServerError
+		if httpErr, ok := err.(*util.HTTPError); ok {
+			event.StatusCode = httpErr.StatusCode
+		}
+	default:
+		event.StatusCode = resp.StatusCode
+		event.Outcome = types.AuditSuccess
+		if resp.StatusCode >= http.StatusBadRequest {
+			event.Outcome = types.AuditFailure
+		} else if req.Method != http.MethodDelete {
+			after = asObject(resp.Body)
+			if _, found := after["id"]; !found {
+				after = nil
+			}
+		}
+	}
+	if event.Outcome == types.AuditFailure {
+		// the resource is not changed by a failed request, so only the requested changes are recorded
+		before = nil
+	}
+	if req.Method != http.MethodDelete {
+		if after == nil {
+			after = asObject(body)
+		}
+		after = merge(before, after)
+	}
+	if len(event.ResourceID) == 0 {
+		if id, ok := after["id"].(string); ok {
+			event.ResourceID = id
+		}
+	}
+	event.Tenant = f.tenant(req, after, before)
+	if before == nil {
+		event.Changes = changes(nil, requestedChanges(req.Method, body, after))
+	} else {
+		event.Changes = changes(before, after)
+	}
+
+	f.store(req, event)
+	return resp, err
+}
+
+func (f *AuditFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/v1/**"),
+				web.Methods(http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete),
+			},
+		},
+	}
+}
+
+// currentState returns the state of the resource before it is changed by an update or a delete request. Created
+// resources have no previous state, so they are not read
+func (f *AuditFilter) currentState(req *web.Request, resource types.ObjectType, resourceID string) map[string]interface{} {
+	if len(resourceID) == 0 || !auditedTypes[resource] || (req.Method != http.MethodPatch && req.Method != http.MethodDelete) {
+		return nil
+	}
+	ctx := util.StateContext{Context: req.Context()}
+	object, err := f.repository.Get(ctx, resource, query.ByField(query.EqualsOperator, "id", resourceID))
+	if err != nil {
+		if err != util.ErrNotFoundInStorage {
+			log.C(ctx).WithError(err).Warnf("Could not get the state of %s %s for the audit trail", resource, resourceID)
+		}
+		return nil
+	}
+	bytes, err := json.Marshal(object)
+	if err != nil {
+		return nil
+	}
+	return asObject(bytes)
+}
+
+// tenant returns the tenant of the changed resource from the labels of its states or, if the resource is not
+// returned, the tenant to which the request is restricted
+func (f *AuditFilter) tenant(req *web.Request, states ...map[string]interface{}) string {
+	if len(f.tenantLabelKey) == 0 {
+		return ""
+	}
+	for _, state := range states {
+		labels, ok := state["labels"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if values, ok := labels[f.tenantLabelKey].([]interface{}); ok && len(values) != 0 {
+			if tenant, ok := values[0].(string); ok {
+				return tenant
+			}
+		}
+	}
+	for _, criterion := range query.CriteriaForContext(req.Context()) {
+		if criterion.Type == query.LabelQuery && criterion.LeftOp == f.tenantLabelKey &&
+			criterion.Operator == query.EqualsOperator && len(criterion.RightOp) != 0 {
+			return criterion.RightOp[0]
+		}
+	}
+	return ""
+}
+
+func (f *AuditFilter) store(req *web.Request, event *types.AuditEvent) {
+	ctx := util.StateContext{Context: req.Context()}
+	id, err := uuid.NewV4()
+	if err != nil {
+		log.C(ctx).WithError(err).Error("Could not generate id of audit event")
+		return
+	}
+	currentTime := time.Now().UTC()
+	event.ID = id.String()
+	event.CreatedAt = currentTime
+	event.UpdatedAt = currentTime
+	event.Ready = true
+	if _, err := f.repository.Create(ctx, event); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not store audit event for %s %s", event.Method, event.Path)
+	}
+}
+
+// auditedResource returns the type and the id of the resource changed by a request to the specified path
+func auditedResource(path string) (types.ObjectType, string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 {
+		return types.ObjectType(path), ""
+	}
+	if "/"+segments[0]+"/"+segments[1] == web.OSBURL {
+		// /v1/osb/{broker_id}/v2/service_instances/{instance_id}/service_bindings/{binding_id}
+		switch {
+		case len(segments) >= 8 && segments[6] == "service_bindings":
+			return types.ServiceBindingType, segments[7]
+		case len(segments) >= 6 && segments[4] == "service_instances":
+			return types.ServiceInstanceType, segments[5]
+		default:
+			return types.ObjectType(web.OSBURL), ""
+		}
+	}
+	resource := types.ObjectType("/" + segments[0] + "/" + segments[1])
+	if len(segments) > 2 {
+		return resource, segments[2]
+	}
+	return resource, ""
+}
+
+func auditAction(method string) types.AuditAction {
+	switch method {
+	case http.MethodPost, http.MethodPut:
+		return types.AuditCreate
+	case http.MethodDelete:
+		return types.AuditDelete
+	default:
+		return types.AuditUpdate
+	}
+}
+
+func asObject(body []byte) map[string]interface{} {
+	object := make(map[string]interface{})
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil
+	}
+	return object
+}
+
+func merge(state, changes map[string]interface{}) map[string]interface{} {
+	if state == nil && changes == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(state)+len(changes))
+	for key, value := range state {
+		result[key] = value
+	}
+	for key, value := range changes {
+		result[key] = value
+	}
+	return result
+}
+
+// requestedChanges returns the state of the fields changed by the request. The whole resource is changed when it is
+// created, while an update changes only the fields in the request body
+func requestedChanges(method string, body []byte, after map[string]interface{}) map[string]interface{} {
+	if method != http.MethodPatch {
+		return after
+	}
+	requested := asObject(body)
+	for key := range requested {
+		if value, found := after[key]; found {
+			requested[key] = value
+		}
+	}
+	return requested
+}
+
+// changes returns the fields changed between the two states of a resource with the secured fields redacted
+func changes(before, after map[string]interface{}) json.RawMessage {
+	diff := make(map[string]interface{})
+	for key, value := range before {
+		if !volatileFields[key] && !reflect.DeepEqual(value, after[key]) {
+			diff[key] = change(key, value, after[key])
+		}
+	}
+	for key, value := range after {
+		if _, found := before[key]; !found && !volatileFields[key] {
+			diff[key] = change(key, nil, value)
+		}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	bytes, err := json.Marshal(diff)
+	if err != nil {
+		return nil
+	}
+	return bytes
+}
+
+func change(key string, before, after interface{}) map[string]interface{} {
+	if securedFields[key] {
+		if before != nil {
+			before = redacted
+		}
+		if after != nil {
+			after = redacted
+		}
+	}
+	return map[string]interface{}{"before": redact(before), "after": redact(after)}
+}
+
+func redact(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			if securedFields[key] {
+				value[key] = redacted
+			} else {
+				value[key] = redact(nested)
+			}
+		}
+		return value
+	case []interface{}:
+		for i, nested := range value {
+			value[i] = redact(nested)
+		}
+		return value
+	default:
+		return value
+	}
+}
@@ -0,0 +1,248 @@
+package filters_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit filter", func() {
+	var (
+		repository  *storagefakes.FakeStorage
+		fakeHandler *webfakes.FakeHandler
+		filter      *filters.AuditFilter
+	)
+
+	newRequest := func(method, path, body string) *web.Request {
+		req, err := http.NewRequest(method, "http://example.com"+path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := web.ContextWithUser(req.Context(), &web.UserContext{
+			Name:        "admin",
+			AccessLevel: web.GlobalAccess,
+		})
+		return &web.Request{
+			Request: req.WithContext(ctx),
+			Body:    []byte(body),
+		}
+	}
+
+	storedEvent := func() *types.AuditEvent {
+		Expect(repository.CreateCallCount()).To(Equal(1))
+		_, object := repository.CreateArgsForCall(0)
+		return object.(*types.AuditEvent)
+	}
+
+	storedChanges := func() map[string]map[string]interface{} {
+		changes := make(map[string]map[string]interface{})
+		Expect(json.Unmarshal(storedEvent().Changes, &changes)).To(Succeed())
+		return changes
+	}
+
+	BeforeEach(func() {
+		repository = &storagefakes.FakeStorage{}
+		fakeHandler = &webfakes.FakeHandler{}
+		filter = filters.NewAuditFilter(repository, "tenant")
+	})
+
+	Context("when a resource is created", func() {
+		BeforeEach(func() {
+			fakeHandler.HandleReturns(&web.Response{
+				StatusCode: http.StatusCreated,
+				Body:       []byte(`{"id":"platform-id","name":"cf","credentials":{"basic":{"password":"pass"}},"labels":{"tenant":["tenant-id"]}}`),
+			}, nil)
+		})
+
+		It("records the actor, the resource and the outcome", func() {
+			_, err := filter.Run(newRequest(http.MethodPost, web.PlatformsURL, `{"name":"cf"}`), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			event := storedEvent()
+			Expect(event.ID).ToNot(BeEmpty())
+			Expect(event.Actor).To(Equal("admin"))
+			Expect(event.AccessLevel).To(Equal(web.GlobalAccess.String()))
+			Expect(event.Tenant).To(Equal("tenant-id"))
+			Expect(event.Resource).To(Equal(types.PlatformType))
+			Expect(event.ResourceID).To(Equal("platform-id"))
+			Expect(event.Action).To(Equal(types.AuditCreate))
+			Expect(event.Outcome).To(Equal(types.AuditSuccess))
+			Expect(event.StatusCode).To(Equal(http.StatusCreated))
+			Expect(repository.GetCallCount()).To(Equal(0))
+		})
+
+		It("redacts the secured fields", func() {
+			_, err := filter.Run(newRequest(http.MethodPost, web.PlatformsURL, `{"name":"cf"}`), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			changes := storedChanges()
+			Expect(changes["name"]["after"]).To(Equal("cf"))
+			Expect(changes["credentials"]["before"]).To(BeNil())
+			Expect(changes["credentials"]["after"]).To(Equal("[REDACTED]"))
+			Expect(string(storedEvent().Changes)).ToNot(ContainSubstring("pass\""))
+		})
+	})
+
+	Context("when a resource is updated", func() {
+		BeforeEach(func() {
+			fakeHandler.HandleReturns(&web.Response{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"platform-id","name":"k8s","type":"cloudfoundry","labels":{"env":[{"password":"pass"}]}}`),
+			}, nil)
+		})
+
+		It("records the state of the changed fields before and after the update", func() {
+			repository.GetReturns(&types.Platform{
+				Base: types.Base{
+					ID:     "platform-id",
+					Labels: types.Labels{"tenant": {"tenant-id"}},
+				},
+				Name:        "cf",
+				Type:        "cloudfoundry",
+				Credentials: &types.Credentials{Basic: &types.Basic{Username: "user", Password: "pass"}},
+			}, nil)
+			body := `{"name":"k8s","labels":[{"op":"add","key":"env","values":["dev"]}]}`
+			_, err := filter.Run(newRequest(http.MethodPatch, web.PlatformsURL+"/platform-id", body), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(repository.GetCallCount()).To(Equal(1))
+
+			event := storedEvent()
+			Expect(event.Action).To(Equal(types.AuditUpdate))
+			Expect(event.ResourceID).To(Equal("platform-id"))
+			Expect(event.Tenant).To(Equal("tenant-id"))
+			changes := storedChanges()
+			Expect(changes).To(HaveKey("name"))
+			Expect(changes).ToNot(HaveKey("type"))
+			Expect(changes).ToNot(HaveKey("credentials"))
+			Expect(changes["name"]["before"]).To(Equal("cf"))
+			Expect(changes["name"]["after"]).To(Equal("k8s"))
+			Expect(changes["labels"]["before"]).To(Equal(map[string]interface{}{
+				"tenant": []interface{}{"tenant-id"},
+			}))
+			Expect(changes["labels"]["after"]).To(Equal(map[string]interface{}{
+				"env": []interface{}{map[string]interface{}{"password": "[REDACTED]"}},
+			}))
+		})
+
+		It("records only the requested fields when the resource cannot be read", func() {
+			repository.GetReturns(nil, util.ErrNotFoundInStorage)
+			body := `{"name":"k8s"}`
+			_, err := filter.Run(newRequest(http.MethodPatch, web.PlatformsURL+"/platform-id", body), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			changes := storedChanges()
+			Expect(changes).To(HaveLen(1))
+			Expect(changes["name"]["before"]).To(BeNil())
+			Expect(changes["name"]["after"]).To(Equal("k8s"))
+		})
+	})
+
+	Context("when a resource is deleted", func() {
+		It("records the state of the resource before the delete with the secured fields redacted", func() {
+			repository.GetReturns(&types.Platform{
+				Base:        types.Base{ID: "platform-id"},
+				Name:        "cf",
+				Credentials: &types.Credentials{Basic: &types.Basic{Username: "user", Password: "pass"}},
+			}, nil)
+			fakeHandler.HandleReturns(&web.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, nil)
+
+			_, err := filter.Run(newRequest(http.MethodDelete, web.PlatformsURL+"/platform-id", ""), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			changes := storedChanges()
+			Expect(changes["name"]["before"]).To(Equal("cf"))
+			Expect(changes["name"]["after"]).To(BeNil())
+			Expect(changes["credentials"]["before"]).To(Equal("[REDACTED]"))
+			Expect(changes["credentials"]["after"]).To(BeNil())
+			Expect(string(storedEvent().Changes)).ToNot(ContainSubstring("pass\""))
+		})
+	})
+
+	Context("when an instance is provisioned with parameters and context", func() {
+		It("redacts the parameters and the context", func() {
+			fakeHandler.HandleReturns(&web.Response{StatusCode: http.StatusCreated, Body: []byte(`{}`)}, nil)
+			path := web.OSBURL + "/broker-id/v2/service_instances/instance-id"
+			body := `{"service_id":"service-id","parameters":{"password":"pass"},"context":{"token":"secret"}}`
+
+			_, err := filter.Run(newRequest(http.MethodPut, path, body), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			changes := storedChanges()
+			Expect(changes["service_id"]["after"]).To(Equal("service-id"))
+			Expect(changes["parameters"]["after"]).To(Equal("[REDACTED]"))
+			Expect(changes["context"]["after"]).To(Equal("[REDACTED]"))
+			Expect(string(storedEvent().Changes)).ToNot(ContainSubstring("secret"))
+		})
+	})
+
+	Context("when a tenant deletes a resource", func() {
+		It("records the tenant from the request criteria", func() {
+			fakeHandler.HandleReturns(&web.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, nil)
+			req := newRequest(http.MethodDelete, web.ServiceInstancesURL+"/instance-id", "")
+			ctx, err := query.AddCriteria(req.Context(), query.ByLabel(query.EqualsOperator, "tenant", "tenant-id"))
+			Expect(err).ToNot(HaveOccurred())
+			req.Request = req.WithContext(ctx)
+
+			_, err = filter.Run(req, fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(storedEvent().Tenant).To(Equal("tenant-id"))
+		})
+	})
+
+	Context("when an OSB binding is deleted", func() {
+		It("records the binding as the changed resource", func() {
+			repository.GetReturns(nil, util.ErrNotFoundInStorage)
+			fakeHandler.HandleReturns(&web.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, nil)
+			path := web.OSBURL + "/broker-id/v2/service_instances/instance-id/service_bindings/binding-id"
+
+			_, err := filter.Run(newRequest(http.MethodDelete, path, ""), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			event := storedEvent()
+			Expect(event.Resource).To(Equal(types.ServiceBindingType))
+			Expect(event.ResourceID).To(Equal("binding-id"))
+			Expect(event.Action).To(Equal(types.AuditDelete))
+			Expect(event.Changes).To(BeNil())
+		})
+	})
+
+	Context("when the request fails", func() {
+		It("records the failure and returns the error", func() {
+			fakeHandler.HandleReturns(nil, &util.HTTPError{
+				ErrorType:   "Forbidden",
+				Description: "not authorized",
+				StatusCode:  http.StatusForbidden,
+			})
+
+			repository.GetReturns(&types.ServiceBroker{Base: types.Base{ID: "broker-id"}, Name: "broker"}, nil)
+
+			_, err := filter.Run(newRequest(http.MethodDelete, web.ServiceBrokersURL+"/broker-id", ""), fakeHandler)
+			Expect(err).To(HaveOccurred())
+
+			event := storedEvent()
+			Expect(event.Outcome).To(Equal(types.AuditFailure))
+			Expect(event.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(event.Changes).To(BeNil())
+		})
+	})
+
+	Context("when the audit event cannot be stored", func() {
+		It("does not fail the request", func() {
+			repository.CreateReturns(nil, context.DeadlineExceeded)
+			fakeHandler.HandleReturns(&web.Response{StatusCode: http.StatusCreated, Body: []byte(`{"id":"id"}`)}, nil)
+
+			resp, err := filter.Run(newRequest(http.MethodPost, web.PlatformsURL, `{}`), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		})
+	})
+})
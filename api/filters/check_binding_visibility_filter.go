@@ -70,8 +70,7 @@ func (f *serviceBindingVisibilityFilter) Run(req *web.Request, next web.Handler)
 	}
 
 	var instanceID string
-	if req.Method == http.MethodPatch {
-		bindingID := req.PathParams[web.PathParamResourceID]
+	if bindingID, found := req.PathParams[web.PathParamResourceID]; found {
 		bindingObj, err := f.repository.Get(ctx, types.ServiceBindingType, query.ByField(query.EqualsOperator, "id", bindingID))
 		if err != nil {
 			return nil, util.HandleStorageError(err, types.ServiceBindingType.String())
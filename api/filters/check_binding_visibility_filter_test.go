@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters_test
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Service Binding Visibility Filter", func() {
+	const tenantLabelKey = "tenant"
+
+	var (
+		repository  *storagefakes.FakeStorage
+		fakeHandler *webfakes.FakeHandler
+		filter      web.Filter
+	)
+
+	newRequest := func(method, path, body string, pathParams map[string]string) *web.Request {
+		req, err := http.NewRequest(method, "http://example.com"+path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx, err := query.AddCriteria(req.Context(), query.ByLabel(query.EqualsOperator, tenantLabelKey, "tenant-id"))
+		Expect(err).ToNot(HaveOccurred())
+		return &web.Request{
+			Request:    req.WithContext(ctx),
+			Body:       []byte(body),
+			PathParams: pathParams,
+		}
+	}
+
+	checkedInstanceID := func() string {
+		Expect(repository.CountCallCount()).To(Equal(1))
+		_, objectType, criteria := repository.CountArgsForCall(0)
+		Expect(objectType).To(Equal(types.ServiceInstanceType))
+		return query.RetrieveFromCriteria("id", criteria...)
+	}
+
+	BeforeEach(func() {
+		repository = &storagefakes.FakeStorage{}
+		repository.GetReturns(&types.ServiceBinding{
+			Base:              types.Base{ID: "binding-id"},
+			ServiceInstanceID: "instance-id",
+		}, nil)
+		repository.CountReturns(1, nil)
+		fakeHandler = &webfakes.FakeHandler{}
+		filter = filters.NewServiceBindingVisibilityFilter(repository, tenantLabelKey)
+	})
+
+	Context("when a binding is created", func() {
+		It("checks the instance in the request body", func() {
+			_, err := filter.Run(newRequest(http.MethodPost, web.ServiceBindingsURL, `{"service_instance_id":"other-instance-id"}`, nil), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(repository.GetCallCount()).To(Equal(0))
+			Expect(checkedInstanceID()).To(Equal("other-instance-id"))
+			Expect(fakeHandler.HandleCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when a binding is rotated", func() {
+		var request *web.Request
+
+		BeforeEach(func() {
+			request = newRequest(http.MethodPost, web.ServiceBindingsURL+"/binding-id"+web.RotateURL, `{"service_instance_id":"other-instance-id"}`,
+				map[string]string{web.PathParamResourceID: "binding-id"})
+		})
+
+		It("checks the instance of the binding", func() {
+			_, err := filter.Run(request, fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(repository.GetCallCount()).To(Equal(1))
+			Expect(checkedInstanceID()).To(Equal("instance-id"))
+			Expect(fakeHandler.HandleCallCount()).To(Equal(1))
+		})
+
+		It("returns 404 if the instance is not accessible by the tenant", func() {
+			repository.CountReturns(0, nil)
+
+			_, err := filter.Run(request, fakeHandler)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusNotFound))
+			Expect(fakeHandler.HandleCallCount()).To(Equal(0))
+		})
+
+		It("returns 404 if the binding does not exist", func() {
+			repository.GetReturns(nil, util.ErrNotFoundInStorage)
+
+			_, err := filter.Run(request, fakeHandler)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusNotFound))
+			Expect(repository.CountCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when a binding is updated", func() {
+		It("checks the instance of the binding", func() {
+			_, err := filter.Run(newRequest(http.MethodPatch, web.ServiceBindingsURL+"/binding-id", `{"name":"new-name"}`,
+				map[string]string{web.PathParamResourceID: "binding-id"}), fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(checkedInstanceID()).To(Equal("instance-id"))
+		})
+	})
+})
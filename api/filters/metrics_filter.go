@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsFilterName is the name of the metrics filter
+const MetricsFilterName = "MetricsFilter"
+
+// MetricsFilter counts the handled requests and observes their latency by method, route and response status
+type MetricsFilter struct {
+	requests  *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+}
+
+// NewMetricsFilter returns a metrics filter whose metrics are registered in the provided registerer
+func NewMetricsFilter(registerer prometheus.Registerer) *MetricsFilter {
+	labels := []string{"method", "route", "status"}
+	filter := &MetricsFilter{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sm",
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of handled HTTP requests.",
+		}, labels),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "sm",
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the handled HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+	}
+	registerer.MustRegister(filter.requests, filter.durations)
+	return filter
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*MetricsFilter) Name() string {
+	return MetricsFilterName
+}
+
+// Run records the metrics of the request after it is handled by the rest of the chain. Requests whose response
+// writer is hijacked, e.g. websocket connections, are not recorded as their duration is the one of the connection.
+func (mf *MetricsFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	start := time.Now()
+	resp, err := next.Handle(req)
+	if req.IsResponseWriterHijacked() {
+		return resp, err
+	}
+
+	labels := prometheus.Labels{
+		"method": req.Method,
+		"route":  requestRoute(req),
+		"status": strconv.Itoa(responseStatus(resp, err)),
+	}
+	mf.requests.With(labels).Inc()
+	mf.durations.With(labels).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*MetricsFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+			},
+		},
+	}
+}
+
+// requestRoute returns the path template of the route handling the request, so that the number of label values
+// does not grow with the resource IDs in the request paths
+func requestRoute(req *web.Request) string {
+	if route := mux.CurrentRoute(req.Request); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
+
+// responseStatus returns the status of the response which is sent for the provided handler result
+func responseStatus(resp *web.Response, err error) int {
+	if err == nil {
+		return resp.StatusCode
+	}
+	switch e := err.(type) {
+	case *util.UnsupportedQueryError:
+		return http.StatusBadRequest
+	case *util.HTTPError:
+		if e.StatusCode != 0 {
+			return e.StatusCode
+		}
+	}
+	return http.StatusInternalServerError
+}
@@ -57,6 +57,9 @@ func oidcAuthnMatchers() []web.FilterMatcher {
 					web.ConfigURL+"/**",
 					web.ProfileURL+"/**",
 					web.OperationsURL+"/**",
+					web.EventsURL+"/**",
+					web.AuditEventsURL+"/**",
+					web.TenantURL+"/*"+web.QuotasURL,
 				),
 			},
 		},
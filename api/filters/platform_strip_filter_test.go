@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/tidwall/sjson"
+
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Platform Strip Filter", func() {
+	const (
+		propertyNotToBeDeleted = "name"
+		defaultValue           = "value"
+	)
+	var (
+		filter                    PlatformStripFilter
+		handler                   *webfakes.FakeHandler
+		jsonWithPropertiesToStrip string
+	)
+
+	BeforeEach(func() {
+		handler = &webfakes.FakeHandler{}
+		jsonWithPropertiesToStrip = `{}`
+	})
+
+	for _, method := range []string{http.MethodPost, http.MethodPatch} {
+		method := method
+		Context(method+" platform", func() {
+			When("body has properties which cannot be set", func() {
+				It("should remove them from request body", func() {
+					var err error
+					for _, prop := range platformUnmodifiableProperties {
+						jsonWithPropertiesToStrip, err = sjson.Set(jsonWithPropertiesToStrip, prop, defaultValue)
+						Expect(err).ToNot(HaveOccurred())
+					}
+					jsonWithPropertiesToStrip, err = sjson.Set(jsonWithPropertiesToStrip, propertyNotToBeDeleted, defaultValue)
+					Expect(err).ToNot(HaveOccurred())
+
+					req := mockedRequest(method, jsonWithPropertiesToStrip)
+					_, err = filter.Run(req, handler)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(handler.HandleCallCount()).To(Equal(1))
+					requestBody := handler.HandleArgsForCall(0).Body
+					for _, prop := range platformUnmodifiableProperties {
+						Expect(gjson.GetBytes(requestBody, prop).Exists()).To(BeFalse())
+					}
+					Expect(gjson.GetBytes(requestBody, propertyNotToBeDeleted).String()).To(Equal(defaultValue))
+				})
+			})
+		})
+	}
+})
@@ -65,6 +65,13 @@ func (flo *ProtectedLabelsFilter) Run(req *web.Request, next web.Handler) (*web.
 			return next.Handle(req)
 		}
 
+		if req.URL.Path == web.VisibilitiesBatchURL {
+			if err := flo.checkBatchItems(req.Body); err != nil {
+				return nil, err
+			}
+			return next.Handle(req)
+		}
+
 		var result types.Base
 		if err := json.Unmarshal(req.Body, &result); err != nil {
 			return nil, &util.HTTPError{
@@ -108,6 +115,47 @@ func (flo *ProtectedLabelsFilter) Run(req *web.Request, next web.Handler) (*web.
 	return next.Handle(req)
 }
 
+// checkBatchItems checks the labels of the visibilities created and the label changes applied by a visibilities batch
+func (flo *ProtectedLabelsFilter) checkBatchItems(body []byte) error {
+	var batch struct {
+		Items []struct {
+			Visibility *types.Base        `json:"visibility"`
+			Labels     types.LabelChanges `json:"labels"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: "Invalid JSON body",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	for _, item := range batch.Items {
+		if item.Visibility != nil {
+			for lKey := range item.Visibility.Labels {
+				if flo.protectedLabels[lKey] {
+					return &util.HTTPError{
+						ErrorType:   "BadRequest",
+						Description: fmt.Sprintf("Set/Add values for label %s is not allowed", lKey),
+						StatusCode:  http.StatusBadRequest,
+					}
+				}
+			}
+		}
+		for _, lc := range item.Labels {
+			if flo.protectedLabels[lc.Key] {
+				return &util.HTTPError{
+					ErrorType:   "BadRequest",
+					Description: fmt.Sprintf("Modifying is not allowed for label %s", lc.Key),
+					StatusCode:  http.StatusBadRequest,
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (flo *ProtectedLabelsFilter) FilterMatchers() []web.FilterMatcher {
 	return []web.FilterMatcher{
 		{
@@ -117,6 +117,47 @@ var _ = Describe("Forbidden label operations filter", func() {
 		})
 	})
 
+	Context("POST to visibilities batch", func() {
+		var batchRequest = func(json string) *web.Request {
+			req, err := http.NewRequest(http.MethodPost, web.VisibilitiesBatchURL, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			return &web.Request{Request: req, Body: []byte(json)}
+		}
+
+		When("a created visibility has forbidden labels", func() {
+			It("should return 400", func() {
+				req := batchRequest(`{"items": [{"action": "create", "visibility": {"labels": {"forbidden": ["forbidden_value"]}}}]}`)
+				_, err := filter.Run(req, handler)
+				httpErr, ok := err.(*util.HTTPError)
+				Expect(ok).To(BeTrue())
+				Expect(httpErr.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(httpErr.Description).To(ContainSubstring("Set/Add values for label forbidden is not allowed"))
+				Expect(handler.HandleCallCount()).To(Equal(0))
+			})
+		})
+
+		When("a visibility is modified with forbidden labels", func() {
+			It("should return 400", func() {
+				req := batchRequest(`{"items": [{"action": "update", "id": "id", "labels": [{"op": "add", "key":"forbidden", "values":["forbidden_value"]}]}]}`)
+				_, err := filter.Run(req, handler)
+				httpErr, ok := err.(*util.HTTPError)
+				Expect(ok).To(BeTrue())
+				Expect(httpErr.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(httpErr.Description).To(ContainSubstring("Modifying is not allowed for label forbidden"))
+				Expect(handler.HandleCallCount()).To(Equal(0))
+			})
+		})
+
+		When("items have no forbidden labels", func() {
+			It("should call next filter in chain", func() {
+				req := batchRequest(`{"items": [{"action": "create", "visibility": {"labels": {"allowed": ["value"]}}}, {"action": "update", "id": "id", "labels": [{"op": "add", "key":"allowed", "values":["value"]}]}]}`)
+				_, err := filter.Run(req, handler)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(handler.HandleCallCount()).To(Equal(1))
+			})
+		})
+	})
+
 	Context("PATCH", func() {
 		When("entity is modified with forbidden labels", func() {
 			It("should return 400", func() {
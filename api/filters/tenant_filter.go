@@ -3,7 +3,10 @@ package filters
 import (
 	"errors"
 	"fmt"
+	"net/http"
+
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/web"
 )
 
@@ -16,7 +19,7 @@ func NewMultitenancyFilters(labelKey string, extractTenantFunc func(request *web
 		return nil, errors.New("extractTenantFunc should be provided")
 	}
 
-	return NewLabelingFilters(LabelName, labelKey, []string{web.PlatformsURL, web.ServiceBrokersURL, web.ServiceInstancesURL, web.ServiceBindingsURL}, func(request *web.Request) (string, error) {
+	extractValueFunc := func(request *web.Request) (string, error) {
 		ctx := request.Context()
 
 		userContext, found := web.UserFromContext(ctx)
@@ -30,7 +33,29 @@ func NewMultitenancyFilters(labelKey string, extractTenantFunc func(request *web
 		}
 
 		return extractTenantFunc(request)
-	}), nil
+	}
+
+	multitenancyFilters := NewLabelingFilters(LabelName, labelKey, []string{web.PlatformsURL, web.ServiceBrokersURL, web.ServiceInstancesURL, web.ServiceBindingsURL, web.EventsURL}, extractValueFunc)
+	return append(multitenancyFilters, newAuditEventsCriteriaFilter(extractValueFunc)), nil
+}
+
+// newAuditEventsCriteriaFilter creates a LabelingFilter that restricts the returned audit events to the ones of the tenant
+func newAuditEventsCriteriaFilter(extractValueFunc func(request *web.Request) (string, error)) *LabelingFilter {
+	return &LabelingFilter{
+		LabelKey:     "tenant",
+		FilterName:   LabelName + "AuditEvents" + LabelCriteriaFilterNameSuffix,
+		BasePaths:    []string{web.AuditEventsURL},
+		Methods:      []string{http.MethodGet},
+		ExtractValue: extractValueFunc,
+		LabelingFunc: func(request *web.Request, field, value string) error {
+			ctx, err := query.AddCriteria(request.Context(), query.ByField(query.EqualsOperator, field, value))
+			if err != nil {
+				return fmt.Errorf("could not add criteria with field %s and value %s: %s", field, value, err)
+			}
+			request.Request = request.WithContext(ctx)
+			return nil
+		},
+	}
 }
 
 //TenantLabelingFilterName returns the name of the filter that is adding the tenant label to tenant-scoped resources
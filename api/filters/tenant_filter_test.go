@@ -127,6 +127,45 @@ var _ = Describe("TenantFilters", func() {
 				}
 			})
 
+			Describe("Audit events criteria filter", func() {
+				auditEventsFilter := func() web.Filter {
+					return multitenancyFilters[len(multitenancyFilters)-1]
+				}
+
+				When("request is sent with tenant scope", func() {
+					It("should add a criteria by the tenant field", func() {
+						newReq, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+						Expect(err).ShouldNot(HaveOccurred())
+						fakeRequest.Request = newReq.WithContext(web.ContextWithUser(context.Background(), &web.UserContext{
+							AuthenticationType: web.Bearer,
+							Name:               "test",
+							AccessLevel:        web.TenantAccess,
+						}))
+						_, err = auditEventsFilter().Run(fakeRequest, fakeHandler)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(fakeHandler.HandleCallCount()).To(Equal(1))
+						criteria := query.CriteriaForContext(fakeHandler.HandleArgsForCall(0).Context())
+						Expect(criteria).To(ConsistOf(query.ByField(query.EqualsOperator, "tenant", tenant)))
+					})
+				})
+
+				When("request is sent with global scope", func() {
+					It("should not modify the request criteria", func() {
+						newReq, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+						Expect(err).ShouldNot(HaveOccurred())
+						fakeRequest.Request = newReq.WithContext(web.ContextWithUser(context.Background(), &web.UserContext{
+							AuthenticationType: web.Bearer,
+							Name:               "test",
+							AccessLevel:        web.GlobalAccess,
+						}))
+						_, err = auditEventsFilter().Run(fakeRequest, fakeHandler)
+						Expect(err).ToNot(HaveOccurred())
+						criteria := query.CriteriaForContext(fakeHandler.HandleArgsForCall(0).Context())
+						Expect(criteria).To(BeEmpty())
+					})
+				})
+			})
+
 			Describe("Labeling filter", func() {
 
 				Describe("Tenant access", func() {
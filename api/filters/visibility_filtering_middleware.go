@@ -11,6 +11,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
 )
 
 type visibilityFilteringMiddleware struct {
@@ -20,20 +21,11 @@ type visibilityFilteringMiddleware struct {
 
 func (m visibilityFilteringMiddleware) Run(req *web.Request, next web.Handler) (*web.Response, error) {
 	ctx := req.Context()
-	userCtx, ok := web.UserFromContext(ctx)
-	if !ok {
-		return nil, errors.New("no user found")
-	}
-	if userCtx.AuthenticationType != web.Basic {
-		log.C(ctx).Debugf("Authentication is %s, not basic so proceed without visibility filter criteria", userCtx.AuthenticationType)
-		return next.Handle(req)
-	}
-	platform := &types.Platform{}
-	if err := userCtx.Data(platform); err != nil {
+	platform, err := visibilityFilteredPlatform(ctx)
+	if err != nil {
 		return nil, err
 	}
-	if platform.Type != types.K8sPlatformType {
-		log.C(ctx).Debugf("Platform type is %s, which is not kubernetes. Skip filtering on visibilities", platform.Type)
+	if platform == nil {
 		return next.Handle(req)
 	}
 
@@ -71,3 +63,59 @@ func (m visibilityFilteringMiddleware) Run(req *web.Request, next web.Handler) (
 	req.Request = req.WithContext(ctx)
 	return next.Handle(req)
 }
+
+// visibilityFilteredPlatform returns the platform making the request if the resources it gets are filtered by
+// the visibilities of the platform or nil otherwise
+func visibilityFilteredPlatform(ctx context.Context) (*types.Platform, error) {
+	userCtx, ok := web.UserFromContext(ctx)
+	if !ok {
+		return nil, errors.New("no user found")
+	}
+	if userCtx.AuthenticationType != web.Basic {
+		log.C(ctx).Debugf("Authentication is %s, not basic so proceed without visibility filter criteria", userCtx.AuthenticationType)
+		return nil, nil
+	}
+	platform := &types.Platform{}
+	if err := userCtx.Data(platform); err != nil {
+		return nil, err
+	}
+	if platform.Type != types.K8sPlatformType {
+		log.C(ctx).Debugf("Platform type is %s, which is not kubernetes. Skip filtering on visibilities", platform.Type)
+		return nil, nil
+	}
+	return platform, nil
+}
+
+// VisibilityCriteria returns the criteria with which the service plans, service offerings or service brokers of the
+// provided type are filtered by the visibilities of the platform making the request, e.g. when they are fetched as
+// related resources. The returned criteria are empty if the resources are not filtered and visible is false if none
+// of the resources are visible to the platform.
+func VisibilityCriteria(ctx context.Context, repository storage.Repository, objectType types.ObjectType) (criteria []query.Criterion, visible bool, err error) {
+	var criteriaFunc func(ctx context.Context, platformID string) (*query.Criterion, error)
+	switch objectType {
+	case types.ServicePlanType:
+		criteriaFunc = plansCriteriaFunc(repository)
+	case types.ServiceOfferingType:
+		criteriaFunc = servicesCriteriaFunc(repository)
+	case types.ServiceBrokerType:
+		criteriaFunc = brokersCriteriaFunc(repository)
+	default:
+		return nil, true, nil
+	}
+
+	if _, found := web.UserFromContext(ctx); !found {
+		return nil, true, nil
+	}
+	platform, err := visibilityFilteredPlatform(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if platform == nil {
+		return nil, true, nil
+	}
+	criterion, err := criteriaFunc(ctx, platform.ID)
+	if err != nil || criterion == nil {
+		return nil, false, err
+	}
+	return []query.Criterion{*criterion}, true, nil
+}
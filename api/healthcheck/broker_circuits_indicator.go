@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/health"
+)
+
+// NewBrokerCircuitsIndicator returns new health indicator for the brokers to which the requests fail fast
+func NewBrokerCircuitsIndicator(circuits *operations.BrokerCircuits) health.Indicator {
+	return &brokerCircuitsIndicator{
+		circuits: circuits,
+	}
+}
+
+type brokerCircuitsIndicator struct {
+	circuits *operations.BrokerCircuits
+}
+
+// Name returns the name of the indicator
+func (bci *brokerCircuitsIndicator) Name() string {
+	return health.BrokerCircuitsIndicatorName
+}
+
+// Status returns status of the health check
+func (bci *brokerCircuitsIndicator) Status() (interface{}, error) {
+	details := bci.circuits.Unavailable()
+	if len(details) > 0 {
+		return details, fmt.Errorf("there are %d unavailable brokers", len(details))
+	}
+	return details, nil
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Broker Circuits Indicator", func() {
+	var indicator health.Indicator
+	var circuits *operations.BrokerCircuits
+	var broker *types.ServiceBroker
+
+	BeforeEach(func() {
+		circuits = operations.NewBrokerCircuits(&operations.BrokerCircuitSettings{FailureThreshold: 1, OpenTimeout: time.Minute})
+		broker = &types.ServiceBroker{Base: types.Base{ID: "broker-id"}, Name: "test-broker"}
+		indicator = NewBrokerCircuitsIndicator(circuits)
+	})
+
+	Context("Name", func() {
+		It("should not be empty", func() {
+			Expect(indicator.Name()).Should(Equal(health.BrokerCircuitsIndicatorName))
+		})
+	})
+
+	Context("There are brokers with open circuits", func() {
+		BeforeEach(func() {
+			circuits.RecordFailure(broker, "broker is down")
+		})
+		It("should return error", func() {
+			details, err := indicator.Status()
+			Expect(err).Should(HaveOccurred())
+			Expect(details.(map[string]*operations.BrokerHealth)).To(HaveKey(broker.Name))
+		})
+	})
+
+	Context("All broker circuits are closed", func() {
+		It("should not return error", func() {
+			_, err := indicator.Status()
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+})
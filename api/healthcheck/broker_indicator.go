@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// NewBrokerIndicator returns new health indicator for the catalog refreshes of the brokers
+func NewBrokerIndicator(ctx context.Context, repository storage.Repository) health.Indicator {
+	return &brokerIndicator{
+		ctx:        ctx,
+		repository: repository,
+	}
+}
+
+type brokerIndicator struct {
+	repository storage.Repository
+	ctx        context.Context
+}
+
+// Name returns the name of the indicator
+func (bi *brokerIndicator) Name() string {
+	return health.BrokersIndicatorName
+}
+
+// Status returns status of the health check. A broker is down if the last refresh of its catalog failed and the broker
+// has not been updated since.
+func (bi *brokerIndicator) Status() (interface{}, error) {
+	objList, err := bi.repository.List(bi.ctx, types.ServiceBrokerType)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch brokers health from storage: %v", err)
+	}
+	brokers := objList.(*types.ServiceBrokers).ServiceBrokers
+	if len(brokers) == 0 {
+		return map[string]*health.Health{}, nil
+	}
+
+	ids := make([]string, 0, len(brokers))
+	for _, broker := range brokers {
+		ids = append(ids, broker.ID)
+	}
+	lastOperations, err := bi.repository.QueryForList(bi.ctx, types.OperationType, storage.QueryForLastOperationsPerResource, map[string]interface{}{
+		"id_list":       ids,
+		"resource_type": types.ServiceBrokerType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch last operations of brokers from storage: %v", err)
+	}
+	failedRefreshes := make(map[string]*types.Operation)
+	for i := 0; i < lastOperations.Len(); i++ {
+		operation := lastOperations.ItemAt(i).(*types.Operation)
+		if operation.Description == operations.CatalogRefreshDescription && operation.State == types.FAILED {
+			failedRefreshes[operation.ResourceID] = operation
+		}
+	}
+
+	details := make(map[string]*health.Health)
+	for _, broker := range brokers {
+		healthObj := health.New().WithStatus(health.StatusUp)
+		if operation, failed := failedRefreshes[broker.ID]; failed {
+			healthObj.WithStatus(health.StatusDown).
+				WithDetail("since", operation.UpdatedAt).
+				WithDetail("errors", operation.Errors)
+		}
+		details[broker.Name] = healthObj
+	}
+	if len(failedRefreshes) > 0 {
+		err = fmt.Errorf("catalog refresh of %d brokers failed", len(failedRefreshes))
+	}
+	return details, err
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Brokers Indicator", func() {
+	var indicator health.Indicator
+	var repository *storagefakes.FakeStorage
+	var operation *types.Operation
+
+	BeforeEach(func() {
+		repository = &storagefakes.FakeStorage{}
+		broker := &types.ServiceBroker{Base: types.Base{ID: "broker-id"}, Name: "test-broker"}
+		repository.ListReturns(&types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{broker}}, nil)
+		operation = &types.Operation{
+			Description:  operations.CatalogRefreshDescription,
+			Type:         types.UPDATE,
+			State:        types.SUCCEEDED,
+			ResourceID:   broker.ID,
+			ResourceType: types.ServiceBrokerType,
+		}
+		repository.QueryForListReturns(&types.Operations{Operations: []*types.Operation{operation}}, nil)
+		indicator = NewBrokerIndicator(context.TODO(), repository)
+	})
+
+	Context("Name", func() {
+		It("should not be empty", func() {
+			Expect(indicator.Name()).Should(Equal(health.BrokersIndicatorName))
+		})
+	})
+
+	Context("The last catalog refresh of a broker failed", func() {
+		BeforeEach(func() {
+			operation.State = types.FAILED
+			operation.Errors = []byte(`{"description":"catalog fetch failed"}`)
+		})
+		It("should return error", func() {
+			details, err := indicator.Status()
+			Expect(err).Should(HaveOccurred())
+			brokerHealth := details.(map[string]*health.Health)["test-broker"]
+			Expect(brokerHealth.Status).To(Equal(health.StatusDown))
+			Expect(brokerHealth.Details["errors"]).ShouldNot(BeNil())
+		})
+	})
+
+	Context("The last operation of a broker failed but is not a catalog refresh", func() {
+		BeforeEach(func() {
+			operation.State = types.FAILED
+			operation.Description = ""
+		})
+		It("should not return error", func() {
+			_, err := indicator.Status()
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("The last catalog refresh of a broker succeeded", func() {
+		It("should not return error", func() {
+			details, err := indicator.Status()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(details.(map[string]*health.Health)["test-broker"].Status).To(Equal(health.StatusUp))
+		})
+	})
+
+	Context("Storage returns error", func() {
+		var expectedErr error
+		BeforeEach(func() {
+			expectedErr = errors.New("storage err")
+			repository.ListReturns(nil, expectedErr)
+		})
+		It("should return error", func() {
+			_, err := indicator.Status()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(expectedErr.Error()))
+		})
+	})
+})
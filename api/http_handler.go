@@ -21,9 +21,11 @@ import (
 	"net/http"
 
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/tracing"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // HTTPHandler converts a pkg/web.Handler and pkg/web.HandlerFunc to a standard http.Handler
@@ -48,11 +50,14 @@ func (h *HTTPHandler) Handle(req *web.Request) (resp *web.Response, err error) {
 // ServeHTTP implements the http.Handler interface and allows wrapping web.Handlers into http.Handlers
 func (h *HTTPHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	var err error
-	ctx := req.Context()
+	var response *web.Response
+	ctx, span := tracing.StartServerSpan(req, routeName(req))
+	req = req.WithContext(ctx)
 	defer func() {
 		if err != nil {
 			util.WriteError(ctx, err, res)
 		}
+		endServerSpan(ctx, span, response, err)
 	}()
 
 	var request *web.Request
@@ -61,7 +66,6 @@ func (h *HTTPHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	var response *web.Response
 	response, err = h.Handler.Handle(request)
 	ctx = request.Context() // logging filter may have enriched the context with a logger
 	if request.IsResponseWriterHijacked() {
@@ -87,6 +91,36 @@ func (h *HTTPHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func routeName(req *http.Request) string {
+	if route := mux.CurrentRoute(req); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return req.URL.Path
+}
+
+func endServerSpan(ctx context.Context, span trace.Span, response *web.Response, err error) {
+	span.SetAttributes(tracing.CorrelationIDKey.String(log.CorrelationIDFromContext(ctx)))
+	switch e := err.(type) {
+	case nil:
+		if response != nil {
+			tracing.SetHTTPStatus(span, response.StatusCode)
+		}
+	case *util.HTTPError:
+		tracing.SetHTTPStatus(span, e.StatusCode)
+	case *util.UnsupportedQueryError:
+		tracing.SetHTTPStatus(span, http.StatusBadRequest)
+	default:
+		tracing.SetHTTPStatus(span, http.StatusInternalServerError)
+	}
+	if err != nil {
+		// the status of the span is derived from the response status and not from the error
+		span.RecordError(err)
+	}
+	span.End()
+}
+
 func convertToWebRequest(request *http.Request, rw http.ResponseWriter) (*web.Request, error) {
 	pathParams := mux.Vars(request)
 
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace is the prefix of the names of all Service Manager metrics
+const Namespace = "sm"
+
+// OperationCounter provides the number of stored operations
+type OperationCounter interface {
+	OperationCounts(ctx context.Context) ([]operations.OperationCount, error)
+}
+
+type operationsCollector struct {
+	ctx             context.Context
+	counter         OperationCounter
+	refreshInterval time.Duration
+	operations      *prometheus.Desc
+
+	mutex       sync.Mutex
+	counts      []operations.OperationCount
+	refreshedAt time.Time
+}
+
+// NewOperationsCollector returns a collector of the number of stored operations by resource type, type and state.
+// The operations are counted at most once per refresh interval, the last counts are exposed in the meantime.
+func NewOperationsCollector(ctx context.Context, counter OperationCounter, refreshInterval time.Duration) prometheus.Collector {
+	return &operationsCollector{
+		ctx:             ctx,
+		counter:         counter,
+		refreshInterval: refreshInterval,
+		operations: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "operations"),
+			"Number of stored operations.", []string{"resource_type", "type", "state"}, nil),
+	}
+}
+
+func (c *operationsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.operations
+}
+
+func (c *operationsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.counts == nil || time.Since(c.refreshedAt) >= c.refreshInterval {
+		counts, err := c.counter.OperationCounts(c.ctx)
+		if err != nil {
+			log.C(c.ctx).WithError(err).Warn("Could not count operations, exposing the last counts")
+		} else {
+			c.counts = counts
+			c.refreshedAt = time.Now()
+		}
+	}
+
+	for _, count := range c.counts {
+		ch <- prometheus.MustNewConstMetric(c.operations, prometheus.GaugeValue, float64(count.Count),
+			count.ResourceType.String(), string(count.Type), string(count.State))
+	}
+}
+
+type workerPoolsCollector struct {
+	pools       map[string]*operations.Scheduler
+	size        *prometheus.Desc
+	busyWorkers *prometheus.Desc
+}
+
+// NewWorkerPoolsCollector returns a collector of the size and the busy workers of the provided worker pools
+func NewWorkerPoolsCollector(pools map[string]*operations.Scheduler) prometheus.Collector {
+	return &workerPoolsCollector{
+		pools: pools,
+		size: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "scheduler", "workers"),
+			"Number of workers in the worker pool.", []string{"pool"}, nil),
+		busyWorkers: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "scheduler", "busy_workers"),
+			"Number of workers in the worker pool which are executing operations.", []string{"pool"}, nil),
+	}
+}
+
+func (c *workerPoolsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.busyWorkers
+}
+
+func (c *workerPoolsCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, scheduler := range c.pools {
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(scheduler.PoolSize()), name)
+		ch <- prometheus.MustNewConstMetric(c.busyWorkers, prometheus.GaugeValue, float64(scheduler.BusyWorkers()), name)
+	}
+}
+
+type notificatorCollector struct {
+	notificator         storage.Notificator
+	connectedPlatforms  *prometheus.Desc
+	queuedNotifications *prometheus.Desc
+}
+
+// NewNotificatorCollector returns a collector of the connected platforms and the queued notifications of a notificator
+func NewNotificatorCollector(notificator storage.Notificator) prometheus.Collector {
+	return &notificatorCollector{
+		notificator: notificator,
+		connectedPlatforms: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "notifications", "connected_platforms"),
+			"Number of platforms connected for receiving notifications.", nil, nil),
+		queuedNotifications: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "notifications", "queued"),
+			"Number of notifications waiting to be sent to the connected platforms.", nil, nil),
+	}
+}
+
+func (c *notificatorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.connectedPlatforms
+	ch <- c.queuedNotifications
+}
+
+func (c *notificatorCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.notificator.Stats()
+	ch <- prometheus.MustNewConstMetric(c.connectedPlatforms, prometheus.GaugeValue, float64(stats.ConnectedPlatforms))
+	ch <- prometheus.MustNewConstMetric(c.queuedNotifications, prometheus.GaugeValue, float64(stats.QueuedNotifications))
+}
+
+type dbStatsCollector struct {
+	stats              func() sql.DBStats
+	maxOpenConnections *prometheus.Desc
+	openConnections    *prometheus.Desc
+	inUseConnections   *prometheus.Desc
+	idleConnections    *prometheus.Desc
+	waitCount          *prometheus.Desc
+	waitDuration       *prometheus.Desc
+}
+
+// NewDBStatsCollector returns a collector of the statistics of a database connection pool
+func NewDBStatsCollector(stats func() sql.DBStats) prometheus.Collector {
+	return &dbStatsCollector{
+		stats: stats,
+		maxOpenConnections: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "storage", "max_open_connections"),
+			"Maximum number of open connections to the database.", nil, nil),
+		openConnections: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "storage", "open_connections"),
+			"Number of established connections to the database, both in use and idle.", nil, nil),
+		inUseConnections: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "storage", "in_use_connections"),
+			"Number of connections to the database which are currently in use.", nil, nil),
+		idleConnections: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "storage", "idle_connections"),
+			"Number of idle connections to the database.", nil, nil),
+		waitCount: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "storage", "wait_count_total"),
+			"Total number of connections waited for.", nil, nil),
+		waitDuration: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "storage", "wait_duration_seconds_total"),
+			"Total time blocked waiting for a new connection.", nil, nil),
+	}
+}
+
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpenConnections
+	ch <- c.openConnections
+	ch <- c.inUseConnections
+	ch <- c.idleConnections
+	ch <- c.waitCount
+	ch <- c.waitDuration
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpenConnections, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.openConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUseConnections, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idleConnections, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
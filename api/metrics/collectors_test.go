@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/api/metrics"
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeOperationCounter struct {
+	calls  int
+	counts []operations.OperationCount
+	err    error
+}
+
+func (c *fakeOperationCounter) OperationCounts(_ context.Context) ([]operations.OperationCount, error) {
+	c.calls++
+	return c.counts, c.err
+}
+
+var _ = Describe("Metrics", func() {
+	Describe("operations collector", func() {
+		var counter *fakeOperationCounter
+
+		BeforeEach(func() {
+			counter = &fakeOperationCounter{
+				counts: []operations.OperationCount{
+					{ResourceType: types.ServiceInstanceType, Type: types.CREATE, State: types.IN_PROGRESS, Count: 3},
+				},
+			}
+		})
+
+		It("exposes the operation counts", func() {
+			collector := metrics.NewOperationsCollector(context.Background(), counter, time.Minute)
+			expected := `
+# HELP sm_operations Number of stored operations.
+# TYPE sm_operations gauge
+sm_operations{resource_type="/v1/service_instances",state="in progress",type="create"} 3
+`
+			Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected))).To(Succeed())
+		})
+
+		It("counts the operations at most once per refresh interval", func() {
+			collector := metrics.NewOperationsCollector(context.Background(), counter, time.Minute)
+			testutil.CollectAndCount(collector)
+			testutil.CollectAndCount(collector)
+			Expect(counter.calls).To(Equal(1))
+		})
+
+		It("exposes the last counts when counting fails", func() {
+			collector := metrics.NewOperationsCollector(context.Background(), counter, 0)
+			Expect(testutil.CollectAndCount(collector)).To(Equal(1))
+
+			counter.err = errors.New("expected")
+			Expect(testutil.CollectAndCount(collector)).To(Equal(1))
+			Expect(counter.calls).To(Equal(2))
+		})
+	})
+
+	Describe("worker pools collector", func() {
+		It("exposes the size and the busy workers of each pool", func() {
+			settings := operations.DefaultSettings()
+			scheduler := operations.NewScheduler(context.Background(), nil, settings, nil, 5, nil)
+			collector := metrics.NewWorkerPoolsCollector(map[string]*operations.Scheduler{"pool": scheduler})
+			expected := `
+# HELP sm_scheduler_busy_workers Number of workers in the worker pool which are executing operations.
+# TYPE sm_scheduler_busy_workers gauge
+sm_scheduler_busy_workers{pool="pool"} 0
+# HELP sm_scheduler_workers Number of workers in the worker pool.
+# TYPE sm_scheduler_workers gauge
+sm_scheduler_workers{pool="pool"} 5
+`
+			Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected))).To(Succeed())
+		})
+	})
+
+	Describe("notificator collector", func() {
+		It("exposes the connected platforms and the queued notifications", func() {
+			notificator := &storagefakes.FakeNotificator{}
+			notificator.StatsReturns(storage.NotificatorStats{ConnectedPlatforms: 2, QueuedNotifications: 7})
+			collector := metrics.NewNotificatorCollector(notificator)
+			expected := `
+# HELP sm_notifications_connected_platforms Number of platforms connected for receiving notifications.
+# TYPE sm_notifications_connected_platforms gauge
+sm_notifications_connected_platforms 2
+# HELP sm_notifications_queued Number of notifications waiting to be sent to the connected platforms.
+# TYPE sm_notifications_queued gauge
+sm_notifications_queued 7
+`
+			Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected))).To(Succeed())
+		})
+	})
+
+	Describe("db stats collector", func() {
+		It("exposes the connection pool statistics", func() {
+			collector := metrics.NewDBStatsCollector(func() sql.DBStats {
+				return sql.DBStats{MaxOpenConnections: 10, OpenConnections: 4, InUse: 3, Idle: 1, WaitCount: 2, WaitDuration: time.Second}
+			})
+			expected := `
+# HELP sm_storage_in_use_connections Number of connections to the database which are currently in use.
+# TYPE sm_storage_in_use_connections gauge
+sm_storage_in_use_connections 3
+# HELP sm_storage_wait_duration_seconds_total Total time blocked waiting for a new connection.
+# TYPE sm_storage_wait_duration_seconds_total counter
+sm_storage_wait_duration_seconds_total 1
+`
+			Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected),
+				"sm_storage_in_use_connections", "sm_storage_wait_duration_seconds_total")).To(Succeed())
+			Expect(testutil.CollectAndCount(collector)).To(Equal(6))
+		})
+	})
+
+	Describe("controller", func() {
+		It("exposes the gathered metrics in the text format", func() {
+			registry := prometheus.NewRegistry()
+			gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "Test gauge."})
+			gauge.Set(42)
+			registry.MustRegister(gauge)
+
+			controller := &metrics.Controller{Gatherer: registry}
+			route := controller.Routes()[0]
+			Expect(route.Endpoint.Path).To(Equal(web.MetricsURL))
+
+			request := httptest.NewRequest(http.MethodGet, web.MetricsURL, nil)
+			resp, err := route.Handler.Handle(&web.Request{Request: request})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(HavePrefix("text/plain"))
+			Expect(string(resp.Body)).To(ContainSubstring("test_gauge 42"))
+		})
+	})
+})
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Controller exposes the gathered metrics in the Prometheus exposition format
+type Controller struct {
+	Gatherer prometheus.Gatherer
+}
+
+var _ web.Controller = &Controller{}
+
+// Routes implements the web.Controller interface and returns the metrics route
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.MetricsURL,
+			},
+			Handler: c.metrics,
+		},
+	}
+}
+
+// metrics handler for GET /metrics
+func (c *Controller) metrics(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	metricFamilies, err := c.Gatherer.Gather()
+	if err != nil {
+		// the metrics which were gathered successfully are still exposed
+		log.C(ctx).WithError(err).Warn("Could not gather all metrics")
+	}
+
+	format := expfmt.Negotiate(r.Header)
+	body := &bytes.Buffer{}
+	encoder := expfmt.NewEncoder(body, format)
+	for _, metricFamily := range metricFamilies {
+		if err := encoder.Encode(metricFamily); err != nil {
+			return nil, err
+		}
+	}
+
+	return &web.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{string(format)}},
+		Body:       body.Bytes(),
+	}, nil
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Enabled                   bool          `mapstructure:"enabled" description:"whether the Prometheus metrics endpoint is exposed (the endpoint is not authenticated)"`
+	OperationsRefreshInterval time.Duration `mapstructure:"operations_refresh_interval" description:"minimum time between two counts of the stored operations"`
+}
+
+// DefaultSettings returns the default values for the metrics
+func DefaultSettings() *Settings {
+	return &Settings{
+		Enabled:                   false,
+		OperationsRefreshInterval: time.Minute,
+	}
+}
+
+// Validate validates the metrics settings
+func (s *Settings) Validate() error {
+	if s.OperationsRefreshInterval < 0 {
+		return fmt.Errorf("validate metrics settings: operations_refresh_interval should be >= 0")
+	}
+	return nil
+}
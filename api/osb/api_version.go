@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// APIVersion2_14 is the oldest OSB API version negotiated with the brokers
+	APIVersion2_14 = "2.14"
+	// APIVersion2_15 introduces the maintenance_info of plans and instances
+	APIVersion2_15 = "2.15"
+	// APIVersion2_16 introduces the metadata of instances and bindings
+	APIVersion2_16 = "2.16"
+	// APIVersion2_17 introduces the binding rotation
+	APIVersion2_17 = "2.17"
+
+	// LatestAPIVersion is the newest OSB API version supported by the Service Manager
+	LatestAPIVersion = APIVersion2_17
+)
+
+// versionedFields are the request fields introduced after the oldest supported OSB API version with the versions
+// introducing them
+var versionedFields = []struct {
+	path    string
+	version string
+}{
+	{path: "maintenance_info", version: APIVersion2_15},
+	{path: "previous_values.maintenance_info", version: APIVersion2_15},
+	{path: "predecessor_binding_id", version: APIVersion2_17},
+}
+
+// SupportedAPIVersions are the OSB API versions supported by the Service Manager ordered from the newest
+var SupportedAPIVersions = []string{APIVersion2_17, APIVersion2_16, APIVersion2_15, APIVersion2_14}
+
+// IsSupportedAPIVersion returns whether the OSB API version is supported by the Service Manager
+func IsSupportedAPIVersion(version string) bool {
+	for _, supported := range SupportedAPIVersions {
+		if supported == version {
+			return true
+		}
+	}
+	return false
+}
+
+// APIVersionAtLeast returns whether the OSB API version is greater than or equal to the minimum version.
+// Versions which cannot be parsed are considered older than any valid version.
+func APIVersionAtLeast(version, minimum string) bool {
+	major, minor, ok := parseAPIVersion(version)
+	if !ok {
+		return false
+	}
+	minMajor, minMinor, ok := parseAPIVersion(minimum)
+	if !ok {
+		return true
+	}
+	return major > minMajor || (major == minMajor && minor >= minMinor)
+}
+
+// BrokerAPIVersion returns the OSB API version negotiated with the broker, or the oldest supported version
+// if the broker has not been negotiated with yet
+func BrokerAPIVersion(broker *types.ServiceBroker) string {
+	if broker.APIVersion == "" {
+		return APIVersion2_14
+	}
+	return broker.APIVersion
+}
+
+// negotiateAPIVersion returns the OSB API version of a request from a platform to the broker,
+// which is the older of the versions requested by the platform and negotiated with the broker
+func negotiateAPIVersion(requested string, broker *types.ServiceBroker) string {
+	if broker.APIVersion == "" || (requested != "" && !APIVersionAtLeast(requested, broker.APIVersion)) {
+		return requested
+	}
+	return broker.APIVersion
+}
+
+// StripUnsupportedFields removes the fields of the request body which are introduced by OSB API versions newer than
+// the version of the request, so that they are not sent to brokers which do not support them
+func StripUnsupportedFields(body []byte, version string) ([]byte, error) {
+	if len(version) == 0 || !gjson.ValidBytes(body) {
+		return body, nil
+	}
+	var err error
+	for _, field := range versionedFields {
+		if APIVersionAtLeast(version, field.version) || !gjson.GetBytes(body, field.path).Exists() {
+			continue
+		}
+		if body, err = sjson.DeleteBytes(body, field.path); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+func parseAPIVersion(version string) (int, int, bool) {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb_test
+
+import (
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OSB API version", func() {
+	DescribeTable("APIVersionAtLeast",
+		func(version, minimum string, expected bool) {
+			Expect(osb.APIVersionAtLeast(version, minimum)).To(Equal(expected))
+		},
+		Entry("same version", "2.16", "2.16", true),
+		Entry("newer minor version", "2.17", "2.15", true),
+		Entry("older minor version", "2.14", "2.15", false),
+		Entry("minor versions are compared as numbers", "2.9", "2.14", false),
+		Entry("newer major version", "3.0", "2.17", true),
+		Entry("invalid version", "latest", "2.14", false),
+	)
+
+	Describe("BrokerAPIVersion", func() {
+		It("returns the oldest supported version for brokers which have not been negotiated with", func() {
+			Expect(osb.BrokerAPIVersion(&types.ServiceBroker{})).To(Equal(osb.APIVersion2_14))
+		})
+
+		It("returns the negotiated version", func() {
+			Expect(osb.BrokerAPIVersion(&types.ServiceBroker{APIVersion: osb.APIVersion2_16})).To(Equal(osb.APIVersion2_16))
+		})
+	})
+
+	Describe("StripUnsupportedFields", func() {
+		body := []byte(`{"service_id":"service-id","maintenance_info":{"version":"1.0.0"},"previous_values":{"plan_id":"plan-id","maintenance_info":{"version":"0.9.0"}},"predecessor_binding_id":"binding-id"}`)
+
+		It("removes the fields introduced by newer versions", func() {
+			stripped, err := osb.StripUnsupportedFields(body, osb.APIVersion2_14)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stripped).To(MatchJSON(`{"service_id":"service-id","previous_values":{"plan_id":"plan-id"}}`))
+
+			stripped, err = osb.StripUnsupportedFields(body, osb.APIVersion2_16)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stripped).To(MatchJSON(`{"service_id":"service-id","maintenance_info":{"version":"1.0.0"},"previous_values":{"plan_id":"plan-id","maintenance_info":{"version":"0.9.0"}}}`))
+		})
+
+		It("keeps the fields supported by the version", func() {
+			stripped, err := osb.StripUnsupportedFields(body, osb.APIVersion2_17)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stripped).To(MatchJSON(body))
+		})
+
+		It("keeps the body if the version is unknown", func() {
+			stripped, err := osb.StripUnsupportedFields(body, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stripped).To(MatchJSON(body))
+		})
+	})
+
+	Describe("IsSupportedAPIVersion", func() {
+		It("supports the versions from 2.14 to 2.17", func() {
+			Expect(osb.IsSupportedAPIVersion("2.13")).To(BeFalse())
+			for _, version := range []string{"2.14", "2.15", "2.16", "2.17"} {
+				Expect(osb.IsSupportedAPIVersion(version)).To(BeTrue())
+			}
+		})
+	})
+})
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// CatalogCache caches the broker catalogs filtered by the visibilities of the platforms.
+//
+// The cached catalogs of a platform are invalidated by the notifications for the platform, so a notification consumer
+// is registered for each platform with cached catalogs. Broker notifications are created only for the platforms which
+// are connected to the Service Manager, so changes of the broker catalogs are detected by the digest of the unfiltered
+// catalog instead.
+type CatalogCache struct {
+	notificator storage.Notificator
+
+	mutex      sync.Mutex
+	platforms  map[string]*platformCatalogs
+	generation int64
+}
+
+type platformCatalogs struct {
+	queue storage.NotificationQueue
+	// generation changes when the catalogs are invalidated, so that catalogs filtered before the invalidation are not
+	// cached after it
+	generation int64
+	catalogs   map[string]*CachedCatalog
+}
+
+// CachedCatalog is a broker catalog filtered for a platform
+type CachedCatalog struct {
+	// Source is the digest of the unfiltered broker catalog
+	Source string
+	// Body is the filtered catalog
+	Body []byte
+	// ETag is the entity tag of the filtered catalog
+	ETag string
+}
+
+// NewCatalogCache returns a CatalogCache invalidated by the notifications of the notificator
+func NewCatalogCache(notificator storage.Notificator) *CatalogCache {
+	return &CatalogCache{
+		notificator: notificator,
+		platforms:   make(map[string]*platformCatalogs),
+	}
+}
+
+// Get returns the cached catalog of the broker for the platform if it is filtered from the catalog with the specified
+// source digest. The returned generation has to be provided when a newly filtered catalog is put in the cache.
+func (c *CatalogCache) Get(ctx context.Context, platform *types.Platform, brokerID, source string) (*CachedCatalog, int64, bool) {
+	c.mutex.Lock()
+	entry, found := c.platforms[platform.ID]
+	c.mutex.Unlock()
+
+	if !found {
+		var err error
+		if entry, err = c.register(platform); err != nil {
+			log.C(ctx).WithError(err).Warnf("Could not register catalog cache for notifications of platform %s. Catalogs will not be cached", platform.ID)
+			return nil, types.InvalidRevision, false
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	catalog, found := entry.catalogs[brokerID]
+	if !found || catalog.Source != source {
+		return nil, entry.generation, false
+	}
+	return catalog, entry.generation, true
+}
+
+// register registers a notification consumer for the platform and adds an empty entry for its catalogs. The consumer
+// is registered without holding the mutex, as the notificator may block, so the entry registered concurrently by
+// another request is returned if there is one.
+func (c *CatalogCache) register(platform *types.Platform) (*platformCatalogs, error) {
+	queue, _, err := c.notificator.RegisterConsumer(platform, types.InvalidRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if entry, found := c.platforms[platform.ID]; found {
+		c.mutex.Unlock()
+		if err := c.notificator.UnregisterConsumer(queue); err != nil {
+			log.D().WithError(err).Warnf("Could not unregister catalog cache from notifications of platform %s", platform.ID)
+		}
+		return entry, nil
+	}
+	c.generation++
+	entry := &platformCatalogs{
+		queue:      queue,
+		generation: c.generation,
+		catalogs:   make(map[string]*CachedCatalog),
+	}
+	c.platforms[platform.ID] = entry
+	c.mutex.Unlock()
+
+	go c.invalidateOnNotifications(platform.ID, entry)
+	return entry, nil
+}
+
+// Put caches the filtered catalog of the broker for the platform unless the catalogs of the platform were invalidated
+// after the provided generation was returned by Get
+func (c *CatalogCache) Put(platformID, brokerID string, generation int64, catalog *CachedCatalog) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, found := c.platforms[platformID]
+	if !found || entry.generation != generation {
+		return
+	}
+	entry.catalogs[brokerID] = catalog
+}
+
+func (c *CatalogCache) invalidateOnNotifications(platformID string, entry *platformCatalogs) {
+	logger := log.D()
+	for notification := range entry.queue.Channel() {
+		logger.Debugf("Invalidating cached catalogs of platform %s on %s notification for %s", platformID, notification.Type, notification.Resource)
+		c.mutex.Lock()
+		c.generation++
+		entry.generation = c.generation
+		entry.catalogs = make(map[string]*CachedCatalog)
+		c.mutex.Unlock()
+	}
+
+	// the queue is closed if it overflows or the notificator is stopped
+	logger.Debugf("Notifications of platform %s stopped. Removing its cached catalogs", platformID)
+	c.mutex.Lock()
+	if c.platforms[platformID] == entry {
+		delete(c.platforms, platformID)
+	}
+	c.mutex.Unlock()
+	if err := c.notificator.UnregisterConsumer(entry.queue); err != nil {
+		logger.WithError(err).Warnf("Could not unregister catalog cache from notifications of platform %s", platformID)
+	}
+}
+
+// digest returns the hex encoded SHA-256 digest of the catalog
+func digest(catalog []byte) string {
+	sum := sha256.Sum256(catalog)
+	return hex.EncodeToString(sum[:])
+}
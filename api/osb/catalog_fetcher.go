@@ -19,6 +19,7 @@ package osb
 import (
 	"context"
 	"fmt"
+	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 )
@@ -26,9 +27,39 @@ import (
 const brokerCatalogURL = "%s/v2/catalog"
 const brokerAPIVersionHeader = "X-Broker-API-Version"
 
-// CatalogFetcher creates a broker catalog fetcher that uses the provided request function to call the specified broker's catalog endpoint
+// CatalogFetcher creates a broker catalog fetcher that uses the provided request function to call the specified broker's catalog endpoint.
+// The fetcher negotiates the OSB API version with the broker starting from brokerAPIVersion: if the broker responds with
+// 412 Precondition Failed, the catalog is requested again with the next older supported version. The negotiated version
+// is set to the broker and is used in the subsequent requests to it.
 func CatalogFetcher(doRequestWithClient util.DoRequestWithClientFunc, brokerAPIVersion string) func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
 	return func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
-		return Get(doRequestWithClient, brokerAPIVersion, ctx, broker, fmt.Sprintf(brokerCatalogURL, broker.BrokerURL), "catalog")
+		var err error
+		for _, version := range negotiableAPIVersions(brokerAPIVersion) {
+			var catalog []byte
+			catalog, err = Get(doRequestWithClient, version, ctx, broker, fmt.Sprintf(brokerCatalogURL, broker.BrokerURL), "catalog")
+			if err == nil {
+				broker.APIVersion = version
+				return catalog, nil
+			}
+			if httpErr, ok := err.(*util.HTTPError); !ok || httpErr.ErrorType != unsupportedAPIVersionErrorType {
+				return nil, err
+			}
+			log.C(ctx).Infof("broker with name %s does not support OSB API version %s", broker.Name, version)
+		}
+		return nil, err
 	}
 }
+
+// negotiableAPIVersions returns the supported OSB API versions which are not newer than the latest version ordered from the newest
+func negotiableAPIVersions(latest string) []string {
+	if !IsSupportedAPIVersion(latest) {
+		return []string{latest}
+	}
+	var versions []string
+	for _, version := range SupportedAPIVersions {
+		if APIVersionAtLeast(latest, version) {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
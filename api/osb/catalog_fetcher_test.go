@@ -241,4 +241,62 @@ var _ = Describe("Catalog CatalogFetcher", func() {
 			Expect(rawCatalog).To(Equal(t.expectedResponse))
 		}
 	}, entries...)
+
+	Describe("OSB API version negotiation", func() {
+		var requestedVersions []string
+		var supportedVersions map[string]bool
+		var broker types.ServiceBroker
+
+		fetch := func() ([]byte, error) {
+			fetcher := osb.CatalogFetcher(func(request *http.Request, client *http.Client) (*http.Response, error) {
+				version := request.Header.Get("X-Broker-API-Version")
+				requestedVersions = append(requestedVersions, version)
+				if !supportedVersions[version] {
+					return common.DoHTTP(&common.HTTPReaction{Status: http.StatusPreconditionFailed, Body: `{"description":"unsupported version"}`}, nil)(request)
+				}
+				return common.DoHTTP(&common.HTTPReaction{Status: http.StatusOK, Body: simpleCatalog}, nil)(request)
+			}, osb.LatestAPIVersion)
+			return fetcher(context.TODO(), &broker)
+		}
+
+		BeforeEach(func() {
+			requestedVersions = nil
+			broker = testBroker
+		})
+
+		When("the broker supports the latest version", func() {
+			It("fetches the catalog with the latest version", func() {
+				supportedVersions = map[string]bool{osb.APIVersion2_17: true, osb.APIVersion2_14: true}
+
+				catalog, err := fetch()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(catalog).To(Equal([]byte(simpleCatalog)))
+				Expect(requestedVersions).To(Equal([]string{osb.APIVersion2_17}))
+				Expect(broker.APIVersion).To(Equal(osb.APIVersion2_17))
+			})
+		})
+
+		When("the broker supports only older versions", func() {
+			It("fetches the catalog with the newest version supported by the broker", func() {
+				supportedVersions = map[string]bool{osb.APIVersion2_15: true, osb.APIVersion2_14: true}
+
+				_, err := fetch()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(requestedVersions).To(Equal([]string{osb.APIVersion2_17, osb.APIVersion2_16, osb.APIVersion2_15}))
+				Expect(broker.APIVersion).To(Equal(osb.APIVersion2_15))
+			})
+		})
+
+		When("the broker supports none of the versions", func() {
+			It("returns error", func() {
+				supportedVersions = map[string]bool{}
+
+				_, err := fetch()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("OSB API version 2.14 is not supported"))
+				Expect(requestedVersions).To(HaveLen(len(osb.SupportedAPIVersions)))
+				Expect(broker.APIVersion).To(BeEmpty())
+			})
+		})
+	})
 })
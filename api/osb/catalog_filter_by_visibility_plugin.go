@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/security"
@@ -18,9 +19,12 @@ import (
 
 const CatalogFilterByVisibilityPluginName = "CatalogFilterByVisibilityPlugin"
 
-func NewCatalogFilterByVisibilityPlugin(repository storage.Repository) *CatalogFilterByVisibilityPlugin {
+// NewCatalogFilterByVisibilityPlugin returns a plugin which filters the catalogs fetched by platforms by the visibilities
+// of the platforms. The filtered catalogs are cached if a cache is provided.
+func NewCatalogFilterByVisibilityPlugin(repository storage.Repository, cache *CatalogCache) *CatalogFilterByVisibilityPlugin {
 	return &CatalogFilterByVisibilityPlugin{
 		repository: repository,
+		cache:      cache,
 	}
 }
 
@@ -30,6 +34,7 @@ func (c *CatalogFilterByVisibilityPlugin) Name() string {
 
 type CatalogFilterByVisibilityPlugin struct {
 	repository storage.Repository
+	cache      *CatalogCache
 }
 
 func (c *CatalogFilterByVisibilityPlugin) FetchCatalog(req *web.Request, next web.Handler) (*web.Response, error) {
@@ -47,7 +52,7 @@ func (c *CatalogFilterByVisibilityPlugin) FetchCatalog(req *web.Request, next we
 
 	if userCtx.AuthenticationType != web.Basic {
 		log.C(ctx).Debugf("Authentication is %s, not basic. Skip filtering on visibilities", userCtx.AuthenticationType)
-		return res, nil
+		return conditionalCatalogResponse(req, res, etag(res.Body)), nil
 	}
 	platform := &types.Platform{}
 	if err := userCtx.Data(platform); err != nil {
@@ -55,12 +60,71 @@ func (c *CatalogFilterByVisibilityPlugin) FetchCatalog(req *web.Request, next we
 	}
 
 	brokerID := req.PathParams[BrokerIDPathParam]
+	catalog, err := c.filteredCatalog(ctx, platform, brokerID, res.Body)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = catalog.Body
+	return conditionalCatalogResponse(req, res, catalog.ETag), nil
+}
+
+func (c *CatalogFilterByVisibilityPlugin) filteredCatalog(ctx context.Context, platform *types.Platform, brokerID string, brokerCatalog []byte) (*CachedCatalog, error) {
+	var source string
+	var generation int64
+	if c.cache != nil {
+		var cached *CachedCatalog
+		var found bool
+		source = digest(brokerCatalog)
+		if cached, generation, found = c.cache.Get(ctx, platform, brokerID, source); found {
+			log.C(ctx).Debugf("Using cached catalog of broker %s for platform %s", brokerID, platform.ID)
+			return cached, nil
+		}
+	}
+
 	visibleCatalogPlans, serviceOfferings, servicePlans, err := getVisiblePlansByBrokerIDAndPlatformID(ctx, c.repository, brokerID, platform)
 	if err != nil {
 		return nil, err
 	}
-	res.Body, err = filterCatalogByVisiblePlans(res.Body, visibleCatalogPlans, serviceOfferings, servicePlans)
-	return res, err
+	body, err := filterCatalogByVisiblePlans(brokerCatalog, visibleCatalogPlans, serviceOfferings, servicePlans)
+	if err != nil {
+		return nil, err
+	}
+	catalog := &CachedCatalog{
+		Source: source,
+		Body:   body,
+		ETag:   etag(body),
+	}
+	if c.cache != nil {
+		c.cache.Put(platform.ID, brokerID, generation, catalog)
+	}
+	return catalog, nil
+}
+
+// etag returns a strong entity tag of the catalog
+func etag(catalog []byte) string {
+	return `"` + digest(catalog) + `"`
+}
+
+// conditionalCatalogResponse sets the entity tag of the catalog response and replaces it with a Not Modified response
+// if the entity tag matches the If-None-Match header of the request
+func conditionalCatalogResponse(req *web.Request, res *web.Response, etag string) *web.Response {
+	if res.Header == nil {
+		res.Header = http.Header{}
+	}
+	res.Header.Set("ETag", etag)
+
+	for _, match := range strings.Split(req.Header.Get("If-None-Match"), ",") {
+		match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+		if match == etag || match == "*" {
+			header := http.Header{}
+			header.Set("ETag", etag)
+			return &web.Response{
+				StatusCode: http.StatusNotModified,
+				Header:     header,
+			}
+		}
+	}
+	return res
 }
 
 func getVisiblePlansByBrokerIDAndPlatformID(ctx context.Context, repository storage.Repository, brokerID string, platform *types.Platform) (map[string]bool, map[string]string, map[string]string, error) {
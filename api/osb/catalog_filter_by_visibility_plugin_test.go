@@ -0,0 +1,220 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	"github.com/tidwall/gjson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog filter by visibility plugin", func() {
+	const brokerCatalog = `{"services":[{"id":"service","name":"service","plans":[{"id":"visible","name":"visible"},{"id":"hidden","name":"hidden"}]}]}`
+
+	var (
+		repository  *storagefakes.FakeStorage
+		notificator *storagefakes.FakeNotificator
+		queue       storage.NotificationQueue
+		next        *webfakes.FakeHandler
+		catalog     string
+		plugin      *CatalogFilterByVisibilityPlugin
+	)
+
+	newRequest := func(authenticationType web.AuthenticationType, headers map[string]string) *web.Request {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/v1/osb/broker-id/v2/catalog", nil)
+		Expect(err).ToNot(HaveOccurred())
+		for header, value := range headers {
+			req.Header.Set(header, value)
+		}
+		platform, err := json.Marshal(&types.Platform{Base: types.Base{ID: "platform-id"}, Type: "kubernetes"})
+		Expect(err).ToNot(HaveOccurred())
+		ctx := web.ContextWithUser(req.Context(), &web.UserContext{
+			Name:               "platform",
+			AuthenticationType: authenticationType,
+			Data: func(data interface{}) error {
+				return json.Unmarshal(platform, data)
+			},
+		})
+		return &web.Request{
+			Request:    req.WithContext(ctx),
+			PathParams: map[string]string{BrokerIDPathParam: "broker-id"},
+		}
+	}
+
+	fetchCatalog := func(headers map[string]string) *web.Response {
+		res, err := plugin.FetchCatalog(newRequest(web.Basic, headers), next)
+		Expect(err).ToNot(HaveOccurred())
+		return res
+	}
+
+	planIDs := func(res *web.Response) []string {
+		var ids []string
+		for _, id := range gjson.GetBytes(res.Body, "services.#.plans.#.id").Array() {
+			for _, planID := range id.Array() {
+				ids = append(ids, planID.String())
+			}
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		catalog = brokerCatalog
+		next = &webfakes.FakeHandler{}
+		next.HandleCalls(func(request *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte(catalog)}, nil
+		})
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.ServiceOfferingType:
+				return &types.ServiceOfferings{ServiceOfferings: []*types.ServiceOffering{
+					{Base: types.Base{ID: "offering-id"}, CatalogID: "service", Name: "service"},
+				}}, nil
+			case types.ServicePlanType:
+				return &types.ServicePlans{ServicePlans: []*types.ServicePlan{
+					{Base: types.Base{ID: "visible-plan-id"}, CatalogID: "visible", Name: "visible"},
+					{Base: types.Base{ID: "hidden-plan-id"}, CatalogID: "hidden", Name: "hidden"},
+				}}, nil
+			}
+			return nil, errors.New("unexpected list")
+		})
+		repository.ListNoLabelsReturns(&types.Visibilities{Visibilities: []*types.Visibility{
+			{ServicePlanID: "visible-plan-id"},
+		}}, nil)
+
+		var err error
+		queue, err = storage.NewNotificationQueue(10)
+		Expect(err).ToNot(HaveOccurred())
+		notificator = &storagefakes.FakeNotificator{}
+		notificator.RegisterConsumerReturns(queue, types.InvalidRevision, nil)
+
+		plugin = NewCatalogFilterByVisibilityPlugin(repository, nil)
+	})
+
+	It("filters the catalog by the visibilities of the platform", func() {
+		res := fetchCatalog(nil)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(planIDs(res)).To(ConsistOf("visible"))
+		Expect(gjson.GetBytes(res.Body, "services.0.metadata.sm_offering_id").String()).To(Equal("offering-id"))
+		Expect(gjson.GetBytes(res.Body, "services.0.plans.0.metadata.sm_plan_id").String()).To(Equal("visible-plan-id"))
+	})
+
+	It("returns the entity tag of the filtered catalog", func() {
+		res := fetchCatalog(nil)
+		Expect(res.Header.Get("ETag")).To(Equal(etag(res.Body)))
+	})
+
+	When("the catalog matches the If-None-Match header", func() {
+		It("returns Not Modified", func() {
+			tag := fetchCatalog(nil).Header.Get("ETag")
+
+			res := fetchCatalog(map[string]string{"If-None-Match": `"other", W/` + tag})
+			Expect(res.StatusCode).To(Equal(http.StatusNotModified))
+			Expect(res.Header.Get("ETag")).To(Equal(tag))
+			Expect(res.Body).To(BeEmpty())
+		})
+	})
+
+	When("the catalog does not match the If-None-Match header", func() {
+		It("returns the catalog", func() {
+			res := fetchCatalog(map[string]string{"If-None-Match": `"other"`})
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(planIDs(res)).To(ConsistOf("visible"))
+		})
+	})
+
+	When("the user is not a platform", func() {
+		It("returns the unfiltered catalog with its entity tag", func() {
+			res, err := plugin.FetchCatalog(newRequest(web.Bearer, nil), next)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(res.Body)).To(Equal(brokerCatalog))
+			Expect(res.Header.Get("ETag")).To(Equal(etag([]byte(brokerCatalog))))
+			Expect(repository.ListCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("with catalog cache", func() {
+		BeforeEach(func() {
+			plugin = NewCatalogFilterByVisibilityPlugin(repository, NewCatalogCache(notificator))
+		})
+
+		It("filters the catalog once", func() {
+			first := fetchCatalog(nil)
+			listCalls := repository.ListCallCount()
+
+			second := fetchCatalog(nil)
+			Expect(second.Body).To(Equal(first.Body))
+			Expect(second.Header.Get("ETag")).To(Equal(first.Header.Get("ETag")))
+			Expect(repository.ListCallCount()).To(Equal(listCalls))
+			Expect(notificator.RegisterConsumerCallCount()).To(Equal(1))
+		})
+
+		It("filters the catalog again after a notification for the platform", func() {
+			fetchCatalog(nil)
+			listCalls := repository.ListCallCount()
+
+			Expect(queue.Enqueue(&types.Notification{Resource: types.VisibilityType, Type: types.CREATED})).To(Succeed())
+			Eventually(func() int {
+				fetchCatalog(nil)
+				return repository.ListCallCount()
+			}).Should(BeNumerically(">", listCalls))
+		})
+
+		It("filters the catalog again after the broker catalog changes", func() {
+			fetchCatalog(nil)
+			listCalls := repository.ListCallCount()
+
+			catalog = `{"services":[{"id":"service","name":"service","plans":[{"id":"visible","name":"visible"}]}]}`
+			fetchCatalog(nil)
+			Expect(repository.ListCallCount()).To(BeNumerically(">", listCalls))
+		})
+
+		It("stops caching the catalogs of the platform when its notifications stop", func() {
+			fetchCatalog(nil)
+			queue.Close()
+
+			Eventually(notificator.UnregisterConsumerCallCount).Should(Equal(1))
+			fetchCatalog(nil)
+			Expect(notificator.RegisterConsumerCallCount()).To(Equal(2))
+		})
+
+		When("the notifications of the platform cannot be consumed", func() {
+			It("does not cache the catalog", func() {
+				notificator.RegisterConsumerReturns(nil, types.InvalidRevision, errors.New("notificator is not running"))
+				fetchCatalog(nil)
+				listCalls := repository.ListCallCount()
+
+				res := fetchCatalog(nil)
+				Expect(planIDs(res)).To(ConsistOf("visible"))
+				Expect(repository.ListCallCount()).To(BeNumerically(">", listCalls))
+			})
+		})
+	})
+})
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/client"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/quota"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+)
+
+const CheckTenantQuotaPluginName = "CheckTenantQuotaPlugin"
+
+type checkTenantQuotaPlugin struct {
+	repository       storage.Repository
+	tenantIdentifier string
+	checker          *quota.Checker
+}
+
+// NewCheckTenantQuotaPlugin creates new plugin that rejects provision and bind requests exceeding the quota of the tenant
+// before they reach the broker. The quota is enforced once more when the resources are stored, and resources rejected
+// at that point are deleted at the broker so that they are not left orphaned.
+func NewCheckTenantQuotaPlugin(repository storage.Repository, tenantIdentifier string) *checkTenantQuotaPlugin {
+	return &checkTenantQuotaPlugin{
+		repository:       repository,
+		tenantIdentifier: tenantIdentifier,
+		checker:          quota.NewChecker(tenantIdentifier),
+	}
+}
+
+// Name returns the name of the plugin
+func (p *checkTenantQuotaPlugin) Name() string {
+	return CheckTenantQuotaPluginName
+}
+
+// Provision intercepts provision requests and checks if the tenant in the OSB context can create one more instance
+func (p *checkTenantQuotaPlugin) Provision(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	requestPayload := &provisionRequest{}
+	if err := decodeRequestBody(req, requestPayload); err != nil {
+		return nil, err
+	}
+	tenant := gjson.GetBytes(requestPayload.RawContext, p.tenantIdentifier).String()
+	if len(tenant) == 0 {
+		return next.Handle(req)
+	}
+
+	// provision requests of existing instances do not create new ones
+	_, err := p.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", requestPayload.InstanceID))
+	if err == nil {
+		return next.Handle(req)
+	}
+	if err != util.ErrNotFoundInStorage {
+		return nil, util.HandleStorageError(err, string(types.ServiceInstanceType))
+	}
+
+	plan, err := findServicePlanByCatalogIDs(ctx, p.repository, requestPayload.BrokerID, requestPayload.ServiceID, requestPayload.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checker.CheckInstance(ctx, p.repository, tenant, plan.GetID(), false); err != nil {
+		return nil, err
+	}
+
+	response, err := next.Handle(req)
+	if quotaExceeded(err) {
+		// a concurrent request of the tenant consumed the quota after the check above and the instance provisioned
+		// by the broker could not be stored
+		path := fmt.Sprintf("/v2/service_instances/%s", requestPayload.InstanceID)
+		p.mitigateOrphan(ctx, req, requestPayload.BrokerID, path, requestPayload.ServiceID, requestPayload.PlanID)
+	}
+	return response, err
+}
+
+// Bind intercepts bind requests and checks if the tenant owning the instance can create one more binding for it
+func (p *checkTenantQuotaPlugin) Bind(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	bindingID := req.PathParams[BindingIDPathParam]
+	// bind requests of existing bindings do not create new ones
+	_, err := p.repository.Get(ctx, types.ServiceBindingType, query.ByField(query.EqualsOperator, "id", bindingID))
+	if err == nil {
+		return next.Handle(req)
+	}
+	if err != util.ErrNotFoundInStorage {
+		return nil, util.HandleStorageError(err, string(types.ServiceBindingType))
+	}
+
+	if err := p.checker.CheckBinding(ctx, p.repository, req.PathParams[InstanceIDPathParam], false); err != nil {
+		return nil, err
+	}
+
+	response, err := next.Handle(req)
+	if quotaExceeded(err) {
+		requestPayload := &bindRequest{}
+		if decodeErr := decodeRequestBody(req, requestPayload); decodeErr != nil {
+			log.C(ctx).WithError(decodeErr).Errorf("Could not unbind service binding %s", bindingID)
+			return response, err
+		}
+		path := fmt.Sprintf("/v2/service_instances/%s/service_bindings/%s", req.PathParams[InstanceIDPathParam], bindingID)
+		p.mitigateOrphan(ctx, req, requestPayload.BrokerID, path, requestPayload.ServiceID, requestPayload.PlanID)
+	}
+	return response, err
+}
+
+func quotaExceeded(err error) bool {
+	httpErr, ok := err.(*util.HTTPError)
+	return ok && httpErr.ErrorType == quota.ExceededErrorType
+}
+
+// mitigateOrphan deletes the resource at the path of the broker which the broker created although it exceeds the quota
+func (p *checkTenantQuotaPlugin) mitigateOrphan(ctx context.Context, req *web.Request, brokerID, path, serviceID, planID string) {
+	logger := log.C(ctx)
+	logger.Warnf("Tenant quota exceeded after %s was created at broker with id %s. Deleting it...", path, brokerID)
+
+	obj, err := p.repository.Get(ctx, types.ServiceBrokerType, query.ByField(query.EqualsOperator, "id", brokerID))
+	if err != nil {
+		logger.WithError(err).Errorf("Could not get broker with id %s to delete %s", brokerID, path)
+		return
+	}
+	broker := obj.(*types.ServiceBroker)
+
+	brokerClient, err := client.NewBrokerClient(broker, util.ClientRequest, ctx)
+	if err != nil {
+		logger.WithError(err).Errorf("Could not delete %s at broker with id %s", path, brokerID)
+		return
+	}
+	params := map[string]string{
+		"service_id":         serviceID,
+		"plan_id":            planID,
+		"accepts_incomplete": "true",
+	}
+	headers := map[string]string{
+		brokerAPIVersionHeader: negotiateAPIVersion(req.Header.Get(brokerAPIVersionHeader), broker),
+	}
+	response, err := brokerClient.SendRequest(ctx, http.MethodDelete, broker.BrokerURL+path, params, nil, headers)
+	if err != nil {
+		logger.WithError(err).Errorf("Could not delete %s at broker with id %s", path, brokerID)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusAccepted && response.StatusCode != http.StatusGone {
+		logger.Errorf("Deleting %s at broker with id %s failed with status code %d", path, brokerID, response.StatusCode)
+	}
+}
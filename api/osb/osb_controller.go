@@ -34,7 +34,9 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/Peripli/service-manager/operations"
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/tracing"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
@@ -48,6 +50,8 @@ type BrokerFetcherFunc func(ctx context.Context, brokerID string) (*types.Servic
 // Controller implements api.Controller by providing OSB API logic
 type Controller struct {
 	BrokerFetcher BrokerFetcherFunc
+	// BrokerCircuits fails fast the requests to brokers which keep failing, nil disables it
+	BrokerCircuits *operations.BrokerCircuits
 }
 
 var _ web.Controller = &Controller{}
@@ -83,6 +87,9 @@ func (c *Controller) handler(request *web.Request, f func(r *web.Request, logger
 
 	response, err := f(request, logger, broker)
 	if err != nil {
+		if httpErr, ok := err.(*util.HTTPError); ok {
+			return nil, httpErr
+		}
 		logger.WithError(err).Errorf("error proxying call to service broker with id %s", brokerID)
 		return nil, &util.HTTPError{
 			ErrorType:   "ServiceBrokerErr",
@@ -122,6 +129,15 @@ func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.S
 		modifiedRequest.Header.Set("X-Correlation-ID", correlationID)
 	}
 
+	version := negotiateAPIVersion(modifiedRequest.Header.Get(brokerAPIVersionHeader), broker)
+	if version != "" {
+		modifiedRequest.Header.Set(brokerAPIVersionHeader, version)
+	}
+	body, err := StripUnsupportedFields(r.Body, version)
+	if err != nil {
+		return nil, fmt.Errorf("could not remove the fields not supported by OSB API version %s: %s", version, err)
+	}
+
 	referencedInstance := getReferencedInstance(ctx)
 	if referencedInstance != nil {
 		modifiedRequest.URL.Path = getPathForReferencedInstance(referencedInstance, osbPath[1])
@@ -129,8 +145,8 @@ func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.S
 		modifiedRequest.URL.Path = osbPath[1]
 	}
 
-	modifiedRequest.Body = ioutil.NopCloser(bytes.NewReader(r.Body))
-	modifiedRequest.ContentLength = int64(len(r.Body))
+	modifiedRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+	modifiedRequest.ContentLength = int64(len(body))
 
 	// This is needed because the request is shallow copy of the request to the Service Manager
 	// This sets the host header to point to the service broker that the request will be proxied to
@@ -142,12 +158,39 @@ func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.S
 		return nil, fmt.Errorf("unable to build proxy for service broker %s", broker.Name)
 	}
 
+	if c.BrokerCircuits != nil {
+		if err := c.BrokerCircuits.Allow(broker); err != nil {
+			logger.Warnf("Failing fast the request to service broker %s as its circuit is open", broker.Name)
+			return nil, err
+		}
+	}
+
 	recorder := httptest.NewRecorder()
 
 	proxy.ServeHTTP(recorder, modifiedRequest)
+	c.recordBrokerResult(ctx, broker, recorder)
 	return validateBrokerResponse(recorder, broker)
 }
 
+// recordBrokerResult records the result of the request to the broker in the circuit of the broker
+func (c *Controller) recordBrokerResult(ctx context.Context, broker *types.ServiceBroker, recorder *httptest.ResponseRecorder) {
+	if c.BrokerCircuits == nil {
+		return
+	}
+	switch {
+	case ctx.Err() != nil:
+		c.BrokerCircuits.RecordAbort(broker)
+	case operations.IsBrokerUnavailable(recorder.Code):
+		failure := gjson.GetBytes(recorder.Body.Bytes(), "description").String()
+		if failure == "" {
+			failure = fmt.Sprintf("service broker %s responded with status %d", broker.Name, recorder.Code)
+		}
+		c.BrokerCircuits.RecordFailure(broker, failure)
+	default:
+		c.BrokerCircuits.RecordSuccess(broker)
+	}
+}
+
 func getReferencedInstance(ctx context.Context) *types.ServiceInstance {
 	instanceFromContext, _ := types.SharedInstanceFromContext(ctx)
 	return instanceFromContext
@@ -215,6 +258,7 @@ func buildProxy(targetBrokerURL *url.URL, logger *logrus.Entry, broker *types.Se
 		logger.Infof("configuring broker tls for %s", broker.Name)
 		proxy.Transport = client.GetTransportWithTLS(tlsConfig, logger)
 	}
+	proxy.Transport = tracing.NewTransport(proxy.Transport)
 
 	proxy.ModifyResponse = func(response *http.Response) error {
 		logger.Infof("Service broker %s replied with status %d", broker.Name, response.StatusCode)
@@ -91,6 +91,8 @@ type provisionResponse struct {
 	Description    string `json:"description"`
 	DashboardURL   string `json:"dashboard_url"`
 	InstanceUsable bool   `json:"instance_usable"`
+
+	Metadata json.RawMessage `json:"metadata"`
 }
 
 func (b *provisionResponse) GetError() string {
@@ -123,12 +125,13 @@ func (lb *lastBindOperationResponse) GetDescription() string {
 type bindRequest struct {
 	commonRequestDetails
 
-	ServiceID    string                 `json:"service_id"`
-	PlanID       string                 `json:"plan_id"`
-	BindingID    string                 `json:"binding_id"`
-	RawContext   json.RawMessage        `json:"context"`
-	BindResource json.RawMessage        `json:"bind_resource"`
-	Parameters   map[string]interface{} `json:"parameters"`
+	ServiceID            string                 `json:"service_id"`
+	PlanID               string                 `json:"plan_id"`
+	BindingID            string                 `json:"binding_id"`
+	PredecessorBindingID string                 `json:"predecessor_binding_id"`
+	RawContext           json.RawMessage        `json:"context"`
+	BindResource         json.RawMessage        `json:"bind_resource"`
+	Parameters           map[string]interface{} `json:"parameters"`
 }
 
 func (br *bindRequest) Validate() error {
@@ -150,6 +153,7 @@ type bindResponse struct {
 	SyslogDrainUrl  string          `json:"syslog_drain_url"`
 	VolumeMounts    json.RawMessage `json:"volume_mounts"`
 	Endpoints       json.RawMessage `json:"endpoints"`
+	Metadata        json.RawMessage `json:"metadata"`
 }
 
 func (b *bindResponse) GetError() string {
@@ -828,6 +832,7 @@ func (sp *storePlugin) storeInstance(ctx context.Context, storage storage.Reposi
 		DashboardURL:         resp.DashboardURL,
 		MaintenanceInfo:      req.RawMaintenanceInfo,
 		Context:              reqCtx,
+		Metadata:             resp.Metadata,
 		Usable:               true,
 		ReferencedInstanceID: referencedInstanceID,
 	}
@@ -856,16 +861,18 @@ func (sp *storePlugin) storeBinding(ctx context.Context, repository storage.Repo
 			Labels:    make(map[string][]string),
 			Ready:     ready,
 		},
-		Name:              bindingName,
-		ServiceInstanceID: req.InstanceID,
-		SyslogDrainURL:    resp.SyslogDrainUrl,
-		RouteServiceURL:   resp.RouteServiceUrl,
-		VolumeMounts:      resp.VolumeMounts,
-		Endpoints:         resp.Endpoints,
-		Context:           reqCtx,
-		BindResource:      req.BindResource,
-		Parameters:        req.Parameters,
-		Credentials:       nil,
+		Name:                 bindingName,
+		ServiceInstanceID:    req.InstanceID,
+		PredecessorBindingID: req.PredecessorBindingID,
+		SyslogDrainURL:       resp.SyslogDrainUrl,
+		RouteServiceURL:      resp.RouteServiceUrl,
+		VolumeMounts:         resp.VolumeMounts,
+		Endpoints:            resp.Endpoints,
+		Context:              reqCtx,
+		BindResource:         req.BindResource,
+		Parameters:           req.Parameters,
+		Metadata:             resp.Metadata,
+		Credentials:          nil,
 	}
 
 	serviceInstanceObj, err := storage.GetObjectByField(ctx, repository, types.ServiceInstanceType, "id", req.InstanceID)
@@ -924,6 +931,9 @@ func (sp *storePlugin) updateInstance(ctx context.Context, storage storage.Repos
 	if len(req.MaintenanceInfo) != 0 {
 		serviceInstance.MaintenanceInfo = req.MaintenanceInfo
 	}
+	if len(resp.Metadata) != 0 {
+		serviceInstance.Metadata = resp.Metadata
+	}
 	if len(req.RawContext) != 0 {
 		serviceInstance.Context = req.RawContext
 	}
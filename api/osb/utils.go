@@ -11,6 +11,8 @@ import (
 	"net/http"
 )
 
+const unsupportedAPIVersionErrorType = "UnsupportedAPIVersion"
+
 func Get(doRequestWithClient util.DoRequestWithClientFunc, brokerAPIVersion string, ctx context.Context, broker *types.ServiceBroker, url string, resourceType string) ([]byte, error) {
 
 	log.C(ctx).Debugf("attempting to fetch %s from URL %s and broker with name %s", resourceType, url, broker.Name)
@@ -31,6 +33,15 @@ func Get(doRequestWithClient util.DoRequestWithClientFunc, brokerAPIVersion stri
 		}
 	}
 
+	if response.StatusCode == http.StatusPreconditionFailed {
+		log.C(ctx).Errorf("error fetching %s from URL %s and broker with name %s: OSB API version %s is not supported", resourceType, url, broker.Name, brokerAPIVersion)
+		return nil, &util.HTTPError{
+			ErrorType:   unsupportedAPIVersionErrorType,
+			Description: fmt.Sprintf("error fetching %s from URL %s and broker with name %s: OSB API version %s is not supported", resourceType, url, broker.Name, brokerAPIVersion),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
 	if response.StatusCode != http.StatusOK {
 		log.C(ctx).WithError(err).Errorf("error fetching %s from URL %s and broker with name %s: %s", resourceType, url, broker.Name, util.HandleResponseError(response))
 		return nil, &util.HTTPError{
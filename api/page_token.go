@@ -0,0 +1,183 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const pagingSequenceField = "paging_sequence"
+
+// pageToken is the position of the last item of a page in the order in which the items are listed.
+// The next page starts with the items which come after this position, so that the pages stay stable
+// when items are created or deleted between the requests.
+type pageToken struct {
+	// OrderBy is the order in which the items are listed, e.g. name DESC
+	OrderBy []string `json:"order_by"`
+	// Values are the values of the ordered fields of the last item of the page
+	Values []string `json:"values"`
+}
+
+// orderCriteria returns the criteria for the order requested by the orderBy query parameter. The items are
+// additionally ordered by paging sequence, so that items with equal values of the requested fields have a stable order.
+func orderCriteria(r *web.Request) ([]query.Criterion, error) {
+	var criteria []query.Criterion
+	if expression := r.URL.Query().Get(query.OrderBy); expression != "" {
+		var err error
+		if criteria, err = query.OrderResultByExpression(expression); err != nil {
+			return nil, err
+		}
+	}
+	for _, criterion := range criteria {
+		if criterion.RightOp[0] == pagingSequenceField {
+			return criteria, nil
+		}
+	}
+	return append(criteria, query.OrderResultBy(pagingSequenceField, query.AscOrder)), nil
+}
+
+func orderKeys(order []query.Criterion) []string {
+	keys := make([]string, 0, len(order))
+	for _, criterion := range order {
+		keys = append(keys, strings.Join(criterion.RightOp, " "))
+	}
+	return keys
+}
+
+// parsePageToken returns the values of the ordered fields after which the page starts or nil for the first page
+func (c *BaseController) parsePageToken(ctx context.Context, rawToken string, order []query.Criterion) ([]string, error) {
+	if rawToken == "" {
+		return nil, nil
+	}
+	tokenBytes, err := base64.StdEncoding.DecodeString(rawToken)
+	if err != nil {
+		log.C(ctx).Infof("Invalid token provided: %v", err)
+		return nil, errInvalidToken()
+	}
+	if pagingSequence, isLegacy := legacyPageToken(tokenBytes); isLegacy {
+		if len(order) != 1 || order[0].RightOp[0] != pagingSequenceField {
+			log.C(ctx).Infof("Invalid token provided: paging sequence token used for order %v", orderKeys(order))
+			return nil, errInvalidToken()
+		}
+		return []string{pagingSequence}, nil
+	}
+	token := &pageToken{}
+	if err := json.Unmarshal(tokenBytes, token); err != nil {
+		log.C(ctx).Infof("Invalid token provided: %v", err)
+		return nil, errInvalidToken()
+	}
+	if !reflect.DeepEqual(token.OrderBy, orderKeys(order)) || len(token.Values) != len(order) {
+		log.C(ctx).Infof("Invalid token provided: token for order %v used for order %v", token.OrderBy, orderKeys(order))
+		return nil, errInvalidToken()
+	}
+	return token.Values, nil
+}
+
+// legacyPageToken returns the paging sequence of a page token issued before the tokens contained the order, which is
+// still accepted for the default order so that the clients can continue listing the pages
+func legacyPageToken(tokenBytes []byte) (string, bool) {
+	pagingSequence, err := strconv.ParseInt(string(tokenBytes), 10, 64)
+	if err != nil || pagingSequence < 0 {
+		return "", false
+	}
+	return string(tokenBytes), true
+}
+
+func generatePageToken(obj types.Object, order []query.Criterion) (string, error) {
+	values := make([]string, 0, len(order))
+	for _, criterion := range order {
+		value, err := orderValue(obj, criterion.RightOp[0])
+		if err != nil {
+			return "", err
+		}
+		values = append(values, value)
+	}
+	tokenBytes, err := json.Marshal(&pageToken{
+		OrderBy: orderKeys(order),
+		Values:  values,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(tokenBytes), nil
+}
+
+// orderValue returns the value of the field of the object with the provided json name in the format
+// in which it can be compared by the storage
+func orderValue(obj types.Object, field string) (string, error) {
+	if field == pagingSequenceField {
+		return strconv.FormatInt(obj.GetPagingSequence(), 10), nil
+	}
+	value, found := fieldByJSONName(reflect.Indirect(reflect.ValueOf(obj)), field)
+	if !found {
+		return "", &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field for order by: %s", field)}
+	}
+	if timestamp, ok := value.Interface().(time.Time); ok {
+		return timestamp.UTC().Format(time.RFC3339Nano), nil
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	default:
+		return "", &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field for order by: %s", field)}
+	}
+}
+
+func fieldByJSONName(value reflect.Value, name string) (reflect.Value, bool) {
+	if value.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Anonymous {
+			if embedded, found := fieldByJSONName(reflect.Indirect(value.Field(i)), name); found {
+				return embedded, true
+			}
+			continue
+		}
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func errInvalidToken() error {
+	return &util.HTTPError{
+		ErrorType:   "TokenInvalid",
+		Description: "Invalid token provided.",
+		StatusCode:  http.StatusBadRequest,
+	}
+}
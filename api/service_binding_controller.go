@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/operations"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
@@ -35,7 +36,6 @@ const serviceBindingOSBURL = "%s/v2/service_instances/%s/service_bindings/%s"
 // ServiceBindingController implements api.Controller by providing service bindings API logic
 type ServiceBindingController struct {
 	*BaseController
-	osbVersion string
 }
 
 func NewServiceBindingController(ctx context.Context, options *Options) *ServiceBindingController {
@@ -43,7 +43,6 @@ func NewServiceBindingController(ctx context.Context, options *Options) *Service
 		BaseController: NewAsyncController(ctx, options, web.ServiceBindingsURL, types.ServiceBindingType, true, func() types.Object {
 			return &types.ServiceBinding{}
 		}, true),
-		osbVersion: options.APISettings.OSBVersion,
 	}
 }
 
@@ -56,6 +55,13 @@ func (c *ServiceBindingController) Routes() []web.Route {
 			},
 			Handler: c.CreateObject,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s%s", c.resourceBaseURL, web.StatsURL),
+			},
+			Handler: c.GetStats,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -70,6 +76,13 @@ func (c *ServiceBindingController) Routes() []web.Route {
 			},
 			Handler: c.GetOperation,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   fmt.Sprintf("%s/{%s}%s/{%s}", c.resourceBaseURL, web.PathParamResourceID, web.ResourceOperationsURL, web.PathParamID),
+			},
+			Handler: c.CancelOperation,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -98,6 +111,13 @@ func (c *ServiceBindingController) Routes() []web.Route {
 			},
 			Handler: c.PatchObjectNameAndLabels,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}%s", c.resourceBaseURL, web.PathParamResourceID, web.RotateURL),
+			},
+			Handler: c.RotateBinding,
+		},
 	}
 }
 func (c *ServiceBindingController) GetParameters(r *web.Request) (*web.Response, error) {
@@ -148,7 +168,7 @@ func (c *ServiceBindingController) GetParameters(r *web.Request) (*web.Response,
 
 	osbUrl := fmt.Sprintf("%s?service_id=%s&plan_id=%s", fmt.Sprintf(serviceBindingOSBURL, broker.BrokerURL, serviceBinding.ServiceInstanceID, serviceBindingId), service.CatalogID, plan.CatalogID)
 	log.C(ctx).Infof("fetch binding request: %s", osbUrl)
-	serviceBindingBytes, err := osb.Get(util.ClientRequest, c.osbVersion, ctx,
+	serviceBindingBytes, err := osb.Get(util.ClientRequest, osb.BrokerAPIVersion(broker), ctx,
 		broker,
 		osbUrl,
 		types.ServiceBindingType.String())
@@ -193,3 +213,60 @@ func (c *ServiceBindingController) PatchObjectNameAndLabels(r *web.Request) (*we
 
 	return c.PatchObject(r)
 }
+
+// RotateBinding creates a successor of the binding with new credentials. The rotated binding is deleted by the
+// maintainer after the binding rotation grace period.
+func (c *ServiceBindingController) RotateBinding(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	bindingID := r.PathParams[web.PathParamResourceID]
+	log.C(ctx).Debugf("Rotating %s with id %s", c.objectType, bindingID)
+
+	criteria := append(query.CriteriaForContext(ctx), query.ByField(query.EqualsOperator, "id", bindingID))
+	bindingObject, err := c.repository.Get(ctx, types.ServiceBindingType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.ServiceBindingType.String())
+	}
+	binding := bindingObject.(*types.ServiceBinding)
+
+	instanceObject, err := c.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", binding.ServiceInstanceID))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.ServiceInstanceType.String())
+	}
+	instance := instanceObject.(*types.ServiceInstance)
+	if instance.PlatformID != types.SMPlatform {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: "only bindings of service instances provisioned through the Service Manager can be rotated",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	instanceID := instance.ID
+	if len(instance.ReferencedInstanceID) > 0 {
+		instanceID = instance.ReferencedInstanceID
+	}
+	_, plan, err := storage.GetServiceOfferingAndPlanByServiceInstanceId(c.repository, ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.BindingRotatable == nil || !*plan.BindingRotatable {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("bindings of plan %s are not rotatable", plan.Name),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	successor := &types.ServiceBinding{
+		Base: types.Base{
+			Labels: binding.Labels,
+		},
+		Name:                 binding.Name,
+		ServiceInstanceID:    binding.ServiceInstanceID,
+		BindResource:         binding.BindResource,
+		Parameters:           binding.Parameters,
+		PredecessorBindingID: binding.ID,
+	}
+
+	return c.createObject(r, successor, operations.BindingRotationDescription)
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/catalog"
+	"github.com/Peripli/service-manager/storage/interceptors"
+	"github.com/tidwall/sjson"
+)
+
+// ServiceBrokerController implements api.Controller by providing service brokers API logic
+type ServiceBrokerController struct {
+	*BaseController
+	catalogPreviewer *interceptors.BrokerCatalogPreviewer
+	brokerCircuits   *operations.BrokerCircuits
+}
+
+// NewServiceBrokerController returns a controller for the service brokers API
+func NewServiceBrokerController(ctx context.Context, options *Options) *ServiceBrokerController {
+	return &ServiceBrokerController{
+		BaseController: NewAsyncController(ctx, options, web.ServiceBrokersURL, types.ServiceBrokerType, false, func() types.Object {
+			return &types.ServiceBroker{}
+		}, false),
+		catalogPreviewer: &interceptors.BrokerCatalogPreviewer{
+			CatalogFetcher: osb.CatalogFetcher(util.ClientRequest, options.APISettings.OSBVersion),
+			CatalogLoader:  catalog.Load,
+		},
+		brokerCircuits: options.BrokerCircuits,
+	}
+}
+
+func (c *ServiceBrokerController) Routes() []web.Route {
+	routes := c.BaseController.Routes()
+	for i := range routes {
+		if routes[i].Endpoint.Method == http.MethodGet && routes[i].Endpoint.Path == fmt.Sprintf("%s/{%s}", c.resourceBaseURL, web.PathParamResourceID) {
+			routes[i].Handler = c.GetServiceBroker
+		}
+	}
+	return append(routes, web.Route{
+		Endpoint: web.Endpoint{
+			Method: http.MethodPost,
+			Path:   fmt.Sprintf("%s/{%s}%s", c.resourceBaseURL, web.PathParamResourceID, web.BrokerCatalogPreviewURL),
+		},
+		Handler: c.PreviewCatalog,
+	})
+}
+
+// GetServiceBroker returns the service broker with its health as observed by the requests sent to it
+func (c *ServiceBrokerController) GetServiceBroker(r *web.Request) (*web.Response, error) {
+	response, err := c.GetSingleObject(r)
+	if err != nil || c.brokerCircuits == nil || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+
+	brokerHealth := c.brokerCircuits.Health(r.PathParams[web.PathParamResourceID])
+	if response.Body, err = sjson.SetBytes(response.Body, "health", brokerHealth); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// PreviewCatalog fetches the catalog of the broker and returns the changes which an update of the broker would apply
+// to the offerings, plans and visibilities in the Service Manager. Nothing is persisted.
+func (c *ServiceBrokerController) PreviewCatalog(r *web.Request) (*web.Response, error) {
+	brokerID := r.PathParams[web.PathParamResourceID]
+	ctx := r.Context()
+	log.C(ctx).Debugf("previewing catalog changes of %s with id %s", c.objectType, brokerID)
+
+	byID := query.ByField(query.EqualsOperator, "id", brokerID)
+	criteria := query.CriteriaForContext(ctx)
+	brokerObject, err := c.repository.Get(ctx, types.ServiceBrokerType, append(criteria, byID)...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, c.objectType.String())
+	}
+
+	diff, err := c.catalogPreviewer.Preview(ctx, c.repository, brokerObject.(*types.ServiceBroker), tenantCriteria(ctx, c.tenantLabelKey)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return util.NewJSONResponse(http.StatusOK, diff)
+}
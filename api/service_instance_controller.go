@@ -34,7 +34,6 @@ const serviceInstanceOSBURL string = "%s/v2/service_instances/%s"
 // ServiceInstanceController implements api.Controller by providing service Instances API logic
 type ServiceInstanceController struct {
 	*BaseController
-	osbVersion string
 }
 
 func NewServiceInstanceController(ctx context.Context, options *Options) *ServiceInstanceController {
@@ -43,7 +42,6 @@ func NewServiceInstanceController(ctx context.Context, options *Options) *Servic
 		BaseController: NewAsyncController(ctx, options, web.ServiceInstancesURL, types.ServiceInstanceType, true, func() types.Object {
 			return &types.ServiceInstance{}
 		}, true),
-		osbVersion: options.APISettings.OSBVersion,
 	}
 }
 
@@ -56,6 +54,13 @@ func (c *ServiceInstanceController) Routes() []web.Route {
 			},
 			Handler: c.CreateObject,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s%s", c.resourceBaseURL, web.StatsURL),
+			},
+			Handler: c.GetStats,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -79,6 +84,13 @@ func (c *ServiceInstanceController) Routes() []web.Route {
 			},
 			Handler: c.GetOperation,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   fmt.Sprintf("%s/{%s}%s/{%s}", c.resourceBaseURL, web.PathParamResourceID, web.ResourceOperationsURL, web.PathParamID),
+			},
+			Handler: c.CancelOperation,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -137,7 +149,7 @@ func (c *ServiceInstanceController) GetParameters(r *web.Request) (*web.Response
 
 	osbUrl := fmt.Sprintf("%s?service_id=%s&plan_id=%s", fmt.Sprintf(serviceInstanceOSBURL, broker.BrokerURL, serviceInstanceId), service.CatalogID, plan.CatalogID)
 	log.C(ctx).Infof("fetch instance request: %s", osbUrl)
-	serviceInstanceBytes, err := osb.Get(util.ClientRequest, c.osbVersion, ctx,
+	serviceInstanceBytes, err := osb.Get(util.ClientRequest, osb.BrokerAPIVersion(broker), ctx,
 		broker,
 		osbUrl,
 		types.ServiceInstanceType.String())
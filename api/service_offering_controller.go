@@ -39,6 +39,13 @@ func NewServiceOfferingController(ctx context.Context, options *Options) *Servic
 }
 func (c *ServiceOfferingController) Routes() []web.Route {
 	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s%s", web.ServiceOfferingsURL, web.StatsURL),
+			},
+			Handler: c.GetStats,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
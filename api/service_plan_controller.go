@@ -40,6 +40,13 @@ func NewServicePlanController(ctx context.Context, options *Options) *ServicePla
 
 func (c *ServicePlanController) Routes() []web.Route {
 	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s%s", web.ServicePlansURL, web.StatsURL),
+			},
+			Handler: c.GetStats,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// statsPage is the DTO for the counts of the resources grouped by the requested fields and label keys
+type statsPage struct {
+	ItemsCount int                 `json:"num_items"`
+	Items      []*types.GroupCount `json:"items"`
+}
+
+// GetStats handles the counting of the resources matching the fieldQuery and labelQuery grouped by the fields
+// provided in the groupBy query parameter and by the label keys provided in the groupByLabel query parameter
+func (c *BaseController) GetStats(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	fields := splitQueryParam(r.URL.Query().Get(web.QueryParamGroupBy))
+	labelKeys := splitQueryParam(r.URL.Query().Get(web.QueryParamGroupByLabel))
+	if err := c.validateGroupByFields(fields); err != nil {
+		return nil, err
+	}
+	if err := validateUnique(append(append([]string{}, fields...), labelKeys...)); err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Debugf("Counting %s grouped by fields %v and label keys %v", c.objectType, fields, labelKeys)
+	groups, err := c.repository.CountGroups(ctx, c.objectType, fields, labelKeys, query.CriteriaForContext(ctx)...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, c.objectType.String())
+	}
+
+	return util.NewJSONResponse(http.StatusOK, &statsPage{
+		ItemsCount: len(groups),
+		Items:      groups,
+	})
+}
+
+// validateGroupByFields checks that the resources are grouped only by fields holding single values which are returned by the API
+func (c *BaseController) validateGroupByFields(fields []string) error {
+	object := reflect.Indirect(reflect.ValueOf(c.objectBlueprint()))
+	for _, field := range fields {
+		value, found := fieldByJSONName(object, field)
+		if !found {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field for group by: %s", field)}
+		}
+		if value.Type() == reflect.TypeOf(time.Time{}) {
+			continue
+		}
+		switch value.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+		default:
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field for group by: %s", field)}
+		}
+	}
+	return nil
+}
+
+func validateUnique(values []string) error {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if seen[value] {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("duplicate group by value: %s", value)}
+		}
+		seen[value] = true
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/quota"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// TenantQuotaController manages the quotas of the tenants and reports their usage. Tenant users can only get the
+// quota of their own tenant.
+type TenantQuotaController struct {
+	repository        storage.TransactionalRepository
+	checker           *quota.Checker
+	extractTenantFunc func(*web.Request) (string, error)
+}
+
+// NewTenantQuotaController creates a controller of the quotas of the tenants identified by the provided label key
+func NewTenantQuotaController(repository storage.TransactionalRepository, tenantIdentifier string, extractTenantFunc func(*web.Request) (string, error)) *TenantQuotaController {
+	return &TenantQuotaController{
+		repository:        repository,
+		checker:           quota.NewChecker(tenantIdentifier),
+		extractTenantFunc: extractTenantFunc,
+	}
+}
+
+type tenantQuotaResponse struct {
+	TenantID string             `json:"tenant_id"`
+	Quota    *types.TenantQuota `json:"quota"`
+	Usage    *quota.Usage       `json:"usage"`
+}
+
+func (c *TenantQuotaController) GetQuota(req *web.Request) (*web.Response, error) {
+	ctx := req.Context()
+	tenantID := req.PathParams[web.PathParamResourceID]
+	callerTenantID, err := c.callerTenant(req)
+	if err != nil {
+		return nil, err
+	}
+	if callerTenantID != "" && callerTenantID != tenantID {
+		log.C(ctx).Errorf("Tenant %s is not allowed to get the quota of tenant %s", callerTenantID, tenantID)
+		return nil, util.HandleStorageError(util.ErrNotFoundInStorage, string(types.TenantType))
+	}
+
+	tenantQuota, err := c.checker.Quota(ctx, c.repository, tenantID, false)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.TenantQuotaType))
+	}
+	usage, err := c.checker.Usage(ctx, c.repository, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return util.NewJSONResponse(http.StatusOK, &tenantQuotaResponse{
+		TenantID: tenantID,
+		Quota:    tenantQuota,
+		Usage:    usage,
+	})
+}
+
+func (c *TenantQuotaController) SetQuota(req *web.Request) (*web.Response, error) {
+	ctx := req.Context()
+	if err := c.assertGlobalAccess(req); err != nil {
+		return nil, err
+	}
+
+	tenantQuota := &types.TenantQuota{}
+	if err := util.BytesToObject(req.Body, tenantQuota); err != nil {
+		return nil, err
+	}
+	tenantQuota.ID = req.PathParams[web.PathParamResourceID]
+	tenantQuota.Labels = nil
+	if err := c.assertExisting(ctx, types.ServiceOfferingType, tenantQuota.MaxInstancesPerOffering); err != nil {
+		return nil, err
+	}
+	if err := c.assertExisting(ctx, types.ServicePlanType, tenantQuota.MaxInstancesPerPlan); err != nil {
+		return nil, err
+	}
+
+	var result types.Object
+	err := c.repository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
+		now := time.Now().UTC()
+		tenantQuota.UpdatedAt = now
+		tenantQuota.Ready = true
+		existing, err := c.checker.Quota(ctx, storage, tenantQuota.ID, true)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			log.C(ctx).Infof("Creating quota of tenant %s", tenantQuota.ID)
+			tenantQuota.CreatedAt = now
+			result, err = storage.Create(ctx, tenantQuota)
+			return err
+		}
+
+		log.C(ctx).Infof("Updating quota of tenant %s", tenantQuota.ID)
+		tenantQuota.CreatedAt = existing.CreatedAt
+		tenantQuota.PagingSequence = existing.PagingSequence
+		result, err = storage.Update(ctx, tenantQuota, types.LabelChanges{})
+		return err
+	})
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.TenantQuotaType))
+	}
+
+	return util.NewJSONResponse(http.StatusOK, result)
+}
+
+func (c *TenantQuotaController) DeleteQuota(req *web.Request) (*web.Response, error) {
+	ctx := req.Context()
+	if err := c.assertGlobalAccess(req); err != nil {
+		return nil, err
+	}
+
+	tenantID := req.PathParams[web.PathParamResourceID]
+	log.C(ctx).Infof("Deleting quota of tenant %s", tenantID)
+	if err := c.repository.Delete(ctx, types.TenantQuotaType, query.ByField(query.EqualsOperator, "id", tenantID)); err != nil {
+		return nil, util.HandleStorageError(err, string(types.TenantQuotaType))
+	}
+
+	return util.NewJSONResponse(http.StatusOK, map[string]string{})
+}
+
+// callerTenant returns the tenant of the user or an empty string if the user is not restricted to a tenant
+func (c *TenantQuotaController) callerTenant(req *web.Request) (string, error) {
+	user, found := web.UserFromContext(req.Context())
+	if !found || user.AccessLevel == web.GlobalAccess {
+		return "", nil
+	}
+	return c.extractTenantFunc(req)
+}
+
+func (c *TenantQuotaController) assertGlobalAccess(req *web.Request) error {
+	callerTenantID, err := c.callerTenant(req)
+	if err != nil {
+		return err
+	}
+	if callerTenantID != "" {
+		return &util.HTTPError{
+			ErrorType:   "Forbidden",
+			Description: "tenant users are not allowed to change quotas",
+			StatusCode:  http.StatusForbidden,
+		}
+	}
+	return nil
+}
+
+// assertExisting verifies that the resources for which limits are set exist
+func (c *TenantQuotaController) assertExisting(ctx context.Context, objectType types.ObjectType, limits map[string]int) error {
+	if len(limits) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(limits))
+	for id := range limits {
+		ids = append(ids, id)
+	}
+	count, err := c.repository.Count(ctx, objectType, query.ByField(query.InOperator, "id", ids...))
+	if err != nil {
+		return err
+	}
+	if count != len(ids) {
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("quota references non-existing %s", objectType),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return nil
+}
+
+func (c *TenantQuotaController) Routes() []web.Route {
+	path := fmt.Sprintf("%s/{%s}%s", web.TenantURL, web.PathParamResourceID, web.QuotasURL)
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   path,
+			},
+			Handler: c.GetQuota,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPut,
+				Path:   path,
+			},
+			Handler: c.SetQuota,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   path,
+			},
+			Handler: c.DeleteQuota,
+		},
+	}
+}
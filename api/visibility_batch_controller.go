@@ -0,0 +1,283 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+	"github.com/gofrs/uuid"
+)
+
+// VisibilityBatchAction is the change applied by a single item of a visibilities batch
+type VisibilityBatchAction string
+
+const (
+	// VisibilityBatchCreate creates the visibility provided in the item
+	VisibilityBatchCreate VisibilityBatchAction = "create"
+	// VisibilityBatchUpdate applies the label changes provided in the item to an existing visibility
+	VisibilityBatchUpdate VisibilityBatchAction = "update"
+	// VisibilityBatchDelete deletes an existing visibility
+	VisibilityBatchDelete VisibilityBatchAction = "delete"
+)
+
+// VisibilityBatchRequest is the body of a request to the visibilities batch API
+type VisibilityBatchRequest struct {
+	// CoalesceNotifications specifies whether the notifications for changes of the same visibility should be merged
+	CoalesceNotifications bool                   `json:"coalesce_notifications"`
+	Items                 []*VisibilityBatchItem `json:"items"`
+}
+
+// Validate implements InputValidator and verifies all items of the batch
+func (vbr *VisibilityBatchRequest) Validate() error {
+	if len(vbr.Items) == 0 {
+		return errors.New("batch must contain at least one item")
+	}
+	for i, item := range vbr.Items {
+		if item == nil {
+			return fmt.Errorf("batch item %d is empty", i)
+		}
+		if err := item.Validate(); err != nil {
+			return fmt.Errorf("batch item %d is invalid: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// VisibilityBatchItem is a single change of a visibilities batch
+type VisibilityBatchItem struct {
+	Action     VisibilityBatchAction `json:"action"`
+	ID         string                `json:"id,omitempty"`
+	Visibility *types.Visibility     `json:"visibility,omitempty"`
+	Labels     types.LabelChanges    `json:"labels,omitempty"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields for the action of the item are populated
+func (vbi *VisibilityBatchItem) Validate() error {
+	switch vbi.Action {
+	case VisibilityBatchCreate:
+		if vbi.Visibility == nil {
+			return errors.New("missing visibility")
+		}
+		return vbi.Visibility.Validate()
+	case VisibilityBatchUpdate:
+		if vbi.ID == "" {
+			return errors.New("missing visibility id")
+		}
+		if len(vbi.Labels) == 0 {
+			return errors.New("missing label changes")
+		}
+		return vbi.Labels.Validate()
+	case VisibilityBatchDelete:
+		if vbi.ID == "" {
+			return errors.New("missing visibility id")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported action %q", vbi.Action)
+	}
+}
+
+// VisibilityBatchResult is the outcome of a single item of a visibilities batch
+type VisibilityBatchResult struct {
+	StatusCode  int               `json:"status_code"`
+	Visibility  *types.Visibility `json:"visibility,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+// VisibilityBatchResponse is the body of a response from the visibilities batch API. The results are in the order
+// of the items in the request.
+type VisibilityBatchResponse struct {
+	Results []*VisibilityBatchResult `json:"results"`
+}
+
+// VisibilityBatchController applies many visibility changes in a single transaction
+type VisibilityBatchController struct {
+	repository   storage.TransactionalRepository
+	maxBatchSize int
+}
+
+// NewVisibilityBatchController returns a new visibilities batch controller
+func NewVisibilityBatchController(options *Options) *VisibilityBatchController {
+	return &VisibilityBatchController{
+		repository:   options.Repository,
+		maxBatchSize: options.APISettings.MaxBatchSize,
+	}
+}
+
+func (c *VisibilityBatchController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.VisibilitiesBatchURL,
+			},
+			Handler: c.ApplyBatch,
+		},
+	}
+}
+
+// ApplyBatch applies all items of the batch in a single transaction. If any of the items fails, none of the changes
+// are applied and the response status is the one of the failed item.
+func (c *VisibilityBatchController) ApplyBatch(r *web.Request) (*web.Response, error) {
+	if err := util.ValidateJSONContentType(r.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+
+	batch := &VisibilityBatchRequest{}
+	if err := util.BytesToObject(r.Body, batch); err != nil {
+		return nil, err
+	}
+	if len(batch.Items) > c.maxBatchSize {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("batch must not contain more than %d items", c.maxBatchSize),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	ctx := r.Context()
+	log.C(ctx).Debugf("Applying batch of %d visibility changes", len(batch.Items))
+
+	var coalescer *interceptors.NotificationsCoalescer
+	if batch.CoalesceNotifications {
+		ctx, coalescer = interceptors.ContextWithNotificationsCoalescer(ctx)
+	}
+
+	results := make([]*VisibilityBatchResult, len(batch.Items))
+	failedItem := -1
+	err := c.repository.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+		for i, item := range batch.Items {
+			result, err := c.applyItem(ctx, repository, item)
+			if err != nil {
+				failedItem = i
+				return err
+			}
+			results[i] = result
+		}
+		if coalescer != nil {
+			return coalescer.Flush(ctx, repository)
+		}
+		return nil
+	})
+	if err == nil {
+		return util.NewJSONResponse(http.StatusOK, &VisibilityBatchResponse{Results: results})
+	}
+	if failedItem < 0 {
+		return nil, err
+	}
+
+	log.C(ctx).Infof("Visibility batch item %d failed, none of the batch changes are applied: %s", failedItem, err)
+	httpErr := util.ToHTTPError(ctx, err)
+	for i := range results {
+		results[i] = &VisibilityBatchResult{
+			StatusCode:  http.StatusFailedDependency,
+			Error:       "FailedDependency",
+			Description: fmt.Sprintf("not applied because batch item %d failed", failedItem),
+		}
+	}
+	results[failedItem] = &VisibilityBatchResult{
+		StatusCode:  httpErr.StatusCode,
+		Error:       httpErr.ErrorType,
+		Description: httpErr.Description,
+	}
+	return util.NewJSONResponse(httpErr.StatusCode, &VisibilityBatchResponse{Results: results})
+}
+
+func (c *VisibilityBatchController) applyItem(ctx context.Context, repository storage.Repository, item *VisibilityBatchItem) (*VisibilityBatchResult, error) {
+	switch item.Action {
+	case VisibilityBatchCreate:
+		visibility, err := createBatchVisibility(ctx, repository, item.Visibility)
+		if err != nil {
+			return nil, err
+		}
+		return &VisibilityBatchResult{StatusCode: http.StatusCreated, Visibility: visibility}, nil
+	case VisibilityBatchUpdate:
+		visibility, err := updateBatchVisibilityLabels(ctx, repository, item.ID, item.Labels)
+		if err != nil {
+			return nil, err
+		}
+		return &VisibilityBatchResult{StatusCode: http.StatusOK, Visibility: visibility}, nil
+	default:
+		if err := deleteBatchVisibility(ctx, repository, item.ID); err != nil {
+			return nil, err
+		}
+		return &VisibilityBatchResult{StatusCode: http.StatusOK}, nil
+	}
+}
+
+func createBatchVisibility(ctx context.Context, repository storage.Repository, visibility *types.Visibility) (*types.Visibility, error) {
+	if visibility.ID == "" {
+		UUID, err := uuid.NewV4()
+		if err != nil {
+			return nil, fmt.Errorf("could not generate GUID for visibility: %s", err)
+		}
+		visibility.ID = UUID.String()
+	}
+	currentTime := time.Now().UTC()
+	visibility.CreatedAt = currentTime
+	visibility.UpdatedAt = currentTime
+	visibility.Ready = true
+
+	object, err := repository.Create(ctx, visibility)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.VisibilityType.String())
+	}
+	return object.(*types.Visibility), nil
+}
+
+func updateBatchVisibilityLabels(ctx context.Context, repository storage.Repository, visibilityID string, labelChanges types.LabelChanges) (*types.Visibility, error) {
+	ctx, err := query.AddCriteria(ctx, query.ByField(query.EqualsOperator, "id", visibilityID))
+	if err != nil {
+		return nil, err
+	}
+	criteria := query.CriteriaForContext(ctx)
+	object, err := repository.Get(ctx, types.VisibilityType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.VisibilityType.String())
+	}
+
+	labels, _, _ := query.ApplyLabelChangesToLabels(labelChanges, object.GetLabels())
+	object.SetLabels(labels)
+	object.SetReady(true)
+
+	object, err = repository.Update(ctx, object, labelChanges, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.VisibilityType.String())
+	}
+	return object.(*types.Visibility), nil
+}
+
+func deleteBatchVisibility(ctx context.Context, repository storage.Repository, visibilityID string) error {
+	ctx, err := query.AddCriteria(ctx, query.ByField(query.EqualsOperator, "id", visibilityID))
+	if err != nil {
+		return err
+	}
+	err = repository.Delete(ctx, types.VisibilityType, query.CriteriaForContext(ctx)...)
+	return util.HandleStorageError(err, types.VisibilityType.String())
+}
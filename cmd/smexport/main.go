@@ -0,0 +1,174 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// smexport exports the platforms, service brokers and visibilities of a Service Manager in a signed archive
+// and imports such an archive in another Service Manager.
+//
+// Usage:
+//
+//	smexport -url https://sm.example.com -file archive.json export
+//	smexport -url https://sm.example.com -file archive.json -mode skip -dry-run import
+//
+// The export key is read from the SM_EXPORT_KEY environment variable and the bearer token of the admin user
+// from the SM_TOKEN environment variable, so that they do not appear in the process list.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Peripli/service-manager/api/configuration"
+	"github.com/Peripli/service-manager/pkg/export"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+func main() {
+	smURL := flag.String("url", "", "URL of the Service Manager")
+	file := flag.String("file", "", "file to which the archive is exported or from which it is imported, standard output/input if not set")
+	mode := flag.String("mode", string(export.FailOnConflicts), "how the imported entities which conflict with existing ones are handled: skip, overwrite or fail")
+	dryRun := flag.Bool("dry-run", false, "only report the changes which the import would make")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <export|import>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *smURL == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := &client{
+		url:   strings.TrimSuffix(*smURL, "/"),
+		token: os.Getenv("SM_TOKEN"),
+		key:   os.Getenv("SM_EXPORT_KEY"),
+	}
+	var err error
+	switch flag.Arg(0) {
+	case "export":
+		err = c.export(*file)
+	case "import":
+		err = c.importArchive(*file, export.ConflictMode(*mode), *dryRun)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type client struct {
+	url   string
+	token string
+	key   string
+}
+
+func (c *client) export(file string) error {
+	body, err := c.post(web.ExportURL, nil, &configuration.ExportRequest{Key: c.key}, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		_, err = os.Stdout.Write(body)
+		return err
+	}
+	return ioutil.WriteFile(file, body, 0600)
+}
+
+func (c *client) importArchive(file string, mode export.ConflictMode, dryRun bool) error {
+	if err := mode.Validate(); err != nil {
+		return err
+	}
+	var input io.Reader = os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+	archive := &export.Archive{}
+	if err := json.NewDecoder(input).Decode(archive); err != nil {
+		return fmt.Errorf("could not read archive: %s", err)
+	}
+
+	params := url.Values{}
+	params.Set(web.QueryParamMode, string(mode))
+	if dryRun {
+		params.Set(web.QueryParamDryRun, "true")
+	}
+	body, err := c.post(web.ImportURL, params, &configuration.ImportRequest{Key: c.key, Archive: archive}, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	report := &export.Report{}
+	if err := json.Unmarshal(body, report); err != nil {
+		return err
+	}
+	for _, item := range report.Items {
+		line := fmt.Sprintf("%-10s %-20s %-36s %s", item.Action, item.Type, item.ID, item.Name)
+		if item.Error != "" {
+			line += ": " + item.Error
+		}
+		fmt.Println(line)
+	}
+	if report.Failed() {
+		return fmt.Errorf("the import of some entities failed")
+	}
+	return nil
+}
+
+func (c *client) post(path string, params url.Values, requestBody interface{}, expectedStatus int) ([]byte, error) {
+	payload, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	requestURL := c.url + path
+	if len(params) != 0 {
+		requestURL += "?" + params.Encode()
+	}
+	request, err := http.NewRequest(http.MethodPost, requestURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != expectedStatus {
+		return nil, fmt.Errorf("request to %s failed with status %d: %s", path, response.StatusCode, body)
+	}
+	return body, nil
+}
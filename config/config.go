@@ -28,10 +28,16 @@ import (
 	"github.com/Peripli/service-manager/pkg/httpclient"
 
 	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/api/metrics"
+	"github.com/Peripli/service-manager/pkg/audit"
 	"github.com/Peripli/service-manager/pkg/env"
 	"github.com/Peripli/service-manager/pkg/health"
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security/authenticators"
+	"github.com/Peripli/service-manager/pkg/security/policy"
 	"github.com/Peripli/service-manager/pkg/server"
+	"github.com/Peripli/service-manager/pkg/tracing"
+	"github.com/Peripli/service-manager/pkg/webhooks"
 	"github.com/Peripli/service-manager/pkg/ws"
 	"github.com/Peripli/service-manager/storage"
 	"github.com/spf13/pflag"
@@ -39,17 +45,23 @@ import (
 
 // Settings is used to setup the Service Manager
 type Settings struct {
-	Server       *server.Settings
-	Cache        *cache.Settings
-	Storage      *storage.Settings
-	Log          *log.Settings
-	API          *api.Settings
-	Operations   *operations.Settings
-	WebSocket    *ws.Settings
-	HTTPClient   *httpclient.Settings
-	Health       *health.Settings
-	Multitenancy *multitenancy.Settings
-	Agents       *agents.Settings
+	Server             *server.Settings
+	Cache              *cache.Settings
+	Storage            *storage.Settings
+	Log                *log.Settings
+	API                *api.Settings
+	Operations         *operations.Settings
+	WebSocket          *ws.Settings
+	HTTPClient         *httpclient.Settings
+	Health             *health.Settings
+	Multitenancy       *multitenancy.Settings
+	Agents             *agents.Settings
+	Webhooks           *webhooks.Settings
+	Metrics            *metrics.Settings
+	Tracing            *tracing.Settings
+	Authorization      *policy.Settings
+	Audit              *audit.Settings
+	ClientCertificates *authenticators.CertificateSettings
 }
 
 // AddPFlags adds the SM config flags to the provided flag set
@@ -61,17 +73,23 @@ func AddPFlags(set *pflag.FlagSet) {
 // DefaultSettings returns the default values for configuring the Service Manager
 func DefaultSettings() *Settings {
 	return &Settings{
-		Cache:        cache.DefaultSettings(),
-		Server:       server.DefaultSettings(),
-		Storage:      storage.DefaultSettings(),
-		Log:          log.DefaultSettings(),
-		API:          api.DefaultSettings(),
-		Operations:   operations.DefaultSettings(),
-		WebSocket:    ws.DefaultSettings(),
-		HTTPClient:   httpclient.DefaultSettings(),
-		Health:       health.DefaultSettings(),
-		Multitenancy: multitenancy.DefaultSettings(),
-		Agents:       agents.DefaultSettings(),
+		Cache:              cache.DefaultSettings(),
+		Server:             server.DefaultSettings(),
+		Storage:            storage.DefaultSettings(),
+		Log:                log.DefaultSettings(),
+		API:                api.DefaultSettings(),
+		Operations:         operations.DefaultSettings(),
+		WebSocket:          ws.DefaultSettings(),
+		HTTPClient:         httpclient.DefaultSettings(),
+		Health:             health.DefaultSettings(),
+		Multitenancy:       multitenancy.DefaultSettings(),
+		Agents:             agents.DefaultSettings(),
+		Webhooks:           webhooks.DefaultSettings(),
+		Metrics:            metrics.DefaultSettings(),
+		Tracing:            tracing.DefaultSettings(),
+		Authorization:      policy.DefaultSettings(),
+		Audit:              audit.DefaultSettings(),
+		ClientCertificates: authenticators.DefaultCertificateSettings(),
 	}
 }
 
@@ -88,7 +106,7 @@ func New(env env.Environment) (*Settings, error) {
 func (c *Settings) Validate() error {
 	validatable := []interface {
 		Validate() error
-	}{c.Server, c.Storage, c.Log, c.Health, c.API, c.Operations, c.WebSocket, c.Multitenancy, c.Agents, c.HTTPClient}
+	}{c.Server, c.Storage, c.Log, c.Health, c.API, c.Operations, c.WebSocket, c.Multitenancy, c.Agents, c.HTTPClient, c.Webhooks, c.Metrics, c.Tracing, c.Authorization, c.Audit, c.ClientCertificates}
 
 	for _, item := range validatable {
 		if err := item.Validate(); err != nil {
@@ -29,6 +29,7 @@ import (
 	cfg "github.com/Peripli/service-manager/config"
 	"github.com/Peripli/service-manager/pkg/env/envfakes"
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security/policy"
 	"github.com/Peripli/service-manager/pkg/server"
 	"github.com/Peripli/service-manager/storage"
 	. "github.com/onsi/ginkgo"
@@ -206,6 +207,55 @@ var _ = Describe("config", func() {
 			})
 		})
 
+		Context("when TransactionalRepository previous encryption key is not 32 bytes", func() {
+			It("returns an error", func() {
+				config.Storage.PreviousEncryptionKey = "short"
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when key rotation batch size is < 1", func() {
+			It("returns an error", func() {
+				config.Storage.KeyRotation.BatchSize = 0
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when key rotation refresh interval is <= 0", func() {
+			It("returns an error", func() {
+				config.Storage.KeyRotation.RefreshInterval = 0
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when key provider type is unsupported", func() {
+			It("returns an error", func() {
+				config.Storage.KeyProvider.Type = "unsupported"
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when an authorization policy is invalid", func() {
+			It("returns an error", func() {
+				config.Authorization.Policies = []policy.Policy{{Role: "operator", Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "unknown"}}
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when audit events retention is not positive", func() {
+			It("returns an error", func() {
+				config.Audit.KeepFor = 0
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when client certificates are enabled without certificate authorities", func() {
+			It("returns an error", func() {
+				config.ClientCertificates.Enabled = true
+				assertErrorDuringValidate()
+			})
+		})
+
 		Context("when API token issuer URL is missing", func() {
 			It("returns an error", func() {
 				config.API.TokenIssuerURL = ""
@@ -284,6 +334,13 @@ var _ = Describe("config", func() {
 			})
 		})
 
+		Context("when binding rotation grace period < 0", func() {
+			It("returns an error", func() {
+				config.Operations.BindingRotationGracePeriod = -time.Second
+				assertErrorDuringValidate()
+			})
+		})
+
 		Context("when operation default pool size is <= 0", func() {
 			It("returns an error", func() {
 				config.Operations.DefaultPoolSize = 0
@@ -302,6 +359,98 @@ var _ = Describe("config", func() {
 				assertErrorDuringValidate()
 			})
 		})
+
+		Context("when operation retry policy multiplier is < 1", func() {
+			It("returns an error", func() {
+				config.Operations.RetryPolicy.Multiplier = 0.5
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when operation retry policy jitter is > 1", func() {
+			It("returns an error", func() {
+				config.Operations.RetryPolicy.Jitter = 1.5
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when operation retry policy max attempts is < 0", func() {
+			It("returns an error", func() {
+				config.Operations.RetryPolicy.MaxAttempts = -1
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when operation pool retry policy is invalid", func() {
+			It("returns an error", func() {
+				config.Operations.Pools = []operations.PoolSettings{
+					{
+						Resource:    "ServiceInstance",
+						Size:        5,
+						RetryPolicy: &operations.RetryPolicySettings{Multiplier: 2, MaxAttempts: -1},
+					},
+				}
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when OSB version is not supported", func() {
+			It("returns an error", func() {
+				config.API.OSBVersion = "2.13"
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when broker circuit failure threshold is < 0", func() {
+			It("returns an error", func() {
+				config.Operations.BrokerCircuit.FailureThreshold = -1
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when broker circuit open timeout is 0 and the broker circuit breaker is enabled", func() {
+			It("returns an error", func() {
+				config.Operations.BrokerCircuit.FailureThreshold = 3
+				config.Operations.BrokerCircuit.OpenTimeout = 0
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when catalog refresh interval is < 0", func() {
+			It("returns an error", func() {
+				config.Operations.CatalogRefresh.Interval = -time.Minute
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when catalog refresh check interval is 0", func() {
+			It("returns an error", func() {
+				config.Operations.CatalogRefresh.CheckInterval = 0
+				assertErrorDuringValidate()
+			})
+		})
+		Context("when webhooks batch size is < 1", func() {
+			It("returns an error", func() {
+				config.Webhooks.BatchSize = 0
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when metrics operations refresh interval is < 0", func() {
+			It("returns an error", func() {
+				config.Metrics.OperationsRefreshInterval = -time.Second
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when tracing is enabled and sample ratio is > 1", func() {
+			It("returns an error", func() {
+				config.Tracing.Enabled = true
+				config.Tracing.SampleRatio = 1.5
+				assertErrorDuringValidate()
+			})
+		})
+
 		Context("when agents versions json is malformed", func() {
 			It("should return an error", func() {
 				config.Agents.Versions = `rsions":["1.0.0", "1.0.1", "1.0.2"],"k8s-versions":["2.0.0", "2.0.1"]}`
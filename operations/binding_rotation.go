@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// BindingRotationDescription is the description of the operations which create the successors of rotated bindings
+	BindingRotationDescription = "binding rotation"
+
+	// RotatedBindingDeletionDescription is the description of the operations which delete the rotated bindings
+	RotatedBindingDeletionDescription = "deletion of rotated binding"
+)
+
+// DeleteRotatedBindings schedules the deletion of the bindings which were rotated before the binding rotation grace
+// period. The rotated bindings are found by their successors, so that they are deleted regardless of the lifespan of
+// the rotation operations.
+func (om *Maintainer) DeleteRotatedBindings() {
+	currentTime := time.Now()
+	criteria := []query.Criterion{
+		query.ByField(query.NotEqualsOperator, "predecessor_binding_id", ""),
+		// check if the grace period of the rotated binding has passed since the successor binding was created
+		query.ByField(query.LessThanOperator, "created_at", util.ToRFCNanoFormat(currentTime.Add(-om.settings.BindingRotationGracePeriod))),
+	}
+
+	objectList, err := om.repository.List(om.smCtx, types.ServiceBindingType, criteria...)
+	if err != nil {
+		log.C(om.smCtx).Debugf("Failed to fetch successors of rotated bindings: %s", err)
+		return
+	}
+
+	predecessorIDs := make([]string, 0, objectList.Len())
+	for i := 0; i < objectList.Len(); i++ {
+		successor := objectList.ItemAt(i).(*types.ServiceBinding)
+		// the rotated binding is kept until its successor is created
+		if successor.Ready {
+			predecessorIDs = append(predecessorIDs, successor.PredecessorBindingID)
+		}
+	}
+	if len(predecessorIDs) == 0 {
+		return
+	}
+
+	objectList, err = om.repository.List(om.smCtx, types.ServiceBindingType, query.ByField(query.InOperator, "id", predecessorIDs...))
+	if err != nil {
+		log.C(om.smCtx).Debugf("Failed to fetch rotated bindings: %s", err)
+		return
+	}
+
+	for i := 0; i < objectList.Len(); i++ {
+		predecessor := objectList.ItemAt(i).(*types.ServiceBinding)
+		if err := om.deleteRotatedBinding(predecessor); err != nil {
+			if httpErr, ok := err.(*util.HTTPError); ok && httpErr.ErrorType == "ServiceUnavailable" {
+				log.C(om.smCtx).Infof("Worker pool for bindings is busy. Deletion of rotated bindings will be scheduled later")
+				return
+			}
+			log.C(om.smCtx).Warnf("Failed to schedule deletion of rotated binding with ID (%s): %s", predecessor.ID, err)
+		}
+	}
+
+	log.C(om.smCtx).Debug("Finished scheduling deletion of rotated bindings")
+}
+
+func (om *Maintainer) deleteRotatedBinding(binding *types.ServiceBinding) error {
+	operationID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate GUID for rotated binding deletion operation: %s", err)
+	}
+	correlationID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate correlation ID for rotated binding deletion operation: %s", err)
+	}
+	operation := &types.Operation{
+		Base: types.Base{
+			ID:        operationID.String(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Labels:    make(map[string][]string),
+			Ready:     true,
+		},
+		Description:   RotatedBindingDeletionDescription,
+		Type:          types.DELETE,
+		State:         types.IN_PROGRESS,
+		ResourceID:    binding.ID,
+		ResourceType:  types.ServiceBindingType,
+		PlatformID:    types.SMPlatform,
+		CorrelationID: correlationID.String(),
+		Context:       &types.OperationContext{Async: true},
+	}
+
+	byID := query.ByField(query.EqualsOperator, "id", binding.ID)
+	action := func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+		err := repository.Delete(ctx, types.ServiceBindingType, byID)
+		if err != nil {
+			if err == util.ErrNotFoundInStorage {
+				return nil, nil
+			}
+			return nil, util.HandleStorageError(err, types.ServiceBindingType.String())
+		}
+		return nil, nil
+	}
+
+	logger := log.C(om.smCtx).WithField(log.FieldCorrelationID, operation.CorrelationID)
+	logger.Infof("Scheduling deletion of rotated binding %s with operation %s", binding.ID, operation.ID)
+	return om.scheduler.ScheduleAsyncStorageAction(log.ContextWithLogger(om.smCtx, logger), operation, action)
+}
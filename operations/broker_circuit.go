@@ -0,0 +1,246 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// BrokerCircuitSettings defines when the requests to a failing broker fail fast
+type BrokerCircuitSettings struct {
+	FailureThreshold int           `mapstructure:"failure_threshold" description:"the number of consecutive failed requests to a broker after which the requests to the broker fail fast and its operations are not retried, 0 disables it"`
+	OpenTimeout      time.Duration `mapstructure:"open_timeout" description:"the time for which the requests to a failing broker fail fast before a probe request is sent to it"`
+}
+
+// DefaultBrokerCircuitSettings returns the default broker circuit settings
+func DefaultBrokerCircuitSettings() *BrokerCircuitSettings {
+	return &BrokerCircuitSettings{
+		OpenTimeout: 30 * time.Second,
+	}
+}
+
+// Validate validates the broker circuit settings
+func (s *BrokerCircuitSettings) Validate() error {
+	if s.FailureThreshold < 0 {
+		return fmt.Errorf("validate Settings: broker circuit FailureThreshold must not be negative")
+	}
+	if s.FailureThreshold > 0 && s.OpenTimeout <= 0 {
+		return fmt.Errorf("validate Settings: broker circuit OpenTimeout must be larger than 0")
+	}
+	return nil
+}
+
+// CircuitState is the state of the circuit of a broker
+type CircuitState string
+
+const (
+	// CircuitClosed indicates that the requests are sent to the broker
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen indicates that the requests to the broker fail fast
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen indicates that a single probe request is sent to the broker to check if it has recovered
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// BrokerHealth is the health of a broker as observed by the requests sent to it
+type BrokerHealth struct {
+	Status              health.Status `json:"status"`
+	Circuit             CircuitState  `json:"circuit"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	LastError           string        `json:"last_error,omitempty"`
+	LastFailureAt       time.Time     `json:"last_failure_at,omitempty"`
+	OpenedAt            time.Time     `json:"opened_at,omitempty"`
+}
+
+type brokerCircuit struct {
+	name        string
+	state       CircuitState
+	failures    int
+	lastError   string
+	lastFailure time.Time
+	openedAt    time.Time
+	probing     bool
+}
+
+// BrokerCircuits is a circuit breaker for the requests to each broker, both the ones proxied by the OSB API and the
+// ones sent by the operations of the Service Manager API. The circuit of a broker opens after a number of consecutive
+// failed requests. While it is open the requests to the broker fail fast and the operations towards the broker are
+// not retried. After the open timeout a single probe request is sent to the broker. The circuit closes if the probe
+// succeeds and opens again otherwise.
+//
+// The circuits are kept in memory, so each Service Manager instance observes the brokers separately.
+type BrokerCircuits struct {
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mutex    sync.Mutex
+	circuits map[string]*brokerCircuit
+}
+
+// NewBrokerCircuits returns BrokerCircuits with the provided settings
+func NewBrokerCircuits(settings *BrokerCircuitSettings) *BrokerCircuits {
+	return &BrokerCircuits{
+		failureThreshold: settings.FailureThreshold,
+		openTimeout:      settings.OpenTimeout,
+		circuits:         make(map[string]*brokerCircuit),
+	}
+}
+
+// Allow returns an error if a request to the broker must fail fast. If the open timeout of the broker circuit has
+// passed, the request is allowed as a probe and the other requests fail fast until its result is recorded.
+func (bc *BrokerCircuits) Allow(broker *types.ServiceBroker) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	circuit, found := bc.circuits[broker.ID]
+	if !found || circuit.state == CircuitClosed {
+		return nil
+	}
+	if circuit.state == CircuitOpen && time.Since(circuit.openedAt) >= bc.openTimeout {
+		circuit.state = CircuitHalfOpen
+	}
+	if circuit.state == CircuitHalfOpen && !circuit.probing {
+		circuit.probing = true
+		return nil
+	}
+	return &util.HTTPError{
+		ErrorType: "ServiceBrokerUnavailable",
+		Description: fmt.Sprintf("service broker %s is unavailable after %d consecutive failed requests, last error: %s",
+			broker.Name, circuit.failures, circuit.lastError),
+		StatusCode: http.StatusServiceUnavailable,
+	}
+}
+
+// RecordSuccess closes the circuit of the broker
+func (bc *BrokerCircuits) RecordSuccess(broker *types.ServiceBroker) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	delete(bc.circuits, broker.ID)
+}
+
+// RecordFailure counts a failed request to the broker and opens its circuit once the failure threshold is reached or
+// the probe request failed
+func (bc *BrokerCircuits) RecordFailure(broker *types.ServiceBroker, failure string) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	circuit, found := bc.circuits[broker.ID]
+	if !found {
+		circuit = &brokerCircuit{state: CircuitClosed}
+		bc.circuits[broker.ID] = circuit
+	}
+	circuit.name = broker.Name
+	circuit.failures++
+	circuit.lastError = failure
+	circuit.lastFailure = time.Now().UTC()
+	if circuit.state == CircuitHalfOpen || circuit.failures >= bc.failureThreshold {
+		circuit.state = CircuitOpen
+		circuit.openedAt = circuit.lastFailure
+		circuit.probing = false
+	}
+}
+
+// RecordAbort releases the probe of the broker if the request was aborted before the broker responded
+func (bc *BrokerCircuits) RecordAbort(broker *types.ServiceBroker) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if circuit, found := bc.circuits[broker.ID]; found {
+		circuit.probing = false
+	}
+}
+
+// OpenUntil returns the time until which the requests to the broker fail fast or the zero time if they are allowed
+func (bc *BrokerCircuits) OpenUntil(brokerID string) time.Time {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	circuit, found := bc.circuits[brokerID]
+	if !found || circuit.state != CircuitOpen {
+		return time.Time{}
+	}
+	openUntil := circuit.openedAt.Add(bc.openTimeout)
+	if time.Now().After(openUntil) {
+		return time.Time{}
+	}
+	return openUntil
+}
+
+// Health returns the health of the broker
+func (bc *BrokerCircuits) Health(brokerID string) *BrokerHealth {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	circuit, found := bc.circuits[brokerID]
+	if !found {
+		return &BrokerHealth{Status: health.StatusUp, Circuit: CircuitClosed}
+	}
+	return circuit.health()
+}
+
+// Unavailable returns the health of the brokers with open circuits by broker name
+func (bc *BrokerCircuits) Unavailable() map[string]*BrokerHealth {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	unavailable := make(map[string]*BrokerHealth)
+	for _, circuit := range bc.circuits {
+		if circuit.state != CircuitClosed {
+			unavailable[circuit.name] = circuit.health()
+		}
+	}
+	return unavailable
+}
+
+func (c *brokerCircuit) health() *BrokerHealth {
+	status := health.StatusUp
+	if c.state != CircuitClosed {
+		status = health.StatusDown
+	}
+	return &BrokerHealth{
+		Status:              status,
+		Circuit:             c.state,
+		ConsecutiveFailures: c.failures,
+		LastError:           c.lastError,
+		LastFailureAt:       c.lastFailure,
+		OpenedAt:            c.openedAt,
+	}
+}
+
+// IsBrokerUnavailable returns whether the response status indicates that the broker is not able to process requests
+func IsBrokerUnavailable(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+// isBrokerCircuitOpen returns whether the operation should not be executed as the circuit of the broker responsible
+// for its resource is open
+func isBrokerCircuitOpen(circuits *BrokerCircuits, operation *types.Operation) bool {
+	if circuits == nil || operation.Context == nil || operation.Context.BrokerID == "" {
+		return false
+	}
+	return !circuits.OpenUntil(operation.Context.BrokerID).IsZero()
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Broker circuits", func() {
+	const openTimeout = 50 * time.Millisecond
+
+	var (
+		circuits *BrokerCircuits
+		broker   *types.ServiceBroker
+	)
+
+	openCircuit := func() {
+		circuits.RecordFailure(broker, "broker is down")
+		circuits.RecordFailure(broker, "broker is down")
+	}
+
+	BeforeEach(func() {
+		circuits = NewBrokerCircuits(&BrokerCircuitSettings{FailureThreshold: 2, OpenTimeout: openTimeout})
+		broker = &types.ServiceBroker{Base: types.Base{ID: "broker-id"}, Name: "broker"}
+	})
+
+	It("allows the requests to a broker without failures", func() {
+		Expect(circuits.Allow(broker)).To(Succeed())
+		Expect(circuits.Health(broker.ID)).To(Equal(&BrokerHealth{Status: health.StatusUp, Circuit: CircuitClosed}))
+	})
+
+	It("allows the requests to a broker with less failures than the threshold", func() {
+		circuits.RecordFailure(broker, "broker is down")
+		Expect(circuits.Allow(broker)).To(Succeed())
+		Expect(circuits.Unavailable()).To(BeEmpty())
+	})
+
+	When("the failure threshold is reached", func() {
+		BeforeEach(openCircuit)
+
+		It("fails fast the requests to the broker", func() {
+			err := circuits.Allow(broker)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(err.Error()).To(ContainSubstring("broker is down"))
+		})
+
+		It("does not retry the operations towards the broker until the open timeout passes", func() {
+			Expect(circuits.OpenUntil(broker.ID)).To(BeTemporally("~", time.Now().Add(openTimeout), openTimeout))
+			Expect(isBrokerCircuitOpen(circuits, &types.Operation{Context: &types.OperationContext{BrokerID: broker.ID}})).To(BeTrue())
+			Expect(isBrokerCircuitOpen(circuits, &types.Operation{Context: &types.OperationContext{BrokerID: "other-broker-id"}})).To(BeFalse())
+		})
+
+		It("reports the broker as unavailable", func() {
+			brokerHealth := circuits.Health(broker.ID)
+			Expect(brokerHealth.Status).To(Equal(health.StatusDown))
+			Expect(brokerHealth.Circuit).To(Equal(CircuitOpen))
+			Expect(brokerHealth.ConsecutiveFailures).To(Equal(2))
+			Expect(circuits.Unavailable()).To(HaveKey(broker.Name))
+		})
+
+		When("the open timeout passes", func() {
+			BeforeEach(func() {
+				time.Sleep(openTimeout)
+			})
+
+			It("allows the operations towards the broker to be retried", func() {
+				Expect(circuits.OpenUntil(broker.ID)).To(BeZero())
+			})
+
+			It("allows a single probe request", func() {
+				Expect(circuits.Allow(broker)).To(Succeed())
+				Expect(circuits.Health(broker.ID).Circuit).To(Equal(CircuitHalfOpen))
+				Expect(circuits.Allow(broker)).ToNot(Succeed())
+			})
+
+			It("closes the circuit if the probe succeeds", func() {
+				Expect(circuits.Allow(broker)).To(Succeed())
+				circuits.RecordSuccess(broker)
+				Expect(circuits.Allow(broker)).To(Succeed())
+				Expect(circuits.Health(broker.ID).Circuit).To(Equal(CircuitClosed))
+			})
+
+			It("opens the circuit again if the probe fails", func() {
+				Expect(circuits.Allow(broker)).To(Succeed())
+				circuits.RecordFailure(broker, "broker is still down")
+				Expect(circuits.Allow(broker)).ToNot(Succeed())
+				Expect(circuits.Health(broker.ID).Circuit).To(Equal(CircuitOpen))
+			})
+
+			It("allows another probe if the probe is aborted", func() {
+				Expect(circuits.Allow(broker)).To(Succeed())
+				circuits.RecordAbort(broker)
+				Expect(circuits.Allow(broker)).To(Succeed())
+			})
+		})
+	})
+})
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// runningActions holds the async actions executed by all schedulers of this Service Manager instance, so that
+// the action of a cancelled operation can be stopped regardless of the scheduler which started it
+var runningActions = &actionRegistry{actions: make(map[string]*runningAction)}
+
+type runningAction struct {
+	cancel context.CancelFunc
+}
+
+type actionRegistry struct {
+	mutex   sync.Mutex
+	actions map[string]*runningAction
+}
+
+// register stores the cancel function of the action executed for the operation and returns a function which removes it
+func (r *actionRegistry) register(operationID string, cancel context.CancelFunc) func() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	action := &runningAction{cancel: cancel}
+	r.actions[operationID] = action
+	return func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		// orphan mitigation for the same operation might have been registered in the meantime
+		if r.actions[operationID] == action {
+			delete(r.actions, operationID)
+		}
+	}
+}
+
+// cancel stops the action executed for the operation, if there is such
+func (r *actionRegistry) cancel(operationID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	action, found := r.actions[operationID]
+	if !found {
+		return false
+	}
+	action.cancel()
+	delete(r.actions, operationID)
+	return true
+}
+
+// CancelOperation marks the provided in progress operation as cancelled and stops its execution. If a resource
+// provisioned by a broker might have been left behind, orphan mitigation is scheduled for it. The operation is locked
+// while it is cancelled, so that it cannot be completed by its action at the same time.
+func (s *Scheduler) CancelOperation(ctx context.Context, operation *types.Operation) error {
+	if err := validateOperationCancellable(operation); err != nil {
+		return err
+	}
+
+	log.C(ctx).Infof("Cancelling %s operation with id %s for resource of type %s with id %s", operation.Type, operation.ID, operation.ResourceType, operation.ResourceID)
+	requiresOrphanMitigation := operation.Type == types.CREATE && isProvisionedByBroker(operation.ResourceType)
+	// the state is updated before stopping the action, so that the action does not complete the operation
+	if err := s.repository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
+		lockedOperation, err := lockOperation(ctx, storage, operation.ID)
+		if err != nil {
+			return err
+		}
+		// the action might have completed the operation since it was retrieved
+		if err := validateOperationCancellable(lockedOperation); err != nil {
+			return err
+		}
+
+		*operation = *lockedOperation
+		operation.Reschedule = false
+		operation.RescheduleTimestamp = time.Time{}
+		if requiresOrphanMitigation {
+			operation.DeletionScheduled = time.Now().UTC()
+		}
+		return updateOperationState(ctx, storage, operation, types.CANCELLED, errOperationCancelled())
+	}); err != nil {
+		return err
+	}
+
+	if runningActions.cancel(operation.ID) {
+		log.C(ctx).Infof("Stopped the execution of cancelled operation with id %s", operation.ID)
+	}
+
+	if requiresOrphanMitigation {
+		byID := query.ByField(query.EqualsOperator, "id", operation.ResourceID)
+		action := func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+			err := repository.Delete(ctx, operation.ResourceType, byID)
+			if err != nil {
+				if err == util.ErrNotFoundInStorage {
+					return nil, nil
+				}
+				return nil, util.HandleStorageError(err, operation.ResourceType.String())
+			}
+			return nil, nil
+		}
+
+		// the operation is still persisted as cancelled, so the maintainer reschedules the orphan mitigation if this fails
+		if err := s.ScheduleAsyncStorageAction(ctx, operation, action); err != nil {
+			log.C(ctx).Warnf("Failed to schedule orphan mitigation for cancelled operation with id %s: %s", operation.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func validateOperationCancellable(operation *types.Operation) error {
+	if operation.State != types.IN_PROGRESS || operation.InOrphanMitigationState() {
+		return &util.HTTPError{
+			ErrorType:   "UnprocessableEntity",
+			Description: "only operations in progress can be cancelled",
+			StatusCode:  http.StatusUnprocessableEntity,
+		}
+	}
+	if operation.PlatformID != types.SMPlatform {
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: "only operations executed by the Service Manager can be cancelled",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	if operation.CascadeRootID != "" {
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: "cascade operations cannot be cancelled",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return nil
+}
+
+// lockOperation retrieves the operation with the provided id and locks it until the end of the transaction
+func lockOperation(ctx context.Context, repository storage.Repository, operationID string) (*types.Operation, error) {
+	operation, err := repository.GetForUpdate(ctx, types.OperationType, query.ByField(query.EqualsOperator, "id", operationID))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+	return operation.(*types.Operation), nil
+}
+
+func errOperationCancelled() error {
+	return &util.HTTPError{
+		ErrorType:   "OperationCancelled",
+		Description: "operation was cancelled",
+		StatusCode:  http.StatusConflict,
+	}
+}
+
+// isProvisionedByBroker returns whether resources of the provided type are created by sending a request to a broker
+func isProvisionedByBroker(objectType types.ObjectType) bool {
+	return objectType == types.ServiceInstanceType || objectType == types.ServiceBindingType
+}
+
+// failureState returns the state in which the operation should be left when its execution fails. Cancelled operations
+// remain cancelled even if the orphan mitigation for them fails.
+func failureState(operation *types.Operation) types.OperationState {
+	if operation.State == types.CANCELLED {
+		return types.CANCELLED
+	}
+	return types.FAILED
+}
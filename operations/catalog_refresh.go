@@ -0,0 +1,265 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	catalogRefreshLockIndex = 301
+
+	// CatalogRefreshDescription is the description of the operations which refresh the broker catalogs periodically
+	CatalogRefreshDescription = "scheduled catalog refresh"
+)
+
+// CatalogRefreshSettings defines the periodic refresh of the broker catalogs
+type CatalogRefreshSettings struct {
+	Interval      time.Duration `mapstructure:"interval" description:"the interval between catalog refreshes of each broker, 0 disables the refresh of the brokers without an interval label"`
+	CheckInterval time.Duration `mapstructure:"check_interval" description:"the interval between checks for brokers with due catalog refreshes"`
+	LabelKey      string        `mapstructure:"label_key" description:"the key of the broker label overriding the catalog refresh interval of the broker, the label value 0 disables the refresh of the broker"`
+}
+
+// DefaultCatalogRefreshSettings returns the default catalog refresh settings
+func DefaultCatalogRefreshSettings() *CatalogRefreshSettings {
+	return &CatalogRefreshSettings{
+		Interval:      0,
+		CheckInterval: time.Minute,
+		LabelKey:      "catalog_refresh_interval",
+	}
+}
+
+// Validate validates the catalog refresh settings
+func (s *CatalogRefreshSettings) Validate() error {
+	if s.Interval < 0 {
+		return fmt.Errorf("validate Settings: catalog refresh Interval must not be negative")
+	}
+	if s.CheckInterval <= minTimePeriod {
+		return fmt.Errorf("validate Settings: catalog refresh CheckInterval must be larger than %s", minTimePeriod)
+	}
+	if len(s.LabelKey) == 0 {
+		return fmt.Errorf("validate Settings: catalog refresh LabelKey must not be empty")
+	}
+	return nil
+}
+
+// interval returns the catalog refresh interval of the broker. The label of the broker takes precedence over the
+// configured interval.
+func (s *CatalogRefreshSettings) interval(broker *types.ServiceBroker) (time.Duration, error) {
+	values, found := broker.GetLabels()[s.LabelKey]
+	if !found || len(values) == 0 {
+		return s.Interval, nil
+	}
+	interval, err := time.ParseDuration(values[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s label value %s: %s", s.LabelKey, values[0], err)
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf("invalid %s label value %s: interval must not be negative", s.LabelKey, values[0])
+	}
+	return interval, nil
+}
+
+// CatalogRefresher schedules a go routine which refreshes the catalogs of the brokers with a catalog refresh interval.
+// A refresh is an update of the broker executed by the scheduler of the broker operations, so it fetches the catalog
+// of the broker like a broker update and it is recorded as an operation of the broker.
+type CatalogRefresher struct {
+	started bool
+
+	repository storage.Repository
+	scheduler  *Scheduler
+	locker     storage.Locker
+	settings   *CatalogRefreshSettings
+}
+
+// NewCatalogRefresher constructs a CatalogRefresher scheduling the refreshes with the provided scheduler
+func NewCatalogRefresher(repository storage.Repository, scheduler *Scheduler, lockerCreatorFunc storage.LockerCreatorFunc, settings *CatalogRefreshSettings) *CatalogRefresher {
+	return &CatalogRefresher{
+		repository: repository,
+		scheduler:  scheduler,
+		locker:     lockerCreatorFunc(catalogRefreshLockIndex),
+		settings:   settings,
+	}
+}
+
+// Start schedules the catalog refresher. It cannot be used concurrently.
+func (r *CatalogRefresher) Start(ctx context.Context, group *sync.WaitGroup) error {
+	if r.started {
+		return errors.New("catalog refresher already started")
+	}
+	r.started = true
+	group.Add(1)
+	go func() {
+		defer func() {
+			r.started = false
+			group.Done()
+		}()
+		log.C(ctx).Infof("Scheduling checks for due broker catalog refreshes every %s", r.settings.CheckInterval)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.settings.CheckInterval):
+				r.refresh(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *CatalogRefresher) refresh(ctx context.Context) {
+	// only a single Service Manager instance checks the brokers, so that a catalog is not refreshed more than once
+	if err := r.locker.TryLock(ctx); err != nil {
+		log.C(ctx).Debugf("Failed to retrieve lock for catalog refresh: %s", err)
+		return
+	}
+	defer func() {
+		if err := r.locker.Unlock(ctx); err != nil {
+			log.C(ctx).Warnf("Could not unlock for catalog refresh: %s", err)
+		}
+	}()
+
+	brokers, err := r.dueBrokers(ctx)
+	if err != nil {
+		log.C(ctx).WithError(err).Error("could not fetch brokers with due catalog refreshes")
+		return
+	}
+
+	for _, broker := range brokers {
+		if err := r.refreshBroker(ctx, broker); err != nil {
+			if httpErr, ok := err.(*util.HTTPError); ok && httpErr.ErrorType == "ServiceUnavailable" {
+				log.C(ctx).Infof("Worker pool for brokers is busy. Catalog refresh of broker %s will be scheduled later", broker.ID)
+				return
+			}
+			log.C(ctx).WithError(err).Errorf("could not schedule catalog refresh of broker %s", broker.ID)
+		}
+	}
+}
+
+// dueBrokers returns the brokers whose catalog refresh interval has passed since the broker or its last operation
+// was updated. A failed refresh is therefore not retried before the interval passes again.
+func (r *CatalogRefresher) dueBrokers(ctx context.Context) ([]*types.ServiceBroker, error) {
+	objectList, err := r.repository.List(ctx, types.ServiceBrokerType)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := make(map[string]time.Duration)
+	ids := make([]string, 0)
+	for _, broker := range objectList.(*types.ServiceBrokers).ServiceBrokers {
+		interval, err := r.settings.interval(broker)
+		if err != nil {
+			log.C(ctx).Warnf("Skipping catalog refresh of broker %s: %s", broker.ID, err)
+			continue
+		}
+		if interval > 0 {
+			intervals[broker.ID] = interval
+			ids = append(ids, broker.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	lastOperations, err := r.repository.QueryForList(ctx, types.OperationType, storage.QueryForLastOperationsPerResource, map[string]interface{}{
+		"id_list":       ids,
+		"resource_type": types.ServiceBrokerType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	lastUpdates := make(map[string]time.Time)
+	for i := 0; i < lastOperations.Len(); i++ {
+		operation := lastOperations.ItemAt(i).(*types.Operation)
+		if operation.State == types.IN_PROGRESS {
+			// the broker is being changed, so its catalog is refreshed after the change
+			lastUpdates[operation.ResourceID] = time.Now()
+			continue
+		}
+		lastUpdates[operation.ResourceID] = operation.UpdatedAt
+	}
+
+	brokers := make([]*types.ServiceBroker, 0)
+	for _, broker := range objectList.(*types.ServiceBrokers).ServiceBrokers {
+		interval, found := intervals[broker.ID]
+		if !found {
+			continue
+		}
+		lastUpdate := broker.UpdatedAt
+		if lastUpdates[broker.ID].After(lastUpdate) {
+			lastUpdate = lastUpdates[broker.ID]
+		}
+		if time.Since(lastUpdate) >= interval {
+			brokers = append(brokers, broker)
+		}
+	}
+	return brokers, nil
+}
+
+func (r *CatalogRefresher) refreshBroker(ctx context.Context, broker *types.ServiceBroker) error {
+	operationID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate GUID for catalog refresh operation: %s", err)
+	}
+	correlationID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate correlation ID for catalog refresh operation: %s", err)
+	}
+	operation := &types.Operation{
+		Base: types.Base{
+			ID:        operationID.String(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Labels:    make(map[string][]string),
+			Ready:     true,
+		},
+		Description:   CatalogRefreshDescription,
+		Type:          types.UPDATE,
+		State:         types.IN_PROGRESS,
+		ResourceID:    broker.ID,
+		ResourceType:  types.ServiceBrokerType,
+		PlatformID:    types.SMPlatform,
+		CorrelationID: correlationID.String(),
+		Context:       &types.OperationContext{Async: true},
+	}
+
+	byID := query.ByField(query.EqualsOperator, "id", broker.ID)
+	action := func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+		// the broker is fetched again, as it may have been changed since the brokers were listed
+		object, err := repository.GetForUpdate(ctx, types.ServiceBrokerType, byID)
+		if err != nil {
+			return nil, util.HandleStorageError(err, types.ServiceBrokerType.String())
+		}
+		object, err = repository.Update(ctx, object, types.LabelChanges{}, byID)
+		return object, util.HandleStorageError(err, types.ServiceBrokerType.String())
+	}
+
+	logger := log.C(ctx).WithField(log.FieldCorrelationID, operation.CorrelationID)
+	logger.Infof("Scheduling catalog refresh of broker %s with operation %s", broker.ID, operation.ID)
+	return r.scheduler.ScheduleAsyncStorageAction(log.ContextWithLogger(ctx, logger), operation, action)
+}
@@ -39,10 +39,16 @@ type Settings struct {
 	DeleteOperationsBatchSize int           `mapstructure:"delete_operations_batch_size" description:"delete operation batch size"`
 	Lifespan                  time.Duration `mapstructure:"lifespan" description:"after that time is passed since its creation, the operation can be cleaned up by the maintainer"`
 
+	BindingRotationGracePeriod time.Duration `mapstructure:"binding_rotation_grace_period" description:"after that time is passed since the rotation of a service binding, the rotated binding is deleted by the maintainer"`
+
 	ReschedulingInterval     time.Duration `mapstructure:"rescheduling_interval" description:"the interval between auto rescheduling of operation actions"`
 	ReschedulingLongInterval time.Duration `mapstructure:"rescheduling_long_interval" description:"the interval between auto rescheduling of operation actions after multiple retries"`
 	PollingInterval          time.Duration `mapstructure:"polling_interval" description:"the interval between polls for async requests"`
 
+	RetryPolicy    *RetryPolicySettings    `mapstructure:"retry_policy" description:"the default policy for retrying operation actions"`
+	BrokerCircuit  *BrokerCircuitSettings  `mapstructure:"broker_circuit" description:"the circuit breaker for the requests to failing brokers"`
+	CatalogRefresh *CatalogRefreshSettings `mapstructure:"catalog_refresh" description:"the periodic refresh of the broker catalogs"`
+
 	DefaultPoolSize               int            `mapstructure:"default_pool_size" description:"default worker pool size"`
 	DefaultCascadePollingPoolSize int            `mapstructure:"default_cascade_polling_pool_size" description:"default worker pool size"`
 	Pools                         []PoolSettings `mapstructure:"pools" description:"defines the different available worker pools"`
@@ -60,10 +66,14 @@ func DefaultSettings() *Settings {
 		MaintainerRetryInterval:        10 * time.Minute,
 		DeleteOperationsBatchSize:      1000,
 		Lifespan:                       7 * 24 * time.Hour,
+		BindingRotationGracePeriod:     24 * time.Hour,
 		ReschedulingInterval:           10 * time.Second,
 		ReschedulingLongInterval:       1 * time.Hour,
 		PollingInterval:                4 * time.Second,
 		PollCascadeInterval:            4 * time.Second,
+		RetryPolicy:                    DefaultRetryPolicySettings(),
+		BrokerCircuit:                  DefaultBrokerCircuitSettings(),
+		CatalogRefresh:                 DefaultCatalogRefreshSettings(),
 		DefaultPoolSize:                20,
 		DefaultCascadePollingPoolSize:  20,
 		Pools:                          []PoolSettings{},
@@ -91,12 +101,30 @@ func (s *Settings) Validate() error {
 	if s.MaintainerRetryInterval <= minTimePeriod {
 		return fmt.Errorf("validate Settings: MaintainerRetryInterval must be larger than %s", minTimePeriod)
 	}
+	if s.BindingRotationGracePeriod < 0 {
+		return fmt.Errorf("validate Settings: BindingRotationGracePeriod must not be negative")
+	}
 	if s.DefaultPoolSize <= 0 {
 		return fmt.Errorf("validate Settings: DefaultPoolSize must be larger than 0")
 	}
 	if s.DefaultCascadePollingPoolSize <= 0 {
 		return fmt.Errorf("validate Settings: DefaultCascadePollingPoolSize must be larger than 0")
 	}
+	if s.RetryPolicy != nil {
+		if err := s.RetryPolicy.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.BrokerCircuit != nil {
+		if err := s.BrokerCircuit.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.CatalogRefresh != nil {
+		if err := s.CatalogRefresh.Validate(); err != nil {
+			return err
+		}
+	}
 	for _, pool := range s.Pools {
 		if err := pool.Validate(); err != nil {
 			return err
@@ -106,10 +134,36 @@ func (s *Settings) Validate() error {
 	return nil
 }
 
+// retryPolicy returns the policy for retrying the actions of operations for the provided resource type. The intervals
+// which are not set are taken from the rescheduling intervals.
+func (s *Settings) retryPolicy(resourceType types.ObjectType) *RetryPolicySettings {
+	policy := *DefaultRetryPolicySettings()
+	if s.RetryPolicy != nil {
+		policy = *s.RetryPolicy
+	}
+	for _, pool := range s.Pools {
+		if pool.Resource == resourceType.String() && pool.RetryPolicy != nil {
+			policy = *pool.RetryPolicy
+			break
+		}
+	}
+	if policy.InitialInterval == 0 {
+		policy.InitialInterval = s.ReschedulingInterval
+	}
+	if policy.MaxInterval == 0 {
+		policy.MaxInterval = s.ReschedulingLongInterval
+	}
+	if policy.MaxInterval < policy.InitialInterval {
+		policy.MaxInterval = policy.InitialInterval
+	}
+	return &policy
+}
+
 // PoolSettings defines the settings for a worker pool
 type PoolSettings struct {
-	Resource string `mapstructure:"resource" description:"name of the resource for which a worker pool is created"`
-	Size     int    `mapstructure:"size" description:"size of the worker pool"`
+	Resource    string               `mapstructure:"resource" description:"name of the resource for which a worker pool is created"`
+	Size        int                  `mapstructure:"size" description:"size of the worker pool"`
+	RetryPolicy *RetryPolicySettings `mapstructure:"retry_policy" description:"the policy for retrying the operation actions for the resource"`
 }
 
 // Validate validates the Pool settings
@@ -117,6 +171,11 @@ func (ps *PoolSettings) Validate() error {
 	if ps.Size <= 0 {
 		return fmt.Errorf("validate Settings: Pool size for resource '%s' must be larger than 0", ps.Resource)
 	}
+	if ps.RetryPolicy != nil {
+		if err := ps.RetryPolicy.Validate(); err != nil {
+			return fmt.Errorf("validate Settings: Pool for resource '%s': %s", ps.Resource, err)
+		}
+	}
 
 	return nil
 }
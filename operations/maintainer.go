@@ -50,19 +50,21 @@ type Maintainer struct {
 	scheduler               *Scheduler
 	cascadePollingScheduler *Scheduler
 	settings                *Settings
+	brokerCircuits          *BrokerCircuits
 	wg                      *sync.WaitGroup
 	functors                []maintainerFunctor
 	operationLockers        map[string]storage.Locker
 }
 
 // NewMaintainer constructs a Maintainer
-func NewMaintainer(smCtx context.Context, repository storage.TransactionalRepository, lockerCreatorFunc storage.LockerCreatorFunc, options *Settings, wg *sync.WaitGroup) *Maintainer {
+func NewMaintainer(smCtx context.Context, repository storage.TransactionalRepository, lockerCreatorFunc storage.LockerCreatorFunc, options *Settings, brokerCircuits *BrokerCircuits, wg *sync.WaitGroup) *Maintainer {
 	maintainer := &Maintainer{
 		smCtx:                   smCtx,
 		repository:              repository,
-		scheduler:               NewScheduler(smCtx, repository, options, options.DefaultPoolSize, wg),
-		cascadePollingScheduler: NewScheduler(smCtx, repository, options, options.DefaultCascadePollingPoolSize, wg),
+		scheduler:               NewScheduler(smCtx, repository, options, brokerCircuits, options.DefaultPoolSize, wg),
+		cascadePollingScheduler: NewScheduler(smCtx, repository, options, brokerCircuits, options.DefaultCascadePollingPoolSize, wg),
 		settings:                options,
+		brokerCircuits:          brokerCircuits,
 		wg:                      wg,
 	}
 
@@ -117,6 +119,11 @@ func NewMaintainer(smCtx context.Context, repository storage.TransactionalReposi
 			execute:  maintainer.rescheduleOrphanMitigationOperations,
 			interval: options.MaintainerRetryInterval,
 		},
+		{
+			name:     "deleteRotatedBindings",
+			execute:  maintainer.DeleteRotatedBindings,
+			interval: options.MaintainerRetryInterval,
+		},
 	}
 
 	operationLockers := make(map[string]storage.Locker)
@@ -288,13 +295,13 @@ func (om *Maintainer) cleanupInternalSuccessfulOperations() {
 	log.C(om.smCtx).Debug("Finished cleaning up successful internal operations")
 }
 
-// cleanupInternalFailedOperations cleans up all failed internal operations which are older than some specified time
+// cleanupInternalFailedOperations cleans up all failed or cancelled internal operations which are older than some specified time
 func (om *Maintainer) cleanupInternalFailedOperations() {
 	currentTime := time.Now()
 
 	criteria := []query.Criterion{
 		query.ByField(query.EqualsOperator, "platform_id", types.SMPlatform),
-		query.ByField(query.EqualsOperator, "state", string(types.FAILED)),
+		query.ByField(query.InOperator, "state", string(types.FAILED), string(types.CANCELLED)),
 		query.ByField(query.EqualsOperator, "reschedule", "false"),
 		query.ByField(query.EqualsOperator, "deletion_scheduled", ZeroTime),
 		// ignore cascade operations
@@ -306,7 +313,7 @@ func (om *Maintainer) cleanupInternalFailedOperations() {
 
 	om.batchDeleteOperation(criteria, om.settings.DeleteOperationsBatchSize)
 
-	log.C(om.smCtx).Debug("Finished cleaning up failed internal operations")
+	log.C(om.smCtx).Debug("Finished cleaning up failed and cancelled internal operations")
 }
 
 func (om *Maintainer) CleanupResourcelessOperations() {
@@ -340,7 +347,7 @@ func (om *Maintainer) CleanupResourcelessOperations() {
 	log.C(om.smCtx).Debug("Finished cleaning up resource-less operations")
 }
 
-// rescheduleUnfinishedOperations reschedules IN_PROGRESS operations which are reschedulable, not scheduled for deletion and no goroutine is processing at the moment
+// rescheduleUnfinishedOperations reschedules IN_PROGRESS operations which are reschedulable, not scheduled for deletion, due for their next attempt and no goroutine is processing at the moment
 func (om *Maintainer) rescheduleUnfinishedOperations() {
 	currentTime := time.Now()
 	criteria := []query.Criterion{
@@ -350,6 +357,8 @@ func (om *Maintainer) rescheduleUnfinishedOperations() {
 		query.ByField(query.EqualsOperator, "deletion_scheduled", ZeroTime),
 		// check if operation hasn't been updated for the operation's maximum allowed time to execute
 		query.ByField(query.LessThanOperator, "updated_at", util.ToRFCNanoFormat(currentTime.Add(-om.settings.ActionTimeout))),
+		// check if the retry policy of the operation allows the next attempt
+		query.ByField(query.LessThanOperator, "next_attempt_timestamp", util.ToRFCNanoFormat(currentTime)),
 	}
 
 	objectList, err := om.repository.List(om.smCtx, types.OperationType, criteria...)
@@ -364,6 +373,11 @@ func (om *Maintainer) rescheduleUnfinishedOperations() {
 		logger := log.C(om.smCtx).WithField(log.FieldCorrelationID, operation.CorrelationID)
 		ctx := log.ContextWithLogger(om.smCtx, logger)
 
+		if isBrokerCircuitOpen(om.brokerCircuits, operation) {
+			logger.Debugf("Skipping rescheduling of operation with ID (%s) as the circuit of its broker is open", operation.ID)
+			continue
+		}
+
 		var action storageAction
 
 		switch operation.Type {
@@ -513,6 +527,8 @@ func (om *Maintainer) rescheduleOrphanMitigationOperations() {
 		query.ByField(query.NotEqualsOperator, "type", string(types.UPDATE)),
 		// check if operation hasn't been updated for the operation's maximum allowed time to execute
 		query.ByField(query.LessThanOperator, "updated_at", util.ToRFCNanoFormat(currentTime.Add(-om.settings.ActionTimeout))),
+		// check if the retry policy of the operation allows the next attempt
+		query.ByField(query.LessThanOperator, "next_attempt_timestamp", util.ToRFCNanoFormat(currentTime)),
 	}
 
 	objectList, err := om.repository.List(om.smCtx, types.OperationType, criteria...)
@@ -527,6 +543,11 @@ func (om *Maintainer) rescheduleOrphanMitigationOperations() {
 		logger := log.C(om.smCtx).WithField(log.FieldCorrelationID, operation.CorrelationID)
 		ctx := log.ContextWithLogger(om.smCtx, logger)
 
+		if isBrokerCircuitOpen(om.brokerCircuits, operation) {
+			logger.Debugf("Skipping rescheduling of operation with ID (%s) as the circuit of its broker is open", operation.ID)
+			continue
+		}
+
 		byID := query.ByField(query.EqualsOperator, "id", operation.ResourceID)
 
 		action := func(ctx context.Context, repository storage.Repository) (types.Object, error) {
@@ -41,3 +41,8 @@ func Set(ctx context.Context, operation *types.Operation) (context.Context, erro
 
 	return context.WithValue(ctx, operationCtxKey{}, operation), nil
 }
+
+// Clear returns a context which does not carry the currently running operation
+func Clear(ctx context.Context) context.Context {
+	return context.WithValue(ctx, operationCtxKey{}, nil)
+}
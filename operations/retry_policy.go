@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// RetryPolicySettings defines how the actions of unfinished operations and orphan mitigations are retried
+type RetryPolicySettings struct {
+	InitialInterval time.Duration `mapstructure:"initial_interval" description:"the delay before the first retry of an operation action, defaults to the rescheduling interval"`
+	MaxInterval     time.Duration `mapstructure:"max_interval" description:"the maximum delay between retries of an operation action, defaults to the rescheduling long interval"`
+	Multiplier      float64       `mapstructure:"multiplier" description:"the factor by which the delay between retries grows after each attempt"`
+	Jitter          float64       `mapstructure:"jitter" description:"the maximum fraction of the delay between retries by which it is randomly increased or decreased"`
+	MaxAttempts     int           `mapstructure:"max_attempts" description:"the maximum number of attempts to execute an operation action, 0 means unlimited"`
+}
+
+// DefaultRetryPolicySettings returns the default retry policy
+func DefaultRetryPolicySettings() *RetryPolicySettings {
+	return &RetryPolicySettings{
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// Validate validates the retry policy settings
+func (rp *RetryPolicySettings) Validate() error {
+	if rp.InitialInterval < 0 || rp.MaxInterval < 0 {
+		return fmt.Errorf("validate Settings: retry policy intervals must not be negative")
+	}
+	if rp.Multiplier < 1 {
+		return fmt.Errorf("validate Settings: retry policy Multiplier must be at least 1")
+	}
+	if rp.Jitter < 0 || rp.Jitter > 1 {
+		return fmt.Errorf("validate Settings: retry policy Jitter must be between 0 and 1")
+	}
+	if rp.MaxAttempts < 0 {
+		return fmt.Errorf("validate Settings: retry policy MaxAttempts must not be negative")
+	}
+	return nil
+}
+
+// delay returns the time to wait before the next attempt of an operation action which has already been attempted
+// the provided number of times
+func (rp *RetryPolicySettings) delay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := float64(rp.InitialInterval) * math.Pow(rp.Multiplier, float64(attempts-1))
+	if delay > float64(rp.MaxInterval) {
+		delay = float64(rp.MaxInterval)
+	}
+	if rp.Jitter > 0 {
+		delay += delay * rp.Jitter * (2*randomFloat() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// attemptsExceeded returns whether an operation action which has already been attempted the provided number of times
+// may not be attempted any more
+func (rp *RetryPolicySettings) attemptsExceeded(attempts int) bool {
+	return rp.MaxAttempts > 0 && attempts >= rp.MaxAttempts
+}
+
+// scheduleNextAttempt sets the time of the next attempt of the failed action of the operation according to its retry
+// policy. Operations towards a broker whose circuit is open are not retried before the circuit open timeout passes.
+func (s *Scheduler) scheduleNextAttempt(ctx context.Context, operation *types.Operation) {
+	policy := s.settings.retryPolicy(operation.ResourceType)
+	nextAttempt := time.Now().UTC().Add(policy.delay(operation.Attempts))
+	if s.brokerCircuits != nil && operation.Context != nil && operation.Context.BrokerID != "" {
+		brokerID := operation.Context.BrokerID
+		if openUntil := s.brokerCircuits.OpenUntil(brokerID); openUntil.After(nextAttempt) {
+			log.C(ctx).Infof("Circuit of broker with id %s is open, operation with id %s will not be retried before %s", brokerID, operation.ID, openUntil)
+			nextAttempt = openUntil.UTC()
+		}
+	}
+	operation.NextAttemptTimestamp = nextAttempt
+}
+
+var (
+	randomMutex sync.Mutex
+	random      = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func randomFloat() float64 {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	return random.Float64()
+}
@@ -29,9 +29,11 @@ import (
 
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/tracing"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/storage"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type storageAction func(ctx context.Context, repository storage.Repository) (types.Object, error)
@@ -45,13 +47,14 @@ type Scheduler struct {
 	actionTimeout                  time.Duration
 	reconciliationOperationTimeout time.Duration
 	cascadeOrphanMitigationTimeout time.Duration
-	reschedulingDelay              time.Duration
-	reschedulingLongDelay          time.Duration
+	settings                       *Settings
+	brokerCircuits                 *BrokerCircuits
 	wg                             *sync.WaitGroup
 }
 
-// NewScheduler constructs a Scheduler
-func NewScheduler(smCtx context.Context, repository storage.TransactionalRepository, settings *Settings, poolSize int, wg *sync.WaitGroup) *Scheduler {
+// NewScheduler constructs a Scheduler. The operations towards brokers whose circuits in brokerCircuits are open are
+// not retried before the circuits can be probed, nil brokerCircuits disables it.
+func NewScheduler(smCtx context.Context, repository storage.TransactionalRepository, settings *Settings, brokerCircuits *BrokerCircuits, poolSize int, wg *sync.WaitGroup) *Scheduler {
 	return &Scheduler{
 		smCtx:                          smCtx,
 		repository:                     repository,
@@ -59,12 +62,22 @@ func NewScheduler(smCtx context.Context, repository storage.TransactionalReposit
 		actionTimeout:                  settings.ActionTimeout,
 		reconciliationOperationTimeout: settings.ReconciliationOperationTimeout,
 		cascadeOrphanMitigationTimeout: settings.CascadeOrphanMitigationTimeout,
-		reschedulingDelay:              settings.ReschedulingInterval,
-		reschedulingLongDelay:          settings.ReschedulingLongInterval,
+		settings:                       settings,
+		brokerCircuits:                 brokerCircuits,
 		wg:                             wg,
 	}
 }
 
+// PoolSize returns the number of workers which can execute asynchronous storage actions concurrently
+func (s *Scheduler) PoolSize() int {
+	return cap(s.workers)
+}
+
+// BusyWorkers returns the number of workers which are currently executing asynchronous storage actions
+func (s *Scheduler) BusyWorkers() int {
+	return len(s.workers)
+}
+
 //Identifies the preferred execution mode and execute the storage action
 func (s *Scheduler) ScheduleStorageAction(ctx context.Context, operation *types.Operation, action storageAction, isAsyncSupported bool) (types.Object, bool, error) {
 	var object types.Object
@@ -127,7 +140,9 @@ func (s *Scheduler) ScheduleSyncStorageAction(ctx context.Context, operation *ty
 		return nil, err
 	}
 
+	ctxWithOp, span := tracing.StartSpan(ctxWithOp, operationSpanName(operation), operationSpanAttributes(operation)...)
 	object, actionErr := action(ctxWithOp, s.repository)
+	tracing.End(span, actionErr)
 	if actionErr != nil {
 		log.C(ctx).Errorf("failed to execute action for %s operation with id %s for %s entity with id %s: %s", operation.Type, operation.ID, operation.ResourceType, operation.ResourceID, actionErr)
 	}
@@ -152,6 +167,9 @@ func (s *Scheduler) ScheduleAsyncStorageAction(ctx context.Context, operation *t
 		s.wg.Add(1)
 		stateCtx := util.StateContext{Context: ctx}
 		go func(operation *types.Operation) {
+			// the operation outlives the request which scheduled it, so it is traced in a new trace linked to the request trace
+			stateCtx, span := tracing.StartLinkedSpan(stateCtx, operationSpanName(operation), operationSpanAttributes(operation)...)
+			defer span.End()
 			defer func() {
 				if panicErr := recover(); panicErr != nil {
 					errMessage := fmt.Errorf("job panicked while executing: %s", panicErr)
@@ -182,6 +200,7 @@ func (s *Scheduler) ScheduleAsyncStorageAction(ctx context.Context, operation *t
 
 			stateCtxWithOpAndTimeout, timeoutCtxCancel := context.WithTimeout(stateCtxWithOp, s.actionTimeout)
 			defer timeoutCtxCancel()
+			defer runningActions.register(operation.ID, timeoutCtxCancel)()
 			go func() {
 				select {
 				case <-s.smCtx.Done():
@@ -194,6 +213,7 @@ func (s *Scheduler) ScheduleAsyncStorageAction(ctx context.Context, operation *t
 			var actionErr error
 			var objectAfterAction types.Object
 			if objectAfterAction, actionErr = action(stateCtxWithOpAndTimeout, s.repository); actionErr != nil {
+				tracing.SetError(span, actionErr)
 				log.C(stateCtx).Errorf("failed to execute action for %s operation with id %s for %s entity with id %s: %s", operation.Type, operation.ID, operation.ResourceType, operation.ResourceID, actionErr)
 			}
 
@@ -477,6 +497,12 @@ func (s *Scheduler) handleActionResponse(ctx context.Context, actionObject types
 		return nil, err
 	}
 
+	// if the operation was cancelled while the action was executing, the outcome of the action is discarded
+	if opAfterJob.State == types.CANCELLED && opBeforeJob.State != types.CANCELLED {
+		log.C(ctx).Infof("%s operation with id %s for %s entity with id %s was cancelled during its execution", opAfterJob.Type, opAfterJob.ID, opAfterJob.ResourceType, opAfterJob.ResourceID)
+		return nil, errOperationCancelled()
+	}
+
 	// if an action error has occurred we mark the operation as failed and check if deletion has to be scheduled
 	if actionError != nil {
 		return nil, s.handleActionResponseFailure(ctx, actionError, opAfterJob)
@@ -491,6 +517,7 @@ func (s *Scheduler) handleActionResponse(ctx context.Context, actionObject types
 }
 
 func (s *Scheduler) handleActionResponseFailure(ctx context.Context, actionError error, opAfterJob *types.Operation) error {
+	s.scheduleNextAttempt(ctx, opAfterJob)
 	if err := s.repository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
 		// after a failed FAILED CREATE operation, update the ready field to false
 		if opAfterJob.Type == types.CREATE && opAfterJob.State == types.FAILED {
@@ -507,7 +534,7 @@ func (s *Scheduler) handleActionResponseFailure(ctx context.Context, actionError
 			}
 		}
 
-		newState := types.FAILED
+		newState := failureState(opAfterJob)
 		// if this is a force cascade action, we are trying to delete it directly from the database
 		// in case we are failing to delete it the operation will be marked as failed
 		if opAfterJob.IsForceDeleteCascadeOperation() && !opAfterJob.InOrphanMitigationState() {
@@ -548,12 +575,9 @@ func (s *Scheduler) handleActionResponseFailure(ctx context.Context, actionError
 		}
 
 		log.C(ctx).Infof("Scheduling of required delete operation after actual operation with id %s failed", opAfterJob.ID)
-		// if deletion timestamp was set on the op, reschedule the same op with delete action and wait for reschedulingDelay time
+		// if deletion timestamp was set on the op, reschedule the same op with delete action and wait until its next attempt
 		// so that we don't DOS the broker
-		reschedulingDelayTimeout := time.After(s.reschedulingDelay)
-		if time.Now().UTC().After(opAfterJob.DeletionScheduled.Add(s.actionTimeout * 2)) {
-			reschedulingDelayTimeout = time.After(s.reschedulingLongDelay)
-		}
+		reschedulingDelayTimeout := time.After(time.Until(opAfterJob.NextAttemptTimestamp))
 		select {
 		case <-s.smCtx.Done():
 			return fmt.Errorf("sm context canceled: %s", s.smCtx.Err())
@@ -572,10 +596,20 @@ func (s *Scheduler) handleActionResponseFailure(ctx context.Context, actionError
 
 func (s *Scheduler) handleActionResponseSuccess(ctx context.Context, actionObject types.Object, opAfterJob *types.Operation) (types.Object, error) {
 	if err := s.repository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
+		// the operation is locked, so that it cannot be cancelled while it is completed
+		lockedOperation, err := lockOperation(ctx, storage, opAfterJob.ID)
+		if err != nil {
+			return err
+		}
+		if lockedOperation.State == types.CANCELLED && opAfterJob.State != types.CANCELLED {
+			log.C(ctx).Infof("%s operation with id %s for %s entity with id %s was cancelled before its completion", opAfterJob.Type, opAfterJob.ID, opAfterJob.ResourceType, opAfterJob.ResourceID)
+			return errOperationCancelled()
+		}
+
 		finalState := opAfterJob.State
 		if opAfterJob.Type != types.DELETE && opAfterJob.InOrphanMitigationState() {
-			// successful orphan mitigation for CREATE/UPDATE should still leave the operation as FAILED
-			finalState = types.FAILED
+			// successful orphan mitigation for CREATE/UPDATE should still leave the operation as FAILED (or CANCELLED)
+			finalState = failureState(opAfterJob)
 		} else {
 			// Guard to avoid set SUCCEEDED state on pending cascade operations
 			if len(opAfterJob.CascadeRootID) == 0 || opAfterJob.State != types.PENDING {
@@ -656,18 +690,34 @@ func (s *Scheduler) validateOperationDoesNotExceedTimeouts(operation *types.Oper
 	return nil
 }
 
+func (s *Scheduler) validateOperationDoesNotExceedAttempts(operation *types.Operation) error {
+	policy := s.settings.retryPolicy(operation.ResourceType)
+	if policy.attemptsExceeded(operation.Attempts) {
+		return &util.HTTPError{
+			ErrorType:   "ManualActionRequired",
+			Description: fmt.Sprintf("operation has been attempted %d times and has exceeded the maximum number of attempts. Rootcause error: %s", operation.Attempts, operation.Errors),
+			StatusCode:  http.StatusUnprocessableEntity,
+		}
+	}
+	return nil
+}
+
 func (s *Scheduler) executeOperationPreconditions(ctx context.Context, operation *types.Operation) error {
 	if operation.State == types.SUCCEEDED ||
-		(operation.State == types.FAILED && !operation.InOrphanMitigationState()) {
+		((operation.State == types.FAILED || operation.State == types.CANCELLED) && !operation.InOrphanMitigationState()) {
 		return fmt.Errorf("scheduling for operations %+v is not allowed due to invalid state", operation)
 	}
 
 	// if operation has reached the maximum allowed timeout for auto rescheduling of operation actions
 	// if cascade operation has reached the maximum allowed time for orphan mitigation
+	// if operation has reached the maximum allowed attempts of its retry policy
 	err := s.validateOperationDoesNotExceedTimeouts(operation)
+	if err == nil {
+		err = s.validateOperationDoesNotExceedAttempts(operation)
+	}
 	if err != nil {
 		operation.DeletionScheduled = time.Time{}
-		if opErr := updateOperationState(ctx, s.repository, operation, types.FAILED, err); opErr != nil {
+		if opErr := updateOperationState(ctx, s.repository, operation, failureState(operation), err); opErr != nil {
 			return fmt.Errorf("failed to update error of operation with id %s to %s", operation.ID, err)
 		}
 		return err
@@ -690,7 +740,7 @@ func (s *Scheduler) executeOperationPreconditions(ctx context.Context, operation
 
 		// Block updates of service instances or bindings that were not created successfully
 		if operation.Type == types.UPDATE {
-			if lastOperation.Type == types.CREATE && lastOperation.State == types.FAILED {
+			if lastOperation.Type == types.CREATE && (lastOperation.State == types.FAILED || lastOperation.State == types.CANCELLED) {
 				if operation.ResourceType == types.ServiceBindingType || operation.ResourceType == types.ServiceInstanceType {
 					return &util.HTTPError{
 						ErrorType:   "UpdateOperationIsNotAllowed",
@@ -702,6 +752,11 @@ func (s *Scheduler) executeOperationPreconditions(ctx context.Context, operation
 		}
 	}
 
+	// the operation is not picked up by the maintainer before its action could have timed out and the retry delay has passed
+	operation.Attempts++
+	retryDelay := s.settings.retryPolicy(operation.ResourceType).delay(operation.Attempts)
+	operation.NextAttemptTimestamp = time.Now().UTC().Add(s.actionTimeout + retryDelay)
+
 	if err := s.storeOrUpdateOperation(ctx, operation, currentOpExists); err != nil {
 		return err
 	}
@@ -709,6 +764,22 @@ func (s *Scheduler) executeOperationPreconditions(ctx context.Context, operation
 	return nil
 }
 
+func operationSpanName(operation *types.Operation) string {
+	return fmt.Sprintf("operation %s %s", operation.Type, operation.ResourceType)
+}
+
+// operationSpanAttributes returns the attributes of an operation span. The correlation id links the operations which are
+// not scheduled by a request, such as the ones rescheduled by the maintainer, to the trace of the originating request.
+func operationSpanAttributes(operation *types.Operation) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		tracing.OperationIDKey.String(operation.ID),
+		tracing.OperationTypeKey.String(string(operation.Type)),
+		tracing.ResourceTypeKey.String(operation.ResourceType.String()),
+		tracing.ResourceIDKey.String(operation.ResourceID),
+		tracing.CorrelationIDKey.String(operation.CorrelationID),
+	}
+}
+
 func initialLogMessage(ctx context.Context, operation *types.Operation, async bool) {
 	var logPrefix string
 	if operation.Reschedule {
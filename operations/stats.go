@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+const (
+	// DefaultWorkerPool is the name of the worker pool used by the maintainer to reschedule operations
+	DefaultWorkerPool = "maintainer"
+	// CascadePollingWorkerPool is the name of the worker pool used by the maintainer to poll cascade operations
+	CascadePollingWorkerPool = "cascade_polling"
+)
+
+var (
+	countedResourceTypes = []types.ObjectType{
+		types.ServiceBrokerType,
+		types.PlatformType,
+		types.VisibilityType,
+		types.ServiceInstanceType,
+		types.ServiceBindingType,
+		types.TenantType,
+	}
+	countedOperationTypes  = []types.OperationCategory{types.CREATE, types.UPDATE, types.DELETE}
+	countedOperationStates = []types.OperationState{types.PENDING, types.IN_PROGRESS, types.SUCCEEDED, types.FAILED, types.CANCELLED}
+)
+
+// OperationCount is the number of operations of a type and state for resources of a type
+type OperationCount struct {
+	ResourceType types.ObjectType
+	Type         types.OperationCategory
+	State        types.OperationState
+	Count        int
+}
+
+// OperationCounts returns the number of stored operations for each resource type, operation type and state. The
+// operations are counted with a single grouped query and the combinations without operations are reported as zero.
+func (om *Maintainer) OperationCounts(ctx context.Context) ([]OperationCount, error) {
+	resourceTypes := make([]string, 0, len(countedResourceTypes))
+	for _, resourceType := range countedResourceTypes {
+		resourceTypes = append(resourceTypes, resourceType.String())
+	}
+	groups, err := om.repository.CountGroups(ctx, types.OperationType, []string{"resource_type", "type", "state"}, nil,
+		query.ByField(query.InOperator, "resource_type", resourceTypes...))
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[OperationCount]int, len(groups))
+	for _, group := range groups {
+		key := OperationCount{
+			ResourceType: types.ObjectType(fieldValue(group, "resource_type")),
+			Type:         types.OperationCategory(fieldValue(group, "type")),
+			State:        types.OperationState(fieldValue(group, "state")),
+		}
+		grouped[key] += group.Count
+	}
+
+	counts := make([]OperationCount, 0, len(countedResourceTypes)*len(countedOperationTypes)*len(countedOperationStates))
+	for _, resourceType := range countedResourceTypes {
+		for _, operationType := range countedOperationTypes {
+			for _, state := range countedOperationStates {
+				count := OperationCount{
+					ResourceType: resourceType,
+					Type:         operationType,
+					State:        state,
+				}
+				count.Count = grouped[count]
+				counts = append(counts, count)
+			}
+		}
+	}
+	return counts, nil
+}
+
+func fieldValue(group *types.GroupCount, field string) string {
+	if value := group.Fields[field]; value != nil {
+		return *value
+	}
+	return ""
+}
+
+// WorkerPools returns the schedulers of the maintainer by the name of their worker pool
+func (om *Maintainer) WorkerPools() map[string]*Scheduler {
+	return map[string]*Scheduler{
+		DefaultWorkerPool:        om.scheduler,
+		CascadePollingWorkerPool: om.cascadePollingScheduler,
+	}
+}
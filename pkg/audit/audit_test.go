@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit", func() {
+	Describe("Settings", func() {
+		var settings *Settings
+
+		BeforeEach(func() {
+			settings = DefaultSettings()
+		})
+
+		It("accepts the default settings", func() {
+			Expect(settings.Validate()).To(Succeed())
+		})
+
+		Context("when keep for is not positive", func() {
+			It("returns an error", func() {
+				settings.KeepFor = 0
+				Expect(settings.Validate()).To(HaveOccurred())
+			})
+		})
+
+		Context("when clean interval is not positive", func() {
+			It("returns an error", func() {
+				settings.CleanInterval = 0
+				Expect(settings.Validate()).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Cleaner", func() {
+		var (
+			repository *storagefakes.FakeStorage
+			cleaner    *Cleaner
+			ctx        context.Context
+			cancel     context.CancelFunc
+			group      *sync.WaitGroup
+		)
+
+		BeforeEach(func() {
+			repository = &storagefakes.FakeStorage{}
+			settings := DefaultSettings()
+			settings.KeepFor = time.Hour
+			settings.CleanInterval = 10 * time.Millisecond
+			cleaner = NewCleaner(repository, settings)
+			ctx, cancel = context.WithCancel(context.Background())
+			group = &sync.WaitGroup{}
+		})
+
+		AfterEach(func() {
+			cancel()
+			group.Wait()
+		})
+
+		It("deletes the audit events older than the retention period", func() {
+			Expect(cleaner.Start(ctx, group)).To(Succeed())
+			Eventually(repository.DeleteCallCount).Should(BeNumerically(">", 0))
+
+			_, objectType, criteria := repository.DeleteArgsForCall(0)
+			Expect(objectType).To(Equal(types.AuditEventType))
+			Expect(criteria).To(HaveLen(1))
+			Expect(criteria[0].LeftOp).To(Equal("created_at"))
+			Expect(criteria[0].Operator).To(Equal(query.LessThanOperator))
+		})
+
+		It("cannot be started twice", func() {
+			Expect(cleaner.Start(ctx, group)).To(Succeed())
+			Expect(cleaner.Start(ctx, group)).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Cleaner schedules a go routine which deletes the audit events older than the retention period
+type Cleaner struct {
+	started bool
+
+	repository storage.Repository
+	settings   *Settings
+}
+
+// NewCleaner creates a Cleaner deleting the expired audit events from the repository
+func NewCleaner(repository storage.Repository, settings *Settings) *Cleaner {
+	return &Cleaner{
+		repository: repository,
+		settings:   settings,
+	}
+}
+
+// Start schedules the cleaner. It cannot be used concurrently.
+func (c *Cleaner) Start(ctx context.Context, group *sync.WaitGroup) error {
+	if c.started {
+		return errors.New("audit cleaner already started")
+	}
+	c.started = true
+	group.Add(1)
+	go func() {
+		defer func() {
+			c.started = false
+			group.Done()
+		}()
+		log.C(ctx).Infof("Scheduling audit events cleaning every %s", c.settings.CleanInterval)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.settings.CleanInterval):
+				c.clean(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *Cleaner) clean(ctx context.Context) {
+	cleanTimestamp := util.ToRFCNanoFormat(time.Now().Add(-c.settings.KeepFor))
+	log.C(ctx).Infof("Deleting audit events created before %s", cleanTimestamp)
+
+	q := query.ByField(query.LessThanOperator, "created_at", cleanTimestamp)
+	if err := c.repository.Delete(ctx, types.AuditEventType, q); err != nil {
+		if err == util.ErrNotFoundInStorage {
+			log.C(ctx).Debug("no expired audit events to delete")
+		} else {
+			log.C(ctx).WithError(err).Error("could not delete expired audit events")
+		}
+	}
+}
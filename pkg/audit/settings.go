@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Enabled       bool          `mapstructure:"enabled" description:"whether the mutating API and OSB requests are recorded in the audit trail. The events are stored after the requests are handled and may be lost if storing them fails"`
+	KeepFor       time.Duration `mapstructure:"keep_for" description:"the time to keep an audit event in the storage"`
+	CleanInterval time.Duration `mapstructure:"clean_interval" description:"time between deletions of the expired audit events"`
+}
+
+// DefaultSettings returns the default values for the audit trail
+func DefaultSettings() *Settings {
+	return &Settings{
+		Enabled:       false,
+		KeepFor:       time.Hour * 24 * 90,
+		CleanInterval: time.Hour,
+	}
+}
+
+// Validate validates the audit trail settings
+func (s *Settings) Validate() error {
+	if s.KeepFor <= 0 {
+		return fmt.Errorf("validate audit settings: keep_for should be > 0")
+	}
+	if s.CleanInterval <= 0 {
+		return fmt.Errorf("validate audit settings: clean_interval should be > 0")
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/tracing"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"net/http"
@@ -56,7 +57,10 @@ func (bc *BrokerClient) addBasicAuth(req *http.Request) *BrokerClient {
 
 func (bc *BrokerClient) authAndTlsDecorator(requestHandler util.DoRequestWithClientFunc) util.DoRequestFunc {
 	return func(req *http.Request) (*http.Response, error) {
-		client := http.DefaultClient
+		client := &http.Client{
+			Timeout:   http.DefaultClient.Timeout,
+			Transport: tracing.NewTransport(http.DefaultClient.Transport),
+		}
 		ctx := req.Context()
 		logger := log.C(ctx)
 		if bc.broker.Credentials.BasicExists() {
@@ -66,7 +70,7 @@ func (bc *BrokerClient) authAndTlsDecorator(requestHandler util.DoRequestWithCli
 		if bc.tlsConfig != nil {
 			client = &http.Client{}
 			logger.Infof("configuring broker tls for %s", bc.broker.Name)
-			client.Transport = GetTransportWithTLS(bc.tlsConfig, logger)
+			client.Transport = tracing.NewTransport(GetTransportWithTLS(bc.tlsConfig, logger))
 			return requestHandler(req, client)
 		}
 
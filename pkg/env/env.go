@@ -22,6 +22,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
 
@@ -63,9 +64,18 @@ type Environment interface {
 	AllSettings() map[string]interface{}
 }
 
+// ConfigWatcher is implemented by environments which notify about changes of the configuration file
+type ConfigWatcher interface {
+	// AddOnConfigChangeHandlers registers handlers which are triggered when the configuration file changes
+	AddOnConfigChangeHandlers(handlers ...func(env Environment) func(event fsnotify.Event))
+}
+
 // ViperEnv represents an implementation of the Environment interface that uses viper
 type ViperEnv struct {
 	*viper.Viper
+
+	mutex                  sync.RWMutex
+	onConfigChangeHandlers []func(env Environment) func(event fsnotify.Event)
 }
 
 // EmptyFlagSet creates an empty flag set and adds the default set of flags to it
@@ -117,6 +127,13 @@ func New(ctx context.Context, set *pflag.FlagSet, onConfigChangeHandlers ...func
 	return v, nil
 }
 
+// AddOnConfigChangeHandlers registers handlers which are triggered when the configuration file changes
+func (v *ViperEnv) AddOnConfigChangeHandlers(handlers ...func(env Environment) func(event fsnotify.Event)) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.onConfigChangeHandlers = append(v.onConfigChangeHandlers, handlers...)
+}
+
 func (v *ViperEnv) AllSettings() map[string]interface{} {
 	return v.Viper.AllSettings()
 }
@@ -178,11 +195,14 @@ func (v *ViperEnv) setupConfigFile(ctx context.Context, onConfigChangeHandlers .
 		}
 	}
 
-	onConfigChangeHandlers = append(onConfigChangeHandlers, dynamicLogHandler)
+	v.AddOnConfigChangeHandlers(append(onConfigChangeHandlers, dynamicLogHandler)...)
 
 	v.Viper.OnConfigChange(func(event fsnotify.Event) {
 		log.C(ctx).Warnf("Configuration file was changed by event %s. Triggering on config changed handlers...", event.String())
-		for _, handler := range onConfigChangeHandlers {
+		v.mutex.RLock()
+		handlers := v.onConfigChangeHandlers
+		v.mutex.RUnlock()
+		for _, handler := range handlers {
 			handler(v)(event)
 		}
 	})
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/fatih/structs"
+	"github.com/fsnotify/fsnotify"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
@@ -489,6 +490,30 @@ var _ = Describe("Env", func() {
 						Expect(log.Configuration().Output).ToNot(Equal(newOutput))
 					})
 				})
+
+				Context("when config change handlers are added after the environment is created", func() {
+					It("invokes them when the config file changes", func() {
+						verifyEnvCreated()
+						watcher, ok := environment.(env.ConfigWatcher)
+						Expect(ok).To(BeTrue())
+
+						changes := make(chan string, 10)
+						watcher.AddOnConfigChangeHandlers(func(e env.Environment) func(event fsnotify.Event) {
+							return func(event fsnotify.Event) {
+								changes <- cast.ToString(e.Get("wstring"))
+							}
+						})
+
+						f := cfgFile.Location + string(filepath.Separator) + cfgFile.Name + "." + cfgFile.Format
+						fileContent := cfgFile.content.(FlatOuter)
+						fileContent.WString = "changed"
+						bytes, err := yaml.Marshal(fileContent)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(ioutil.WriteFile(f, bytes, 0640)).To(Succeed())
+
+						Eventually(changes).Should(Receive(Equal("changed")))
+					})
+				})
 			})
 		})
 
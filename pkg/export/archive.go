@@ -0,0 +1,195 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+const (
+	// Version is the version of the archive format produced by the exporter
+	Version = 1
+
+	// MinKeyLength is the minimum length of the export key
+	MinKeyLength = 32
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	// ErrInvalidSignature is returned when the archive was modified or signed with another export key
+	ErrInvalidSignature = errors.New("archive signature is invalid: the archive was modified or signed with another export key")
+
+	// ErrInvalidKey is returned when the export key is too short
+	ErrInvalidKey = fmt.Errorf("export key must be at least %d characters long", MinKeyLength)
+
+	// ErrUnsupportedVersion is returned when the archive was produced with another version of the archive format
+	ErrUnsupportedVersion = fmt.Errorf("unsupported archive version, supported version is %d", Version)
+)
+
+// Archive is a snapshot of the registry entities of the Service Manager. The credentials of the entities are encrypted
+// with a key derived from the export key and the archive is signed with another key derived from it, so that it can be
+// imported only by the holders of the export key and only as it was exported.
+type Archive struct {
+	Version        int              `json:"version"`
+	CreatedAt      time.Time        `json:"created_at"`
+	Platforms      []*Platform      `json:"platforms"`
+	ServiceBrokers []*ServiceBroker `json:"service_brokers"`
+	Visibilities   []*Visibility    `json:"visibilities"`
+	Signature      string           `json:"signature,omitempty"`
+}
+
+// Platform is an exported platform. Its credentials are encrypted with the export key.
+type Platform struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Description string       `json:"description,omitempty"`
+	Technical   bool         `json:"technical,omitempty"`
+	Credentials string       `json:"credentials,omitempty"`
+	Labels      types.Labels `json:"labels,omitempty"`
+}
+
+// ServiceBroker is an exported service broker. Its credentials are encrypted with the export key.
+// The catalog is not exported as it is fetched from the broker when the broker is imported.
+type ServiceBroker struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	BrokerURL   string       `json:"broker_url"`
+	Credentials string       `json:"credentials,omitempty"`
+	Labels      types.Labels `json:"labels,omitempty"`
+}
+
+// Visibility is an exported visibility. The service plan is referenced by its catalog id and the id of its broker,
+// as the ids of the service plans are generated anew when the catalog of the broker is imported.
+type Visibility struct {
+	ID                   string       `json:"id"`
+	PlatformID           string       `json:"platform_id,omitempty"`
+	ServiceBrokerID      string       `json:"service_broker_id"`
+	ServicePlanCatalogID string       `json:"service_plan_catalog_id"`
+	Labels               types.Labels `json:"labels,omitempty"`
+}
+
+// keys are the keys derived from the export key for encrypting the credentials and for signing the archive
+type keys struct {
+	encryption []byte
+	signing    []byte
+}
+
+func deriveKeys(exportKey string) (*keys, error) {
+	if len(exportKey) < MinKeyLength {
+		return nil, ErrInvalidKey
+	}
+	derive := func(purpose string) []byte {
+		mac := hmac.New(sha256.New, []byte(exportKey))
+		mac.Write([]byte(purpose))
+		return mac.Sum(nil)
+	}
+	return &keys{
+		encryption: derive("encryption"),
+		signing:    derive("signing"),
+	}, nil
+}
+
+// sign sets the signature of the archive which is the hex encoded HMAC-SHA256 of the archive without signature
+func (a *Archive) sign(k *keys) error {
+	signature, err := a.signature(k)
+	if err != nil {
+		return err
+	}
+	a.Signature = signature
+	return nil
+}
+
+// verify checks the version and the signature of the archive
+func (a *Archive) verify(k *keys) error {
+	if a.Version != Version {
+		return ErrUnsupportedVersion
+	}
+	if !strings.HasPrefix(a.Signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	signature, err := a.signature(k)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(a.Signature), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (a *Archive) signature(k *keys) (string, error) {
+	unsigned := *a
+	unsigned.Signature = ""
+	payload, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, k.signing)
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// sealCredentials returns the base64 encoded credentials encrypted with the export key or empty string if there are no credentials
+func sealCredentials(ctx context.Context, encrypter security.Encrypter, k *keys, credentials *types.Credentials) (string, error) {
+	if credentials == nil {
+		return "", nil
+	}
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := encrypter.Encrypt(ctx, plaintext, k.encryption)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// openCredentials returns the credentials sealed with the export key or nil if there are no credentials
+func openCredentials(ctx context.Context, encrypter security.Encrypter, k *keys, sealed string) (*types.Credentials, error) {
+	if sealed == "" {
+		return nil, nil
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := encrypter.Decrypt(ctx, ciphertext, k.encryption)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt credentials: %s", err)
+	}
+	credentials := &types.Credentials{}
+	if err := json.Unmarshal(plaintext, credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
@@ -0,0 +1,535 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/util/slice"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Exporter exports the platforms, service brokers and visibilities of the Service Manager together with their labels
+// and imports them in another Service Manager. The entities are imported through the provided repository one by one,
+// so that when it is the interceptable repository the interceptors fire as if the entities were created with the API,
+// e.g. the catalogs of the imported brokers are fetched and their offerings and plans are created.
+type Exporter struct {
+	repository storage.TransactionalRepository
+	encrypter  security.Encrypter
+}
+
+// NewExporter creates an exporter working with the provided repository
+func NewExporter(repository storage.TransactionalRepository) *Exporter {
+	return &Exporter{
+		repository: repository,
+		encrypter:  &security.AESEncrypter{},
+	}
+}
+
+// Export returns a signed archive of the entities with their credentials encrypted with the export key
+func (e *Exporter) Export(ctx context.Context, exportKey string) (*Archive, error) {
+	k, err := deriveKeys(exportKey)
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{
+		Version:        Version,
+		CreatedAt:      time.Now().UTC(),
+		Platforms:      make([]*Platform, 0),
+		ServiceBrokers: make([]*ServiceBroker, 0),
+		Visibilities:   make([]*Visibility, 0),
+	}
+
+	platforms, err := e.repository.List(ctx, types.PlatformType)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < platforms.Len(); i++ {
+		platform := platforms.ItemAt(i).(*types.Platform)
+		if platform.ID == types.SMPlatform {
+			continue
+		}
+		credentials, err := sealCredentials(ctx, e.encrypter, k, platform.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		archive.Platforms = append(archive.Platforms, &Platform{
+			ID:          platform.ID,
+			Name:        platform.Name,
+			Type:        platform.Type,
+			Description: platform.Description,
+			Technical:   platform.Technical,
+			Credentials: credentials,
+			Labels:      platform.Labels,
+		})
+	}
+
+	brokers, err := e.repository.List(ctx, types.ServiceBrokerType)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < brokers.Len(); i++ {
+		broker := brokers.ItemAt(i).(*types.ServiceBroker)
+		credentials, err := sealCredentials(ctx, e.encrypter, k, broker.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		archive.ServiceBrokers = append(archive.ServiceBrokers, &ServiceBroker{
+			ID:          broker.ID,
+			Name:        broker.Name,
+			Description: broker.Description,
+			BrokerURL:   broker.BrokerURL,
+			Credentials: credentials,
+			Labels:      broker.Labels,
+		})
+	}
+
+	if archive.Visibilities, err = e.exportVisibilities(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := archive.sign(k); err != nil {
+		return nil, err
+	}
+	log.C(ctx).Infof("Exported %d platforms, %d service brokers and %d visibilities",
+		len(archive.Platforms), len(archive.ServiceBrokers), len(archive.Visibilities))
+	return archive, nil
+}
+
+func (e *Exporter) exportVisibilities(ctx context.Context) ([]*Visibility, error) {
+	offerings, err := e.repository.ListNoLabels(ctx, types.ServiceOfferingType)
+	if err != nil {
+		return nil, err
+	}
+	brokerIDs := make(map[string]string, offerings.Len())
+	for i := 0; i < offerings.Len(); i++ {
+		offering := offerings.ItemAt(i).(*types.ServiceOffering)
+		brokerIDs[offering.ID] = offering.BrokerID
+	}
+	plans, err := e.repository.ListNoLabels(ctx, types.ServicePlanType)
+	if err != nil {
+		return nil, err
+	}
+	plansByID := make(map[string]*types.ServicePlan, plans.Len())
+	for i := 0; i < plans.Len(); i++ {
+		plan := plans.ItemAt(i).(*types.ServicePlan)
+		plansByID[plan.ID] = plan
+	}
+
+	visibilities, err := e.repository.List(ctx, types.VisibilityType)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Visibility, 0, visibilities.Len())
+	for i := 0; i < visibilities.Len(); i++ {
+		visibility := visibilities.ItemAt(i).(*types.Visibility)
+		plan, found := plansByID[visibility.ServicePlanID]
+		if !found {
+			return nil, fmt.Errorf("service plan %s of visibility %s not found", visibility.ServicePlanID, visibility.ID)
+		}
+		result = append(result, &Visibility{
+			ID:                   visibility.ID,
+			PlatformID:           visibility.PlatformID,
+			ServiceBrokerID:      brokerIDs[plan.ServiceOfferingID],
+			ServicePlanCatalogID: plan.CatalogID,
+			Labels:               visibility.Labels,
+		})
+	}
+	return result, nil
+}
+
+// Import imports the entities of the archive which was exported with the same export key. The entities which conflict
+// with existing ones, i.e. have the same id or name, are handled according to the conflict mode of the options.
+// The returned report lists the action taken for each entity or, in dry-run mode, the action which would be taken.
+func (e *Exporter) Import(ctx context.Context, archive *Archive, exportKey string, options ImportOptions) (*Report, error) {
+	if err := options.Mode.Validate(); err != nil {
+		return nil, err
+	}
+	k, err := deriveKeys(exportKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := archive.verify(k); err != nil {
+		return nil, err
+	}
+
+	i := &importer{
+		Exporter:  e,
+		keys:      k,
+		mode:      options.Mode,
+		platforms: make(map[string]*entry),
+		brokers:   make(map[string]*entry),
+	}
+	entries, err := i.plan(ctx, archive)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		DryRun: options.DryRun,
+		Mode:   options.Mode,
+		Items:  make([]*ReportItem, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		report.Items = append(report.Items, entry.item)
+	}
+	if conflicts := report.conflicts(); len(conflicts) != 0 && options.Mode == FailOnConflicts && !options.DryRun {
+		return report, &ConflictError{Items: conflicts}
+	}
+	if options.DryRun {
+		return report, nil
+	}
+
+	for _, entry := range entries {
+		if entry.item.Action == ActionSkip {
+			continue
+		}
+		if err := entry.apply(ctx); err != nil {
+			log.C(ctx).WithError(err).Errorf("Could not import %s with id %s", entry.item.Type, entry.item.ID)
+			entry.item.Error = err.Error()
+		}
+	}
+	log.C(ctx).Infof("Imported %d entities, %d failed", len(report.Items)-len(report.failed()), len(report.failed()))
+	return report, nil
+}
+
+// importer holds the state of a single import
+type importer struct {
+	*Exporter
+	keys *keys
+	mode ConflictMode
+
+	// platforms and brokers are the entries of the imported platforms and brokers by their id in the archive
+	platforms map[string]*entry
+	brokers   map[string]*entry
+}
+
+// entry is a single imported entity
+type entry struct {
+	item *ReportItem
+	// existing is the existing entity which conflicts with the imported one
+	existing types.Object
+	apply    func(ctx context.Context) error
+}
+
+// targetID returns the id which the imported entity has after the import
+func (e *entry) targetID() string {
+	if e.existing != nil {
+		return e.existing.GetID()
+	}
+	return e.item.ID
+}
+
+// plan determines the action taken for each entity of the archive. The brokers are imported after the platforms and
+// the visibilities after both of them, so that the visibilities can reference the imported platforms and service plans.
+func (i *importer) plan(ctx context.Context, archive *Archive) ([]*entry, error) {
+	entries := make([]*entry, 0, len(archive.Platforms)+len(archive.ServiceBrokers)+len(archive.Visibilities))
+	for _, platform := range archive.Platforms {
+		entry, err := i.planPlatform(ctx, platform)
+		if err != nil {
+			return nil, err
+		}
+		i.platforms[platform.ID] = entry
+		entries = append(entries, entry)
+	}
+	for _, broker := range archive.ServiceBrokers {
+		entry, err := i.planBroker(ctx, broker)
+		if err != nil {
+			return nil, err
+		}
+		i.brokers[broker.ID] = entry
+		entries = append(entries, entry)
+	}
+	for _, visibility := range archive.Visibilities {
+		entry, err := i.planVisibility(ctx, visibility)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (i *importer) planPlatform(ctx context.Context, platform *Platform) (*entry, error) {
+	credentials, err := openCredentials(ctx, i.encrypter, i.keys, platform.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := i.findExisting(ctx, types.PlatformType, platform.ID, platform.Name)
+	if err != nil {
+		return nil, err
+	}
+	e := i.newEntry(types.PlatformType, platform.ID, platform.Name, existing)
+	e.apply = func(ctx context.Context) error {
+		ctx = web.ContextWithKeepPlatformCredentialsFlag(ctx, true)
+		if e.existing == nil {
+			_, err := i.repository.Create(ctx, &types.Platform{
+				Base:        newBase(platform.ID, platform.Labels),
+				Name:        platform.Name,
+				Type:        platform.Type,
+				Description: platform.Description,
+				Technical:   platform.Technical,
+				Credentials: credentials,
+			})
+			return err
+		}
+		updated := e.existing.(*types.Platform)
+		updated.Name = platform.Name
+		updated.Type = platform.Type
+		updated.Description = platform.Description
+		if credentials != nil {
+			updated.Credentials = credentials
+		}
+		return i.overwrite(ctx, updated, platform.Labels)
+	}
+	return e, nil
+}
+
+func (i *importer) planBroker(ctx context.Context, broker *ServiceBroker) (*entry, error) {
+	credentials, err := openCredentials(ctx, i.encrypter, i.keys, broker.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := i.findExisting(ctx, types.ServiceBrokerType, broker.ID, broker.Name)
+	if err != nil {
+		return nil, err
+	}
+	e := i.newEntry(types.ServiceBrokerType, broker.ID, broker.Name, existing)
+	e.apply = func(ctx context.Context) error {
+		if e.existing == nil {
+			_, err := i.repository.Create(ctx, &types.ServiceBroker{
+				Base:        newBase(broker.ID, broker.Labels),
+				Name:        broker.Name,
+				Description: broker.Description,
+				BrokerURL:   broker.BrokerURL,
+				Credentials: credentials,
+			})
+			return err
+		}
+		updated := e.existing.(*types.ServiceBroker)
+		updated.Name = broker.Name
+		updated.Description = broker.Description
+		updated.BrokerURL = broker.BrokerURL
+		if credentials != nil {
+			updated.Credentials = credentials
+		}
+		return i.overwrite(ctx, updated, broker.Labels)
+	}
+	return e, nil
+}
+
+func (i *importer) planVisibility(ctx context.Context, visibility *Visibility) (*entry, error) {
+	existing, err := i.findExisting(ctx, types.VisibilityType, visibility.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil && i.brokerExists(visibility.ServiceBrokerID) {
+		// the visibility of a plan of an existing broker can be found also by its platform and plan
+		if existing, err = i.findVisibility(ctx, visibility); err != nil {
+			return nil, err
+		}
+	}
+	e := i.newEntry(types.VisibilityType, visibility.ID, "", existing)
+	e.apply = func(ctx context.Context) error {
+		existing := e.existing
+		if existing == nil {
+			// the visibility could have been created by the interceptors when its broker was imported, e.g. for public plans
+			var err error
+			if existing, err = i.findVisibility(ctx, visibility); err != nil {
+				return err
+			}
+		}
+		if existing == nil {
+			planID, err := i.servicePlanID(ctx, visibility)
+			if err != nil {
+				return err
+			}
+			_, err = i.repository.Create(ctx, &types.Visibility{
+				Base:          newBase(visibility.ID, visibility.Labels),
+				PlatformID:    i.platformID(visibility.PlatformID),
+				ServicePlanID: planID,
+			})
+			return err
+		}
+		return i.overwrite(ctx, existing, visibility.Labels)
+	}
+	return e, nil
+}
+
+func (i *importer) newEntry(objectType types.ObjectType, id, name string, existing types.Object) *entry {
+	e := &entry{
+		item: &ReportItem{
+			Type:   objectType,
+			ID:     id,
+			Name:   name,
+			Action: ActionCreate,
+		},
+		existing: existing,
+	}
+	if existing == nil {
+		return e
+	}
+	e.item.ExistingID = existing.GetID()
+	switch i.mode {
+	case SkipConflicts:
+		e.item.Action = ActionSkip
+	case OverwriteConflicts:
+		e.item.Action = ActionOverwrite
+	default:
+		e.item.Action = ActionConflict
+	}
+	return e
+}
+
+// findExisting returns the existing entity with the provided id or name or nil if there is no such entity
+func (i *importer) findExisting(ctx context.Context, objectType types.ObjectType, id, name string) (types.Object, error) {
+	object, err := i.repository.Get(ctx, objectType, query.ByField(query.EqualsOperator, "id", id))
+	if err == nil || err != util.ErrNotFoundInStorage {
+		return object, err
+	}
+	if name == "" {
+		return nil, nil
+	}
+	object, err = i.repository.Get(ctx, objectType, query.ByField(query.EqualsOperator, "name", name))
+	if err == util.ErrNotFoundInStorage {
+		return nil, nil
+	}
+	return object, err
+}
+
+// findVisibility returns the existing visibility of the same platform and service plan or nil if there is no such visibility
+func (i *importer) findVisibility(ctx context.Context, visibility *Visibility) (types.Object, error) {
+	planID, err := i.servicePlanID(ctx, visibility)
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil, nil
+		}
+		return nil, err
+	}
+	visibilities, err := i.repository.List(ctx, types.VisibilityType, query.ByField(query.EqualsOperator, "service_plan_id", planID))
+	if err != nil {
+		return nil, err
+	}
+	platformID := i.platformID(visibility.PlatformID)
+	for j := 0; j < visibilities.Len(); j++ {
+		if existing := visibilities.ItemAt(j).(*types.Visibility); existing.PlatformID == platformID {
+			return existing, nil
+		}
+	}
+	return nil, nil
+}
+
+// servicePlanID returns the id of the service plan of the visibility among the plans of its imported broker
+func (i *importer) servicePlanID(ctx context.Context, visibility *Visibility) (string, error) {
+	brokerID := i.brokerID(visibility.ServiceBrokerID)
+	offerings, err := i.repository.ListNoLabels(ctx, types.ServiceOfferingType, query.ByField(query.EqualsOperator, "broker_id", brokerID))
+	if err != nil {
+		return "", err
+	}
+	offeringIDs := make([]string, 0, offerings.Len())
+	for j := 0; j < offerings.Len(); j++ {
+		offeringIDs = append(offeringIDs, offerings.ItemAt(j).GetID())
+	}
+	if len(offeringIDs) == 0 {
+		return "", util.ErrNotFoundInStorage
+	}
+	plan, err := i.repository.Get(ctx, types.ServicePlanType,
+		query.ByField(query.InOperator, "service_offering_id", offeringIDs...),
+		query.ByField(query.EqualsOperator, "catalog_id", visibility.ServicePlanCatalogID))
+	if err != nil {
+		return "", err
+	}
+	return plan.GetID(), nil
+}
+
+func (i *importer) brokerExists(brokerID string) bool {
+	entry, found := i.brokers[brokerID]
+	return !found || entry.existing != nil
+}
+
+// brokerID returns the id of the imported broker or the provided id if the broker is not part of the archive
+func (i *importer) brokerID(brokerID string) string {
+	if entry, found := i.brokers[brokerID]; found {
+		return entry.targetID()
+	}
+	return brokerID
+}
+
+// platformID returns the id of the imported platform or the provided id if the platform is not part of the archive
+func (i *importer) platformID(platformID string) string {
+	if entry, found := i.platforms[platformID]; found {
+		return entry.targetID()
+	}
+	return platformID
+}
+
+// overwrite updates the existing entity replacing its labels with the provided ones
+func (i *importer) overwrite(ctx context.Context, object types.Object, labels types.Labels) error {
+	labelChanges := labelChanges(object.GetLabels(), labels)
+	object.SetLabels(labels)
+	object.SetUpdatedAt(time.Now().UTC())
+	_, err := i.repository.Update(ctx, object, labelChanges, query.ByField(query.EqualsOperator, "id", object.GetID()))
+	return err
+}
+
+// labelChanges returns the changes which turn the current labels into the desired ones
+func labelChanges(current, desired types.Labels) types.LabelChanges {
+	var changes types.LabelChanges
+	for key, values := range current {
+		if _, found := desired[key]; !found {
+			changes = append(changes, &types.LabelChange{Operation: types.RemoveLabelOperation, Key: key, Values: values})
+			continue
+		}
+		if removed := difference(values, desired[key]); len(removed) != 0 {
+			changes = append(changes, &types.LabelChange{Operation: types.RemoveLabelValuesOperation, Key: key, Values: removed})
+		}
+	}
+	for key, values := range desired {
+		if added := difference(values, current[key]); len(added) != 0 {
+			changes = append(changes, &types.LabelChange{Operation: types.AddLabelValuesOperation, Key: key, Values: added})
+		}
+	}
+	return changes
+}
+
+func difference(values, excluded []string) []string {
+	var result []string
+	for _, value := range values {
+		if !slice.StringsAnyEquals(excluded, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func newBase(id string, labels types.Labels) types.Base {
+	now := time.Now().UTC()
+	return types.Base{
+		ID:        id,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Labels:    labels,
+		Ready:     true,
+	}
+}
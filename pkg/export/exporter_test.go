@@ -0,0 +1,340 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/export"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/inmemory"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const exportKey = "bSDK3ac8TwOQBgUGjXDYbFvDUfEUhR7k"
+
+func newRepository() storage.TransactionalRepository {
+	settings := storage.DefaultSettings()
+	settings.Type = storage.InMemoryStorage
+	settings.EncryptionKey = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+	s := &inmemory.Storage{}
+	Expect(s.Open(settings)).To(Succeed())
+	return storage.NewInterceptableTransactionalRepository(s)
+}
+
+func newBase(id string, labels types.Labels) types.Base {
+	return types.Base{
+		ID:        id,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Labels:    labels,
+		Ready:     true,
+	}
+}
+
+func create(ctx context.Context, repository storage.Repository, object types.Object) {
+	_, err := repository.Create(ctx, object)
+	Expect(err).ToNot(HaveOccurred())
+}
+
+func get(ctx context.Context, repository storage.Repository, objectType types.ObjectType, id string) types.Object {
+	object, err := repository.Get(ctx, objectType, query.ByField(query.EqualsOperator, "id", id))
+	Expect(err).ToNot(HaveOccurred())
+	return object
+}
+
+// createBrokerWithPlan creates a broker with a single offering and plan as the catalog interceptors would do
+func createBrokerWithPlan(ctx context.Context, repository storage.Repository, brokerID, planID string) {
+	create(ctx, repository, &types.ServiceBroker{
+		Base:      newBase(brokerID, nil),
+		Name:      brokerID + "-name",
+		BrokerURL: "http://" + brokerID + ".example.com",
+		Credentials: &types.Credentials{
+			Basic: &types.Basic{Username: brokerID + "-user", Password: "password"},
+		},
+	})
+	create(ctx, repository, &types.ServiceOffering{
+		Base:      newBase(brokerID+"-offering", nil),
+		Name:      "offering",
+		CatalogID: "offering-catalog-id",
+		BrokerID:  brokerID,
+	})
+	create(ctx, repository, &types.ServicePlan{
+		Base:              newBase(planID, nil),
+		Name:              "plan",
+		CatalogID:         "plan-catalog-id",
+		ServiceOfferingID: brokerID + "-offering",
+	})
+}
+
+func actions(report *export.Report) map[string]export.Action {
+	result := make(map[string]export.Action)
+	for _, item := range report.Items {
+		Expect(item.Error).To(BeEmpty(), "import of %s %s failed", item.Type, item.ID)
+		result[item.ID] = item.Action
+	}
+	return result
+}
+
+var _ = Describe("Exporter", func() {
+	var (
+		ctx    context.Context
+		source storage.TransactionalRepository
+		target storage.TransactionalRepository
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		source = newRepository()
+		target = newRepository()
+
+		create(ctx, source, &types.Platform{
+			Base: newBase("platform", types.Labels{"region": {"eu", "us"}}),
+			Name: "platform-name",
+			Type: types.K8sPlatformType,
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{Username: "platform-user", Password: "platform-password"},
+			},
+		})
+		create(ctx, source, &types.Platform{
+			Base: newBase(types.SMPlatform, nil),
+			Name: types.SMPlatform,
+			Type: types.SMPlatform,
+		})
+		createBrokerWithPlan(ctx, source, "broker", "source-plan")
+		create(ctx, source, &types.Visibility{
+			Base:          newBase("visibility", types.Labels{"org": {"org1"}}),
+			PlatformID:    "platform",
+			ServicePlanID: "source-plan",
+		})
+	})
+
+	Describe("Export", func() {
+		It("exports the platforms, brokers and visibilities with encrypted credentials", func() {
+			archive, err := export.NewExporter(source).Export(ctx, exportKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(archive.Version).To(Equal(export.Version))
+			Expect(archive.Signature).ToNot(BeEmpty())
+			Expect(archive.Platforms).To(HaveLen(1))
+			Expect(archive.Platforms[0].ID).To(Equal("platform"))
+			Expect(archive.Platforms[0].Labels).To(Equal(types.Labels{"region": {"eu", "us"}}))
+			Expect(archive.Platforms[0].Credentials).ToNot(BeEmpty())
+			Expect(archive.Platforms[0].Credentials).ToNot(ContainSubstring("platform-password"))
+			Expect(archive.ServiceBrokers).To(HaveLen(1))
+			Expect(archive.ServiceBrokers[0].BrokerURL).To(Equal("http://broker.example.com"))
+			Expect(archive.Visibilities).To(HaveLen(1))
+			Expect(archive.Visibilities[0].ServiceBrokerID).To(Equal("broker"))
+			Expect(archive.Visibilities[0].ServicePlanCatalogID).To(Equal("plan-catalog-id"))
+		})
+
+		It("fails when the export key is too short", func() {
+			_, err := export.NewExporter(source).Export(ctx, "short")
+			Expect(err).To(Equal(export.ErrInvalidKey))
+		})
+	})
+
+	Describe("Import", func() {
+		var archive *export.Archive
+
+		BeforeEach(func() {
+			var err error
+			archive, err = export.NewExporter(source).Export(ctx, exportKey)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		importArchive := func(mode export.ConflictMode, dryRun bool) (*export.Report, error) {
+			return export.NewExporter(target).Import(ctx, archive, exportKey, export.ImportOptions{Mode: mode, DryRun: dryRun})
+		}
+
+		It("fails when the archive is modified", func() {
+			archive.ServiceBrokers[0].BrokerURL = "http://attacker.example.com"
+			_, err := importArchive(export.FailOnConflicts, false)
+			Expect(err).To(Equal(export.ErrInvalidSignature))
+		})
+
+		It("fails when the archive is imported with another export key", func() {
+			_, err := export.NewExporter(target).Import(ctx, archive, "Yt5XxTRgwWyX7kVq1QQYkqJ3WmaSTCp9", export.ImportOptions{Mode: export.FailOnConflicts})
+			Expect(err).To(Equal(export.ErrInvalidSignature))
+		})
+
+		It("fails when the archive version is not supported", func() {
+			archive.Version = export.Version + 1
+			_, err := importArchive(export.FailOnConflicts, false)
+			Expect(err).To(Equal(export.ErrUnsupportedVersion))
+		})
+
+		It("fails when the conflict mode is not supported", func() {
+			_, err := importArchive("merge", false)
+			Expect(err).To(HaveOccurred())
+		})
+
+		Context("when there are no conflicts", func() {
+			It("imports the entities with their credentials and labels", func() {
+				// the catalog interceptors would create the offerings and plans of the imported broker
+				target.(*storage.InterceptableTransactionalRepository).AddCreateOnTxInterceptorProvider(types.ServiceBrokerType, &catalogInterceptorProvider{}, storage.InterceptorOrder{})
+
+				report, err := importArchive(export.FailOnConflicts, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(actions(report)).To(Equal(map[string]export.Action{
+					"platform":   export.ActionCreate,
+					"broker":     export.ActionCreate,
+					"visibility": export.ActionCreate,
+				}))
+
+				platform := get(ctx, target, types.PlatformType, "platform").(*types.Platform)
+				Expect(platform.Credentials.Basic.Password).To(Equal("platform-password"))
+				Expect(platform.Labels).To(Equal(types.Labels{"region": {"eu", "us"}}))
+				broker := get(ctx, target, types.ServiceBrokerType, "broker").(*types.ServiceBroker)
+				Expect(broker.Credentials.Basic.Password).To(Equal("password"))
+				visibility := get(ctx, target, types.VisibilityType, "visibility").(*types.Visibility)
+				Expect(visibility.PlatformID).To(Equal("platform"))
+				Expect(visibility.ServicePlanID).To(Equal("target-plan"))
+				Expect(visibility.Labels).To(Equal(types.Labels{"org": {"org1"}}))
+			})
+		})
+
+		Context("when there are conflicts", func() {
+			BeforeEach(func() {
+				create(ctx, target, &types.Platform{
+					Base: newBase("existing-platform", types.Labels{"region": {"eu", "asia"}, "team": {"a"}}),
+					Name: "platform-name",
+					Type: types.K8sPlatformType,
+					Credentials: &types.Credentials{
+						Basic: &types.Basic{Username: "existing-user", Password: "existing-password"},
+					},
+				})
+				createBrokerWithPlan(ctx, target, "broker", "target-plan")
+			})
+
+			It("reports the conflicts in dry-run mode without importing anything", func() {
+				report, err := importArchive(export.FailOnConflicts, true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(report.DryRun).To(BeTrue())
+				Expect(actions(report)).To(Equal(map[string]export.Action{
+					"platform":   export.ActionConflict,
+					"broker":     export.ActionConflict,
+					"visibility": export.ActionCreate,
+				}))
+				Expect(report.Items[0].ExistingID).To(Equal("existing-platform"))
+
+				count, err := target.Count(ctx, types.VisibilityType)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(0))
+			})
+
+			It("fails without importing anything in fail mode", func() {
+				report, err := importArchive(export.FailOnConflicts, false)
+				Expect(err).To(BeAssignableToTypeOf(&export.ConflictError{}))
+				Expect(err.(*export.ConflictError).Items).To(HaveLen(2))
+				Expect(report).ToNot(BeNil())
+
+				count, err := target.Count(ctx, types.VisibilityType)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(0))
+			})
+
+			It("keeps the existing entities in skip mode", func() {
+				report, err := importArchive(export.SkipConflicts, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(actions(report)).To(Equal(map[string]export.Action{
+					"platform":   export.ActionSkip,
+					"broker":     export.ActionSkip,
+					"visibility": export.ActionCreate,
+				}))
+
+				platform := get(ctx, target, types.PlatformType, "existing-platform").(*types.Platform)
+				Expect(platform.Credentials.Basic.Password).To(Equal("existing-password"))
+				visibility := get(ctx, target, types.VisibilityType, "visibility").(*types.Visibility)
+				Expect(visibility.PlatformID).To(Equal("existing-platform"))
+				Expect(visibility.ServicePlanID).To(Equal("target-plan"))
+			})
+
+			It("overwrites the existing entities in overwrite mode", func() {
+				report, err := importArchive(export.OverwriteConflicts, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(actions(report)).To(Equal(map[string]export.Action{
+					"platform":   export.ActionOverwrite,
+					"broker":     export.ActionOverwrite,
+					"visibility": export.ActionCreate,
+				}))
+
+				platform := get(ctx, target, types.PlatformType, "existing-platform").(*types.Platform)
+				Expect(platform.Credentials.Basic.Password).To(Equal("platform-password"))
+				Expect(platform.Labels).To(Equal(types.Labels{"region": {"eu", "us"}}))
+			})
+
+			It("reports the existing visibility of the same platform and plan as conflict", func() {
+				create(ctx, target, &types.Visibility{
+					Base:          newBase("existing-visibility", nil),
+					PlatformID:    "existing-platform",
+					ServicePlanID: "target-plan",
+				})
+
+				report, err := importArchive(export.SkipConflicts, true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(report.Items[2].Action).To(Equal(export.ActionSkip))
+				Expect(report.Items[2].ExistingID).To(Equal("existing-visibility"))
+			})
+		})
+	})
+})
+
+// catalogInterceptorProvider provides an interceptor which creates the offering and plan of the imported broker
+// in place of the catalog interceptors which fetch the catalog from the broker
+type catalogInterceptorProvider struct{}
+
+func (*catalogInterceptorProvider) Name() string {
+	return "test-catalog"
+}
+
+func (*catalogInterceptorProvider) Provide() storage.CreateOnTxInterceptor {
+	return &catalogInterceptor{}
+}
+
+type catalogInterceptor struct{}
+
+func (*catalogInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, obj types.Object) (types.Object, error) {
+		broker, err := h(ctx, repository, obj)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := repository.Create(ctx, &types.ServiceOffering{
+			Base:      newBase(broker.GetID()+"-offering", nil),
+			Name:      "offering",
+			CatalogID: "offering-catalog-id",
+			BrokerID:  broker.GetID(),
+		}); err != nil {
+			return nil, err
+		}
+		if _, err := repository.Create(ctx, &types.ServicePlan{
+			Base:              newBase("target-plan", nil),
+			Name:              "plan",
+			CatalogID:         "plan-catalog-id",
+			ServiceOfferingID: broker.GetID() + "-offering",
+		}); err != nil {
+			return nil, err
+		}
+		return broker, nil
+	}
+}
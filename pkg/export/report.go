@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// ConflictMode determines how the imported entities which conflict with existing ones are handled
+type ConflictMode string
+
+const (
+	// SkipConflicts keeps the existing entities and skips the conflicting imported ones
+	SkipConflicts ConflictMode = "skip"
+
+	// OverwriteConflicts overwrites the existing entities with the conflicting imported ones
+	OverwriteConflicts ConflictMode = "overwrite"
+
+	// FailOnConflicts fails the import without importing anything if there are conflicting entities
+	FailOnConflicts ConflictMode = "fail"
+)
+
+// Validate checks whether the conflict mode is supported
+func (m ConflictMode) Validate() error {
+	switch m {
+	case SkipConflicts, OverwriteConflicts, FailOnConflicts:
+		return nil
+	default:
+		return fmt.Errorf("unsupported conflict mode %q, supported modes are %s, %s and %s", m, SkipConflicts, OverwriteConflicts, FailOnConflicts)
+	}
+}
+
+// Action is the action taken for an imported entity
+type Action string
+
+const (
+	// ActionCreate means that the entity is created
+	ActionCreate Action = "create"
+
+	// ActionOverwrite means that the existing entity is overwritten
+	ActionOverwrite Action = "overwrite"
+
+	// ActionSkip means that the entity is skipped as it conflicts with an existing one
+	ActionSkip Action = "skip"
+
+	// ActionConflict means that the entity conflicts with an existing one and the import fails
+	ActionConflict Action = "conflict"
+)
+
+// ImportOptions are the options of an import
+type ImportOptions struct {
+	Mode   ConflictMode
+	DryRun bool
+}
+
+// ReportItem is the outcome of the import of a single entity
+type ReportItem struct {
+	Type       types.ObjectType `json:"type"`
+	ID         string           `json:"id"`
+	Name       string           `json:"name,omitempty"`
+	Action     Action           `json:"action"`
+	ExistingID string           `json:"existing_id,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// Report is the outcome of an import
+type Report struct {
+	DryRun bool          `json:"dry_run"`
+	Mode   ConflictMode  `json:"mode"`
+	Items  []*ReportItem `json:"items"`
+}
+
+func (r *Report) conflicts() []*ReportItem {
+	var result []*ReportItem
+	for _, item := range r.Items {
+		if item.Action == ActionConflict {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func (r *Report) failed() []*ReportItem {
+	var result []*ReportItem
+	for _, item := range r.Items {
+		if item.Error != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Failed returns whether the import of some entities failed
+func (r *Report) Failed() bool {
+	return len(r.failed()) != 0
+}
+
+// ConflictError is returned when the import fails because of conflicting entities
+type ConflictError struct {
+	Items []*ReportItem
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("import failed: %d entities conflict with existing ones", len(e.Items))
+}
@@ -36,6 +36,12 @@ const StorageIndicatorName = "storage"
 const PlatformsIndicatorName = "platforms"
 const MonitoredPlatformsHealthIndicatorName = "monitored_platforms"
 
+// BrokersIndicatorName is the name of brokers indicator
+const BrokersIndicatorName = "brokers"
+
+// BrokerCircuitsIndicatorName is the name of broker circuits indicator
+const BrokerCircuitsIndicatorName = "broker_circuits"
+
 // indicatorNames is a list of names of indicators which will be registered with default settings
 // as part of default health settings, this will allow binding them as part of environment.
 // If an indicator is registered but not specified in this list, it will be configured with
@@ -46,6 +52,8 @@ var indicatorNames = [...]string{
 	StorageIndicatorName,
 	PlatformsIndicatorName,
 	MonitoredPlatformsHealthIndicatorName,
+	BrokersIndicatorName,
+	BrokerCircuitsIndicatorName,
 }
 
 // Settings type to be loaded from the environment
@@ -55,6 +63,8 @@ type Settings struct {
 	MonitoredPlatformsThreshold     int                           `mapstructure:"monitored_platforms_threshold"`
 	EnablePlatformIndicator         bool                          `mapstructure:"enable_platforms_indicator"`
 	EnableMonitorPlatformsIndicator bool                          `mapstructure:"enable_monitor_platforms_indicator"`
+	EnableBrokersIndicator          bool                          `mapstructure:"enable_brokers_indicator"`
+	EnableBrokerCircuitsIndicator   bool                          `mapstructure:"enable_broker_circuits_indicator"`
 }
 
 // DefaultSettings returns default values for health settings
@@ -63,12 +73,19 @@ func DefaultSettings() *Settings {
 	for _, name := range indicatorNames {
 		defaultIndicatorSettings[name] = DefaultIndicatorSettings()
 	}
+	// a failing broker does not affect the availability of the Service Manager
+	for _, name := range []string{BrokersIndicatorName, BrokerCircuitsIndicatorName} {
+		defaultIndicatorSettings[name].Fatal = false
+		defaultIndicatorSettings[name].FailuresThreshold = 0
+	}
 	return &Settings{
 		Indicators:                      defaultIndicatorSettings,
 		PlatformMaxInactive:             60 * 24 * time.Hour,
 		MonitoredPlatformsThreshold:     10,
 		EnablePlatformIndicator:         false,
 		EnableMonitorPlatformsIndicator: false,
+		EnableBrokersIndicator:          false,
+		EnableBrokerCircuitsIndicator:   false,
 	}
 }
 
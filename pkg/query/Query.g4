@@ -1,7 +1,11 @@
 grammar Query ;
 
-expression: criterions EOF ;
-criterions: criterion (Concat criterions)? ;
+expression: disjunction EOF ;
+disjunction: conjunction (Or disjunction)? ;
+conjunction: term (Concat conjunction)? ;
+term: negation | group | criterion ;
+negation: Not term ;
+group: OpenBracket disjunction CloseBracket ;
 criterion: multivariate | univariate  ;
 multivariate: Key Whitespace MultiOp Whitespace multiValues ;
 univariate: Key Whitespace UniOp Whitespace Value ;
@@ -11,6 +15,8 @@ manyValues: Value (ValueSeparator manyValues)? ;
 MultiOp:  'in' | 'notin' ;
 UniOp: 'eq' | 'ne' | 'gt' | 'lt' | 'ge' | 'le' | 'en' | 'contains' ;
 Concat: Whitespace 'and' Whitespace ;
+Or: Whitespace 'or' Whitespace ;
+Not: 'not' Whitespace ;
 Value: STRING | NUMBER | BOOLEAN | DATETIME ;
 ValueSeparator: ',' | ', ' ;
 Key: [-_/a-zA-Z0-9\\]+ ;
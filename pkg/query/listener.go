@@ -33,9 +33,48 @@ type queryListener struct {
 	rightOp      []string
 	op           string
 	criteriaType CriterionType
+	operands     []Criterion
 	result       []Criterion
 }
 
+// ExitExpression is called when production expression is exited.
+func (s *queryListener) ExitExpression(ctx *parser.ExpressionContext) {
+	if s.err != nil || len(s.operands) == 0 {
+		return
+	}
+	criterion := s.pop()
+	if criterion.Operator == AndOperator {
+		// top level conjunctions are kept flat so that existing consumers can inspect the criteria one by one
+		s.result = criterion.Criteria
+	} else {
+		s.result = []Criterion{criterion}
+	}
+}
+
+// ExitDisjunction is called when production disjunction is exited.
+func (s *queryListener) ExitDisjunction(ctx *parser.DisjunctionContext) {
+	if s.err != nil || ctx.Or() == nil {
+		return
+	}
+	s.combine(OrOperator)
+}
+
+// ExitConjunction is called when production conjunction is exited.
+func (s *queryListener) ExitConjunction(ctx *parser.ConjunctionContext) {
+	if s.err != nil || ctx.Concat() == nil {
+		return
+	}
+	s.combine(AndOperator)
+}
+
+// ExitNegation is called when production negation is exited.
+func (s *queryListener) ExitNegation(ctx *parser.NegationContext) {
+	if s.err != nil {
+		return
+	}
+	s.operands = append(s.operands, ByNot(s.pop()))
+}
+
 // ExitUnivariate is called when production univariate is exited.
 func (s *queryListener) ExitUnivariate(ctx *parser.UnivariateContext) {
 	if s.err != nil {
@@ -81,11 +120,33 @@ func (s *queryListener) storeCriterion() error {
 	if err = criterion.Validate(); err != nil {
 		return err
 	}
-	s.result = append(s.result, criterion)
+	s.operands = append(s.operands, criterion)
 	s.rightOp = []string{}
 	return nil
 }
 
+// combine replaces the two topmost operands with a single criterion joining them with the logical operator
+func (s *queryListener) combine(operator Operator) {
+	right := s.pop()
+	left := s.pop()
+	var criteria []Criterion
+	for _, operand := range []Criterion{left, right} {
+		if operand.Operator == operator {
+			criteria = append(criteria, operand.Criteria...)
+		} else {
+			criteria = append(criteria, operand)
+		}
+	}
+	s.operands = append(s.operands, newLogicalCriterion(operator, criteria))
+}
+
+func (s *queryListener) pop() Criterion {
+	last := len(s.operands) - 1
+	criterion := s.operands[last]
+	s.operands = s.operands[:last]
+	return criterion
+}
+
 func (s *queryListener) ReportAmbiguity(recognizer antlr.Parser, dfa *antlr.DFA, startIndex, stopIndex int, exact bool, ambigAlts *antlr.BitSet, configs antlr.ATNConfigSet) {
 }
 
@@ -46,6 +46,13 @@ const (
 	EqualsOrNilOperator enOperator = "en"
 
 	NoOperator noOperator = "nop"
+
+	// AndOperator combines the nested criteria and tests if all of them are satisfied
+	AndOperator andOperator = "and"
+	// OrOperator combines the nested criteria and tests if at least one of them is satisfied
+	OrOperator orOperator = "or"
+	// NotOperator takes a single nested criterion and tests if it is not satisfied
+	NotOperator notOperator = "not"
 )
 
 type eqOperator string
@@ -299,3 +306,57 @@ func (noOperator) IsNullable() bool {
 func (noOperator) IsNumeric() bool {
 	return false
 }
+
+type andOperator string
+
+func (o andOperator) String() string {
+	return string(o)
+}
+
+func (andOperator) Type() OperatorType {
+	return LogicalOperator
+}
+
+func (andOperator) IsNullable() bool {
+	return false
+}
+
+func (andOperator) IsNumeric() bool {
+	return false
+}
+
+type orOperator string
+
+func (o orOperator) String() string {
+	return string(o)
+}
+
+func (orOperator) Type() OperatorType {
+	return LogicalOperator
+}
+
+func (orOperator) IsNullable() bool {
+	return false
+}
+
+func (orOperator) IsNumeric() bool {
+	return false
+}
+
+type notOperator string
+
+func (o notOperator) String() string {
+	return string(o)
+}
+
+func (notOperator) Type() OperatorType {
+	return LogicalOperator
+}
+
+func (notOperator) IsNullable() bool {
+	return false
+}
+
+func (notOperator) IsNumeric() bool {
+	return false
+}
@@ -27,11 +27,35 @@ func (s *BaseQueryListener) EnterExpression(ctx *ExpressionContext) {}
 // ExitExpression is called when production expression is exited.
 func (s *BaseQueryListener) ExitExpression(ctx *ExpressionContext) {}
 
-// EnterCriterions is called when production criterions is entered.
-func (s *BaseQueryListener) EnterCriterions(ctx *CriterionsContext) {}
+// EnterDisjunction is called when production disjunction is entered.
+func (s *BaseQueryListener) EnterDisjunction(ctx *DisjunctionContext) {}
 
-// ExitCriterions is called when production criterions is exited.
-func (s *BaseQueryListener) ExitCriterions(ctx *CriterionsContext) {}
+// ExitDisjunction is called when production disjunction is exited.
+func (s *BaseQueryListener) ExitDisjunction(ctx *DisjunctionContext) {}
+
+// EnterConjunction is called when production conjunction is entered.
+func (s *BaseQueryListener) EnterConjunction(ctx *ConjunctionContext) {}
+
+// ExitConjunction is called when production conjunction is exited.
+func (s *BaseQueryListener) ExitConjunction(ctx *ConjunctionContext) {}
+
+// EnterTerm is called when production term is entered.
+func (s *BaseQueryListener) EnterTerm(ctx *TermContext) {}
+
+// ExitTerm is called when production term is exited.
+func (s *BaseQueryListener) ExitTerm(ctx *TermContext) {}
+
+// EnterNegation is called when production negation is entered.
+func (s *BaseQueryListener) EnterNegation(ctx *NegationContext) {}
+
+// ExitNegation is called when production negation is exited.
+func (s *BaseQueryListener) ExitNegation(ctx *NegationContext) {}
+
+// EnterGroup is called when production group is entered.
+func (s *BaseQueryListener) EnterGroup(ctx *GroupContext) {}
+
+// ExitGroup is called when production group is exited.
+func (s *BaseQueryListener) ExitGroup(ctx *GroupContext) {}
 
 // EnterCriterion is called when production criterion is entered.
 func (s *BaseQueryListener) EnterCriterion(ctx *CriterionContext) {}
@@ -11,8 +11,20 @@ type QueryListener interface {
 	// EnterExpression is called when entering the expression production.
 	EnterExpression(c *ExpressionContext)
 
-	// EnterCriterions is called when entering the criterions production.
-	EnterCriterions(c *CriterionsContext)
+	// EnterDisjunction is called when entering the disjunction production.
+	EnterDisjunction(c *DisjunctionContext)
+
+	// EnterConjunction is called when entering the conjunction production.
+	EnterConjunction(c *ConjunctionContext)
+
+	// EnterTerm is called when entering the term production.
+	EnterTerm(c *TermContext)
+
+	// EnterNegation is called when entering the negation production.
+	EnterNegation(c *NegationContext)
+
+	// EnterGroup is called when entering the group production.
+	EnterGroup(c *GroupContext)
 
 	// EnterCriterion is called when entering the criterion production.
 	EnterCriterion(c *CriterionContext)
@@ -32,8 +44,20 @@ type QueryListener interface {
 	// ExitExpression is called when exiting the expression production.
 	ExitExpression(c *ExpressionContext)
 
-	// ExitCriterions is called when exiting the criterions production.
-	ExitCriterions(c *CriterionsContext)
+	// ExitDisjunction is called when exiting the disjunction production.
+	ExitDisjunction(c *DisjunctionContext)
+
+	// ExitConjunction is called when exiting the conjunction production.
+	ExitConjunction(c *ConjunctionContext)
+
+	// ExitTerm is called when exiting the term production.
+	ExitTerm(c *TermContext)
+
+	// ExitNegation is called when exiting the negation production.
+	ExitNegation(c *NegationContext)
+
+	// ExitGroup is called when exiting the group production.
+	ExitGroup(c *GroupContext)
 
 	// ExitCriterion is called when exiting the criterion production.
 	ExitCriterion(c *CriterionContext)
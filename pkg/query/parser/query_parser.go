@@ -16,41 +16,50 @@ var _ = reflect.Copy
 var _ = strconv.Itoa
 
 var parserATN = []uint16{
-	3, 24715, 42794, 33075, 47597, 16764, 15335, 30598, 22884, 3, 12, 52, 4,
+	3, 24715, 42794, 33075, 47597, 16764, 15335, 30598, 22884, 3, 14, 77, 4,
 	2, 9, 2, 4, 3, 9, 3, 4, 4, 9, 4, 4, 5, 9, 5, 4, 6, 9, 6, 4, 7, 9, 7, 4,
-	8, 9, 8, 3, 2, 3, 2, 3, 2, 3, 3, 3, 3, 3, 3, 5, 3, 23, 10, 3, 3, 4, 3,
-	4, 5, 4, 27, 10, 4, 3, 5, 3, 5, 3, 5, 3, 5, 3, 5, 3, 5, 3, 6, 3, 6, 3,
-	6, 3, 6, 3, 6, 3, 6, 3, 7, 3, 7, 5, 7, 43, 10, 7, 3, 7, 3, 7, 3, 8, 3,
-	8, 3, 8, 5, 8, 50, 10, 8, 3, 8, 2, 2, 9, 2, 4, 6, 8, 10, 12, 14, 2, 2,
-	2, 48, 2, 16, 3, 2, 2, 2, 4, 19, 3, 2, 2, 2, 6, 26, 3, 2, 2, 2, 8, 28,
-	3, 2, 2, 2, 10, 34, 3, 2, 2, 2, 12, 40, 3, 2, 2, 2, 14, 46, 3, 2, 2, 2,
-	16, 17, 5, 4, 3, 2, 17, 18, 7, 2, 2, 3, 18, 3, 3, 2, 2, 2, 19, 22, 5, 6,
-	4, 2, 20, 21, 7, 5, 2, 2, 21, 23, 5, 4, 3, 2, 22, 20, 3, 2, 2, 2, 22, 23,
-	3, 2, 2, 2, 23, 5, 3, 2, 2, 2, 24, 27, 5, 8, 5, 2, 25, 27, 5, 10, 6, 2,
-	26, 24, 3, 2, 2, 2, 26, 25, 3, 2, 2, 2, 27, 7, 3, 2, 2, 2, 28, 29, 7, 8,
-	2, 2, 29, 30, 7, 11, 2, 2, 30, 31, 7, 3, 2, 2, 31, 32, 7, 11, 2, 2, 32,
-	33, 5, 12, 7, 2, 33, 9, 3, 2, 2, 2, 34, 35, 7, 8, 2, 2, 35, 36, 7, 11,
-	2, 2, 36, 37, 7, 4, 2, 2, 37, 38, 7, 11, 2, 2, 38, 39, 7, 6, 2, 2, 39,
-	11, 3, 2, 2, 2, 40, 42, 7, 9, 2, 2, 41, 43, 5, 14, 8, 2, 42, 41, 3, 2,
-	2, 2, 42, 43, 3, 2, 2, 2, 43, 44, 3, 2, 2, 2, 44, 45, 7, 10, 2, 2, 45,
-	13, 3, 2, 2, 2, 46, 49, 7, 6, 2, 2, 47, 48, 7, 7, 2, 2, 48, 50, 5, 14,
-	8, 2, 49, 47, 3, 2, 2, 2, 49, 50, 3, 2, 2, 2, 50, 15, 3, 2, 2, 2, 6, 22,
-	26, 42, 49,
+	8, 9, 8, 4, 9, 9, 9, 4, 10, 9, 10, 4, 11, 9, 11, 4, 12, 9, 12, 3, 2, 3,
+	2, 3, 2, 3, 3, 3, 3, 3, 3, 5, 3, 31, 10, 3, 3, 4, 3, 4, 3, 4, 5, 4, 36,
+	10, 4, 3, 5, 3, 5, 3, 5, 5, 5, 41, 10, 5, 3, 6, 3, 6, 3, 6, 3, 7, 3, 7,
+	3, 7, 3, 7, 3, 8, 3, 8, 5, 8, 52, 10, 8, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9,
+	3, 9, 3, 10, 3, 10, 3, 10, 3, 10, 3, 10, 3, 10, 3, 11, 3, 11, 5, 11, 68,
+	10, 11, 3, 11, 3, 11, 3, 12, 3, 12, 3, 12, 5, 12, 75, 10, 12, 3, 12, 2,
+	2, 13, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22, 2, 2, 2, 72, 2, 24, 3, 2,
+	2, 2, 4, 27, 3, 2, 2, 2, 6, 32, 3, 2, 2, 2, 8, 40, 3, 2, 2, 2, 10, 42,
+	3, 2, 2, 2, 12, 45, 3, 2, 2, 2, 14, 51, 3, 2, 2, 2, 16, 53, 3, 2, 2, 2,
+	18, 59, 3, 2, 2, 2, 20, 65, 3, 2, 2, 2, 22, 71, 3, 2, 2, 2, 24, 25, 5,
+	4, 3, 2, 25, 26, 7, 2, 2, 3, 26, 3, 3, 2, 2, 2, 27, 30, 5, 6, 4, 2, 28,
+	29, 7, 6, 2, 2, 29, 31, 5, 4, 3, 2, 30, 28, 3, 2, 2, 2, 30, 31, 3, 2, 2,
+	2, 31, 5, 3, 2, 2, 2, 32, 35, 5, 8, 5, 2, 33, 34, 7, 5, 2, 2, 34, 36, 5,
+	6, 4, 2, 35, 33, 3, 2, 2, 2, 35, 36, 3, 2, 2, 2, 36, 7, 3, 2, 2, 2, 37,
+	41, 5, 10, 6, 2, 38, 41, 5, 12, 7, 2, 39, 41, 5, 14, 8, 2, 40, 37, 3, 2,
+	2, 2, 40, 38, 3, 2, 2, 2, 40, 39, 3, 2, 2, 2, 41, 9, 3, 2, 2, 2, 42, 43,
+	7, 7, 2, 2, 43, 44, 5, 8, 5, 2, 44, 11, 3, 2, 2, 2, 45, 46, 7, 11, 2, 2,
+	46, 47, 5, 4, 3, 2, 47, 48, 7, 12, 2, 2, 48, 13, 3, 2, 2, 2, 49, 52, 5,
+	16, 9, 2, 50, 52, 5, 18, 10, 2, 51, 49, 3, 2, 2, 2, 51, 50, 3, 2, 2, 2,
+	52, 15, 3, 2, 2, 2, 53, 54, 7, 10, 2, 2, 54, 55, 7, 13, 2, 2, 55, 56, 7,
+	3, 2, 2, 56, 57, 7, 13, 2, 2, 57, 58, 5, 20, 11, 2, 58, 17, 3, 2, 2, 2,
+	59, 60, 7, 10, 2, 2, 60, 61, 7, 13, 2, 2, 61, 62, 7, 4, 2, 2, 62, 63, 7,
+	13, 2, 2, 63, 64, 7, 8, 2, 2, 64, 19, 3, 2, 2, 2, 65, 67, 7, 11, 2, 2,
+	66, 68, 5, 22, 12, 2, 67, 66, 3, 2, 2, 2, 67, 68, 3, 2, 2, 2, 68, 69, 3,
+	2, 2, 2, 69, 70, 7, 12, 2, 2, 70, 21, 3, 2, 2, 2, 71, 74, 7, 8, 2, 2, 72,
+	73, 7, 9, 2, 2, 73, 75, 5, 22, 12, 2, 74, 72, 3, 2, 2, 2, 74, 75, 3, 2,
+	2, 2, 75, 23, 3, 2, 2, 2, 8, 30, 35, 40, 51, 67, 74,
 }
 var deserializer = antlr.NewATNDeserializer(nil)
 var deserializedATN = deserializer.DeserializeFromUInt16(parserATN)
 
 var literalNames = []string{
-	"", "", "", "", "", "", "", "'('", "')'", "' '",
+	"", "", "", "", "", "", "", "", "", "'('", "')'", "' '",
 }
 var symbolicNames = []string{
-	"", "MultiOp", "UniOp", "Concat", "Value", "ValueSeparator", "Key", "OpenBracket",
-	"CloseBracket", "Whitespace", "WS",
+	"", "MultiOp", "UniOp", "Concat", "Or", "Not", "Value", "ValueSeparator",
+	"Key", "OpenBracket", "CloseBracket", "Whitespace", "WS",
 }
 
 var ruleNames = []string{
-	"expression", "criterions", "criterion", "multivariate", "univariate",
-	"multiValues", "manyValues",
+	"expression", "disjunction", "conjunction", "term", "negation", "group",
+	"criterion", "multivariate", "univariate", "multiValues", "manyValues",
 }
 var decisionToDFA = make([]*antlr.DFA, len(deserializedATN.DecisionToState))
 
@@ -84,24 +93,30 @@ const (
 	QueryParserMultiOp        = 1
 	QueryParserUniOp          = 2
 	QueryParserConcat         = 3
-	QueryParserValue          = 4
-	QueryParserValueSeparator = 5
-	QueryParserKey            = 6
-	QueryParserOpenBracket    = 7
-	QueryParserCloseBracket   = 8
-	QueryParserWhitespace     = 9
-	QueryParserWS             = 10
+	QueryParserOr             = 4
+	QueryParserNot            = 5
+	QueryParserValue          = 6
+	QueryParserValueSeparator = 7
+	QueryParserKey            = 8
+	QueryParserOpenBracket    = 9
+	QueryParserCloseBracket   = 10
+	QueryParserWhitespace     = 11
+	QueryParserWS             = 12
 )
 
 // QueryParser rules.
 const (
 	QueryParserRULE_expression   = 0
-	QueryParserRULE_criterions   = 1
-	QueryParserRULE_criterion    = 2
-	QueryParserRULE_multivariate = 3
-	QueryParserRULE_univariate   = 4
-	QueryParserRULE_multiValues  = 5
-	QueryParserRULE_manyValues   = 6
+	QueryParserRULE_disjunction  = 1
+	QueryParserRULE_conjunction  = 2
+	QueryParserRULE_term         = 3
+	QueryParserRULE_negation     = 4
+	QueryParserRULE_group        = 5
+	QueryParserRULE_criterion    = 6
+	QueryParserRULE_multivariate = 7
+	QueryParserRULE_univariate   = 8
+	QueryParserRULE_multiValues  = 9
+	QueryParserRULE_manyValues   = 10
 )
 
 // IExpressionContext is an interface to support dynamic dispatch.
@@ -142,14 +157,14 @@ func NewExpressionContext(parser antlr.Parser, parent antlr.ParserRuleContext, i
 
 func (s *ExpressionContext) GetParser() antlr.Parser { return s.parser }
 
-func (s *ExpressionContext) Criterions() ICriterionsContext {
-	var t = s.GetTypedRuleContext(reflect.TypeOf((*ICriterionsContext)(nil)).Elem(), 0)
+func (s *ExpressionContext) Disjunction() IDisjunctionContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*IDisjunctionContext)(nil)).Elem(), 0)
 
 	if t == nil {
 		return nil
 	}
 
-	return t.(ICriterionsContext)
+	return t.(IDisjunctionContext)
 }
 
 func (s *ExpressionContext) EOF() antlr.TerminalNode {
@@ -198,102 +213,229 @@ func (p *QueryParser) Expression() (localctx IExpressionContext) {
 
 	p.EnterOuterAlt(localctx, 1)
 	{
-		p.SetState(14)
-		p.Criterions()
+		p.SetState(22)
+		p.Disjunction()
 	}
 	{
-		p.SetState(15)
+		p.SetState(23)
 		p.Match(QueryParserEOF)
 	}
 
 	return localctx
 }
 
-// ICriterionsContext is an interface to support dynamic dispatch.
-type ICriterionsContext interface {
+// IDisjunctionContext is an interface to support dynamic dispatch.
+type IDisjunctionContext interface {
 	antlr.ParserRuleContext
 
 	// GetParser returns the parser.
 	GetParser() antlr.Parser
 
-	// IsCriterionsContext differentiates from other interfaces.
-	IsCriterionsContext()
+	// IsDisjunctionContext differentiates from other interfaces.
+	IsDisjunctionContext()
 }
 
-type CriterionsContext struct {
+type DisjunctionContext struct {
 	*antlr.BaseParserRuleContext
 	parser antlr.Parser
 }
 
-func NewEmptyCriterionsContext() *CriterionsContext {
-	var p = new(CriterionsContext)
+func NewEmptyDisjunctionContext() *DisjunctionContext {
+	var p = new(DisjunctionContext)
 	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(nil, -1)
-	p.RuleIndex = QueryParserRULE_criterions
+	p.RuleIndex = QueryParserRULE_disjunction
 	return p
 }
 
-func (*CriterionsContext) IsCriterionsContext() {}
+func (*DisjunctionContext) IsDisjunctionContext() {}
 
-func NewCriterionsContext(parser antlr.Parser, parent antlr.ParserRuleContext, invokingState int) *CriterionsContext {
-	var p = new(CriterionsContext)
+func NewDisjunctionContext(parser antlr.Parser, parent antlr.ParserRuleContext, invokingState int) *DisjunctionContext {
+	var p = new(DisjunctionContext)
 
 	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(parent, invokingState)
 
 	p.parser = parser
-	p.RuleIndex = QueryParserRULE_criterions
+	p.RuleIndex = QueryParserRULE_disjunction
 
 	return p
 }
 
-func (s *CriterionsContext) GetParser() antlr.Parser { return s.parser }
+func (s *DisjunctionContext) GetParser() antlr.Parser { return s.parser }
 
-func (s *CriterionsContext) Criterion() ICriterionContext {
-	var t = s.GetTypedRuleContext(reflect.TypeOf((*ICriterionContext)(nil)).Elem(), 0)
+func (s *DisjunctionContext) Conjunction() IConjunctionContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*IConjunctionContext)(nil)).Elem(), 0)
 
 	if t == nil {
 		return nil
 	}
 
-	return t.(ICriterionContext)
+	return t.(IConjunctionContext)
+}
+
+func (s *DisjunctionContext) Or() antlr.TerminalNode {
+	return s.GetToken(QueryParserOr, 0)
+}
+
+func (s *DisjunctionContext) Disjunction() IDisjunctionContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*IDisjunctionContext)(nil)).Elem(), 0)
+
+	if t == nil {
+		return nil
+	}
+
+	return t.(IDisjunctionContext)
+}
+
+func (s *DisjunctionContext) GetRuleContext() antlr.RuleContext {
+	return s
+}
+
+func (s *DisjunctionContext) ToStringTree(ruleNames []string, recog antlr.Recognizer) string {
+	return antlr.TreesStringTree(s, ruleNames, recog)
+}
+
+func (s *DisjunctionContext) EnterRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(QueryListener); ok {
+		listenerT.EnterDisjunction(s)
+	}
+}
+
+func (s *DisjunctionContext) ExitRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(QueryListener); ok {
+		listenerT.ExitDisjunction(s)
+	}
+}
+
+func (p *QueryParser) Disjunction() (localctx IDisjunctionContext) {
+	localctx = NewDisjunctionContext(p, p.GetParserRuleContext(), p.GetState())
+	p.EnterRule(localctx, 2, QueryParserRULE_disjunction)
+	var _la int
+
+	defer func() {
+		p.ExitRule()
+	}()
+
+	defer func() {
+		if err := recover(); err != nil {
+			if v, ok := err.(antlr.RecognitionException); ok {
+				localctx.SetException(v)
+				p.GetErrorHandler().ReportError(p, v)
+				p.GetErrorHandler().Recover(p, v)
+			} else {
+				panic(err)
+			}
+		}
+	}()
+
+	p.EnterOuterAlt(localctx, 1)
+	{
+		p.SetState(25)
+		p.Conjunction()
+	}
+	p.SetState(28)
+	p.GetErrorHandler().Sync(p)
+	_la = p.GetTokenStream().LA(1)
+
+	if _la == QueryParserOr {
+		{
+			p.SetState(26)
+			p.Match(QueryParserOr)
+		}
+		{
+			p.SetState(27)
+			p.Disjunction()
+		}
+
+	}
+
+	return localctx
+}
+
+// IConjunctionContext is an interface to support dynamic dispatch.
+type IConjunctionContext interface {
+	antlr.ParserRuleContext
+
+	// GetParser returns the parser.
+	GetParser() antlr.Parser
+
+	// IsConjunctionContext differentiates from other interfaces.
+	IsConjunctionContext()
+}
+
+type ConjunctionContext struct {
+	*antlr.BaseParserRuleContext
+	parser antlr.Parser
+}
+
+func NewEmptyConjunctionContext() *ConjunctionContext {
+	var p = new(ConjunctionContext)
+	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(nil, -1)
+	p.RuleIndex = QueryParserRULE_conjunction
+	return p
+}
+
+func (*ConjunctionContext) IsConjunctionContext() {}
+
+func NewConjunctionContext(parser antlr.Parser, parent antlr.ParserRuleContext, invokingState int) *ConjunctionContext {
+	var p = new(ConjunctionContext)
+
+	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(parent, invokingState)
+
+	p.parser = parser
+	p.RuleIndex = QueryParserRULE_conjunction
+
+	return p
+}
+
+func (s *ConjunctionContext) GetParser() antlr.Parser { return s.parser }
+
+func (s *ConjunctionContext) Term() ITermContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*ITermContext)(nil)).Elem(), 0)
+
+	if t == nil {
+		return nil
+	}
+
+	return t.(ITermContext)
 }
 
-func (s *CriterionsContext) Concat() antlr.TerminalNode {
+func (s *ConjunctionContext) Concat() antlr.TerminalNode {
 	return s.GetToken(QueryParserConcat, 0)
 }
 
-func (s *CriterionsContext) Criterions() ICriterionsContext {
-	var t = s.GetTypedRuleContext(reflect.TypeOf((*ICriterionsContext)(nil)).Elem(), 0)
+func (s *ConjunctionContext) Conjunction() IConjunctionContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*IConjunctionContext)(nil)).Elem(), 0)
 
 	if t == nil {
 		return nil
 	}
 
-	return t.(ICriterionsContext)
+	return t.(IConjunctionContext)
 }
 
-func (s *CriterionsContext) GetRuleContext() antlr.RuleContext {
+func (s *ConjunctionContext) GetRuleContext() antlr.RuleContext {
 	return s
 }
 
-func (s *CriterionsContext) ToStringTree(ruleNames []string, recog antlr.Recognizer) string {
+func (s *ConjunctionContext) ToStringTree(ruleNames []string, recog antlr.Recognizer) string {
 	return antlr.TreesStringTree(s, ruleNames, recog)
 }
 
-func (s *CriterionsContext) EnterRule(listener antlr.ParseTreeListener) {
+func (s *ConjunctionContext) EnterRule(listener antlr.ParseTreeListener) {
 	if listenerT, ok := listener.(QueryListener); ok {
-		listenerT.EnterCriterions(s)
+		listenerT.EnterConjunction(s)
 	}
 }
 
-func (s *CriterionsContext) ExitRule(listener antlr.ParseTreeListener) {
+func (s *ConjunctionContext) ExitRule(listener antlr.ParseTreeListener) {
 	if listenerT, ok := listener.(QueryListener); ok {
-		listenerT.ExitCriterions(s)
+		listenerT.ExitConjunction(s)
 	}
 }
 
-func (p *QueryParser) Criterions() (localctx ICriterionsContext) {
-	localctx = NewCriterionsContext(p, p.GetParserRuleContext(), p.GetState())
-	p.EnterRule(localctx, 2, QueryParserRULE_criterions)
+func (p *QueryParser) Conjunction() (localctx IConjunctionContext) {
+	localctx = NewConjunctionContext(p, p.GetParserRuleContext(), p.GetState())
+	p.EnterRule(localctx, 4, QueryParserRULE_conjunction)
 	var _la int
 
 	defer func() {
@@ -314,23 +456,381 @@ func (p *QueryParser) Criterions() (localctx ICriterionsContext) {
 
 	p.EnterOuterAlt(localctx, 1)
 	{
-		p.SetState(17)
-		p.Criterion()
+		p.SetState(30)
+		p.Term()
 	}
-	p.SetState(20)
+	p.SetState(33)
 	p.GetErrorHandler().Sync(p)
 	_la = p.GetTokenStream().LA(1)
 
 	if _la == QueryParserConcat {
 		{
-			p.SetState(18)
+			p.SetState(31)
 			p.Match(QueryParserConcat)
 		}
 		{
-			p.SetState(19)
-			p.Criterions()
+			p.SetState(32)
+			p.Conjunction()
+		}
+
+	}
+
+	return localctx
+}
+
+// ITermContext is an interface to support dynamic dispatch.
+type ITermContext interface {
+	antlr.ParserRuleContext
+
+	// GetParser returns the parser.
+	GetParser() antlr.Parser
+
+	// IsTermContext differentiates from other interfaces.
+	IsTermContext()
+}
+
+type TermContext struct {
+	*antlr.BaseParserRuleContext
+	parser antlr.Parser
+}
+
+func NewEmptyTermContext() *TermContext {
+	var p = new(TermContext)
+	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(nil, -1)
+	p.RuleIndex = QueryParserRULE_term
+	return p
+}
+
+func (*TermContext) IsTermContext() {}
+
+func NewTermContext(parser antlr.Parser, parent antlr.ParserRuleContext, invokingState int) *TermContext {
+	var p = new(TermContext)
+
+	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(parent, invokingState)
+
+	p.parser = parser
+	p.RuleIndex = QueryParserRULE_term
+
+	return p
+}
+
+func (s *TermContext) GetParser() antlr.Parser { return s.parser }
+
+func (s *TermContext) Negation() INegationContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*INegationContext)(nil)).Elem(), 0)
+
+	if t == nil {
+		return nil
+	}
+
+	return t.(INegationContext)
+}
+
+func (s *TermContext) Group() IGroupContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*IGroupContext)(nil)).Elem(), 0)
+
+	if t == nil {
+		return nil
+	}
+
+	return t.(IGroupContext)
+}
+
+func (s *TermContext) Criterion() ICriterionContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*ICriterionContext)(nil)).Elem(), 0)
+
+	if t == nil {
+		return nil
+	}
+
+	return t.(ICriterionContext)
+}
+
+func (s *TermContext) GetRuleContext() antlr.RuleContext {
+	return s
+}
+
+func (s *TermContext) ToStringTree(ruleNames []string, recog antlr.Recognizer) string {
+	return antlr.TreesStringTree(s, ruleNames, recog)
+}
+
+func (s *TermContext) EnterRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(QueryListener); ok {
+		listenerT.EnterTerm(s)
+	}
+}
+
+func (s *TermContext) ExitRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(QueryListener); ok {
+		listenerT.ExitTerm(s)
+	}
+}
+
+func (p *QueryParser) Term() (localctx ITermContext) {
+	localctx = NewTermContext(p, p.GetParserRuleContext(), p.GetState())
+	p.EnterRule(localctx, 6, QueryParserRULE_term)
+
+	defer func() {
+		p.ExitRule()
+	}()
+
+	defer func() {
+		if err := recover(); err != nil {
+			if v, ok := err.(antlr.RecognitionException); ok {
+				localctx.SetException(v)
+				p.GetErrorHandler().ReportError(p, v)
+				p.GetErrorHandler().Recover(p, v)
+			} else {
+				panic(err)
+			}
+		}
+	}()
+
+	p.SetState(38)
+	p.GetErrorHandler().Sync(p)
+
+	switch p.GetTokenStream().LA(1) {
+	case QueryParserNot:
+		p.EnterOuterAlt(localctx, 1)
+		{
+			p.SetState(35)
+			p.Negation()
+		}
+
+	case QueryParserOpenBracket:
+		p.EnterOuterAlt(localctx, 2)
+		{
+			p.SetState(36)
+			p.Group()
+		}
+
+	case QueryParserKey:
+		p.EnterOuterAlt(localctx, 3)
+		{
+			p.SetState(37)
+			p.Criterion()
+		}
+
+	default:
+		panic(antlr.NewNoViableAltException(p, nil, nil, nil, nil, nil))
+	}
+
+	return localctx
+}
+
+// INegationContext is an interface to support dynamic dispatch.
+type INegationContext interface {
+	antlr.ParserRuleContext
+
+	// GetParser returns the parser.
+	GetParser() antlr.Parser
+
+	// IsNegationContext differentiates from other interfaces.
+	IsNegationContext()
+}
+
+type NegationContext struct {
+	*antlr.BaseParserRuleContext
+	parser antlr.Parser
+}
+
+func NewEmptyNegationContext() *NegationContext {
+	var p = new(NegationContext)
+	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(nil, -1)
+	p.RuleIndex = QueryParserRULE_negation
+	return p
+}
+
+func (*NegationContext) IsNegationContext() {}
+
+func NewNegationContext(parser antlr.Parser, parent antlr.ParserRuleContext, invokingState int) *NegationContext {
+	var p = new(NegationContext)
+
+	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(parent, invokingState)
+
+	p.parser = parser
+	p.RuleIndex = QueryParserRULE_negation
+
+	return p
+}
+
+func (s *NegationContext) GetParser() antlr.Parser { return s.parser }
+
+func (s *NegationContext) Not() antlr.TerminalNode {
+	return s.GetToken(QueryParserNot, 0)
+}
+
+func (s *NegationContext) Term() ITermContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*ITermContext)(nil)).Elem(), 0)
+
+	if t == nil {
+		return nil
+	}
+
+	return t.(ITermContext)
+}
+
+func (s *NegationContext) GetRuleContext() antlr.RuleContext {
+	return s
+}
+
+func (s *NegationContext) ToStringTree(ruleNames []string, recog antlr.Recognizer) string {
+	return antlr.TreesStringTree(s, ruleNames, recog)
+}
+
+func (s *NegationContext) EnterRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(QueryListener); ok {
+		listenerT.EnterNegation(s)
+	}
+}
+
+func (s *NegationContext) ExitRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(QueryListener); ok {
+		listenerT.ExitNegation(s)
+	}
+}
+
+func (p *QueryParser) Negation() (localctx INegationContext) {
+	localctx = NewNegationContext(p, p.GetParserRuleContext(), p.GetState())
+	p.EnterRule(localctx, 8, QueryParserRULE_negation)
+
+	defer func() {
+		p.ExitRule()
+	}()
+
+	defer func() {
+		if err := recover(); err != nil {
+			if v, ok := err.(antlr.RecognitionException); ok {
+				localctx.SetException(v)
+				p.GetErrorHandler().ReportError(p, v)
+				p.GetErrorHandler().Recover(p, v)
+			} else {
+				panic(err)
+			}
+		}
+	}()
+
+	p.EnterOuterAlt(localctx, 1)
+	{
+		p.SetState(40)
+		p.Match(QueryParserNot)
+	}
+	{
+		p.SetState(41)
+		p.Term()
+	}
+
+	return localctx
+}
+
+// IGroupContext is an interface to support dynamic dispatch.
+type IGroupContext interface {
+	antlr.ParserRuleContext
+
+	// GetParser returns the parser.
+	GetParser() antlr.Parser
+
+	// IsGroupContext differentiates from other interfaces.
+	IsGroupContext()
+}
+
+type GroupContext struct {
+	*antlr.BaseParserRuleContext
+	parser antlr.Parser
+}
+
+func NewEmptyGroupContext() *GroupContext {
+	var p = new(GroupContext)
+	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(nil, -1)
+	p.RuleIndex = QueryParserRULE_group
+	return p
+}
+
+func (*GroupContext) IsGroupContext() {}
+
+func NewGroupContext(parser antlr.Parser, parent antlr.ParserRuleContext, invokingState int) *GroupContext {
+	var p = new(GroupContext)
+
+	p.BaseParserRuleContext = antlr.NewBaseParserRuleContext(parent, invokingState)
+
+	p.parser = parser
+	p.RuleIndex = QueryParserRULE_group
+
+	return p
+}
+
+func (s *GroupContext) GetParser() antlr.Parser { return s.parser }
+
+func (s *GroupContext) OpenBracket() antlr.TerminalNode {
+	return s.GetToken(QueryParserOpenBracket, 0)
+}
+
+func (s *GroupContext) Disjunction() IDisjunctionContext {
+	var t = s.GetTypedRuleContext(reflect.TypeOf((*IDisjunctionContext)(nil)).Elem(), 0)
+
+	if t == nil {
+		return nil
+	}
+
+	return t.(IDisjunctionContext)
+}
+
+func (s *GroupContext) CloseBracket() antlr.TerminalNode {
+	return s.GetToken(QueryParserCloseBracket, 0)
+}
+
+func (s *GroupContext) GetRuleContext() antlr.RuleContext {
+	return s
+}
+
+func (s *GroupContext) ToStringTree(ruleNames []string, recog antlr.Recognizer) string {
+	return antlr.TreesStringTree(s, ruleNames, recog)
+}
+
+func (s *GroupContext) EnterRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(QueryListener); ok {
+		listenerT.EnterGroup(s)
+	}
+}
+
+func (s *GroupContext) ExitRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(QueryListener); ok {
+		listenerT.ExitGroup(s)
+	}
+}
+
+func (p *QueryParser) Group() (localctx IGroupContext) {
+	localctx = NewGroupContext(p, p.GetParserRuleContext(), p.GetState())
+	p.EnterRule(localctx, 10, QueryParserRULE_group)
+
+	defer func() {
+		p.ExitRule()
+	}()
+
+	defer func() {
+		if err := recover(); err != nil {
+			if v, ok := err.(antlr.RecognitionException); ok {
+				localctx.SetException(v)
+				p.GetErrorHandler().ReportError(p, v)
+				p.GetErrorHandler().Recover(p, v)
+			} else {
+				panic(err)
+			}
 		}
+	}()
 
+	p.EnterOuterAlt(localctx, 1)
+	{
+		p.SetState(43)
+		p.Match(QueryParserOpenBracket)
+	}
+	{
+		p.SetState(44)
+		p.Disjunction()
+	}
+	{
+		p.SetState(45)
+		p.Match(QueryParserCloseBracket)
 	}
 
 	return localctx
@@ -416,7 +916,7 @@ func (s *CriterionContext) ExitRule(listener antlr.ParseTreeListener) {
 
 func (p *QueryParser) Criterion() (localctx ICriterionContext) {
 	localctx = NewCriterionContext(p, p.GetParserRuleContext(), p.GetState())
-	p.EnterRule(localctx, 4, QueryParserRULE_criterion)
+	p.EnterRule(localctx, 12, QueryParserRULE_criterion)
 
 	defer func() {
 		p.ExitRule()
@@ -434,20 +934,20 @@ func (p *QueryParser) Criterion() (localctx ICriterionContext) {
 		}
 	}()
 
-	p.SetState(24)
+	p.SetState(49)
 	p.GetErrorHandler().Sync(p)
-	switch p.GetInterpreter().AdaptivePredict(p.GetTokenStream(), 1, p.GetParserRuleContext()) {
+	switch p.GetInterpreter().AdaptivePredict(p.GetTokenStream(), 3, p.GetParserRuleContext()) {
 	case 1:
 		p.EnterOuterAlt(localctx, 1)
 		{
-			p.SetState(22)
+			p.SetState(47)
 			p.Multivariate()
 		}
 
 	case 2:
 		p.EnterOuterAlt(localctx, 2)
 		{
-			p.SetState(23)
+			p.SetState(48)
 			p.Univariate()
 		}
 
@@ -542,7 +1042,7 @@ func (s *MultivariateContext) ExitRule(listener antlr.ParseTreeListener) {
 
 func (p *QueryParser) Multivariate() (localctx IMultivariateContext) {
 	localctx = NewMultivariateContext(p, p.GetParserRuleContext(), p.GetState())
-	p.EnterRule(localctx, 6, QueryParserRULE_multivariate)
+	p.EnterRule(localctx, 14, QueryParserRULE_multivariate)
 
 	defer func() {
 		p.ExitRule()
@@ -562,23 +1062,23 @@ func (p *QueryParser) Multivariate() (localctx IMultivariateContext) {
 
 	p.EnterOuterAlt(localctx, 1)
 	{
-		p.SetState(26)
+		p.SetState(51)
 		p.Match(QueryParserKey)
 	}
 	{
-		p.SetState(27)
+		p.SetState(52)
 		p.Match(QueryParserWhitespace)
 	}
 	{
-		p.SetState(28)
+		p.SetState(53)
 		p.Match(QueryParserMultiOp)
 	}
 	{
-		p.SetState(29)
+		p.SetState(54)
 		p.Match(QueryParserWhitespace)
 	}
 	{
-		p.SetState(30)
+		p.SetState(55)
 		p.MultiValues()
 	}
 
@@ -665,7 +1165,7 @@ func (s *UnivariateContext) ExitRule(listener antlr.ParseTreeListener) {
 
 func (p *QueryParser) Univariate() (localctx IUnivariateContext) {
 	localctx = NewUnivariateContext(p, p.GetParserRuleContext(), p.GetState())
-	p.EnterRule(localctx, 8, QueryParserRULE_univariate)
+	p.EnterRule(localctx, 16, QueryParserRULE_univariate)
 
 	defer func() {
 		p.ExitRule()
@@ -685,23 +1185,23 @@ func (p *QueryParser) Univariate() (localctx IUnivariateContext) {
 
 	p.EnterOuterAlt(localctx, 1)
 	{
-		p.SetState(32)
+		p.SetState(57)
 		p.Match(QueryParserKey)
 	}
 	{
-		p.SetState(33)
+		p.SetState(58)
 		p.Match(QueryParserWhitespace)
 	}
 	{
-		p.SetState(34)
+		p.SetState(59)
 		p.Match(QueryParserUniOp)
 	}
 	{
-		p.SetState(35)
+		p.SetState(60)
 		p.Match(QueryParserWhitespace)
 	}
 	{
-		p.SetState(36)
+		p.SetState(61)
 		p.Match(QueryParserValue)
 	}
 
@@ -786,7 +1286,7 @@ func (s *MultiValuesContext) ExitRule(listener antlr.ParseTreeListener) {
 
 func (p *QueryParser) MultiValues() (localctx IMultiValuesContext) {
 	localctx = NewMultiValuesContext(p, p.GetParserRuleContext(), p.GetState())
-	p.EnterRule(localctx, 10, QueryParserRULE_multiValues)
+	p.EnterRule(localctx, 18, QueryParserRULE_multiValues)
 	var _la int
 
 	defer func() {
@@ -807,22 +1307,22 @@ func (p *QueryParser) MultiValues() (localctx IMultiValuesContext) {
 
 	p.EnterOuterAlt(localctx, 1)
 	{
-		p.SetState(38)
+		p.SetState(63)
 		p.Match(QueryParserOpenBracket)
 	}
-	p.SetState(40)
+	p.SetState(65)
 	p.GetErrorHandler().Sync(p)
 	_la = p.GetTokenStream().LA(1)
 
 	if _la == QueryParserValue {
 		{
-			p.SetState(39)
+			p.SetState(64)
 			p.ManyValues()
 		}
 
 	}
 	{
-		p.SetState(42)
+		p.SetState(67)
 		p.Match(QueryParserCloseBracket)
 	}
 
@@ -907,7 +1407,7 @@ func (s *ManyValuesContext) ExitRule(listener antlr.ParseTreeListener) {
 
 func (p *QueryParser) ManyValues() (localctx IManyValuesContext) {
 	localctx = NewManyValuesContext(p, p.GetParserRuleContext(), p.GetState())
-	p.EnterRule(localctx, 12, QueryParserRULE_manyValues)
+	p.EnterRule(localctx, 20, QueryParserRULE_manyValues)
 	var _la int
 
 	defer func() {
@@ -928,20 +1428,20 @@ func (p *QueryParser) ManyValues() (localctx IManyValuesContext) {
 
 	p.EnterOuterAlt(localctx, 1)
 	{
-		p.SetState(44)
+		p.SetState(69)
 		p.Match(QueryParserValue)
 	}
-	p.SetState(47)
+	p.SetState(72)
 	p.GetErrorHandler().Sync(p)
 	_la = p.GetTokenStream().LA(1)
 
 	if _la == QueryParserValueSeparator {
 		{
-			p.SetState(45)
+			p.SetState(70)
 			p.Match(QueryParserValueSeparator)
 		}
 		{
-			p.SetState(46)
+			p.SetState(71)
 			p.ManyValues()
 		}
 
@@ -60,6 +60,8 @@ const (
 	UnivariateOperator OperatorType = "univariate"
 	// MultivariateOperator denotes that the operator expects more than one variable on the right side
 	MultivariateOperator OperatorType = "multivariate"
+	// LogicalOperator denotes that the operator combines the nested criteria instead of comparing operands
+	LogicalOperator OperatorType = "logical"
 )
 
 // OrderType is the type of the order in which result is presented
@@ -77,6 +79,8 @@ const (
 	OrderBy string = "orderBy"
 	// Limit should be used as a left operand in Criterion to signify the
 	Limit string = "limit"
+	// After should be used as a left operand in Criterion to signify the position in the result order after which the result starts
+	After string = "after"
 )
 
 var (
@@ -113,6 +117,8 @@ type Criterion struct {
 	RightOp []string
 	// Type is the type of the query
 	Type CriterionType
+	// Criteria are the nested criteria combined by a logical operator
+	Criteria []Criterion
 }
 
 // ByField constructs a new criterion for field querying
@@ -142,18 +148,86 @@ func OrderResultBy(field string, orderType OrderType) Criterion {
 	return NewCriterion(OrderBy, NoOperator, []string{field, string(orderType)}, ResultQuery)
 }
 
+// OrderResultByExpression constructs criteria for result order from an expression such as "name desc,created_at".
+// The order type of each field is asc or desc and defaults to asc. Ordering by labels is not supported.
+func OrderResultByExpression(expression string) ([]Criterion, error) {
+	var criteria []Criterion
+	for _, rule := range strings.Split(expression, ",") {
+		parts := strings.Fields(rule)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("order by rule \"%s\" is invalid. Expected a field name optionally followed by asc or desc", strings.TrimSpace(rule))}
+		}
+		field := parts[0]
+		if strings.Contains(field, "/") {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("ordering by %s is not supported. Only fields of the resource can be used for ordering", field)}
+		}
+		orderType := AscOrder
+		if len(parts) == 2 {
+			orderType = OrderType(strings.ToUpper(parts[1]))
+			if orderType != AscOrder && orderType != DescOrder {
+				return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("order type %s is invalid. Expected asc or desc", parts[1])}
+			}
+		}
+		for _, c := range criteria {
+			if c.RightOp[0] == field {
+				return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("field %s is used more than once for ordering", field)}
+			}
+		}
+		criteria = append(criteria, OrderResultBy(field, orderType))
+	}
+	return criteria, nil
+}
+
+// ResultAfter constructs a new criterion which starts the result after the row with the provided values
+// of the fields by which the result is ordered. The values are expected in the order of the order by criteria.
+func ResultAfter(values ...string) Criterion {
+	return NewCriterion(After, NoOperator, values, ResultQuery)
+}
+
 // LimitResultBy constructs a new criterion for limit result with
 func LimitResultBy(limit int) Criterion {
 	limitString := strconv.Itoa(limit)
 	return NewCriterion(Limit, NoOperator, []string{limitString}, ResultQuery)
 }
 
+// ByAll constructs a new criterion which is satisfied when all of the provided criteria are satisfied
+func ByAll(criteria ...Criterion) Criterion {
+	return newLogicalCriterion(AndOperator, criteria)
+}
+
+// ByAny constructs a new criterion which is satisfied when at least one of the provided criteria is satisfied
+func ByAny(criteria ...Criterion) Criterion {
+	return newLogicalCriterion(OrOperator, criteria)
+}
+
+// ByNot constructs a new criterion which is satisfied when the provided criterion is not satisfied
+func ByNot(criterion Criterion) Criterion {
+	return newLogicalCriterion(NotOperator, []Criterion{criterion})
+}
+
+func newLogicalCriterion(operator Operator, criteria []Criterion) Criterion {
+	var criterionType CriterionType
+	if len(criteria) > 0 {
+		criterionType = criteria[0].Type
+	}
+	return Criterion{Operator: operator, Criteria: criteria, Type: criterionType}
+}
+
 func NewCriterion(leftOp string, operator Operator, rightOp []string, criteriaType CriterionType) Criterion {
 	return Criterion{LeftOp: leftOp, Operator: operator, RightOp: rightOp, Type: criteriaType}
 }
 
+// IsCompound returns true if the criterion combines nested criteria using a logical operator
+func (c Criterion) IsCompound() bool {
+	return c.Operator != nil && c.Operator.Type() == LogicalOperator
+}
+
 // Validate the criterion fields
 func (c Criterion) Validate() error {
+	if c.IsCompound() {
+		return c.validateCompound()
+	}
+
 	if len(c.RightOp) == 0 {
 		return errors.New("missing right operand")
 	}
@@ -201,11 +275,35 @@ func (c Criterion) Validate() error {
 	return nil
 }
 
+func (c Criterion) validateCompound() error {
+	if len(c.Criteria) == 0 {
+		return &util.UnsupportedQueryError{Message: fmt.Sprintf("operator %s expects at least one nested criterion", c.Operator)}
+	}
+	if c.Type != FieldQuery && c.Type != LabelQuery {
+		return &util.UnsupportedQueryError{Message: fmt.Sprintf("operator %s is supported only for %s and %s", c.Operator, FieldQuery, LabelQuery)}
+	}
+	if c.Operator == NotOperator && len(c.Criteria) > 1 {
+		return &util.UnsupportedQueryError{Message: fmt.Sprintf("operator %s expects exactly one nested criterion", c.Operator)}
+	}
+	for _, nested := range c.Criteria {
+		if nested.Type != c.Type {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("%s cannot be combined with %s using operator %s", nested.Type, c.Type, c.Operator)}
+		}
+		if err := nested.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func validateCriteria(criteria []Criterion) error {
 	fieldQueryLeftOperands := make(map[string]int)
 	labelQueryLeftOperands := make(map[string]int)
 
 	for _, criterion := range criteria {
+		if criterion.IsCompound() {
+			continue
+		}
 		if criterion.Type == FieldQuery {
 			fieldQueryLeftOperands[criterion.LeftOp]++
 		}
@@ -217,7 +315,7 @@ func validateCriteria(criteria []Criterion) error {
 	for _, c := range criteria {
 		leftOp := c.LeftOp
 		// disallow duplicate label queries
-		if count, ok := labelQueryLeftOperands[leftOp]; ok && count > 1 && c.Type == LabelQuery {
+		if count, ok := labelQueryLeftOperands[leftOp]; ok && count > 1 && c.Type == LabelQuery && !c.IsCompound() {
 			return &util.UnsupportedQueryError{Message: fmt.Sprintf("duplicate label query key: %s", leftOp)}
 		}
 		if err := c.Validate(); err != nil {
@@ -266,7 +364,7 @@ func Parse(criterionType CriterionType, expression string) ([]Criterion, error)
 	input := antlr.NewInputStream(expression)
 	lexer := parser.NewQueryLexer(input)
 	lexer.RemoveErrorListeners()
-	stream := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
+	stream := antlr.NewCommonTokenStream(newKeyAwareTokenSource(lexer), antlr.TokenDefaultChannel)
 
 	p := parser.NewQueryParser(stream)
 	p.RemoveErrorListeners()
@@ -313,7 +411,17 @@ func isDateTime(str string) bool {
 
 func validateWholeCriteria(criteria ...Criterion) error {
 	isLimited := false
+	orderCount, after := 0, -1
 	for _, criterion := range criteria {
+		if criterion.Type == ResultQuery && criterion.LeftOp == OrderBy {
+			orderCount++
+		}
+		if criterion.Type == ResultQuery && criterion.LeftOp == After {
+			if after >= 0 {
+				return fmt.Errorf("zero/one after criterion expected but multiple provided")
+			}
+			after = len(criterion.RightOp)
+		}
 		if criterion.LeftOp == Limit {
 			if isLimited {
 				return fmt.Errorf("zero/one limit criterion expected but multiple provided")
@@ -321,5 +429,8 @@ func validateWholeCriteria(criteria ...Criterion) error {
 			isLimited = true
 		}
 	}
+	if after >= 0 && after != orderCount {
+		return &util.UnsupportedQueryError{Message: fmt.Sprintf("after criterion expects %d values, one for each order by criterion, but %d provided", orderCount, after)}
+	}
 	return nil
 }
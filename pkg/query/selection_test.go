@@ -26,6 +26,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/util"
 )
 
 var _ = Describe("Selection", func() {
@@ -298,6 +299,107 @@ new line`))
 			}
 		}
 
+		for _, queryType := range []CriterionType{FieldQuery, LabelQuery} {
+			Describe(string("logical operators with queryType - "+queryType), func() {
+				queryType := queryType
+				Context("When queries are joined with or", func() {
+					It("Should build a single or criterion", func() {
+						criteria, err := Parse(queryType, "leftop1 eq 'rightop1' or leftop2 eq 'rightop2' or leftop3 eq 'rightop3'")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(criteria).To(ConsistOf(ByAny(
+							NewCriterion("leftop1", EqualsOperator, []string{"rightop1"}, queryType),
+							NewCriterion("leftop2", EqualsOperator, []string{"rightop2"}, queryType),
+							NewCriterion("leftop3", EqualsOperator, []string{"rightop3"}, queryType),
+						)))
+					})
+				})
+
+				Context("When and and or are mixed without parentheses", func() {
+					It("Should bind and tighter than or", func() {
+						criteria, err := Parse(queryType, "leftop1 eq 'rightop1' and leftop2 eq 'rightop2' or leftop3 eq 'rightop3'")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(criteria).To(ConsistOf(ByAny(
+							ByAll(
+								NewCriterion("leftop1", EqualsOperator, []string{"rightop1"}, queryType),
+								NewCriterion("leftop2", EqualsOperator, []string{"rightop2"}, queryType),
+							),
+							NewCriterion("leftop3", EqualsOperator, []string{"rightop3"}, queryType),
+						)))
+					})
+				})
+
+				Context("When parentheses group an or expression", func() {
+					It("Should keep the top level and criteria flat", func() {
+						criteria, err := Parse(queryType, "leftop1 eq 'rightop1' and (leftop2 eq 'rightop2' or leftop2 in ('rightop3'))")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(criteria).To(ConsistOf(
+							NewCriterion("leftop1", EqualsOperator, []string{"rightop1"}, queryType),
+							ByAny(
+								NewCriterion("leftop2", EqualsOperator, []string{"rightop2"}, queryType),
+								NewCriterion("leftop2", InOperator, []string{"rightop3"}, queryType),
+							),
+						))
+					})
+				})
+
+				Context("When a query is negated", func() {
+					It("Should build a not criterion", func() {
+						criteria, err := Parse(queryType, "not (leftop1 eq 'rightop1' or leftop2 eq 'rightop2') and not leftop3 eq 'rightop3'")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(criteria).To(ConsistOf(
+							ByNot(ByAny(
+								NewCriterion("leftop1", EqualsOperator, []string{"rightop1"}, queryType),
+								NewCriterion("leftop2", EqualsOperator, []string{"rightop2"}, queryType),
+							)),
+							ByNot(NewCriterion("leftop3", EqualsOperator, []string{"rightop3"}, queryType)),
+						))
+					})
+				})
+
+				Context("When notin operator is used next to not", func() {
+					It("Should parse both", func() {
+						criteria, err := Parse(queryType, "not leftop1 notin ('rightop1')")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(criteria).To(ConsistOf(ByNot(NewCriterion("leftop1", NotInOperator, []string{"rightop1"}, queryType))))
+					})
+				})
+
+				Context("When the key of the criterion is not", func() {
+					It("Should treat not as the key", func() {
+						criteria, err := Parse(queryType, "not eq 'rightop1' and not not in ('rightop2')")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(criteria).To(ConsistOf(
+							NewCriterion("not", EqualsOperator, []string{"rightop1"}, queryType),
+							ByNot(NewCriterion("not", InOperator, []string{"rightop2"}, queryType)),
+						))
+					})
+				})
+
+				Context("When parentheses are not balanced", func() {
+					It("Should return error", func() {
+						criteria, err := Parse(queryType, "(leftop1 eq 'rightop1' or leftop2 eq 'rightop2'")
+						Expect(err).To(HaveOccurred())
+						Expect(criteria).To(BeNil())
+					})
+				})
+
+				Context("When or has a missing operand", func() {
+					It("Should return error", func() {
+						criteria, err := Parse(queryType, "leftop1 eq 'rightop1' or")
+						Expect(err).To(HaveOccurred())
+						Expect(criteria).To(BeNil())
+					})
+				})
+			})
+		}
+
+		Context("When logical operators are used for exist query", func() {
+			It("Should return error", func() {
+				criteria, err := Parse(ExistQuery, "leftop1 eq 'rightop1' or leftop2 eq 'rightop2'")
+				Expect(err).To(HaveOccurred())
+				Expect(criteria).To(BeNil())
+			})
+		})
 	})
 
 	DescribeTable("Validate Criterion",
@@ -352,5 +454,67 @@ new line`))
 		Entry("New line character is not allowed in right operand",
 			ByField(EqualsOperator, "left", "one\ntwo"),
 			"forbidden new line character"),
+		Entry("Logical operator without nested criteria is not allowed",
+			ByAny(),
+			"expects at least one nested criterion"),
+		Entry("Not operator with multiple nested criteria is not allowed",
+			Criterion{Operator: NotOperator, Type: FieldQuery, Criteria: []Criterion{ByField(EqualsOperator, "a", "b"), ByField(EqualsOperator, "c", "d")}},
+			"expects exactly one nested criterion"),
+		Entry("Field and label queries are not allowed to be mixed by logical operator",
+			ByAny(ByField(EqualsOperator, "a", "b"), ByLabel(EqualsOperator, "c", "d")),
+			"cannot be combined"),
+		Entry("Invalid nested criterion is not allowed",
+			ByNot(ByField(EqualsOperator, "left", "right1", "right2")),
+			"single value operation"),
+		Entry("Logical operators are not allowed for exist queries",
+			ByAny(ByExists("SELECT 1")),
+			"supported only for"),
+		Entry("Valid logical criterion is allowed",
+			ByAll(ByLabel(EqualsOperator, "a", "b"), ByNot(ByLabel(InOperator, "a", "c", "d")))),
 	)
+
+	Describe("Order result by expression", func() {
+		Context("with valid expression", func() {
+			It("returns order by criteria in the order of the expression", func() {
+				criteria, err := OrderResultByExpression("name DESC, created_at,id asc")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(criteria).To(Equal([]Criterion{
+					OrderResultBy("name", DescOrder),
+					OrderResultBy("created_at", AscOrder),
+					OrderResultBy("id", AscOrder),
+				}))
+			})
+		})
+
+		DescribeTable("with invalid expression",
+			func(expression, expectedErr string) {
+				_, err := OrderResultByExpression(expression)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(BeAssignableToTypeOf(&util.UnsupportedQueryError{}))
+				Expect(err.Error()).To(ContainSubstring(expectedErr))
+			},
+			Entry("empty rule", "name,", "is invalid"),
+			Entry("unknown order type", "name up", "order type up is invalid"),
+			Entry("too many words", "name asc desc", "is invalid"),
+			Entry("label or json path", "context/platform", "ordering by context/platform is not supported"),
+			Entry("duplicate field", "name,name desc", "used more than once"),
+		)
+	})
+
+	Describe("Add after criterion to context", func() {
+		Context("when the number of values matches the order by criteria", func() {
+			It("adds it", func() {
+				_, err := AddCriteria(ctx, OrderResultBy("name", AscOrder), OrderResultBy("id", AscOrder), ResultAfter("a", "1"))
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when the number of values does not match the order by criteria", func() {
+			It("returns error", func() {
+				_, err := AddCriteria(ctx, OrderResultBy("name", AscOrder), ResultAfter("a", "1"))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("after criterion expects 1 values"))
+			})
+		})
+	})
 })
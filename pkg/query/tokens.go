@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"github.com/Peripli/service-manager/pkg/query/parser"
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+)
+
+const notKeyword = "not"
+
+// keyAwareTokenSource turns the not operator tokens which are followed by a comparison operator into key tokens,
+// so that fields and labels named "not" can still be queried
+type keyAwareTokenSource struct {
+	*parser.QueryLexer
+	buffer []antlr.Token
+}
+
+func newKeyAwareTokenSource(lexer *parser.QueryLexer) *keyAwareTokenSource {
+	return &keyAwareTokenSource{QueryLexer: lexer}
+}
+
+// NextToken returns the next token of the lexer, treating not as an operator only where a term is expected
+func (s *keyAwareTokenSource) NextToken() antlr.Token {
+	token := s.next()
+	if token.GetTokenType() != parser.QueryLexerNot {
+		return token
+	}
+	following := s.peek().GetTokenType()
+	if following != parser.QueryLexerUniOp && following != parser.QueryLexerMultiOp {
+		return token
+	}
+
+	factory := s.GetTokenFactory()
+	start, line, column := token.GetStart(), token.GetLine(), token.GetColumn()
+	key := factory.Create(token.GetSource(), parser.QueryLexerKey, notKeyword, token.GetChannel(),
+		start, start+len(notKeyword)-1, line, column)
+	whitespace := factory.Create(token.GetSource(), parser.QueryLexerWhitespace, " ", token.GetChannel(),
+		start+len(notKeyword), token.GetStop(), line, column+len(notKeyword))
+	s.buffer = append([]antlr.Token{whitespace}, s.buffer...)
+	return key
+}
+
+func (s *keyAwareTokenSource) next() antlr.Token {
+	if len(s.buffer) == 0 {
+		return s.QueryLexer.NextToken()
+	}
+	token := s.buffer[0]
+	s.buffer = s.buffer[1:]
+	return token
+}
+
+func (s *keyAwareTokenSource) peek() antlr.Token {
+	if len(s.buffer) == 0 {
+		s.buffer = append(s.buffer, s.QueryLexer.NextToken())
+	}
+	return s.buffer[0]
+}
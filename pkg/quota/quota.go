@@ -0,0 +1,236 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package quota enforces the limits of the resources which the tenants can create
+package quota
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// ExceededErrorType is the error type of the errors returned when a tenant reaches its quota
+const ExceededErrorType = "QuotaExceeded"
+
+// Usage is the number of resources of a tenant counted against its quota
+type Usage struct {
+	Instances            int            `json:"instances"`
+	InstancesPerOffering map[string]int `json:"instances_per_offering"`
+	InstancesPerPlan     map[string]int `json:"instances_per_plan"`
+	BindingsPerInstance  map[string]int `json:"bindings_per_instance"`
+}
+
+// Checker checks whether the tenants, identified by the value of their tenant label, can create more resources
+type Checker struct {
+	tenantIdentifier string
+}
+
+// NewChecker creates a checker of the quotas of the tenants identified by the provided label key
+func NewChecker(tenantIdentifier string) *Checker {
+	return &Checker{tenantIdentifier: tenantIdentifier}
+}
+
+// TenantOf returns the tenant of the object or an empty string if it does not belong to a tenant
+func (c *Checker) TenantOf(obj types.Object) string {
+	values := obj.GetLabels()[c.tenantIdentifier]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Quota returns the quota of the tenant or nil if the tenant has no quota. When lock is true the quota is locked until
+// the end of the transaction of the repository, so that the concurrent checks of the tenant are serialized.
+func (c *Checker) Quota(ctx context.Context, repository storage.Repository, tenant string, lock bool) (*types.TenantQuota, error) {
+	get := repository.Get
+	if lock {
+		get = repository.GetForUpdate
+	}
+	quota, err := get(ctx, types.TenantQuotaType, query.ByField(query.EqualsOperator, "id", tenant))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return quota.(*types.TenantQuota), nil
+}
+
+// CheckInstance returns an error if the tenant cannot create one more service instance of the plan
+func (c *Checker) CheckInstance(ctx context.Context, repository storage.Repository, tenant, planID string, lock bool) error {
+	if tenant == "" {
+		return nil
+	}
+	quota, err := c.Quota(ctx, repository, tenant, lock)
+	if err != nil || quota == nil {
+		return err
+	}
+
+	byTenant := query.ByLabel(query.EqualsOperator, c.tenantIdentifier, tenant)
+	if quota.MaxInstances != nil {
+		count, err := repository.Count(ctx, types.ServiceInstanceType, byTenant)
+		if err != nil {
+			return err
+		}
+		if count >= *quota.MaxInstances {
+			return exceeded("tenant %s reached its quota of %d service instances", tenant, *quota.MaxInstances)
+		}
+	}
+
+	if limit, found := quota.MaxInstancesPerPlan[planID]; found {
+		count, err := repository.Count(ctx, types.ServiceInstanceType, byTenant,
+			query.ByField(query.EqualsOperator, "service_plan_id", planID))
+		if err != nil {
+			return err
+		}
+		if count >= limit {
+			return exceeded("tenant %s reached its quota of %d service instances of service plan %s", tenant, limit, planID)
+		}
+	}
+
+	if len(quota.MaxInstancesPerOffering) == 0 {
+		return nil
+	}
+	plan, err := repository.Get(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "id", planID))
+	if err != nil {
+		return util.HandleStorageError(err, string(types.ServicePlanType))
+	}
+	offeringID := plan.(*types.ServicePlan).ServiceOfferingID
+	limit, found := quota.MaxInstancesPerOffering[offeringID]
+	if !found {
+		return nil
+	}
+	plans, err := repository.ListNoLabels(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "service_offering_id", offeringID))
+	if err != nil {
+		return err
+	}
+	planIDs := make([]string, 0, plans.Len())
+	for i := 0; i < plans.Len(); i++ {
+		planIDs = append(planIDs, plans.ItemAt(i).GetID())
+	}
+	count, err := repository.Count(ctx, types.ServiceInstanceType, byTenant,
+		query.ByField(query.InOperator, "service_plan_id", planIDs...))
+	if err != nil {
+		return err
+	}
+	if count >= limit {
+		return exceeded("tenant %s reached its quota of %d service instances of service offering %s", tenant, limit, offeringID)
+	}
+
+	return nil
+}
+
+// CheckBinding returns an error if the tenant owning the service instance cannot create one more binding for it
+func (c *Checker) CheckBinding(ctx context.Context, repository storage.Repository, instanceID string, lock bool) error {
+	instance, err := repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", instanceID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			// the existence of the instance is verified when the binding is created
+			return nil
+		}
+		return err
+	}
+	tenant := c.TenantOf(instance)
+	if tenant == "" {
+		return nil
+	}
+	quota, err := c.Quota(ctx, repository, tenant, lock)
+	if err != nil || quota == nil || quota.MaxBindingsPerInstance == nil {
+		return err
+	}
+
+	count, err := repository.Count(ctx, types.ServiceBindingType, query.ByField(query.EqualsOperator, "service_instance_id", instanceID))
+	if err != nil {
+		return err
+	}
+	if count >= *quota.MaxBindingsPerInstance {
+		return exceeded("tenant %s reached its quota of %d service bindings of service instance %s", tenant, *quota.MaxBindingsPerInstance, instanceID)
+	}
+
+	return nil
+}
+
+// Usage counts the resources of the tenant
+func (c *Checker) Usage(ctx context.Context, repository storage.Repository, tenant string) (*Usage, error) {
+	usage := &Usage{
+		InstancesPerOffering: make(map[string]int),
+		InstancesPerPlan:     make(map[string]int),
+		BindingsPerInstance:  make(map[string]int),
+	}
+
+	byTenant := query.ByLabel(query.EqualsOperator, c.tenantIdentifier, tenant)
+	groups, err := repository.CountGroups(ctx, types.ServiceInstanceType, []string{"service_plan_id"}, nil, byTenant)
+	if err != nil {
+		return nil, err
+	}
+	planIDs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		planID := group.Fields["service_plan_id"]
+		if planID == nil {
+			continue
+		}
+		usage.Instances += group.Count
+		usage.InstancesPerPlan[*planID] = group.Count
+		planIDs = append(planIDs, *planID)
+	}
+	if len(planIDs) == 0 {
+		return usage, nil
+	}
+
+	plans, err := repository.ListNoLabels(ctx, types.ServicePlanType, query.ByField(query.InOperator, "id", planIDs...))
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < plans.Len(); i++ {
+		plan := plans.ItemAt(i).(*types.ServicePlan)
+		usage.InstancesPerOffering[plan.ServiceOfferingID] += usage.InstancesPerPlan[plan.ID]
+	}
+
+	instances, err := repository.ListNoLabels(ctx, types.ServiceInstanceType, byTenant)
+	if err != nil {
+		return nil, err
+	}
+	instanceIDs := make([]string, 0, instances.Len())
+	for i := 0; i < instances.Len(); i++ {
+		instanceIDs = append(instanceIDs, instances.ItemAt(i).GetID())
+	}
+	groups, err = repository.CountGroups(ctx, types.ServiceBindingType, []string{"service_instance_id"}, nil,
+		query.ByField(query.InOperator, "service_instance_id", instanceIDs...))
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if instanceID := group.Fields["service_instance_id"]; instanceID != nil {
+			usage.BindingsPerInstance[*instanceID] = group.Count
+		}
+	}
+
+	return usage, nil
+}
+
+func exceeded(format string, args ...interface{}) error {
+	return &util.HTTPError{
+		ErrorType:   ExceededErrorType,
+		Description: fmt.Sprintf(format, args...),
+		StatusCode:  http.StatusForbidden,
+	}
+}
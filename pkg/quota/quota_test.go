@@ -0,0 +1,211 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package quota_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/quota"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/inmemory"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const tenantKey = "tenant"
+
+func newBase(id string, labels types.Labels) types.Base {
+	return types.Base{
+		ID:        id,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Labels:    labels,
+		Ready:     true,
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+var _ = Describe("Checker", func() {
+	var (
+		ctx        context.Context
+		repository storage.TransactionalRepository
+		checker    *quota.Checker
+	)
+
+	create := func(object types.Object) {
+		_, err := repository.Create(ctx, object)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	createInstance := func(id, planID, tenant string) {
+		create(&types.ServiceInstance{
+			Base:          newBase(id, types.Labels{tenantKey: {tenant}}),
+			Name:          id,
+			ServicePlanID: planID,
+			PlatformID:    "platform",
+		})
+	}
+
+	createBinding := func(id, instanceID string) {
+		create(&types.ServiceBinding{
+			Base:              newBase(id, nil),
+			Name:              id,
+			ServiceInstanceID: instanceID,
+		})
+	}
+
+	setQuota := func(tenantQuota *types.TenantQuota) {
+		tenantQuota.Base = newBase("tenant1", nil)
+		create(tenantQuota)
+	}
+
+	expectQuotaExceeded := func(err error) {
+		Expect(err).To(HaveOccurred())
+		httpErr, ok := err.(*util.HTTPError)
+		Expect(ok).To(BeTrue())
+		Expect(httpErr.StatusCode).To(Equal(http.StatusForbidden))
+		Expect(httpErr.ErrorType).To(Equal("QuotaExceeded"))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		settings := storage.DefaultSettings()
+		settings.Type = storage.InMemoryStorage
+		settings.EncryptionKey = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+		s := &inmemory.Storage{}
+		Expect(s.Open(settings)).To(Succeed())
+		repository = storage.NewInterceptableTransactionalRepository(s)
+		checker = quota.NewChecker(tenantKey)
+
+		create(&types.Platform{
+			Base: newBase("platform", nil),
+			Name: "platform",
+			Type: "kubernetes",
+		})
+		create(&types.ServiceBroker{
+			Base:      newBase("broker", nil),
+			Name:      "broker",
+			BrokerURL: "http://broker.example.com",
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{Username: "user", Password: "password"},
+			},
+		})
+		for _, offeringID := range []string{"offering1", "offering2"} {
+			create(&types.ServiceOffering{
+				Base:        newBase(offeringID, nil),
+				Name:        offeringID,
+				CatalogID:   offeringID,
+				CatalogName: offeringID,
+				BrokerID:    "broker",
+			})
+		}
+		for planID, offeringID := range map[string]string{"plan1": "offering1", "plan2": "offering1", "plan3": "offering2"} {
+			create(&types.ServicePlan{
+				Base:              newBase(planID, nil),
+				Name:              planID,
+				CatalogID:         planID,
+				CatalogName:       planID,
+				ServiceOfferingID: offeringID,
+			})
+		}
+
+		createInstance("instance1", "plan1", "tenant1")
+		createInstance("instance2", "plan2", "tenant1")
+		createInstance("instance3", "plan1", "tenant2")
+		createBinding("binding1", "instance1")
+	})
+
+	Describe("CheckInstance", func() {
+		It("allows any instance when the tenant has no quota", func() {
+			Expect(checker.CheckInstance(ctx, repository, "tenant1", "plan1", false)).To(Succeed())
+		})
+
+		It("allows instances without tenant", func() {
+			setQuota(&types.TenantQuota{MaxInstances: intPtr(0)})
+			Expect(checker.CheckInstance(ctx, repository, "", "plan1", false)).To(Succeed())
+		})
+
+		It("counts the instances of the tenant against the maximum instances", func() {
+			setQuota(&types.TenantQuota{MaxInstances: intPtr(3)})
+			Expect(checker.CheckInstance(ctx, repository, "tenant1", "plan3", false)).To(Succeed())
+
+			createInstance("instance4", "plan3", "tenant1")
+			expectQuotaExceeded(checker.CheckInstance(ctx, repository, "tenant1", "plan3", false))
+		})
+
+		It("counts the instances of the plan against the maximum instances of the plan", func() {
+			setQuota(&types.TenantQuota{MaxInstancesPerPlan: map[string]int{"plan1": 1}})
+			expectQuotaExceeded(checker.CheckInstance(ctx, repository, "tenant1", "plan1", false))
+			Expect(checker.CheckInstance(ctx, repository, "tenant1", "plan2", false)).To(Succeed())
+		})
+
+		It("counts the instances of all plans of the offering against the maximum instances of the offering", func() {
+			setQuota(&types.TenantQuota{MaxInstancesPerOffering: map[string]int{"offering1": 2, "offering2": 1}})
+			expectQuotaExceeded(checker.CheckInstance(ctx, repository, "tenant1", "plan1", false))
+			Expect(checker.CheckInstance(ctx, repository, "tenant1", "plan3", false)).To(Succeed())
+		})
+
+		It("checks the quota with the quota locked in a transaction", func() {
+			setQuota(&types.TenantQuota{MaxInstances: intPtr(2)})
+			err := repository.InTransaction(ctx, func(ctx context.Context, txStorage storage.Repository) error {
+				return checker.CheckInstance(ctx, txStorage, "tenant1", "plan1", true)
+			})
+			expectQuotaExceeded(err)
+		})
+	})
+
+	Describe("CheckBinding", func() {
+		It("counts the bindings of the instance against the maximum bindings per instance", func() {
+			setQuota(&types.TenantQuota{MaxBindingsPerInstance: intPtr(1)})
+			expectQuotaExceeded(checker.CheckBinding(ctx, repository, "instance1", false))
+			Expect(checker.CheckBinding(ctx, repository, "instance2", false)).To(Succeed())
+		})
+
+		It("allows bindings of instances of tenants without quota", func() {
+			setQuota(&types.TenantQuota{MaxBindingsPerInstance: intPtr(0)})
+			Expect(checker.CheckBinding(ctx, repository, "instance3", false)).To(Succeed())
+		})
+	})
+
+	Describe("Usage", func() {
+		It("counts the resources of the tenant", func() {
+			usage, err := checker.Usage(ctx, repository, "tenant1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(usage).To(Equal(&quota.Usage{
+				Instances:            2,
+				InstancesPerOffering: map[string]int{"offering1": 2},
+				InstancesPerPlan:     map[string]int{"plan1": 1, "plan2": 1},
+				BindingsPerInstance:  map[string]int{"instance1": 1},
+			}))
+		})
+
+		It("returns no usage for tenants without resources", func() {
+			usage, err := checker.Usage(ctx, repository, "tenant3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(usage.Instances).To(BeZero())
+			Expect(usage.InstancesPerPlan).To(BeEmpty())
+		})
+	})
+})
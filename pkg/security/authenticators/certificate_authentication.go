@@ -0,0 +1,507 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticators
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// CertificateSettings configures the authentication of platforms and API clients with client certificates
+type CertificateSettings struct {
+	Enabled        bool                 `mapstructure:"enabled" description:"whether platforms and API clients can authenticate with client certificates"`
+	CAFile         string               `mapstructure:"ca_file" description:"PEM file with the certificate authorities issuing the client certificates"`
+	CRLFiles       []string             `mapstructure:"crl_files" description:"PEM or DER files with the certificate revocation lists of the certificate authorities"`
+	Header         string               `mapstructure:"header" description:"header in which a TLS terminating proxy forwards the client certificate. The certificate of the TLS connection is used if empty"`
+	TrustedProxies []string             `mapstructure:"trusted_proxies" description:"IP addresses or CIDR ranges of the TLS terminating proxies from which the header with the client certificate is accepted"`
+	ReloadInterval time.Duration        `mapstructure:"reload_interval" description:"interval after which the certificate authorities and revocation lists are reloaded from their files"`
+	Mappings       []CertificateMapping `mapstructure:"mappings" description:"mappings of client certificates to platforms and API clients. The first matching mapping is applied"`
+}
+
+// CertificateMapping maps the client certificates matching all of its conditions to a platform or an API client
+type CertificateMapping struct {
+	// Subject is the distinguished name of the certificate subject, e.g. CN=agent,O=example
+	Subject string `mapstructure:"subject"`
+	// SAN is a DNS name, email address, IP address or URI from the subject alternative names of the certificate
+	SAN string `mapstructure:"san"`
+	// Fingerprints are the SHA-256 fingerprints of the certificates. More than one may be specified while a
+	// certificate is being rotated
+	Fingerprints []string `mapstructure:"fingerprints"`
+	// PlatformID is the id of the platform authenticated by the certificate
+	PlatformID string `mapstructure:"platform_id"`
+	// Client is the name of the API client authenticated by the certificate
+	Client string `mapstructure:"client"`
+	// AccessLevel is the access level granted to the platform or API client - global, all_tenants or tenant. No access
+	// is granted if empty so that it is granted by the authorization filters
+	AccessLevel string `mapstructure:"access_level"`
+}
+
+// DefaultCertificateSettings returns the default values for the authentication with client certificates
+func DefaultCertificateSettings() *CertificateSettings {
+	return &CertificateSettings{
+		Enabled:        false,
+		CRLFiles:       []string{},
+		TrustedProxies: []string{},
+		ReloadInterval: 5 * time.Minute,
+		Mappings:       []CertificateMapping{},
+	}
+}
+
+// Validate validates the client certificates settings
+func (s *CertificateSettings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if len(s.CAFile) == 0 {
+		return fmt.Errorf("validate client certificates settings: ca_file missing")
+	}
+	if s.ReloadInterval <= 0 {
+		return fmt.Errorf("validate client certificates settings: reload_interval must be positive")
+	}
+	if len(s.Header) != 0 && len(s.TrustedProxies) == 0 {
+		return fmt.Errorf("validate client certificates settings: trusted_proxies required when header is set")
+	}
+	if _, err := parseNetworks(s.TrustedProxies); err != nil {
+		return fmt.Errorf("validate client certificates settings: %s", err)
+	}
+	for i, mapping := range s.Mappings {
+		if err := mapping.validate(); err != nil {
+			return fmt.Errorf("validate client certificates settings: mapping %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+func (m *CertificateMapping) validate() error {
+	if len(m.Subject) == 0 && len(m.SAN) == 0 && len(m.Fingerprints) == 0 {
+		return errors.New("subject, san or fingerprints required")
+	}
+	if (len(m.PlatformID) == 0) == (len(m.Client) == 0) {
+		return errors.New("either platform_id or client required")
+	}
+	if len(m.AccessLevel) != 0 {
+		if _, err := web.ParseAccessLevel(m.AccessLevel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseNetworks parses IP addresses and CIDR ranges
+func parseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			_, network, err := net.ParseCIDR(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %s: %s", value, err)
+			}
+			networks = append(networks, network)
+			continue
+		}
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid trusted proxy %s", value)
+		}
+		bits := 8 * len(ip.To16())
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return networks, nil
+}
+
+func (m *CertificateMapping) matches(certificate *x509.Certificate) bool {
+	if len(m.Subject) != 0 && m.Subject != certificate.Subject.String() {
+		return false
+	}
+	if len(m.SAN) != 0 && !hasSAN(certificate, m.SAN) {
+		return false
+	}
+	if len(m.Fingerprints) != 0 {
+		fingerprint := Fingerprint(certificate)
+		for _, expected := range m.Fingerprints {
+			if normalizeFingerprint(expected) == fingerprint {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Fingerprint returns the hex encoded SHA-256 fingerprint of the certificate
+func Fingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.Replace(fingerprint, ":", "", -1))
+}
+
+func hasSAN(certificate *x509.Certificate, san string) bool {
+	names := append(append([]string{}, certificate.DNSNames...), certificate.EmailAddresses...)
+	for _, ip := range certificate.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range certificate.URIs {
+		names = append(names, uri.String())
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, san) {
+			return true
+		}
+	}
+	return false
+}
+
+// certificateUser is the user context data of the API clients authenticated with client certificates
+type certificateUser struct {
+	Subject     string `json:"subject"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// trust contains the certificate authorities and the revoked certificates loaded from the configured files
+type trust struct {
+	roots       *x509.CertPool
+	authorities []*x509.Certificate
+	// revoked contains the serial numbers of the revoked certificates by raw subject of their issuer
+	revoked  map[string]map[string]bool
+	loadedAt time.Time
+}
+
+// Certificate authenticates the requests of platforms and API clients with verified client certificates
+type Certificate struct {
+	repository     storage.Repository
+	settings       *CertificateSettings
+	trustedProxies []*net.IPNet
+
+	mutex sync.Mutex
+	trust *trust
+}
+
+// NewCertificateAuthenticator returns a Certificate authenticator which maps the client certificates issued by the
+// configured certificate authorities to the platforms in the repository or to API clients
+func NewCertificateAuthenticator(repository storage.Repository, settings *CertificateSettings) (*Certificate, error) {
+	trustedProxies, err := parseNetworks(settings.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	trust, err := loadTrust(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &Certificate{
+		repository:     repository,
+		settings:       settings,
+		trustedProxies: trustedProxies,
+		trust:          trust,
+	}, nil
+}
+
+// Authenticate authenticates the requests with a client certificate matching one of the configured mappings
+func (a *Certificate) Authenticate(request *web.Request) (*web.UserContext, httpsec.Decision, error) {
+	ctx := request.Context()
+	certificates, err := a.clientCertificates(request)
+	if err != nil {
+		return nil, httpsec.Deny, err
+	}
+	if len(certificates) == 0 {
+		return nil, httpsec.Abstain, nil
+	}
+	certificate := certificates[0]
+
+	if err := a.verify(certificates); err != nil {
+		return nil, httpsec.Deny, fmt.Errorf("client certificate %s is not trusted: %s", certificate.Subject, err)
+	}
+
+	for _, mapping := range a.settings.Mappings {
+		if !mapping.matches(certificate) {
+			continue
+		}
+		log.C(ctx).Debugf("Client certificate %s matches mapping of platform %q client %q", certificate.Subject, mapping.PlatformID, mapping.Client)
+		accessLevel := web.NoAccess
+		if len(mapping.AccessLevel) != 0 {
+			if accessLevel, err = web.ParseAccessLevel(mapping.AccessLevel); err != nil {
+				return nil, httpsec.Abstain, err
+			}
+		}
+		var user *web.UserContext
+		var decision httpsec.Decision
+		if len(mapping.PlatformID) != 0 {
+			user, decision, err = a.platformUser(request, mapping.PlatformID)
+		} else {
+			user, decision, err = buildCertificateResponse(mapping.Client, certificate)
+		}
+		if user != nil {
+			user.AccessLevel = accessLevel
+		}
+		return user, decision, err
+	}
+
+	return nil, httpsec.Deny, fmt.Errorf("client certificate %s is not mapped to a platform or client", certificate.Subject)
+}
+
+// platformUser returns the user context of the platform. It is the same as the one of the platform basic credentials
+// so that the platform specific filters are applied to the requests authenticated with certificates as well
+func (a *Certificate) platformUser(request *web.Request, platformID string) (*web.UserContext, httpsec.Decision, error) {
+	platform, err := a.repository.Get(request.Context(), types.PlatformType, query.ByField(query.EqualsOperator, "id", platformID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil, httpsec.Deny, fmt.Errorf("platform %s of the client certificate not found", platformID)
+		}
+		return nil, httpsec.Abstain, fmt.Errorf("could not get platform %s from storage: %s", platformID, err)
+	}
+	return buildResponse(platform.(*types.Platform).Name, platform)
+}
+
+func buildCertificateResponse(client string, certificate *x509.Certificate) (*web.UserContext, httpsec.Decision, error) {
+	bytes, err := json.Marshal(&certificateUser{
+		Subject:     certificate.Subject.String(),
+		Fingerprint: Fingerprint(certificate),
+	})
+	if err != nil {
+		return nil, httpsec.Abstain, err
+	}
+
+	return &web.UserContext{
+		Data: func(v interface{}) error {
+			return json.Unmarshal(bytes, v)
+		},
+		AuthenticationType: web.Certificate,
+		Name:               client,
+		AccessLevel:        web.NoAccess,
+	}, httpsec.Allow, nil
+}
+
+// clientCertificates returns the client certificate followed by the intermediate certificates sent by the client
+func (a *Certificate) clientCertificates(request *web.Request) ([]*x509.Certificate, error) {
+	if len(a.settings.Header) == 0 {
+		if request.TLS == nil {
+			return nil, nil
+		}
+		return request.TLS.PeerCertificates, nil
+	}
+
+	value := request.Header.Get(a.settings.Header)
+	if len(value) == 0 {
+		return nil, nil
+	}
+	if !a.fromTrustedProxy(request) {
+		return nil, fmt.Errorf("client certificate forwarded by untrusted address %s", request.RemoteAddr)
+	}
+	return parseForwardedCertificates(value)
+}
+
+// fromTrustedProxy checks whether the request is sent by one of the trusted proxies which forward the client certificates
+func (a *Certificate) fromTrustedProxy(request *web.Request) bool {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range a.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseForwardedCertificates parses URL encoded PEM certificates or a base64 encoded DER certificate
+func parseForwardedCertificates(value string) ([]*x509.Certificate, error) {
+	if unescaped, err := url.QueryUnescape(value); err == nil && strings.Contains(unescaped, "-----BEGIN") {
+		var certificates []*x509.Certificate
+		rest := []byte(unescaped)
+		for {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %s", err)
+			}
+			certificates = append(certificates, certificate)
+		}
+		if len(certificates) == 0 {
+			return nil, errors.New("invalid client certificate: no certificate found")
+		}
+		return certificates, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %s", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %s", err)
+	}
+	return []*x509.Certificate{certificate}, nil
+}
+
+func (a *Certificate) verify(certificates []*x509.Certificate) error {
+	trust := a.currentTrust()
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	chains, err := certificates[0].Verify(x509.VerifyOptions{
+		Roots:         trust.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, chain := range chains {
+		for _, certificate := range chain {
+			if trust.revoked[string(certificate.RawIssuer)][certificate.SerialNumber.String()] {
+				return fmt.Errorf("certificate %s with serial number %s is revoked", certificate.Subject, certificate.SerialNumber)
+			}
+		}
+	}
+	return nil
+}
+
+// currentTrust returns the loaded certificate authorities and revocation lists, reloading them when they are older
+// than the reload interval so that rotated authorities and new revocations are applied without restart
+func (a *Certificate) currentTrust() *trust {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if time.Since(a.trust.loadedAt) >= a.settings.ReloadInterval {
+		trust, err := loadTrust(a.settings)
+		if err != nil {
+			log.D().WithError(err).Error("Could not reload client certificate authorities. Using the previously loaded ones")
+			a.trust.loadedAt = time.Now()
+		} else {
+			a.trust = trust
+		}
+	}
+	return a.trust
+}
+
+func loadTrust(settings *CertificateSettings) (*trust, error) {
+	bytes, err := ioutil.ReadFile(settings.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read client certificate authorities: %s", err)
+	}
+	result := &trust{
+		roots:    x509.NewCertPool(),
+		revoked:  make(map[string]map[string]bool),
+		loadedAt: time.Now(),
+	}
+	for {
+		var block *pem.Block
+		if block, bytes = pem.Decode(bytes); block == nil {
+			break
+		}
+		authority, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse client certificate authority: %s", err)
+		}
+		result.roots.AddCert(authority)
+		result.authorities = append(result.authorities, authority)
+	}
+	if len(result.authorities) == 0 {
+		return nil, fmt.Errorf("no client certificate authorities found in %s", settings.CAFile)
+	}
+
+	for _, file := range settings.CRLFiles {
+		if err := result.loadRevocationList(file); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (t *trust) loadRevocationList(file string) error {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("could not read certificate revocation list: %s", err)
+	}
+	if block, _ := pem.Decode(bytes); block != nil {
+		bytes = block.Bytes
+	}
+	list, err := x509.ParseRevocationList(bytes)
+	if err != nil {
+		return fmt.Errorf("could not parse certificate revocation list %s: %s", file, err)
+	}
+	issuer, err := t.issuerOf(list)
+	if err != nil {
+		return fmt.Errorf("certificate revocation list %s: %s", file, err)
+	}
+	if !list.NextUpdate.IsZero() && list.NextUpdate.Before(time.Now()) {
+		log.D().Warnf("Certificate revocation list %s of %s has expired", file, issuer.Subject)
+	}
+
+	revoked, found := t.revoked[string(issuer.RawSubject)]
+	if !found {
+		revoked = make(map[string]bool)
+		t.revoked[string(issuer.RawSubject)] = revoked
+	}
+	for _, certificate := range list.RevokedCertificateEntries {
+		revoked[serialNumber(certificate)] = true
+	}
+	return nil
+}
+
+func (t *trust) issuerOf(list *x509.RevocationList) (*x509.Certificate, error) {
+	for _, authority := range t.authorities {
+		if list.CheckSignatureFrom(authority) == nil {
+			return authority, nil
+		}
+	}
+	return nil, errors.New("not signed by any of the certificate authorities")
+}
+
+func serialNumber(certificate x509.RevocationListEntry) string {
+	if certificate.SerialNumber == nil {
+		return new(big.Int).String()
+	}
+	return certificate.SerialNumber.String()
+}
@@ -0,0 +1,383 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticators_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/security/authenticators"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	serial      int64
+}
+
+func newTestAuthority(name string) *testAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	certificate, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+	return &testAuthority{certificate: certificate, key: key, serial: 1}
+}
+
+func (a *testAuthority) issue(name string, dnsNames ...string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	a.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(a.serial),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"example"}},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.certificate, &key.PublicKey, a.key)
+	Expect(err).ToNot(HaveOccurred())
+	certificate, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+	return certificate
+}
+
+func (a *testAuthority) revocationList(revoked ...*x509.Certificate) []byte {
+	var entries []pkix.RevokedCertificate
+	for _, certificate := range revoked {
+		entries = append(entries, pkix.RevokedCertificate{SerialNumber: certificate.SerialNumber, RevocationTime: time.Now()})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:              big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:          time.Now().Add(-time.Minute),
+		NextUpdate:          time.Now().Add(time.Hour),
+		RevokedCertificates: entries,
+	}, a.certificate, a.key)
+	Expect(err).ToNot(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+func encodeCertificate(certificate *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+}
+
+var _ = Describe("Certificate Authenticator", func() {
+	var (
+		dir            string
+		authority      *testAuthority
+		settings       *authenticators.CertificateSettings
+		fakeRepository *storagefakes.FakeStorage
+		authenticator  *authenticators.Certificate
+		request        *http.Request
+	)
+
+	writeFile := func(name string, content []byte) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, content, 0600)).To(Succeed())
+		return path
+	}
+
+	withPeerCertificate := func(certificate *x509.Certificate) *web.Request {
+		request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}
+		return &web.Request{Request: request}
+	}
+
+	newAuthenticator := func() {
+		var err error
+		authenticator, err = authenticators.NewCertificateAuthenticator(fakeRepository, settings)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "certificates")
+		Expect(err).ToNot(HaveOccurred())
+
+		authority = newTestAuthority("platforms-ca")
+		settings = authenticators.DefaultCertificateSettings()
+		settings.Enabled = true
+		settings.CAFile = writeFile("ca.pem", encodeCertificate(authority.certificate))
+		settings.Mappings = []authenticators.CertificateMapping{
+			{Subject: "CN=operator,O=example", Client: "operator", AccessLevel: "global"},
+			{SAN: "agent.example.com", PlatformID: "platform-id", AccessLevel: "tenant"},
+		}
+
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.GetReturns(&types.Platform{Base: types.Base{ID: "platform-id"}, Name: "k8s"}, nil)
+
+		request, err = http.NewRequest(http.MethodGet, "https://example.com/v1/service_instances", nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Describe("NewCertificateAuthenticator", func() {
+		Context("when the certificate authorities cannot be loaded", func() {
+			It("returns an error", func() {
+				settings.CAFile = writeFile("empty.pem", []byte{})
+				_, err := authenticators.NewCertificateAuthenticator(fakeRepository, settings)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a revocation list is not signed by the certificate authorities", func() {
+			It("returns an error", func() {
+				settings.CRLFiles = []string{writeFile("crl.pem", newTestAuthority("other-ca").revocationList())}
+				_, err := authenticators.NewCertificateAuthenticator(fakeRepository, settings)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Authenticate", func() {
+		BeforeEach(func() {
+			newAuthenticator()
+		})
+
+		Context("when there is no client certificate", func() {
+			It("abstains", func() {
+				user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(user).To(BeNil())
+				Expect(decision).To(Equal(httpsec.Abstain))
+			})
+		})
+
+		Context("when the certificate is mapped to an API client", func() {
+			It("allows with the configured access level", func() {
+				certificate := authority.issue("operator")
+				user, decision, err := authenticator.Authenticate(withPeerCertificate(certificate))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decision).To(Equal(httpsec.Allow))
+				Expect(user.Name).To(Equal("operator"))
+				Expect(user.AuthenticationType).To(Equal(web.Certificate))
+				Expect(user.AccessLevel).To(Equal(web.GlobalAccess))
+
+				data := make(map[string]string)
+				Expect(user.Data(&data)).To(Succeed())
+				Expect(data["fingerprint"]).To(Equal(authenticators.Fingerprint(certificate)))
+			})
+		})
+
+		Context("when the certificate is mapped to a platform", func() {
+			It("allows as the platform", func() {
+				user, decision, err := authenticator.Authenticate(withPeerCertificate(authority.issue("agent", "agent.example.com")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decision).To(Equal(httpsec.Allow))
+				Expect(user.Name).To(Equal("k8s"))
+				Expect(user.AuthenticationType).To(Equal(web.Basic))
+				Expect(user.AccessLevel).To(Equal(web.TenantAccess))
+
+				platform := &types.Platform{}
+				Expect(user.Data(platform)).To(Succeed())
+				Expect(platform.ID).To(Equal("platform-id"))
+			})
+
+			Context("and the platform does not exist", func() {
+				It("denies", func() {
+					fakeRepository.GetReturns(nil, util.ErrNotFoundInStorage)
+					user, decision, err := authenticator.Authenticate(withPeerCertificate(authority.issue("agent", "agent.example.com")))
+					Expect(err).To(HaveOccurred())
+					Expect(user).To(BeNil())
+					Expect(decision).To(Equal(httpsec.Deny))
+				})
+			})
+		})
+
+		Context("when the certificate is mapped by fingerprint", func() {
+			It("allows any of the configured fingerprints", func() {
+				certificate := authority.issue("agent")
+				settings.Mappings = []authenticators.CertificateMapping{
+					{Fingerprints: []string{"00:11", authenticators.Fingerprint(certificate)}, Client: "agent"},
+				}
+				user, decision, err := authenticator.Authenticate(withPeerCertificate(certificate))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decision).To(Equal(httpsec.Allow))
+				Expect(user.Name).To(Equal("agent"))
+				Expect(user.AccessLevel).To(Equal(web.NoAccess))
+			})
+		})
+
+		Context("when the certificate is not mapped", func() {
+			It("denies", func() {
+				user, decision, err := authenticator.Authenticate(withPeerCertificate(authority.issue("unknown")))
+				Expect(err).To(HaveOccurred())
+				Expect(user).To(BeNil())
+				Expect(decision).To(Equal(httpsec.Deny))
+			})
+		})
+
+		Context("when the certificate is issued by an unknown authority", func() {
+			It("denies", func() {
+				user, decision, err := authenticator.Authenticate(withPeerCertificate(newTestAuthority("other-ca").issue("operator")))
+				Expect(err).To(HaveOccurred())
+				Expect(user).To(BeNil())
+				Expect(decision).To(Equal(httpsec.Deny))
+			})
+		})
+
+		Context("when the certificate is revoked", func() {
+			It("denies", func() {
+				certificate := authority.issue("operator")
+				settings.CRLFiles = []string{writeFile("crl.pem", authority.revocationList(certificate))}
+				newAuthenticator()
+
+				user, decision, err := authenticator.Authenticate(withPeerCertificate(certificate))
+				Expect(err).To(HaveOccurred())
+				Expect(user).To(BeNil())
+				Expect(decision).To(Equal(httpsec.Deny))
+
+				_, decision, err = authenticator.Authenticate(withPeerCertificate(authority.issue("operator")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decision).To(Equal(httpsec.Allow))
+			})
+		})
+
+		Context("when the certificate authorities are rotated", func() {
+			It("trusts the new authorities after the reload interval", func() {
+				settings.ReloadInterval = time.Millisecond
+				rotated := newTestAuthority("rotated-ca")
+				certificate := rotated.issue("operator")
+
+				_, decision, _ := authenticator.Authenticate(withPeerCertificate(certificate))
+				Expect(decision).To(Equal(httpsec.Deny))
+
+				writeFile("ca.pem", append(encodeCertificate(authority.certificate), encodeCertificate(rotated.certificate)...))
+				time.Sleep(5 * time.Millisecond)
+
+				_, decision, err := authenticator.Authenticate(withPeerCertificate(certificate))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decision).To(Equal(httpsec.Allow))
+			})
+		})
+
+		Context("when the certificate is forwarded in a header", func() {
+			BeforeEach(func() {
+				settings.Header = "X-Forwarded-Client-Cert"
+				settings.TrustedProxies = []string{"10.0.0.0/8"}
+				request.RemoteAddr = "10.0.0.1:50000"
+				newAuthenticator()
+			})
+
+			It("authenticates URL encoded PEM certificates", func() {
+				request.Header.Set(settings.Header, url.QueryEscape(string(encodeCertificate(authority.issue("operator")))))
+				user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decision).To(Equal(httpsec.Allow))
+				Expect(user.Name).To(Equal("operator"))
+			})
+
+			It("authenticates base64 encoded DER certificates", func() {
+				request.Header.Set(settings.Header, base64.StdEncoding.EncodeToString(authority.issue("operator").Raw))
+				user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decision).To(Equal(httpsec.Allow))
+				Expect(user.Name).To(Equal("operator"))
+			})
+
+			It("denies invalid certificates", func() {
+				request.Header.Set(settings.Header, "invalid")
+				_, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+				Expect(err).To(HaveOccurred())
+				Expect(decision).To(Equal(httpsec.Deny))
+			})
+
+			It("denies certificates forwarded by untrusted addresses", func() {
+				request.RemoteAddr = "192.168.0.1:50000"
+				request.Header.Set(settings.Header, base64.StdEncoding.EncodeToString(authority.issue("operator").Raw))
+				user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+				Expect(err).To(HaveOccurred())
+				Expect(user).To(BeNil())
+				Expect(decision).To(Equal(httpsec.Deny))
+			})
+
+			It("ignores the certificate of the connection", func() {
+				user, decision, err := authenticator.Authenticate(withPeerCertificate(authority.issue("operator")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(user).To(BeNil())
+				Expect(decision).To(Equal(httpsec.Abstain))
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		It("accepts the default settings", func() {
+			Expect(authenticators.DefaultCertificateSettings().Validate()).To(Succeed())
+		})
+
+		It("requires a mapping condition", func() {
+			settings.Mappings = []authenticators.CertificateMapping{{Client: "operator"}}
+			Expect(settings.Validate()).ToNot(Succeed())
+		})
+
+		It("requires either a platform or a client", func() {
+			settings.Mappings = []authenticators.CertificateMapping{{Subject: "CN=operator", Client: "operator", PlatformID: "platform-id"}}
+			Expect(settings.Validate()).ToNot(Succeed())
+		})
+
+		It("requires trusted proxies when the certificate is forwarded in a header", func() {
+			settings.Header = "X-Forwarded-Client-Cert"
+			Expect(settings.Validate()).ToNot(Succeed())
+			settings.TrustedProxies = []string{"10.0.0.1", "fd00::/8"}
+			Expect(settings.Validate()).To(Succeed())
+		})
+
+		It("requires valid trusted proxies", func() {
+			settings.TrustedProxies = []string{"proxy"}
+			Expect(settings.Validate()).ToNot(Succeed())
+		})
+
+		It("requires a supported access level", func() {
+			settings.Mappings = []authenticators.CertificateMapping{{Subject: "CN=operator", Client: "operator", AccessLevel: "admin"}}
+			Expect(settings.Validate()).ToNot(Succeed())
+		})
+	})
+})
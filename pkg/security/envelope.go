@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// envelopeHeader prefixes envelope encrypted data, so that it can be told apart from data encrypted directly with
+// the storage encryption key
+var envelopeHeader = []byte("sm:envelope:v1:")
+
+// maxCachedDataKeys is the number of unwrapped data keys kept in memory to avoid calling the key provider on each read
+const maxCachedDataKeys = 1000
+
+// EnvelopeEncrypter is an Encrypter which encrypts values with data keys obtained from a KeyProvider. A data key is
+// used for a limited time and number of values before a new one is generated.
+// The wrapped data key and the ID of the key encryption key are stored next to the ciphertext.
+// The key passed to Encrypt is ignored and the one passed to Decrypt is used only for data encrypted before
+// envelope encryption was enabled, so that such data stays readable until it is re-encrypted
+type EnvelopeEncrypter struct {
+	keyProvider     KeyProvider
+	encrypter       Encrypter
+	dataKeyLifetime time.Duration
+	dataKeyMaxUses  int
+
+	mutex           sync.Mutex
+	dataKeys        map[string][]byte
+	dataKey         *DataKey
+	dataKeyExpiry   time.Time
+	dataKeyUsesLeft int
+}
+
+// NewEnvelopeEncrypter creates an EnvelopeEncrypter using data keys from the specified key provider for the time and
+// number of values specified in the settings
+func NewEnvelopeEncrypter(keyProvider KeyProvider, settings *KeyProviderSettings) *EnvelopeEncrypter {
+	return &EnvelopeEncrypter{
+		keyProvider:     keyProvider,
+		encrypter:       &AESEncrypter{},
+		dataKeyLifetime: settings.DataKeyLifetime,
+		dataKeyMaxUses:  settings.DataKeyMaxUses,
+		dataKeys:        make(map[string][]byte),
+	}
+}
+
+// Encrypt encrypts the plaintext with the current data key and returns it together with the wrapped data key
+func (e *EnvelopeEncrypter) Encrypt(ctx context.Context, plaintext []byte, _ []byte) ([]byte, error) {
+	dataKey, err := e.currentDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := e.encrypter.Encrypt(ctx, plaintext, dataKey.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := bytes.NewBuffer(append([]byte{}, envelopeHeader...))
+	writeField(envelope, []byte(dataKey.KeyID))
+	writeField(envelope, dataKey.Wrapped)
+	envelope.Write(ciphertext)
+	return envelope.Bytes(), nil
+}
+
+// Decrypt decrypts the ciphertext with the data key stored next to it. Data which is not envelope encrypted is
+// decrypted with the provided key
+func (e *EnvelopeEncrypter) Decrypt(ctx context.Context, ciphertext []byte, key []byte) ([]byte, error) {
+	if !bytes.HasPrefix(ciphertext, envelopeHeader) {
+		return e.encrypter.Decrypt(ctx, ciphertext, key)
+	}
+
+	envelope := bytes.NewBuffer(ciphertext[len(envelopeHeader):])
+	keyID, err := readField(envelope)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := readField(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := e.unwrap(ctx, string(keyID), wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return e.encrypter.Decrypt(ctx, envelope.Bytes(), dataKey)
+}
+
+// currentDataKey returns the data key used for encrypting new values, generating a new one once it has expired or
+// has been used for the maximum number of values
+func (e *EnvelopeEncrypter) currentDataKey(ctx context.Context) (*DataKey, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.dataKey == nil || e.dataKeyUsesLeft <= 0 || !time.Now().Before(e.dataKeyExpiry) {
+		dataKey, err := e.keyProvider.GenerateDataKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		e.dataKey = dataKey
+		e.dataKeyExpiry = time.Now().Add(e.dataKeyLifetime)
+		e.dataKeyUsesLeft = e.dataKeyMaxUses
+	}
+	e.dataKeyUsesLeft--
+	return e.dataKey, nil
+}
+
+func (e *EnvelopeEncrypter) unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	cacheKey := keyID + ":" + string(wrapped)
+
+	e.mutex.Lock()
+	dataKey, found := e.dataKeys[cacheKey]
+	e.mutex.Unlock()
+	if found {
+		return dataKey, nil
+	}
+
+	dataKey, err := e.keyProvider.UnwrapDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(e.dataKeys) >= maxCachedDataKeys {
+		e.dataKeys = make(map[string][]byte)
+	}
+	e.dataKeys[cacheKey] = dataKey
+	return dataKey, nil
+}
+
+func writeField(buffer *bytes.Buffer, field []byte) {
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(len(field)))
+	buffer.Write(length)
+	buffer.Write(field)
+}
+
+func readField(buffer *bytes.Buffer) ([]byte, error) {
+	length := buffer.Next(2)
+	if len(length) != 2 {
+		return nil, fmt.Errorf("malformed envelope")
+	}
+	size := int(binary.BigEndian.Uint16(length))
+	field := buffer.Next(size)
+	if len(field) != size {
+		return nil, fmt.Errorf("malformed envelope")
+	}
+	return field, nil
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security_test
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/security/securityfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Envelope Encrypter", func() {
+	var (
+		ctx         context.Context
+		keyProvider *securityfakes.FakeKeyProvider
+		encrypter   *security.EnvelopeEncrypter
+		settings    *security.KeyProviderSettings
+		kek         []byte
+	)
+	plaintext := []byte("plaintext")
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		kek = make([]byte, 32)
+		_, err := rand.Read(kek)
+		Expect(err).ToNot(HaveOccurred())
+
+		aes := &security.AESEncrypter{}
+		keyProvider = &securityfakes.FakeKeyProvider{}
+		keyProvider.GenerateDataKeyStub = func(ctx context.Context) (*security.DataKey, error) {
+			dataKey := make([]byte, 32)
+			if _, err := rand.Read(dataKey); err != nil {
+				return nil, err
+			}
+			wrapped, err := aes.Encrypt(ctx, dataKey, kek)
+			return &security.DataKey{KeyID: "key-1", Plaintext: dataKey, Wrapped: wrapped}, err
+		}
+		keyProvider.UnwrapDataKeyStub = func(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+			if keyID != "key-1" {
+				return nil, fmt.Errorf("unknown key %s", keyID)
+			}
+			return aes.Decrypt(ctx, wrapped, kek)
+		}
+		settings = security.DefaultKeyProviderSettings()
+		settings.DataKeyMaxUses = 2
+		encrypter = security.NewEnvelopeEncrypter(keyProvider, settings)
+	})
+
+	It("reuses a data key for the maximum number of values", func() {
+		first, err := encrypter.Encrypt(ctx, plaintext, nil)
+		Expect(err).ToNot(HaveOccurred())
+		second, err := encrypter.Encrypt(ctx, plaintext, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keyProvider.GenerateDataKeyCallCount()).To(Equal(1))
+
+		_, err = encrypter.Encrypt(ctx, plaintext, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keyProvider.GenerateDataKeyCallCount()).To(Equal(2))
+
+		Expect(first).ToNot(Equal(second))
+		Expect(string(first)).To(ContainSubstring("key-1"))
+		Expect(string(first)).ToNot(ContainSubstring(string(plaintext)))
+	})
+
+	Context("when the data key has expired", func() {
+		BeforeEach(func() {
+			settings.DataKeyLifetime = time.Millisecond
+			encrypter = security.NewEnvelopeEncrypter(keyProvider, settings)
+		})
+
+		It("encrypts the value with a new data key", func() {
+			_, err := encrypter.Encrypt(ctx, plaintext, nil)
+			Expect(err).ToNot(HaveOccurred())
+			time.Sleep(2 * time.Millisecond)
+			_, err = encrypter.Encrypt(ctx, plaintext, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(keyProvider.GenerateDataKeyCallCount()).To(Equal(2))
+		})
+	})
+
+	It("decrypts the value with the data key stored next to it", func() {
+		ciphertext, err := encrypter.Encrypt(ctx, plaintext, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		decrypted, err := security.NewEnvelopeEncrypter(keyProvider, settings).Decrypt(ctx, ciphertext, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decrypted).To(Equal(plaintext))
+		_, keyID, _ := keyProvider.UnwrapDataKeyArgsForCall(0)
+		Expect(keyID).To(Equal("key-1"))
+	})
+
+	It("caches the unwrapped data keys", func() {
+		ciphertext, err := encrypter.Encrypt(ctx, plaintext, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < 2; i++ {
+			_, err := encrypter.Decrypt(ctx, ciphertext, nil)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(keyProvider.UnwrapDataKeyCallCount()).To(Equal(1))
+	})
+
+	Context("when the value was encrypted before envelope encryption was enabled", func() {
+		It("decrypts it with the provided key", func() {
+			ciphertext, err := (&security.AESEncrypter{}).Encrypt(ctx, plaintext, kek)
+			Expect(err).ToNot(HaveOccurred())
+
+			decrypted, err := encrypter.Decrypt(ctx, ciphertext, kek)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decrypted).To(Equal(plaintext))
+			Expect(keyProvider.UnwrapDataKeyCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the envelope is malformed", func() {
+		It("returns an error", func() {
+			ciphertext, err := encrypter.Encrypt(ctx, plaintext, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = encrypter.Decrypt(ctx, ciphertext[:20], nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the key provider fails", func() {
+		It("returns the error", func() {
+			keyProvider.GenerateDataKeyReturns(nil, fmt.Errorf("kms unavailable"))
+			_, err := encrypter.Encrypt(ctx, plaintext, nil)
+			Expect(err).To(MatchError("kms unavailable"))
+		})
+	})
+})
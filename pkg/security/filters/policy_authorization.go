@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// PolicyAuthorizationFilterName is the name of PolicyAuthorizationFilter
+const PolicyAuthorizationFilterName = "PolicyAuthorizationFilter"
+
+// PolicyAuthorizationFilter authorizes the requests using the configured authorization policies. Requests on which
+// the policies abstain from a decision are authorized by the authorization filters following it
+type PolicyAuthorizationFilter struct {
+	authorizer http.Authorizer
+}
+
+// NewPolicyAuthorizationFilter returns a PolicyAuthorizationFilter applying the decisions of the specified authorizer
+func NewPolicyAuthorizationFilter(authorizer http.Authorizer) *PolicyAuthorizationFilter {
+	return &PolicyAuthorizationFilter{
+		authorizer: authorizer,
+	}
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter
+func (f *PolicyAuthorizationFilter) Name() string {
+	return PolicyAuthorizationFilterName
+}
+
+// Run implements web.Filter and denies the requests which are not allowed by the policies
+func (f *PolicyAuthorizationFilter) Run(request *web.Request, next web.Handler) (*web.Response, error) {
+	decision, accessLevel, err := f.authorizer.Authorize(request)
+	switch decision {
+	case http.Deny:
+		log.C(request.Context()).Info(err)
+		return nil, security.ForbiddenHTTPError(fmt.Sprintf("not authorized: %s", err))
+	case http.Allow:
+		ctx := request.Context()
+		userContext, found := web.UserFromContext(ctx)
+		if !found {
+			return nil, fmt.Errorf("authorization failed due to missing user context")
+		}
+		userContext.AccessLevel = accessLevel
+		ctx = web.ContextWithUser(ctx, userContext)
+		request.Request = request.WithContext(web.ContextWithAuthorization(ctx))
+	default:
+		if err != nil {
+			return nil, err
+		}
+	}
+	return next.Handle(request)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed
+func (f *PolicyAuthorizationFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+			},
+		},
+	}
+}
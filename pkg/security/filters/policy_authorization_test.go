@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/security/http/httpfakes"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Policy authorization filter", func() {
+	var (
+		req        *web.Request
+		handler    *webfakes.FakeHandler
+		authorizer *httpfakes.FakeAuthorizer
+		filter     web.Filter
+	)
+
+	BeforeEach(func() {
+		req = &web.Request{
+			Request: httptest.NewRequest("GET", "/", nil),
+		}
+		req.Request = req.WithContext(web.ContextWithUser(req.Context(), &web.UserContext{AuthenticationType: web.Bearer}))
+		handler = &webfakes.FakeHandler{}
+		authorizer = &httpfakes.FakeAuthorizer{}
+		filter = NewPolicyAuthorizationFilter(authorizer)
+	})
+
+	Context("when the policies allow the request", func() {
+		It("marks the request as authorized with the access level of the policy", func() {
+			authorizer.AuthorizeReturns(httpsec.Allow, web.TenantAccess, nil)
+			_, err := filter.Run(req, handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.HandleCallCount()).To(Equal(1))
+
+			authorizedReq := handler.HandleArgsForCall(0)
+			Expect(web.IsAuthorized(authorizedReq.Context())).To(BeTrue())
+			user, _ := web.UserFromContext(authorizedReq.Context())
+			Expect(user.AccessLevel).To(Equal(web.TenantAccess))
+		})
+	})
+
+	Context("when the policies deny the request", func() {
+		It("should return 403", func() {
+			authorizer.AuthorizeReturns(httpsec.Deny, web.NoAccess, errors.New("no policy"))
+			_, err := filter.Run(req, handler)
+			httpErr, ok := err.(*util.HTTPError)
+			Expect(ok).To(BeTrue())
+			Expect(httpErr.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(handler.HandleCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the policies abstain", func() {
+		It("should continue without authorizing the request", func() {
+			authorizer.AuthorizeReturns(httpsec.Abstain, web.NoAccess, nil)
+			_, err := filter.Run(req, handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(web.IsAuthorized(handler.HandleArgsForCall(0).Context())).To(BeFalse())
+		})
+
+		It("should return the authorization error", func() {
+			authorizer.AuthorizeReturns(httpsec.Abstain, web.NoAccess, errors.New("expected"))
+			_, err := filter.Run(req, handler)
+			Expect(err).To(MatchError("expected"))
+			Expect(handler.HandleCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// FileKeyProviderType is the type of the key provider which reads the key encryption keys from a local file
+	FileKeyProviderType = "file"
+	// HTTPKeyProviderType is the type of the key provider which delegates to an external key management service over HTTP
+	HTTPKeyProviderType = "http"
+
+	dataKeySize = 32
+)
+
+// DataKey is a key used for encrypting a single record. It is stored next to the record only in its wrapped form -
+// encrypted with the key encryption key with ID KeyID, which never leaves the key provider
+type DataKey struct {
+	KeyID     string `json:"key_id"`
+	Plaintext []byte `json:"plaintext"`
+	Wrapped   []byte `json:"ciphertext"`
+}
+
+// KeyProvider generates data keys and unwraps them using key encryption keys held outside of the Service Manager,
+// e.g. in a KMS or an HSM
+//
+//go:generate counterfeiter . KeyProvider
+type KeyProvider interface {
+	// GenerateDataKey generates a new data key wrapped with the current key encryption key
+	GenerateDataKey(ctx context.Context) (*DataKey, error)
+
+	// UnwrapDataKey decrypts a data key wrapped with the key encryption key with the specified ID
+	UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// KeyProviderSettings type to be loaded from the environment
+type KeyProviderSettings struct {
+	Type            string        `mapstructure:"type" description:"type of the provider of the key encryption keys - file or http. The storage encryption key is used if empty"`
+	KeyID           string        `mapstructure:"key_id" description:"ID of the key encryption key used for wrapping new data keys"`
+	File            string        `mapstructure:"file" description:"path to a JSON file mapping key IDs to base64 encoded 32 bytes long key encryption keys"`
+	URL             string        `mapstructure:"url" description:"URL of the key management service"`
+	Token           string        `mapstructure:"token" description:"bearer token used for authenticating to the key management service"`
+	Timeout         time.Duration `mapstructure:"timeout" description:"timeout of a single request to the key management service"`
+	DataKeyLifetime time.Duration `mapstructure:"data_key_lifetime" description:"time for which a data key is used for encrypting new values"`
+	DataKeyMaxUses  int           `mapstructure:"data_key_max_uses" description:"maximum number of values encrypted with a single data key"`
+}
+
+// DefaultKeyProviderSettings returns default values for the key provider settings
+func DefaultKeyProviderSettings() *KeyProviderSettings {
+	return &KeyProviderSettings{
+		Timeout:         10 * time.Second,
+		DataKeyLifetime: 5 * time.Minute,
+		DataKeyMaxUses:  1000,
+	}
+}
+
+// Validate validates the key provider settings
+func (s *KeyProviderSettings) Validate() error {
+	switch s.Type {
+	case "":
+		return nil
+	case FileKeyProviderType:
+		if len(s.File) == 0 {
+			return fmt.Errorf("validate key provider settings: file missing")
+		}
+	case HTTPKeyProviderType:
+		if len(s.URL) == 0 {
+			return fmt.Errorf("validate key provider settings: url missing")
+		}
+		if s.Timeout <= 0 {
+			return fmt.Errorf("validate key provider settings: timeout should be > 0")
+		}
+	default:
+		return fmt.Errorf("validate key provider settings: unsupported type %s", s.Type)
+	}
+	if len(s.KeyID) == 0 {
+		return fmt.Errorf("validate key provider settings: key_id missing")
+	}
+	if s.DataKeyLifetime <= 0 {
+		return fmt.Errorf("validate key provider settings: data_key_lifetime should be > 0")
+	}
+	if s.DataKeyMaxUses < 1 {
+		return fmt.Errorf("validate key provider settings: data_key_max_uses should be > 0")
+	}
+	return nil
+}
+
+// NewKeyProvider creates the key provider configured in the settings. It returns nil if no key provider is configured
+func NewKeyProvider(settings *KeyProviderSettings) (KeyProvider, error) {
+	switch settings.Type {
+	case "":
+		return nil, nil
+	case FileKeyProviderType:
+		return NewFileKeyProvider(settings.File, settings.KeyID)
+	case HTTPKeyProviderType:
+		return NewHTTPKeyProvider(settings.URL, settings.KeyID, settings.Token, settings.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unsupported key provider type %s", settings.Type)
+	}
+}
+
+// FileKeyProvider is a KeyProvider which wraps the data keys locally with key encryption keys loaded from a file.
+// Similarly to a PKCS#11 token, the key encryption keys are referenced only by their IDs
+type FileKeyProvider struct {
+	keyID     string
+	keys      map[string][]byte
+	encrypter Encrypter
+}
+
+// NewFileKeyProvider creates a FileKeyProvider which wraps new data keys with the key with the specified ID.
+// The file contains a JSON object mapping key IDs to base64 encoded 32 bytes long keys
+func NewFileKeyProvider(path, keyID string) (*FileKeyProvider, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key encryption keys file: %v", err)
+	}
+	encodedKeys := make(map[string]string)
+	if err := json.Unmarshal(content, &encodedKeys); err != nil {
+		return nil, fmt.Errorf("could not parse key encryption keys file: %v", err)
+	}
+
+	keys := make(map[string][]byte, len(encodedKeys))
+	for id, encodedKey := range encodedKeys {
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode key encryption key %s: %v", id, err)
+		}
+		if len(key) != dataKeySize {
+			return nil, fmt.Errorf("key encryption key %s must be exactly %d bytes long but was %d bytes long", id, dataKeySize, len(key))
+		}
+		keys[id] = key
+	}
+	if _, found := keys[keyID]; !found {
+		return nil, fmt.Errorf("key encryption key %s not found in %s", keyID, path)
+	}
+
+	return &FileKeyProvider{
+		keyID:     keyID,
+		keys:      keys,
+		encrypter: &AESEncrypter{},
+	}, nil
+}
+
+// GenerateDataKey generates a new data key wrapped with the current key encryption key
+func (p *FileKeyProvider) GenerateDataKey(ctx context.Context) (*DataKey, error) {
+	plaintext := make([]byte, dataKeySize)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, fmt.Errorf("could not generate data key: %v", err)
+	}
+	wrapped, err := p.encrypter.Encrypt(ctx, plaintext, p.keys[p.keyID])
+	if err != nil {
+		return nil, err
+	}
+	return &DataKey{
+		KeyID:     p.keyID,
+		Plaintext: plaintext,
+		Wrapped:   wrapped,
+	}, nil
+}
+
+// UnwrapDataKey decrypts a data key wrapped with the key encryption key with the specified ID
+func (p *FileKeyProvider) UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, found := p.keys[keyID]
+	if !found {
+		return nil, fmt.Errorf("key encryption key %s not found", keyID)
+	}
+	return p.encrypter.Decrypt(ctx, wrapped, key)
+}
+
+// HTTPKeyProvider is a KeyProvider which delegates to an external key management service. New data keys are
+// generated with POST {url}/data_keys and unwrapped with POST {url}/data_keys/unwrap. All keys are base64 encoded
+type HTTPKeyProvider struct {
+	url    string
+	keyID  string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPKeyProvider creates an HTTPKeyProvider for the key management service at the specified URL
+func NewHTTPKeyProvider(url, keyID, token string, timeout time.Duration) *HTTPKeyProvider {
+	return &HTTPKeyProvider{
+		url:    strings.TrimSuffix(url, "/"),
+		keyID:  keyID,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// GenerateDataKey generates a new data key wrapped with the current key encryption key
+func (p *HTTPKeyProvider) GenerateDataKey(ctx context.Context) (*DataKey, error) {
+	dataKey := &DataKey{}
+	if err := p.call(ctx, "/data_keys", &DataKey{KeyID: p.keyID}, dataKey); err != nil {
+		return nil, fmt.Errorf("could not generate data key: %v", err)
+	}
+	if len(dataKey.Plaintext) != dataKeySize || len(dataKey.Wrapped) == 0 {
+		return nil, fmt.Errorf("could not generate data key: key management service returned an invalid data key")
+	}
+	if len(dataKey.KeyID) == 0 {
+		dataKey.KeyID = p.keyID
+	}
+	return dataKey, nil
+}
+
+// UnwrapDataKey decrypts a data key wrapped with the key encryption key with the specified ID
+func (p *HTTPKeyProvider) UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	dataKey := &DataKey{}
+	if err := p.call(ctx, "/data_keys/unwrap", &DataKey{KeyID: keyID, Wrapped: wrapped}, dataKey); err != nil {
+		return nil, fmt.Errorf("could not unwrap data key: %v", err)
+	}
+	return dataKey.Plaintext, nil
+}
+
+func (p *HTTPKeyProvider) call(ctx context.Context, path string, requestBody, responseBody *DataKey) error {
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, p.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(p.token) != 0 {
+		request.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	response, err := p.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("key management service responded with status %d", response.StatusCode)
+	}
+	return json.NewDecoder(response.Body).Decode(responseBody)
+}
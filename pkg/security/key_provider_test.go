@@ -0,0 +1,265 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/security"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Key providers", func() {
+	var (
+		ctx     context.Context
+		dir     string
+		keyFile string
+	)
+
+	writeKeys := func(keys map[string]string) {
+		content, err := json.Marshal(keys)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(keyFile, content, 0600)).To(Succeed())
+	}
+
+	encodedKey := func(key string) string {
+		return base64.StdEncoding.EncodeToString([]byte(key))
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		var err error
+		dir, err = ioutil.TempDir("", "keys")
+		Expect(err).ToNot(HaveOccurred())
+		keyFile = filepath.Join(dir, "keys.json")
+		writeKeys(map[string]string{
+			"key-1": encodedKey("ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"),
+			"key-2": encodedKey("Z9D2xZRHqHJr3vkkEC8Ww1MY5r8YsDvM"),
+		})
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Describe("FileKeyProvider", func() {
+		It("wraps new data keys with the configured key encryption key", func() {
+			provider, err := security.NewFileKeyProvider(keyFile, "key-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			dataKey, err := provider.GenerateDataKey(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dataKey.KeyID).To(Equal("key-1"))
+			Expect(dataKey.Plaintext).To(HaveLen(32))
+			Expect(dataKey.Wrapped).ToNot(ContainSubstring(string(dataKey.Plaintext)))
+
+			plaintext, err := provider.UnwrapDataKey(ctx, dataKey.KeyID, dataKey.Wrapped)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plaintext).To(Equal(dataKey.Plaintext))
+		})
+
+		It("unwraps data keys wrapped with a previous key encryption key", func() {
+			provider, err := security.NewFileKeyProvider(keyFile, "key-1")
+			Expect(err).ToNot(HaveOccurred())
+			dataKey, err := provider.GenerateDataKey(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			provider, err = security.NewFileKeyProvider(keyFile, "key-2")
+			Expect(err).ToNot(HaveOccurred())
+			plaintext, err := provider.UnwrapDataKey(ctx, dataKey.KeyID, dataKey.Wrapped)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plaintext).To(Equal(dataKey.Plaintext))
+		})
+
+		Context("when the key encryption key is unknown", func() {
+			It("fails to unwrap the data key", func() {
+				provider, err := security.NewFileKeyProvider(keyFile, "key-1")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = provider.UnwrapDataKey(ctx, "key-3", []byte("wrapped"))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the configured key ID is missing from the file", func() {
+			It("returns an error", func() {
+				_, err := security.NewFileKeyProvider(keyFile, "key-3")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a key is not 32 bytes long", func() {
+			It("returns an error", func() {
+				writeKeys(map[string]string{"key-1": encodedKey("short")})
+				_, err := security.NewFileKeyProvider(keyFile, "key-1")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the file does not exist", func() {
+			It("returns an error", func() {
+				_, err := security.NewFileKeyProvider(filepath.Join(dir, "missing.json"), "key-1")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("HTTPKeyProvider", func() {
+		var (
+			server        *httptest.Server
+			provider      *security.HTTPKeyProvider
+			authorization string
+			status        int
+		)
+
+		// the stub key management service delegates to a file key provider
+		stub := func(kms security.KeyProvider) http.Handler {
+			mux := http.NewServeMux()
+			respond := func(w http.ResponseWriter, r *http.Request, dataKey func(request *security.DataKey) (*security.DataKey, error)) {
+				authorization = r.Header.Get("Authorization")
+				if status != http.StatusOK {
+					w.WriteHeader(status)
+					return
+				}
+				request := &security.DataKey{}
+				Expect(json.NewDecoder(r.Body).Decode(request)).To(Succeed())
+				response, err := dataKey(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(json.NewEncoder(w).Encode(response)).To(Succeed())
+			}
+			mux.HandleFunc("/data_keys", func(w http.ResponseWriter, r *http.Request) {
+				respond(w, r, func(request *security.DataKey) (*security.DataKey, error) {
+					Expect(request.KeyID).To(Equal("key-1"))
+					return kms.GenerateDataKey(ctx)
+				})
+			})
+			mux.HandleFunc("/data_keys/unwrap", func(w http.ResponseWriter, r *http.Request) {
+				respond(w, r, func(request *security.DataKey) (*security.DataKey, error) {
+					plaintext, err := kms.UnwrapDataKey(ctx, request.KeyID, request.Wrapped)
+					return &security.DataKey{Plaintext: plaintext}, err
+				})
+			})
+			return mux
+		}
+
+		BeforeEach(func() {
+			kms, err := security.NewFileKeyProvider(keyFile, "key-1")
+			Expect(err).ToNot(HaveOccurred())
+			status = http.StatusOK
+			server = httptest.NewServer(stub(kms))
+			provider = security.NewHTTPKeyProvider(server.URL+"/", "key-1", "token", time.Second)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("generates and unwraps data keys using the key management service", func() {
+			dataKey, err := provider.GenerateDataKey(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dataKey.KeyID).To(Equal("key-1"))
+			Expect(dataKey.Plaintext).To(HaveLen(32))
+			Expect(authorization).To(Equal("Bearer token"))
+
+			plaintext, err := provider.UnwrapDataKey(ctx, dataKey.KeyID, dataKey.Wrapped)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plaintext).To(Equal(dataKey.Plaintext))
+		})
+
+		Context("when the key management service responds with an error", func() {
+			It("returns an error", func() {
+				status = http.StatusForbidden
+				_, err := provider.GenerateDataKey(ctx)
+				Expect(err).To(MatchError(ContainSubstring("403")))
+				_, err = provider.UnwrapDataKey(ctx, "key-1", []byte("wrapped"))
+				Expect(err).To(MatchError(ContainSubstring("403")))
+			})
+		})
+	})
+
+	Describe("NewKeyProvider", func() {
+		It("creates the configured key provider", func() {
+			settings := security.DefaultKeyProviderSettings()
+			provider, err := security.NewKeyProvider(settings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(provider).To(BeNil())
+
+			settings.Type, settings.File, settings.KeyID = security.FileKeyProviderType, keyFile, "key-1"
+			provider, err = security.NewKeyProvider(settings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(provider).To(BeAssignableToTypeOf(&security.FileKeyProvider{}))
+
+			settings.Type, settings.URL = security.HTTPKeyProviderType, "http://localhost"
+			provider, err = security.NewKeyProvider(settings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(provider).To(BeAssignableToTypeOf(&security.HTTPKeyProvider{}))
+		})
+	})
+
+	Describe("KeyProviderSettings", func() {
+		var settings *security.KeyProviderSettings
+
+		BeforeEach(func() {
+			settings = security.DefaultKeyProviderSettings()
+			settings.KeyID = "key-1"
+		})
+
+		It("is valid when no key provider is configured", func() {
+			Expect(security.DefaultKeyProviderSettings().Validate()).To(Succeed())
+		})
+
+		It("requires a file for the file key provider", func() {
+			settings.Type = security.FileKeyProviderType
+			Expect(settings.Validate()).ToNot(Succeed())
+			settings.File = keyFile
+			Expect(settings.Validate()).To(Succeed())
+		})
+
+		It("requires a URL for the http key provider", func() {
+			settings.Type = security.HTTPKeyProviderType
+			Expect(settings.Validate()).ToNot(Succeed())
+			settings.URL = "http://localhost"
+			Expect(settings.Validate()).To(Succeed())
+		})
+
+		It("requires a key ID", func() {
+			settings.Type, settings.File, settings.KeyID = security.FileKeyProviderType, keyFile, ""
+			Expect(settings.Validate()).ToNot(Succeed())
+		})
+
+		It("requires the data keys to be used for a limited time and number of values", func() {
+			settings.Type, settings.File = security.FileKeyProviderType, keyFile
+			settings.DataKeyLifetime = 0
+			Expect(settings.Validate()).ToNot(Succeed())
+			settings.DataKeyLifetime, settings.DataKeyMaxUses = time.Minute, 0
+			Expect(settings.Validate()).ToNot(Succeed())
+		})
+
+		It("rejects unsupported types", func() {
+			settings.Type = "pkcs11"
+			Expect(settings.Validate()).ToNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,362 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util/slice"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/fsnotify/fsnotify"
+	"github.com/tidwall/gjson"
+)
+
+const anything = "*"
+
+// references maps the fields referencing other resources to the type of the referenced resource
+var references = map[string]types.ObjectType{
+	"platform_id":         types.PlatformType,
+	"broker_id":           types.ServiceBrokerType,
+	"service_offering_id": types.ServiceOfferingType,
+	"service_plan_id":     types.ServicePlanType,
+	"service_instance_id": types.ServiceInstanceType,
+}
+
+// Authorizer authorizes the requests of users authenticated with a bearer token according to the configured policies.
+// The policies can be reloaded while the Service Manager is running
+type Authorizer struct {
+	repository storage.Repository
+
+	mutex    sync.RWMutex
+	roles    []RoleMapping
+	policies []*compiledPolicy
+}
+
+// NewAuthorizer creates an Authorizer applying the policies from the settings. The repository is used for checking
+// the labels of the resources referenced by the requests
+func NewAuthorizer(settings *Settings, repository storage.Repository) (*Authorizer, error) {
+	authorizer := &Authorizer{
+		repository: repository,
+	}
+	if err := authorizer.Reload(settings); err != nil {
+		return nil, err
+	}
+	return authorizer, nil
+}
+
+// Reload replaces the applied role mappings and policies with the ones from the settings
+func (a *Authorizer) Reload(settings *Settings) error {
+	policies := make([]*compiledPolicy, 0, len(settings.Policies))
+	for i := range settings.Policies {
+		policy, err := compile(&settings.Policies[i])
+		if err != nil {
+			return fmt.Errorf("invalid policy %d: %s", i, err)
+		}
+		policies = append(policies, policy)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.roles = settings.Roles
+	a.policies = policies
+	return nil
+}
+
+// OnConfigChange is an env.Environment config change handler which reloads the policies from the environment
+func (a *Authorizer) OnConfigChange(e env.Environment) func(event fsnotify.Event) {
+	return func(event fsnotify.Event) {
+		if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+			return
+		}
+		ctx := context.Background()
+		settings := struct {
+			Authorization *Settings
+		}{Authorization: DefaultSettings()}
+		if err := e.Unmarshal(&settings); err != nil {
+			log.C(ctx).WithError(err).Error("Could not load authorization policies after configuration change")
+			return
+		}
+		if err := a.Reload(settings.Authorization); err != nil {
+			log.C(ctx).WithError(err).Error("Could not reload authorization policies. Keeping the previous ones")
+			return
+		}
+		log.C(ctx).Infof("Reloaded %d authorization policies", len(settings.Authorization.Policies))
+	}
+}
+
+// Authorize implements httpsec.Authorizer. It allows the request if one of the policies of the user roles allows it
+// and applies the label query of the policy to the request. It abstains if no policy of the user roles matches the
+// requested resource and method, so that the request is authorized by the other authorizers
+func (a *Authorizer) Authorize(request *web.Request) (httpsec.Decision, web.AccessLevel, error) {
+	ctx := request.Context()
+	user, found := web.UserFromContext(ctx)
+	if !found || user.AuthenticationType != web.Bearer {
+		return httpsec.Abstain, web.NoAccess, nil
+	}
+
+	claims := make(map[string]interface{})
+	if err := user.Data(&claims); err != nil {
+		return httpsec.Deny, web.NoAccess, fmt.Errorf("could not extract claims from token: %v", err)
+	}
+
+	a.mutex.RLock()
+	roles := rolesOf(a.roles, claims)
+	policies := a.policies
+	a.mutex.RUnlock()
+
+	matched := false
+	for _, policy := range policies {
+		if !roles[policy.Role] || !policy.matches(request) {
+			continue
+		}
+		matched = true
+		allowed, err := a.apply(request, policy)
+		if err != nil {
+			return httpsec.Abstain, web.NoAccess, err
+		}
+		if allowed {
+			log.C(ctx).Debugf("Request %s %s allowed by policy of role %s", request.Method, request.URL.Path, policy.Role)
+			return httpsec.Allow, policy.accessLevel, nil
+		}
+	}
+
+	if !matched {
+		return httpsec.Abstain, web.NoAccess, nil
+	}
+
+	roleNames := make([]string, 0, len(roles))
+	for role := range roles {
+		roleNames = append(roleNames, role)
+	}
+	sort.Strings(roleNames)
+	return httpsec.Deny, web.NoAccess, fmt.Errorf("no policy of roles %v allows %s %s", roleNames, request.Method, request.URL.Path)
+}
+
+// apply checks the field and label restrictions of the policy. The label query is added to the criteria of the
+// request if it is applied to the requested resources
+func (a *Authorizer) apply(request *web.Request, policy *compiledPolicy) (bool, error) {
+	body := gjson.ParseBytes(request.Body)
+	if len(policy.Fields) != 0 && len(request.Body) != 0 {
+		allowed := true
+		body.ForEach(func(key, _ gjson.Result) bool {
+			allowed = slice.StringsAnyEquals(policy.Fields, key.String())
+			return allowed
+		})
+		if !allowed {
+			return false, nil
+		}
+	}
+
+	if len(policy.criteria) == 0 {
+		return true, nil
+	}
+
+	if len(policy.Reference) != 0 {
+		id := body.Get(policy.Reference).String()
+		if len(id) == 0 {
+			return false, nil
+		}
+		criteria := append([]query.Criterion{query.ByField(query.EqualsOperator, "id", id)}, policy.criteria...)
+		count, err := a.repository.Count(request.Context(), policy.referenceType, criteria...)
+		if err != nil {
+			return false, fmt.Errorf("could not check labels of %s %s: %s", policy.referenceType, id, err)
+		}
+		return count > 0, nil
+	}
+
+	if request.Method == http.MethodPost {
+		labels := types.Labels{}
+		for key, values := range body.Get("labels").Map() {
+			for _, value := range values.Array() {
+				labels[key] = append(labels[key], value.String())
+			}
+		}
+		return matchesAll(policy.criteria, labels), nil
+	}
+
+	if request.Method == http.MethodPatch {
+		// the labels restricting the resources of the policy cannot be changed, otherwise the resources can be moved
+		// out of (or into) the ones allowed by the policy
+		for _, labelChange := range body.Get("labels").Array() {
+			if policy.restrictsLabel(labelChange.Get("key").String()) {
+				return false, nil
+			}
+		}
+	}
+
+	ctx, err := query.AddCriteria(request.Context(), policy.criteria...)
+	if err != nil {
+		return false, err
+	}
+	request.Request = request.WithContext(ctx)
+	return true, nil
+}
+
+type compiledPolicy struct {
+	*Policy
+	criteria      []query.Criterion
+	referenceType types.ObjectType
+	accessLevel   web.AccessLevel
+}
+
+func compile(policy *Policy) (*compiledPolicy, error) {
+	if len(policy.Role) == 0 {
+		return nil, fmt.Errorf("role missing")
+	}
+	if len(policy.Resources) == 0 || len(policy.Methods) == 0 {
+		return nil, fmt.Errorf("resources and methods are required")
+	}
+
+	if len(policy.AccessLevel) == 0 {
+		return nil, fmt.Errorf("access level missing")
+	}
+	accessLevel, err := web.ParseAccessLevel(policy.AccessLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	criteria, err := query.Parse(query.LabelQuery, policy.LabelQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label query: %s", err)
+	}
+
+	var referenceType types.ObjectType
+	if len(policy.Reference) != 0 {
+		var found bool
+		if referenceType, found = references[policy.Reference]; !found {
+			return nil, fmt.Errorf("unsupported reference %s", policy.Reference)
+		}
+		if len(criteria) == 0 {
+			return nil, fmt.Errorf("reference %s requires a label query", policy.Reference)
+		}
+	}
+
+	return &compiledPolicy{
+		Policy:        policy,
+		criteria:      criteria,
+		referenceType: referenceType,
+		accessLevel:   accessLevel,
+	}, nil
+}
+
+// restrictsLabel returns whether the label query of the policy restricts the values of the label with the specified key
+func (p *compiledPolicy) restrictsLabel(key string) bool {
+	return restrictsLabel(p.criteria, key)
+}
+
+func restrictsLabel(criteria []query.Criterion, key string) bool {
+	for _, criterion := range criteria {
+		if criterion.LeftOp == key || restrictsLabel(criterion.Criteria, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *compiledPolicy) matches(request *web.Request) bool {
+	methodMatches := false
+	for _, method := range p.Methods {
+		if method == anything || strings.EqualFold(method, request.Method) {
+			methodMatches = true
+			break
+		}
+	}
+	if !methodMatches {
+		return false
+	}
+
+	path := strings.TrimSuffix(request.URL.Path, "/")
+	for _, resource := range p.Resources {
+		resource = strings.TrimSuffix(resource, "/")
+		if resource == anything || path == resource || strings.HasPrefix(path, resource+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// rolesOf returns the roles granted by the mappings to the user with the specified token claims
+func rolesOf(mappings []RoleMapping, claims map[string]interface{}) map[string]bool {
+	roles := make(map[string]bool)
+	for _, mapping := range mappings {
+		for _, value := range claimValues(claims[mapping.Claim]) {
+			if slice.StringsAnyEquals(mapping.Values, value) {
+				roles[mapping.Role] = true
+				break
+			}
+		}
+	}
+	return roles
+}
+
+func claimValues(claim interface{}) []string {
+	switch claim := claim.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values := make([]string, 0, len(claim))
+		for _, value := range claim {
+			values = append(values, fmt.Sprint(value))
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(claim)}
+	}
+}
+
+// matchesAll evaluates the label criteria against the labels of a resource which is not stored yet
+func matchesAll(criteria []query.Criterion, labels types.Labels) bool {
+	for _, criterion := range criteria {
+		if !matches(criterion, labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(criterion query.Criterion, labels types.Labels) bool {
+	switch criterion.Operator {
+	case query.AndOperator:
+		return matchesAll(criterion.Criteria, labels)
+	case query.OrOperator:
+		for _, nested := range criterion.Criteria {
+			if matches(nested, labels) {
+				return true
+			}
+		}
+		return false
+	case query.NotOperator:
+		return !matchesAll(criterion.Criteria, labels)
+	case query.EqualsOperator, query.InOperator:
+		return len(slice.StringsIntersection(labels[criterion.LeftOp], criterion.RightOp)) != 0
+	case query.NotEqualsOperator, query.NotInOperator:
+		return len(slice.StringsIntersection(labels[criterion.LeftOp], criterion.RightOp)) == 0
+	default:
+		return false
+	}
+}
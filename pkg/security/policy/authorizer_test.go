@@ -0,0 +1,310 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/security/policy"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authorizer", func() {
+	var (
+		settings   *policy.Settings
+		repository *storagefakes.FakeStorage
+		authorizer *policy.Authorizer
+		claims     map[string]interface{}
+	)
+
+	newRequest := func(method, path, body string) *web.Request {
+		request := &web.Request{
+			Request: httptest.NewRequest(method, path, nil),
+			Body:    []byte(body),
+		}
+		request.Request = request.WithContext(web.ContextWithUser(request.Context(), &web.UserContext{
+			Data: func(data interface{}) error {
+				bytes, err := json.Marshal(claims)
+				if err != nil {
+					return err
+				}
+				return json.Unmarshal(bytes, data)
+			},
+			AuthenticationType: web.Bearer,
+			Name:               "user",
+		}))
+		return request
+	}
+
+	authorize := func(request *web.Request) (httpsec.Decision, web.AccessLevel, error) {
+		var err error
+		authorizer, err = policy.NewAuthorizer(settings, repository)
+		Expect(err).ToNot(HaveOccurred())
+		return authorizer.Authorize(request)
+	}
+
+	BeforeEach(func() {
+		repository = &storagefakes.FakeStorage{}
+		claims = map[string]interface{}{
+			"groups": []string{"operators"},
+		}
+		settings = policy.DefaultSettings()
+		settings.Roles = []policy.RoleMapping{
+			{Role: "operator", Claim: "groups", Values: []string{"operators"}},
+			{Role: "auditor", Claim: "cid", Values: []string{"audit-client"}},
+		}
+	})
+
+	Context("when the user is not authenticated with a bearer token", func() {
+		It("abstains", func() {
+			settings.Policies = []policy.Policy{{Role: "operator", Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "global"}}
+			request := newRequest(http.MethodGet, web.PlatformsURL, "")
+			user, _ := web.UserFromContext(request.Context())
+			user.AuthenticationType = web.Basic
+
+			decision, _, err := authorize(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+	})
+
+	Context("when the role of the user has a matching policy", func() {
+		BeforeEach(func() {
+			settings.Policies = []policy.Policy{
+				{Role: "auditor", Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "global"},
+				{Role: "operator", Resources: []string{web.PlatformsURL}, Methods: []string{"get"}, AccessLevel: "all_tenants"},
+			}
+		})
+
+		It("allows the request with the access level of the policy", func() {
+			decision, accessLevel, err := authorize(newRequest(http.MethodGet, web.PlatformsURL+"/123", ""))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+			Expect(accessLevel).To(Equal(web.AllTenantAccess))
+		})
+
+		It("abstains from other methods", func() {
+			decision, _, err := authorize(newRequest(http.MethodDelete, web.PlatformsURL+"/123", ""))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+
+		It("abstains from other resources", func() {
+			decision, _, _ := authorize(newRequest(http.MethodGet, web.ServiceBrokersURL, ""))
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+
+		It("does not match resources sharing only a prefix", func() {
+			decision, _, _ := authorize(newRequest(http.MethodGet, web.PlatformsURL+"_other", ""))
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+
+		It("grants roles from single valued claims", func() {
+			claims = map[string]interface{}{"cid": "audit-client"}
+			decision, _, err := authorize(newRequest(http.MethodDelete, web.ServiceBrokersURL+"/123", ""))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+		})
+	})
+
+	Context("when the user has no roles", func() {
+		It("abstains", func() {
+			claims = map[string]interface{}{"groups": []string{"developers"}}
+			settings.Policies = []policy.Policy{{Role: "operator", Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "global"}}
+
+			decision, _, err := authorize(newRequest(http.MethodGet, web.PlatformsURL, ""))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+	})
+
+	Context("when the policy restricts the fields", func() {
+		BeforeEach(func() {
+			settings.Policies = []policy.Policy{{Role: "operator", Resources: []string{web.PlatformsURL}, Methods: []string{http.MethodPatch}, Fields: []string{"description", "labels"}, AccessLevel: "global"}}
+		})
+
+		It("allows requests changing only these fields", func() {
+			decision, _, _ := authorize(newRequest(http.MethodPatch, web.PlatformsURL+"/123", `{"description": "new"}`))
+			Expect(decision).To(Equal(httpsec.Allow))
+		})
+
+		It("denies requests changing other fields", func() {
+			decision, _, err := authorize(newRequest(http.MethodPatch, web.PlatformsURL+"/123", `{"description": "new", "name": "new"}`))
+			Expect(err).To(MatchError(ContainSubstring("no policy of roles [operator] allows PATCH")))
+			Expect(decision).To(Equal(httpsec.Deny))
+		})
+	})
+
+	Context("when the policy has a label query", func() {
+		BeforeEach(func() {
+			settings.Policies = []policy.Policy{{Role: "operator", Resources: []string{web.PlatformsURL}, Methods: []string{"*"}, LabelQuery: "region eq 'eu' and env ne 'prod'", AccessLevel: "global"}}
+		})
+
+		It("restricts the requested resources to the ones matching the query", func() {
+			request := newRequest(http.MethodGet, web.PlatformsURL, "")
+			decision, _, err := authorize(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+
+			criteria := query.CriteriaForContext(request.Context())
+			Expect(criteria).To(ConsistOf(
+				query.ByLabel(query.EqualsOperator, "region", "eu"),
+				query.ByLabel(query.NotEqualsOperator, "env", "prod"),
+			))
+		})
+
+		It("allows creating resources with matching labels", func() {
+			decision, _, _ := authorize(newRequest(http.MethodPost, web.PlatformsURL, `{"name": "p", "labels": {"region": ["eu"]}}`))
+			Expect(decision).To(Equal(httpsec.Allow))
+		})
+
+		It("allows changing the labels not restricted by the query", func() {
+			decision, _, _ := authorize(newRequest(http.MethodPatch, web.PlatformsURL+"/123", `{"labels": [{"op": "add", "key": "team", "values": ["a"]}]}`))
+			Expect(decision).To(Equal(httpsec.Allow))
+		})
+
+		It("denies changing the labels restricted by the query", func() {
+			decision, _, _ := authorize(newRequest(http.MethodPatch, web.PlatformsURL+"/123", `{"labels": [{"op": "add", "key": "region", "values": ["us"]}]}`))
+			Expect(decision).To(Equal(httpsec.Deny))
+
+			decision, _, _ = authorize(newRequest(http.MethodPatch, web.PlatformsURL+"/123", `{"labels": [{"op": "remove", "key": "env"}]}`))
+			Expect(decision).To(Equal(httpsec.Deny))
+		})
+
+		It("denies creating resources with labels not matching the query", func() {
+			decision, _, _ := authorize(newRequest(http.MethodPost, web.PlatformsURL, `{"name": "p", "labels": {"region": ["eu"], "env": ["prod"]}}`))
+			Expect(decision).To(Equal(httpsec.Deny))
+
+			decision, _, _ = authorize(newRequest(http.MethodPost, web.PlatformsURL, `{"name": "p"}`))
+			Expect(decision).To(Equal(httpsec.Deny))
+		})
+	})
+
+	Context("when the policy has a compound label query", func() {
+		BeforeEach(func() {
+			settings.Policies = []policy.Policy{{Role: "operator", Resources: []string{web.PlatformsURL}, Methods: []string{"*"}, LabelQuery: "region eq 'eu' or region eq 'us'", AccessLevel: "global"}}
+		})
+
+		It("allows changing the labels not restricted by the query", func() {
+			decision, _, _ := authorize(newRequest(http.MethodPatch, web.PlatformsURL+"/123", `{"labels": [{"op": "add", "key": "team", "values": ["a"]}]}`))
+			Expect(decision).To(Equal(httpsec.Allow))
+		})
+
+		It("denies changing the labels restricted by the nested criteria", func() {
+			decision, _, _ := authorize(newRequest(http.MethodPatch, web.PlatformsURL+"/123", `{"labels": [{"op": "add", "key": "region", "values": ["apac"]}]}`))
+			Expect(decision).To(Equal(httpsec.Deny))
+
+			decision, _, _ = authorize(newRequest(http.MethodPatch, web.PlatformsURL+"/123", `{"labels": [{"op": "remove", "key": "region"}]}`))
+			Expect(decision).To(Equal(httpsec.Deny))
+		})
+	})
+
+	Context("when the policy applies the label query to a referenced resource", func() {
+		BeforeEach(func() {
+			settings.Policies = []policy.Policy{{Role: "operator", Resources: []string{web.VisibilitiesURL}, Methods: []string{http.MethodPost}, LabelQuery: "region eq 'eu'", Reference: "platform_id", AccessLevel: "global"}}
+		})
+
+		It("allows the request if the referenced resource matches the query", func() {
+			repository.CountReturns(1, nil)
+			decision, _, err := authorize(newRequest(http.MethodPost, web.VisibilitiesURL, `{"platform_id": "123"}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+
+			_, objectType, criteria := repository.CountArgsForCall(0)
+			Expect(objectType).To(Equal(types.PlatformType))
+			Expect(criteria).To(ConsistOf(
+				query.ByField(query.EqualsOperator, "id", "123"),
+				query.ByLabel(query.EqualsOperator, "region", "eu"),
+			))
+		})
+
+		It("denies the request if the referenced resource does not match the query", func() {
+			repository.CountReturns(0, nil)
+			decision, _, _ := authorize(newRequest(http.MethodPost, web.VisibilitiesURL, `{"platform_id": "123"}`))
+			Expect(decision).To(Equal(httpsec.Deny))
+		})
+
+		It("returns an error if the referenced resource cannot be checked", func() {
+			repository.CountReturns(0, errors.New("expected"))
+			decision, _, err := authorize(newRequest(http.MethodPost, web.VisibilitiesURL, `{"platform_id": "123"}`))
+			Expect(err).To(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+	})
+
+	Describe("Reload", func() {
+		It("replaces the applied policies", func() {
+			settings.Policies = []policy.Policy{{Role: "operator", Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "global"}}
+			decision, _, _ := authorize(newRequest(http.MethodGet, web.PlatformsURL, ""))
+			Expect(decision).To(Equal(httpsec.Allow))
+
+			Expect(authorizer.Reload(policy.DefaultSettings())).To(Succeed())
+			decision, _, _ = authorizer.Authorize(newRequest(http.MethodGet, web.PlatformsURL, ""))
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+
+		It("keeps the applied policies if the new ones are invalid", func() {
+			settings.Policies = []policy.Policy{{Role: "operator", Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "global"}}
+			authorize(newRequest(http.MethodGet, web.PlatformsURL, ""))
+
+			invalid := policy.DefaultSettings()
+			invalid.Policies = []policy.Policy{{Role: "operator", Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "unknown"}}
+			Expect(authorizer.Reload(invalid)).ToNot(Succeed())
+
+			decision, _, _ := authorizer.Authorize(newRequest(http.MethodGet, web.PlatformsURL, ""))
+			Expect(decision).To(Equal(httpsec.Allow))
+		})
+	})
+})
+
+var _ = Describe("Settings", func() {
+	var settings *policy.Settings
+
+	BeforeEach(func() {
+		settings = policy.DefaultSettings()
+	})
+
+	It("accepts the default settings", func() {
+		Expect(settings.Validate()).To(Succeed())
+	})
+
+	DescribeTable("returns an error for invalid settings",
+		func(mapping policy.RoleMapping, p policy.Policy) {
+			settings.Roles = []policy.RoleMapping{mapping}
+			settings.Policies = []policy.Policy{p}
+			Expect(settings.Validate()).ToNot(Succeed())
+		},
+		Entry("role mapping without claim", policy.RoleMapping{Role: "r", Values: []string{"v"}}, policy.Policy{Role: "r", Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "global"}),
+		Entry("policy without role", policy.RoleMapping{Role: "r", Claim: "c", Values: []string{"v"}}, policy.Policy{Resources: []string{"*"}, Methods: []string{"*"}, AccessLevel: "global"}),
+		Entry("policy without methods", policy.RoleMapping{Role: "r", Claim: "c", Values: []string{"v"}}, policy.Policy{Role: "r", Resources: []string{"*"}, AccessLevel: "global"}),
+		Entry("invalid label query", policy.RoleMapping{Role: "r", Claim: "c", Values: []string{"v"}}, policy.Policy{Role: "r", Resources: []string{"*"}, Methods: []string{"*"}, LabelQuery: "region eq", AccessLevel: "global"}),
+		Entry("unsupported reference", policy.RoleMapping{Role: "r", Claim: "c", Values: []string{"v"}}, policy.Policy{Role: "r", Resources: []string{"*"}, Methods: []string{"*"}, LabelQuery: "a eq 'b'", Reference: "name", AccessLevel: "global"}),
+		Entry("policy without access level", policy.RoleMapping{Role: "r", Claim: "c", Values: []string{"v"}}, policy.Policy{Role: "r", Resources: []string{"*"}, Methods: []string{"*"}}),
+		Entry("reference without label query", policy.RoleMapping{Role: "r", Claim: "c", Values: []string{"v"}}, policy.Policy{Role: "r", Resources: []string{"*"}, Methods: []string{"*"}, Reference: "platform_id", AccessLevel: "global"}),
+	)
+})
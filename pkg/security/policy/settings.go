@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"fmt"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Enabled  bool          `mapstructure:"enabled" description:"whether requests authenticated with a bearer token are authorized by the configured policies"`
+	Roles    []RoleMapping `mapstructure:"roles" description:"mappings of token claims to roles"`
+	Policies []Policy      `mapstructure:"policies" description:"permissions granted to the roles. The first policy allowing a request is applied"`
+}
+
+// RoleMapping grants a role to the users whose token claim has one of the specified values
+type RoleMapping struct {
+	Role   string   `mapstructure:"role"`
+	Claim  string   `mapstructure:"claim"`
+	Values []string `mapstructure:"values"`
+}
+
+// Policy allows a role to perform the specified HTTP methods on the specified resources
+type Policy struct {
+	// Role is the role to which the policy applies
+	Role string `mapstructure:"role"`
+	// Resources are the base paths of the resources, e.g. /v1/visibilities, or * for all resources
+	Resources []string `mapstructure:"resources"`
+	// Methods are the allowed HTTP methods or * for all methods
+	Methods []string `mapstructure:"methods"`
+	// Fields restricts the top-level fields which may be present in the request body. All fields are allowed if empty
+	Fields []string `mapstructure:"fields"`
+	// LabelQuery restricts the resources to the ones matching the label query, e.g. region eq 'eu'
+	LabelQuery string `mapstructure:"label_query"`
+	// Reference is a field of the request body referencing the resource which should match the label query,
+	// e.g. platform_id. The label query is applied to the requested resource if empty
+	Reference string `mapstructure:"reference"`
+	// AccessLevel is the access level granted by the policy - global, all_tenants or tenant
+	AccessLevel string `mapstructure:"access_level"`
+}
+
+// DefaultSettings returns the default values for the authorization policies
+func DefaultSettings() *Settings {
+	return &Settings{
+		Enabled:  false,
+		Roles:    []RoleMapping{},
+		Policies: []Policy{},
+	}
+}
+
+// Validate validates the authorization policies
+func (s *Settings) Validate() error {
+	for _, mapping := range s.Roles {
+		if len(mapping.Role) == 0 || len(mapping.Claim) == 0 || len(mapping.Values) == 0 {
+			return fmt.Errorf("validate authorization settings: role mappings require role, claim and values")
+		}
+	}
+	for i := range s.Policies {
+		if _, err := compile(&s.Policies[i]); err != nil {
+			return fmt.Errorf("validate authorization settings: policy %d: %s", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package securityfakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/security"
+)
+
+type FakeKeyProvider struct {
+	GenerateDataKeyStub        func(context.Context) (*security.DataKey, error)
+	generateDataKeyMutex       sync.RWMutex
+	generateDataKeyArgsForCall []struct {
+		arg1 context.Context
+	}
+	generateDataKeyReturns struct {
+		result1 *security.DataKey
+		result2 error
+	}
+	generateDataKeyReturnsOnCall map[int]struct {
+		result1 *security.DataKey
+		result2 error
+	}
+	UnwrapDataKeyStub        func(context.Context, string, []byte) ([]byte, error)
+	unwrapDataKeyMutex       sync.RWMutex
+	unwrapDataKeyArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 []byte
+	}
+	unwrapDataKeyReturns struct {
+		result1 []byte
+		result2 error
+	}
+	unwrapDataKeyReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeKeyProvider) GenerateDataKey(arg1 context.Context) (*security.DataKey, error) {
+	fake.generateDataKeyMutex.Lock()
+	ret, specificReturn := fake.generateDataKeyReturnsOnCall[len(fake.generateDataKeyArgsForCall)]
+	fake.generateDataKeyArgsForCall = append(fake.generateDataKeyArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GenerateDataKeyStub
+	fakeReturns := fake.generateDataKeyReturns
+	fake.recordInvocation("GenerateDataKey", []interface{}{arg1})
+	fake.generateDataKeyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKeyProvider) GenerateDataKeyCallCount() int {
+	fake.generateDataKeyMutex.RLock()
+	defer fake.generateDataKeyMutex.RUnlock()
+	return len(fake.generateDataKeyArgsForCall)
+}
+
+func (fake *FakeKeyProvider) GenerateDataKeyCalls(stub func(context.Context) (*security.DataKey, error)) {
+	fake.generateDataKeyMutex.Lock()
+	defer fake.generateDataKeyMutex.Unlock()
+	fake.GenerateDataKeyStub = stub
+}
+
+func (fake *FakeKeyProvider) GenerateDataKeyArgsForCall(i int) context.Context {
+	fake.generateDataKeyMutex.RLock()
+	defer fake.generateDataKeyMutex.RUnlock()
+	argsForCall := fake.generateDataKeyArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeKeyProvider) GenerateDataKeyReturns(result1 *security.DataKey, result2 error) {
+	fake.generateDataKeyMutex.Lock()
+	defer fake.generateDataKeyMutex.Unlock()
+	fake.GenerateDataKeyStub = nil
+	fake.generateDataKeyReturns = struct {
+		result1 *security.DataKey
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKeyProvider) GenerateDataKeyReturnsOnCall(i int, result1 *security.DataKey, result2 error) {
+	fake.generateDataKeyMutex.Lock()
+	defer fake.generateDataKeyMutex.Unlock()
+	fake.GenerateDataKeyStub = nil
+	if fake.generateDataKeyReturnsOnCall == nil {
+		fake.generateDataKeyReturnsOnCall = make(map[int]struct {
+			result1 *security.DataKey
+			result2 error
+		})
+	}
+	fake.generateDataKeyReturnsOnCall[i] = struct {
+		result1 *security.DataKey
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKeyProvider) UnwrapDataKey(arg1 context.Context, arg2 string, arg3 []byte) ([]byte, error) {
+	var arg3Copy []byte
+	if arg3 != nil {
+		arg3Copy = make([]byte, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.unwrapDataKeyMutex.Lock()
+	ret, specificReturn := fake.unwrapDataKeyReturnsOnCall[len(fake.unwrapDataKeyArgsForCall)]
+	fake.unwrapDataKeyArgsForCall = append(fake.unwrapDataKeyArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 []byte
+	}{arg1, arg2, arg3Copy})
+	stub := fake.UnwrapDataKeyStub
+	fakeReturns := fake.unwrapDataKeyReturns
+	fake.recordInvocation("UnwrapDataKey", []interface{}{arg1, arg2, arg3Copy})
+	fake.unwrapDataKeyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKeyProvider) UnwrapDataKeyCallCount() int {
+	fake.unwrapDataKeyMutex.RLock()
+	defer fake.unwrapDataKeyMutex.RUnlock()
+	return len(fake.unwrapDataKeyArgsForCall)
+}
+
+func (fake *FakeKeyProvider) UnwrapDataKeyCalls(stub func(context.Context, string, []byte) ([]byte, error)) {
+	fake.unwrapDataKeyMutex.Lock()
+	defer fake.unwrapDataKeyMutex.Unlock()
+	fake.UnwrapDataKeyStub = stub
+}
+
+func (fake *FakeKeyProvider) UnwrapDataKeyArgsForCall(i int) (context.Context, string, []byte) {
+	fake.unwrapDataKeyMutex.RLock()
+	defer fake.unwrapDataKeyMutex.RUnlock()
+	argsForCall := fake.unwrapDataKeyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeKeyProvider) UnwrapDataKeyReturns(result1 []byte, result2 error) {
+	fake.unwrapDataKeyMutex.Lock()
+	defer fake.unwrapDataKeyMutex.Unlock()
+	fake.UnwrapDataKeyStub = nil
+	fake.unwrapDataKeyReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKeyProvider) UnwrapDataKeyReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.unwrapDataKeyMutex.Lock()
+	defer fake.unwrapDataKeyMutex.Unlock()
+	fake.UnwrapDataKeyStub = nil
+	if fake.unwrapDataKeyReturnsOnCall == nil {
+		fake.unwrapDataKeyReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.unwrapDataKeyReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKeyProvider) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.generateDataKeyMutex.RLock()
+	defer fake.generateDataKeyMutex.RUnlock()
+	fake.unwrapDataKeyMutex.RLock()
+	defer fake.unwrapDataKeyMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeKeyProvider) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ security.KeyProvider = new(FakeKeyProvider)
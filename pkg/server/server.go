@@ -19,6 +19,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -48,6 +49,8 @@ type Settings struct {
 	ShutdownTimeout    time.Duration `mapstructure:"shutdown_timeout" description:"time to wait for the server to shutdown"`
 	MaxBodyBytes       int           `mapstructure:"max_body_bytes" description:"maximum bytes size of incoming body"`
 	MaxHeaderBytes     int           `mapstructure:"max_header_bytes" description:"the maximum number of bytes the server will read parsing the request header"`
+	TLSCertFile        string        `mapstructure:"tls_cert_file" description:"PEM file with the certificate of the server. The server listens for TLS connections and requests client certificates if set"`
+	TLSKeyFile         string        `mapstructure:"tls_key_file" description:"PEM file with the private key of the server certificate"`
 }
 
 // DefaultSettings returns the default values for configuring the Service Manager
@@ -73,6 +76,9 @@ func (s *Settings) Validate() error {
 	if s.ShutdownTimeout == 0 {
 		return fmt.Errorf("validate Settings: ShutdownTimeout missing")
 	}
+	if (len(s.TLSCertFile) == 0) != (len(s.TLSKeyFile) == 0) {
+		return fmt.Errorf("validate Settings: both TLSCertFile and TLSKeyFile must be set")
+	}
 
 	return nil
 }
@@ -146,16 +152,29 @@ func (s *Server) Run(ctx context.Context, wg *sync.WaitGroup) {
 		ReadTimeout:    s.Config.RequestTimeout,
 		MaxHeaderBytes: s.Config.MaxHeaderBytes,
 	}
-	startServer(ctx, handler, s.Config.ShutdownTimeout, wg)
+	if len(s.Config.TLSCertFile) != 0 {
+		// client certificates are requested but verified by the client certificate authenticator, so that
+		// the trusted certificate authorities can be rotated without restart
+		handler.TLSConfig = &tls.Config{
+			ClientAuth: tls.RequestClientCert,
+		}
+	}
+	startServer(ctx, handler, s.Config, wg)
 }
 
-func startServer(ctx context.Context, server *http.Server, shutdownTimeout time.Duration, wg *sync.WaitGroup) {
+func startServer(ctx context.Context, server *http.Server, config *Settings, wg *sync.WaitGroup) {
 	wg.Add(1)
-	go gracefulShutdown(ctx, server, shutdownTimeout, wg)
+	go gracefulShutdown(ctx, server, config.ShutdownTimeout, wg)
 
 	log.C(ctx).Infof("Server listening on %s...", server.Addr)
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	var err error
+	if len(config.TLSCertFile) != 0 {
+		err = server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		log.C(ctx).Fatal(err)
 	}
 }
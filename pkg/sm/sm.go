@@ -35,6 +35,7 @@ import (
 	"github.com/Peripli/service-manager/operations"
 
 	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/export"
 
 	"github.com/Peripli/service-manager/pkg/health"
 
@@ -45,23 +46,29 @@ import (
 	"github.com/Peripli/service-manager/storage/catalog"
 
 	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/security/policy"
 
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/storage/interceptors"
 
 	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/api/configuration"
 	"github.com/Peripli/service-manager/api/healthcheck"
+	"github.com/Peripli/service-manager/api/metrics"
 	"github.com/Peripli/service-manager/config"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/server"
+	"github.com/Peripli/service-manager/pkg/tracing"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/storage"
-	"github.com/Peripli/service-manager/storage/postgres"
 
 	_ "github.com/Kount/pq-timeouts"
 	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/audit"
 	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/webhooks"
 	"github.com/go-redis/redis"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // ServiceManagerBuilder type is an extension point that allows adding additional filters, plugins and
@@ -72,6 +79,9 @@ type ServiceManagerBuilder struct {
 	Storage              *storage.InterceptableTransactionalRepository
 	Notificator          storage.Notificator
 	NotificationCleaner  *storage.NotificationCleaner
+	WebhookDeliverer     *webhooks.Deliverer
+	AuditCleaner         *audit.Cleaner
+	CatalogRefresher     *operations.CatalogRefresher
 	OperationMaintainer  *operations.Maintainer
 	OSBClientProvider    osbc.CreateFunc
 	ctx                  context.Context
@@ -89,6 +99,9 @@ type ServiceManager struct {
 	Server              *server.Server
 	Notificator         storage.Notificator
 	NotificationCleaner *storage.NotificationCleaner
+	WebhookDeliverer    *webhooks.Deliverer
+	AuditCleaner        *audit.Cleaner
+	CatalogRefresher    *operations.CatalogRefresher
 }
 
 // New returns service-manager Server with default setup
@@ -129,21 +142,40 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 	}
 
 	// Setup storage
-	log.C(ctx).Info("Setting up Service Manager storage...")
-	smStorage := &postgres.Storage{
-		ConnectFunc: func(driver string, url string) (*sql.DB, error) {
-			return sql.Open(driver, url)
-		},
+	log.C(ctx).Infof("Setting up Service Manager %s storage...", cfg.Storage.Type)
+	smStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		return nil, err
 	}
 
-	// Decorate the storage with credentials encryption/decryption
-	encryptingDecorator := storage.EncryptingDecorator(ctx, &security.AESEncrypter{}, smStorage, postgres.EncryptingLocker(smStorage))
+	// Decorate the storage with credentials encryption/decryption. The encryption keys in the key store are always
+	// encrypted with the storage encryption key, while the credentials may use envelope encryption
+	keyEncrypter := &security.AESEncrypter{}
+	var encrypter security.Encrypter = keyEncrypter
+	keyProvider, err := security.NewKeyProvider(cfg.Storage.KeyProvider)
+	if err != nil {
+		return nil, err
+	}
+	if keyProvider != nil {
+		log.C(ctx).Infof("Using envelope encryption with data keys from the %s key provider", cfg.Storage.KeyProvider.Type)
+		encrypter = security.NewEnvelopeEncrypter(keyProvider, cfg.Storage.KeyProvider)
+	}
+	encryptingDecorator := storage.EncryptingDecorator(ctx, keyEncrypter, encrypter, smStorage.keyStore, smStorage.encryptingLocker, cfg.Storage.KeyRotation.RefreshInterval)
 	integrityDecorator := storage.DataIntegrityDecorator(cfg.Storage.IntegrityProcessor)
 
+	waitGroup := &sync.WaitGroup{}
+	decorators := []storage.TransactionalRepositoryDecorator{integrityDecorator, encryptingDecorator}
+	if cfg.Tracing.Enabled {
+		if err = configureTracing(ctx, cfg.Tracing, waitGroup); err != nil {
+			return nil, err
+		}
+		// the tracing decorator is the outermost one, so that the storage spans include the encryption and integrity processing
+		decorators = append([]storage.TransactionalRepositoryDecorator{storage.TracingDecorator()}, decorators...)
+	}
+
 	// Initialize the storage with graceful termination
 	var transactionalRepository storage.TransactionalRepository
-	waitGroup := &sync.WaitGroup{}
-	if transactionalRepository, err = storage.InitializeWithSafeTermination(ctx, smStorage, cfg.Storage, waitGroup, integrityDecorator, encryptingDecorator); err != nil {
+	if transactionalRepository, err = storage.InitializeWithSafeTermination(ctx, smStorage.Storage, cfg.Storage, waitGroup, decorators...); err != nil {
 		return nil, fmt.Errorf("error opening storage: %s", err)
 	}
 
@@ -153,21 +185,26 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 	// Setup core API
 	log.C(ctx).Info("Setting up Service Manager core API...")
 
-	pgNotificator, err := postgres.NewNotificator(smStorage, cfg.Storage)
+	notificator, err := smStorage.newNotificator(smStorage.Storage, cfg.Storage)
 	if err != nil {
 		return nil, fmt.Errorf("could not create notificator: %v", err)
 	}
 
+	var brokerCircuits *operations.BrokerCircuits
+	if cfg.Operations.BrokerCircuit != nil && cfg.Operations.BrokerCircuit.FailureThreshold > 0 {
+		brokerCircuits = operations.NewBrokerCircuits(cfg.Operations.BrokerCircuit)
+	}
 	apiOptions := &api.Options{
 		RedisClient:       redisClient,
 		Repository:        interceptableRepository,
 		APISettings:       cfg.API,
 		OperationSettings: cfg.Operations,
 		WSSettings:        cfg.WebSocket,
-		Notificator:       pgNotificator,
+		Notificator:       notificator,
 		WaitGroup:         waitGroup,
 		TenantLabelKey:    cfg.Multitenancy.LabelKey,
 		Agents:            cfg.Agents,
+		BrokerCircuits:    brokerCircuits,
 	}
 	API, err := api.New(ctx, e, apiOptions)
 	if err != nil {
@@ -182,7 +219,24 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 	API.RegisterFilters(&filters.RegeneratePlatformCredentialsFilter{}, &filters.TechnicalPlatformFilter{Storage: interceptableRepository})
 	API.RegisterFiltersAfter(secFilters.AuthorizationFilterName, &filters.CheckPlatformSuspendedFilter{}, &filters.ForceDeleteValidationFilter{})
 
-	storageHealthIndicator, err := storage.NewSQLHealthIndicator(storage.PingFunc(smStorage.PingContext))
+	if cfg.Authorization.Enabled {
+		authorizer, err := policy.NewAuthorizer(cfg.Authorization, interceptableRepository)
+		if err != nil {
+			return nil, fmt.Errorf("error creating authorization policies: %s", err)
+		}
+		API.RegisterFiltersBefore(secFilters.AuthorizationFilterName, secFilters.NewPolicyAuthorizationFilter(authorizer))
+		if watcher, ok := e.(env.ConfigWatcher); ok {
+			watcher.AddOnConfigChangeHandlers(authorizer.OnConfigChange)
+		}
+	}
+
+	var auditCleaner *audit.Cleaner
+	if cfg.Audit.Enabled {
+		API.RegisterFiltersAfter(secFilters.AuthenticationFilterName, filters.NewAuditFilter(interceptableRepository, cfg.Multitenancy.LabelKey))
+		auditCleaner = audit.NewCleaner(interceptableRepository, cfg.Audit)
+	}
+
+	storageHealthIndicator, err := storage.NewSQLHealthIndicator(storage.PingFunc(smStorage.Storage.PingContext))
 	if err != nil {
 		return nil, fmt.Errorf("error creating storage health indicator: %s", err)
 	}
@@ -199,30 +253,51 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		log.C(ctx).Info("enabling monitor platforms indicator")
 		API.SetIndicator(healthcheck.NewMonitoredPlatformsIndicator(ctx, interceptableRepository, cfg.Health.MonitoredPlatformsThreshold))
 	}
+	if cfg.Health.EnableBrokersIndicator {
+		log.C(ctx).Info("enabling brokers indicator")
+		API.SetIndicator(healthcheck.NewBrokerIndicator(ctx, interceptableRepository))
+	}
+	if cfg.Health.EnableBrokerCircuitsIndicator && brokerCircuits != nil {
+		log.C(ctx).Info("enabling broker circuits indicator")
+		API.SetIndicator(healthcheck.NewBrokerCircuitsIndicator(brokerCircuits))
+	}
 
 	notificationCleaner := &storage.NotificationCleaner{
 		Storage:  interceptableRepository,
 		Settings: *cfg.Storage,
 	}
 
-	postgresLockerCreatorFunc := func(advisoryIndex int) storage.Locker {
-		return &postgres.Locker{Storage: smStorage, AdvisoryIndex: advisoryIndex}
+	webhookDeliverer := webhooks.NewDeliverer(interceptableRepository, smStorage.lockerCreator, cfg.Webhooks)
+
+	// the catalog refreshes are scheduled in the worker pool of the broker operations
+	brokersScheduler := api.WorkerPools(API.Controllers)[types.ServiceBrokerType.String()]
+	catalogRefresher := operations.NewCatalogRefresher(interceptableRepository, brokersScheduler, smStorage.lockerCreator, cfg.Operations.CatalogRefresh)
+
+	operationMaintainer := operations.NewMaintainer(ctx, interceptableRepository, smStorage.lockerCreator, cfg.Operations, brokerCircuits, waitGroup)
+	if cfg.Metrics.Enabled {
+		registerMetrics(ctx, API, cfg.Metrics, operationMaintainer, notificator, smStorage.dbStats)
 	}
 
-	operationMaintainer := operations.NewMaintainer(ctx, interceptableRepository, postgresLockerCreatorFunc, cfg.Operations, waitGroup)
 	osbClientTimeout := math.Min(float64(cfg.HTTPClient.Timeout), float64(cfg.Server.RequestTimeout))
 	osbClientTimeoutDuration := time.Duration(osbClientTimeout)
 	osbClientProvider := osb.NewBrokerClientProvider(cfg.HTTPClient.SkipSSLValidation, int(osbClientTimeoutDuration.Seconds()))
 
-	encryptingRepository, err := encryptingDecorator(smStorage)
+	encryptingRepository, err := encryptingDecorator(smStorage.Storage)
 	if err != nil {
 		return nil, fmt.Errorf("error decorating storage with encryption: %s", err)
 	}
+	keyRotator := storage.NewKeyRotator(ctx, encryptingRepository, smStorage.keyStore, keyEncrypter, smStorage.lockerCreator, cfg.Storage.KeyRotation, waitGroup)
+	API.RegisterControllers(&configuration.KeyRotationController{KeyRotator: keyRotator})
+	API.RegisterControllers(&configuration.ExportController{Exporter: export.NewExporter(interceptableRepository)})
+
 	smb := &ServiceManagerBuilder{
 		API:                  API,
 		Storage:              interceptableRepository,
-		Notificator:          pgNotificator,
+		Notificator:          notificator,
 		NotificationCleaner:  notificationCleaner,
+		WebhookDeliverer:     webhookDeliverer,
+		AuditCleaner:         auditCleaner,
+		CatalogRefresher:     catalogRefresher,
 		OperationMaintainer:  operationMaintainer,
 		ctx:                  ctx,
 		wg:                   waitGroup,
@@ -234,7 +309,11 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		RedisClient:          redisClient,
 	}
 
-	smb.RegisterPlugins(osb.NewCatalogFilterByVisibilityPlugin(interceptableRepository))
+	var catalogCache *osb.CatalogCache
+	if cfg.API.OSBCatalogCacheEnabled {
+		catalogCache = osb.NewCatalogCache(notificator)
+	}
+	smb.RegisterPlugins(osb.NewCatalogFilterByVisibilityPlugin(interceptableRepository, catalogCache))
 	smb.RegisterPluginsBefore(osb.CheckInstanceOwnerhipPluginName, osb.NewStorePlugin(interceptableRepository))
 	smb.RegisterPluginsBefore(osb.OSBStorePluginName, osb.NewCheckVisibilityPlugin(interceptableRepository))
 	smb.RegisterPlugins(osb.NewCheckPlatformIDPlugin(interceptableRepository))
@@ -256,6 +335,8 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		}).Register().
 		WithCreateAroundTxInterceptorProvider(types.PlatformType, &interceptors.GeneratePlatformCredentialsInterceptorProvider{}).Register().
 		WithUpdateAroundTxInterceptorProvider(types.PlatformType, &interceptors.RegeneratePlatformCredentialsInterceptorProvider{}).Register().
+		WithCreateOnTxInterceptorProvider(types.PlatformType, &interceptors.PlatformWebhookCreateInterceptorProvider{}).Register().
+		WithUpdateOnTxInterceptorProvider(types.PlatformType, &interceptors.PlatformWebhookUpdateInterceptorProvider{}).Register().
 		WithCreateOnTxInterceptorProvider(types.VisibilityType, &interceptors.VisibilityCreateNotificationsInterceptorProvider{}).Register().
 		WithUpdateOnTxInterceptorProvider(types.VisibilityType, &interceptors.VisibilityUpdateNotificationsInterceptorProvider{}).Register().
 		WithDeleteOnTxInterceptorProvider(types.VisibilityType, &interceptors.VisibilityDeleteNotificationsInterceptorProvider{}).Register().
@@ -272,12 +353,20 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 			NotificationsKeepFor: cfg.Storage.Notification.KeepFor,
 		}).After(interceptors.BrokerDeleteCatalogInterceptorName).Register()
 
+	for _, objectType := range api.EventResources {
+		smb.
+			WithCreateOnTxInterceptorProvider(objectType, &interceptors.EventsCreateInterceptorProvider{}).Register().
+			WithUpdateOnTxInterceptorProvider(objectType, &interceptors.EventsUpdateInterceptorProvider{}).Register().
+			WithDeleteOnTxInterceptorProvider(objectType, &interceptors.EventsDeleteInterceptorProvider{}).Register()
+	}
+
 	baseSMAAPInterceptorProvider := &interceptors.BaseSMAAPInterceptorProvider{
 		OSBClientCreateFunc: osbClientProvider,
 		Repository:          interceptableRepository,
 		TenantKey:           cfg.Multitenancy.LabelKey,
 		PollingInterval:     cfg.Operations.PollingInterval,
 		ContextSigner:       &osb.ContextSigner{ContextPrivateKey: cfg.API.OSBRSAPrivateKey},
+		BrokerCircuits:      brokerCircuits,
 	}
 
 	smb.
@@ -345,7 +434,51 @@ func (smb *ServiceManagerBuilder) Build() *ServiceManager {
 		Server:              srv,
 		Notificator:         smb.Notificator,
 		NotificationCleaner: smb.NotificationCleaner,
+		WebhookDeliverer:    smb.WebhookDeliverer,
+		AuditCleaner:        smb.AuditCleaner,
+		CatalogRefresher:    smb.CatalogRefresher,
+	}
+}
+
+// configureTracing sets up the export of the tracing spans and flushes the remaining spans when the context is cancelled
+func configureTracing(ctx context.Context, settings *tracing.Settings, group *sync.WaitGroup) error {
+	log.C(ctx).Infof("Exporting tracing spans to %s", settings.Endpoint)
+	shutdown, err := tracing.Configure(ctx, settings)
+	if err != nil {
+		return fmt.Errorf("error configuring tracing: %s", err)
+	}
+	util.StartInWaitGroupWithContext(ctx, func(c context.Context) {
+		<-c.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(shutdownCtx); err != nil {
+			log.C(c).WithError(err).Error("could not flush tracing spans")
+		}
+	}, group)
+	return nil
+}
+
+// registerMetrics exposes the metrics of the API, the operations, the notifications and the storage on the metrics endpoint
+func registerMetrics(ctx context.Context, API *web.API, settings *metrics.Settings, maintainer *operations.Maintainer, notificator storage.Notificator, dbStats func() sql.DBStats) {
+	workerPools := api.WorkerPools(API.Controllers)
+	for name, scheduler := range maintainer.WorkerPools() {
+		workerPools[name] = scheduler
 	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		metrics.NewOperationsCollector(ctx, maintainer, settings.OperationsRefreshInterval),
+		metrics.NewWorkerPoolsCollector(workerPools),
+		metrics.NewNotificatorCollector(notificator),
+	)
+	if dbStats != nil {
+		registry.MustRegister(metrics.NewDBStatsCollector(dbStats))
+	}
+
+	API.RegisterFiltersBefore(filters.ContextTimeoutFilterName, filters.NewMetricsFilter(registry))
+	API.RegisterControllers(&metrics.Controller{Gatherer: registry})
 }
 
 func (smb *ServiceManagerBuilder) registerSMPlatform() error {
@@ -403,6 +536,17 @@ func (sm *ServiceManager) Run() {
 	if err := sm.NotificationCleaner.Start(sm.ctx, sm.wg); err != nil {
 		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager notification cleaner")
 	}
+	if err := sm.WebhookDeliverer.Start(sm.ctx, sm.wg); err != nil {
+		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager webhook deliverer")
+	}
+	if sm.AuditCleaner != nil {
+		if err := sm.AuditCleaner.Start(sm.ctx, sm.wg); err != nil {
+			log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager audit cleaner")
+		}
+	}
+	if err := sm.CatalogRefresher.Start(sm.ctx, sm.wg); err != nil {
+		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager catalog refresher")
+	}
 
 	sm.Server.Run(sm.ctx, sm.wg)
 
@@ -594,11 +738,27 @@ func (smb *ServiceManagerBuilder) EnableMultitenancy(labelKey string, extractTen
 	)
 
 	smb.RegisterPlugins(osb.NewCheckInstanceOwnershipPlugin(smb.Storage, labelKey))
+	smb.RegisterPluginsBefore(osb.OSBStorePluginName, osb.NewCheckTenantQuotaPlugin(smb.Storage, labelKey))
+	smb.RegisterControllers(api.NewTenantQuotaController(smb.Storage, labelKey, extractTenantFunc))
 
 	smb.WithCreateOnTxInterceptorProvider(types.ServiceInstanceType, interceptors.NewOSBServiceInstanceTenantLabelingInterceptor(labelKey)).
 		AroundTxAfter(interceptors.ServiceInstanceCreateInterceptorProviderName).Register()
 	smb.WithCreateOnTxInterceptorProvider(types.ServiceBindingType, interceptors.NewOSBBindingTenantLabelingInterceptor(labelKey)).
 		AroundTxAfter(interceptors.ServiceBindingCreateInterceptorProviderName).Register()
+	smb.WithCreateAroundTxInterceptorProvider(types.ServiceInstanceType, &interceptors.TenantQuotaInstanceCreateInterceptorProvider{
+		TenantIdentifier: labelKey,
+		Repository:       smb.Storage,
+	}).AroundTxBefore(interceptors.ServiceInstanceCreateInterceptorProviderName).Register()
+	smb.WithCreateOnTxInterceptorProvider(types.ServiceInstanceType, &interceptors.TenantQuotaInstanceCreateOnTxInterceptorProvider{
+		TenantIdentifier: labelKey,
+	}).OnTxAfter(interceptors.ServiceInstanceCreateInterceptorName).Register()
+	smb.WithCreateAroundTxInterceptorProvider(types.ServiceBindingType, &interceptors.TenantQuotaBindingCreateInterceptorProvider{
+		TenantIdentifier: labelKey,
+		Repository:       smb.Storage,
+	}).AroundTxBefore(interceptors.ServiceBindingCreateInterceptorProviderName).Register()
+	smb.WithCreateOnTxInterceptorProvider(types.ServiceBindingType, &interceptors.TenantQuotaBindingCreateOnTxInterceptorProvider{
+		TenantIdentifier: labelKey,
+	}).OnTxAfter(interceptors.ServiceBindingCreateInterceptorName).Register()
 	smb.WithCreateOnTxInterceptorProvider(types.OperationType, &interceptors.OperationsCreateInsterceptorProvider{
 		TenantIdentifier: labelKey,
 	}).Register()
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package sm
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/inmemory"
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+// smStorage bundles the storage selected in the storage settings together with the components built on top of it
+type smStorage struct {
+	storage.Storage
+	keyStore         storage.KeyStore
+	encryptingLocker storage.Locker
+	lockerCreator    storage.LockerCreatorFunc
+	newNotificator   func(storage.Storage, *storage.Settings) (storage.Notificator, error)
+	// dbStats returns the statistics of the database connection pool, if the storage has one
+	dbStats func() sql.DBStats
+}
+
+func newStorage(settings *storage.Settings) (*smStorage, error) {
+	switch settings.Type {
+	case storage.PostgresStorage, "":
+		pgStorage := &postgres.Storage{
+			ConnectFunc: func(driver string, url string) (*sql.DB, error) {
+				return sql.Open(driver, url)
+			},
+		}
+		return &smStorage{
+			Storage:          pgStorage,
+			keyStore:         pgStorage,
+			encryptingLocker: postgres.EncryptingLocker(pgStorage),
+			lockerCreator: func(advisoryIndex int) storage.Locker {
+				return &postgres.Locker{Storage: pgStorage, AdvisoryIndex: advisoryIndex}
+			},
+			newNotificator: func(st storage.Storage, settings *storage.Settings) (storage.Notificator, error) {
+				return postgres.NewNotificator(st, settings)
+			},
+			dbStats: pgStorage.Stats,
+		}, nil
+	case storage.InMemoryStorage:
+		inMemoryStorage := &inmemory.Storage{}
+		return &smStorage{
+			Storage:          inMemoryStorage,
+			keyStore:         inMemoryStorage,
+			encryptingLocker: inmemory.EncryptingLocker(inMemoryStorage),
+			lockerCreator: func(advisoryIndex int) storage.Locker {
+				return &inmemory.Locker{Storage: inMemoryStorage, AdvisoryIndex: advisoryIndex}
+			},
+			newNotificator: func(st storage.Storage, settings *storage.Settings) (storage.Notificator, error) {
+				return inmemory.NewNotificator(st, settings)
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage type %s", settings.Type)
+	}
+}
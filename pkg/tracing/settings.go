@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import "fmt"
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Enabled     bool    `mapstructure:"enabled" description:"whether to export tracing spans"`
+	Endpoint    string  `mapstructure:"endpoint" description:"host and port of the OTLP/HTTP collector to which the spans are exported"`
+	Insecure    bool    `mapstructure:"insecure" description:"whether to export the spans over plain HTTP"`
+	SampleRatio float64 `mapstructure:"sample_ratio" description:"ratio of the traces started by the Service Manager which are sampled. Traces of incoming requests follow the sampling decision of the caller"`
+	ServiceName string  `mapstructure:"service_name" description:"service name reported with the exported spans"`
+}
+
+// DefaultSettings returns the default values for tracing
+func DefaultSettings() *Settings {
+	return &Settings{
+		Enabled:     false,
+		Endpoint:    "localhost:4318",
+		Insecure:    false,
+		SampleRatio: 1,
+		ServiceName: "service-manager",
+	}
+}
+
+// Validate validates the tracing settings
+func (s *Settings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.Endpoint == "" {
+		return fmt.Errorf("validate tracing settings: endpoint missing")
+	}
+	if s.SampleRatio < 0 || s.SampleRatio > 1 {
+		return fmt.Errorf("validate tracing settings: sample_ratio should be between 0 and 1")
+	}
+	if s.ServiceName == "" {
+		return fmt.Errorf("validate tracing settings: service_name missing")
+	}
+	return nil
+}
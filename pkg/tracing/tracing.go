@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tracing provides distributed tracing of the Service Manager requests and operations using OpenTelemetry
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/Peripli/service-manager"
+
+const (
+	// CorrelationIDKey is the span attribute containing the correlation id of the request which triggered the span
+	CorrelationIDKey = attribute.Key("sm.correlation_id")
+	// OperationIDKey is the span attribute containing the id of the executed operation
+	OperationIDKey = attribute.Key("sm.operation.id")
+	// OperationTypeKey is the span attribute containing the type of the executed operation
+	OperationTypeKey = attribute.Key("sm.operation.type")
+	// ResourceTypeKey is the span attribute containing the type of the resource
+	ResourceTypeKey = attribute.Key("sm.resource.type")
+	// ResourceIDKey is the span attribute containing the id of the resource
+	ResourceIDKey = attribute.Key("sm.resource.id")
+)
+
+// Configure sets up the global tracer provider, which exports the spans to the configured OTLP/HTTP collector, and the
+// W3C trace context propagator. The returned function flushes the remaining spans and stops the export.
+func Configure(ctx context.Context, settings *Settings) (func(context.Context) error, error) {
+	options := []otlphttp.Option{otlphttp.WithEndpoint(settings.Endpoint)}
+	if settings.Insecure {
+		options = append(options, otlphttp.WithInsecure())
+	}
+	exporter, err := otlp.NewExporter(ctx, otlphttp.NewDriver(options...))
+	if err != nil {
+		return nil, fmt.Errorf("could not create tracing exporter: %s", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(settings.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.ServiceNameKey.String(settings.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span which is a child of the span in the context, if there is one
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// StartLinkedSpan starts a span in a new trace, which is linked to the span in the context, if there is one. It is used
+// for work which outlives the request that triggered it, such as asynchronous operations.
+func StartLinkedSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	options := []trace.SpanOption{trace.WithNewRoot(), trace.WithAttributes(attributes...)}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: spanContext}))
+	}
+	return tracer().Start(ctx, name, options...)
+}
+
+// StartClientSpan starts a span for an outgoing call to another service
+func StartClientSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// StartServerSpan starts a span for an incoming request. The span continues the trace propagated in the request headers.
+func StartServerSpan(request *http.Request, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+	attributes = append(attributes, semconv.HTTPServerAttributesFromHTTPRequest("", name, request)...)
+	return tracer().Start(ctx, request.Method+" "+name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+}
+
+// SetHTTPStatus records the status code of a HTTP response in the span
+func SetHTTPStatus(span trace.Span, statusCode int) {
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(statusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(statusCode))
+}
+
+// SetError records the error, if there is one, in the span and marks the span as failed
+func SetError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// End records the error, if there is one, in the span and ends it
+func End(span trace.Span, err error) {
+	SetError(span, err)
+	span.End()
+}
+
+// tracer is fetched from the global provider on each use, so that spans are exported by the last configured provider
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
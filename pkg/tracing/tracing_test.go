@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Peripli/service-manager/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+	var exporter *tracetest.InMemoryExporter
+
+	BeforeEach(func() {
+		exporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+
+	AfterEach(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	Describe("Settings", func() {
+		var settings *tracing.Settings
+
+		BeforeEach(func() {
+			settings = tracing.DefaultSettings()
+			settings.Enabled = true
+		})
+
+		It("are valid by default", func() {
+			Expect(settings.Validate()).To(Succeed())
+		})
+
+		It("are not validated when tracing is disabled", func() {
+			settings.Enabled = false
+			settings.Endpoint = ""
+			Expect(settings.Validate()).To(Succeed())
+		})
+
+		It("require an endpoint", func() {
+			settings.Endpoint = ""
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+
+		It("require a sample ratio between 0 and 1", func() {
+			settings.SampleRatio = -0.1
+			Expect(settings.Validate()).To(HaveOccurred())
+			settings.SampleRatio = 1.1
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Describe("End", func() {
+		It("marks the span as failed on error", func() {
+			_, span := tracing.StartSpan(context.Background(), "failing")
+			tracing.End(span, errors.New("expected"))
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].StatusCode).To(Equal(codes.Error))
+			Expect(spans[0].StatusMessage).To(Equal("expected"))
+		})
+	})
+
+	Describe("StartLinkedSpan", func() {
+		It("starts a new trace linked to the span in the context", func() {
+			ctx, parent := tracing.StartSpan(context.Background(), "request")
+			_, linked := tracing.StartLinkedSpan(ctx, "operation", tracing.CorrelationIDKey.String("correlation"))
+			linked.End()
+			parent.End()
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(2))
+			operation := spans[0]
+			Expect(operation.Name).To(Equal("operation"))
+			Expect(operation.SpanContext.TraceID()).ToNot(Equal(parent.SpanContext().TraceID()))
+			Expect(operation.Parent.IsValid()).To(BeFalse())
+			Expect(operation.Links).To(HaveLen(1))
+			Expect(operation.Links[0].SpanContext.SpanID()).To(Equal(parent.SpanContext().SpanID()))
+			Expect(operation.Attributes).To(ContainElement(tracing.CorrelationIDKey.String("correlation")))
+		})
+
+		It("starts a span without links when the context has no span", func() {
+			_, span := tracing.StartLinkedSpan(context.Background(), "operation")
+			span.End()
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Links).To(BeEmpty())
+		})
+	})
+
+	Describe("StartServerSpan", func() {
+		It("continues the trace propagated in the request headers", func() {
+			ctx, caller := tracing.StartClientSpan(context.Background(), "caller")
+			request := httptest.NewRequest(http.MethodGet, "/v1/service_instances", nil)
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+			_, span := tracing.StartServerSpan(request, "/v1/service_instances")
+			tracing.SetHTTPStatus(span, http.StatusOK)
+			span.End()
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Name).To(Equal("GET /v1/service_instances"))
+			Expect(spans[0].SpanKind).To(Equal(trace.SpanKindServer))
+			Expect(spans[0].SpanContext.TraceID()).To(Equal(caller.SpanContext().TraceID()))
+			Expect(spans[0].Parent.SpanID()).To(Equal(caller.SpanContext().SpanID()))
+		})
+	})
+
+	Describe("Transport", func() {
+		var server *httptest.Server
+		var traceparent string
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparent = r.Header.Get("traceparent")
+				w.WriteHeader(http.StatusBadGateway)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("traces the request and propagates the trace context", func() {
+			ctx, parent := tracing.StartSpan(context.Background(), "parent")
+			request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			client := &http.Client{Transport: tracing.NewTransport(nil)}
+			response, err := client.Do(request.WithContext(ctx))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Body.Close()).To(Succeed())
+			Expect(request.Header.Get("traceparent")).To(BeEmpty())
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].SpanKind).To(Equal(trace.SpanKindClient))
+			Expect(spans[0].Parent.SpanID()).To(Equal(parent.SpanContext().SpanID()))
+			Expect(spans[0].StatusCode).To(Equal(codes.Error))
+			Expect(traceparent).To(ContainSubstring(spans[0].SpanContext.SpanID().String()))
+		})
+	})
+})
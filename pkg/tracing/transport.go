@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// Transport is a http.RoundTripper which traces the outgoing requests and propagates the trace context
+// to the called services in the request headers
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport wraps the provided round tripper in a tracing Transport. If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, span := StartClientSpan(request.Context(), "HTTP "+request.Method, semconv.HTTPClientAttributesFromHTTPRequest(request)...)
+
+	// a round tripper should not modify the provided request
+	request = request.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	response, err := t.Base.RoundTrip(request)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	SetHTTPStatus(span, response.StatusCode)
+	span.End()
+	return response, nil
+}
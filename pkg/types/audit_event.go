@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// AuditAction is the kind of change recorded by an audit event
+type AuditAction string
+
+const (
+	// AuditCreate is recorded for requests creating a resource
+	AuditCreate AuditAction = "create"
+	// AuditUpdate is recorded for requests updating a resource
+	AuditUpdate AuditAction = "update"
+	// AuditDelete is recorded for requests deleting a resource
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditOutcome is the outcome of an audited request
+type AuditOutcome string
+
+const (
+	// AuditSuccess is the outcome of requests which were processed or accepted for processing
+	AuditSuccess AuditOutcome = "success"
+	// AuditFailure is the outcome of requests which were rejected or failed
+	AuditFailure AuditOutcome = "failure"
+)
+
+//go:generate smgen api AuditEvent
+// AuditEvent is a record of a single request changing the Service Manager resources
+type AuditEvent struct {
+	Base
+	Actor         string          `json:"actor"`
+	Tenant        string          `json:"tenant,omitempty"`
+	AccessLevel   string          `json:"access_level"`
+	Resource      ObjectType      `json:"resource"`
+	ResourceID    string          `json:"resource_id,omitempty"`
+	Action        AuditAction     `json:"action"`
+	Method        string          `json:"method"`
+	Path          string          `json:"path"`
+	Changes       json.RawMessage `json:"changes,omitempty"`
+	CorrelationID string          `json:"correlation_id"`
+	Outcome       AuditOutcome    `json:"outcome"`
+	StatusCode    int             `json:"status_code"`
+}
+
+func (e *AuditEvent) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	event := obj.(*AuditEvent)
+	if e.Actor != event.Actor ||
+		e.Tenant != event.Tenant ||
+		e.AccessLevel != event.AccessLevel ||
+		e.Resource != event.Resource ||
+		e.ResourceID != event.ResourceID ||
+		e.Action != event.Action ||
+		e.Method != event.Method ||
+		e.Path != event.Path ||
+		e.CorrelationID != event.CorrelationID ||
+		e.Outcome != event.Outcome ||
+		e.StatusCode != event.StatusCode ||
+		!reflect.DeepEqual(e.Changes, event.Changes) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *AuditEvent) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Resource == "" {
+		return fmt.Errorf("audit event resource missing")
+	}
+	if e.Action == "" {
+		return fmt.Errorf("audit event action missing")
+	}
+	if e.Outcome == "" {
+		return fmt.Errorf("audit event outcome missing")
+	}
+
+	return nil
+}
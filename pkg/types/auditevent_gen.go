@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const AuditEventType ObjectType = web.AuditEventsURL
+
+type AuditEvents struct {
+	AuditEvents []*AuditEvent `json:"audit_events"`
+}
+
+func (e *AuditEvents) Add(object Object) {
+	e.AuditEvents = append(e.AuditEvents, object.(*AuditEvent))
+}
+
+func (e *AuditEvents) ItemAt(index int) Object {
+	return e.AuditEvents[index]
+}
+
+func (e *AuditEvents) Len() int {
+	return len(e.AuditEvents)
+}
+
+func (e *AuditEvent) GetType() ObjectType {
+	return AuditEventType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *AuditEvent) MarshalJSON() ([]byte, error) {
+	type E AuditEvent
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -91,12 +91,20 @@ func ObjectListIDsToStringArray(objectList ObjectList) []string {
 
 // ObjectPage is the DTO for a given page of resources when listing
 type ObjectPage struct {
-	//Token represents the base64 encoded paging_sequence of the last entity in items list
+	//Token represents the opaque position of the last entity in items list in the requested order
 	Token      string   `json:"token,omitempty"`
 	ItemsCount int      `json:"num_items"`
 	Items      []Object `json:"items"`
 }
 
+// GroupCount is the number of resources having the same values of the grouped fields and label keys.
+// Values of fields which are not set and of label keys which the resources do not have are nil.
+type GroupCount struct {
+	Fields map[string]*string `json:"fields,omitempty"`
+	Labels map[string]*string `json:"labels,omitempty"`
+	Count  int                `json:"count"`
+}
+
 // ObjectArray is an ObjectList backed by a slice of Object's
 type ObjectArray struct {
 	Objects []Object
@@ -55,6 +55,9 @@ const (
 
 	// FAILED represents the state of an operation after unsuccessful execution
 	FAILED OperationState = "failed"
+
+	// CANCELLED represents the state of an operation which was cancelled before its execution finished
+	CANCELLED OperationState = "cancelled"
 )
 
 type RelatedType struct {
@@ -87,6 +90,10 @@ type Operation struct {
 	RescheduleTimestamp time.Time `json:"reschedule_timestamp,omitempty"`
 	// DeletionScheduled specifies the time when an operation was marked for deletion
 	DeletionScheduled time.Time `json:"deletion_scheduled,omitempty"`
+	// Attempts is the number of times the action of the operation has been scheduled
+	Attempts int `json:"attempts"`
+	// NextAttemptTimestamp is the time before which the action of an unfinished operation is not retried
+	NextAttemptTimestamp time.Time `json:"next_attempt_timestamp,omitempty"`
 }
 
 type UserInfo struct {
@@ -100,6 +107,7 @@ type OperationContext struct {
 	Cascade           bool              `json:"-"`
 	ServicePlanID     string            `json:"service_plan_id"`
 	ServiceInstanceID string            `json:"service_instance_id"`
+	BrokerID          string            `json:"broker_id,omitempty"`
 	UserInfo          *UserInfo         `json:"user_info"`
 	Params            map[string]string `json:"params"`
 }
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Peripli/service-manager/pkg/web"
+	"net/url"
 	"reflect"
 	"time"
 
@@ -49,18 +50,52 @@ type Platform struct {
 	Base
 	Secured           `json:"-"`
 	Strip             `json:"-"`
-	Type              string       `json:"type"`
-	Name              string       `json:"name"`
-	Description       string       `json:"description"`
-	Credentials       *Credentials `json:"credentials,omitempty"`
-	OldCredentials    *Credentials `json:"old_credentials,omitempty"`
-	Version           string       `json:"-"`
-	Active            bool         `json:"-"`
-	Suspended         bool         `json:"suspended,omitempty"`
-	LastActive        time.Time    `json:"-"`
-	Integrity         []byte       `json:"-"`
-	CredentialsActive bool         `json:"credentials_active,omitempty"`
-	Technical         bool         `json:"technical,omitempty"` //technical platforms are only used for managing visibilities, and are excluded in notification and credential management flows
+	Type              string         `json:"type"`
+	Name              string         `json:"name"`
+	Description       string         `json:"description"`
+	Credentials       *Credentials   `json:"credentials,omitempty"`
+	OldCredentials    *Credentials   `json:"old_credentials,omitempty"`
+	Version           string         `json:"-"`
+	Active            bool           `json:"-"`
+	Suspended         bool           `json:"suspended,omitempty"`
+	LastActive        time.Time      `json:"-"`
+	Integrity         []byte         `json:"-"`
+	CredentialsActive bool           `json:"credentials_active,omitempty"`
+	Technical         bool           `json:"technical,omitempty"` //technical platforms are only used for managing visibilities, and are excluded in notification and credential management flows
+	Webhook           *Webhook       `json:"webhook,omitempty"`
+	WebhookStatus     *WebhookStatus `json:"webhook_status,omitempty"`
+}
+
+// Webhook configures the delivery of the platform notifications with HTTP requests instead of over a websocket
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret is the key with which the HMAC signatures of the delivered notifications are computed
+	Secret string `json:"secret,omitempty"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (w *Webhook) Validate() error {
+	if w.URL == "" {
+		return errors.New("missing webhook url")
+	}
+	webhookURL, err := url.Parse(w.URL)
+	if err != nil || !webhookURL.IsAbs() || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") {
+		return fmt.Errorf("webhook url %s is not a valid absolute http url", w.URL)
+	}
+	if w.Secret == "" {
+		return errors.New("missing webhook secret")
+	}
+	return nil
+}
+
+// WebhookStatus is the state of the delivery of the platform notifications to its webhook
+type WebhookStatus struct {
+	// Revision is the revision of the last notification delivered to the webhook
+	Revision            int64     `json:"revision"`
+	LastDeliveredAt     time.Time `json:"last_delivered_at,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	NextAttemptAt       time.Time `json:"next_attempt_at,omitempty"`
 }
 
 func (e *Platform) Equals(obj Object) bool {
@@ -89,6 +124,9 @@ func (e *Platform) Sanitize(ctx context.Context) {
 	e.OldCredentials = nil
 	e.CredentialsActive = false
 	e.Technical = false
+	if e.Webhook != nil {
+		e.Webhook.Secret = ""
+	}
 }
 
 func (e *Platform) Encrypt(ctx context.Context, encryptionFunc func(context.Context, []byte) ([]byte, error)) error {
@@ -129,6 +167,13 @@ func (e *Platform) transform(ctx context.Context, transformationFunc func(contex
 		}
 		e.OldCredentials.Basic.Password = string(transformedOldPassword)
 	}
+	if e.Webhook != nil && e.Webhook.Secret != "" {
+		transformedSecret, err := transformationFunc(ctx, []byte(e.Webhook.Secret))
+		if err != nil {
+			return err
+		}
+		e.Webhook.Secret = string(transformedSecret)
+	}
 	return nil
 }
 
@@ -166,5 +211,8 @@ func (e *Platform) Validate() error {
 	if util.HasRFC3986ReservedSymbols(e.ID) {
 		return fmt.Errorf("%s contains invalid character(s)", e.ID)
 	}
+	if e.Webhook != nil {
+		return e.Webhook.Validate()
+	}
 	return nil
 }
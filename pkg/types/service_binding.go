@@ -31,17 +31,19 @@ import (
 // ServiceBinding struct
 type ServiceBinding struct {
 	Base
-	Secured           `json:"-"`
-	Name              string                 `json:"name"`
-	ServiceInstanceID string                 `json:"service_instance_id"`
-	SyslogDrainURL    string                 `json:"syslog_drain_url,omitempty"`
-	RouteServiceURL   string                 `json:"route_service_url,omitempty"`
-	VolumeMounts      json.RawMessage        `json:"volume_mounts,omitempty"`
-	Endpoints         json.RawMessage        `json:"endpoints,omitempty"`
-	Context           json.RawMessage        `json:"context,omitempty"`
-	BindResource      json.RawMessage        `json:"bind_resource,omitempty"`
-	Credentials       json.RawMessage        `json:"credentials,omitempty"`
-	Parameters        map[string]interface{} `json:"parameters,omitempty"`
+	Secured              `json:"-"`
+	Name                 string                 `json:"name"`
+	ServiceInstanceID    string                 `json:"service_instance_id"`
+	PredecessorBindingID string                 `json:"predecessor_binding_id,omitempty"`
+	SyslogDrainURL       string                 `json:"syslog_drain_url,omitempty"`
+	RouteServiceURL      string                 `json:"route_service_url,omitempty"`
+	VolumeMounts         json.RawMessage        `json:"volume_mounts,omitempty"`
+	Endpoints            json.RawMessage        `json:"endpoints,omitempty"`
+	Context              json.RawMessage        `json:"context,omitempty"`
+	BindResource         json.RawMessage        `json:"bind_resource,omitempty"`
+	Credentials          json.RawMessage        `json:"credentials,omitempty"`
+	Metadata             json.RawMessage        `json:"metadata,omitempty"`
+	Parameters           map[string]interface{} `json:"parameters,omitempty"`
 
 	Integrity []byte `json:"-"`
 }
@@ -86,13 +88,15 @@ func (e *ServiceBinding) Equals(obj Object) bool {
 	binding := obj.(*ServiceBinding)
 	if e.Name != binding.Name ||
 		e.ServiceInstanceID != binding.ServiceInstanceID ||
+		e.PredecessorBindingID != binding.PredecessorBindingID ||
 		e.SyslogDrainURL != binding.SyslogDrainURL ||
 		e.RouteServiceURL != binding.RouteServiceURL ||
 		!reflect.DeepEqual(e.VolumeMounts, binding.VolumeMounts) ||
 		!reflect.DeepEqual(e.Endpoints, binding.Endpoints) ||
 		!reflect.DeepEqual(e.Context, binding.Context) ||
 		!reflect.DeepEqual(e.BindResource, binding.BindResource) ||
-		!reflect.DeepEqual(e.Credentials, binding.Credentials) {
+		!reflect.DeepEqual(e.Credentials, binding.Credentials) ||
+		!reflect.DeepEqual(e.Metadata, binding.Metadata) {
 		return false
 	}
 
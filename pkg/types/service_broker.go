@@ -42,6 +42,7 @@ type ServiceBroker struct {
 	Description string             `json:"description"`
 	BrokerURL   string             `json:"broker_url"`
 	Credentials *Credentials       `json:"credentials,omitempty"`
+	APIVersion  string             `json:"api_version,omitempty"`
 	Catalog     json.RawMessage    `json:"-"`
 	Services    []*ServiceOffering `json:"-"`
 }
@@ -37,6 +37,7 @@ type ServiceInstance struct {
 	DashboardURL         string               `json:"dashboard_url,omitempty"`
 	MaintenanceInfo      json.RawMessage      `json:"maintenance_info,omitempty"`
 	Context              json.RawMessage      `json:"context,omitempty"`
+	Metadata             json.RawMessage      `json:"metadata,omitempty"`
 	UpdateValues         InstanceUpdateValues `json:"-"`
 	PreviousValues       json.RawMessage      `json:"-"`
 
@@ -66,6 +67,7 @@ func (e *ServiceInstance) Equals(obj Object) bool {
 		e.Shared != instance.Shared ||
 		!reflect.DeepEqual(e.UpdateValues, instance.UpdateValues) ||
 		!reflect.DeepEqual(e.Context, instance.Context) ||
+		!reflect.DeepEqual(e.Metadata, instance.Metadata) ||
 		!reflect.DeepEqual(e.MaintenanceInfo, instance.MaintenanceInfo) {
 		return false
 	}
@@ -32,11 +32,12 @@ type ServicePlan struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
-	CatalogID     string `json:"catalog_id"`
-	CatalogName   string `json:"catalog_name"`
-	Free          *bool  `json:"free,omitempty"`
-	Bindable      *bool  `json:"bindable,omitempty"`
-	PlanUpdatable *bool  `json:"plan_updateable,omitempty"`
+	CatalogID        string `json:"catalog_id"`
+	CatalogName      string `json:"catalog_name"`
+	Free             *bool  `json:"free,omitempty"`
+	Bindable         *bool  `json:"bindable,omitempty"`
+	PlanUpdatable    *bool  `json:"plan_updateable,omitempty"`
+	BindingRotatable *bool  `json:"binding_rotatable,omitempty"`
 
 	Metadata               json.RawMessage `json:"metadata,omitempty"`
 	Schemas                json.RawMessage `json:"schemas,omitempty"`
@@ -63,6 +64,9 @@ func (e *ServicePlan) Equals(obj Object) bool {
 		(e.PlanUpdatable == nil && plan.PlanUpdatable != nil) ||
 		(e.PlanUpdatable != nil && plan.PlanUpdatable == nil) ||
 		(e.PlanUpdatable != nil && plan.PlanUpdatable != nil && *e.PlanUpdatable != *plan.PlanUpdatable) ||
+		(e.BindingRotatable == nil && plan.BindingRotatable != nil) ||
+		(e.BindingRotatable != nil && plan.BindingRotatable == nil) ||
+		(e.BindingRotatable != nil && plan.BindingRotatable != nil && *e.BindingRotatable != *plan.BindingRotatable) ||
 		e.CatalogID != plan.CatalogID ||
 		e.CatalogName != plan.CatalogName ||
 		e.Description != plan.Description ||
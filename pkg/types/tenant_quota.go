@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+//go:generate smgen api TenantQuota
+// TenantQuota limits the resources which a tenant can create. Its id is the id of the tenant.
+// Limits which are not set are unlimited.
+type TenantQuota struct {
+	Base
+	MaxInstances            *int           `json:"max_instances,omitempty"`
+	MaxInstancesPerOffering map[string]int `json:"max_instances_per_offering,omitempty"`
+	MaxInstancesPerPlan     map[string]int `json:"max_instances_per_plan,omitempty"`
+	MaxBindingsPerInstance  *int           `json:"max_bindings_per_instance,omitempty"`
+}
+
+func (e *TenantQuota) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	quota := obj.(*TenantQuota)
+	if !reflect.DeepEqual(e.MaxInstances, quota.MaxInstances) ||
+		!reflect.DeepEqual(e.MaxInstancesPerOffering, quota.MaxInstancesPerOffering) ||
+		!reflect.DeepEqual(e.MaxInstancesPerPlan, quota.MaxInstancesPerPlan) ||
+		!reflect.DeepEqual(e.MaxBindingsPerInstance, quota.MaxBindingsPerInstance) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *TenantQuota) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.MaxInstances != nil && *e.MaxInstances < 0 {
+		return fmt.Errorf("max_instances must not be negative")
+	}
+	if e.MaxBindingsPerInstance != nil && *e.MaxBindingsPerInstance < 0 {
+		return fmt.Errorf("max_bindings_per_instance must not be negative")
+	}
+	for offeringID, limit := range e.MaxInstancesPerOffering {
+		if offeringID == "" {
+			return fmt.Errorf("max_instances_per_offering contains an empty service offering id")
+		}
+		if limit < 0 {
+			return fmt.Errorf("max_instances_per_offering of service offering %s must not be negative", offeringID)
+		}
+	}
+	for planID, limit := range e.MaxInstancesPerPlan {
+		if planID == "" {
+			return fmt.Errorf("max_instances_per_plan contains an empty service plan id")
+		}
+		if limit < 0 {
+			return fmt.Errorf("max_instances_per_plan of service plan %s must not be negative", planID)
+		}
+	}
+
+	return nil
+}
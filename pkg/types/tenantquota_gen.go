@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const TenantQuotaType ObjectType = web.TenantQuotasURL
+
+type TenantQuotas struct {
+	TenantQuotas []*TenantQuota `json:"tenant_quotas"`
+}
+
+func (e *TenantQuotas) Add(object Object) {
+	e.TenantQuotas = append(e.TenantQuotas, object.(*TenantQuota))
+}
+
+func (e *TenantQuotas) ItemAt(index int) Object {
+	return e.TenantQuotas[index]
+}
+
+func (e *TenantQuotas) Len() int {
+	return len(e.TenantQuotas)
+}
+
+func (e *TenantQuota) GetType() ObjectType {
+	return TenantQuotaType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *TenantQuota) MarshalJSON() ([]byte, error) {
+	type E TenantQuota
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
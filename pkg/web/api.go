@@ -42,6 +42,24 @@ const (
 
 	// QueryParamForce is the value used to denote if the requested resource should be purged from db
 	QueryParamForce = "force"
+
+	// QueryParamFields is the value used to denote the comma separated fields of the requested resources which should be returned
+	QueryParamFields = "fields"
+
+	// QueryParamExpand is the value used to denote the comma separated related resources which should be returned with the requested resources
+	QueryParamExpand = "expand"
+
+	// QueryParamGroupBy is the value used to denote the comma separated fields by which the counted resources are grouped
+	QueryParamGroupBy = "groupBy"
+
+	// QueryParamGroupByLabel is the value used to denote the comma separated label keys by which the counted resources are grouped
+	QueryParamGroupByLabel = "groupByLabel"
+
+	// QueryParamMode is the value used to denote how the imported resources which conflict with existing ones are handled
+	QueryParamMode = "mode"
+
+	// QueryParamDryRun is the value used to denote that the request should only report the changes it would make
+	QueryParamDryRun = "dryRun"
 )
 
 // API is the primary point for REST API registration
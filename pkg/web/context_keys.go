@@ -14,6 +14,7 @@ const (
 	authorizationErrorKey
 	shouldStoreBindingsKey
 	generatePlatformCredentialsKey
+	keepPlatformCredentialsKey
 	smaapOperatedKey
 	originatingIdentityKey
 	operationContextKey
@@ -38,6 +39,17 @@ func ContextWithGeneratePlatformCredentialsFlag(ctx context.Context, generate bo
 	return context.WithValue(ctx, generatePlatformCredentialsKey, generate)
 }
 
+// ShouldKeepPlatformCredentials returns whether the provided credentials of a created platform have to be kept instead of generated
+func ShouldKeepPlatformCredentials(ctx context.Context) bool {
+	keep := ctx.Value(keepPlatformCredentialsKey)
+	return keep != nil && keep.(bool)
+}
+
+// ContextWithKeepPlatformCredentialsFlag sets the keepPlatformCredentials flag in the context
+func ContextWithKeepPlatformCredentialsFlag(ctx context.Context, keep bool) context.Context {
+	return context.WithValue(ctx, keepPlatformCredentialsKey, keep)
+}
+
 // ShouldStoreBindings returns whether the request has to store bindings
 func ShouldStoreBindings(ctx context.Context) bool {
 	shouldStoreBindings := ctx.Value(shouldStoreBindingsKey)
@@ -21,6 +21,7 @@ import (
 	"net/http"
 
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/tracing"
 )
 
 // Request contains the original http.Request, path parameters and the raw body
@@ -150,7 +151,7 @@ func (fs Filters) ChainMatching(route Route) Handler {
 }
 
 // Chain chains the Filters around the specified Handler and returns a Handler. It also adds logic for logging before
-// entering and after exiting from filters.
+// entering and after exiting from filters. Each filter is run in its own tracing span.
 func (fs Filters) Chain(h Handler) Handler {
 	wrappedFilters := make([]Handler, len(fs)+1)
 	wrappedFilters[len(fs)] = h
@@ -158,7 +159,11 @@ func (fs Filters) Chain(h Handler) Handler {
 	for i := len(fs) - 1; i >= 0; i-- {
 		i := i
 		wrappedFilters[i] = HandlerFunc(func(r *Request) (*Response, error) {
-			return fs[i].Run(r, wrappedFilters[i+1])
+			ctx, span := tracing.StartSpan(r.Context(), "filter "+fs[i].Name())
+			r.Request = r.WithContext(ctx)
+			resp, err := fs[i].Run(r, wrappedFilters[i+1])
+			tracing.End(span, err)
+			return resp, err
 		})
 	}
 
@@ -21,6 +21,9 @@ const (
 	// VisibilitiesURL is the URL path to manage visibilities
 	VisibilitiesURL = "/" + apiVersion + "/visibilities"
 
+	// VisibilitiesBatchURL is the URL path to create, update and delete many visibilities at once
+	VisibilitiesBatchURL = VisibilitiesURL + "/batch"
+
 	// NotificationsURL is the URL path to manage notifications
 	NotificationsURL = "/" + apiVersion + "/notifications"
 
@@ -33,6 +36,9 @@ const (
 	// MonitorHealthURL is the path of the healthcheck endpoint
 	MonitorHealthURL = "/" + apiVersion + "/monitor/health"
 
+	// MetricsURL is the path of the Prometheus metrics endpoint
+	MetricsURL = "/metrics"
+
 	// InfoURL is the path of the info endpoint
 	InfoURL = "/" + apiVersion + "/info"
 
@@ -42,11 +48,32 @@ const (
 	// LoggingConfigURL is the Logging Configuration API URL path
 	LoggingConfigURL = ConfigURL + "/logging"
 
+	// KeyRotationURL is the URL path to rotate the storage encryption key and obtain the progress of the rotation
+	KeyRotationURL = ConfigURL + "/key_rotation"
+
+	// ExportURL is the URL path to export the platforms, service brokers and visibilities in a signed archive
+	ExportURL = ConfigURL + "/export"
+
+	// ImportURL is the URL path to import an archive produced by the export
+	ImportURL = ConfigURL + "/import"
+
 	// ResourceOperationsURL is the URL path fetch operations for a resource
 	ResourceOperationsURL = "/operations"
 
 	ParametersURL = "/parameters"
 
+	// RotateURL is the URL path to rotate the credentials of a service binding
+	RotateURL = "/rotate"
+
+	// StatsURL is the URL path to count resources grouped by fields and labels
+	StatsURL = "/stats"
+
+	// QuotasURL is the URL path to manage the quotas of a tenant
+	QuotasURL = "/quotas"
+
+	// BrokerCatalogPreviewURL is the URL path to preview the catalog changes of a service broker update
+	BrokerCatalogPreviewURL = "/catalog_preview"
+
 	// OperationsURL is the operations API base URL path
 	OperationsURL = "/" + apiVersion + "/operations"
 
@@ -56,6 +83,15 @@ const (
 	// ProfileURL is the Configuration API base URL path
 	ProfileURL = "/" + apiVersion + "/profile"
 
+	// EventsURL is the URL path to fetch the change feed of the resources
+	EventsURL = "/" + apiVersion + "/events"
+
+	// AuditEventsURL is the URL path to query the audit trail of the changes to the resources
+	AuditEventsURL = "/" + apiVersion + "/audit_events"
+
+	// TenantQuotasURL identifies the tenant quotas in the storage, they are managed through TenantURL
+	TenantQuotasURL = "/" + apiVersion + "/tenant_quotas"
+
 	TenantURL = "/" + apiVersion + "/tenants"
 	AgentsURL = "/" + apiVersion + "/agents/versions"
 )
@@ -16,14 +16,17 @@
 
 package web
 
+import "fmt"
+
 // AuthenticationType specifies the authentication type that is stored in the user context
 type AuthenticationType int
 
-var authenticationTypes = []string{"Basic", "Bearer"}
+var authenticationTypes = []string{"Basic", "Bearer", "Certificate"}
 
 const (
 	Basic AuthenticationType = iota
 	Bearer
+	Certificate
 )
 
 // String implements Stringer and converts the decision to human-readable value
@@ -58,6 +61,21 @@ func (a AccessLevel) String() string {
 	return levels[a]
 }
 
+var configuredLevels = map[string]AccessLevel{
+	"global":      GlobalAccess,
+	"all_tenants": AllTenantAccess,
+	"tenant":      TenantAccess,
+}
+
+// ParseAccessLevel returns the access level with the specified configuration name - global, all_tenants or tenant
+func ParseAccessLevel(level string) (AccessLevel, error) {
+	accessLevel, found := configuredLevels[level]
+	if !found {
+		return NoAccess, fmt.Errorf("unsupported access level %s", level)
+	}
+	return accessLevel, nil
+}
+
 // UserContext holds the information for the current user
 type UserContext struct {
 	// Data unmarshals the additional user context details into the specified struct
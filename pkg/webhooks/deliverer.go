@@ -0,0 +1,245 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// deliveryLockIndexBase is the first advisory index of the platform delivery locks. It is far above the advisory
+// indexes of the other locks, so that the indexes derived from the platform ids do not collide with them.
+const deliveryLockIndexBase = 1 << 30
+
+// Deliverer schedules a go routine which delivers the notifications of the platforms with a webhook. The notifications
+// of each platform are delivered one by one in the order of their revisions. The revision of the last delivered
+// notification is stored in the platform webhook status, so that the delivery continues from it after a failure or
+// a restart. A notification is delivered at least once.
+//
+// The notifications are delivered once they are older than the settle period, so that the notifications with lower
+// revisions stored in transactions which are still running are not skipped.
+type Deliverer struct {
+	started bool
+
+	repository        storage.Repository
+	lockerCreatorFunc storage.LockerCreatorFunc
+	settings          *Settings
+	client            *http.Client
+}
+
+// NewDeliverer constructs a Deliverer
+func NewDeliverer(repository storage.Repository, lockerCreatorFunc storage.LockerCreatorFunc, settings *Settings) *Deliverer {
+	return &Deliverer{
+		repository:        repository,
+		lockerCreatorFunc: lockerCreatorFunc,
+		settings:          settings,
+		client:            &http.Client{Timeout: settings.Timeout},
+	}
+}
+
+// Start schedules the deliverer. It cannot be used concurrently.
+func (d *Deliverer) Start(ctx context.Context, group *sync.WaitGroup) error {
+	if d.started {
+		return errors.New("webhook deliverer already started")
+	}
+	d.started = true
+	group.Add(1)
+	go func() {
+		defer func() {
+			d.started = false
+			group.Done()
+		}()
+		log.C(ctx).Infof("Scheduling webhook delivery every %s", d.settings.DeliveryInterval.String())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(d.settings.DeliveryInterval):
+				d.deliver(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+func (d *Deliverer) deliver(ctx context.Context) {
+	platforms, err := d.repository.List(ctx, types.PlatformType,
+		query.ByField(query.NotEqualsOperator, "webhook_url", ""),
+		query.ByField(query.LessThanOperator, "webhook_next_attempt_at", util.ToRFCNanoFormat(time.Now())))
+	if err != nil {
+		log.C(ctx).WithError(err).Error("could not fetch platforms with webhooks")
+		return
+	}
+
+	for i := 0; i < platforms.Len(); i++ {
+		platformID := platforms.ItemAt(i).GetID()
+		if err := d.deliverToPlatform(ctx, platformID); err != nil {
+			log.C(ctx).WithError(err).Errorf("could not deliver notifications to the webhook of platform %s", platformID)
+		}
+	}
+}
+
+// deliverToPlatform delivers the notifications of the platform. Only a single Service Manager instance delivers the
+// notifications of a platform at a time, so that their revision order is kept.
+func (d *Deliverer) deliverToPlatform(ctx context.Context, platformID string) error {
+	locker := d.lockerCreatorFunc(deliveryLockIndex(platformID))
+	if err := locker.TryLock(ctx); err != nil {
+		log.C(ctx).Debugf("Failed to retrieve lock for webhook delivery to platform %s: %s", platformID, err)
+		return nil
+	}
+	defer func() {
+		if err := locker.Unlock(ctx); err != nil {
+			log.C(ctx).Warnf("Could not unlock for webhook delivery to platform %s: %s", platformID, err)
+		}
+	}()
+
+	// the platform is fetched once locked, so that the notifications delivered by another instance are not repeated
+	object, err := d.repository.Get(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", platformID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil
+		}
+		return err
+	}
+	platform := object.(*types.Platform)
+	if platform.Webhook == nil {
+		return nil
+	}
+	status := platform.WebhookStatus
+	if status == nil {
+		status = &types.WebhookStatus{}
+	}
+	if time.Now().Before(status.NextAttemptAt) {
+		return nil
+	}
+
+	notifications, err := d.repository.List(ctx, types.NotificationType,
+		query.ByField(query.EqualsOrNilOperator, "platform_id", platform.ID),
+		query.ByField(query.GreaterThanOperator, "revision", strconv.FormatInt(status.Revision, 10)),
+		query.ByField(query.LessThanOperator, "created_at", util.ToRFCNanoFormat(time.Now().Add(-d.settings.SettlePeriod))),
+		query.OrderResultBy("revision", query.AscOrder),
+		query.LimitResultBy(d.settings.BatchSize))
+	if err != nil {
+		return err
+	}
+	if notifications.Len() == 0 {
+		return nil
+	}
+
+	log.C(ctx).Debugf("Delivering %d notifications to the webhook of platform %s", notifications.Len(), platform.ID)
+	for i := 0; i < notifications.Len(); i++ {
+		notification := notifications.ItemAt(i).(*types.Notification)
+		if err := d.post(ctx, platform.Webhook, notification); err != nil {
+			status.ConsecutiveFailures++
+			status.LastError = err.Error()
+			status.NextAttemptAt = time.Now().UTC().Add(d.settings.backoff(status.ConsecutiveFailures))
+			log.C(ctx).Warnf("Delivery of notification %s to the webhook of platform %s failed %d consecutive times, next attempt at %s: %s",
+				notification.ID, platform.ID, status.ConsecutiveFailures, status.NextAttemptAt, err)
+			break
+		}
+		status.Revision = notification.Revision
+		status.LastDeliveredAt = time.Now().UTC()
+		status.ConsecutiveFailures = 0
+		status.LastError = ""
+		status.NextAttemptAt = time.Time{}
+	}
+
+	return d.updateStatus(ctx, platform.ID, status)
+}
+
+// deliveryLockIndex returns the advisory index of the lock of the delivery to the platform
+func deliveryLockIndex(platformID string) int {
+	hash := fnv.New32a()
+	// writing to a hash never fails
+	_, _ = hash.Write([]byte(platformID))
+	return deliveryLockIndexBase + int(hash.Sum32()%deliveryLockIndexBase)
+}
+
+func (d *Deliverer) post(ctx context.Context, webhook *types.Webhook, notification *types.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+	request.Header.Set(RevisionHeader, strconv.FormatInt(notification.Revision, 10))
+	if correlationID := notification.CorrelationID; correlationID != "" {
+		request.Header.Set(log.CorrelationIDHeaders[0], correlationID)
+	}
+
+	response, err := d.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := io.Copy(ioutil.Discard, response.Body); err != nil {
+			log.C(ctx).Debugf("Could not read the response of webhook %s: %s", webhook.URL, err)
+		}
+		if err := response.Body.Close(); err != nil {
+			log.C(ctx).Debugf("Could not close the response of webhook %s: %s", webhook.URL, err)
+		}
+	}()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// updateStatus stores the delivery status of the platform webhook. The platform is fetched again, so that changes
+// made to it during the delivery are not overwritten.
+func (d *Deliverer) updateStatus(ctx context.Context, platformID string, status *types.WebhookStatus) error {
+	object, err := d.repository.Get(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", platformID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil
+		}
+		return err
+	}
+
+	platform := object.(*types.Platform)
+	if platform.Webhook == nil {
+		// the webhook was removed during the delivery
+		return nil
+	}
+	platform.WebhookStatus = status
+	_, err = d.repository.Update(ctx, platform, nil)
+	return err
+}
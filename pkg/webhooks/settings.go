@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhooks
+
+import (
+	"fmt"
+	"time"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	DeliveryInterval time.Duration `mapstructure:"delivery_interval" description:"time between checks for notifications which should be delivered to platform webhooks"`
+	BatchSize        int           `mapstructure:"batch_size" description:"maximum number of notifications delivered to a platform webhook in a single check"`
+	Timeout          time.Duration `mapstructure:"timeout" description:"timeout of a single webhook request"`
+	MinBackoff       time.Duration `mapstructure:"min_backoff" description:"time to wait before retrying the first failed delivery to a webhook"`
+	MaxBackoff       time.Duration `mapstructure:"max_backoff" description:"maximum time to wait before retrying a failed delivery to a webhook"`
+	SettlePeriod     time.Duration `mapstructure:"settle_period" description:"the time after which the notifications are delivered to the webhooks, it should exceed the duration of the transactions storing them"`
+}
+
+// DefaultSettings returns the default values for the webhook delivery
+func DefaultSettings() *Settings {
+	return &Settings{
+		DeliveryInterval: time.Second * 5,
+		BatchSize:        100,
+		Timeout:          time.Second * 10,
+		MinBackoff:       time.Second * 5,
+		MaxBackoff:       time.Minute * 10,
+		SettlePeriod:     time.Second * 5,
+	}
+}
+
+// Validate validates the webhook delivery settings
+func (s *Settings) Validate() error {
+	if s.DeliveryInterval <= 0 {
+		return fmt.Errorf("validate webhooks settings: delivery_interval should be > 0")
+	}
+	if s.BatchSize <= 0 {
+		return fmt.Errorf("validate webhooks settings: batch_size should be > 0")
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("validate webhooks settings: timeout should be > 0")
+	}
+	if s.MinBackoff <= 0 {
+		return fmt.Errorf("validate webhooks settings: min_backoff should be > 0")
+	}
+	if s.MaxBackoff < s.MinBackoff {
+		return fmt.Errorf("validate webhooks settings: max_backoff should be >= min_backoff")
+	}
+	if s.SettlePeriod < 0 {
+		return fmt.Errorf("validate webhooks settings: settle_period should be >= 0")
+	}
+	return nil
+}
+
+// backoff returns the time to wait before retrying the delivery after the provided number of consecutive failures
+func (s *Settings) backoff(failures int) time.Duration {
+	delay := s.MinBackoff
+	for i := 1; i < failures && delay < s.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.MaxBackoff {
+		return s.MaxBackoff
+	}
+	return delay
+}
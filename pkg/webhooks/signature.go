@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// SignatureHeader is the header containing the signature of a delivered notification
+	SignatureHeader = "X-Peripli-Webhook-Signature"
+	// TimestampHeader is the header containing the unix time at which a notification was delivered
+	TimestampHeader = "X-Peripli-Webhook-Timestamp"
+	// RevisionHeader is the header containing the revision of a delivered notification
+	RevisionHeader = "X-Peripli-Notification-Revision"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the signature of a notification delivered at the provided timestamp. The signature is the hex encoded
+// HMAC-SHA256 of the timestamp and the body separated by a dot, computed with the webhook secret.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether the signature of a delivered notification is valid for the webhook secret
+func VerifySignature(secret, timestamp string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhooks
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhooks", func() {
+	Describe("Sign", func() {
+		body := []byte(`{"revision":1}`)
+
+		It("verifies a signature computed with the same secret", func() {
+			signature := Sign("secret", "1600000000", body)
+			Expect(signature).To(HavePrefix("sha256="))
+			Expect(VerifySignature("secret", "1600000000", body, signature)).To(BeTrue())
+		})
+
+		It("does not verify a signature computed with another secret", func() {
+			signature := Sign("other", "1600000000", body)
+			Expect(VerifySignature("secret", "1600000000", body, signature)).To(BeFalse())
+		})
+
+		It("does not verify a signature for another timestamp", func() {
+			signature := Sign("secret", "1600000000", body)
+			Expect(VerifySignature("secret", "1600000001", body, signature)).To(BeFalse())
+		})
+
+		It("does not verify a signature without prefix", func() {
+			signature := Sign("secret", "1600000000", body)
+			Expect(VerifySignature("secret", "1600000000", body, signature[len("sha256="):])).To(BeFalse())
+		})
+	})
+
+	Describe("deliveryLockIndex", func() {
+		It("returns the same index for the same platform", func() {
+			Expect(deliveryLockIndex("platform")).To(Equal(deliveryLockIndex("platform")))
+		})
+
+		It("returns indexes above the indexes of the other locks", func() {
+			for _, platformID := range []string{"", "platform", "4f6c6f6e-8e2b-4d5c-9f37-1a2b3c4d5e6f"} {
+				Expect(deliveryLockIndex(platformID)).To(BeNumerically(">=", deliveryLockIndexBase))
+			}
+		})
+	})
+
+	Describe("Settings", func() {
+		var settings *Settings
+
+		BeforeEach(func() {
+			settings = DefaultSettings()
+		})
+
+		It("accepts the default settings", func() {
+			Expect(settings.Validate()).To(Succeed())
+		})
+
+		Context("when batch size is not positive", func() {
+			It("returns an error", func() {
+				settings.BatchSize = 0
+				Expect(settings.Validate()).To(HaveOccurred())
+			})
+		})
+
+		Context("when max backoff is less than min backoff", func() {
+			It("returns an error", func() {
+				settings.MaxBackoff = settings.MinBackoff / 2
+				Expect(settings.Validate()).To(HaveOccurred())
+			})
+		})
+
+		Context("when settle period is negative", func() {
+			It("returns an error", func() {
+				settings.SettlePeriod = -time.Second
+				Expect(settings.Validate()).To(HaveOccurred())
+			})
+		})
+
+		It("doubles the backoff up to the max backoff", func() {
+			settings.MinBackoff = time.Second
+			settings.MaxBackoff = 5 * time.Second
+			Expect(settings.backoff(1)).To(Equal(time.Second))
+			Expect(settings.backoff(2)).To(Equal(2 * time.Second))
+			Expect(settings.backoff(3)).To(Equal(4 * time.Second))
+			Expect(settings.backoff(4)).To(Equal(5 * time.Second))
+			Expect(settings.backoff(100)).To(Equal(5 * time.Second))
+		})
+	})
+})
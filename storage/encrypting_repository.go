@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/Peripli/service-manager/pkg/log"
@@ -36,10 +37,31 @@ type KeyStore interface {
 
 	// SetEncryptionKey sets the provided encryption key in the KeyStore after applying the specified transformation function
 	SetEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error
+
+	// GetPreviousEncryptionKey returns the encryption key replaced by the last rotation after applying the specified transformation
+	// function. It returns an empty key if the encryption key was never rotated
+	GetPreviousEncryptionKey(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error)
+
+	// RotateEncryptionKey sets the provided encryption key in the KeyStore after applying the specified transformation function
+	// and keeps the replaced encryption key as the previous one
+	RotateEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error
+
+	// RewrapEncryptionKeys re-encrypts the encryption keys in the KeyStore, which are encrypted with the previous encryption key
+	// in the environment, with the current one. The keys stay decryptable with the previous encryption key in the environment
+	// until an instance without it finalises the rotation. It returns whether the encryption keys were re-encrypted or finalised
+	RewrapEncryptionKeys(ctx context.Context, decryptFunc, encryptFunc func(context.Context, []byte, []byte) ([]byte, error)) (bool, error)
+
+	// GetKeyRotation returns the progress of the last encryption key rotation or nil if the encryption key was never rotated
+	GetKeyRotation(ctx context.Context) (*KeyRotation, error)
+
+	// SetKeyRotation stores the progress of the encryption key rotation
+	SetKeyRotation(ctx context.Context, rotation *KeyRotation) error
 }
 
-// EncryptingDecorator creates a TransactionalRepositoryDecorator that can be used to add encrypting/decrypting logic to a TransactionalRepository
-func EncryptingDecorator(ctx context.Context, encrypter security.Encrypter, keyStore KeyStore, locker Locker) TransactionalRepositoryDecorator {
+// EncryptingDecorator creates a TransactionalRepositoryDecorator that can be used to add encrypting/decrypting logic to a TransactionalRepository.
+// The encryption keys in the KeyStore are encrypted with the keyEncrypter and the credentials are encrypted with the encrypter.
+// The encryption keys are reloaded from the KeyStore after the specified refresh interval, so that keys rotated by other instances are used.
+func EncryptingDecorator(ctx context.Context, keyEncrypter, encrypter security.Encrypter, keyStore KeyStore, locker Locker, refreshInterval time.Duration) TransactionalRepositoryDecorator {
 	return func(next TransactionalRepository) (TransactionalRepository, error) {
 		ctx, cancelFunc := context.WithTimeout(ctx, 2*time.Second)
 		defer cancelFunc()
@@ -53,7 +75,15 @@ func EncryptingDecorator(ctx context.Context, encrypter security.Encrypter, keyS
 			}
 		}()
 
-		encryptionKey, err := keyStore.GetEncryptionKey(ctx, encrypter.Decrypt)
+		rewrapped, err := keyStore.RewrapEncryptionKeys(ctx, keyEncrypter.Decrypt, keyEncrypter.Encrypt)
+		if err != nil {
+			return nil, fmt.Errorf("could not re-encrypt encryption keys with the new storage encryption key: %v", err)
+		}
+		if rewrapped {
+			log.C(ctx).Info("Successfully re-encrypted the encryption keys with the new storage encryption key")
+		}
+
+		encryptionKey, err := keyStore.GetEncryptionKey(ctx, keyEncrypter.Decrypt)
 		if err != nil {
 			return nil, err
 		}
@@ -67,35 +97,53 @@ func EncryptingDecorator(ctx context.Context, encrypter security.Encrypter, keyS
 			}
 
 			encryptionKey = newEncryptionKey
-			if err = keyStore.SetEncryptionKey(ctx, newEncryptionKey, encrypter.Encrypt); err != nil {
+			if err = keyStore.SetEncryptionKey(ctx, newEncryptionKey, keyEncrypter.Encrypt); err != nil {
 				return nil, err
 			}
 			logger.Info("Successfully generated new encryption key")
 		}
 
-		return NewEncryptingRepository(next, encrypter, encryptionKey)
+		previousEncryptionKey, err := keyStore.GetPreviousEncryptionKey(ctx, keyEncrypter.Decrypt)
+		if err != nil {
+			return nil, err
+		}
+
+		return newEncryptingRepository(next, encrypter, &encryptionKeys{
+			keyStore:        keyStore,
+			keyEncrypter:    keyEncrypter,
+			encrypter:       encrypter,
+			refreshInterval: refreshInterval,
+			current:         encryptionKey,
+			previous:        previousEncryptionKey,
+			loadedAt:        time.Now(),
+		}), nil
 	}
 }
 
 //NewEncryptingRepository creates a new TransactionalEncryptingRepository using the specified encrypter and encryption key
 func NewEncryptingRepository(repository TransactionalRepository, encrypter security.Encrypter, key []byte) (*TransactionalEncryptingRepository, error) {
-	encryptingRepository := &TransactionalEncryptingRepository{
+	return newEncryptingRepository(repository, encrypter, &encryptionKeys{
+		encrypter: encrypter,
+		current:   key,
+	}), nil
+}
+
+func newEncryptingRepository(repository TransactionalRepository, encrypter security.Encrypter, keys *encryptionKeys) *TransactionalEncryptingRepository {
+	return &TransactionalEncryptingRepository{
 		encryptingRepository: &encryptingRepository{
-			repository:    repository,
-			encrypter:     encrypter,
-			encryptionKey: key,
+			repository: repository,
+			encrypter:  encrypter,
+			keys:       keys,
 		},
 		repository: repository,
 	}
-
-	return encryptingRepository, nil
 }
 
 type encryptingRepository struct {
 	repository Repository
 	encrypter  security.Encrypter
 
-	encryptionKey []byte
+	keys *encryptionKeys
 }
 
 //TransactionalEncryptingRepository is a TransactionalRepository with that also encrypts credentials of Secured objects
@@ -150,7 +198,7 @@ func (er *encryptingRepository) Get(ctx context.Context, objectType types.Object
 }
 
 func (er *encryptingRepository) GetForUpdate(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
-	obj, err := er.repository.Get(ctx, objectType, criteria...)
+	obj, err := er.repository.GetForUpdate(ctx, objectType, criteria...)
 	if err != nil {
 		return nil, err
 	}
@@ -201,6 +249,10 @@ func (er *encryptingRepository) CountLabelValues(ctx context.Context, objectType
 	return er.repository.CountLabelValues(ctx, objectType, criteria...)
 }
 
+func (er *encryptingRepository) CountGroups(ctx context.Context, objectType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error) {
+	return er.repository.CountGroups(ctx, objectType, fields, labelKeys, criteria...)
+}
+
 func (er *encryptingRepository) Update(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, _ ...query.Criterion) (types.Object, error) {
 	if err := er.encrypt(ctx, obj); err != nil {
 		return nil, err
@@ -247,18 +299,14 @@ func (er *encryptingRepository) Delete(ctx context.Context, objectType types.Obj
 
 func (er *encryptingRepository) encrypt(ctx context.Context, obj types.Object) error {
 	if securedObject, isSecured := obj.(types.Secured); isSecured {
-		return securedObject.Encrypt(ctx, func(ctx context.Context, bytes []byte) ([]byte, error) {
-			return er.encrypter.Encrypt(ctx, bytes, er.encryptionKey)
-		})
+		return securedObject.Encrypt(ctx, er.keys.encrypt)
 	}
 	return nil
 }
 
 func (er *encryptingRepository) decrypt(ctx context.Context, obj types.Object) error {
 	if securedObject, isSecured := obj.(types.Secured); isSecured {
-		return securedObject.Decrypt(ctx, func(ctx context.Context, bytes []byte) ([]byte, error) {
-			return er.encrypter.Decrypt(ctx, bytes, er.encryptionKey)
-		})
+		return securedObject.Decrypt(ctx, er.keys.decrypt)
 	}
 	return nil
 }
@@ -267,9 +315,9 @@ func (er *encryptingRepository) decrypt(ctx context.Context, obj types.Object) e
 func (er *TransactionalEncryptingRepository) InTransaction(ctx context.Context, f func(ctx context.Context, storage Repository) error) error {
 	return er.repository.InTransaction(ctx, func(ctx context.Context, storage Repository) error {
 		return f(ctx, &encryptingRepository{
-			repository:    storage,
-			encrypter:     er.encrypter,
-			encryptionKey: er.encryptionKey,
+			repository: storage,
+			encrypter:  er.encrypter,
+			keys:       er.keys,
 		})
 	})
 }
@@ -277,3 +325,79 @@ func (er *TransactionalEncryptingRepository) InTransaction(ctx context.Context,
 func (er *encryptingRepository) GetEntities() []EntityMetadata {
 	return er.repository.GetEntities()
 }
+
+// encryptionKeys holds the current encryption key, which is used for encryption, and the encryption key replaced by the last
+// rotation, which is used for decrypting the credentials not yet re-encrypted with the current one
+type encryptionKeys struct {
+	// keyStore is used to reload the encryption keys. The keys are never reloaded if it is nil
+	keyStore        KeyStore
+	keyEncrypter    security.Encrypter
+	encrypter       security.Encrypter
+	refreshInterval time.Duration
+
+	mutex    sync.RWMutex
+	current  []byte
+	previous []byte
+	loadedAt time.Time
+}
+
+func (k *encryptionKeys) encrypt(ctx context.Context, bytes []byte) ([]byte, error) {
+	current, _, err := k.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return k.encrypter.Encrypt(ctx, bytes, current)
+}
+
+func (k *encryptionKeys) decrypt(ctx context.Context, bytes []byte) ([]byte, error) {
+	current, previous, err := k.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := k.decryptWith(ctx, bytes, current, previous)
+	if err == nil || k.keyStore == nil {
+		return decrypted, err
+	}
+
+	// the credentials may be encrypted with a key rotated by another instance
+	log.C(ctx).WithError(err).Info("Could not decrypt credentials with the loaded encryption keys. Reloading the encryption keys...")
+	if current, previous, err = k.reload(ctx); err != nil {
+		return nil, err
+	}
+	return k.decryptWith(ctx, bytes, current, previous)
+}
+
+func (k *encryptionKeys) decryptWith(ctx context.Context, bytes, current, previous []byte) ([]byte, error) {
+	decrypted, err := k.encrypter.Decrypt(ctx, bytes, current)
+	if err != nil && len(previous) != 0 {
+		return k.encrypter.Decrypt(ctx, bytes, previous)
+	}
+	return decrypted, err
+}
+
+func (k *encryptionKeys) get(ctx context.Context) ([]byte, []byte, error) {
+	k.mutex.RLock()
+	current, previous, loadedAt := k.current, k.previous, k.loadedAt
+	k.mutex.RUnlock()
+
+	if k.keyStore == nil || time.Since(loadedAt) < k.refreshInterval {
+		return current, previous, nil
+	}
+	return k.reload(ctx)
+}
+
+func (k *encryptionKeys) reload(ctx context.Context) ([]byte, []byte, error) {
+	current, err := k.keyStore.GetEncryptionKey(ctx, k.keyEncrypter.Decrypt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not reload encryption key: %v", err)
+	}
+	previous, err := k.keyStore.GetPreviousEncryptionKey(ctx, k.keyEncrypter.Decrypt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not reload previous encryption key: %v", err)
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.current, k.previous, k.loadedAt = current, previous, time.Now()
+	return current, previous, nil
+}
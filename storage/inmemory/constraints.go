@@ -0,0 +1,186 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// The constraints below mirror the ones created by the postgres migrations so that both storages reject the same
+// changes. They are keyed by table name.
+
+// uniqueConstraints lists the column sets which must be unique in a table. As in SQL, NULL values never conflict.
+var uniqueConstraints = map[string][][]string{
+	"platforms":                   {{"name"}, {"username"}},
+	"brokers":                     {{"name"}},
+	"service_offerings":           {{"broker_id", "name"}, {"broker_id", "catalog_id"}, {"broker_id", "catalog_name"}},
+	"service_plans":               {{"service_offering_id", "name"}, {"service_offering_id", "catalog_id"}, {"service_offering_id", "catalog_name"}},
+	"visibilities":                {{"platform_id", "service_plan_id"}},
+	"broker_platform_credentials": {{"platform_id", "broker_id"}, {"broker_id", "username"}},
+}
+
+type foreignKey struct {
+	table      string
+	column     string
+	references string
+	// cascade specifies whether the referencing rows are deleted together with the referenced one or the deletion
+	// of the referenced row is rejected
+	cascade bool
+}
+
+var foreignKeys = []foreignKey{
+	{table: "service_offerings", column: "broker_id", references: "brokers", cascade: true},
+	{table: "service_plans", column: "service_offering_id", references: "service_offerings", cascade: true},
+	{table: "visibilities", column: "platform_id", references: "platforms", cascade: true},
+	{table: "visibilities", column: "service_plan_id", references: "service_plans", cascade: true},
+	{table: "notifications", column: "platform_id", references: "platforms", cascade: true},
+	{table: "service_instances", column: "service_plan_id", references: "service_plans"},
+	{table: "service_instances", column: "platform_id", references: "platforms"},
+	{table: "service_bindings", column: "service_instance_id", references: "service_instances"},
+	{table: "broker_platform_credentials", column: "platform_id", references: "platforms", cascade: true},
+	{table: "broker_platform_credentials", column: "broker_id", references: "brokers", cascade: true},
+}
+
+// checkConstraints contains additional validations of the rows stored in a table
+var checkConstraints = map[string]func(db *database, t *table, r *row) error{
+	"visibilities": checkUniquePublicPlan,
+}
+
+func (s *Storage) validateRow(db *database, t *table, r *row) error {
+	for _, constraint := range uniqueConstraints[t.name] {
+		if err := validateUnique(db, t, r, constraint); err != nil {
+			return err
+		}
+	}
+
+	for _, fk := range foreignKeys {
+		if fk.table != t.name {
+			continue
+		}
+		value := t.columns[fk.column].value(r.entity)
+		if value == nil {
+			continue
+		}
+		referencedTable := s.scheme.tableByName(fk.references)
+		if referencedTable == nil {
+			continue
+		}
+		if _, found := db.rows(referencedTable.objectType)[fmt.Sprint(value)]; !found {
+			return &util.ErrBadRequestStorage{
+				Cause: fmt.Errorf("insert or update on table %s violates foreign key constraint: key (%s)=(%s) is not present in table %s", t.name, fk.column, value, fk.references),
+			}
+		}
+	}
+
+	if check, found := checkConstraints[t.name]; found {
+		if err := check(db, t, r); err != nil {
+			return &util.ErrBadRequestStorage{Cause: err}
+		}
+	}
+
+	for key := range r.labels {
+		if key == "" {
+			return &util.ErrBadRequestStorage{
+				Cause: fmt.Errorf("label key for entity with id %s in table %s cannot be empty", r.id(), t.name),
+			}
+		}
+	}
+	return nil
+}
+
+func validateUnique(db *database, t *table, r *row, constraint []string) error {
+	values := make([]interface{}, 0, len(constraint))
+	for _, columnName := range constraint {
+		value := t.columns[columnName].value(r.entity)
+		if value == nil {
+			return nil
+		}
+		values = append(values, value)
+	}
+
+	for id, existing := range db.rows(t.objectType) {
+		if id == r.id() {
+			continue
+		}
+		conflict := true
+		for i, columnName := range constraint {
+			if t.columns[columnName].value(existing.entity) != values[i] {
+				conflict = false
+				break
+			}
+		}
+		if conflict {
+			return util.ErrAlreadyExistsInStorage
+		}
+	}
+	return nil
+}
+
+// checkUniquePublicPlan allows a plan to either have a single public visibility or visibilities for specific platforms
+func checkUniquePublicPlan(db *database, t *table, r *row) error {
+	planID := t.columns["service_plan_id"].value(r.entity)
+	isPublic := t.columns["platform_id"].value(r.entity) == nil
+	for id, existing := range db.rows(t.objectType) {
+		if id == r.id() || t.columns["service_plan_id"].value(existing.entity) != planID {
+			continue
+		}
+		existingIsPublic := t.columns["platform_id"].value(existing.entity) == nil
+		if existingIsPublic || isPublic {
+			return fmt.Errorf("visibility for plan %s violates check constraint unique_public_plan_visibility", planID)
+		}
+	}
+	return nil
+}
+
+// deletionsFor returns the rows which are deleted together with the provided one by cascading foreign keys. An error
+// is returned if a row referencing any of them prevents the deletion.
+func (s *Storage) deletionsFor(db *database, rootType *table, t *table, r *row, deletions map[*table]map[string]bool) error {
+	if deletions[t] == nil {
+		deletions[t] = make(map[string]bool)
+	}
+	if deletions[t][r.id()] {
+		return nil
+	}
+	deletions[t][r.id()] = true
+
+	for _, fk := range foreignKeys {
+		if fk.references != t.name {
+			continue
+		}
+		referencingTable := s.scheme.tableByName(fk.table)
+		if referencingTable == nil {
+			continue
+		}
+		for _, referencingRow := range db.rows(referencingTable.objectType) {
+			if referencingTable.columns[fk.column].value(referencingRow.entity) != r.id() {
+				continue
+			}
+			if !fk.cascade {
+				return &util.ErrForeignKeyViolation{
+					Entity:          rootType.objectType.String(),
+					ReferenceEntity: referencingTable.objectType.String(),
+				}
+			}
+			if err := s.deletionsFor(db, rootType, referencingTable, referencingRow, deletions); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,480 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// predicate tests whether a row satisfies a criterion
+type predicate func(r *row) bool
+
+type orderRule struct {
+	column    *column
+	orderType query.OrderType
+}
+
+// selection is the compiled form of a list of criteria. It is built once per query so that invalid criteria are
+// rejected even if there are no rows to test them against.
+type selection struct {
+	repository      *repository
+	table           *table
+	predicates      []predicate
+	labelPredicates []predicate
+	orderBy         []orderRule
+	after           []string
+	limit           int
+	hasLimit        bool
+}
+
+func newSelection(r *repository, t *table, criteria ...query.Criterion) (*selection, error) {
+	s := &selection{repository: r, table: t}
+	for _, criterion := range criteria {
+		if err := criterion.Validate(); err != nil {
+			return nil, err
+		}
+		if criterion.Type == query.ResultQuery {
+			if err := s.addResultCriterion(criterion); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		p, err := s.compile(criterion)
+		if err != nil {
+			return nil, err
+		}
+		s.predicates = append(s.predicates, p)
+		if criterion.Type == query.LabelQuery && !criterion.IsCompound() {
+			s.labelPredicates = append(s.labelPredicates, p)
+		}
+	}
+	if s.after != nil {
+		p, err := s.compileAfter()
+		if err != nil {
+			return nil, err
+		}
+		s.predicates = append(s.predicates, p)
+	}
+	return s, nil
+}
+
+func (s *selection) addResultCriterion(criterion query.Criterion) error {
+	switch criterion.LeftOp {
+	case query.OrderBy:
+		orderType := query.OrderType(criterion.RightOp[1])
+		if orderType != query.AscOrder && orderType != query.DescOrder {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported order type: %s", orderType)}
+		}
+		c, found := s.table.columns[criterion.RightOp[0]]
+		if !found || !c.isOrderable() {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported entity field for order by: %s", criterion.RightOp[0])}
+		}
+		s.orderBy = append(s.orderBy, orderRule{column: c, orderType: orderType})
+	case query.After:
+		if s.after != nil {
+			return fmt.Errorf("zero/one after criterion expected but multiple provided")
+		}
+		s.after = criterion.RightOp
+	case query.Limit:
+		if s.hasLimit {
+			return fmt.Errorf("zero/one limit expected but multiple provided")
+		}
+		limit, err := strconv.Atoi(criterion.RightOp[0])
+		if err != nil {
+			return fmt.Errorf("could not convert string to int: %s", err.Error())
+		}
+		s.limit = limit
+		s.hasLimit = true
+	}
+	return nil
+}
+
+// compileAfter returns a predicate which is satisfied by the rows coming after the values of the after criterion
+// in the order of the selection
+func (s *selection) compileAfter() (predicate, error) {
+	if len(s.after) != len(s.orderBy) {
+		return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("after criterion expects %d values, one for each order by criterion, but %d provided", len(s.orderBy), len(s.after))}
+	}
+	values := make([]interface{}, 0, len(s.after))
+	for i, value := range s.after {
+		c := s.orderBy[i].column
+		parse := func(value string) (interface{}, error) { return value, nil }
+		switch {
+		case c.isNumeric():
+			parse = parseNumber
+		case c.isTime():
+			parse = parseTime
+		}
+		parsed, err := parse(value)
+		if err != nil {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("invalid value %s for field %s: %s", value, c.name, err)}
+		}
+		values = append(values, parsed)
+	}
+
+	return func(r *row) bool {
+		for i, rule := range s.orderBy {
+			cmp := compare(rule.column.value(r.entity), values[i])
+			if rule.orderType == query.DescOrder {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp > 0
+			}
+		}
+		return false
+	}, nil
+}
+
+func (s *selection) compile(criterion query.Criterion) (predicate, error) {
+	if criterion.IsCompound() {
+		return s.compileCompound(criterion)
+	}
+	switch criterion.Type {
+	case query.FieldQuery:
+		return s.compileField(criterion)
+	case query.LabelQuery:
+		return compileLabel(criterion), nil
+	case query.ExistQuery, query.Subquery:
+		return s.compileSubQuery(criterion)
+	default:
+		return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("%s criteria are not supported by the in-memory storage", criterion.Type)}
+	}
+}
+
+// compileSubQuery evaluates the sub-query once and returns a predicate checking whether the correlated column of the
+// row is among its results
+func (s *selection) compileSubQuery(criterion query.Criterion) (predicate, error) {
+	columnName, values, err := resolveSubQuery(s.repository.db, s.repository.storage, criterion.RightOp[0])
+	if err != nil {
+		return nil, err
+	}
+	if criterion.Type == query.Subquery {
+		columnName = criterion.LeftOp
+	}
+	c, found := s.table.columns[columnName]
+	if !found {
+		return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query key: %s", columnName)}
+	}
+	exists := criterion.Operator != query.NotExistsSubquery
+	return func(r *row) bool {
+		value := c.value(r.entity)
+		if value == nil {
+			return !exists
+		}
+		return values[fmt.Sprint(value)] == exists
+	}, nil
+}
+
+func (s *selection) compileCompound(criterion query.Criterion) (predicate, error) {
+	nested := make([]predicate, 0, len(criterion.Criteria))
+	for _, nestedCriterion := range criterion.Criteria {
+		p, err := s.compile(nestedCriterion)
+		if err != nil {
+			return nil, err
+		}
+		nested = append(nested, p)
+	}
+	switch criterion.Operator {
+	case query.OrOperator:
+		return func(r *row) bool {
+			for _, p := range nested {
+				if p(r) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case query.NotOperator:
+		return func(r *row) bool {
+			return !nested[0](r)
+		}, nil
+	default:
+		return func(r *row) bool {
+			for _, p := range nested {
+				if !p(r) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	}
+}
+
+func (s *selection) compileField(criterion query.Criterion) (predicate, error) {
+	columnName := criterion.LeftOp
+	var jsonPath []string
+	if strings.Contains(columnName, "/") {
+		parts := strings.Split(columnName, "/")
+		columnName, jsonPath = parts[0], parts[1:]
+		if c, found := s.table.columns[columnName]; found && !c.isJSON() {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query: json notation on non json column: %s", columnName)}
+		}
+	}
+	c, found := s.table.columns[columnName]
+	if !found {
+		return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query key: %s", criterion.LeftOp)}
+	}
+	if criterion.Operator == query.ContainsOperator && !c.isText() {
+		return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query: the operator '%s' is not applicable on non-string columns: %s", criterion.Operator.String(), columnName)}
+	}
+
+	// json values and columns which are neither numbers nor timestamps are compared by their text representation
+	parse := func(value string) (interface{}, error) { return value, nil }
+	if len(jsonPath) == 0 {
+		switch {
+		case c.isNumeric():
+			parse = parseNumber
+		case c.isTime():
+			parse = parseTime
+		}
+	}
+	rightOp := make([]interface{}, 0, len(criterion.RightOp))
+	for _, value := range criterion.RightOp {
+		parsed, err := parse(value)
+		if err != nil {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("invalid value %s for field %s: %s", value, criterion.LeftOp, err)}
+		}
+		rightOp = append(rightOp, parsed)
+	}
+
+	return func(r *row) bool {
+		value := c.value(r.entity)
+		if value != nil && len(jsonPath) != 0 {
+			value = jsonPathValue(value.(string), jsonPath)
+		}
+		if value == nil {
+			return criterion.Operator.IsNullable()
+		}
+		return evaluate(criterion.Operator, value, rightOp)
+	}, nil
+}
+
+// compileLabel returns a predicate which is satisfied if any of the values of the label with the criterion key
+// satisfies the criterion
+func compileLabel(criterion query.Criterion) predicate {
+	rightOp := make([]interface{}, 0, len(criterion.RightOp))
+	for _, value := range criterion.RightOp {
+		rightOp = append(rightOp, value)
+	}
+	return func(r *row) bool {
+		for _, value := range r.labels[criterion.LeftOp] {
+			if evaluate(criterion.Operator, value, rightOp) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (s *selection) matches(r *row) bool {
+	for _, p := range s.predicates {
+		if !p(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the rows satisfying the selection. As in the postgres storage the rows are ordered by paging sequence
+// unless another order is requested and the limit is applied on the ordered rows.
+func (s *selection) apply() []*row {
+	result := make([]*row, 0)
+	for _, r := range s.repository.db.sortedRows(s.table) {
+		if s.matches(r) {
+			result = append(result, r)
+		}
+	}
+	if len(s.orderBy) != 0 {
+		sort.SliceStable(result, func(i, j int) bool {
+			for _, rule := range s.orderBy {
+				cmp := compareNullable(rule.column.value(result[i].entity), rule.column.value(result[j].entity))
+				if rule.orderType == query.DescOrder {
+					cmp = -cmp
+				}
+				if cmp != 0 {
+					return cmp < 0
+				}
+			}
+			return false
+		})
+	}
+	if s.hasLimit && s.limit < len(result) {
+		result = result[:s.limit]
+	}
+	return result
+}
+
+// countLabels returns the number of labels of the row which satisfy the label criteria of the selection or the
+// number of all labels of the row if there are no label criteria
+func (s *selection) countLabels(r *row) int {
+	count := 0
+	for key, values := range r.labels {
+		for _, value := range values {
+			if len(s.labelPredicates) == 0 {
+				count++
+				continue
+			}
+			single := &row{entity: r.entity, labels: map[string][]string{key: {value}}}
+			for _, p := range s.labelPredicates {
+				if p(single) {
+					count++
+					break
+				}
+			}
+		}
+	}
+	return count
+}
+
+func evaluate(operator query.Operator, value interface{}, rightOp []interface{}) bool {
+	switch operator {
+	case query.EqualsOperator, query.EqualsOrNilOperator:
+		return compare(value, rightOp[0]) == 0
+	case query.NotEqualsOperator:
+		return compare(value, rightOp[0]) != 0
+	case query.GreaterThanOperator:
+		return compare(value, rightOp[0]) > 0
+	case query.GreaterThanOrEqualOperator:
+		return compare(value, rightOp[0]) >= 0
+	case query.LessThanOperator:
+		return compare(value, rightOp[0]) < 0
+	case query.LessThanOrEqualOperator:
+		return compare(value, rightOp[0]) <= 0
+	case query.InOperator:
+		return contains(value, rightOp)
+	case query.NotInOperator:
+		return !contains(value, rightOp)
+	case query.ContainsOperator:
+		return strings.Contains(fmt.Sprint(value), fmt.Sprint(rightOp[0]))
+	default:
+		return false
+	}
+}
+
+func contains(value interface{}, values []interface{}) bool {
+	for _, v := range values {
+		if compare(value, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares two non-NULL values of the same column
+func compare(left, right interface{}) int {
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		switch {
+		case l < r:
+			return -1
+		case l > r:
+			return 1
+		}
+		return 0
+	case time.Time:
+		r := right.(time.Time)
+		switch {
+		case l.Before(r):
+			return -1
+		case l.After(r):
+			return 1
+		}
+		return 0
+	default:
+		return strings.Compare(fmt.Sprint(left), fmt.Sprint(right))
+	}
+}
+
+// compareNullable compares column values ordering NULL values as greater than any other value
+func compareNullable(left, right interface{}) int {
+	switch {
+	case left == nil && right == nil:
+		return 0
+	case left == nil:
+		return 1
+	case right == nil:
+		return -1
+	}
+	return compare(left, right)
+}
+
+func parseNumber(value string) (interface{}, error) {
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return number, nil
+}
+
+// timeLayouts are the layouts of the timestamp literals accepted by the storage, e.g. 0001-01-01 00:00:00+00
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+func parseTime(value string) (interface{}, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var timestamp time.Time
+		if timestamp, err = time.Parse(layout, value); err == nil {
+			return timestamp, nil
+		}
+	}
+	return nil, err
+}
+
+// jsonPathValue returns the text of the json value found at the provided path or nil if there is no such value
+func jsonPathValue(document string, path []string) interface{} {
+	var current interface{}
+	if err := json.Unmarshal([]byte(document), &current); err != nil {
+		return nil
+	}
+	for _, key := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if current, ok = object[key]; !ok {
+			return nil
+		}
+	}
+	switch v := current.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	default:
+		text, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		return string(text)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"sort"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// row is a single stored entity together with its labels. Rows are never modified after being stored, changes
+// replace the whole row so that they can be shared between the committed state and running transactions.
+type row struct {
+	entity storage.Entity
+	labels types.Labels
+}
+
+func (r *row) id() string {
+	return r.entity.GetID()
+}
+
+// rows holds the rows of a single table by their id
+type rows map[string]*row
+
+// database is a snapshot of the stored data. Transactions work on a copy of the committed snapshot which shares the
+// tables that are not modified by the transaction.
+type database struct {
+	tables map[types.ObjectType]rows
+	// copied contains the tables which have already been copied by the transaction working on the snapshot
+	copied map[types.ObjectType]bool
+}
+
+func newDatabase() *database {
+	return &database{
+		tables: make(map[types.ObjectType]rows),
+		copied: make(map[types.ObjectType]bool),
+	}
+}
+
+// snapshot returns a copy of the database whose tables are copied on first write
+func (db *database) snapshot() *database {
+	tables := make(map[types.ObjectType]rows, len(db.tables))
+	for objectType, tableRows := range db.tables {
+		tables[objectType] = tableRows
+	}
+	return &database{
+		tables: tables,
+		copied: make(map[types.ObjectType]bool),
+	}
+}
+
+func (db *database) rows(objectType types.ObjectType) rows {
+	return db.tables[objectType]
+}
+
+func (db *database) writableRows(objectType types.ObjectType) rows {
+	if !db.copied[objectType] {
+		tableRows := make(rows, len(db.tables[objectType])+1)
+		for id, r := range db.tables[objectType] {
+			tableRows[id] = r
+		}
+		db.tables[objectType] = tableRows
+		db.copied[objectType] = true
+	}
+	return db.tables[objectType]
+}
+
+func (db *database) put(objectType types.ObjectType, r *row) {
+	db.writableRows(objectType)[r.id()] = r
+}
+
+func (db *database) remove(objectType types.ObjectType, id string) {
+	delete(db.writableRows(objectType), id)
+}
+
+// sortedRows returns the rows of the table in the order of their paging sequence
+func (db *database) sortedRows(t *table) []*row {
+	tableRows := db.rows(t.objectType)
+	result := make([]*row, 0, len(tableRows))
+	for _, r := range tableRows {
+		result = append(result, r)
+	}
+	sequence, hasSequence := t.columns[pagingSequenceColumn]
+	sort.Slice(result, func(i, j int) bool {
+		if hasSequence {
+			return sequence.value(result[i].entity).(int64) < sequence.value(result[j].entity).(int64)
+		}
+		return result[i].id() < result[j].id()
+	})
+	return result
+}
+
+func copyLabels(labels types.Labels) types.Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+	result := make(types.Labels, len(labels))
+	for key, values := range labels {
+		result[key] = append([]string{}, values...)
+	}
+	return result
+}
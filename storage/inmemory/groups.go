@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// group is a set of rows having the same values of the grouped columns and label keys
+type group struct {
+	values []interface{}
+	count  int
+}
+
+// countGroups counts the rows grouped by the values of the provided columns and label keys the same way as the postgres
+// storage does. Rows having multiple values of a grouped label key are counted in the group of each of the values and
+// rows which do not have a grouped label key are counted in the group with no value for it. The largest groups come first.
+func countGroups(rows []*row, columns []*column, labelKeys []string) []*types.GroupCount {
+	groups := make(map[string]*group)
+	var order []*group
+	for _, r := range rows {
+		for _, values := range groupValueCombinations(r, columns, labelKeys) {
+			key := groupKey(values)
+			g, found := groups[key]
+			if !found {
+				g = &group{values: values}
+				groups[key] = g
+				order = append(order, g)
+			}
+			g.count++
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].count != order[j].count {
+			return order[i].count > order[j].count
+		}
+		for k := range order[i].values {
+			if cmp := compareNullable(order[i].values[k], order[j].values[k]); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+
+	result := make([]*types.GroupCount, 0, len(order))
+	for _, g := range order {
+		groupCount := &types.GroupCount{Count: g.count}
+		if len(columns) != 0 {
+			groupCount.Fields = make(map[string]*string, len(columns))
+			for i, c := range columns {
+				groupCount.Fields[c.name] = groupValue(g.values[i])
+			}
+		}
+		if len(labelKeys) != 0 {
+			groupCount.Labels = make(map[string]*string, len(labelKeys))
+			for i, key := range labelKeys {
+				groupCount.Labels[key] = groupValue(g.values[len(columns)+i])
+			}
+		}
+		result = append(result, groupCount)
+	}
+	return result
+}
+
+// groupValueCombinations returns the values of the grouped columns combined with each of the values of the grouped label keys
+func groupValueCombinations(r *row, columns []*column, labelKeys []string) [][]interface{} {
+	values := make([]interface{}, 0, len(columns)+len(labelKeys))
+	for _, c := range columns {
+		values = append(values, c.value(r.entity))
+	}
+	combinations := [][]interface{}{values}
+	for _, key := range labelKeys {
+		labelValues := []interface{}{nil}
+		if len(r.labels[key]) != 0 {
+			labelValues = labelValues[:0]
+			for _, value := range r.labels[key] {
+				labelValues = append(labelValues, value)
+			}
+		}
+		next := make([][]interface{}, 0, len(combinations)*len(labelValues))
+		for _, combination := range combinations {
+			for _, value := range labelValues {
+				next = append(next, append(append([]interface{}{}, combination...), value))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+func groupKey(values []interface{}) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		if text := groupValue(value); text != nil {
+			parts = append(parts, fmt.Sprintf("%q", *text))
+		} else {
+			parts = append(parts, "null")
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// groupValue returns the text representation of a grouped value as it is returned by the API or nil for NULL values
+func groupValue(value interface{}) *string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		text = v.UTC().Format(time.RFC3339Nano)
+	default:
+		text = fmt.Sprint(v)
+	}
+	return &text
+}
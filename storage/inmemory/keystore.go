@@ -0,0 +1,211 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const securityLockIndex = 111
+
+// EncryptingLocker builds an encrypting storage.Locker with the pre-defined lock index
+func EncryptingLocker(storage *Storage) storage.Locker {
+	return &Locker{Storage: storage, AdvisoryIndex: securityLockIndex}
+}
+
+// GetEncryptionKey returns the encryption key used to encrypt the credentials for brokers
+func (s *Storage) GetEncryptionKey(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	s.checkOpen()
+
+	s.mutex.RLock()
+	encryptedKey, rewrappedKey := s.encryptionKey, s.rewrappedEncryptionKey
+	s.mutex.RUnlock()
+	if encryptedKey == nil {
+		return []byte{}, nil
+	}
+
+	return s.unwrap(ctx, encryptedKey, rewrappedKey, transformationFunc)
+}
+
+// SetEncryptionKey Sets the encryption key by encrypting it beforehand with the encryption key in the environment
+func (s *Storage) SetEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
+	s.checkOpen()
+
+	encryptedKey, rewrappedKey, err := s.wrap(ctx, key, transformationFunc)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.encryptionKey, s.rewrappedEncryptionKey = encryptedKey, rewrappedKey
+	return nil
+}
+
+// GetPreviousEncryptionKey returns the encryption key which was replaced by the last encryption key rotation
+func (s *Storage) GetPreviousEncryptionKey(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	s.checkOpen()
+
+	s.mutex.RLock()
+	encryptedKey, rewrappedKey := s.previousEncryptionKey, s.rewrappedPreviousEncryptionKey
+	s.mutex.RUnlock()
+	if encryptedKey == nil {
+		return []byte{}, nil
+	}
+
+	return s.unwrap(ctx, encryptedKey, rewrappedKey, transformationFunc)
+}
+
+// RotateEncryptionKey sets the encryption key by encrypting it beforehand with the encryption key in the environment and keeps
+// the replaced encryption key as the previous one
+func (s *Storage) RotateEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
+	s.checkOpen()
+
+	encryptedKey, rewrappedKey, err := s.wrap(ctx, key, transformationFunc)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.previousEncryptionKey, s.rewrappedPreviousEncryptionKey = s.encryptionKey, s.rewrappedEncryptionKey
+	s.encryptionKey, s.rewrappedEncryptionKey = encryptedKey, rewrappedKey
+	return nil
+}
+
+// RewrapEncryptionKeys re-encrypts the encryption keys, which are encrypted with the previous encryption key in the environment,
+// with the current one. The re-encrypted keys are kept next to the original ones until the previous encryption key is removed
+// from the environment, so that the instances which still use it can decrypt the encryption keys.
+func (s *Storage) RewrapEncryptionKeys(ctx context.Context, decryptFunc, encryptFunc func(context.Context, []byte, []byte) ([]byte, error)) (bool, error) {
+	s.checkOpen()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.encryptionKey == nil {
+		return false, nil
+	}
+
+	if len(s.layerOnePreviousEncryptionKey) == 0 {
+		if s.rewrappedEncryptionKey == nil {
+			return false, nil
+		}
+		// the rotation of the encryption key in the environment is finalised only by the instances using the new one
+		if _, err := decryptFunc(ctx, s.rewrappedEncryptionKey, s.layerOneEncryptionKey); err != nil {
+			return false, nil
+		}
+		s.encryptionKey, s.previousEncryptionKey = s.rewrappedEncryptionKey, s.rewrappedPreviousEncryptionKey
+		s.rewrappedEncryptionKey, s.rewrappedPreviousEncryptionKey = nil, nil
+		return true, nil
+	}
+
+	if s.rewrappedEncryptionKey != nil {
+		return false, nil
+	}
+	if _, err := decryptFunc(ctx, s.encryptionKey, s.layerOneEncryptionKey); err == nil {
+		return false, nil
+	}
+
+	rewrappedKey, err := s.rewrap(ctx, s.encryptionKey, decryptFunc, encryptFunc)
+	if err != nil {
+		return false, err
+	}
+	var rewrappedPreviousKey []byte
+	if s.previousEncryptionKey != nil {
+		if rewrappedPreviousKey, err = s.rewrap(ctx, s.previousEncryptionKey, decryptFunc, encryptFunc); err != nil {
+			return false, err
+		}
+	}
+
+	s.rewrappedEncryptionKey, s.rewrappedPreviousEncryptionKey = rewrappedKey, rewrappedPreviousKey
+	return true, nil
+}
+
+func (s *Storage) rewrap(ctx context.Context, encryptedKey []byte, decryptFunc, encryptFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	key, err := decryptFunc(ctx, encryptedKey, s.layerOnePreviousEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return encryptFunc(ctx, key, s.layerOneEncryptionKey)
+}
+
+// wrap encrypts the key with the encryption key in the environment. While the encryption key in the environment is
+// replaced, the key is encrypted with the previous one and its rewrapped copy is encrypted with the new one.
+func (s *Storage) wrap(ctx context.Context, key []byte, encryptFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, []byte, error) {
+	if len(s.layerOnePreviousEncryptionKey) == 0 {
+		encryptedKey, err := encryptFunc(ctx, key, s.layerOneEncryptionKey)
+		return encryptedKey, nil, err
+	}
+
+	encryptedKey, err := encryptFunc(ctx, key, s.layerOnePreviousEncryptionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	rewrappedKey, err := encryptFunc(ctx, key, s.layerOneEncryptionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encryptedKey, rewrappedKey, nil
+}
+
+// unwrap decrypts the key with the first encryption key in the environment which it is encrypted with
+func (s *Storage) unwrap(ctx context.Context, encryptedKey, rewrappedKey []byte, decryptFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	if rewrappedKey != nil {
+		if key, err := decryptFunc(ctx, rewrappedKey, s.layerOneEncryptionKey); err == nil {
+			return key, nil
+		}
+	}
+	key, err := decryptFunc(ctx, encryptedKey, s.layerOneEncryptionKey)
+	if err != nil && len(s.layerOnePreviousEncryptionKey) != 0 {
+		return decryptFunc(ctx, encryptedKey, s.layerOnePreviousEncryptionKey)
+	}
+	return key, err
+}
+
+// GetKeyRotation returns the progress of the last encryption key rotation
+func (s *Storage) GetKeyRotation(ctx context.Context) (*storage.KeyRotation, error) {
+	s.checkOpen()
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.keyRotation == nil {
+		return nil, nil
+	}
+	return copyKeyRotation(s.keyRotation), nil
+}
+
+// SetKeyRotation stores the progress of the encryption key rotation
+func (s *Storage) SetKeyRotation(ctx context.Context, rotation *storage.KeyRotation) error {
+	s.checkOpen()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keyRotation = copyKeyRotation(rotation)
+	return nil
+}
+
+// copyKeyRotation copies the progress, so that it is not shared with the key rotator updating it
+func copyKeyRotation(rotation *storage.KeyRotation) *storage.KeyRotation {
+	result := *rotation
+	result.Reencrypted = make(map[types.ObjectType]int, len(rotation.Reencrypted))
+	for objectType, count := range rotation.Reencrypted {
+		result.Reencrypted[objectType] = count
+	}
+	return &result
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/log"
+)
+
+var ErrLockAcquisition = errors.New("failed to acquire lock")
+
+// Locker is the in-memory counterpart of the postgres advisory lock. Lockers with the same advisory index created for
+// the same storage exclude each other.
+type Locker struct {
+	*Storage
+	isLocked      bool
+	AdvisoryIndex int
+	lockerMutex   sync.Mutex
+}
+
+// Lock acquires the lock so that only one process can manipulate the guarded resource.
+// Returns an error if the process has already acquired the lock
+func (l *Locker) Lock(ctx context.Context) error {
+	log.C(ctx).Debugf("Attempting to lock advisory lock with index (%d)", l.AdvisoryIndex)
+	l.lockerMutex.Lock()
+	defer l.lockerMutex.Unlock()
+	if l.isLocked {
+		log.C(ctx).Infof("Locker with advisory index (%d) is locked, so no attempt to lock it", l.AdvisoryIndex)
+		return fmt.Errorf("lock is already acquired")
+	}
+
+	select {
+	case l.lock() <- struct{}{}:
+	case <-ctx.Done():
+		log.C(ctx).Infof("Failed to lock locker with advisory index (%d)", l.AdvisoryIndex)
+		return ctx.Err()
+	}
+	l.isLocked = true
+
+	log.C(ctx).Debugf("Successfully locked locker with advisory index (%d)", l.AdvisoryIndex)
+	return nil
+}
+
+// TryLock acquires the lock only if it is not held by anyone else.
+// Returns an error if the process has already acquired the lock
+func (l *Locker) TryLock(ctx context.Context) error {
+	log.C(ctx).Debugf("Attempting to try_lock advisory lock with index (%d)", l.AdvisoryIndex)
+	l.lockerMutex.Lock()
+	defer l.lockerMutex.Unlock()
+	if l.isLocked {
+		log.C(ctx).Infof("Locker with advisory index (%d) is locked, so no attempt to try_lock it", l.AdvisoryIndex)
+		return fmt.Errorf("try_lock is already acquired")
+	}
+
+	select {
+	case l.lock() <- struct{}{}:
+	default:
+		log.C(ctx).Debugf("Failed to try_lock locker with advisory index (%d) - already locked", l.AdvisoryIndex)
+		return ErrLockAcquisition
+	}
+	l.isLocked = true
+
+	log.C(ctx).Debugf("Successfully try_locked locker with advisory index (%d)", l.AdvisoryIndex)
+	return nil
+}
+
+// Unlock releases the lock.
+func (l *Locker) Unlock(ctx context.Context) error {
+	log.C(ctx).Debugf("Attempting to unlock advisory lock with index (%d)", l.AdvisoryIndex)
+	l.lockerMutex.Lock()
+	defer l.lockerMutex.Unlock()
+	if !l.isLocked {
+		log.C(ctx).Infof("Locker with advisory index (%d) is not locked, so no attempt to unlock it", l.AdvisoryIndex)
+		return nil
+	}
+
+	<-l.lock()
+	l.isLocked = false
+
+	log.C(ctx).Debugf("Successfully unlocked locker with advisory index (%d)", l.AdvisoryIndex)
+	return nil
+}
+
+// lock returns the channel representing the lock with the advisory index of the locker. The lock is held while the
+// channel contains a value.
+func (l *Locker) lock() chan struct{} {
+	l.locksMutex.Lock()
+	defer l.locksMutex.Unlock()
+	if _, found := l.locks[l.AdvisoryIndex]; !found {
+		l.locks[l.AdvisoryIndex] = make(chan struct{}, 1)
+	}
+	return l.locks[l.AdvisoryIndex]
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peripli/service-manager/storage/inmemory"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storage Locker", func() {
+	var (
+		ctx          context.Context
+		s            *inmemory.Storage
+		locker       *inmemory.Locker
+		secondLocker *inmemory.Locker
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		s = &inmemory.Storage{}
+		Expect(s.Open(newSettings())).To(Succeed())
+		locker = &inmemory.Locker{Storage: s, AdvisoryIndex: 1}
+		secondLocker = &inmemory.Locker{Storage: s, AdvisoryIndex: 1}
+	})
+
+	Describe("Lock", func() {
+		Context("when the locker is already locked", func() {
+			It("returns an error", func() {
+				Expect(locker.Lock(ctx)).To(Succeed())
+				Expect(locker.Lock(ctx)).To(MatchError("lock is already acquired"))
+			})
+		})
+
+		Context("when another locker holds the lock", func() {
+			It("waits until the lock is released", func() {
+				Expect(locker.Lock(ctx)).To(Succeed())
+
+				locked := make(chan error)
+				go func() {
+					locked <- secondLocker.Lock(ctx)
+				}()
+				Consistently(locked, 100*time.Millisecond).ShouldNot(Receive())
+
+				Expect(locker.Unlock(ctx)).To(Succeed())
+				Eventually(locked).Should(Receive(BeNil()))
+			})
+
+			It("returns an error when the context is done", func() {
+				Expect(locker.Lock(ctx)).To(Succeed())
+
+				timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+				defer cancel()
+				Expect(secondLocker.Lock(timeoutCtx)).To(Equal(context.DeadlineExceeded))
+			})
+		})
+
+		Context("when a locker with different index holds its lock", func() {
+			It("succeeds", func() {
+				Expect(locker.Lock(ctx)).To(Succeed())
+				Expect((&inmemory.Locker{Storage: s, AdvisoryIndex: 2}).Lock(ctx)).To(Succeed())
+			})
+		})
+	})
+
+	Describe("TryLock", func() {
+		Context("when another locker holds the lock", func() {
+			It("returns lock acquisition error", func() {
+				Expect(locker.TryLock(ctx)).To(Succeed())
+				Expect(secondLocker.TryLock(ctx)).To(Equal(inmemory.ErrLockAcquisition))
+			})
+		})
+
+		Context("when the lock is released", func() {
+			It("succeeds", func() {
+				Expect(locker.TryLock(ctx)).To(Succeed())
+				Expect(locker.Unlock(ctx)).To(Succeed())
+				Expect(secondLocker.TryLock(ctx)).To(Succeed())
+			})
+		})
+	})
+
+	Describe("Unlock", func() {
+		Context("when the locker is not locked", func() {
+			It("succeeds", func() {
+				Expect(locker.Unlock(ctx)).To(Succeed())
+			})
+		})
+	})
+})
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/storage"
+)
+
+// namedQuery is the in-memory counterpart of a sql named query. It returns the matching rows of the queried table and
+// whether their labels are part of the result.
+type namedQuery func(r *repository, t *table, params map[string]interface{}) ([]*row, bool, error)
+
+var namedQueries = map[storage.NamedQuery]namedQuery{
+	storage.QueryByMissingLabel:                          queryByLabel(false),
+	storage.QueryByExistingLabel:                         queryByLabel(true),
+	storage.QueryForLastOperationsPerResource:            queryForLastOperationsPerResource,
+	storage.QueryForLabelLessVisibilities:                queryForLabelLessVisibilities,
+	storage.QueryForLabelLessPlanVisibilities:            queryForLabelLessPlanVisibilities,
+	storage.QueryForVisibilityWithPlatformAndPlan:        queryForVisibilityWithPlatformAndPlan,
+	storage.QueryForPlanByNameAndOfferingsWithVisibility: queryForPlanByNameAndOfferingsWithVisibility,
+	storage.QueryForSharedInstances:                      queryForSharedInstances,
+}
+
+func queryByLabel(exists bool) namedQuery {
+	return func(r *repository, t *table, params map[string]interface{}) ([]*row, bool, error) {
+		key := stringParam(params["key"])
+		return filterRows(r.db, t, func(current *row) bool {
+			_, found := current.labels[key]
+			return found == exists
+		}), true, nil
+	}
+}
+
+func queryForLastOperationsPerResource(r *repository, t *table, params map[string]interface{}) ([]*row, bool, error) {
+	ids := stringParams(params["id_list"])
+	resourceType := stringParam(params["resource_type"])
+	lastOperations := lastOperationPerResource(r.db, t, func(current *row) bool {
+		return containsString(ids, columnText(t, current, "resource_id")) && columnText(t, current, "resource_type") == resourceType
+	})
+	return filterRows(r.db, t, func(current *row) bool {
+		return lastOperations[current.id()]
+	}), false, nil
+}
+
+func queryForLabelLessVisibilities(r *repository, t *table, params map[string]interface{}) ([]*row, bool, error) {
+	platformIDs := stringParams(params["platform_ids"])
+	return filterRows(r.db, t, func(current *row) bool {
+		platformID := t.columns["platform_id"].value(current.entity)
+		return len(current.labels) == 0 && (platformID == nil || containsString(platformIDs, fmt.Sprint(platformID)))
+	}), false, nil
+}
+
+func queryForLabelLessPlanVisibilities(r *repository, t *table, params map[string]interface{}) ([]*row, bool, error) {
+	planIDs := stringParams(params["service_plan_ids"])
+	return filterRows(r.db, t, func(current *row) bool {
+		return len(current.labels) == 0 && containsString(planIDs, columnText(t, current, "service_plan_id"))
+	}), false, nil
+}
+
+func queryForVisibilityWithPlatformAndPlan(r *repository, t *table, params map[string]interface{}) ([]*row, bool, error) {
+	planID := stringParam(params["service_plan_id"])
+	platformID := stringParam(params["platform_id"])
+	key := stringParam(params["key"])
+	value := stringParam(params["val"])
+	return filterRows(r.db, t, func(current *row) bool {
+		if columnText(t, current, "service_plan_id") != planID {
+			return false
+		}
+		visibilityPlatformID := t.columns["platform_id"].value(current.entity)
+		return visibilityPlatformID == nil ||
+			(visibilityPlatformID == platformID && (key == "" || len(current.labels) == 0)) ||
+			containsString(current.labels[key], value)
+	}), false, nil
+}
+
+func queryForPlanByNameAndOfferingsWithVisibility(r *repository, t *table, params map[string]interface{}) ([]*row, bool, error) {
+	offerings, err := r.storage.scheme.tableByNameOrError("service_offerings")
+	if err != nil {
+		return nil, false, err
+	}
+	visibilities, err := r.storage.scheme.tableByNameOrError("visibilities")
+	if err != nil {
+		return nil, false, err
+	}
+	platformID := stringParam(params["platform_id"])
+	planName := stringParam(params["service_plan_name"])
+	offeringName := stringParam(params["service_offering_name"])
+	key := stringParam(params["key"])
+	value := stringParam(params["val"])
+
+	visiblePlans := make(map[string]bool)
+	for _, visibility := range r.db.rows(visibilities.objectType) {
+		visibilityPlatformID := visibilities.columns["platform_id"].value(visibility.entity)
+		if (visibilityPlatformID == platformID && containsString(visibility.labels[key], value)) ||
+			((visibilityPlatformID == nil || visibilityPlatformID == platformID) && len(visibility.labels) == 0) {
+			visiblePlans[columnText(visibilities, visibility, "service_plan_id")] = true
+		}
+	}
+
+	return filterRows(r.db, t, func(current *row) bool {
+		if !visiblePlans[current.id()] || columnText(t, current, "catalog_name") != planName {
+			return false
+		}
+		offering, found := r.db.rows(offerings.objectType)[columnText(t, current, "service_offering_id")]
+		return found && columnText(offerings, offering, "catalog_name") == offeringName
+	}), false, nil
+}
+
+func queryForSharedInstances(r *repository, t *table, params map[string]interface{}) ([]*row, bool, error) {
+	plans, err := r.storage.scheme.tableByNameOrError("service_plans")
+	if err != nil {
+		return nil, false, err
+	}
+	offeringID := stringParam(params["offering_id"])
+	tenantIdentifier := stringParam(params["tenant_identifier"])
+	tenantID := stringParam(params["tenant_id"])
+
+	return filterRows(r.db, t, func(current *row) bool {
+		if columnText(t, current, "shared") != "true" || !containsString(current.labels[tenantIdentifier], tenantID) {
+			return false
+		}
+		plan, found := r.db.rows(plans.objectType)[columnText(t, current, "service_plan_id")]
+		return found && columnText(plans, plan, "service_offering_id") == offeringID
+	}), true, nil
+}
+
+func filterRows(db *database, t *table, filter func(*row) bool) []*row {
+	result := make([]*row, 0)
+	for _, current := range db.sortedRows(t) {
+		if filter(current) {
+			result = append(result, current)
+		}
+	}
+	return result
+}
+
+// columnText returns the text representation of the column value or an empty string if the value is NULL
+func columnText(t *table, r *row, columnName string) string {
+	value := t.columns[columnName].value(r.entity)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func stringParam(param interface{}) string {
+	if param == nil {
+		return ""
+	}
+	return fmt.Sprint(param)
+}
+
+func stringParams(param interface{}) []string {
+	switch values := param.(type) {
+	case []string:
+		return values
+	case []interface{}:
+		result := make([]string, 0, len(values))
+		for _, value := range values {
+			result = append(result, stringParam(value))
+		}
+		return result
+	case nil:
+		return nil
+	default:
+		return []string{stringParam(values)}
+	}
+}
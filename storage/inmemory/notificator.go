@@ -0,0 +1,307 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Notificator delivers the notifications committed to an in-memory storage to the registered consumers
+type Notificator struct {
+	storage   *Storage
+	queueSize int
+
+	// pendingMutex guards the notifications which are committed but not yet processed. The storage only appends to
+	// them, so committing a transaction never waits for the consumers.
+	pendingMutex sync.Mutex
+	pending      []*types.Notification
+	hasPending   chan struct{}
+
+	// consumersMutex guards the consumers, the last known revision and whether the notificator is running. All
+	// notifications up to the last known revision have already been sent to the registered consumers.
+	consumersMutex    sync.Mutex
+	consumers         map[string][]storage.NotificationQueue
+	platforms         map[string]*types.Platform
+	lastKnownRevision int64
+	isRunning         bool
+
+	notificationFilters []storage.ReceiversFilterFunc
+	ctx                 context.Context
+	listenerID          int
+}
+
+// NewNotificator returns new Notificator for the provided in-memory storage
+func NewNotificator(st storage.Storage, settings *storage.Settings) (*Notificator, error) {
+	inMemoryStorage, ok := st.(*Storage)
+	if !ok {
+		return nil, errors.New("expected notification storage to be in-memory")
+	}
+	n := &Notificator{
+		storage:           inMemoryStorage,
+		queueSize:         settings.Notification.QueuesSize,
+		hasPending:        make(chan struct{}, 1),
+		consumers:         make(map[string][]storage.NotificationQueue),
+		platforms:         make(map[string]*types.Platform),
+		lastKnownRevision: types.InvalidRevision,
+	}
+	return n, nil
+}
+
+// Start starts the Notificator. It must not be called concurrently.
+func (n *Notificator) Start(ctx context.Context, group *sync.WaitGroup) error {
+	if n.ctx != nil {
+		return errors.New("notificator already started")
+	}
+	n.ctx = ctx
+
+	// the listener is added before reading the last revision, so that no notification is missed
+	n.listenerID = n.storage.addNotificationListener(n.enqueue)
+	lastRevision, err := n.lastRevision()
+	if err != nil {
+		n.storage.removeNotificationListener(n.listenerID)
+		return err
+	}
+	n.consumersMutex.Lock()
+	n.lastKnownRevision = lastRevision
+	n.isRunning = true
+	n.consumersMutex.Unlock()
+
+	util.StartInWaitGroupWithContext(ctx, n.processNotifications, group)
+	return nil
+}
+
+// RegisterConsumer registers a consumer which receives the notifications after the provided revision
+func (n *Notificator) RegisterConsumer(consumer *types.Platform, lastKnownRevision int64) (storage.NotificationQueue, int64, error) {
+	n.consumersMutex.Lock()
+	defer n.consumersMutex.Unlock()
+	if !n.isRunning {
+		return nil, types.InvalidRevision, errors.New("cannot register consumer - Notificator is not running")
+	}
+
+	lastKnownRevisionToSM := n.lastKnownRevision
+	if lastKnownRevision > lastKnownRevisionToSM {
+		log.C(n.ctx).Debug("lastKnownRevision is grater than the one SM knows")
+		return nil, types.InvalidRevision, util.ErrInvalidNotificationRevision
+	}
+
+	queue, err := storage.NewNotificationQueue(n.queueSize)
+	if err != nil {
+		return nil, types.InvalidRevision, err
+	}
+	if lastKnownRevision != types.InvalidRevision && lastKnownRevision != lastKnownRevisionToSM {
+		if err := n.enqueueMissedNotifications(queue, consumer, lastKnownRevision, lastKnownRevisionToSM); err != nil {
+			return nil, types.InvalidRevision, err
+		}
+	}
+
+	if len(n.consumers[consumer.ID]) == 0 {
+		n.platforms[consumer.ID] = consumer
+	}
+	n.consumers[consumer.ID] = append(n.consumers[consumer.ID], queue)
+	return queue, lastKnownRevisionToSM, nil
+}
+
+// UnregisterConsumer must be called to stop receiving notifications in the queue
+func (n *Notificator) UnregisterConsumer(queue storage.NotificationQueue) error {
+	n.consumersMutex.Lock()
+	defer n.consumersMutex.Unlock()
+	queue.Close()
+	for platformID, queues := range n.consumers {
+		for i, platformQueue := range queues {
+			if platformQueue.ID() != queue.ID() {
+				continue
+			}
+			n.consumers[platformID] = append(queues[:i], queues[i+1:]...)
+			if len(n.consumers[platformID]) == 0 {
+				delete(n.consumers, platformID)
+				delete(n.platforms, platformID)
+			}
+			return nil
+		}
+	}
+	return nil // Consumer already unregistered
+}
+
+// RegisterFilter adds new notification filter. It must not be called concurrently.
+func (n *Notificator) RegisterFilter(f storage.ReceiversFilterFunc) {
+	n.notificationFilters = append(n.notificationFilters, f)
+}
+
+// Stats returns statistics about the registered consumers
+func (n *Notificator) Stats() storage.NotificatorStats {
+	n.consumersMutex.Lock()
+	defer n.consumersMutex.Unlock()
+	stats := storage.NotificatorStats{
+		ConnectedPlatforms: len(n.consumers),
+	}
+	for _, platformConsumers := range n.consumers {
+		for _, queue := range platformConsumers {
+			stats.QueuedNotifications += len(queue.Channel())
+		}
+	}
+	return stats
+}
+
+func (n *Notificator) enqueueMissedNotifications(queue storage.NotificationQueue, platform *types.Platform, lastKnownRevision, lastKnownRevisionToSM int64) error {
+	revisionQuery := query.ByField(query.EqualsOperator, "revision", strconv.FormatInt(lastKnownRevision, 10))
+	if _, err := n.storage.Get(n.ctx, types.NotificationType, revisionQuery); err != nil {
+		if err == util.ErrNotFoundInStorage {
+			log.C(n.ctx).WithError(err).Debugf("Notification with revision %d not found in storage", lastKnownRevision)
+			return util.ErrInvalidNotificationRevision
+		}
+		return err
+	}
+
+	objectList, err := n.storage.List(n.ctx, types.NotificationType,
+		query.OrderResultBy("revision", query.AscOrder),
+		query.ByField(query.GreaterThanOperator, "revision", strconv.FormatInt(lastKnownRevision, 10)),
+		query.ByField(query.LessThanOrEqualOperator, "revision", strconv.FormatInt(lastKnownRevisionToSM, 10)),
+		query.ByField(query.EqualsOrNilOperator, "platform_id", platform.ID))
+	if err != nil {
+		return err
+	}
+	missedNotifications := make([]*types.Notification, 0, objectList.Len())
+	for _, notification := range objectList.(*types.Notifications).Notifications {
+		if len(n.filterRecipients([]*types.Platform{platform}, notification)) != 0 {
+			missedNotifications = append(missedNotifications, notification)
+		}
+	}
+
+	if n.queueSize < len(missedNotifications) {
+		log.C(n.ctx).Debugf("Too many missed notifications %d", len(missedNotifications))
+		return util.ErrInvalidNotificationRevision
+	}
+	for _, notification := range missedNotifications {
+		if err := queue.Enqueue(notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue is called by the storage for each committed notification
+func (n *Notificator) enqueue(notification *types.Notification) {
+	n.pendingMutex.Lock()
+	defer n.pendingMutex.Unlock()
+	n.pending = append(n.pending, notification)
+	select {
+	case n.hasPending <- struct{}{}:
+	default:
+	}
+}
+
+func (n *Notificator) processNotifications(ctx context.Context) {
+	defer func() {
+		log.C(ctx).Info("context cancelled, stopping Notificator...")
+		n.storage.removeNotificationListener(n.listenerID)
+		n.consumersMutex.Lock()
+		defer n.consumersMutex.Unlock()
+		n.isRunning = false
+		for _, queues := range n.consumers {
+			for _, queue := range queues {
+				queue.Close()
+			}
+		}
+		n.consumers = make(map[string][]storage.NotificationQueue)
+		n.platforms = make(map[string]*types.Platform)
+	}()
+	for {
+		select {
+		case <-n.hasPending:
+			n.pendingMutex.Lock()
+			notifications := n.pending
+			n.pending = nil
+			n.pendingMutex.Unlock()
+			for _, notification := range notifications {
+				n.processNotification(notification)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (n *Notificator) processNotification(notification *types.Notification) {
+	n.consumersMutex.Lock()
+	defer n.consumersMutex.Unlock()
+	if notification.Revision <= n.lastKnownRevision {
+		return
+	}
+	n.lastKnownRevision = notification.Revision
+
+	recipients := n.getRecipients(notification.PlatformID)
+	if len(recipients) == 0 {
+		log.C(n.ctx).Debugf("No recipients to receive notification %s", notification.ID)
+		return
+	}
+	recipients = n.filterRecipients(recipients, notification)
+	log.C(n.ctx).Debugf("%d platforms should receive notification %s", len(recipients), notification.ID)
+	for _, platform := range recipients {
+		for _, consumer := range n.consumers[platform.ID] {
+			if err := consumer.Enqueue(notification); err != nil {
+				log.C(n.ctx).WithError(err).Infof("Consumer %s notification queue returned error %v", consumer.ID(), err)
+				consumer.Close()
+			}
+		}
+	}
+}
+
+func (n *Notificator) getRecipients(platformID string) []*types.Platform {
+	if platformID == "" {
+		recipients := make([]*types.Platform, 0, len(n.platforms))
+		for _, platform := range n.platforms {
+			recipients = append(recipients, platform)
+		}
+		return recipients
+	}
+	platform, found := n.platforms[platformID]
+	if !found {
+		return nil
+	}
+	return []*types.Platform{platform}
+}
+
+func (n *Notificator) filterRecipients(recipients []*types.Platform, notification *types.Notification) []*types.Platform {
+	for _, filter := range n.notificationFilters {
+		recipients = filter(recipients, notification)
+		if len(recipients) == 0 {
+			return recipients
+		}
+	}
+	return recipients
+}
+
+func (n *Notificator) lastRevision() (int64, error) {
+	objectList, err := n.storage.List(n.ctx, types.NotificationType, query.OrderResultBy("revision", query.DescOrder))
+	if err != nil {
+		return types.InvalidRevision, fmt.Errorf("could not get last notification revision from storage %v", err)
+	}
+	if objectList.Len() == 0 {
+		return types.InvalidRevision, nil
+	}
+	return objectList.(*types.Notifications).Notifications[0].Revision, nil
+}
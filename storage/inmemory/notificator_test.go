@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory_test
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage/inmemory"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Notificator", func() {
+	var (
+		ctx         context.Context
+		cancel      context.CancelFunc
+		wg          *sync.WaitGroup
+		s           *inmemory.Storage
+		notificator *inmemory.Notificator
+		platform    *types.Platform
+	)
+
+	createNotification := func(id, platformID string) *types.Notification {
+		notification, err := s.Create(ctx, &types.Notification{
+			Base:       types.Base{ID: id},
+			PlatformID: platformID,
+			Resource:   types.ServiceBrokerType,
+			Type:       types.CREATED,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return notification.(*types.Notification)
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		s = &inmemory.Storage{}
+		Expect(s.Open(newSettings())).To(Succeed())
+
+		var err error
+		notificator, err = inmemory.NewNotificator(s, newSettings())
+		Expect(err).ToNot(HaveOccurred())
+
+		platform = newPlatform("p1", nil)
+		_, err = s.Create(ctx, platform)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	Context("when the notificator is not started", func() {
+		It("does not register consumers", func() {
+			_, _, err := notificator.RegisterConsumer(platform, types.InvalidRevision)
+			Expect(err).To(MatchError("cannot register consumer - Notificator is not running"))
+		})
+	})
+
+	Context("when the notificator is started", func() {
+		BeforeEach(func() {
+			Expect(notificator.Start(ctx, wg)).To(Succeed())
+		})
+
+		It("cannot be started again", func() {
+			Expect(notificator.Start(ctx, wg)).To(MatchError("notificator already started"))
+		})
+
+		It("sends committed notifications to the consumers of the platform", func() {
+			queue, revision, err := notificator.RegisterConsumer(platform, types.InvalidRevision)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revision).To(Equal(types.InvalidRevision))
+
+			_, err = s.Create(ctx, newPlatform("p2", nil))
+			Expect(err).ToNot(HaveOccurred())
+			createNotification("n1", "p2")
+			notification := createNotification("n2", platform.ID)
+			createNotification("n3", "")
+
+			Eventually(queue.Channel()).Should(Receive(WithTransform(func(n *types.Notification) string { return n.ID }, Equal(notification.ID))))
+			Eventually(queue.Channel()).Should(Receive(WithTransform(func(n *types.Notification) string { return n.ID }, Equal("n3"))))
+		})
+
+		It("applies the registered filters", func() {
+			notificator.RegisterFilter(func(recipients []*types.Platform, notification *types.Notification) []*types.Platform {
+				if notification.ID == "filtered" {
+					return nil
+				}
+				return recipients
+			})
+			queue, _, err := notificator.RegisterConsumer(platform, types.InvalidRevision)
+			Expect(err).ToNot(HaveOccurred())
+
+			createNotification("filtered", platform.ID)
+			createNotification("n1", platform.ID)
+
+			Eventually(queue.Channel()).Should(Receive(WithTransform(func(n *types.Notification) string { return n.ID }, Equal("n1"))))
+		})
+
+		Context("when the consumer knows an older revision", func() {
+			var first *types.Notification
+
+			BeforeEach(func() {
+				first = createNotification("n1", platform.ID)
+				createNotification("n2", platform.ID)
+				Eventually(func() int64 {
+					queue, revision, err := notificator.RegisterConsumer(platform, types.InvalidRevision)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(notificator.UnregisterConsumer(queue)).To(Succeed())
+					return revision
+				}).Should(Equal(first.Revision + 1))
+			})
+
+			It("sends the missed notifications", func() {
+				queue, _, err := notificator.RegisterConsumer(platform, first.Revision)
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(queue.Channel()).Should(Receive(WithTransform(func(n *types.Notification) string { return n.ID }, Equal("n2"))))
+			})
+
+			It("fails if the revision is not known", func() {
+				_, _, err := notificator.RegisterConsumer(platform, first.Revision+10)
+				Expect(err).To(Equal(util.ErrInvalidNotificationRevision))
+			})
+		})
+
+		Context("when the context is cancelled", func() {
+			It("closes the queues", func() {
+				queue, _, err := notificator.RegisterConsumer(platform, types.InvalidRevision)
+				Expect(err).ToNot(HaveOccurred())
+				cancel()
+				wg.Wait()
+				Eventually(queue.Channel()).Should(BeClosed())
+			})
+		})
+	})
+
+	Context("when the storage is not in-memory", func() {
+		It("returns an error", func() {
+			_, err := inmemory.NewNotificator(nil, newSettings())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,309 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+)
+
+const pagingSequenceColumn = "paging_sequence"
+
+var (
+	intType            = reflect.TypeOf(int(1))
+	int64Type          = reflect.TypeOf(int64(1))
+	timeType           = reflect.TypeOf(time.Time{})
+	byteSliceType      = reflect.TypeOf([]byte{})
+	stringType         = reflect.TypeOf("")
+	boolType           = reflect.TypeOf(false)
+	nullableStringType = reflect.TypeOf(sql.NullString{})
+	jsonType           = reflect.TypeOf(sqlxtypes.JSONText{})
+)
+
+// newObjectList returns an empty list for objects of the provided type. The object types known to the Service Manager
+// have dedicated lists, so that the listed objects can be type asserted the same way as the ones returned by the
+// postgres storage.
+func newObjectList(objectType types.ObjectType) types.ObjectList {
+	switch objectType {
+	case types.ServiceBrokerType:
+		return &types.ServiceBrokers{ServiceBrokers: make([]*types.ServiceBroker, 0)}
+	case types.PlatformType:
+		return &types.Platforms{Platforms: make([]*types.Platform, 0)}
+	case types.ServiceOfferingType:
+		return &types.ServiceOfferings{ServiceOfferings: make([]*types.ServiceOffering, 0)}
+	case types.ServicePlanType:
+		return &types.ServicePlans{ServicePlans: make([]*types.ServicePlan, 0)}
+	case types.VisibilityType:
+		return &types.Visibilities{Visibilities: make([]*types.Visibility, 0)}
+	case types.NotificationType:
+		return &types.Notifications{Notifications: make([]*types.Notification, 0)}
+	case types.OperationType:
+		return &types.Operations{Operations: make([]*types.Operation, 0)}
+	case types.ServiceInstanceType:
+		return &types.ServiceInstances{ServiceInstances: make([]*types.ServiceInstance, 0)}
+	case types.ServiceBindingType:
+		return &types.ServiceBindings{ServiceBindings: make([]*types.ServiceBinding, 0)}
+	case types.BrokerPlatformCredentialType:
+		return &types.BrokerPlatformCredentials{BrokerPlatformCredentials: make([]*types.BrokerPlatformCredential, 0)}
+	case types.AuditEventType:
+		return &types.AuditEvents{AuditEvents: make([]*types.AuditEvent, 0)}
+	case types.TenantQuotaType:
+		return &types.TenantQuotas{TenantQuotas: make([]*types.TenantQuota, 0)}
+	default:
+		return types.NewObjectArray()
+	}
+}
+
+// column describes a single field of an entity as it would be stored in a database table
+type column struct {
+	name          string
+	index         []int
+	fieldType     reflect.Type
+	autoIncrement bool
+}
+
+// isNumeric returns true if the column values are compared as numbers
+func (c *column) isNumeric() bool {
+	return c.fieldType == intType || c.fieldType == int64Type
+}
+
+// isTime returns true if the column values are compared as timestamps
+func (c *column) isTime() bool {
+	return c.fieldType == timeType
+}
+
+// isJSON returns true if the column supports querying by json path
+func (c *column) isJSON() bool {
+	return c.fieldType == jsonType
+}
+
+// isText returns true if the column supports the contains operator
+func (c *column) isText() bool {
+	return c.fieldType == stringType || c.fieldType == nullableStringType || c.fieldType == jsonType
+}
+
+// isOrderable returns true if the result can be ordered by the column, i.e. it has no NULL values
+func (c *column) isOrderable() bool {
+	switch c.fieldType {
+	case stringType, intType, int64Type, timeType, boolType:
+		return true
+	}
+	return false
+}
+
+// value returns the value of the column in the provided entity. NULL values are returned as nil, numeric columns
+// as int64, timestamp columns as time.Time and all other columns as their text representation.
+func (c *column) value(entity storage.Entity) interface{} {
+	field := reflect.ValueOf(entity).Elem().FieldByIndex(c.index)
+	switch v := field.Interface().(type) {
+	case string:
+		return v
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case bool:
+		return fmt.Sprint(v)
+	case time.Time:
+		return v
+	case []byte:
+		return string(v)
+	case sqlxtypes.JSONText:
+		if len(v) == 0 {
+			return nil
+		}
+		return string(v)
+	case sqlxtypes.NullJSONText:
+		if !v.Valid {
+			return nil
+		}
+		return string(v.JSONText)
+	case sql.NullString:
+		if !v.Valid {
+			return nil
+		}
+		return v.String
+	case sql.NullBool:
+		if !v.Valid {
+			return nil
+		}
+		return fmt.Sprint(v.Bool)
+	case sql.NullInt64:
+		if !v.Valid {
+			return nil
+		}
+		return v.Int64
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// table holds the metadata of an introduced entity
+type table struct {
+	name       string
+	objectType types.ObjectType
+	entity     storage.Entity
+	columns    map[string]*column
+}
+
+func (t *table) newList() types.ObjectList {
+	return newObjectList(t.objectType)
+}
+
+type scheme struct {
+	tables map[types.ObjectType]*table
+}
+
+func newScheme() *scheme {
+	return &scheme{
+		tables: make(map[types.ObjectType]*table),
+	}
+}
+
+func (s *scheme) introduce(entity storage.Entity) {
+	t := reflect.TypeOf(entity)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic("All entities must be pointers to structs.")
+	}
+	obj, err := entity.ToObject()
+	if err != nil {
+		panic(fmt.Errorf("could introduce entity: %s", err))
+	}
+	objType := obj.GetType()
+	if _, exists := s.tables[objType]; exists {
+		panic(fmt.Sprintf("Entity for object with type %s has already been introduced", objType))
+	}
+
+	tableName := objType.String()
+	if namedEntity, ok := entity.(interface{ TableName() string }); ok {
+		tableName = namedEntity.TableName()
+	}
+	columns := make(map[string]*column)
+	collectColumns(t.Elem(), nil, columns)
+
+	s.tables[objType] = &table{
+		name:       tableName,
+		objectType: objType,
+		entity:     entity,
+		columns:    columns,
+	}
+}
+
+func (s *scheme) table(objectType types.ObjectType) (*table, error) {
+	t, exists := s.tables[objectType]
+	if !exists {
+		return nil, fmt.Errorf("no entity is introduced for object of type %s", objectType)
+	}
+	return t, nil
+}
+
+func (s *scheme) tableByName(name string) *table {
+	for _, t := range s.tables {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// convert returns a detached entity for the provided object
+func (s *scheme) convert(object types.Object) (storage.Entity, *table, error) {
+	t, err := s.table(object.GetType())
+	if err != nil {
+		return nil, nil, err
+	}
+	entity, err := t.entity.FromObject(object)
+	if err != nil {
+		return nil, nil, fmt.Errorf("registered entity cannot convert object from type %s: %s", object.GetType(), err)
+	}
+	return copyEntity(entity), t, nil
+}
+
+func collectColumns(structType reflect.Type, parentIndex []int, columns map[string]*column) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		index := append(append([]int{}, parentIndex...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectColumns(field.Type, index, columns)
+			continue
+		}
+		dbTag := field.Tag.Get("db")
+		if dbTag == "-" || field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(dbTag, ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		columns[name] = &column{
+			name:          name,
+			index:         index,
+			fieldType:     field.Type,
+			autoIncrement: strings.Contains(dbTag, "auto_increment"),
+		}
+	}
+}
+
+// copyEntity returns a copy of the entity which does not share any byte slices with the original. Timestamps are
+// rounded to microseconds as this is the precision with which they are persisted by the postgres storage and the
+// fields which are not persisted at all are cleared.
+func copyEntity(entity storage.Entity) storage.Entity {
+	original := reflect.ValueOf(entity).Elem()
+	copied := reflect.New(original.Type())
+	copied.Elem().Set(original)
+	detachFields(copied.Elem())
+	return copied.Interface().(storage.Entity)
+}
+
+func detachFields(value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch {
+		case value.Type().Field(i).Tag.Get("db") == "-":
+			field.Set(reflect.Zero(field.Type()))
+		case field.Type() == timeType:
+			field.Set(reflect.ValueOf(field.Interface().(time.Time).Round(time.Microsecond)))
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 && !field.IsNil():
+			detached := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(detached, field)
+			field.Set(detached)
+		case field.Kind() == reflect.Struct:
+			detachFields(field)
+		}
+	}
+}
+
+func (s *scheme) entities() []storage.EntityMetadata {
+	entities := make([]storage.EntityMetadata, 0, len(s.tables))
+	for _, t := range s.tables {
+		entities = append(entities, storage.EntityMetadata{
+			Name:      t.objectType.String(),
+			TableName: t.name,
+		})
+	}
+	return entities
+}
@@ -0,0 +1,662 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package inmemory contains an implementation of storage.Storage which keeps all data in memory. It behaves like the
+// postgres storage and allows running a complete Service Manager without a database, e.g. in tests or when embedding.
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+// Storage is an in-memory storage.Storage. Transactions are serialized and work on a snapshot of the data which
+// replaces the committed data once the transaction succeeds. Reads outside of transactions see the committed data.
+type Storage struct {
+	scheme *scheme
+	*data
+
+	layerOneEncryptionKey         []byte
+	layerOnePreviousEncryptionKey []byte
+}
+
+// data is the state of an in-memory database. Like connections to the same postgres database, storages which are
+// opened with the same URI share it.
+type data struct {
+	// mutex guards the committed database and the encryption keys
+	mutex                 sync.RWMutex
+	db                    *database
+	encryptionKey         []byte
+	previousEncryptionKey []byte
+	// the rewrapped encryption keys are encrypted with the new storage encryption key while the storage encryption key
+	// is replaced, so that the instances using the previous one can still decrypt the encryption keys
+	rewrappedEncryptionKey         []byte
+	rewrappedPreviousEncryptionKey []byte
+	keyRotation                    *storage.KeyRotation
+
+	// txMutex serializes the transactions
+	txMutex sync.Mutex
+
+	// sequences hold the last values of the auto increment columns. As in postgres they are not transactional.
+	sequenceMutex sync.Mutex
+	sequences     map[string]int64
+
+	locksMutex sync.Mutex
+	locks      map[int]chan struct{}
+
+	listenersMutex        sync.Mutex
+	lastListenerID        int
+	notificationListeners map[int]func(*types.Notification)
+}
+
+var (
+	sharedDataMutex sync.Mutex
+	sharedData      = make(map[string]*data)
+)
+
+func newData() *data {
+	return &data{
+		db:                    newDatabase(),
+		sequences:             make(map[string]int64),
+		locks:                 make(map[int]chan struct{}),
+		notificationListeners: make(map[int]func(*types.Notification)),
+	}
+}
+
+// openData returns the data shared by the storages opened with the provided URI. Storages opened without URI get
+// data of their own.
+func openData(uri string) *data {
+	if uri == "" {
+		return newData()
+	}
+	sharedDataMutex.Lock()
+	defer sharedDataMutex.Unlock()
+	if _, found := sharedData[uri]; !found {
+		sharedData[uri] = newData()
+	}
+	return sharedData[uri]
+}
+
+func (s *Storage) Introduce(entity storage.Entity) {
+	s.scheme.introduce(entity)
+}
+
+// Open opens the storage. It must not be called concurrently.
+func (s *Storage) Open(settings *storage.Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	if s.data == nil {
+		s.scheme = newScheme()
+		s.scheme.introduce(&postgres.Broker{})
+		s.scheme.introduce(&postgres.Platform{})
+		s.scheme.introduce(&postgres.ServiceOffering{})
+		s.scheme.introduce(&postgres.ServicePlan{})
+		s.scheme.introduce(&postgres.Visibility{})
+		s.scheme.introduce(&postgres.Notification{})
+		s.scheme.introduce(&postgres.Operation{})
+		s.scheme.introduce(&postgres.ServiceInstance{})
+		s.scheme.introduce(&postgres.ServiceBinding{})
+		s.scheme.introduce(&postgres.BrokerPlatformCredential{})
+		s.scheme.introduce(&postgres.AuditEvent{})
+		s.scheme.introduce(&postgres.TenantQuota{})
+
+		s.layerOneEncryptionKey = []byte(settings.EncryptionKey)
+		s.layerOnePreviousEncryptionKey = []byte(settings.PreviousEncryptionKey)
+		s.data = openData(settings.URI)
+	}
+
+	return nil
+}
+
+// Close releases nothing as the data lives as long as the storages using it. The data is kept so that the storage
+// can still be used by components which are shutting down.
+func (s *Storage) Close() error {
+	return nil
+}
+
+func (s *Storage) checkOpen() {
+	if s.data == nil {
+		log.D().Panicln("Storage is not yet open")
+	}
+}
+
+func (s *Storage) PingContext(_ context.Context) error {
+	s.checkOpen()
+	return nil
+}
+
+// committed returns a read-only repository working on the committed data
+func (s *Storage) committed() *repository {
+	s.checkOpen()
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return &repository{storage: s, db: s.db}
+}
+
+func (s *Storage) Create(ctx context.Context, obj types.Object) (types.Object, error) {
+	var result types.Object
+	err := s.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+		var err error
+		result, err = repository.Create(ctx, obj)
+		return err
+	})
+	return result, err
+}
+
+func (s *Storage) Get(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+	return s.committed().Get(ctx, objectType, criteria...)
+}
+
+func (s *Storage) GetForUpdate(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+	return s.committed().GetForUpdate(ctx, objectType, criteria...)
+}
+
+func (s *Storage) List(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	return s.committed().List(ctx, objectType, criteria...)
+}
+
+func (s *Storage) ListNoLabels(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	return s.committed().ListNoLabels(ctx, objectType, criteria...)
+}
+
+func (s *Storage) Count(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+	return s.committed().Count(ctx, objectType, criteria...)
+}
+
+func (s *Storage) CountLabelValues(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+	return s.committed().CountLabelValues(ctx, objectType, criteria...)
+}
+
+func (s *Storage) CountGroups(ctx context.Context, objectType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error) {
+	return s.committed().CountGroups(ctx, objectType, fields, labelKeys, criteria...)
+}
+
+func (s *Storage) QueryForList(ctx context.Context, objectType types.ObjectType, queryName storage.NamedQuery, queryParams map[string]interface{}) (types.ObjectList, error) {
+	return s.committed().QueryForList(ctx, objectType, queryName, queryParams)
+}
+
+func (s *Storage) DeleteReturning(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	var result types.ObjectList
+	err := s.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+		var err error
+		result, err = repository.DeleteReturning(ctx, objectType, criteria...)
+		return err
+	})
+	return result, err
+}
+
+func (s *Storage) Delete(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) error {
+	return s.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+		return repository.Delete(ctx, objectType, criteria...)
+	})
+}
+
+func (s *Storage) Update(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, criteria ...query.Criterion) (types.Object, error) {
+	var result types.Object
+	err := s.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+		var err error
+		result, err = repository.Update(ctx, obj, labelChanges, criteria...)
+		return err
+	})
+	return result, err
+}
+
+func (s *Storage) UpdateLabels(ctx context.Context, objectType types.ObjectType, objectID string, labelChanges types.LabelChanges, criteria ...query.Criterion) error {
+	return s.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+		return repository.UpdateLabels(ctx, objectType, objectID, labelChanges, criteria...)
+	})
+}
+
+func (s *Storage) GetEntities() []storage.EntityMetadata {
+	return s.scheme.entities()
+}
+
+// InTransaction executes f on a snapshot of the committed data which replaces the committed data if f succeeds.
+// Transactions are executed one at a time, so f must not write through the storage itself as this would block.
+func (s *Storage) InTransaction(ctx context.Context, f func(ctx context.Context, storage storage.Repository) error) error {
+	s.checkOpen()
+	s.txMutex.Lock()
+	defer s.txMutex.Unlock()
+
+	s.mutex.RLock()
+	transactionalRepository := &repository{storage: s, db: s.db.snapshot(), inTransaction: true}
+	s.mutex.RUnlock()
+
+	if err := f(ctx, transactionalRepository); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.db = transactionalRepository.db
+	s.mutex.Unlock()
+
+	// notifications are published while still holding the transaction lock so that they are received in the order
+	// of their revisions
+	s.publish(transactionalRepository.notifications)
+	return nil
+}
+
+// nextValue returns the next value of the sequence of the provided auto increment column
+func (s *Storage) nextValue(t *table, c *column) int64 {
+	s.sequenceMutex.Lock()
+	defer s.sequenceMutex.Unlock()
+	key := t.name + "." + c.name
+	s.sequences[key]++
+	return s.sequences[key]
+}
+
+// addNotificationListener registers a listener for the committed notifications and returns its id
+func (s *Storage) addNotificationListener(listener func(*types.Notification)) int {
+	s.listenersMutex.Lock()
+	defer s.listenersMutex.Unlock()
+	s.lastListenerID++
+	s.notificationListeners[s.lastListenerID] = listener
+	return s.lastListenerID
+}
+
+func (s *Storage) removeNotificationListener(id int) {
+	s.listenersMutex.Lock()
+	defer s.listenersMutex.Unlock()
+	delete(s.notificationListeners, id)
+}
+
+func (s *Storage) publish(notifications []*types.Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+	s.listenersMutex.Lock()
+	defer s.listenersMutex.Unlock()
+	for _, notification := range notifications {
+		for _, listener := range s.notificationListeners {
+			listener(notification)
+		}
+	}
+}
+
+// repository implements storage.Repository on top of a database snapshot
+type repository struct {
+	storage       *Storage
+	db            *database
+	inTransaction bool
+	// notifications contains the notifications created in the transaction
+	notifications []*types.Notification
+}
+
+func (r *repository) checkWritable() {
+	if !r.inTransaction {
+		log.D().Panicln("In-memory storage can only be modified in a transaction")
+	}
+}
+
+func (r *repository) Create(ctx context.Context, obj types.Object) (types.Object, error) {
+	r.checkWritable()
+	entity, t, err := r.storage.scheme.convert(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateLabels(obj.GetLabels()); err != nil {
+		return nil, err
+	}
+	if _, exists := r.db.rows(t.objectType)[entity.GetID()]; exists {
+		return nil, util.ErrAlreadyExistsInStorage
+	}
+
+	newRow := &row{entity: entity, labels: copyLabels(obj.GetLabels())}
+	if err := r.storage.validateRow(r.db, t, newRow); err != nil {
+		return nil, err
+	}
+	for _, c := range t.columns {
+		if c.autoIncrement {
+			reflect.ValueOf(entity).Elem().FieldByIndex(c.index).SetInt(r.storage.nextValue(t, c))
+		}
+	}
+	r.db.put(t.objectType, newRow)
+	log.C(ctx).Debugf("Inserted entity with id %s in table %s", entity.GetID(), t.name)
+
+	createdObj, err := copyEntity(entity).ToObject()
+	if err != nil {
+		return nil, fmt.Errorf("could not convert created entity to object: %s", err)
+	}
+	if notification, ok := createdObj.(*types.Notification); ok {
+		r.notifications = append(r.notifications, notification)
+	}
+	createdObj.SetLabels(obj.GetLabels())
+	return createdObj, nil
+}
+
+func (r *repository) Get(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+	result, err := r.List(ctx, objectType, criteria...)
+	if err != nil {
+		return nil, err
+	}
+	if result.Len() == 0 {
+		return nil, util.ErrNotFoundInStorage
+	}
+	return result.ItemAt(0), nil
+}
+
+// GetForUpdate is the same as Get as the transactions are serialized and do not need row locks
+func (r *repository) GetForUpdate(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+	return r.Get(ctx, objectType, criteria...)
+}
+
+func (r *repository) List(_ context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	return r.list(objectType, true, criteria...)
+}
+
+func (r *repository) ListNoLabels(_ context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	return r.list(objectType, false, criteria...)
+}
+
+func (r *repository) list(objectType types.ObjectType, withLabels bool, criteria ...query.Criterion) (types.ObjectList, error) {
+	t, err := r.storage.scheme.table(objectType)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSelection(r, t, criteria...)
+	if err != nil {
+		return nil, err
+	}
+	return toList(t, s.apply(), withLabels)
+}
+
+func (r *repository) Count(_ context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+	t, err := r.storage.scheme.table(objectType)
+	if err != nil {
+		return 0, err
+	}
+	s, err := newSelection(r, t, withoutLimit(criteria)...)
+	if err != nil {
+		return 0, err
+	}
+	return len(s.apply()), nil
+}
+
+func (r *repository) CountLabelValues(_ context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+	t, err := r.storage.scheme.table(objectType)
+	if err != nil {
+		return 0, err
+	}
+	s, err := newSelection(r, t, withoutLimit(criteria)...)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, matchingRow := range s.apply() {
+		count += s.countLabels(matchingRow)
+	}
+	return count, nil
+}
+
+func (r *repository) CountGroups(_ context.Context, objectType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error) {
+	t, err := r.storage.scheme.table(objectType)
+	if err != nil {
+		return nil, err
+	}
+	if len(withoutLimit(criteria)) != len(criteria) {
+		return nil, &util.UnsupportedQueryError{Message: "limit is not supported when counting groups"}
+	}
+	columns := make([]*column, 0, len(fields))
+	for _, field := range fields {
+		c, found := t.columns[field]
+		if !found {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported entity field for group by: %s", field)}
+		}
+		if c.isJSON() || c.fieldType == byteSliceType {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field type for group by: %s", field)}
+		}
+		columns = append(columns, c)
+	}
+	s, err := newSelection(r, t, criteria...)
+	if err != nil {
+		return nil, err
+	}
+	return countGroups(s.apply(), columns, labelKeys), nil
+}
+
+func (r *repository) QueryForList(_ context.Context, objectType types.ObjectType, queryName storage.NamedQuery, queryParams map[string]interface{}) (types.ObjectList, error) {
+	t, err := r.storage.scheme.table(objectType)
+	if err != nil {
+		return nil, err
+	}
+	namedQuery, found := namedQueries[queryName]
+	if !found {
+		return nil, fmt.Errorf("named query %d is not supported by the in-memory storage", queryName)
+	}
+	result, withLabels, err := namedQuery(r, t, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return toList(t, result, withLabels)
+}
+
+func (r *repository) DeleteReturning(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	r.checkWritable()
+	t, err := r.storage.scheme.table(objectType)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := r.delete(ctx, t, criteria...)
+	if err != nil {
+		return nil, err
+	}
+	return toList(t, deleted, false)
+}
+
+func (r *repository) Delete(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) error {
+	r.checkWritable()
+	t, err := r.storage.scheme.table(objectType)
+	if err != nil {
+		return err
+	}
+	_, err = r.delete(ctx, t, criteria...)
+	return err
+}
+
+func (r *repository) delete(ctx context.Context, t *table, criteria ...query.Criterion) ([]*row, error) {
+	s, err := newSelection(r, t, criteria...)
+	if err != nil {
+		return nil, err
+	}
+	matchingRows := s.apply()
+	if len(matchingRows) == 0 {
+		return nil, util.ErrNotFoundInStorage
+	}
+
+	deletions := make(map[*table]map[string]bool)
+	for _, matchingRow := range matchingRows {
+		if err := r.storage.deletionsFor(r.db, t, t, matchingRow, deletions); err != nil {
+			return nil, err
+		}
+	}
+	for deletedTable, ids := range deletions {
+		for id := range ids {
+			r.db.remove(deletedTable.objectType, id)
+		}
+		log.C(ctx).Debugf("Deleted %d rows from table %s", len(ids), deletedTable.name)
+	}
+	return matchingRows, nil
+}
+
+func (r *repository) Update(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, _ ...query.Criterion) (types.Object, error) {
+	r.checkWritable()
+	obj.SetUpdatedAt(time.Now().UTC())
+
+	entity, t, err := r.storage.scheme.convert(obj)
+	if err != nil {
+		return nil, err
+	}
+	existing, found := r.db.rows(t.objectType)[entity.GetID()]
+	if !found {
+		return nil, util.ErrNotFoundInStorage
+	}
+	for _, c := range t.columns {
+		if c.autoIncrement {
+			field := reflect.ValueOf(entity).Elem().FieldByIndex(c.index)
+			field.Set(reflect.ValueOf(existing.entity).Elem().FieldByIndex(c.index))
+		}
+	}
+	labels, err := applyLabelChanges(existing.labels, labelChanges)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedRow := &row{entity: entity, labels: labels}
+	if err := r.storage.validateRow(r.db, t, updatedRow); err != nil {
+		return nil, err
+	}
+	r.db.put(t.objectType, updatedRow)
+	log.C(ctx).Debugf("Updated entity with id %s in table %s", entity.GetID(), t.name)
+
+	result, err := copyEntity(entity).ToObject()
+	if err != nil {
+		return nil, fmt.Errorf("could not convert updated entity to object: %s", err)
+	}
+	return result, nil
+}
+
+func (r *repository) UpdateLabels(ctx context.Context, objectType types.ObjectType, objectID string, labelChanges types.LabelChanges, _ ...query.Criterion) error {
+	r.checkWritable()
+	t, err := r.storage.scheme.table(objectType)
+	if err != nil {
+		return err
+	}
+	existing, found := r.db.rows(t.objectType)[objectID]
+	if !found {
+		// as in postgres labels of missing entities cannot be added and removing them is a no-op
+		_, addedLabels, _ := query.ApplyLabelChangesToLabels(labelChanges, types.Labels{})
+		if len(addedLabels) != 0 {
+			return &util.ErrBadRequestStorage{Cause: fmt.Errorf("entity with id %s does not exist in table %s", objectID, t.name)}
+		}
+		return nil
+	}
+	labels, err := applyLabelChanges(existing.labels, labelChanges)
+	if err != nil {
+		return err
+	}
+
+	updatedRow := &row{entity: existing.entity, labels: labels}
+	if err := r.storage.validateRow(r.db, t, updatedRow); err != nil {
+		return err
+	}
+	r.db.put(t.objectType, updatedRow)
+	log.C(ctx).Debugf("Updated labels of entity with id %s in table %s", objectID, t.name)
+	return nil
+}
+
+func (r *repository) GetEntities() []storage.EntityMetadata {
+	return r.storage.scheme.entities()
+}
+
+// applyLabelChanges returns the labels resulting from applying the changes on the provided labels
+func applyLabelChanges(labels types.Labels, labelChanges types.LabelChanges) (types.Labels, error) {
+	_, addedLabels, removedLabels := query.ApplyLabelChangesToLabels(labelChanges, types.Labels{})
+	if err := validateLabels(addedLabels); err != nil {
+		return nil, err
+	}
+
+	result := copyLabels(labels)
+	if result == nil {
+		result = types.Labels{}
+	}
+	for key, values := range addedLabels {
+		for _, value := range values {
+			if !containsString(result[key], value) {
+				result[key] = append(result[key], value)
+			}
+		}
+	}
+	for key, values := range removedLabels {
+		if len(values) == 0 {
+			delete(result, key)
+			continue
+		}
+		remaining := make([]string, 0, len(result[key]))
+		for _, value := range result[key] {
+			if !containsString(values, value) {
+				remaining = append(remaining, value)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(result, key)
+		} else {
+			result[key] = remaining
+		}
+	}
+	return copyLabels(result), nil
+}
+
+func validateLabels(labels types.Labels) error {
+	for key, values := range labels {
+		seen := make(map[string]bool, len(values))
+		for _, value := range values {
+			if seen[value] {
+				return fmt.Errorf("duplicate label with key %s and value %s", key, value)
+			}
+			seen[value] = true
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutLimit removes the limit criteria as counting ignores them
+func withoutLimit(criteria []query.Criterion) []query.Criterion {
+	result := make([]query.Criterion, 0, len(criteria))
+	for _, criterion := range criteria {
+		if criterion.Type == query.ResultQuery && criterion.LeftOp == query.Limit {
+			continue
+		}
+		result = append(result, criterion)
+	}
+	return result
+}
+
+// toList converts the rows to a list of detached objects
+func toList(t *table, resultRows []*row, withLabels bool) (types.ObjectList, error) {
+	result := t.newList()
+	for _, resultRow := range resultRows {
+		obj, err := copyEntity(resultRow.entity).ToObject()
+		if err != nil {
+			return nil, err
+		}
+		if withLabels {
+			obj.SetLabels(copyLabels(resultRow.labels))
+		}
+		result.Add(obj)
+	}
+	return result, nil
+}
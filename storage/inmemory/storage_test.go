@@ -0,0 +1,526 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/inmemory"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func newSettings() *storage.Settings {
+	settings := storage.DefaultSettings()
+	settings.Type = storage.InMemoryStorage
+	settings.EncryptionKey = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+	return settings
+}
+
+func newPlatform(id string, labels types.Labels) *types.Platform {
+	return &types.Platform{
+		Base: types.Base{
+			ID:        id,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Labels:    labels,
+			Ready:     true,
+		},
+		Type: "kubernetes",
+		Name: id + "-name",
+		Credentials: &types.Credentials{
+			Basic: &types.Basic{
+				Username: id + "-user",
+				Password: "password",
+			},
+		},
+	}
+}
+
+func platformIDs(list types.ObjectList) []string {
+	ids := make([]string, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		ids = append(ids, list.ItemAt(i).GetID())
+	}
+	return ids
+}
+
+var _ = Describe("In-memory Storage", func() {
+	var (
+		ctx context.Context
+		s   *inmemory.Storage
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		s = &inmemory.Storage{}
+		Expect(s.Open(newSettings())).To(Succeed())
+	})
+
+	Describe("Open", func() {
+		Context("when the settings are invalid", func() {
+			It("returns an error", func() {
+				settings := newSettings()
+				settings.EncryptionKey = ""
+				Expect((&inmemory.Storage{}).Open(settings)).To(HaveOccurred())
+			})
+		})
+
+		Context("when no uri is provided", func() {
+			It("succeeds", func() {
+				settings := newSettings()
+				settings.URI = ""
+				settings.MigrationsURL = ""
+				Expect((&inmemory.Storage{}).Open(settings)).To(Succeed())
+			})
+
+			It("does not share the data with other storages", func() {
+				_, err := s.Create(ctx, newPlatform("p1", nil))
+				Expect(err).ToNot(HaveOccurred())
+
+				other := &inmemory.Storage{}
+				Expect(other.Open(newSettings())).To(Succeed())
+				count, err := other.Count(ctx, types.PlatformType)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(0))
+			})
+		})
+
+		Context("when storages are opened with the same uri", func() {
+			It("shares the data between them", func() {
+				settings := newSettings()
+				settings.URI = "inmemory://shared-data-test"
+				first := &inmemory.Storage{}
+				Expect(first.Open(settings)).To(Succeed())
+				second := &inmemory.Storage{}
+				Expect(second.Open(settings)).To(Succeed())
+
+				_, err := first.Create(ctx, newPlatform("p1", nil))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = second.Get(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", "p1"))
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Create", func() {
+		It("assigns paging sequences and returns the labels", func() {
+			first, err := s.Create(ctx, newPlatform("p1", types.Labels{"key": {"value"}}))
+			Expect(err).ToNot(HaveOccurred())
+			second, err := s.Create(ctx, newPlatform("p2", nil))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(first.GetLabels()).To(Equal(types.Labels{"key": {"value"}}))
+			Expect(second.GetPagingSequence()).To(BeNumerically(">", first.GetPagingSequence()))
+		})
+
+		Context("when an object with the same id exists", func() {
+			It("returns already exists error", func() {
+				_, err := s.Create(ctx, newPlatform("p1", nil))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = s.Create(ctx, newPlatform("p1", nil))
+				Expect(err).To(Equal(util.ErrAlreadyExistsInStorage))
+			})
+		})
+
+		Context("when a unique column value is already used", func() {
+			It("returns already exists error", func() {
+				_, err := s.Create(ctx, newPlatform("p1", nil))
+				Expect(err).ToNot(HaveOccurred())
+				platform := newPlatform("p2", nil)
+				platform.Name = "p1-name"
+				_, err = s.Create(ctx, platform)
+				Expect(err).To(Equal(util.ErrAlreadyExistsInStorage))
+			})
+		})
+
+		Context("when the referenced object does not exist", func() {
+			It("returns bad request error", func() {
+				_, err := s.Create(ctx, &types.Visibility{
+					Base:          types.Base{ID: "v1"},
+					ServicePlanID: "missing",
+				})
+				Expect(err).To(BeAssignableToTypeOf(&util.ErrBadRequestStorage{}))
+			})
+		})
+
+		Context("when labels are duplicated", func() {
+			It("returns an error", func() {
+				_, err := s.Create(ctx, newPlatform("p1", types.Labels{"key": {"value", "value"}}))
+				Expect(err).To(MatchError("duplicate label with key key and value value"))
+			})
+		})
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			for _, platform := range []*types.Platform{
+				newPlatform("p1", types.Labels{"env": {"dev"}, "team": {"a"}}),
+				newPlatform("p2", types.Labels{"env": {"prod"}}),
+				newPlatform("p3", nil),
+			} {
+				_, err := s.Create(ctx, platform)
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("returns typed lists with labels in paging order", func() {
+			list, err := s.List(ctx, types.PlatformType)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(list).To(BeAssignableToTypeOf(&types.Platforms{}))
+			Expect(platformIDs(list)).To(Equal([]string{"p1", "p2", "p3"}))
+			Expect(list.ItemAt(0).GetLabels()).To(HaveLen(2))
+			Expect(list.ItemAt(2).GetLabels()).To(BeNil())
+		})
+
+		It("returns the objects without labels when requested", func() {
+			list, err := s.ListNoLabels(ctx, types.PlatformType)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(list.ItemAt(0).GetLabels()).To(BeNil())
+		})
+
+		It("filters by fields and labels", func() {
+			list, err := s.List(ctx, types.PlatformType,
+				query.ByField(query.InOperator, "id", "p1", "p2"),
+				query.ByLabel(query.NotEqualsOperator, "env", "prod"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(list)).To(Equal([]string{"p1"}))
+		})
+
+		It("accepts postgres timestamp literals", func() {
+			list, err := s.List(ctx, types.PlatformType,
+				query.ByField(query.EqualsOperator, "last_active", "0001-01-01 00:00:00+00"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(list.Len()).To(Equal(3))
+		})
+
+		It("supports or and not criteria", func() {
+			list, err := s.List(ctx, types.PlatformType,
+				query.ByAny(
+					query.ByField(query.EqualsOperator, "name", "p2-name"),
+					query.ByNot(query.ByField(query.NotEqualsOperator, "id", "p3"))))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(list)).To(Equal([]string{"p2", "p3"}))
+		})
+
+		It("applies the limit on the ordered objects", func() {
+			list, err := s.List(ctx, types.PlatformType,
+				query.LimitResultBy(2),
+				query.OrderResultBy("name", query.DescOrder))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(list)).To(Equal([]string{"p3", "p2"}))
+		})
+
+		It("returns the objects after the provided values in the requested order", func() {
+			list, err := s.List(ctx, types.PlatformType,
+				query.OrderResultBy("type", query.AscOrder),
+				query.OrderResultBy("name", query.DescOrder),
+				query.ResultAfter("kubernetes", "p2-name"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(list)).To(Equal([]string{"p1"}))
+		})
+
+		It("compares numeric columns as numbers", func() {
+			all, err := s.List(ctx, types.PlatformType)
+			Expect(err).ToNot(HaveOccurred())
+			sequence := strconv.FormatInt(all.ItemAt(0).GetPagingSequence(), 10)
+
+			list, err := s.List(ctx, types.PlatformType, query.ByField(query.GreaterThanOperator, "paging_sequence", sequence))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(list)).To(Equal([]string{"p2", "p3"}))
+		})
+
+		Context("when the field is unknown", func() {
+			It("returns unsupported query error", func() {
+				_, err := s.List(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "unknown", "value"))
+				Expect(err).To(BeAssignableToTypeOf(&util.UnsupportedQueryError{}))
+			})
+		})
+
+		Context("when the order by field is unknown", func() {
+			It("returns unsupported query error", func() {
+				_, err := s.List(ctx, types.PlatformType, query.OrderResultBy("unknown", query.AscOrder))
+				Expect(err).To(BeAssignableToTypeOf(&util.UnsupportedQueryError{}))
+			})
+		})
+
+		Context("when the order by field is nullable", func() {
+			It("returns unsupported query error", func() {
+				_, err := s.List(ctx, types.PlatformType, query.OrderResultBy("description", query.AscOrder))
+				Expect(err).To(BeAssignableToTypeOf(&util.UnsupportedQueryError{}))
+			})
+		})
+
+		Context("when a sub-query which is not provided by the storage package is used", func() {
+			It("returns unsupported query error", func() {
+				_, err := s.List(ctx, types.PlatformType, query.ByExists("SELECT 1"))
+				Expect(err).To(BeAssignableToTypeOf(&util.UnsupportedQueryError{}))
+			})
+		})
+	})
+
+	Describe("Count and CountLabelValues", func() {
+		BeforeEach(func() {
+			_, err := s.Create(ctx, newPlatform("p1", types.Labels{"env": {"dev", "test"}}))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = s.Create(ctx, newPlatform("p2", types.Labels{"env": {"prod"}, "team": {"a"}}))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("ignores the limit when counting", func() {
+			count, err := s.Count(ctx, types.PlatformType, query.LimitResultBy(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(2))
+		})
+
+		It("counts only the label values matching the label criteria", func() {
+			count, err := s.CountLabelValues(ctx, types.PlatformType)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(4))
+
+			count, err = s.CountLabelValues(ctx, types.PlatformType, query.ByLabel(query.InOperator, "env", "dev", "prod"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(2))
+		})
+	})
+
+	Describe("CountGroups", func() {
+		groupValue := func(value string) *string {
+			return &value
+		}
+
+		BeforeEach(func() {
+			for id, labels := range map[string]types.Labels{
+				"p1": {"env": {"dev", "test"}},
+				"p2": {"env": {"dev"}, "team": {"a"}},
+				"p3": {"team": {"a"}},
+			} {
+				_, err := s.Create(ctx, newPlatform(id, labels))
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("counts the objects in the group of each value of the grouped label keys starting from the largest group", func() {
+			groups, err := s.CountGroups(ctx, types.PlatformType, []string{"type"}, []string{"env"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(groups).To(Equal([]*types.GroupCount{
+				{Fields: map[string]*string{"type": groupValue("kubernetes")}, Labels: map[string]*string{"env": groupValue("dev")}, Count: 2},
+				{Fields: map[string]*string{"type": groupValue("kubernetes")}, Labels: map[string]*string{"env": groupValue("test")}, Count: 1},
+				{Fields: map[string]*string{"type": groupValue("kubernetes")}, Labels: map[string]*string{"env": nil}, Count: 1},
+			}))
+		})
+
+		It("counts only the objects matching the criteria", func() {
+			groups, err := s.CountGroups(ctx, types.PlatformType, nil, []string{"team"}, query.ByLabel(query.EqualsOperator, "env", "dev"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(groups).To(Equal([]*types.GroupCount{
+				{Labels: map[string]*string{"team": groupValue("a")}, Count: 1},
+				{Labels: map[string]*string{"team": nil}, Count: 1},
+			}))
+		})
+
+		Context("when the field cannot be grouped by", func() {
+			It("returns unsupported query error", func() {
+				_, err := s.CountGroups(ctx, types.PlatformType, []string{"unknown"}, nil)
+				Expect(err).To(BeAssignableToTypeOf(&util.UnsupportedQueryError{}))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		var platform types.Object
+
+		BeforeEach(func() {
+			var err error
+			platform, err = s.Create(ctx, newPlatform("p1", types.Labels{"env": {"dev", "test"}, "team": {"a"}}))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("updates the object, keeps the paging sequence and applies the label changes", func() {
+			platform.(*types.Platform).Description = "updated"
+			updated, err := s.Update(ctx, platform, types.LabelChanges{
+				{Operation: types.AddLabelOperation, Key: "env", Values: []string{"dev", "prod"}},
+				{Operation: types.RemoveLabelValuesOperation, Key: "env", Values: []string{"test"}},
+				{Operation: types.RemoveLabelOperation, Key: "team"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated.GetPagingSequence()).To(Equal(platform.GetPagingSequence()))
+
+			result, err := s.Get(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", "p1"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.(*types.Platform).Description).To(Equal("updated"))
+			Expect(result.GetLabels()).To(Equal(types.Labels{"env": {"dev", "prod"}}))
+		})
+
+		Context("when the object does not exist", func() {
+			It("returns not found error", func() {
+				_, err := s.Update(ctx, newPlatform("missing", nil), types.LabelChanges{})
+				Expect(err).To(Equal(util.ErrNotFoundInStorage))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		Context("when no object matches the criteria", func() {
+			It("returns not found error", func() {
+				err := s.Delete(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", "missing"))
+				Expect(err).To(Equal(util.ErrNotFoundInStorage))
+			})
+		})
+
+		It("returns the deleted objects", func() {
+			_, err := s.Create(ctx, newPlatform("p1", types.Labels{"env": {"dev"}}))
+			Expect(err).ToNot(HaveOccurred())
+			deleted, err := s.DeleteReturning(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", "p1"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(deleted)).To(Equal([]string{"p1"}))
+
+			count, err := s.Count(ctx, types.PlatformType)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(0))
+		})
+
+		Context("when the object is referenced", func() {
+			BeforeEach(func() {
+				_, err := s.Create(ctx, newPlatform("p1", nil))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = s.Create(ctx, &types.Notification{
+					Base:       types.Base{ID: "n1"},
+					PlatformID: "p1",
+					Resource:   types.PlatformType,
+					Type:       types.CREATED,
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("deletes the referencing objects with cascading references", func() {
+				Expect(s.Delete(ctx, types.PlatformType)).To(Succeed())
+				count, err := s.Count(ctx, types.NotificationType)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("InTransaction", func() {
+		Context("when the function fails", func() {
+			It("discards the changes", func() {
+				expectedErr := errors.New("expected")
+				err := s.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+					if _, err := repository.Create(ctx, newPlatform("p1", nil)); err != nil {
+						return err
+					}
+					count, err := repository.Count(ctx, types.PlatformType)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(count).To(Equal(1))
+					return expectedErr
+				})
+				Expect(err).To(Equal(expectedErr))
+
+				count, err := s.Count(ctx, types.PlatformType)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(0))
+			})
+		})
+
+		It("isolates the changes until the transaction is committed", func() {
+			err := s.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+				if _, err := repository.Create(ctx, newPlatform("p1", nil)); err != nil {
+					return err
+				}
+				count, err := s.Count(ctx, types.PlatformType)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(0))
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			count, err := s.Count(ctx, types.PlatformType)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+	})
+
+	Describe("QueryForList", func() {
+		It("lists the objects by existing label", func() {
+			_, err := s.Create(ctx, newPlatform("p1", types.Labels{types.Monitored: {"true"}}))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = s.Create(ctx, newPlatform("p2", nil))
+			Expect(err).ToNot(HaveOccurred())
+
+			list, err := s.QueryForList(ctx, types.PlatformType, storage.QueryByExistingLabel, map[string]interface{}{"key": types.Monitored})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(list)).To(Equal([]string{"p1"}))
+			Expect(list.ItemAt(0).GetLabels()).To(HaveKey(types.Monitored))
+		})
+
+		It("lists the last operation per resource", func() {
+			for _, id := range []string{"op1", "op2"} {
+				_, err := s.Create(ctx, &types.Operation{
+					Base:         types.Base{ID: id},
+					Type:         types.CREATE,
+					State:        types.IN_PROGRESS,
+					ResourceID:   "r1",
+					ResourceType: types.PlatformType,
+				})
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			list, err := s.QueryForList(ctx, types.OperationType, storage.QueryForLastOperationsPerResource, map[string]interface{}{
+				"id_list":       []string{"r1"},
+				"resource_type": types.PlatformType,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(list)).To(Equal([]string{"op2"}))
+
+			list, err = s.List(ctx, types.OperationType,
+				query.BySubquery(query.InSubqueryOperator, "id", storage.GetSubQuery(storage.QueryForAllNotLastOperationsPerResource)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platformIDs(list)).To(Equal([]string{"op1"}))
+		})
+	})
+
+	Describe("KeyStore", func() {
+		It("returns empty key if no key is set", func() {
+			key, err := s.GetEncryptionKey(ctx, func(_ context.Context, key []byte, _ []byte) ([]byte, error) {
+				return key, nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(BeEmpty())
+		})
+
+		It("stores the transformed key", func() {
+			err := s.SetEncryptionKey(ctx, []byte("key"), func(_ context.Context, key []byte, _ []byte) ([]byte, error) {
+				return append(key, '!'), nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			key, err := s.GetEncryptionKey(ctx, func(_ context.Context, key []byte, _ []byte) ([]byte, error) {
+				return key, nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(key)).To(Equal("key!"))
+		})
+	})
+})
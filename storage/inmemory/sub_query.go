@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// subQuery evaluates one of the sql sub-queries known to the Service Manager. As the sub-queries are correlated with
+// a single column of the main query, the result is the name of this column together with the values for which the
+// sub-query returns any rows.
+type subQuery func(db *database, s *Storage, params map[string]string) (column string, values map[string]bool, err error)
+
+var subQueries = map[storage.SubQuery]subQuery{
+	storage.QueryForAllLastOperationsPerResource:    lastOperationsSubQuery(true),
+	storage.QueryForAllNotLastOperationsPerResource: lastOperationsSubQuery(false),
+	storage.QueryForOperationsWithResource:          operationsWithResourceSubQuery,
+	storage.QueryForTenantScopedServiceOfferings:    tenantScopedServiceOfferingsSubQuery,
+	storage.QueryForInstanceChildrenByLabel:         instanceChildrenByLabelSubQuery,
+}
+
+var (
+	templateParameter = regexp.MustCompile(`\\\{\\\{\\\.([A-Z_]+)\\\}\\\}`)
+	subQueryPatterns  = compileSubQueryPatterns()
+)
+
+// compileSubQueryPatterns builds regular expressions which match the sub-queries rendered with any parameters
+func compileSubQueryPatterns() map[storage.SubQuery]*regexp.Regexp {
+	patterns := make(map[storage.SubQuery]*regexp.Regexp, len(subQueries))
+	for name := range subQueries {
+		pattern := templateParameter.ReplaceAllString(regexp.QuoteMeta(storage.GetSubQuery(name)), `(?P<$1>.*?)`)
+		patterns[name] = regexp.MustCompile(`(?s)^` + pattern + `$`)
+	}
+	return patterns
+}
+
+// resolveSubQuery finds the sub-query from which the provided sql has been rendered and evaluates it
+func resolveSubQuery(db *database, s *Storage, sql string) (string, map[string]bool, error) {
+	for name, pattern := range subQueryPatterns {
+		match := pattern.FindStringSubmatch(sql)
+		if match == nil {
+			continue
+		}
+		params := make(map[string]string)
+		for i, paramName := range pattern.SubexpNames() {
+			if paramName != "" {
+				params[paramName] = match[i]
+			}
+		}
+		return subQueries[name](db, s, params)
+	}
+	return "", nil, &util.UnsupportedQueryError{Message: "only the sub-queries provided by the storage package are supported by the in-memory storage"}
+}
+
+func lastOperationsSubQuery(last bool) subQuery {
+	return func(db *database, s *Storage, _ map[string]string) (string, map[string]bool, error) {
+		t, err := s.scheme.tableByNameOrError("operations")
+		if err != nil {
+			return "", nil, err
+		}
+		lastOperations := lastOperationPerResource(db, t, func(*row) bool { return true })
+		values := make(map[string]bool)
+		for _, r := range db.rows(t.objectType) {
+			_, isLast := lastOperations[r.id()]
+			if isLast == last {
+				values[r.id()] = true
+			}
+		}
+		return "id", values, nil
+	}
+}
+
+func operationsWithResourceSubQuery(db *database, s *Storage, params map[string]string) (string, map[string]bool, error) {
+	t, err := s.scheme.tableByNameOrError(params["RESOURCE_TABLE"])
+	if err != nil {
+		return "", nil, err
+	}
+	values := make(map[string]bool)
+	for id := range db.rows(t.objectType) {
+		values[id] = true
+	}
+	return "resource_id", values, nil
+}
+
+func tenantScopedServiceOfferingsSubQuery(db *database, s *Storage, params map[string]string) (string, map[string]bool, error) {
+	t, err := s.scheme.tableByNameOrError("brokers")
+	if err != nil {
+		return "", nil, err
+	}
+	values := make(map[string]bool)
+	for id, r := range db.rows(t.objectType) {
+		if _, found := r.labels[params["TENANT_KEY"]]; found {
+			values[id] = true
+		}
+	}
+	return "broker_id", values, nil
+}
+
+func instanceChildrenByLabelSubQuery(db *database, s *Storage, params map[string]string) (string, map[string]bool, error) {
+	t, err := s.scheme.tableByNameOrError("service_instances")
+	if err != nil {
+		return "", nil, err
+	}
+	keys := strings.Split(params["PARENT_KEYS"], ",")
+	for i := range keys {
+		keys[i] = strings.Trim(strings.TrimSpace(keys[i]), "'")
+	}
+	values := make(map[string]bool)
+	for id, r := range db.rows(t.objectType) {
+		for _, key := range keys {
+			if containsString(r.labels[key], params["PARENT_ID"]) {
+				values[id] = true
+			}
+		}
+	}
+	return "id", values, nil
+}
+
+// lastOperationPerResource returns the ids of the operations with the highest paging sequence for each resource among
+// the operations matching the filter
+func lastOperationPerResource(db *database, t *table, filter func(*row) bool) map[string]bool {
+	type resource struct{ id, resourceType interface{} }
+	last := make(map[resource]*row)
+	for _, r := range db.sortedRows(t) {
+		if filter(r) {
+			last[resource{id: t.columns["resource_id"].value(r.entity), resourceType: t.columns["resource_type"].value(r.entity)}] = r
+		}
+	}
+	result := make(map[string]bool, len(last))
+	for _, r := range last {
+		result[r.id()] = true
+	}
+	return result
+}
+
+func (s *scheme) tableByNameOrError(name string) (*table, error) {
+	t := s.tableByName(name)
+	if t == nil {
+		return nil, fmt.Errorf("no entity is introduced for table %s", name)
+	}
+	return t, nil
+}
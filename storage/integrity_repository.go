@@ -148,6 +148,10 @@ func (cr *integrityRepository) CountLabelValues(ctx context.Context, objectType
 	return cr.repository.CountLabelValues(ctx, objectType, criteria...)
 }
 
+func (cr *integrityRepository) CountGroups(ctx context.Context, objectType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error) {
+	return cr.repository.CountGroups(ctx, objectType, fields, labelKeys, criteria...)
+}
+
 func (cr *integrityRepository) DeleteReturning(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
 	return cr.repository.DeleteReturning(ctx, objectType, criteria...)
 }
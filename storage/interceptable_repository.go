@@ -180,7 +180,7 @@ func (ir *queryScopedInterceptableRepository) Get(ctx context.Context, objectTyp
 }
 
 func (ir *queryScopedInterceptableRepository) GetForUpdate(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
-	object, err := ir.repositoryInTransaction.Get(ctx, objectType, criteria...)
+	object, err := ir.repositoryInTransaction.GetForUpdate(ctx, objectType, criteria...)
 	if err != nil {
 		return nil, err
 	}
@@ -220,6 +220,10 @@ func (ir *queryScopedInterceptableRepository) CountLabelValues(ctx context.Conte
 	return ir.repositoryInTransaction.CountLabelValues(ctx, objectType, criteria...)
 }
 
+func (ir *queryScopedInterceptableRepository) CountGroups(ctx context.Context, objectType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error) {
+	return ir.repositoryInTransaction.CountGroups(ctx, objectType, fields, labelKeys, criteria...)
+}
+
 func (ir *queryScopedInterceptableRepository) DeleteReturning(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
 	var resultList types.ObjectList
 	deleteObjectFunc := func(ctx context.Context, _ Repository, _ types.ObjectList, deletionCriteria ...query.Criterion) error {
@@ -615,6 +619,10 @@ func (itr *InterceptableTransactionalRepository) CountLabelValues(ctx context.Co
 	return itr.RawRepository.CountLabelValues(ctx, objectType, criteria...)
 }
 
+func (itr *InterceptableTransactionalRepository) CountGroups(ctx context.Context, objectType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error) {
+	return itr.RawRepository.CountGroups(ctx, objectType, fields, labelKeys, criteria...)
+}
+
 func (itr *InterceptableTransactionalRepository) DeleteReturning(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
 	providedCreateInterceptors, providedUpdateInterceptors, providedDeleteInterceptors := itr.provideInterceptors()
 
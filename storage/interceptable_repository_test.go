@@ -403,6 +403,18 @@ var _ = Describe("Interceptable TransactionalRepository", func() {
 			})
 		})
 
+		It("locks the resources retrieved for update in the transaction", func() {
+			err := interceptableRepository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
+				byID := query.ByField(query.EqualsOperator, "id", "id")
+				_, err := storage.GetForUpdate(ctx, types.ServiceBrokerType, byID)
+				return err
+			})
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(fakeStorage.GetForUpdateCallCount()).To(Equal(1))
+			Expect(fakeStorage.GetCallCount()).To(Equal(0))
+		})
+
 		It("does not get into infinite recursion when an interceptor triggers the same db op for the same db type it intercepts", func() {
 			fakeCreateOnTxInterceptor.OnTxCreateCalls(func(next storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
 				return func(ctx context.Context, txStorage storage.Repository, newObject types.Object) (types.Object, error) {
@@ -0,0 +1,324 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	// BlockingReasonPlanRemoved is the reason for blocking a catalog update by an instance of a plan removed from the catalog
+	BlockingReasonPlanRemoved = "plan_removed"
+	// BlockingReasonSharingNotSupported is the reason for blocking a catalog update by a shared instance of a plan
+	// which no longer supports instance sharing
+	BlockingReasonSharingNotSupported = "instance_sharing_not_supported"
+)
+
+// CatalogDiff contains the changes which an update of a broker would apply to its catalog
+type CatalogDiff struct {
+	ServiceOfferings CatalogChanges `json:"service_offerings"`
+	ServicePlans     CatalogChanges `json:"service_plans"`
+
+	// DroppedVisibilities are the visibilities of the removed plans, which would be deleted together with the plans
+	DroppedVisibilities []*types.Visibility `json:"dropped_visibilities"`
+	// BlockingInstances are the instances because of which the update would fail
+	BlockingInstances []*BlockingInstance `json:"blocking_instances"`
+	// Blocked specifies whether the update would fail because of the blocking instances
+	Blocked bool `json:"blocked"`
+}
+
+// CatalogChanges contains the added, removed and changed offerings or plans of a catalog
+type CatalogChanges struct {
+	Added   []*CatalogChange `json:"added"`
+	Removed []*CatalogChange `json:"removed"`
+	Changed []*CatalogChange `json:"changed"`
+}
+
+// CatalogChange describes a single offering or plan of a catalog diff
+type CatalogChange struct {
+	// ID is the id of the offering or plan in the Service Manager. It is empty for added offerings and plans.
+	ID          string `json:"id,omitempty"`
+	CatalogID   string `json:"catalog_id"`
+	CatalogName string `json:"catalog_name"`
+	// ServiceOfferingCatalogID is the catalog id of the offering of a plan
+	ServiceOfferingCatalogID string `json:"service_offering_catalog_id,omitempty"`
+	// ChangedFields are the fields of a changed offering or plan whose values are different in the new catalog
+	ChangedFields []string `json:"changed_fields,omitempty"`
+}
+
+// BlockingInstance is an instance because of which an update of a broker catalog would fail
+type BlockingInstance struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ServicePlanID string `json:"service_plan_id"`
+	Reason        string `json:"reason"`
+}
+
+// BrokerCatalogPreviewer computes the changes which an update of a broker would apply to its catalog, without
+// persisting anything. It matches the offerings and plans in the same way as the BrokerUpdateCatalogInterceptor.
+type BrokerCatalogPreviewer struct {
+	CatalogFetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+	CatalogLoader  func(ctx context.Context, brokerID string, repository storage.Repository) (*types.ServiceOfferings, error)
+}
+
+// Preview fetches the catalog of the broker and returns its differences from the catalog stored in the Service Manager.
+// When tenant criteria are provided, only the blocking instances matching them and the dropped visibilities of the
+// platforms matching them are returned, while the update is still reported as blocked by the instances of any tenant.
+func (p *BrokerCatalogPreviewer) Preview(ctx context.Context, repository storage.Repository, broker *types.ServiceBroker, tenantCriteria ...query.Criterion) (*CatalogDiff, error) {
+	existingOfferings, err := p.CatalogLoader(ctx, broker.GetID(), repository)
+	if err != nil {
+		return nil, fmt.Errorf("error getting catalog for broker with id %s from SM DB: %s", broker.GetID(), err)
+	}
+
+	newBroker := *broker
+	if err := brokerCatalogAroundTx(ctx, &newBroker, p.CatalogFetcher); err != nil {
+		return nil, err
+	}
+	oldBroker := *broker
+	oldBroker.Services = existingOfferings.ServiceOfferings
+	if err := reuseReferenceInstancePlans(&oldBroker, &newBroker); err != nil {
+		return nil, err
+	}
+
+	scope, err := newPreviewScope(ctx, repository, tenantCriteria)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &CatalogDiff{
+		ServiceOfferings:    newCatalogChanges(),
+		ServicePlans:        newCatalogChanges(),
+		DroppedVisibilities: []*types.Visibility{},
+		BlockingInstances:   []*BlockingInstance{},
+	}
+	existingOfferingsMap, existingPlansPerOfferingMap := convertExistingServiceOfferingsToMaps(existingOfferings.ServiceOfferings)
+
+	for _, offering := range newBroker.Services {
+		existingOffering, found := existingOfferingsMap[offering.CatalogID]
+		if !found {
+			added := offeringChange(offering, nil)
+			// the offerings from the fetched catalog have temporary ids
+			added.ID = ""
+			diff.ServiceOfferings.Added = append(diff.ServiceOfferings.Added, added)
+		} else {
+			delete(existingOfferingsMap, offering.CatalogID)
+			if changedFields := changedOfferingFields(existingOffering, offering); len(changedFields) > 0 {
+				diff.ServiceOfferings.Changed = append(diff.ServiceOfferings.Changed, offeringChange(existingOffering, changedFields))
+			}
+		}
+
+		existingPlans := make(map[string]*types.ServicePlan)
+		for _, plan := range existingPlansPerOfferingMap[offering.CatalogID] {
+			existingPlans[plan.CatalogID] = plan
+		}
+		for _, plan := range offering.Plans {
+			existingPlan, found := existingPlans[plan.CatalogID]
+			if !found {
+				added := planChange(plan, offering, nil)
+				added.ID = ""
+				diff.ServicePlans.Added = append(diff.ServicePlans.Added, added)
+				continue
+			}
+			delete(existingPlans, plan.CatalogID)
+			if changedFields := changedPlanFields(existingPlan, plan); len(changedFields) > 0 {
+				diff.ServicePlans.Changed = append(diff.ServicePlans.Changed, planChange(existingPlan, offering, changedFields))
+			}
+			if !plan.SupportsInstanceSharing() {
+				if err := p.addBlockingInstances(ctx, repository, scope, diff, BlockingReasonSharingNotSupported, existingPlan.ID,
+					query.ByField(query.EqualsOperator, "shared", strconv.FormatBool(true))); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, removedPlan := range existingPlans {
+			if err := p.addRemovedPlan(ctx, repository, scope, diff, removedPlan, offering); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the plans of the removed offerings are removed too
+	for _, removedOffering := range existingOfferingsMap {
+		diff.ServiceOfferings.Removed = append(diff.ServiceOfferings.Removed, offeringChange(removedOffering, nil))
+		for _, removedPlan := range existingPlansPerOfferingMap[removedOffering.CatalogID] {
+			if err := p.addRemovedPlan(ctx, repository, scope, diff, removedPlan, removedOffering); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	diff.Blocked = diff.Blocked || len(diff.BlockingInstances) > 0
+	log.C(ctx).Debugf("Catalog update of broker with id %s would add %d, remove %d and change %d plans", broker.GetID(),
+		len(diff.ServicePlans.Added), len(diff.ServicePlans.Removed), len(diff.ServicePlans.Changed))
+	return diff, nil
+}
+
+// previewScope limits the instances and visibilities of a preview to those of a tenant
+type previewScope struct {
+	tenantCriteria []query.Criterion
+	// platformIDs are the ids of the platforms of the tenant
+	platformIDs []string
+}
+
+func newPreviewScope(ctx context.Context, repository storage.Repository, tenantCriteria []query.Criterion) (*previewScope, error) {
+	if len(tenantCriteria) == 0 {
+		return nil, nil
+	}
+	platforms, err := repository.ListNoLabels(ctx, types.PlatformType, tenantCriteria...)
+	if err != nil {
+		return nil, err
+	}
+	scope := &previewScope{tenantCriteria: tenantCriteria}
+	for i := 0; i < platforms.Len(); i++ {
+		scope.platformIDs = append(scope.platformIDs, platforms.ItemAt(i).GetID())
+	}
+	return scope, nil
+}
+
+func (p *BrokerCatalogPreviewer) addRemovedPlan(ctx context.Context, repository storage.Repository, scope *previewScope, diff *CatalogDiff, plan *types.ServicePlan, offering *types.ServiceOffering) error {
+	diff.ServicePlans.Removed = append(diff.ServicePlans.Removed, planChange(plan, offering, nil))
+
+	criteria := []query.Criterion{query.ByField(query.EqualsOperator, "service_plan_id", plan.ID)}
+	if scope != nil {
+		criteria = append(criteria, query.ByField(query.InOperator, "platform_id", scope.platformIDs...))
+	}
+	if scope == nil || len(scope.platformIDs) > 0 {
+		visibilities, err := repository.List(ctx, types.VisibilityType, criteria...)
+		if err != nil {
+			return err
+		}
+		diff.DroppedVisibilities = append(diff.DroppedVisibilities, visibilities.(*types.Visibilities).Visibilities...)
+	}
+
+	return p.addBlockingInstances(ctx, repository, scope, diff, BlockingReasonPlanRemoved, plan.ID)
+}
+
+func (p *BrokerCatalogPreviewer) addBlockingInstances(ctx context.Context, repository storage.Repository, scope *previewScope, diff *CatalogDiff, reason, planID string, criteria ...query.Criterion) error {
+	criteria = append(criteria, query.ByField(query.EqualsOperator, "service_plan_id", planID))
+	if scope != nil {
+		// the update is blocked by the instances of all tenants, but only the instances of the tenant are listed
+		count, err := repository.Count(ctx, types.ServiceInstanceType, criteria...)
+		if err != nil {
+			return err
+		}
+		diff.Blocked = diff.Blocked || count > 0
+		criteria = append(criteria, scope.tenantCriteria...)
+	}
+	instances, err := repository.ListNoLabels(ctx, types.ServiceInstanceType, criteria...)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances.(*types.ServiceInstances).ServiceInstances {
+		diff.BlockingInstances = append(diff.BlockingInstances, &BlockingInstance{
+			ID:            instance.ID,
+			Name:          instance.Name,
+			ServicePlanID: instance.ServicePlanID,
+			Reason:        reason,
+		})
+	}
+	return nil
+}
+
+func newCatalogChanges() CatalogChanges {
+	return CatalogChanges{
+		Added:   []*CatalogChange{},
+		Removed: []*CatalogChange{},
+		Changed: []*CatalogChange{},
+	}
+}
+
+func offeringChange(offering *types.ServiceOffering, changedFields []string) *CatalogChange {
+	return &CatalogChange{
+		ID:            offering.ID,
+		CatalogID:     offering.CatalogID,
+		CatalogName:   offering.CatalogName,
+		ChangedFields: changedFields,
+	}
+}
+
+func planChange(plan *types.ServicePlan, offering *types.ServiceOffering, changedFields []string) *CatalogChange {
+	return &CatalogChange{
+		ID:                       plan.ID,
+		CatalogID:                plan.CatalogID,
+		CatalogName:              plan.CatalogName,
+		ServiceOfferingCatalogID: offering.CatalogID,
+		ChangedFields:            changedFields,
+	}
+}
+
+func changedOfferingFields(existing, offering *types.ServiceOffering) []string {
+	var fields []string
+	fields = appendIfChanged(fields, "catalog_name", existing.CatalogName != offering.CatalogName)
+	fields = appendIfChanged(fields, "description", existing.Description != offering.Description)
+	fields = appendIfChanged(fields, "bindable", existing.Bindable != offering.Bindable)
+	fields = appendIfChanged(fields, "instances_retrievable", existing.InstancesRetrievable != offering.InstancesRetrievable)
+	fields = appendIfChanged(fields, "bindings_retrievable", existing.BindingsRetrievable != offering.BindingsRetrievable)
+	fields = appendIfChanged(fields, "plan_updateable", existing.PlanUpdatable != offering.PlanUpdatable)
+	fields = appendIfChanged(fields, "allow_context_updates", existing.AllowContextUpdates != offering.AllowContextUpdates)
+	fields = appendIfChanged(fields, "tags", !jsonEqual(existing.Tags, offering.Tags))
+	fields = appendIfChanged(fields, "requires", !jsonEqual(existing.Requires, offering.Requires))
+	fields = appendIfChanged(fields, "metadata", !jsonEqual(existing.Metadata, offering.Metadata))
+	return fields
+}
+
+func changedPlanFields(existing, plan *types.ServicePlan) []string {
+	var fields []string
+	fields = appendIfChanged(fields, "catalog_name", existing.CatalogName != plan.CatalogName)
+	fields = appendIfChanged(fields, "description", existing.Description != plan.Description)
+	fields = appendIfChanged(fields, "free", !reflect.DeepEqual(existing.Free, plan.Free))
+	fields = appendIfChanged(fields, "bindable", !reflect.DeepEqual(existing.Bindable, plan.Bindable))
+	fields = appendIfChanged(fields, "plan_updateable", !reflect.DeepEqual(existing.PlanUpdatable, plan.PlanUpdatable))
+	fields = appendIfChanged(fields, "binding_rotatable", !reflect.DeepEqual(existing.BindingRotatable, plan.BindingRotatable))
+	fields = appendIfChanged(fields, "maximum_polling_duration", existing.MaximumPollingDuration != plan.MaximumPollingDuration)
+	fields = appendIfChanged(fields, "metadata", !jsonEqual(existing.Metadata, plan.Metadata))
+	fields = appendIfChanged(fields, "schemas", !jsonEqual(existing.Schemas, plan.Schemas))
+	fields = appendIfChanged(fields, "maintenance_info", !jsonEqual(existing.MaintenanceInfo, plan.MaintenanceInfo))
+	return fields
+}
+
+func appendIfChanged(fields []string, field string, changed bool) []string {
+	if changed {
+		return append(fields, field)
+	}
+	return fields
+}
+
+// jsonEqual compares JSON values regardless of their formatting, as the database may store them differently
+// than they are returned by the broker. Missing values are equal to null.
+func jsonEqual(a, b json.RawMessage) bool {
+	var aValue, bValue interface{}
+	if len(a) > 0 {
+		if err := json.Unmarshal(a, &aValue); err != nil {
+			return false
+		}
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &bValue); err != nil {
+			return false
+		}
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/types"
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+)
+
+// circuitOSBClient fails fast the calls of an OSB client to a broker whose circuit is open and records the results of
+// the calls in the circuit of the broker. The polls of the last operation are not failed fast, so that the operations
+// in progress are not failed, but their results are recorded as well.
+type circuitOSBClient struct {
+	osbc.Client
+
+	ctx      context.Context
+	circuits *operations.BrokerCircuits
+	broker   *types.ServiceBroker
+}
+
+func newCircuitOSBClient(ctx context.Context, client osbc.Client, circuits *operations.BrokerCircuits, broker *types.ServiceBroker) osbc.Client {
+	if circuits == nil {
+		return client
+	}
+	return &circuitOSBClient{
+		Client:   client,
+		ctx:      ctx,
+		circuits: circuits,
+		broker:   broker,
+	}
+}
+
+func (c *circuitOSBClient) ProvisionInstance(r *osbc.ProvisionRequest) (*osbc.ProvisionResponse, error) {
+	if err := c.circuits.Allow(c.broker); err != nil {
+		return nil, err
+	}
+	response, err := c.Client.ProvisionInstance(r)
+	recordBrokerResult(c.ctx, c.circuits, c.broker, err)
+	return response, err
+}
+
+func (c *circuitOSBClient) UpdateInstance(r *osbc.UpdateInstanceRequest) (*osbc.UpdateInstanceResponse, error) {
+	if err := c.circuits.Allow(c.broker); err != nil {
+		return nil, err
+	}
+	response, err := c.Client.UpdateInstance(r)
+	recordBrokerResult(c.ctx, c.circuits, c.broker, err)
+	return response, err
+}
+
+func (c *circuitOSBClient) DeprovisionInstance(r *osbc.DeprovisionRequest) (*osbc.DeprovisionResponse, error) {
+	if err := c.circuits.Allow(c.broker); err != nil {
+		return nil, err
+	}
+	response, err := c.Client.DeprovisionInstance(r)
+	recordBrokerResult(c.ctx, c.circuits, c.broker, err)
+	return response, err
+}
+
+func (c *circuitOSBClient) PollLastOperation(r *osbc.LastOperationRequest) (*osbc.LastOperationResponse, error) {
+	response, err := c.Client.PollLastOperation(r)
+	recordBrokerResult(c.ctx, c.circuits, c.broker, err)
+	return response, err
+}
+
+func (c *circuitOSBClient) PollBindingLastOperation(r *osbc.BindingLastOperationRequest) (*osbc.LastOperationResponse, error) {
+	response, err := c.Client.PollBindingLastOperation(r)
+	recordBrokerResult(c.ctx, c.circuits, c.broker, err)
+	return response, err
+}
+
+func (c *circuitOSBClient) Bind(r *osbc.BindRequest) (*osbc.BindResponse, error) {
+	if err := c.circuits.Allow(c.broker); err != nil {
+		return nil, err
+	}
+	response, err := c.Client.Bind(r)
+	recordBrokerResult(c.ctx, c.circuits, c.broker, err)
+	return response, err
+}
+
+func (c *circuitOSBClient) Unbind(r *osbc.UnbindRequest) (*osbc.UnbindResponse, error) {
+	if err := c.circuits.Allow(c.broker); err != nil {
+		return nil, err
+	}
+	response, err := c.Client.Unbind(r)
+	recordBrokerResult(c.ctx, c.circuits, c.broker, err)
+	return response, err
+}
+
+func (c *circuitOSBClient) GetBinding(r *osbc.GetBindingRequest) (*osbc.GetBindingResponse, error) {
+	if err := c.circuits.Allow(c.broker); err != nil {
+		return nil, err
+	}
+	response, err := c.Client.GetBinding(r)
+	recordBrokerResult(c.ctx, c.circuits, c.broker, err)
+	return response, err
+}
+
+// recordBrokerResult records the result of a call to the broker in the circuit of the broker. The calls to which the
+// broker responded with an error other than unavailability are successful for the circuit.
+func recordBrokerResult(ctx context.Context, circuits *operations.BrokerCircuits, broker *types.ServiceBroker, err error) {
+	if err == nil {
+		circuits.RecordSuccess(broker)
+		return
+	}
+	if ctx.Err() != nil {
+		circuits.RecordAbort(broker)
+		return
+	}
+	if httpErr, ok := osbc.IsHTTPError(err); ok && !operations.IsBrokerUnavailable(httpErr.StatusCode) {
+		circuits.RecordSuccess(broker)
+		return
+	}
+	circuits.RecordFailure(broker, err.Error())
+}
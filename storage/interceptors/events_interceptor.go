@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/operations/opcontext"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	EventsCreateInterceptorName = "EventsCreateInterceptor"
+	EventsUpdateInterceptorName = "EventsUpdateInterceptor"
+	EventsDeleteInterceptorName = "EventsDeleteInterceptor"
+)
+
+// EventsCreateInterceptorProvider provides an interceptor that records resource creation in the change feed
+type EventsCreateInterceptorProvider struct {
+}
+
+func (*EventsCreateInterceptorProvider) Name() string {
+	return EventsCreateInterceptorName
+}
+
+func (*EventsCreateInterceptorProvider) Provide() storage.CreateOnTxInterceptor {
+	return &eventsInterceptor{}
+}
+
+// EventsUpdateInterceptorProvider provides an interceptor that records resource modification in the change feed
+type EventsUpdateInterceptorProvider struct {
+}
+
+func (*EventsUpdateInterceptorProvider) Name() string {
+	return EventsUpdateInterceptorName
+}
+
+func (*EventsUpdateInterceptorProvider) Provide() storage.UpdateOnTxInterceptor {
+	return &eventsInterceptor{}
+}
+
+// EventsDeleteInterceptorProvider provides an interceptor that records resource deletion in the change feed
+type EventsDeleteInterceptorProvider struct {
+}
+
+func (*EventsDeleteInterceptorProvider) Name() string {
+	return EventsDeleteInterceptorName
+}
+
+func (*EventsDeleteInterceptorProvider) Provide() storage.DeleteOnTxInterceptor {
+	return &eventsInterceptor{}
+}
+
+// eventsInterceptor stores a notification addressed to the Service Manager platform for every change of the
+// intercepted resource. Such notifications are never delivered to registered platforms, the notifications revision
+// gives them a global order and they are exposed as a change feed by the events API. As with platform notifications,
+// resources appear in the feed once they become ready.
+type eventsInterceptor struct {
+}
+
+func (*eventsInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, obj types.Object) (types.Object, error) {
+		newObj, err := h(ctx, repository, obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if !newObj.GetReady() {
+			return newObj, nil
+		}
+
+		if err := createEvent(ctx, repository, types.CREATED, newObj, &Payload{
+			New: &ObjectPayload{
+				Resource: newObj,
+			},
+		}); err != nil {
+			return nil, err
+		}
+
+		return newObj, nil
+	}
+}
+
+func (*eventsInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, oldObject, newObject types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		updatedObject, err := h(ctx, repository, oldObject, newObject, labelChanges...)
+		if err != nil {
+			return nil, err
+		}
+
+		if !updatedObject.GetReady() {
+			return updatedObject, nil
+		}
+
+		// If the updated object has just become ready, then record its creation
+		if !oldObject.GetReady() {
+			if err := createEvent(ctx, repository, types.CREATED, updatedObject, &Payload{
+				New: &ObjectPayload{
+					Resource: updatedObject,
+				},
+			}); err != nil {
+				return nil, err
+			}
+			return updatedObject, nil
+		}
+
+		if err := createEvent(ctx, repository, types.MODIFIED, updatedObject, &Payload{
+			New: &ObjectPayload{
+				Resource: updatedObject,
+			},
+			Old: &ObjectPayload{
+				Resource: oldObject,
+			},
+			LabelChanges: labelChanges,
+		}); err != nil {
+			return nil, err
+		}
+
+		return updatedObject, nil
+	}
+}
+
+func (*eventsInterceptor) OnTxDelete(h storage.InterceptDeleteOnTxFunc) storage.InterceptDeleteOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, objects types.ObjectList, deletionCriteria ...query.Criterion) error {
+		if err := h(ctx, repository, objects, deletionCriteria...); err != nil {
+			return err
+		}
+
+		for i := 0; i < objects.Len(); i++ {
+			oldObject := objects.ItemAt(i)
+			if !oldObject.GetReady() {
+				continue
+			}
+			if err := createEvent(ctx, repository, types.DELETED, oldObject, &Payload{
+				Old: &ObjectPayload{
+					Resource: oldObject,
+				},
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// createEvent stores the notification which represents a change of the object in the feed. The notification has
+// the labels of the object, so that the feed can be filtered by them (e.g. by tenant). It is not a transitive
+// resource of the operation causing the change.
+func createEvent(ctx context.Context, repository storage.Repository, op types.NotificationOperation, object types.Object, payload *Payload) error {
+	labels := types.Labels{}
+	for key, values := range object.GetLabels() {
+		labels[key] = values
+	}
+	return createLabeledNotification(opcontext.Clear(ctx), repository, op, object.GetType(), types.SMPlatform, payload, labels)
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type notificationsCoalescerKey struct{}
+
+// NotificationsCoalescer collects the notifications created with a context and merges the ones for the same resource
+// and platform, so that consumers receive only the net change of each resource. A resource which is created and
+// deleted with the same context results in no notifications at all.
+type NotificationsCoalescer struct {
+	notifications []*types.Notification
+}
+
+// rawPayload is a notification payload whose resources are kept as they were serialized
+type rawPayload struct {
+	New          json.RawMessage    `json:"new,omitempty"`
+	Old          json.RawMessage    `json:"old,omitempty"`
+	LabelChanges types.LabelChanges `json:"label_changes,omitempty"`
+}
+
+// ContextWithNotificationsCoalescer returns a context in which notifications are collected by the returned coalescer
+// instead of being stored. The collected notifications must be stored by calling Flush.
+func ContextWithNotificationsCoalescer(ctx context.Context) (context.Context, *NotificationsCoalescer) {
+	coalescer := &NotificationsCoalescer{}
+	return context.WithValue(ctx, notificationsCoalescerKey{}, coalescer), coalescer
+}
+
+func notificationsCoalescerFromContext(ctx context.Context) *NotificationsCoalescer {
+	coalescer, ok := ctx.Value(notificationsCoalescerKey{}).(*NotificationsCoalescer)
+	if !ok {
+		return nil
+	}
+	return coalescer
+}
+
+// Flush stores the coalesced notifications in the order in which the changes of their resources were first made
+func (nc *NotificationsCoalescer) Flush(ctx context.Context, repository storage.Repository) error {
+	for _, notification := range nc.notifications {
+		if _, err := repository.Create(ctx, notification); err != nil {
+			return err
+		}
+	}
+	log.C(ctx).Debugf("Successfully created %d coalesced notifications", len(nc.notifications))
+	nc.notifications = nil
+	return nil
+}
+
+func (nc *NotificationsCoalescer) add(notification *types.Notification) error {
+	resourceID := notificationResourceID(notification)
+	for i := len(nc.notifications) - 1; i >= 0; i-- {
+		previous := nc.notifications[i]
+		if previous.Resource != notification.Resource || previous.PlatformID != notification.PlatformID ||
+			notificationResourceID(previous) != resourceID {
+			continue
+		}
+
+		merged, err := mergeNotifications(previous, notification)
+		if err != nil {
+			return err
+		}
+		if merged == nil {
+			nc.notifications = append(nc.notifications[:i], nc.notifications[i+1:]...)
+			return nil
+		}
+		if merged == previous {
+			return nil
+		}
+		// the change cannot be merged with the previous one, e.g. a resource is created again after its deletion
+		break
+	}
+
+	nc.notifications = append(nc.notifications, notification)
+	return nil
+}
+
+// mergeNotifications merges the next notification for a resource into the previous one. It returns the previous
+// notification if the merge succeeded, nil if the changes cancel each other and the next notification if they cannot
+// be merged.
+func mergeNotifications(previous, next *types.Notification) (*types.Notification, error) {
+	var previousPayload, nextPayload rawPayload
+	if err := json.Unmarshal(previous.Payload, &previousPayload); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(next.Payload, &nextPayload); err != nil {
+		return nil, err
+	}
+
+	var mergedPayload rawPayload
+	switch {
+	case previous.Type == types.CREATED && next.Type == types.MODIFIED:
+		// labels are left out of the modified resource when only they have changed, so they are recomputed
+		newResource, err := applyLabelChanges(previousPayload.New, nextPayload.New, nextPayload.LabelChanges)
+		if err != nil {
+			return nil, err
+		}
+		mergedPayload = rawPayload{New: newResource}
+	case previous.Type == types.CREATED && next.Type == types.DELETED:
+		return nil, nil
+	case previous.Type == types.MODIFIED && next.Type == types.MODIFIED:
+		mergedPayload = rawPayload{
+			New:          nextPayload.New,
+			Old:          previousPayload.Old,
+			LabelChanges: append(previousPayload.LabelChanges, nextPayload.LabelChanges...),
+		}
+	case previous.Type == types.MODIFIED && next.Type == types.DELETED:
+		previous.Type = types.DELETED
+		mergedPayload = rawPayload{Old: previousPayload.Old}
+	default:
+		return next, nil
+	}
+
+	payloadBytes, err := json.Marshal(mergedPayload)
+	if err != nil {
+		return nil, err
+	}
+	previous.Payload = payloadBytes
+	previous.UpdatedAt = next.UpdatedAt
+	return previous, nil
+}
+
+// applyLabelChanges sets the labels of the new resource to the labels of the old resource with the label changes applied
+func applyLabelChanges(oldResource, newResource json.RawMessage, labelChanges types.LabelChanges) (json.RawMessage, error) {
+	labels := types.Labels{}
+	if oldLabels := gjson.GetBytes(oldResource, "resource.labels"); oldLabels.Exists() {
+		if err := json.Unmarshal([]byte(oldLabels.Raw), &labels); err != nil {
+			return nil, err
+		}
+	}
+	labels, _, _ = query.ApplyLabelChangesToLabels(labelChanges, labels)
+	if len(labels) == 0 {
+		return sjson.DeleteBytes(newResource, "resource.labels")
+	}
+	return sjson.SetBytes(newResource, "resource.labels", labels)
+}
+
+func notificationResourceID(notification *types.Notification) string {
+	if id := gjson.GetBytes(notification.Payload, "new.resource.id"); id.Exists() {
+		return id.String()
+	}
+	return gjson.GetBytes(notification.Payload, "old.resource.id").String()
+}
@@ -228,6 +228,10 @@ func (ni *NotificationsInterceptor) OnTxDelete(h storage.InterceptDeleteOnTxFunc
 }
 
 func CreateNotification(ctx context.Context, repository storage.Repository, op types.NotificationOperation, resource types.ObjectType, platformID string, payload *Payload) error {
+	return createLabeledNotification(ctx, repository, op, resource, platformID, payload, types.Labels{})
+}
+
+func createLabeledNotification(ctx context.Context, repository storage.Repository, op types.NotificationOperation, resource types.ObjectType, platformID string, payload *Payload, labels types.Labels) error {
 	UUID, err := uuid.NewV4()
 	if err != nil {
 		return fmt.Errorf("could not generate GUID for notification of type %s for resource of type %s: %s", op, resource, err)
@@ -255,7 +259,7 @@ func CreateNotification(ctx context.Context, repository storage.Repository, op t
 			ID:        UUID.String(),
 			CreatedAt: currentTime,
 			UpdatedAt: currentTime,
-			Labels:    map[string][]string{},
+			Labels:    labels,
 			Ready:     true,
 		},
 		Resource:      resource,
@@ -265,6 +269,10 @@ func CreateNotification(ctx context.Context, repository storage.Repository, op t
 		CorrelationID: log.CorrelationIDFromContext(ctx),
 	}
 
+	if coalescer := notificationsCoalescerFromContext(ctx); coalescer != nil {
+		return coalescer.add(notification)
+	}
+
 	createdNotification, err := repository.Create(ctx, notification)
 	if err != nil {
 		return err
@@ -41,7 +41,7 @@ type operationSanitizerInterceptor struct {
 func (c *operationSanitizerInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
 	return func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
 		op := newObj.(*types.Operation)
-		if op.State == types.SUCCEEDED || op.State == types.FAILED {
+		if op.State == types.SUCCEEDED || op.State == types.FAILED || op.State == types.CANCELLED {
 			if op.Context != nil && op.Context.UserInfo != nil {
 				op.Context.UserInfo = nil
 			}
@@ -0,0 +1,361 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/client"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+)
+
+const (
+	serviceInstanceURLFormat = "%s/v2/service_instances/%s"
+	serviceBindingURLFormat  = "%s/v2/service_instances/%s/service_bindings/%s"
+)
+
+// osbAPIClient is an OSB client which sends the provision, update, bind and get binding requests with the OSB API
+// version negotiated with the broker. The OSB client library supports OSB API versions up to 2.13, so it can neither
+// send the maintenance_info and predecessor_binding_id of the requests nor read the metadata of the responses.
+// The responses and the errors are the ones of the OSB client library, so that they are handled in the same way.
+// The requests to brokers negotiated with an OSB API version older than 2.16, which have no metadata, are sent by the
+// OSB client library unless they have fields which it cannot send.
+type osbAPIClient struct {
+	osbc.Client
+
+	ctx      context.Context
+	broker   *types.ServiceBroker
+	circuits *operations.BrokerCircuits
+}
+
+func newOSBAPIClient(ctx context.Context, client osbc.Client, broker *types.ServiceBroker, circuits *operations.BrokerCircuits) *osbAPIClient {
+	return &osbAPIClient{
+		Client:   newCircuitOSBClient(ctx, client, circuits, broker),
+		ctx:      detachedContext{Context: ctx},
+		broker:   broker,
+		circuits: circuits,
+	}
+}
+
+// detachedContext keeps the values of the context but is not cancelled with it, so that the requests are not aborted
+// when the operation context is cancelled, as the requests sent by the OSB client library are not. Each request
+// derives its own cancellable context from it.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+type provisionRequestBody struct {
+	ServiceID        string                 `json:"service_id"`
+	PlanID           string                 `json:"plan_id"`
+	OrganizationGUID string                 `json:"organization_guid"`
+	SpaceGUID        string                 `json:"space_guid"`
+	Parameters       map[string]interface{} `json:"parameters,omitempty"`
+	Context          map[string]interface{} `json:"context,omitempty"`
+	MaintenanceInfo  json.RawMessage        `json:"maintenance_info,omitempty"`
+}
+
+type updateInstanceRequestBody struct {
+	ServiceID       string                 `json:"service_id"`
+	PlanID          *string                `json:"plan_id,omitempty"`
+	Parameters      map[string]interface{} `json:"parameters,omitempty"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+	MaintenanceInfo json.RawMessage        `json:"maintenance_info,omitempty"`
+	PreviousValues  map[string]interface{} `json:"previous_values,omitempty"`
+}
+
+type instanceResponseBody struct {
+	DashboardURL *string         `json:"dashboard_url"`
+	Operation    *string         `json:"operation"`
+	Metadata     json.RawMessage `json:"metadata"`
+}
+
+type bindRequestBody struct {
+	ServiceID            string                 `json:"service_id"`
+	PlanID               string                 `json:"plan_id"`
+	Parameters           map[string]interface{} `json:"parameters,omitempty"`
+	BindResource         map[string]interface{} `json:"bind_resource,omitempty"`
+	Context              map[string]interface{} `json:"context,omitempty"`
+	PredecessorBindingID string                 `json:"predecessor_binding_id,omitempty"`
+}
+
+type bindingResponseBody struct {
+	Credentials     map[string]interface{} `json:"credentials"`
+	SyslogDrainURL  *string                `json:"syslog_drain_url"`
+	RouteServiceURL *string                `json:"route_service_url"`
+	VolumeMounts    []interface{}          `json:"volume_mounts"`
+	Parameters      map[string]interface{} `json:"parameters"`
+	Operation       *string                `json:"operation"`
+	Metadata        json.RawMessage        `json:"metadata"`
+}
+
+// apiVersion returns the OSB API version negotiated with the broker
+func (c *osbAPIClient) apiVersion() string {
+	return osb.BrokerAPIVersion(c.broker)
+}
+
+// sentByLibrary returns whether a request is sent by the OSB client library. Requests to brokers negotiated with an
+// OSB API version older than 2.16 are sent by it, unless they have fields introduced by newer versions.
+func (c *osbAPIClient) sentByLibrary(hasNewerFields bool) bool {
+	return !hasNewerFields && !osb.APIVersionAtLeast(c.broker.APIVersion, osb.APIVersion2_16)
+}
+
+// validate returns an error if a field of the request is not supported by the OSB API version of the broker
+func (c *osbAPIClient) validate(field, minimumVersion string) error {
+	if osb.APIVersionAtLeast(c.apiVersion(), minimumVersion) {
+		return nil
+	}
+	return &util.HTTPError{
+		ErrorType: "BadRequest",
+		Description: fmt.Sprintf("%s requires OSB API version %s but broker %s supports OSB API version %s",
+			field, minimumVersion, c.broker.Name, c.apiVersion()),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// ProvisionInstanceWithMaintenanceInfo provisions the instance with the maintenance_info and returns the metadata of
+// the instance returned by the broker
+func (c *osbAPIClient) ProvisionInstanceWithMaintenanceInfo(r *osbc.ProvisionRequest, maintenanceInfo json.RawMessage) (*osbc.ProvisionResponse, json.RawMessage, error) {
+	if c.sentByLibrary(len(maintenanceInfo) != 0) {
+		response, err := c.Client.ProvisionInstance(r)
+		return response, nil, err
+	}
+
+	body := &provisionRequestBody{
+		ServiceID:        r.ServiceID,
+		PlanID:           r.PlanID,
+		OrganizationGUID: r.OrganizationGUID,
+		SpaceGUID:        r.SpaceGUID,
+		Parameters:       r.Parameters,
+		Context:          r.Context,
+		MaintenanceInfo:  maintenanceInfo,
+	}
+	url := fmt.Sprintf(serviceInstanceURLFormat, c.broker.BrokerURL, r.InstanceID)
+	responseBody := &instanceResponseBody{}
+	statusCode, err := c.send(http.MethodPut, url, r.AcceptsIncomplete, body, r.OriginatingIdentity, responseBody,
+		http.StatusOK, http.StatusCreated, http.StatusAccepted)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &osbc.ProvisionResponse{
+		Async:        statusCode == http.StatusAccepted,
+		DashboardURL: responseBody.DashboardURL,
+		OperationKey: operationKey(responseBody.Operation),
+	}, responseBody.Metadata, nil
+}
+
+// UpdateInstanceWithMaintenanceInfo updates the instance to the maintenance_info, if it is not empty, and returns
+// the metadata of the instance returned by the broker
+func (c *osbAPIClient) UpdateInstanceWithMaintenanceInfo(r *osbc.UpdateInstanceRequest, maintenanceInfo, previousMaintenanceInfo json.RawMessage) (*osbc.UpdateInstanceResponse, json.RawMessage, error) {
+	if c.sentByLibrary(len(maintenanceInfo) != 0) {
+		response, err := c.Client.UpdateInstance(r)
+		return response, nil, err
+	}
+
+	body := &updateInstanceRequestBody{
+		ServiceID:       r.ServiceID,
+		PlanID:          r.PlanID,
+		Parameters:      r.Parameters,
+		Context:         r.Context,
+		MaintenanceInfo: maintenanceInfo,
+	}
+	if r.PreviousValues != nil {
+		body.PreviousValues = map[string]interface{}{
+			"plan_id": r.PreviousValues.PlanID,
+		}
+		if len(previousMaintenanceInfo) != 0 {
+			body.PreviousValues["maintenance_info"] = previousMaintenanceInfo
+		}
+	}
+	url := fmt.Sprintf(serviceInstanceURLFormat, c.broker.BrokerURL, r.InstanceID)
+	responseBody := &instanceResponseBody{}
+	statusCode, err := c.send(http.MethodPatch, url, r.AcceptsIncomplete, body, r.OriginatingIdentity, responseBody,
+		http.StatusOK, http.StatusAccepted)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &osbc.UpdateInstanceResponse{
+		Async:        statusCode == http.StatusAccepted,
+		DashboardURL: responseBody.DashboardURL,
+		OperationKey: operationKey(responseBody.Operation),
+	}, responseBody.Metadata, nil
+}
+
+// BindWithPredecessor creates the binding as a successor of the predecessor binding, if it is not empty, and returns
+// the metadata of the binding returned by the broker
+func (c *osbAPIClient) BindWithPredecessor(r *osbc.BindRequest, predecessorBindingID string) (*osbc.BindResponse, json.RawMessage, error) {
+	if c.sentByLibrary(len(predecessorBindingID) != 0) {
+		response, err := c.Client.Bind(r)
+		return response, nil, err
+	}
+
+	body := &bindRequestBody{
+		ServiceID:            r.ServiceID,
+		PlanID:               r.PlanID,
+		Parameters:           r.Parameters,
+		Context:              r.Context,
+		PredecessorBindingID: predecessorBindingID,
+	}
+	if r.BindResource != nil {
+		body.BindResource = map[string]interface{}{}
+		if r.BindResource.AppGUID != nil {
+			body.BindResource["app_guid"] = *r.BindResource.AppGUID
+		}
+		if r.BindResource.Route != nil {
+			body.BindResource["route"] = *r.BindResource.Route
+		}
+	}
+	url := fmt.Sprintf(serviceBindingURLFormat, c.broker.BrokerURL, r.InstanceID, r.BindingID)
+	responseBody := &bindingResponseBody{}
+	statusCode, err := c.send(http.MethodPut, url, r.AcceptsIncomplete, body, r.OriginatingIdentity, responseBody,
+		http.StatusOK, http.StatusCreated, http.StatusAccepted)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &osbc.BindResponse{
+		Async:           statusCode == http.StatusAccepted,
+		Credentials:     responseBody.Credentials,
+		SyslogDrainURL:  responseBody.SyslogDrainURL,
+		RouteServiceURL: responseBody.RouteServiceURL,
+		VolumeMounts:    responseBody.VolumeMounts,
+		OperationKey:    operationKey(responseBody.Operation),
+	}, responseBody.Metadata, nil
+}
+
+// GetBindingWithMetadata fetches the binding and returns its metadata returned by the broker
+func (c *osbAPIClient) GetBindingWithMetadata(r *osbc.GetBindingRequest) (*osbc.GetBindingResponse, json.RawMessage, error) {
+	if c.sentByLibrary(false) {
+		response, err := c.Client.GetBinding(r)
+		return response, nil, err
+	}
+
+	url := fmt.Sprintf(serviceBindingURLFormat, c.broker.BrokerURL, r.InstanceID, r.BindingID)
+	responseBody := &bindingResponseBody{}
+	if _, err := c.send(http.MethodGet, url, false, nil, nil, responseBody, http.StatusOK); err != nil {
+		return nil, nil, err
+	}
+	return &osbc.GetBindingResponse{
+		Credentials:     responseBody.Credentials,
+		SyslogDrainURL:  responseBody.SyslogDrainURL,
+		RouteServiceURL: responseBody.RouteServiceURL,
+		VolumeMounts:    responseBody.VolumeMounts,
+		Parameters:      responseBody.Parameters,
+	}, responseBody.Metadata, nil
+}
+
+// send sends the request to the broker and decodes the response body if the response status is one of the success
+// statuses, otherwise it returns an osbc.HTTPStatusCodeError as the OSB client library
+func (c *osbAPIClient) send(method, url string, acceptsIncomplete bool, body interface{}, identity *osbc.OriginatingIdentity, responseBody interface{}, successStatuses ...int) (int, error) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+
+	brokerClient, err := client.NewBrokerClient(c.broker, util.ClientRequest, ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	params := map[string]string{}
+	if acceptsIncomplete {
+		params[osbc.AcceptsIncomplete] = "true"
+	}
+	headers := map[string]string{
+		osbc.APIVersionHeader: c.apiVersion(),
+	}
+	if identity != nil {
+		headers[osbc.OriginatingIdentityHeader] = fmt.Sprintf("%s %s", identity.Platform, base64.StdEncoding.EncodeToString([]byte(identity.Value)))
+	}
+
+	if c.circuits != nil {
+		if err := c.circuits.Allow(c.broker); err != nil {
+			return 0, err
+		}
+	}
+	statusCode, err := c.sendRequest(ctx, brokerClient, method, url, params, body, headers, acceptsIncomplete, responseBody, successStatuses...)
+	if c.circuits != nil {
+		recordBrokerResult(ctx, c.circuits, c.broker, err)
+	}
+	return statusCode, err
+}
+
+func (c *osbAPIClient) sendRequest(ctx context.Context, brokerClient *client.BrokerClient, method, url string, params map[string]string, body interface{}, headers map[string]string, acceptsIncomplete bool, responseBody interface{}, successStatuses ...int) (int, error) {
+	response, err := brokerClient.SendRequest(ctx, method, url, params, body, headers)
+	if err != nil {
+		return 0, err
+	}
+	responseBytes, err := util.BodyToBytes(response.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, status := range successStatuses {
+		if response.StatusCode != status {
+			continue
+		}
+		if status == http.StatusAccepted && !acceptsIncomplete {
+			break
+		}
+		if len(strings.TrimSpace(string(responseBytes))) != 0 {
+			if err := json.Unmarshal(responseBytes, responseBody); err != nil {
+				return 0, osbc.HTTPStatusCodeError{StatusCode: response.StatusCode, ResponseError: err}
+			}
+		}
+		return response.StatusCode, nil
+	}
+
+	httpErr := osbc.HTTPStatusCodeError{StatusCode: response.StatusCode}
+	brokerResponse := make(map[string]interface{})
+	if err := json.Unmarshal(responseBytes, &brokerResponse); err != nil {
+		httpErr.ResponseError = err
+		return 0, httpErr
+	}
+	if errorMessage, ok := brokerResponse["error"].(string); ok {
+		httpErr.ErrorMessage = &errorMessage
+	}
+	if description, ok := brokerResponse["description"].(string); ok {
+		httpErr.Description = &description
+	}
+	return 0, httpErr
+}
+
+func operationKey(operation *string) *osbc.OperationKey {
+	if operation == nil {
+		return nil
+	}
+	key := osbc.OperationKey(*operation)
+	return &key
+}
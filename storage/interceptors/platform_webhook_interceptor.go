@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	PlatformWebhookCreateInterceptorName = "PlatformWebhookCreateInterceptor"
+	PlatformWebhookUpdateInterceptorName = "PlatformWebhookUpdateInterceptor"
+)
+
+// PlatformWebhookCreateInterceptorProvider provides an interceptor that starts the webhook delivery of new platforms
+// from the current notifications
+type PlatformWebhookCreateInterceptorProvider struct {
+}
+
+func (*PlatformWebhookCreateInterceptorProvider) Name() string {
+	return PlatformWebhookCreateInterceptorName
+}
+
+func (*PlatformWebhookCreateInterceptorProvider) Provide() storage.CreateOnTxInterceptor {
+	return &platformWebhookInterceptor{}
+}
+
+// PlatformWebhookUpdateInterceptorProvider provides an interceptor that starts the webhook delivery of platforms
+// which have been configured with a webhook from the current notifications
+type PlatformWebhookUpdateInterceptorProvider struct {
+}
+
+func (*PlatformWebhookUpdateInterceptorProvider) Name() string {
+	return PlatformWebhookUpdateInterceptorName
+}
+
+func (*PlatformWebhookUpdateInterceptorProvider) Provide() storage.UpdateOnTxInterceptor {
+	return &platformWebhookInterceptor{}
+}
+
+// platformWebhookInterceptor sets the delivery cursor of a platform webhook to the revision of the last notification
+// when the webhook is configured, so that the notifications stored before are not delivered to it
+type platformWebhookInterceptor struct {
+}
+
+func (*platformWebhookInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, obj types.Object) (types.Object, error) {
+		platform := obj.(*types.Platform)
+		if platform.Webhook != nil {
+			if err := startWebhookDelivery(ctx, repository, platform); err != nil {
+				return nil, err
+			}
+		}
+		return h(ctx, repository, obj)
+	}
+}
+
+func (*platformWebhookInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, oldObj, newObj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		oldPlatform := oldObj.(*types.Platform)
+		newPlatform := newObj.(*types.Platform)
+		if oldPlatform.Webhook == nil && newPlatform.Webhook != nil {
+			if err := startWebhookDelivery(ctx, repository, newPlatform); err != nil {
+				return nil, err
+			}
+		}
+		return h(ctx, repository, oldObj, newObj, labelChanges...)
+	}
+}
+
+func startWebhookDelivery(ctx context.Context, repository storage.Repository, platform *types.Platform) error {
+	notifications, err := repository.List(ctx, types.NotificationType,
+		query.OrderResultBy("revision", query.DescOrder),
+		query.LimitResultBy(1))
+	if err != nil {
+		return err
+	}
+
+	platform.WebhookStatus = &types.WebhookStatus{}
+	if notifications.Len() > 0 {
+		platform.WebhookStatus.Revision = notifications.ItemAt(0).(*types.Notification).Revision
+	}
+	return nil
+}
@@ -70,6 +70,11 @@ func (c *generatePlatformCredentialsInterceptor) AroundTxCreate(h storage.Interc
 			return h(ctx, obj)
 		}
 
+		if platform.Credentials != nil && web.ShouldKeepPlatformCredentials(ctx) {
+			log.C(ctx).Infof("Keeping the provided credentials of platform %s", platform.ID)
+			return h(ctx, obj)
+		}
+
 		if err := generateCredentials(ctx, platform); err != nil {
 			return nil, err
 		}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/Peripli/service-manager/operations/opcontext"
 
+	"github.com/Peripli/service-manager/operations"
 	"github.com/Peripli/service-manager/pkg/log"
 
 	"github.com/Peripli/service-manager/pkg/query"
@@ -52,6 +53,7 @@ type bindResponseDetails struct {
 	SyslogDrainURL  *string
 	RouteServiceURL *string
 	VolumeMounts    []interface{}
+	Metadata        json.RawMessage
 }
 
 func (p *ServiceBindingCreateInterceptorProvider) Provide() storage.CreateAroundTxInterceptor {
@@ -61,6 +63,7 @@ func (p *ServiceBindingCreateInterceptorProvider) Provide() storage.CreateAround
 		tenantKey:           p.TenantKey,
 		pollingInterval:     p.PollingInterval,
 		contextSigner:       p.ContextSigner,
+		brokerCircuits:      p.BrokerCircuits,
 	}
 }
 
@@ -94,6 +97,7 @@ type ServiceBindingInterceptor struct {
 	tenantKey           string
 	pollingInterval     time.Duration
 	contextSigner       *osb.ContextSigner
+	brokerCircuits      *operations.BrokerCircuits
 }
 
 func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
@@ -136,7 +140,7 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 			binding.Context = instance.Context
 		}
 
-		osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, instance)
+		osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, i.brokerCircuits, operation, instance)
 		if err != nil {
 			return nil, err
 		}
@@ -179,6 +183,18 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 
 		var bindResponse *osbc.BindResponse
 		if !operation.Reschedule {
+			if len(binding.PredecessorBindingID) != 0 {
+				if err := i.validatePredecessorBinding(ctx, osbClient, binding); err != nil {
+					return nil, err
+				}
+				if len(binding.Parameters) == 0 && service.BindingsRetrievable {
+					if err := i.copyPredecessorParameters(ctx, osbClient, instance, binding); err != nil {
+						return nil, err
+					}
+				}
+			}
+			// the metadata of the binding is provided only by the broker
+			binding.Metadata = nil
 			operation.Context.ServiceInstanceID = binding.ServiceInstanceID
 			bindRequest, err := i.prepareBindRequest(ctx, instance, binding, service.CatalogID, plan.CatalogID, service.BindingsRetrievable, operation.GetUserInfo())
 			if err != nil {
@@ -191,7 +207,8 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 			binding.Context = contextBytes
 
 			log.C(ctx).Infof("Sending bind request %s to broker with name %s", logBindRequest(bindRequest), broker.Name)
-			bindResponse, err = osbClient.Bind(bindRequest)
+			var metadata json.RawMessage
+			bindResponse, metadata, err = osbClient.BindWithPredecessor(bindRequest, binding.PredecessorBindingID)
 			if err != nil {
 				brokerError := &util.HTTPError{
 					ErrorType:   "BrokerError",
@@ -226,6 +243,7 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 				SyslogDrainURL:  bindResponse.SyslogDrainURL,
 				RouteServiceURL: bindResponse.RouteServiceURL,
 				VolumeMounts:    bindResponse.VolumeMounts,
+				Metadata:        metadata,
 			}
 			if err := i.enrichBindingWithBindingResponse(binding, bindResponseDetails); err != nil {
 				return nil, fmt.Errorf("could not enrich binding details with binding response details: %s", err)
@@ -265,7 +283,7 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 
 			object, err := f(ctx, obj)
 			if err != nil {
-				return nil, err
+				return nil, scheduleOrphanMitigationIfQuotaExceeded(ctx, i.repository, operation, err)
 			}
 			binding = object.(*types.ServiceBinding)
 		}
@@ -354,7 +372,7 @@ func (i *ServiceBindingInterceptor) deleteSingleBinding(ctx context.Context, bin
 		binding.Context = instance.Context
 	}
 
-	osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, instance)
+	osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, i.brokerCircuits, operation, instance)
 	if err != nil {
 		return err
 	}
@@ -550,7 +568,7 @@ func prepareUnbindRequest(instance *types.ServiceInstance, binding *types.Servic
 	return unbindRequest
 }
 
-func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbClient osbc.Client, binding *types.ServiceBinding, instance *types.ServiceInstance, plan *types.ServicePlan, operation *types.Operation, brokerID, serviceCatalogID, planCatalogID, operationKey string, enableOrphanMitigation bool) error {
+func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbClient *osbAPIClient, binding *types.ServiceBinding, instance *types.ServiceInstance, plan *types.ServicePlan, operation *types.Operation, brokerID, serviceCatalogID, planCatalogID, operationKey string, enableOrphanMitigation bool) error {
 	var key *osbc.OperationKey
 	if len(operation.ExternalID) != 0 {
 		opKey := osbc.OperationKey(operation.ExternalID)
@@ -688,13 +706,13 @@ func (i *ServiceBindingInterceptor) processMaxPollingDurationElapsed(ctx context
 	}
 }
 
-func (i *ServiceBindingInterceptor) getBindingDetailsFromBroker(ctx context.Context, binding *types.ServiceBinding, operation *types.Operation, brokerID string, osbClient osbc.Client, instance *types.ServiceInstance) (*bindResponseDetails, error) {
+func (i *ServiceBindingInterceptor) getBindingDetailsFromBroker(ctx context.Context, binding *types.ServiceBinding, operation *types.Operation, brokerID string, osbClient *osbAPIClient, instance *types.ServiceInstance) (*bindResponseDetails, error) {
 	getBindingRequest := &osbc.GetBindingRequest{
 		InstanceID: instance.GetID(),
 		BindingID:  binding.ID,
 	}
 	log.C(ctx).Infof("Sending get binding request %s to broker with id %s", logGetBindingRequest(getBindingRequest), brokerID)
-	bindingResponse, err := osbClient.GetBinding(getBindingRequest)
+	bindingResponse, metadata, err := osbClient.GetBindingWithMetadata(getBindingRequest)
 	if err != nil {
 		brokerError := &util.HTTPError{
 			ErrorType:   "BrokerError",
@@ -720,6 +738,7 @@ func (i *ServiceBindingInterceptor) getBindingDetailsFromBroker(ctx context.Cont
 		SyslogDrainURL:  bindingResponse.SyslogDrainURL,
 		RouteServiceURL: bindingResponse.RouteServiceURL,
 		VolumeMounts:    bindingResponse.VolumeMounts,
+		Metadata:        metadata,
 	}
 
 	return bindResponseDetails, nil
@@ -752,6 +771,57 @@ func (i *ServiceBindingInterceptor) enrichBindingWithBindingResponse(binding *ty
 		binding.VolumeMounts = volumeMountBytes
 	}
 
+	if len(response.Metadata) != 0 {
+		binding.Metadata = response.Metadata
+	}
+
+	return nil
+}
+
+// copyPredecessorParameters sets the parameters of the predecessor binding, as fetched from the broker, as the
+// parameters of its successor
+func (i *ServiceBindingInterceptor) copyPredecessorParameters(ctx context.Context, osbClient *osbAPIClient, instance *types.ServiceInstance, binding *types.ServiceBinding) error {
+	getBindingRequest := &osbc.GetBindingRequest{
+		InstanceID: instance.GetID(),
+		BindingID:  binding.PredecessorBindingID,
+	}
+	log.C(ctx).Infof("Sending get binding request %s to broker to fetch the parameters of the predecessor binding", logGetBindingRequest(getBindingRequest))
+	response, _, err := osbClient.GetBindingWithMetadata(getBindingRequest)
+	if err != nil {
+		return &util.HTTPError{
+			ErrorType:   "BrokerError",
+			Description: fmt.Sprintf("could not fetch the parameters of predecessor binding %s from the broker: %s", binding.PredecessorBindingID, err),
+			StatusCode:  http.StatusBadGateway,
+		}
+	}
+	binding.Parameters = response.Parameters
+	return nil
+}
+
+// validatePredecessorBinding verifies that the broker supports binding rotation and that the predecessor binding
+// is a binding of the same instance
+func (i *ServiceBindingInterceptor) validatePredecessorBinding(ctx context.Context, osbClient *osbAPIClient, binding *types.ServiceBinding) error {
+	if err := osbClient.validate("predecessor_binding_id", osb.APIVersion2_17); err != nil {
+		return err
+	}
+	predecessor, err := i.repository.Get(ctx, types.ServiceBindingType, query.ByField(query.EqualsOperator, "id", binding.PredecessorBindingID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: fmt.Sprintf("predecessor binding %s not found", binding.PredecessorBindingID),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		return util.HandleStorageError(err, types.ServiceBindingType.String())
+	}
+	if predecessor.(*types.ServiceBinding).ServiceInstanceID != binding.ServiceInstanceID {
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("predecessor binding %s is not a binding of instance %s", binding.PredecessorBindingID, binding.ServiceInstanceID),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
 	return nil
 }
 
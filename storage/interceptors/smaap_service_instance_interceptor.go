@@ -30,6 +30,7 @@ import (
 
 	"github.com/tidwall/sjson"
 
+	"github.com/Peripli/service-manager/operations"
 	"github.com/Peripli/service-manager/operations/opcontext"
 	"github.com/Peripli/service-manager/pkg/util"
 
@@ -54,6 +55,7 @@ type BaseSMAAPInterceptorProvider struct {
 	TenantKey           string
 	PollingInterval     time.Duration
 	ContextSigner       *osb.ContextSigner
+	BrokerCircuits      *operations.BrokerCircuits
 }
 
 // ServiceInstanceCreateInterceptorProvider provides an interceptor that notifies the actual broker about instance creation
@@ -68,6 +70,7 @@ func (p *ServiceInstanceCreateInterceptorProvider) Provide() storage.CreateAroun
 		tenantKey:           p.TenantKey,
 		pollingInterval:     p.PollingInterval,
 		contextSigner:       p.ContextSigner,
+		brokerCircuits:      p.BrokerCircuits,
 	}
 }
 
@@ -89,6 +92,7 @@ func (p *ServiceInstanceUpdateInterceptorProvider) Provide() storage.UpdateAroun
 		tenantKey:           p.TenantKey,
 		pollingInterval:     p.PollingInterval,
 		contextSigner:       p.ContextSigner,
+		brokerCircuits:      p.BrokerCircuits,
 	}
 }
 
@@ -122,6 +126,7 @@ type ServiceInstanceInterceptor struct {
 	tenantKey           string
 	pollingInterval     time.Duration
 	contextSigner       *osb.ContextSigner
+	brokerCircuits      *operations.BrokerCircuits
 }
 
 func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
@@ -158,7 +163,7 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 			return object, err
 		}
 
-		osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, instance)
+		osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, i.brokerCircuits, operation, instance)
 
 		if err != nil {
 			return nil, err
@@ -174,13 +179,21 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 
 		var provisionResponse *osbc.ProvisionResponse
 		if !operation.Reschedule {
+			if len(instance.MaintenanceInfo) != 0 {
+				if err := osbClient.validate("maintenance_info", osb.APIVersion2_15); err != nil {
+					return nil, err
+				}
+			}
+			// the metadata of the instance is provided only by the broker
+			instance.Metadata = nil
 			operation.Context.ServicePlanID = instance.ServicePlanID
 			provisionRequest, err := i.prepareProvisionRequest(ctx, instance, service.CatalogID, plan.CatalogID, operation.GetUserInfo())
 			if err != nil {
 				return nil, fmt.Errorf("failed to prepare provision request: %s", err)
 			}
 			log.C(ctx).Infof("Sending provision request %s to broker with name %s", logProvisionRequest(provisionRequest), broker.Name)
-			provisionResponse, err = osbClient.ProvisionInstance(provisionRequest)
+			var metadata json.RawMessage
+			provisionResponse, metadata, err = osbClient.ProvisionInstanceWithMaintenanceInfo(provisionRequest, instance.MaintenanceInfo)
 			if err != nil {
 				brokerError := &util.HTTPError{
 					ErrorType:   "BrokerError",
@@ -213,6 +226,7 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 				dashboardURL := *provisionResponse.DashboardURL
 				instance.DashboardURL = dashboardURL
 			}
+			instance.Metadata = metadata
 
 			if provisionResponse.Async {
 				log.C(ctx).Infof("Successful asynchronous provisioning request %s to broker %s returned response %s",
@@ -238,7 +252,7 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 
 			object, err := f(ctx, obj)
 			if err != nil {
-				return nil, err
+				return nil, scheduleOrphanMitigationIfQuotaExceeded(ctx, i.repository, operation, err)
 			}
 			instance = object.(*types.ServiceInstance)
 		}
@@ -280,7 +294,7 @@ func (i *ServiceInstanceInterceptor) AroundTxUpdate(f storage.InterceptUpdateAro
 			return UpdatedObject, err
 		}
 
-		osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, updatedInstance)
+		osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, i.brokerCircuits, operation, updatedInstance)
 		if err != nil {
 			return nil, err
 		}
@@ -325,13 +339,23 @@ func (i *ServiceInstanceInterceptor) AroundTxUpdate(f storage.InterceptUpdateAro
 				}
 			}
 			oldServicePlan := oldServicePlanObj.(*types.ServicePlan)
+
+			// maintenance_info is sent to the broker only if it changed, as it requests an upgrade of the instance
+			var maintenanceInfo json.RawMessage
+			if len(updatedInstance.MaintenanceInfo) != 0 && !jsonEqual(updatedInstance.MaintenanceInfo, instance.MaintenanceInfo) {
+				if err := osbClient.validate("maintenance_info", osb.APIVersion2_15); err != nil {
+					return nil, err
+				}
+				maintenanceInfo = updatedInstance.MaintenanceInfo
+			}
 			var updateInstanceResponse *osbc.UpdateInstanceResponse
 			updateInstanceRequest, err := i.prepareUpdateInstanceRequest(ctx, updatedInstance, service.CatalogID, plan.CatalogID, oldServicePlan.CatalogID, operation.GetUserInfo())
 			if err != nil {
 				return nil, fmt.Errorf("faied to prepare update instance request: %s", err)
 			}
 			log.C(ctx).Infof("Sending update instance request %s to broker with name %s", logUpdateInstanceRequest(updateInstanceRequest), broker.Name)
-			updateInstanceResponse, err = osbClient.UpdateInstance(updateInstanceRequest)
+			var metadata json.RawMessage
+			updateInstanceResponse, metadata, err = osbClient.UpdateInstanceWithMaintenanceInfo(updateInstanceRequest, maintenanceInfo, instance.MaintenanceInfo)
 			if err != nil {
 				brokerError := &util.HTTPError{
 					ErrorType:   "BrokerError",
@@ -349,6 +373,11 @@ func (i *ServiceInstanceInterceptor) AroundTxUpdate(f storage.InterceptUpdateAro
 				dashboardURL := *updateInstanceResponse.DashboardURL
 				updatedInstance.DashboardURL = dashboardURL
 			}
+			// the metadata of the instance is provided only by the broker
+			updatedInstance.Metadata = instance.Metadata
+			if len(metadata) != 0 {
+				updatedInstance.Metadata = metadata
+			}
 			instance.UpdateValues = types.InstanceUpdateValues{
 				ServiceInstance: updatedInstance,
 				LabelChanges:    labelChanges,
@@ -480,7 +509,7 @@ func (i *ServiceInstanceInterceptor) deleteSingleInstance(ctx context.Context, i
 		return nil
 	}
 
-	osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, instance)
+	osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, i.brokerCircuits, operation, instance)
 	if err != nil {
 		return err
 	}
@@ -710,7 +739,10 @@ func (i *ServiceInstanceInterceptor) processMaxPollingDurationElapsed(ctx contex
 	}
 }
 
-func preparePrerequisites(ctx context.Context, repository storage.Repository, osbClientFunc osbc.CreateFunc, instance *types.ServiceInstance) (osbc.Client, *types.ServiceBroker, *types.ServiceOffering, *types.ServicePlan, error) {
+// preparePrerequisites returns the OSB client of the broker of the instance with the broker, service and plan of the
+// instance. The broker is stored in the context of the operation, so that the operation is not retried while the
+// circuit of the broker is open.
+func preparePrerequisites(ctx context.Context, repository storage.Repository, osbClientFunc osbc.CreateFunc, brokerCircuits *operations.BrokerCircuits, operation *types.Operation, instance *types.ServiceInstance) (*osbAPIClient, *types.ServiceBroker, *types.ServiceOffering, *types.ServicePlan, error) {
 	planObject, err := repository.Get(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "id", instance.ServicePlanID))
 	if err != nil {
 		return nil, nil, nil, nil, util.HandleStorageError(err, types.ServicePlanType.String())
@@ -728,6 +760,9 @@ func preparePrerequisites(ctx context.Context, repository storage.Repository, os
 		return nil, nil, nil, nil, util.HandleStorageError(err, types.ServiceBrokerType.String())
 	}
 	broker := brokerObject.(*types.ServiceBroker)
+	if operation != nil && operation.Context != nil {
+		operation.Context.BrokerID = broker.ID
+	}
 
 	tlsConfig, err := broker.GetTLSConfig(log.C(ctx))
 	if err != nil {
@@ -758,7 +793,7 @@ func preparePrerequisites(ctx context.Context, repository storage.Repository, os
 		return nil, nil, nil, nil, err
 	}
 
-	return osbClient, broker, service, plan, nil
+	return newOSBAPIClient(ctx, newTracingOSBClient(ctx, osbClient, broker), broker, brokerCircuits), broker, service, plan, nil
 }
 
 func (i *ServiceInstanceInterceptor) prepareProvisionRequest(ctx context.Context, instance *types.ServiceInstance, serviceCatalogID, planCatalogID string, userInfo *types.UserInfo) (*osbc.ProvisionRequest, error) {
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/operations/opcontext"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/quota"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	TenantQuotaInstanceCreateInterceptorName     = "TenantQuotaInstanceCreateInterceptor"
+	TenantQuotaInstanceCreateOnTxInterceptorName = "TenantQuotaInstanceCreateOnTxInterceptor"
+	TenantQuotaBindingCreateInterceptorName      = "TenantQuotaBindingCreateInterceptor"
+	TenantQuotaBindingCreateOnTxInterceptorName  = "TenantQuotaBindingCreateOnTxInterceptor"
+)
+
+func checkInstanceQuota(ctx context.Context, checker *quota.Checker, repository storage.Repository, obj types.Object, lock bool) error {
+	instance := obj.(*types.ServiceInstance)
+	return checker.CheckInstance(ctx, repository, checker.TenantOf(instance), instance.ServicePlanID, lock)
+}
+
+func checkBindingQuota(ctx context.Context, checker *quota.Checker, repository storage.Repository, obj types.Object, lock bool) error {
+	return checker.CheckBinding(ctx, repository, obj.(*types.ServiceBinding).ServiceInstanceID, lock)
+}
+
+// TenantQuotaInstanceCreateInterceptorProvider provides an interceptor that forbids the creation of service instances
+// exceeding the quota of the tenant before they are created with the broker
+type TenantQuotaInstanceCreateInterceptorProvider struct {
+	TenantIdentifier string
+	Repository       storage.Repository
+}
+
+func (p *TenantQuotaInstanceCreateInterceptorProvider) Name() string {
+	return TenantQuotaInstanceCreateInterceptorName
+}
+
+func (p *TenantQuotaInstanceCreateInterceptorProvider) Provide() storage.CreateAroundTxInterceptor {
+	return &tenantQuotaInterceptor{
+		checker:    quota.NewChecker(p.TenantIdentifier),
+		repository: p.Repository,
+		check:      checkInstanceQuota,
+	}
+}
+
+// TenantQuotaInstanceCreateOnTxInterceptorProvider provides an interceptor that forbids the creation of service
+// instances exceeding the quota of the tenant in the transaction storing them
+type TenantQuotaInstanceCreateOnTxInterceptorProvider struct {
+	TenantIdentifier string
+}
+
+func (p *TenantQuotaInstanceCreateOnTxInterceptorProvider) Name() string {
+	return TenantQuotaInstanceCreateOnTxInterceptorName
+}
+
+func (p *TenantQuotaInstanceCreateOnTxInterceptorProvider) Provide() storage.CreateOnTxInterceptor {
+	return &tenantQuotaInterceptor{
+		checker: quota.NewChecker(p.TenantIdentifier),
+		check:   checkInstanceQuota,
+	}
+}
+
+// TenantQuotaBindingCreateInterceptorProvider provides an interceptor that forbids the creation of service bindings
+// exceeding the quota of the tenant owning the service instance before they are created with the broker
+type TenantQuotaBindingCreateInterceptorProvider struct {
+	TenantIdentifier string
+	Repository       storage.Repository
+}
+
+func (p *TenantQuotaBindingCreateInterceptorProvider) Name() string {
+	return TenantQuotaBindingCreateInterceptorName
+}
+
+func (p *TenantQuotaBindingCreateInterceptorProvider) Provide() storage.CreateAroundTxInterceptor {
+	return &tenantQuotaInterceptor{
+		checker:    quota.NewChecker(p.TenantIdentifier),
+		repository: p.Repository,
+		check:      checkBindingQuota,
+	}
+}
+
+// TenantQuotaBindingCreateOnTxInterceptorProvider provides an interceptor that forbids the creation of service
+// bindings exceeding the quota of the tenant owning the service instance in the transaction storing them
+type TenantQuotaBindingCreateOnTxInterceptorProvider struct {
+	TenantIdentifier string
+}
+
+func (p *TenantQuotaBindingCreateOnTxInterceptorProvider) Name() string {
+	return TenantQuotaBindingCreateOnTxInterceptorName
+}
+
+func (p *TenantQuotaBindingCreateOnTxInterceptorProvider) Provide() storage.CreateOnTxInterceptor {
+	return &tenantQuotaInterceptor{
+		checker: quota.NewChecker(p.TenantIdentifier),
+		check:   checkBindingQuota,
+	}
+}
+
+// tenantQuotaInterceptor checks the quota before the resource is created with the broker, so that no resources
+// exceeding the quota are provisioned, and once more in the transaction creating the resource with the quota of the
+// tenant locked, so that concurrent requests cannot exceed it. The second check is provided separately, so that
+// it also runs in the transactions of the OSB API, which create the resources after the broker has provisioned them.
+type tenantQuotaInterceptor struct {
+	checker    *quota.Checker
+	repository storage.Repository
+	check      func(ctx context.Context, checker *quota.Checker, repository storage.Repository, obj types.Object, lock bool) error
+}
+
+func (i *tenantQuotaInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		if operation, found := opcontext.Get(ctx); found && operation.Reschedule {
+			log.C(ctx).Infof("skipping quota check of %s with id %s as this is a rescheduled operation", obj.GetType(), obj.GetID())
+			return h(ctx, obj)
+		}
+		if err := i.check(ctx, i.checker, i.repository, obj, false); err != nil {
+			return nil, err
+		}
+		return h(ctx, obj)
+	}
+}
+
+func (i *tenantQuotaInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return func(ctx context.Context, txStorage storage.Repository, obj types.Object) (types.Object, error) {
+		// repeated requests of existing resources are rejected as conflicting when they are stored
+		_, err := txStorage.Get(ctx, obj.GetType(), query.ByField(query.EqualsOperator, "id", obj.GetID()))
+		if err == nil {
+			return h(ctx, txStorage, obj)
+		}
+		if err != util.ErrNotFoundInStorage {
+			return nil, err
+		}
+		if err := i.check(ctx, i.checker, txStorage, obj, true); err != nil {
+			return nil, err
+		}
+		return h(ctx, txStorage, obj)
+	}
+}
+
+// scheduleOrphanMitigationIfQuotaExceeded marks the operation for orphan mitigation when the resource created by the
+// broker could not be stored because a concurrent request of the tenant consumed the quota after it was checked.
+// It returns the error of storing the resource unless the operation could not be updated.
+func scheduleOrphanMitigationIfQuotaExceeded(ctx context.Context, repository storage.Repository, operation *types.Operation, err error) error {
+	httpErr, ok := err.(*util.HTTPError)
+	if !ok || httpErr.ErrorType != quota.ExceededErrorType {
+		return err
+	}
+	log.C(ctx).Warnf("Tenant quota exceeded after %s with id %s was created by the broker. Scheduling orphan mitigation...", operation.ResourceType, operation.ResourceID)
+	operation.DeletionScheduled = time.Now().UTC()
+	operation.Reschedule = false
+	operation.RescheduleTimestamp = time.Time{}
+	if _, updateErr := repository.Update(ctx, operation, types.LabelChanges{}); updateErr != nil {
+		return fmt.Errorf("failed to update operation with id %s to schedule orphan mitigation after %s: %s", operation.ID, err, updateErr)
+	}
+	return err
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"unsafe"
+
+	"github.com/Peripli/service-manager/pkg/tracing"
+	"github.com/Peripli/service-manager/pkg/types"
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	brokerIDKey   = attribute.Key("sm.broker.id")
+	brokerNameKey = attribute.Key("sm.broker.name")
+)
+
+// tracingOSBClient runs the calls of an OSB client in client spans which are children of the span of the operation.
+// The trace context of the current call is propagated to the broker by the transport of the OSB client.
+type tracingOSBClient struct {
+	osbc.Client
+
+	ctx     context.Context
+	callCtx context.Context
+	broker  *types.ServiceBroker
+}
+
+func newTracingOSBClient(ctx context.Context, client osbc.Client, broker *types.ServiceBroker) osbc.Client {
+	tracingClient := &tracingOSBClient{
+		Client:  client,
+		ctx:     ctx,
+		callCtx: ctx,
+		broker:  broker,
+	}
+	tracingClient.propagateTraceContext()
+	return tracingClient
+}
+
+// propagateTraceContext wraps the transport of the HTTP client of the OSB client, so that the trace context is injected
+// in the headers of the requests to the broker. The OSB client neither exposes its HTTP client nor builds its requests
+// with a context, so the HTTP client is looked up by reflection and the requests get the trace context of the current
+// call.
+func (c *tracingOSBClient) propagateTraceContext() {
+	client := reflect.ValueOf(c.Client)
+	if client.Kind() != reflect.Ptr || client.Elem().Kind() != reflect.Struct {
+		return
+	}
+	field := client.Elem().FieldByName("httpClient")
+	if !field.IsValid() || field.Type() != reflect.TypeOf(&http.Client{}) {
+		return
+	}
+	httpClient := *(**http.Client)(unsafe.Pointer(field.UnsafeAddr()))
+	if httpClient == nil {
+		return
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient.Transport = &traceContextTransport{base: base, client: c}
+}
+
+func (c *tracingOSBClient) GetCatalog() (*osbc.CatalogResponse, error) {
+	span := c.startSpan("GetCatalog")
+	response, err := c.Client.GetCatalog()
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) ProvisionInstance(r *osbc.ProvisionRequest) (*osbc.ProvisionResponse, error) {
+	span := c.startSpan("ProvisionInstance")
+	response, err := c.Client.ProvisionInstance(r)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) UpdateInstance(r *osbc.UpdateInstanceRequest) (*osbc.UpdateInstanceResponse, error) {
+	span := c.startSpan("UpdateInstance")
+	response, err := c.Client.UpdateInstance(r)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) DeprovisionInstance(r *osbc.DeprovisionRequest) (*osbc.DeprovisionResponse, error) {
+	span := c.startSpan("DeprovisionInstance")
+	response, err := c.Client.DeprovisionInstance(r)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) PollLastOperation(r *osbc.LastOperationRequest) (*osbc.LastOperationResponse, error) {
+	span := c.startSpan("PollLastOperation")
+	response, err := c.Client.PollLastOperation(r)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) PollBindingLastOperation(r *osbc.BindingLastOperationRequest) (*osbc.LastOperationResponse, error) {
+	span := c.startSpan("PollBindingLastOperation")
+	response, err := c.Client.PollBindingLastOperation(r)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) Bind(r *osbc.BindRequest) (*osbc.BindResponse, error) {
+	span := c.startSpan("Bind")
+	response, err := c.Client.Bind(r)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) Unbind(r *osbc.UnbindRequest) (*osbc.UnbindResponse, error) {
+	span := c.startSpan("Unbind")
+	response, err := c.Client.Unbind(r)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) GetBinding(r *osbc.GetBindingRequest) (*osbc.GetBindingResponse, error) {
+	span := c.startSpan("GetBinding")
+	response, err := c.Client.GetBinding(r)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (c *tracingOSBClient) startSpan(call string) trace.Span {
+	ctx, span := tracing.StartClientSpan(c.ctx, "broker "+call,
+		brokerIDKey.String(c.broker.ID),
+		brokerNameKey.String(c.broker.Name))
+	c.callCtx = ctx
+	return span
+}
+
+// traceContextTransport propagates the trace context of the current call of the OSB client to the broker
+type traceContextTransport struct {
+	base   http.RoundTripper
+	client *tracingOSBClient
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *traceContextTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// a round tripper should not modify the provided request
+	request = request.Clone(request.Context())
+	otel.GetTextMapPropagator().Inject(t.client.callCtx, propagation.HeaderCarrier(request.Header))
+	return t.base.RoundTrip(request)
+}
@@ -79,6 +79,14 @@ func (c *uniqueBindingNameInterceptor) checkUniqueName(ctx context.Context, bind
 		query.ByField(query.EqualsOperator, "service_instance_id", binding.ServiceInstanceID),
 		query.ByField(query.EqualsOperator, nameProperty, binding.Name),
 	}
+	if len(binding.PredecessorBindingID) > 0 {
+		// the successor of a rotated binding replaces it and the bindings rotated before it, so it can have the same name
+		rotatedBindingIDs, err := c.rotatedBindingIDs(ctx, binding.PredecessorBindingID)
+		if err != nil {
+			return err
+		}
+		countCriteria = append(countCriteria, query.ByField(query.NotInOperator, "id", rotatedBindingIDs...))
+	}
 	bindingCount, err := c.Repository.Count(ctx, types.ServiceBindingType, countCriteria...)
 	if err != nil {
 		return fmt.Errorf("could not get count of service bindings %s", err)
@@ -94,3 +102,23 @@ func (c *uniqueBindingNameInterceptor) checkUniqueName(ctx context.Context, bind
 
 	return nil
 }
+
+// rotatedBindingIDs returns the IDs of the predecessor binding and of the bindings rotated before it which are not
+// deleted yet
+func (c *uniqueBindingNameInterceptor) rotatedBindingIDs(ctx context.Context, predecessorID string) ([]string, error) {
+	ids := make([]string, 0)
+	visited := make(map[string]bool)
+	for id := predecessorID; len(id) != 0 && !visited[id]; {
+		visited[id] = true
+		ids = append(ids, id)
+		object, err := c.Repository.Get(ctx, types.ServiceBindingType, query.ByField(query.EqualsOperator, "id", id))
+		if err != nil {
+			if err == util.ErrNotFoundInStorage {
+				break
+			}
+			return nil, util.HandleStorageError(err, types.ServiceBindingType.String())
+		}
+		id = object.(*types.ServiceBinding).PredecessorBindingID
+	}
+	return ids, nil
+}
@@ -91,8 +91,9 @@ type CreateAroundTxInterceptorChain struct {
 // AroundTxCreate wraps the provided InterceptCreateAroundTxFunc into all the existing aroundTx funcs
 func (c *CreateAroundTxInterceptorChain) AroundTxCreate(f InterceptCreateAroundTxFunc) InterceptCreateAroundTxFunc {
 	for i := range c.aroundTxNames {
-		if interceptor, found := c.aroundTxFuncs[c.aroundTxNames[len(c.aroundTxNames)-1-i]]; found {
-			f = interceptor.AroundTxCreate(f)
+		name := c.aroundTxNames[len(c.aroundTxNames)-1-i]
+		if interceptor, found := c.aroundTxFuncs[name]; found {
+			f = traceCreateAroundTx(name, interceptor.AroundTxCreate(f))
 		}
 	}
 	return f
@@ -106,8 +107,9 @@ type CreateOnTxInterceptorChain struct {
 // OnTxCreate wraps the provided InterceptCreateOnTxFunc into all the existing onTx funcs
 func (c *CreateOnTxInterceptorChain) OnTxCreate(f InterceptCreateOnTxFunc) InterceptCreateOnTxFunc {
 	for i := range c.onTxNames {
-		if interceptor, found := c.onTxFuncs[c.onTxNames[len(c.onTxNames)-1-i]]; found {
-			f = interceptor.OnTxCreate(f)
+		name := c.onTxNames[len(c.onTxNames)-1-i]
+		if interceptor, found := c.onTxFuncs[name]; found {
+			f = traceCreateOnTx(name, interceptor.OnTxCreate(f))
 		}
 	}
 	return f
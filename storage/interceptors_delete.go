@@ -77,8 +77,9 @@ type DeleteAroundTxInterceptorChain struct {
 // AroundTxDelete wraps the provided InterceptDeleteAroundTxFunc into all the existing aroundTx funcs
 func (c *DeleteAroundTxInterceptorChain) AroundTxDelete(f InterceptDeleteAroundTxFunc) InterceptDeleteAroundTxFunc {
 	for i := range c.aroundTxNames {
-		if interceptor, found := c.aroundTxFuncs[c.aroundTxNames[len(c.aroundTxNames)-1-i]]; found {
-			f = interceptor.AroundTxDelete(f)
+		name := c.aroundTxNames[len(c.aroundTxNames)-1-i]
+		if interceptor, found := c.aroundTxFuncs[name]; found {
+			f = traceDeleteAroundTx(name, interceptor.AroundTxDelete(f))
 		}
 	}
 	return f
@@ -92,8 +93,9 @@ type DeleteOnTxInterceptorChain struct {
 // OnTxDelete wraps the provided InterceptDeleteOnTxFunc into all the existing onTx funcs
 func (c *DeleteOnTxInterceptorChain) OnTxDelete(f InterceptDeleteOnTxFunc) InterceptDeleteOnTxFunc {
 	for i := range c.onTxNames {
-		if interceptor, found := c.onTxFuncs[c.onTxNames[len(c.onTxNames)-1-i]]; found {
-			f = interceptor.OnTxDelete(f)
+		name := c.onTxNames[len(c.onTxNames)-1-i]
+		if interceptor, found := c.onTxFuncs[name]; found {
+			f = traceDeleteOnTx(name, interceptor.OnTxDelete(f))
 		}
 	}
 	return f
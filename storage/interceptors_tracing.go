@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/tracing"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// The functions below run each interceptor of a chain in its own tracing span. As every interceptor calls the next one,
+// the spans are nested in the order in which the interceptors are chained.
+
+func traceCreateAroundTx(name string, f InterceptCreateAroundTxFunc) InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		ctx, span := tracing.StartSpan(ctx, "interceptor "+name+" aroundTx create", tracing.ResourceTypeKey.String(obj.GetType().String()))
+		result, err := f(ctx, obj)
+		tracing.End(span, err)
+		return result, err
+	}
+}
+
+func traceCreateOnTx(name string, f InterceptCreateOnTxFunc) InterceptCreateOnTxFunc {
+	return func(ctx context.Context, txStorage Repository, obj types.Object) (types.Object, error) {
+		ctx, span := tracing.StartSpan(ctx, "interceptor "+name+" onTx create", tracing.ResourceTypeKey.String(obj.GetType().String()))
+		result, err := f(ctx, txStorage, obj)
+		tracing.End(span, err)
+		return result, err
+	}
+}
+
+func traceUpdateAroundTx(name string, f InterceptUpdateAroundTxFunc) InterceptUpdateAroundTxFunc {
+	return func(ctx context.Context, newObj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		ctx, span := tracing.StartSpan(ctx, "interceptor "+name+" aroundTx update", tracing.ResourceTypeKey.String(newObj.GetType().String()))
+		result, err := f(ctx, newObj, labelChanges...)
+		tracing.End(span, err)
+		return result, err
+	}
+}
+
+func traceUpdateOnTx(name string, f InterceptUpdateOnTxFunc) InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, txStorage Repository, oldObj, newObj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		ctx, span := tracing.StartSpan(ctx, "interceptor "+name+" onTx update", tracing.ResourceTypeKey.String(newObj.GetType().String()))
+		result, err := f(ctx, txStorage, oldObj, newObj, labelChanges...)
+		tracing.End(span, err)
+		return result, err
+	}
+}
+
+func traceDeleteAroundTx(name string, f InterceptDeleteAroundTxFunc) InterceptDeleteAroundTxFunc {
+	return func(ctx context.Context, deletionCriteria ...query.Criterion) error {
+		ctx, span := tracing.StartSpan(ctx, "interceptor "+name+" aroundTx delete")
+		err := f(ctx, deletionCriteria...)
+		tracing.End(span, err)
+		return err
+	}
+}
+
+func traceDeleteOnTx(name string, f InterceptDeleteOnTxFunc) InterceptDeleteOnTxFunc {
+	return func(ctx context.Context, txStorage Repository, objects types.ObjectList, deletionCriteria ...query.Criterion) error {
+		ctx, span := tracing.StartSpan(ctx, "interceptor "+name+" onTx delete")
+		err := f(ctx, txStorage, objects, deletionCriteria...)
+		tracing.End(span, err)
+		return err
+	}
+}
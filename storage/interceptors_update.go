@@ -91,8 +91,9 @@ type UpdateAroundTxInterceptorChain struct {
 // AroundTxUpdate wraps the provided InterceptUpdateAroundTxFunc into all the existing aroundTx funcs
 func (c *UpdateAroundTxInterceptorChain) AroundTxUpdate(f InterceptUpdateAroundTxFunc) InterceptUpdateAroundTxFunc {
 	for i := range c.aroundTxNames {
-		if interceptor, found := c.aroundTxFuncs[c.aroundTxNames[len(c.aroundTxNames)-1-i]]; found {
-			f = interceptor.AroundTxUpdate(f)
+		name := c.aroundTxNames[len(c.aroundTxNames)-1-i]
+		if interceptor, found := c.aroundTxFuncs[name]; found {
+			f = traceUpdateAroundTx(name, interceptor.AroundTxUpdate(f))
 		}
 	}
 	return f
@@ -106,8 +107,9 @@ type UpdateOnTxInterceptorChain struct {
 // OnTxUpdate wraps the provided InterceptUpdateOnTxFunc into all the existing onTx funcs
 func (c *UpdateOnTxInterceptorChain) OnTxUpdate(f InterceptUpdateOnTxFunc) InterceptUpdateOnTxFunc {
 	for i := range c.onTxNames {
-		if interceptor, found := c.onTxFuncs[c.onTxNames[len(c.onTxNames)-1-i]]; found {
-			f = interceptor.OnTxUpdate(f)
+		name := c.onTxNames[len(c.onTxNames)-1-i]
+		if interceptor, found := c.onTxFuncs[name]; found {
+			f = traceUpdateOnTx(name, interceptor.OnTxUpdate(f))
 		}
 	}
 	return f
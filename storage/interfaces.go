@@ -56,25 +56,37 @@ var (
 	basepath   = path.Dir(b)
 )
 
+const (
+	// PostgresStorage is the type of the storage which persists the data in a PostgreSQL database
+	PostgresStorage = "postgres"
+	// InMemoryStorage is the type of the storage which keeps the data in memory
+	InMemoryStorage = "inmemory"
+)
+
 // Settings type to be loaded from the environment
 type Settings struct {
-	URI                string                `mapstructure:"uri" description:"URI of the storage"`
-	MigrationsURL      string                `mapstructure:"migrations_url" description:"location of a directory containing sql migrations scripts"`
-	EncryptionKey      string                `mapstructure:"encryption_key" description:"key to use for encrypting database entries"`
-	SkipSSLValidation  bool                  `mapstructure:"skip_ssl_validation" description:"whether to skip ssl verification when connecting to the storage"`
-	SSLMode            string                `mapstructure:"sslmode" description:"defines ssl mode type"`
-	SSLRootCert        string                `mapstructure:"sslrootcert" description:"The location of the root certificate file."`
-	MaxIdleConnections int                   `mapstructure:"max_idle_connections" description:"sets the maximum number of connections in the idle connection pool"`
-	MaxOpenConnections int                   `mapstructure:"max_open_connections" description:"sets the maximum number of open connections to the database"`
-	ReadTimeout        int                   `mapstructure:"read_timeout" description:"sets the limit for reading in milliseconds"`
-	WriteTimeout       int                   `mapstructure:"write_timeout" description:"sets the limit for writing in milliseconds"`
-	Notification       *NotificationSettings `mapstructure:"notification"`
-	IntegrityProcessor security.IntegrityProcessor
+	Type                  string                        `mapstructure:"type" description:"type of the storage - postgres or inmemory"`
+	URI                   string                        `mapstructure:"uri" description:"URI of the storage"`
+	MigrationsURL         string                        `mapstructure:"migrations_url" description:"location of a directory containing sql migrations scripts"`
+	EncryptionKey         string                        `mapstructure:"encryption_key" description:"key to use for encrypting database entries"`
+	PreviousEncryptionKey string                        `mapstructure:"previous_encryption_key" description:"previous key used for encrypting database entries. Set it to the replaced key when rotating the encryption_key and remove it once all instances use the new one"`
+	SkipSSLValidation     bool                          `mapstructure:"skip_ssl_validation" description:"whether to skip ssl verification when connecting to the storage"`
+	SSLMode               string                        `mapstructure:"sslmode" description:"defines ssl mode type"`
+	SSLRootCert           string                        `mapstructure:"sslrootcert" description:"The location of the root certificate file."`
+	MaxIdleConnections    int                           `mapstructure:"max_idle_connections" description:"sets the maximum number of connections in the idle connection pool"`
+	MaxOpenConnections    int                           `mapstructure:"max_open_connections" description:"sets the maximum number of open connections to the database"`
+	ReadTimeout           int                           `mapstructure:"read_timeout" description:"sets the limit for reading in milliseconds"`
+	WriteTimeout          int                           `mapstructure:"write_timeout" description:"sets the limit for writing in milliseconds"`
+	Notification          *NotificationSettings         `mapstructure:"notification"`
+	KeyRotation           *KeyRotationSettings          `mapstructure:"key_rotation"`
+	KeyProvider           *security.KeyProviderSettings `mapstructure:"key_provider"`
+	IntegrityProcessor    security.IntegrityProcessor
 }
 
 // DefaultSettings returns default values for storage settings
 func DefaultSettings() *Settings {
 	return &Settings{
+		Type:               PostgresStorage,
 		URI:                "",
 		MigrationsURL:      fmt.Sprintf("file://%s/postgres/migrations", basepath),
 		EncryptionKey:      "",
@@ -84,6 +96,8 @@ func DefaultSettings() *Settings {
 		ReadTimeout:        900000, //15 minutes
 		WriteTimeout:       900000, //15 minutes
 		Notification:       DefaultNotificationSettings(),
+		KeyRotation:        DefaultKeyRotationSettings(),
+		KeyProvider:        security.DefaultKeyProviderSettings(),
 		IntegrityProcessor: &security.HashingIntegrityProcessor{
 			HashingFunc: func(data []byte) []byte {
 				hash := sha256.Sum256(data)
@@ -95,21 +109,61 @@ func DefaultSettings() *Settings {
 
 // Validate validates the storage settings
 func (s *Settings) Validate() error {
-	if len(s.URI) == 0 {
-		return fmt.Errorf("validate Settings: StorageURI missing")
-	}
-	if len(s.MigrationsURL) == 0 {
-		return fmt.Errorf("validate Settings: StorageMigrationsURL missing")
+	switch s.Type {
+	case PostgresStorage, "":
+		if len(s.URI) == 0 {
+			return fmt.Errorf("validate Settings: StorageURI missing")
+		}
+		if len(s.MigrationsURL) == 0 {
+			return fmt.Errorf("validate Settings: StorageMigrationsURL missing")
+		}
+	case InMemoryStorage:
+	default:
+		return fmt.Errorf("validate Settings: unsupported StorageType %s", s.Type)
 	}
 	if len(s.EncryptionKey) != 32 {
 		return fmt.Errorf("validate Settings: StorageEncryptionKey must be exactly 32 symbols long but was %d symbols long", len(s.EncryptionKey))
 	}
+	if len(s.PreviousEncryptionKey) != 0 && len(s.PreviousEncryptionKey) != 32 {
+		return fmt.Errorf("validate Settings: StoragePreviousEncryptionKey must be exactly 32 symbols long but was %d symbols long", len(s.PreviousEncryptionKey))
+	}
 	if s.IntegrityProcessor == nil {
 		return fmt.Errorf("validate Settings: StorageIntegrityProcessor must not be nil")
 	}
+	if err := s.KeyRotation.Validate(); err != nil {
+		return err
+	}
+	if err := s.KeyProvider.Validate(); err != nil {
+		return err
+	}
 	return s.Notification.Validate()
 }
 
+// KeyRotationSettings type to be loaded from the environment
+type KeyRotationSettings struct {
+	BatchSize       int           `mapstructure:"batch_size" description:"number of entities re-encrypted in a single transaction during encryption key rotation"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" description:"time after which the encryption keys are reloaded from the storage, so that keys rotated by other instances are used"`
+}
+
+// DefaultKeyRotationSettings returns default values for the encryption key rotation settings
+func DefaultKeyRotationSettings() *KeyRotationSettings {
+	return &KeyRotationSettings{
+		BatchSize:       100,
+		RefreshInterval: time.Minute,
+	}
+}
+
+// Validate validates the encryption key rotation settings
+func (s *KeyRotationSettings) Validate() error {
+	if s.BatchSize < 1 {
+		return fmt.Errorf("key rotation batch size (%d) should be at least 1", s.BatchSize)
+	}
+	if s.RefreshInterval <= 0 {
+		return fmt.Errorf("key rotation refresh interval (%s) should be greater than 0", s.RefreshInterval)
+	}
+	return nil
+}
+
 // NotificationSettings type to be loaded from the environment
 type NotificationSettings struct {
 	QueuesSize           int           `mapstructure:"queues_size" description:"maximum number of notifications queued for sending to a client"`
@@ -197,6 +251,9 @@ type Repository interface {
 	// Count label values of retrieved objects of particular type in SM DB
 	CountLabelValues(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error)
 
+	// CountGroups retrieves the number of objects of particular type in SM DB grouped by the values of the provided fields and label keys
+	CountGroups(ctx context.Context, objectType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error)
+
 	// Query for list retrieves a list of items using a named query
 	QueryForList(ctx context.Context, objectType types.ObjectType, queryName NamedQuery, queryParams map[string]interface{}) (types.ObjectList, error)
 
@@ -276,6 +333,17 @@ type Notificator interface {
 
 	// RegisterFilter adds a new filter which decides if a platform should receive given notification
 	RegisterFilter(f ReceiversFilterFunc)
+
+	// Stats returns statistics about the registered consumers
+	Stats() NotificatorStats
+}
+
+// NotificatorStats contains statistics about the consumers registered in a Notificator
+type NotificatorStats struct {
+	// ConnectedPlatforms is the number of platforms with at least one registered consumer
+	ConnectedPlatforms int
+	// QueuedNotifications is the number of notifications which are waiting in the queues of the consumers
+	QueuedNotifications int
 }
 
 // ReceiversFilterFunc filters recipients for a given notifications
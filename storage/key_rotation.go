@@ -0,0 +1,226 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const keyRotationLockIndex = 112
+
+// ErrKeyRotationInProgress is returned when an encryption key rotation is started while another one is in progress
+var ErrKeyRotationInProgress = errors.New("encryption key rotation is already in progress")
+
+// securedTypes are the types of the entities whose credentials are encrypted with the encryption key
+var securedTypes = []types.ObjectType{
+	types.ServiceBrokerType,
+	types.PlatformType,
+	types.BrokerPlatformCredentialType,
+	types.ServiceBindingType,
+}
+
+// KeyRotation is the progress of an encryption key rotation
+type KeyRotation struct {
+	State     types.OperationState `json:"state"`
+	StartedAt time.Time            `json:"started_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	// Reencrypted is the number of entities of each type whose credentials are re-encrypted with the new encryption key
+	Reencrypted map[types.ObjectType]int `json:"reencrypted"`
+	Error       string                   `json:"error,omitempty"`
+}
+
+// KeyRotator replaces the encryption key with a new one and re-encrypts the credentials of all entities in batches.
+// While the entities are re-encrypted, their credentials can be decrypted with both the previous and the new
+// encryption key, so that the Service Manager stays online.
+type KeyRotator struct {
+	smCtx        context.Context
+	wg           *sync.WaitGroup
+	repository   TransactionalRepository
+	keyStore     KeyStore
+	keyEncrypter security.Encrypter
+	locker       Locker
+	settings     *KeyRotationSettings
+}
+
+// NewKeyRotator creates a KeyRotator which re-encrypts the entities through the provided encrypting repository. The new
+// encryption key is encrypted in the KeyStore with the keyEncrypter
+func NewKeyRotator(smCtx context.Context, repository TransactionalRepository, keyStore KeyStore, keyEncrypter security.Encrypter, lockerCreatorFunc LockerCreatorFunc, settings *KeyRotationSettings, wg *sync.WaitGroup) *KeyRotator {
+	return &KeyRotator{
+		smCtx:        smCtx,
+		wg:           wg,
+		repository:   repository,
+		keyStore:     keyStore,
+		keyEncrypter: keyEncrypter,
+		locker:       lockerCreatorFunc(keyRotationLockIndex),
+		settings:     settings,
+	}
+}
+
+// Progress returns the progress of the last encryption key rotation or nil if the encryption key was never rotated
+func (kr *KeyRotator) Progress(ctx context.Context) (*KeyRotation, error) {
+	return kr.keyStore.GetKeyRotation(ctx)
+}
+
+// Start starts the encryption key rotation in the background. If the last rotation did not succeed, the entities are
+// re-encrypted with its encryption key instead of generating a new one, as some of them may already use it.
+func (kr *KeyRotator) Start(ctx context.Context) (*KeyRotation, error) {
+	if err := kr.locker.TryLock(kr.smCtx); err != nil {
+		log.C(ctx).WithError(err).Info("Could not acquire the key rotation lock")
+		return nil, ErrKeyRotationInProgress
+	}
+	rotation, err := kr.begin(ctx)
+	if err != nil {
+		kr.unlock()
+		return nil, err
+	}
+
+	kr.wg.Add(1)
+	go func() {
+		defer kr.wg.Done()
+		defer kr.unlock()
+
+		rotateCtx := log.ContextWithLogger(kr.smCtx, log.C(ctx))
+		kr.reencrypt(rotateCtx, rotation)
+	}()
+
+	return rotation, nil
+}
+
+func (kr *KeyRotator) begin(ctx context.Context) (*KeyRotation, error) {
+	lastRotation, err := kr.keyStore.GetKeyRotation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastRotation == nil || lastRotation.State == types.SUCCEEDED {
+		newEncryptionKey := make([]byte, 32)
+		if _, err := rand.Read(newEncryptionKey); err != nil {
+			return nil, fmt.Errorf("could not generate encryption key: %v", err)
+		}
+		if err := kr.keyStore.RotateEncryptionKey(ctx, newEncryptionKey, kr.keyEncrypter.Encrypt); err != nil {
+			return nil, err
+		}
+		log.C(ctx).Info("Successfully generated new encryption key")
+	} else {
+		log.C(ctx).Infof("Last encryption key rotation is in state %s. Resuming it with the current encryption key...", lastRotation.State)
+	}
+
+	now := time.Now()
+	rotation := &KeyRotation{
+		State:       types.IN_PROGRESS,
+		StartedAt:   now,
+		UpdatedAt:   now,
+		Reencrypted: make(map[types.ObjectType]int, len(securedTypes)),
+	}
+	if err := kr.keyStore.SetKeyRotation(ctx, rotation); err != nil {
+		return nil, err
+	}
+	return rotation, nil
+}
+
+func (kr *KeyRotator) reencrypt(ctx context.Context, rotation *KeyRotation) {
+	logger := log.C(ctx)
+	// the other instances use the previous encryption key until they reload the encryption keys
+	logger.Infof("Waiting %s for all instances to load the new encryption key...", kr.settings.RefreshInterval)
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(kr.settings.RefreshInterval):
+	}
+
+	for _, objectType := range securedTypes {
+		if err := kr.reencryptType(ctx, rotation, objectType); err != nil {
+			logger.WithError(err).Errorf("Encryption key rotation failed while re-encrypting %s", objectType)
+			rotation.State = types.FAILED
+			rotation.Error = err.Error()
+			kr.saveProgress(ctx, rotation)
+			return
+		}
+	}
+
+	rotation.State = types.SUCCEEDED
+	kr.saveProgress(ctx, rotation)
+	logger.Infof("Successfully rotated the encryption key. Re-encrypted entities: %v", rotation.Reencrypted)
+}
+
+func (kr *KeyRotator) reencryptType(ctx context.Context, rotation *KeyRotation, objectType types.ObjectType) error {
+	var pagingSequence int64
+	for {
+		batchSize := 0
+		if err := kr.repository.InTransaction(ctx, func(ctx context.Context, repository Repository) error {
+			objects, err := repository.ListNoLabels(ctx, objectType,
+				query.OrderResultBy("paging_sequence", query.AscOrder),
+				query.ByField(query.GreaterThanOperator, "paging_sequence", strconv.FormatInt(pagingSequence, 10)),
+				query.LimitResultBy(kr.settings.BatchSize))
+			if err != nil {
+				return err
+			}
+			for i := 0; i < objects.Len(); i++ {
+				// the entity is locked and read again, so that changes made since it was listed are not overwritten
+				object, err := repository.GetForUpdate(ctx, objectType, query.ByField(query.EqualsOperator, "id", objects.ItemAt(i).GetID()))
+				if err != nil {
+					if err == util.ErrNotFoundInStorage {
+						continue
+					}
+					return err
+				}
+				// the credentials are decrypted with the key they are encrypted with and encrypted with the current one
+				if _, err := repository.Update(ctx, object, types.LabelChanges{}); err != nil {
+					return err
+				}
+			}
+			if batchSize = objects.Len(); batchSize > 0 {
+				pagingSequence = objects.ItemAt(batchSize - 1).GetPagingSequence()
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if batchSize == 0 {
+			return nil
+		}
+
+		rotation.Reencrypted[objectType] += batchSize
+		kr.saveProgress(ctx, rotation)
+		log.C(ctx).Debugf("Re-encrypted %d entities of type %s", rotation.Reencrypted[objectType], objectType)
+	}
+}
+
+func (kr *KeyRotator) saveProgress(ctx context.Context, rotation *KeyRotation) {
+	rotation.UpdatedAt = time.Now()
+	if err := kr.keyStore.SetKeyRotation(ctx, rotation); err != nil {
+		log.C(ctx).WithError(err).Error("Could not store the progress of the encryption key rotation")
+	}
+}
+
+func (kr *KeyRotator) unlock() {
+	if err := kr.locker.Unlock(kr.smCtx); err != nil {
+		log.C(kr.smCtx).WithError(err).Error("Could not release the key rotation lock")
+	}
+}
@@ -0,0 +1,324 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/inmemory"
+	"github.com/gofrs/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	storageEncryptionKey    = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+	newStorageEncryptionKey = "Z9D2xZRHqHJr3vkkEC8Ww1MY5r8YsDvM"
+)
+
+var _ = Describe("Encryption key rotation", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		wg         *sync.WaitGroup
+		encrypter  *security.AESEncrypter
+		settings   *storage.Settings
+		keyStore   *inmemory.Storage
+		repository storage.TransactionalRepository
+		rotator    *storage.KeyRotator
+	)
+
+	openStorage := func(encryptionKey, previousEncryptionKey string) *inmemory.Storage {
+		settings.EncryptionKey = encryptionKey
+		settings.PreviousEncryptionKey = previousEncryptionKey
+		s := &inmemory.Storage{}
+		Expect(s.Open(settings)).To(Succeed())
+		return s
+	}
+
+	lockerCreator := func(s *inmemory.Storage) storage.LockerCreatorFunc {
+		return func(advisoryIndex int) storage.Locker {
+			return &inmemory.Locker{Storage: s, AdvisoryIndex: advisoryIndex}
+		}
+	}
+
+	decorate := func(s *inmemory.Storage, refreshInterval time.Duration) storage.TransactionalRepository {
+		decorator := storage.EncryptingDecorator(ctx, encrypter, encrypter, s, inmemory.EncryptingLocker(s), refreshInterval)
+		decorated, err := decorator(s)
+		Expect(err).ToNot(HaveOccurred())
+		return decorated
+	}
+
+	// createPlatform stores a platform and returns it with its plaintext credentials
+	createPlatform := func(repository storage.Repository) *types.Platform {
+		id, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		platform := &types.Platform{
+			Base: types.Base{
+				ID:        id.String(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Ready:     true,
+			},
+			Type: "kubernetes",
+			Name: id.String(),
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{
+					Username: "admin-" + id.String(),
+					Password: "password-" + id.String(),
+				},
+			},
+		}
+		stored := *platform
+		stored.Credentials = &types.Credentials{Basic: &types.Basic{
+			Username: platform.Credentials.Basic.Username,
+			Password: platform.Credentials.Basic.Password,
+		}}
+		_, err = repository.Create(ctx, &stored)
+		Expect(err).ToNot(HaveOccurred())
+		return platform
+	}
+
+	getPlatform := func(repository storage.Repository, id string) *types.Platform {
+		platform, err := repository.Get(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", id))
+		Expect(err).ToNot(HaveOccurred())
+		return platform.(*types.Platform)
+	}
+
+	// storedPassword returns the password of the platform as stored in the storage
+	storedPassword := func(id string) []byte {
+		platform, err := keyStore.Get(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", id))
+		Expect(err).ToNot(HaveOccurred())
+		return []byte(platform.(*types.Platform).Credentials.Basic.Password)
+	}
+
+	waitForRotation := func() *storage.KeyRotation {
+		var rotation *storage.KeyRotation
+		Eventually(func() types.OperationState {
+			var err error
+			rotation, err = rotator.Progress(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			return rotation.State
+		}).Should(Equal(types.SUCCEEDED))
+		return rotation
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		encrypter = &security.AESEncrypter{}
+
+		uri, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		settings = storage.DefaultSettings()
+		settings.Type = storage.InMemoryStorage
+		settings.URI = uri.String()
+		settings.KeyRotation.RefreshInterval = 10 * time.Millisecond
+
+		keyStore = openStorage(storageEncryptionKey, "")
+		repository = decorate(keyStore, settings.KeyRotation.RefreshInterval)
+		rotator = storage.NewKeyRotator(ctx, repository, keyStore, encrypter, lockerCreator(keyStore), settings.KeyRotation, wg)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	Describe("Start", func() {
+		var platforms []*types.Platform
+		var encryptionKey []byte
+
+		BeforeEach(func() {
+			platforms = nil
+			for i := 0; i < 3; i++ {
+				platforms = append(platforms, createPlatform(repository))
+			}
+			settings.KeyRotation.BatchSize = 2
+
+			var err error
+			encryptionKey, err = keyStore.GetEncryptionKey(ctx, encrypter.Decrypt)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("re-encrypts the credentials with a new encryption key", func() {
+			rotation, err := rotator.Start(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rotation.State).To(Equal(types.IN_PROGRESS))
+
+			rotation = waitForRotation()
+			Expect(rotation.Reencrypted[types.PlatformType]).To(Equal(len(platforms)))
+
+			newEncryptionKey, err := keyStore.GetEncryptionKey(ctx, encrypter.Decrypt)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(newEncryptionKey).ToNot(Equal(encryptionKey))
+			previousEncryptionKey, err := keyStore.GetPreviousEncryptionKey(ctx, encrypter.Decrypt)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(previousEncryptionKey).To(Equal(encryptionKey))
+
+			for _, platform := range platforms {
+				password, err := encrypter.Decrypt(ctx, storedPassword(platform.ID), newEncryptionKey)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(password)).To(Equal(platform.Credentials.Basic.Password))
+				Expect(getPlatform(repository, platform.ID).Credentials.Basic.Password).To(Equal(platform.Credentials.Basic.Password))
+			}
+		})
+
+		It("decrypts the credentials in instances which have not reloaded the encryption keys", func() {
+			staleRepository := decorate(openStorage(storageEncryptionKey, ""), time.Hour)
+
+			_, err := rotator.Start(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			waitForRotation()
+
+			Expect(getPlatform(staleRepository, platforms[0].ID).Credentials.Basic.Password).To(Equal(platforms[0].Credentials.Basic.Password))
+		})
+
+		Context("when the last rotation failed", func() {
+			BeforeEach(func() {
+				Expect(keyStore.SetKeyRotation(ctx, &storage.KeyRotation{State: types.FAILED})).To(Succeed())
+			})
+
+			It("resumes it with the current encryption key", func() {
+				_, err := rotator.Start(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				waitForRotation()
+
+				currentEncryptionKey, err := keyStore.GetEncryptionKey(ctx, encrypter.Decrypt)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(currentEncryptionKey).To(Equal(encryptionKey))
+			})
+		})
+
+		Context("when envelope encryption is enabled", func() {
+			var keysDir string
+
+			BeforeEach(func() {
+				var err error
+				keysDir, err = ioutil.TempDir("", "keys")
+				Expect(err).ToNot(HaveOccurred())
+				keysFile := filepath.Join(keysDir, "keys.json")
+				keys := fmt.Sprintf(`{"key-1": "%s"}`, base64.StdEncoding.EncodeToString([]byte(newStorageEncryptionKey)))
+				Expect(ioutil.WriteFile(keysFile, []byte(keys), 0600)).To(Succeed())
+
+				keyProvider, err := security.NewFileKeyProvider(keysFile, "key-1")
+				Expect(err).ToNot(HaveOccurred())
+				envelopeEncrypter := security.NewEnvelopeEncrypter(keyProvider, security.DefaultKeyProviderSettings())
+				decorator := storage.EncryptingDecorator(ctx, encrypter, envelopeEncrypter, keyStore, inmemory.EncryptingLocker(keyStore), settings.KeyRotation.RefreshInterval)
+				repository, err = decorator(keyStore)
+				Expect(err).ToNot(HaveOccurred())
+				rotator = storage.NewKeyRotator(ctx, repository, keyStore, encrypter, lockerCreator(keyStore), settings.KeyRotation, wg)
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(keysDir)).To(Succeed())
+			})
+
+			It("re-encrypts the existing credentials using data keys from the key provider", func() {
+				Expect(getPlatform(repository, platforms[0].ID).Credentials.Basic.Password).To(Equal(platforms[0].Credentials.Basic.Password))
+
+				_, err := rotator.Start(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				waitForRotation()
+
+				for _, platform := range platforms {
+					Expect(string(storedPassword(platform.ID))).To(And(HavePrefix("sm:envelope:v1:"), ContainSubstring("key-1")))
+					Expect(getPlatform(repository, platform.ID).Credentials.Basic.Password).To(Equal(platform.Credentials.Basic.Password))
+				}
+			})
+		})
+
+		Context("when another rotation is in progress", func() {
+			var locker storage.Locker
+
+			BeforeEach(func() {
+				locker = lockerCreator(keyStore)(112)
+				Expect(locker.TryLock(ctx)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(locker.Unlock(ctx)).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				_, err := rotator.Start(ctx)
+				Expect(err).To(Equal(storage.ErrKeyRotationInProgress))
+			})
+		})
+	})
+
+	Describe("Progress", func() {
+		Context("when the encryption key was never rotated", func() {
+			It("returns nil", func() {
+				rotation, err := rotator.Progress(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rotation).To(BeNil())
+			})
+		})
+	})
+
+	Describe("EncryptingDecorator", func() {
+		var platform *types.Platform
+
+		BeforeEach(func() {
+			platform = createPlatform(repository)
+		})
+
+		Context("when the storage encryption key is replaced", func() {
+			It("re-encrypts the encryption key with the new storage encryption key", func() {
+				rotated := openStorage(newStorageEncryptionKey, storageEncryptionKey)
+				Expect(getPlatform(decorate(rotated, time.Hour), platform.ID).Credentials.Basic.Password).To(Equal(platform.Credentials.Basic.Password))
+
+				// the previous storage encryption key is no longer needed
+				Expect(getPlatform(decorate(openStorage(newStorageEncryptionKey, ""), time.Hour), platform.ID).Credentials.Basic.Password).
+					To(Equal(platform.Credentials.Basic.Password))
+			})
+
+			It("keeps the encryption key readable with the previous storage encryption key until the rotation is finalised", func() {
+				decorate(openStorage(newStorageEncryptionKey, storageEncryptionKey), time.Hour)
+
+				// an instance which has not been redeployed yet
+				Expect(getPlatform(decorate(openStorage(storageEncryptionKey, ""), time.Hour), platform.ID).Credentials.Basic.Password).
+					To(Equal(platform.Credentials.Basic.Password))
+
+				decorate(openStorage(newStorageEncryptionKey, ""), time.Hour)
+				_, err := storage.EncryptingDecorator(ctx, encrypter, encrypter, keyStore, inmemory.EncryptingLocker(keyStore), time.Hour)(keyStore)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the storage encryption key is replaced without providing the previous one", func() {
+			It("returns an error", func() {
+				rotated := openStorage(newStorageEncryptionKey, "")
+				_, err := storage.EncryptingDecorator(ctx, encrypter, encrypter, rotated, inmemory.EncryptingLocker(rotated), time.Hour)(rotated)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
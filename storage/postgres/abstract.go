@@ -251,6 +251,25 @@ func toBoolPointer(nullBool sql.NullBool) *bool {
 	return &nullBool.Bool
 }
 
+func toNullInt64(i *int) sql.NullInt64 {
+	if i == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{
+		Int64: int64(*i),
+		Valid: true,
+	}
+}
+
+func toIntPointer(nullInt sql.NullInt64) *int {
+	if !nullInt.Valid {
+		return nil
+	}
+
+	i := int(nullInt.Int64)
+	return &i
+}
+
 func getJSONText(item json.RawMessage) sqlxtypes.JSONText {
 	if len(item) == len("null") && string(item) == "null" {
 		return sqlxtypes.JSONText("{}")
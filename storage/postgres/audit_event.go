@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+)
+
+// AuditEvent entity
+//go:generate smgen storage AuditEvent github.com/Peripli/service-manager/pkg/types
+type AuditEvent struct {
+	BaseEntity
+	Actor         string             `db:"actor"`
+	Tenant        sql.NullString     `db:"tenant"`
+	AccessLevel   string             `db:"access_level"`
+	Resource      string             `db:"resource"`
+	ResourceID    sql.NullString     `db:"resource_id"`
+	Action        string             `db:"action"`
+	Method        string             `db:"method"`
+	Path          string             `db:"path"`
+	Changes       sqlxtypes.JSONText `db:"changes"`
+	CorrelationID sql.NullString     `db:"correlation_id"`
+	Outcome       string             `db:"outcome"`
+	StatusCode    int                `db:"status_code"`
+}
+
+func (e *AuditEvent) ToObject() (types.Object, error) {
+	return &types.AuditEvent{
+		Base: types.Base{
+			ID:             e.ID,
+			CreatedAt:      e.CreatedAt,
+			UpdatedAt:      e.UpdatedAt,
+			Labels:         map[string][]string{},
+			PagingSequence: e.PagingSequence,
+			Ready:          e.Ready,
+		},
+		Actor:         e.Actor,
+		Tenant:        e.Tenant.String,
+		AccessLevel:   e.AccessLevel,
+		Resource:      types.ObjectType(e.Resource),
+		ResourceID:    e.ResourceID.String,
+		Action:        types.AuditAction(e.Action),
+		Method:        e.Method,
+		Path:          e.Path,
+		Changes:       getJSONRawMessage(e.Changes),
+		CorrelationID: e.CorrelationID.String,
+		Outcome:       types.AuditOutcome(e.Outcome),
+		StatusCode:    e.StatusCode,
+	}, nil
+}
+
+func (*AuditEvent) FromObject(object types.Object) (storage.Entity, error) {
+	event, ok := object.(*types.AuditEvent)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type AuditEvent")
+	}
+
+	return &AuditEvent{
+		BaseEntity: BaseEntity{
+			ID:             event.ID,
+			CreatedAt:      event.CreatedAt,
+			UpdatedAt:      event.UpdatedAt,
+			PagingSequence: event.PagingSequence,
+			Ready:          event.Ready,
+		},
+		Actor:         event.Actor,
+		Tenant:        toNullString(event.Tenant),
+		AccessLevel:   event.AccessLevel,
+		Resource:      string(event.Resource),
+		ResourceID:    toNullString(event.ResourceID),
+		Action:        string(event.Action),
+		Method:        event.Method,
+		Path:          event.Path,
+		Changes:       getJSONText(event.Changes),
+		CorrelationID: toNullString(event.CorrelationID),
+		Outcome:       string(event.Outcome),
+		StatusCode:    event.StatusCode,
+	}, nil
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &AuditEvent{}
+
+const AuditEventTable = "audit_events"
+
+func (*AuditEvent) LabelEntity() PostgresLabel {
+	return &AuditEventLabel{}
+}
+
+func (*AuditEvent) TableName() string {
+	return AuditEventTable
+}
+
+func (e *AuditEvent) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &AuditEventLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		AuditEventID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *AuditEvent) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*AuditEvent
+			AuditEventLabel `db:"audit_event_labels"`
+		}{}
+	}
+	result := &types.AuditEvents{
+		AuditEvents: make([]*types.AuditEvent, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type AuditEventLabel struct {
+	BaseLabelEntity
+	AuditEventID sql.NullString `db:"audit_event_id"`
+}
+
+func (el AuditEventLabel) LabelsTableName() string {
+	return "audit_event_labels"
+}
+
+func (el AuditEventLabel) ReferenceColumn() string {
+	return "audit_event_id"
+}
@@ -40,6 +40,7 @@ type Broker struct {
 	TlsClientCertificate  string             `db:"tls_client_certificate"`
 	Catalog               sqlxtypes.JSONText `db:"catalog"`
 	SMProvidedCredentials bool               `db:"sm_provided_tls_credentials"`
+	APIVersion            sql.NullString     `db:"api_version"`
 	Services              []*ServiceOffering `db:"-"`
 }
 
@@ -84,8 +85,9 @@ func (e *Broker) ToObject() (types.Object, error) {
 			TLS:       tls,
 			Integrity: e.Integrity,
 		},
-		Catalog:  getJSONRawMessage(e.Catalog),
-		Services: services,
+		APIVersion: e.APIVersion.String,
+		Catalog:    getJSONRawMessage(e.Catalog),
+		Services:   services,
 	}
 	return broker, nil
 }
@@ -116,6 +118,7 @@ func (*Broker) FromObject(object types.Object) (storage.Entity, error) {
 		Name:        broker.Name,
 		Description: toNullString(broker.Description),
 		BrokerURL:   broker.BrokerURL,
+		APIVersion:  toNullString(broker.APIVersion),
 		Catalog:     getJSONText(broker.Catalog),
 		Services:    services,
 	}
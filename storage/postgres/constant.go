@@ -2,4 +2,4 @@ package postgres
 
 // todo: get automatically from folder
 // change this line with the latest version of the migrations:
-const latestMigrationVersion = "20220711181000"
+const latestMigrationVersion = "20261017200000"
@@ -19,12 +19,14 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/Peripli/service-manager/storage"
 
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/jmoiron/sqlx"
+	sqlxtypes "github.com/jmoiron/sqlx/types"
 )
 
 const (
@@ -39,9 +41,13 @@ func EncryptingLocker(storage *Storage) storage.Locker {
 
 // Safe represents a secret entity
 type Safe struct {
-	Secret    []byte    `db:"secret"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Secret                  []byte                 `db:"secret"`
+	PreviousSecret          []byte                 `db:"previous_secret"`
+	RewrappedSecret         []byte                 `db:"rewrapped_secret"`
+	RewrappedPreviousSecret []byte                 `db:"rewrapped_previous_secret"`
+	KeyRotation             sqlxtypes.NullJSONText `db:"key_rotation"`
+	CreatedAt               time.Time              `db:"created_at"`
+	UpdatedAt               time.Time              `db:"updated_at"`
 }
 
 func (s *Safe) GetID() string {
@@ -83,25 +89,188 @@ func (s *Storage) GetEncryptionKey(ctx context.Context, transformationFunc func(
 		}
 		return nil, err
 	}
-	encryptedKey := []byte(safe.Secret)
 
-	return transformationFunc(ctx, encryptedKey, s.layerOneEncryptionKey)
+	return s.unwrap(ctx, safe.Secret, safe.RewrappedSecret, transformationFunc)
 }
 
 // SetEncryptionKey Sets the encryption key by encrypting it beforehand with the encryption key in the environment
 func (s *Storage) SetEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
 	s.checkOpen()
 
-	bytes, err := transformationFunc(ctx, key, s.layerOneEncryptionKey)
+	bytes, rewrappedBytes, err := s.wrap(ctx, key, transformationFunc)
 	if err != nil {
 		return err
 	}
 
 	err = create(ctx, s.db, "safe", &Safe{}, Safe{
-		Secret:    bytes,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Secret:          bytes,
+		RewrappedSecret: rewrappedBytes,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	})
 
 	return err
 }
+
+// GetPreviousEncryptionKey returns the encryption key which was replaced by the last encryption key rotation
+func (s *Storage) GetPreviousEncryptionKey(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	s.checkOpen()
+
+	safe := &Safe{}
+	if err := s.db.GetContext(ctx, safe, "SELECT * FROM safe"); err != nil {
+		if err == sql.ErrNoRows {
+			return []byte{}, nil
+		}
+		return nil, err
+	}
+	if len(safe.PreviousSecret) == 0 {
+		return []byte{}, nil
+	}
+
+	return s.unwrap(ctx, safe.PreviousSecret, safe.RewrappedPreviousSecret, transformationFunc)
+}
+
+// RotateEncryptionKey sets the encryption key by encrypting it beforehand with the encryption key in the environment and keeps
+// the replaced encryption key as the previous one
+func (s *Storage) RotateEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
+	s.checkOpen()
+
+	bytes, rewrappedBytes, err := s.wrap(ctx, key, transformationFunc)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `UPDATE safe SET previous_secret = secret, rewrapped_previous_secret = rewrapped_secret,
+		secret = $1, rewrapped_secret = $2, updated_at = $3`, bytes, rewrappedBytes, time.Now())
+	return err
+}
+
+// RewrapEncryptionKeys re-encrypts the encryption keys, which are encrypted with the previous encryption key in the environment,
+// with the current one. The re-encrypted keys are kept next to the original ones until the previous encryption key is removed
+// from the environment, so that the instances which still use it can decrypt the encryption keys.
+func (s *Storage) RewrapEncryptionKeys(ctx context.Context, decryptFunc, encryptFunc func(context.Context, []byte, []byte) ([]byte, error)) (bool, error) {
+	s.checkOpen()
+
+	safe := &Safe{}
+	if err := s.db.GetContext(ctx, safe, "SELECT * FROM safe"); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if len(s.layerOnePreviousEncryptionKey) == 0 {
+		if len(safe.RewrappedSecret) == 0 {
+			return false, nil
+		}
+		// the rotation of the encryption key in the environment is finalised only by the instances using the new one
+		if _, err := decryptFunc(ctx, safe.RewrappedSecret, s.layerOneEncryptionKey); err != nil {
+			return false, nil
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE safe SET secret = rewrapped_secret, previous_secret = rewrapped_previous_secret,
+			rewrapped_secret = NULL, rewrapped_previous_secret = NULL, updated_at = $1`, time.Now()); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if len(safe.RewrappedSecret) != 0 {
+		return false, nil
+	}
+	if _, err := decryptFunc(ctx, safe.Secret, s.layerOneEncryptionKey); err == nil {
+		return false, nil
+	}
+
+	rewrappedSecret, err := s.rewrap(ctx, safe.Secret, decryptFunc, encryptFunc)
+	if err != nil {
+		return false, err
+	}
+	var rewrappedPreviousSecret []byte
+	if len(safe.PreviousSecret) != 0 {
+		if rewrappedPreviousSecret, err = s.rewrap(ctx, safe.PreviousSecret, decryptFunc, encryptFunc); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE safe SET rewrapped_secret = $1, rewrapped_previous_secret = $2, updated_at = $3",
+		rewrappedSecret, rewrappedPreviousSecret, time.Now()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Storage) rewrap(ctx context.Context, encryptedKey []byte, decryptFunc, encryptFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	key, err := decryptFunc(ctx, encryptedKey, s.layerOnePreviousEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return encryptFunc(ctx, key, s.layerOneEncryptionKey)
+}
+
+// wrap encrypts the key with the encryption key in the environment. While the encryption key in the environment is
+// replaced, the key is encrypted with the previous one and its rewrapped copy is encrypted with the new one.
+func (s *Storage) wrap(ctx context.Context, key []byte, encryptFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, []byte, error) {
+	if len(s.layerOnePreviousEncryptionKey) == 0 {
+		encryptedKey, err := encryptFunc(ctx, key, s.layerOneEncryptionKey)
+		return encryptedKey, nil, err
+	}
+
+	encryptedKey, err := encryptFunc(ctx, key, s.layerOnePreviousEncryptionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	rewrappedKey, err := encryptFunc(ctx, key, s.layerOneEncryptionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encryptedKey, rewrappedKey, nil
+}
+
+// unwrap decrypts the key with the first encryption key in the environment which it is encrypted with
+func (s *Storage) unwrap(ctx context.Context, encryptedKey, rewrappedKey []byte, decryptFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	if len(rewrappedKey) != 0 {
+		if key, err := decryptFunc(ctx, rewrappedKey, s.layerOneEncryptionKey); err == nil {
+			return key, nil
+		}
+	}
+	key, err := decryptFunc(ctx, encryptedKey, s.layerOneEncryptionKey)
+	if err != nil && len(s.layerOnePreviousEncryptionKey) != 0 {
+		return decryptFunc(ctx, encryptedKey, s.layerOnePreviousEncryptionKey)
+	}
+	return key, err
+}
+
+// GetKeyRotation returns the progress of the last encryption key rotation
+func (s *Storage) GetKeyRotation(ctx context.Context) (*storage.KeyRotation, error) {
+	s.checkOpen()
+
+	safe := &Safe{}
+	if err := s.db.GetContext(ctx, safe, "SELECT * FROM safe"); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !safe.KeyRotation.Valid {
+		return nil, nil
+	}
+
+	rotation := &storage.KeyRotation{}
+	if err := safe.KeyRotation.Unmarshal(rotation); err != nil {
+		return nil, err
+	}
+	return rotation, nil
+}
+
+// SetKeyRotation stores the progress of the encryption key rotation
+func (s *Storage) SetKeyRotation(ctx context.Context, rotation *storage.KeyRotation) error {
+	s.checkOpen()
+
+	bytes, err := json.Marshal(rotation)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, "UPDATE safe SET key_rotation = $1", string(bytes))
+	return err
+}
@@ -41,6 +41,7 @@ var (
 	timeType           = reflect.TypeOf(time.Time{})
 	byteSliceType      = reflect.TypeOf([]byte{})
 	stringType         = reflect.TypeOf("")
+	boolType           = reflect.TypeOf(false)
 	nullableStringType = reflect.TypeOf(sql.NullString{})
 	jsonType           = reflect.TypeOf(sqlxtypes.JSONText{})
 )
@@ -64,7 +65,7 @@ func determineCastByType(tagType reflect.Type) string {
 
 func hasMultiVariateOp(criteria []query.Criterion) bool {
 	for _, opt := range criteria {
-		if opt.Operator.Type() == query.MultivariateOperator {
+		if opt.Operator.Type() == query.MultivariateOperator || hasMultiVariateOp(opt.Criteria) {
 			return true
 		}
 	}
@@ -0,0 +1 @@
+ALTER TYPE operation_state ADD VALUE 'cancelled';
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE operations DROP COLUMN attempts;
+ALTER TABLE operations DROP COLUMN next_attempt_timestamp;
+
+COMMIT;
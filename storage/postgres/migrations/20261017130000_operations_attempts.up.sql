@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE operations ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE operations ADD COLUMN next_attempt_timestamp timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE platforms DROP COLUMN webhook_url;
+ALTER TABLE platforms DROP COLUMN webhook_secret;
+ALTER TABLE platforms DROP COLUMN webhook_revision;
+ALTER TABLE platforms DROP COLUMN webhook_delivered_at;
+ALTER TABLE platforms DROP COLUMN webhook_failures;
+ALTER TABLE platforms DROP COLUMN webhook_last_error;
+ALTER TABLE platforms DROP COLUMN webhook_next_attempt_at;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE platforms ADD COLUMN webhook_url varchar(2048) NOT NULL DEFAULT '';
+ALTER TABLE platforms ADD COLUMN webhook_secret text NOT NULL DEFAULT '';
+ALTER TABLE platforms ADD COLUMN webhook_revision bigint NOT NULL DEFAULT 0;
+ALTER TABLE platforms ADD COLUMN webhook_delivered_at timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+ALTER TABLE platforms ADD COLUMN webhook_failures integer NOT NULL DEFAULT 0;
+ALTER TABLE platforms ADD COLUMN webhook_last_error text NOT NULL DEFAULT '';
+ALTER TABLE platforms ADD COLUMN webhook_next_attempt_at timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+
+COMMIT;
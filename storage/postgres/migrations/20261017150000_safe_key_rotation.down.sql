@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE safe DROP COLUMN previous_secret;
+ALTER TABLE safe DROP COLUMN key_rotation;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE safe ADD COLUMN previous_secret bytea;
+ALTER TABLE safe ADD COLUMN key_rotation jsonb;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS audit_event_labels;
+DROP TABLE IF EXISTS audit_events;
+
+COMMIT;
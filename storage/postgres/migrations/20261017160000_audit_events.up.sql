@@ -0,0 +1,42 @@
+BEGIN;
+
+CREATE TABLE audit_events
+(
+  id                varchar(100) PRIMARY KEY,
+  actor             varchar(255) NOT NULL,
+  tenant            varchar(255),
+  access_level      varchar(100) NOT NULL,
+  resource          varchar(100) NOT NULL,
+  resource_id       varchar(100),
+  action            varchar(100) NOT NULL,
+  method            varchar(10) NOT NULL,
+  path              text NOT NULL,
+  changes           json DEFAULT '{}',
+  correlation_id    varchar(100),
+  outcome           varchar(100) NOT NULL,
+  status_code       integer NOT NULL,
+  created_at        timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at        timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence   BIGSERIAL,
+  ready             boolean NOT NULL
+);
+
+CREATE TABLE audit_event_labels
+(
+  id              varchar(100) PRIMARY KEY,
+  key             varchar(255) NOT NULL CHECK (key <> ''),
+  val             varchar(255) NOT NULL CHECK (val <> ''),
+  audit_event_id  varchar(100) NOT NULL REFERENCES audit_events (id) ON DELETE CASCADE,
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, audit_event_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS audit_events_paging_sequence_uindex
+    on audit_events (paging_sequence);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_index
+    on audit_events (created_at);
+CREATE INDEX IF NOT EXISTS audit_events_resource_id_index
+    on audit_events (resource_id);
+
+COMMIT;
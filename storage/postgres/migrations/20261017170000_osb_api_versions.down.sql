@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE brokers DROP COLUMN api_version;
+ALTER TABLE service_instances DROP COLUMN metadata;
+ALTER TABLE service_bindings DROP COLUMN metadata;
+ALTER TABLE service_bindings DROP COLUMN predecessor_binding_id;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE brokers ADD COLUMN api_version varchar(20);
+ALTER TABLE service_instances ADD COLUMN metadata json;
+ALTER TABLE service_bindings ADD COLUMN metadata json;
+ALTER TABLE service_bindings ADD COLUMN predecessor_binding_id varchar(100);
+
+COMMIT;
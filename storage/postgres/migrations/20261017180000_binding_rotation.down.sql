@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE service_plans DROP COLUMN binding_rotatable;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE service_plans ADD COLUMN binding_rotatable boolean;
+
+COMMIT;
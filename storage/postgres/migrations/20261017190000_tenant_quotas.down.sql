@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS tenant_quota_labels;
+DROP TABLE IF EXISTS tenant_quotas;
+
+COMMIT;
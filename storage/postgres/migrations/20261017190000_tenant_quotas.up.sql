@@ -0,0 +1,30 @@
+BEGIN;
+
+CREATE TABLE tenant_quotas
+(
+  id                          varchar(100) PRIMARY KEY,
+  max_instances               integer,
+  max_instances_per_offering  json DEFAULT '{}',
+  max_instances_per_plan      json DEFAULT '{}',
+  max_bindings_per_instance   integer,
+  created_at                  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at                  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence             BIGSERIAL,
+  ready                       boolean NOT NULL
+);
+
+CREATE TABLE tenant_quota_labels
+(
+  id               varchar(100) PRIMARY KEY,
+  key              varchar(255) NOT NULL CHECK (key <> ''),
+  val              varchar(255) NOT NULL CHECK (val <> ''),
+  tenant_quota_id  varchar(100) NOT NULL REFERENCES tenant_quotas (id) ON DELETE CASCADE,
+  created_at       timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, tenant_quota_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tenant_quotas_paging_sequence_uindex
+    on tenant_quotas (paging_sequence);
+
+COMMIT;
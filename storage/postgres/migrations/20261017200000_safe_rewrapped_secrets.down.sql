@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE safe DROP COLUMN rewrapped_secret;
+ALTER TABLE safe DROP COLUMN rewrapped_previous_secret;
+
+COMMIT;
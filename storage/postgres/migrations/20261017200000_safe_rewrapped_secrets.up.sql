@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE safe ADD COLUMN rewrapped_secret bytea;
+ALTER TABLE safe ADD COLUMN rewrapped_previous_secret bytea;
+
+COMMIT;
@@ -272,6 +272,21 @@ func (n *Notificator) RegisterFilter(f storage.ReceiversFilterFunc) {
 	n.notificationFilters = append(n.notificationFilters, f)
 }
 
+// Stats returns statistics about the registered consumers
+func (n *Notificator) Stats() storage.NotificatorStats {
+	n.consumersMutex.Lock()
+	defer n.consumersMutex.Unlock()
+	stats := storage.NotificatorStats{
+		ConnectedPlatforms: n.consumers.Len(),
+	}
+	for _, platformConsumers := range n.consumers.queues {
+		for _, queue := range platformConsumers {
+			stats.QueuedNotifications += len(queue.Channel())
+		}
+	}
+	return stats
+}
+
 func (n *Notificator) closeAllConsumers() {
 	n.consumersMutex.Lock()
 	defer n.consumersMutex.Unlock()
@@ -33,22 +33,24 @@ import (
 //go:generate smgen storage operation github.com/Peripli/service-manager/pkg/types:Operation
 type Operation struct {
 	BaseEntity
-	Description         sql.NullString     `db:"description"`
-	Type                string             `db:"type"`
-	State               string             `db:"state"`
-	ResourceID          string             `db:"resource_id"`
-	TransitiveResources sqlxtypes.JSONText `db:"transitive_resources"`
-	ResourceType        string             `db:"resource_type"`
-	PlatformID          string             `db:"platform_id"`
-	Errors              sqlxtypes.JSONText `db:"errors"`
-	CorrelationID       sql.NullString     `db:"correlation_id"`
-	ExternalID          sql.NullString     `db:"external_id"`
-	CascadeRootID       sql.NullString     `db:"cascade_root_id"`
-	Reschedule          bool               `db:"reschedule"`
-	ParentID            sql.NullString     `db:"parent_id"`
-	RescheduleTimestamp time.Time          `db:"reschedule_timestamp"`
-	DeletionScheduled   time.Time          `db:"deletion_scheduled"`
-	Context             sqlxtypes.JSONText `db:"context"`
+	Description          sql.NullString     `db:"description"`
+	Type                 string             `db:"type"`
+	State                string             `db:"state"`
+	ResourceID           string             `db:"resource_id"`
+	TransitiveResources  sqlxtypes.JSONText `db:"transitive_resources"`
+	ResourceType         string             `db:"resource_type"`
+	PlatformID           string             `db:"platform_id"`
+	Errors               sqlxtypes.JSONText `db:"errors"`
+	CorrelationID        sql.NullString     `db:"correlation_id"`
+	ExternalID           sql.NullString     `db:"external_id"`
+	CascadeRootID        sql.NullString     `db:"cascade_root_id"`
+	Reschedule           bool               `db:"reschedule"`
+	ParentID             sql.NullString     `db:"parent_id"`
+	RescheduleTimestamp  time.Time          `db:"reschedule_timestamp"`
+	DeletionScheduled    time.Time          `db:"deletion_scheduled"`
+	Attempts             int                `db:"attempts"`
+	NextAttemptTimestamp time.Time          `db:"next_attempt_timestamp"`
+	Context              sqlxtypes.JSONText `db:"context"`
 }
 
 func (o *Operation) ToObject() (types.Object, error) {
@@ -74,22 +76,24 @@ func (o *Operation) ToObject() (types.Object, error) {
 			PagingSequence: o.PagingSequence,
 			Ready:          o.Ready,
 		},
-		Description:         o.Description.String,
-		Type:                types.OperationCategory(o.Type),
-		State:               types.OperationState(o.State),
-		ResourceID:          o.ResourceID,
-		TransitiveResources: transitiveResources,
-		ResourceType:        types.ObjectType(o.ResourceType),
-		PlatformID:          o.PlatformID,
-		Errors:              getJSONRawMessage(o.Errors),
-		CorrelationID:       o.CorrelationID.String,
-		ExternalID:          o.ExternalID.String,
-		Reschedule:          o.Reschedule,
-		Context:             &operationContext,
-		CascadeRootID:       o.CascadeRootID.String,
-		ParentID:            o.ParentID.String,
-		RescheduleTimestamp: o.RescheduleTimestamp,
-		DeletionScheduled:   o.DeletionScheduled,
+		Description:          o.Description.String,
+		Type:                 types.OperationCategory(o.Type),
+		State:                types.OperationState(o.State),
+		ResourceID:           o.ResourceID,
+		TransitiveResources:  transitiveResources,
+		ResourceType:         types.ObjectType(o.ResourceType),
+		PlatformID:           o.PlatformID,
+		Errors:               getJSONRawMessage(o.Errors),
+		CorrelationID:        o.CorrelationID.String,
+		ExternalID:           o.ExternalID.String,
+		Reschedule:           o.Reschedule,
+		Context:              &operationContext,
+		CascadeRootID:        o.CascadeRootID.String,
+		ParentID:             o.ParentID.String,
+		RescheduleTimestamp:  o.RescheduleTimestamp,
+		DeletionScheduled:    o.DeletionScheduled,
+		Attempts:             o.Attempts,
+		NextAttemptTimestamp: o.NextAttemptTimestamp,
 	}, nil
 }
 
@@ -121,22 +125,24 @@ func (*Operation) FromObject(object types.Object) (storage.Entity, error) {
 			PagingSequence: operation.PagingSequence,
 			Ready:          operation.Ready,
 		},
-		Description:         toNullString(operation.Description),
-		Type:                string(operation.Type),
-		State:               string(operation.State),
-		ResourceID:          operation.ResourceID,
-		TransitiveResources: getJSONText(transitiveResourcesBytes),
-		ResourceType:        operation.ResourceType.String(),
-		PlatformID:          operation.PlatformID,
-		Errors:              getJSONText(operation.Errors),
-		CorrelationID:       toNullString(operation.CorrelationID),
-		ExternalID:          toNullString(operation.ExternalID),
-		Reschedule:          operation.Reschedule,
-		Context:             getJSONText(operationContext),
-		CascadeRootID:       toNullString(operation.CascadeRootID),
-		ParentID:            toNullString(operation.ParentID),
-		RescheduleTimestamp: operation.RescheduleTimestamp,
-		DeletionScheduled:   operation.DeletionScheduled,
+		Description:          toNullString(operation.Description),
+		Type:                 string(operation.Type),
+		State:                string(operation.State),
+		ResourceID:           operation.ResourceID,
+		TransitiveResources:  getJSONText(transitiveResourcesBytes),
+		ResourceType:         operation.ResourceType.String(),
+		PlatformID:           operation.PlatformID,
+		Errors:               getJSONText(operation.Errors),
+		CorrelationID:        toNullString(operation.CorrelationID),
+		ExternalID:           toNullString(operation.ExternalID),
+		Reschedule:           operation.Reschedule,
+		Context:              getJSONText(operationContext),
+		CascadeRootID:        toNullString(operation.CascadeRootID),
+		ParentID:             toNullString(operation.ParentID),
+		RescheduleTimestamp:  operation.RescheduleTimestamp,
+		DeletionScheduled:    operation.DeletionScheduled,
+		Attempts:             operation.Attempts,
+		NextAttemptTimestamp: operation.NextAttemptTimestamp,
 	}
 	return o, nil
 }
@@ -44,6 +44,13 @@ type Platform struct {
 	LastActive        time.Time      `db:"last_active"`
 	Technical         bool           `db:"technical"`
 	Version           sql.NullString `db:"version"`
+	WebhookURL        string         `db:"webhook_url"`
+	WebhookSecret     string         `db:"webhook_secret"`
+	WebhookRevision   int64          `db:"webhook_revision"`
+	WebhookDelivered  time.Time      `db:"webhook_delivered_at"`
+	WebhookFailures   int            `db:"webhook_failures"`
+	WebhookLastError  string         `db:"webhook_last_error"`
+	WebhookNextTry    time.Time      `db:"webhook_next_attempt_at"`
 }
 
 func (p *Platform) FromObject(object types.Object) (storage.Entity, error) {
@@ -87,6 +94,19 @@ func (p *Platform) FromObject(object types.Object) (storage.Entity, error) {
 		result.OldUsername = platform.OldCredentials.Basic.Username
 		result.OldPassword = platform.OldCredentials.Basic.Password
 	}
+
+	if platform.Webhook != nil {
+		result.WebhookURL = platform.Webhook.URL
+		result.WebhookSecret = platform.Webhook.Secret
+	}
+
+	if platform.WebhookStatus != nil {
+		result.WebhookRevision = platform.WebhookStatus.Revision
+		result.WebhookDelivered = platform.WebhookStatus.LastDeliveredAt
+		result.WebhookFailures = platform.WebhookStatus.ConsecutiveFailures
+		result.WebhookLastError = platform.WebhookStatus.LastError
+		result.WebhookNextTry = platform.WebhookStatus.NextAttemptAt
+	}
 	return result, nil
 }
 
@@ -126,5 +146,18 @@ func (p *Platform) ToObject() (types.Object, error) {
 			},
 		}
 	}
+	if len(p.WebhookURL) > 0 {
+		platform.Webhook = &types.Webhook{
+			URL:    p.WebhookURL,
+			Secret: p.WebhookSecret,
+		}
+		platform.WebhookStatus = &types.WebhookStatus{
+			Revision:            p.WebhookRevision,
+			LastDeliveredAt:     p.WebhookDelivered,
+			ConsecutiveFailures: p.WebhookFailures,
+			LastError:           p.WebhookLastError,
+			NextAttemptAt:       p.WebhookNextTry,
+		}
+	}
 	return platform, nil
 }
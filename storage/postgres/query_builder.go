@@ -7,9 +7,12 @@ import (
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/storage"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/jmoiron/sqlx"
 )
@@ -36,9 +39,24 @@ FROM {{.ENTITY_TABLE}}
 {{.FOR_UPDATE_OF}}
 {{.LIMIT}};`
 
+const CountGroupsQueryTemplate = `
+WITH matching_resources as (SELECT DISTINCT {{.ENTITY_TABLE}}.{{.PRIMARY_KEY}}
+							FROM {{.ENTITY_TABLE}}
+							{{if .hasLabelCriteria}}
+							{{.JOIN}} {{.LABELS_TABLE}}
+								ON {{.ENTITY_TABLE}}.{{.PRIMARY_KEY}} = {{.LABELS_TABLE}}.{{.REF_COLUMN}}
+							{{end}}
+							{{.WHERE}})
+SELECT {{.GROUP_BY_COLUMNS}}COUNT(DISTINCT {{.ENTITY_TABLE}}.{{.PRIMARY_KEY}})
+FROM {{.ENTITY_TABLE}}
+{{.GROUP_BY_JOIN}}
+WHERE {{.ENTITY_TABLE}}.{{.PRIMARY_KEY}} IN 
+	(SELECT matching_resources.{{.PRIMARY_KEY}} FROM matching_resources)
+{{.GROUP_BY}};`
+
 const SelectQueryTemplate = `
 {{if or .hasFieldCriteria .hasLabelCriteria}}
-WITH matching_resources as (SELECT DISTINCT {{.ENTITY_TABLE}}.paging_sequence
+WITH matching_resources as (SELECT DISTINCT {{.ENTITY_TABLE}}.paging_sequence{{.ORDER_BY_COLUMNS}}
 							FROM {{.ENTITY_TABLE}}
 							{{if .hasLabelCriteria}}
 							{{.JOIN}} {{.LABELS_TABLE}} 
@@ -68,7 +86,7 @@ WHERE {{.ENTITY_TABLE}}.paging_sequence IN
 
 const SelectNoLabelsQueryTemplate = `
 {{if or .hasFieldCriteria .hasLabelCriteria}}
-WITH matching_resources as (SELECT DISTINCT {{.ENTITY_TABLE}}.paging_sequence
+WITH matching_resources as (SELECT DISTINCT {{.ENTITY_TABLE}}.paging_sequence{{.ORDER_BY_COLUMNS}}
 							FROM {{.ENTITY_TABLE}}
 							{{if .hasLabelCriteria}}
 							{{.JOIN}} {{.LABELS_TABLE}} 
@@ -147,6 +165,9 @@ type pgQuery struct {
 	queryParams []interface{}
 
 	orderByFields   []orderRule
+	groupByFields   []string
+	groupByLabels   []string
+	after           []string
 	hasLock         bool
 	limit           string
 	returningFields []string
@@ -216,6 +237,66 @@ func (pq *pgQuery) CountLabelValues(ctx context.Context) (int, error) {
 	return count, nil
 }
 
+// CountGroups counts the matching resources grouped by the values of the provided fields and label keys. Resources
+// having multiple values of a grouped label key are counted in the group of each of the values.
+func (pq *pgQuery) CountGroups(ctx context.Context, fields []string, labelKeys []string) ([]*types.GroupCount, error) {
+	if pq.err != nil {
+		return nil, pq.err
+	}
+	if len(pq.limit) != 0 {
+		return nil, &util.UnsupportedQueryError{Message: "limit is not supported when counting groups"}
+	}
+	if err := validateGroupByFields(pq.entityTags, fields); err != nil {
+		return nil, err
+	}
+	pq.groupByFields = fields
+	pq.groupByLabels = labelKeys
+
+	q, err := pq.resolveQueryTemplate(ctx, CountGroupsQueryTemplate)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pq.db.QueryxContext(ctx, q, pq.queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.C(ctx).WithError(err).Error("Could not release connection when counting groups")
+		}
+	}()
+
+	groups := make([]*types.GroupCount, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(fields)+len(labelKeys))
+		dest := make([]interface{}, 0, len(values)+1)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		group := &types.GroupCount{}
+		if err := rows.Scan(append(dest, &group.Count)...); err != nil {
+			return nil, err
+		}
+		if len(fields) != 0 {
+			group.Fields = make(map[string]*string, len(fields))
+			for i, field := range fields {
+				group.Fields[field] = groupValue(values[i])
+			}
+		}
+		if len(labelKeys) != 0 {
+			group.Labels = make(map[string]*string, len(labelKeys))
+			for i, key := range labelKeys {
+				group.Labels[key] = groupValue(values[len(fields)+i])
+			}
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
 func (pq *pgQuery) Delete(ctx context.Context) (sql.Result, error) {
 	q, err := pq.resolveQueryTemplate(ctx, DeleteQueryTemplate)
 	if err != nil {
@@ -254,6 +335,9 @@ func (pq *pgQuery) resolveQueryTemplate(ctx context.Context, template string) (s
 	if pq.labelEntity == nil {
 		return "", fmt.Errorf("query builder requires the entity to have associated label entity")
 	}
+	if err := pq.applyAfter(); err != nil {
+		return "", err
+	}
 	data := pq.getTemplateParams()
 
 	q, err := util.Tsprintf(template, data)
@@ -282,8 +366,12 @@ func (pq *pgQuery) getTemplateParams() map[string]interface{} {
 		"FOR_UPDATE_OF":     pq.lockSQL(),
 		"ORDER_BY":          pq.orderBySQL(),
 		"ORDER_BY_SEQUENCE": pq.orderBySequenceSQL(),
+		"ORDER_BY_COLUMNS":  pq.orderByColumnsSQL(),
 		"LIMIT":             pq.limitSQL(),
 		"RETURNING":         pq.returningSQL(),
+		"GROUP_BY_COLUMNS":  pq.groupByColumnsSQL(),
+		"GROUP_BY_JOIN":     pq.groupByJoinSQL(),
+		"GROUP_BY":          pq.groupBySQL(),
 	}
 	return data
 }
@@ -305,37 +393,23 @@ func (pq *pgQuery) WithCriteria(criteria ...query.Criterion) *pgQuery {
 		if hasMultiVariateOp(criteria) {
 			pq.shouldRebind = true
 		}
+		if criterion.IsCompound() {
+			whereClause, err := pq.compoundWhereClause(criterion)
+			if err != nil {
+				pq.err = err
+				return pq
+			}
+			pq.fieldsWhereClause.children = append(pq.fieldsWhereClause.children, whereClause)
+			continue
+		}
 		switch criterion.Type {
 		case query.FieldQuery:
-			columns := columnsByTags(pq.entityTags)
-			columnName := criterion.LeftOp
-			if strings.Contains(columnName, "/") {
-				columnName = strings.Split(columnName, "/")[0]
-				ttype := findTagType(pq.entityTags, columnName)
-				if ttype != jsonType {
-					pq.err = &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query: json notation on non json column: %s", columnName)}
-					return pq
-				}
-
-			}
-			if !columns[columnName] {
-				pq.err = &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query key: %s", criterion.LeftOp)}
+			whereClause, err := pq.fieldWhereClause(criterion)
+			if err != nil {
+				pq.err = err
 				return pq
 			}
-
-			if criterion.Operator == query.ContainsOperator {
-				ttype := findTagType(pq.entityTags, columnName)
-				if ttype != stringType && ttype != nullableStringType && ttype != jsonType {
-					pq.err = &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query: the operator '%s' is not applicable on non-string columns: %s", criterion.Operator.String(), columnName)}
-					return pq
-				}
-			}
-
-			pq.fieldsWhereClause.children = append(pq.fieldsWhereClause.children, &whereClauseTree{
-				criterion: criterion,
-				dbTags:    pq.entityTags,
-				tableName: pq.entityTableName,
-			})
+			pq.fieldsWhereClause.children = append(pq.fieldsWhereClause.children, whereClause)
 		case query.LabelQuery:
 			labelQueryCount++
 			if labelQueryCount > 1 { // 2 or more labelQueries need to be intersected
@@ -375,6 +449,86 @@ func (pq *pgQuery) WithCriteria(criteria ...query.Criterion) *pgQuery {
 	return pq
 }
 
+func (pq *pgQuery) fieldWhereClause(criterion query.Criterion) (*whereClauseTree, error) {
+	columns := columnsByTags(pq.entityTags)
+	columnName := criterion.LeftOp
+	if strings.Contains(columnName, "/") {
+		columnName = strings.Split(columnName, "/")[0]
+		ttype := findTagType(pq.entityTags, columnName)
+		if ttype != jsonType {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query: json notation on non json column: %s", columnName)}
+		}
+
+	}
+	if !columns[columnName] {
+		return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query key: %s", criterion.LeftOp)}
+	}
+
+	if criterion.Operator == query.ContainsOperator {
+		ttype := findTagType(pq.entityTags, columnName)
+		if ttype != stringType && ttype != nullableStringType && ttype != jsonType {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field query: the operator '%s' is not applicable on non-string columns: %s", criterion.Operator.String(), columnName)}
+		}
+	}
+
+	return &whereClauseTree{
+		criterion: criterion,
+		dbTags:    pq.entityTags,
+		tableName: pq.entityTableName,
+	}, nil
+}
+
+// labelWhereClause matches the entities having a label satisfying the criterion. Unlike the top level label
+// queries which are intersected, it is a standalone condition and can therefore be negated or joined with OR
+func (pq *pgQuery) labelWhereClause(criterion query.Criterion) *whereClauseTree {
+	return &whereClauseTree{
+		children: []*whereClauseTree{
+			{
+				criterion: query.ByField(query.EqualsOperator, "key", criterion.LeftOp),
+				dbTags:    pq.labelEntityTags,
+			},
+			{
+				criterion: query.ByField(criterion.Operator, "val", criterion.RightOp...),
+				dbTags:    pq.labelEntityTags,
+			},
+		},
+		sqlBuilder: &treeSqlBuilder{
+			buildSQL: func(childrenSQL []string) string {
+				return fmt.Sprintf("%s.%s IN (SELECT %s FROM %s WHERE (%s))", pq.entityTableName, PrimaryKeyColumn, pq.labelEntity.ReferenceColumn(), pq.labelEntity.LabelsTableName(), strings.Join(childrenSQL, fmt.Sprintf(" %s ", AND)))
+			},
+		},
+	}
+}
+
+// compoundWhereClause builds a where clause subtree for criteria combined with logical operators
+func (pq *pgQuery) compoundWhereClause(criterion query.Criterion) (*whereClauseTree, error) {
+	whereClause := &whereClauseTree{
+		negate: criterion.Operator == query.NotOperator,
+	}
+	if criterion.Operator == query.OrOperator {
+		whereClause.sqlBuilder = orTreeSqlBuilder
+	}
+	for _, nested := range criterion.Criteria {
+		var child *whereClauseTree
+		var err error
+		switch {
+		case nested.IsCompound():
+			child, err = pq.compoundWhereClause(nested)
+		case nested.Type == query.FieldQuery:
+			child, err = pq.fieldWhereClause(nested)
+		case nested.Type == query.LabelQuery:
+			child = pq.labelWhereClause(nested)
+		default:
+			err = &util.UnsupportedQueryError{Message: fmt.Sprintf("operator %s is not supported for %s", criterion.Operator, nested.Type)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		whereClause.children = append(whereClause.children, child)
+	}
+	return whereClause, nil
+}
+
 func (pq *pgQuery) WithLock() *pgQuery {
 	if pq.err != nil {
 		return pq
@@ -474,7 +628,17 @@ func (pq *pgQuery) processResultCriteria(c query.Criterion) *pgQuery {
 			pq.err = err
 			return pq
 		}
+		if err := validateOrderFieldTypes(pq.entityTags, rule); err != nil {
+			pq.err = err
+			return pq
+		}
 		pq.orderByFields = append(pq.orderByFields, rule)
+	case query.After:
+		if pq.after != nil {
+			pq.err = fmt.Errorf("zero/one after criterion expected but multiple provided")
+			return pq
+		}
+		pq.after = c.RightOp
 	case query.Limit:
 		if pq.limit != "" {
 			pq.err = fmt.Errorf("zero/one limit expected but multiple provided")
@@ -502,11 +666,152 @@ func (pq *pgQuery) orderBySQL() string {
 	return sql
 }
 
+// orderBySequenceSQL orders the matching resources before the limit is applied so that the limit
+// selects the first resources in the requested order or in the order of creation if no order is requested
 func (pq *pgQuery) orderBySequenceSQL() string {
-	if len(pq.limit) > 0 {
+	if len(pq.limit) == 0 {
+		return ""
+	}
+	if len(pq.orderByFields) == 0 {
 		return fmt.Sprintf("ORDER BY %s.paging_sequence ASC", pq.entityTableName)
 	}
-	return ""
+	rules := make([]string, 0, len(pq.orderByFields))
+	for _, rule := range pq.orderByFields {
+		rules = append(rules, fmt.Sprintf("%s.%s %s", pq.entityTableName, rule.field, rule.orderType))
+	}
+	return "ORDER BY " + strings.Join(rules, ", ")
+}
+
+// orderByColumnsSQL returns the columns which have to be selected by the matching resources in order to be ordered by them
+func (pq *pgQuery) orderByColumnsSQL() string {
+	if len(pq.limit) == 0 {
+		return ""
+	}
+	sql := ""
+	for _, rule := range pq.orderByFields {
+		if rule.field != "paging_sequence" {
+			sql += fmt.Sprintf(", %s.%s", pq.entityTableName, rule.field)
+		}
+	}
+	return sql
+}
+
+// groupByColumnsSQL returns the selected columns holding the values of the grouped fields and label keys
+func (pq *pgQuery) groupByColumnsSQL() string {
+	sql := ""
+	for _, field := range pq.groupByFields {
+		sql += fmt.Sprintf("%s.%s, ", pq.entityTableName, field)
+	}
+	for i := range pq.groupByLabels {
+		sql += fmt.Sprintf("%s.val, ", groupLabelsAlias(i))
+	}
+	return sql
+}
+
+// groupByJoinSQL joins the labels having the grouped keys. LEFT JOIN is used so that the resources which do not have
+// a grouped label key are counted in the group with no value for it.
+func (pq *pgQuery) groupByJoinSQL() string {
+	sql := ""
+	for i, key := range pq.groupByLabels {
+		alias := groupLabelsAlias(i)
+		sql += fmt.Sprintf(" LEFT JOIN %s %s ON %s.%s = %s.%s AND %s.key = ?",
+			pq.labelEntity.LabelsTableName(), alias, pq.entityTableName, PrimaryKeyColumn, alias, pq.labelEntity.ReferenceColumn(), alias)
+		pq.queryParams = append(pq.queryParams, key)
+	}
+	return sql
+}
+
+// groupBySQL groups the resources by the selected values and orders the groups starting from the largest one
+func (pq *pgQuery) groupBySQL() string {
+	columnsCount := len(pq.groupByFields) + len(pq.groupByLabels)
+	if columnsCount == 0 {
+		return ""
+	}
+	positions := make([]string, 0, columnsCount)
+	for i := 1; i <= columnsCount; i++ {
+		positions = append(positions, strconv.Itoa(i))
+	}
+	return fmt.Sprintf("GROUP BY %s ORDER BY %d DESC, %s", strings.Join(positions, ", "), columnsCount+1, strings.Join(positions, ", "))
+}
+
+func groupLabelsAlias(index int) string {
+	return fmt.Sprintf("group_labels_%d", index)
+}
+
+func validateGroupByFields(tags []tagType, fields []string) error {
+	columns := columnsByTags(tags)
+	for _, field := range fields {
+		if !columns[field] {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported entity field for group by: %s", field)}
+		}
+		if fieldType := findTagType(tags, field); fieldType == jsonType || fieldType == byteSliceType {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported field type for group by: %s", field)}
+		}
+	}
+	return nil
+}
+
+// groupValue returns the text representation of a grouped value as it is returned by the API or nil for NULL values
+func groupValue(value interface{}) *string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		text = string(v)
+	case time.Time:
+		text = v.UTC().Format(time.RFC3339Nano)
+	default:
+		text = fmt.Sprint(v)
+	}
+	return &text
+}
+
+// applyAfter adds a where clause matching only the resources which come after the provided values in the requested order.
+// For order rules f1, f2 and values v1, v2 the clause is (f1 > v1 OR (f1 = v1 AND f2 > v2)), where < is used for descending order.
+func (pq *pgQuery) applyAfter() error {
+	if pq.after == nil {
+		return nil
+	}
+	if len(pq.after) != len(pq.orderByFields) {
+		return &util.UnsupportedQueryError{Message: fmt.Sprintf("after criterion expects %d values, one for each order by criterion, but %d provided", len(pq.orderByFields), len(pq.after))}
+	}
+
+	var afterClause *whereClauseTree
+	for i := len(pq.orderByFields) - 1; i >= 0; i-- {
+		rule := pq.orderByFields[i]
+		var operator query.Operator = query.GreaterThanOperator
+		if rule.orderType == query.DescOrder {
+			operator = query.LessThanOperator
+		}
+		clause := &whereClauseTree{
+			criterion: query.ByField(operator, rule.field, pq.after[i]),
+			dbTags:    pq.entityTags,
+			tableName: pq.entityTableName,
+		}
+		if afterClause != nil {
+			clause = &whereClauseTree{
+				children: []*whereClauseTree{
+					clause,
+					{
+						children: []*whereClauseTree{
+							{
+								criterion: query.ByField(query.EqualsOperator, rule.field, pq.after[i]),
+								dbTags:    pq.entityTags,
+								tableName: pq.entityTableName,
+							},
+							afterClause,
+						},
+					},
+				},
+				sqlBuilder: orTreeSqlBuilder,
+			}
+		}
+		afterClause = clause
+	}
+	pq.fieldsWhereClause.children = append(pq.fieldsWhereClause.children, afterClause)
+	pq.after = nil
+	return nil
 }
 
 func validateOrderFields(columns map[string]bool, orderRules ...orderRule) error {
@@ -525,6 +830,18 @@ func validateOrderFields(columns map[string]bool, orderRules ...orderRule) error
 	return validateFields(columns, "unsupported entity field for order by: %s", fields...)
 }
 
+// validateOrderFieldTypes checks that the result is ordered only by columns which have total order
+func validateOrderFieldTypes(tags []tagType, orderRules ...orderRule) error {
+	for _, rule := range orderRules {
+		switch findTagType(tags, rule.field) {
+		case stringType, intType, int64Type, timeType, boolType:
+		default:
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported entity field for order by: %s", rule.field)}
+		}
+	}
+	return nil
+}
+
 func validateReturningFields(columns map[string]bool, returningFields ...string) error {
 	if len(returningFields) == 0 {
 		return fmt.Errorf("returning fields cannot be empty")
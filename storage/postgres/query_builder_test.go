@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"github.com/Peripli/service-manager/storage"
 
 	"regexp"
@@ -260,6 +261,48 @@ ORDER BY id DESC, created_at ASC ;`)))
 				})
 			})
 
+			Context("when the field is nullable", func() {
+				It("returns error", func() {
+					_, err := qb.NewQuery(entity).WithCriteria(query.OrderResultBy("platform_id", query.AscOrder)).List(ctx)
+					Expect(err).Should(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("unsupported entity field for order by: platform_id"))
+				})
+			})
+
+			Context("when limit and after criteria are used", func() {
+				It("builds query which selects the resources after the provided values in the requested order", func() {
+					_, err := qb.NewQuery(entity).
+						WithCriteria(query.OrderResultBy("service_plan_id", query.DescOrder),
+							query.OrderResultBy("paging_sequence", query.AscOrder),
+							query.ResultAfter("plan", "5"),
+							query.LimitResultBy(10)).
+						ListNoLabels(ctx)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence, visibilities.service_plan_id
+                            FROM visibilities
+                            WHERE (visibilities.service_plan_id::text < ? OR
+                                  (visibilities.service_plan_id::text = ? AND visibilities.paging_sequence > ?))
+                            ORDER BY visibilities.service_plan_id DESC, visibilities.paging_sequence ASC
+                            LIMIT ?)
+SELECT *
+FROM visibilities
+WHERE visibilities.paging_sequence IN (SELECT matching_resources.paging_sequence FROM matching_resources)
+ORDER BY service_plan_id DESC, paging_sequence ASC ;`)))
+					Expect(queryArgs).To(Equal([]interface{}{"plan", "plan", "5", "10"}))
+				})
+			})
+
+			Context("when the after values do not match the order by criteria", func() {
+				It("returns error", func() {
+					_, err := qb.NewQuery(entity).
+						WithCriteria(query.OrderResultBy("id", query.AscOrder), query.ResultAfter("1", "2")).
+						List(ctx)
+					Expect(err).Should(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("after criterion expects 1 values"))
+				})
+			})
+
 			Context("when order type is missing", func() {
 				It("returns error", func() {
 					_, err := qb.NewQuery(entity).
@@ -350,7 +393,7 @@ ORDER BY visibilities.paging_sequence ASC ;`)))
 					List(ctx)
 				Expect(err).ShouldNot(HaveOccurred())
 				Expect(executedQuery).Should(Equal(trim(`
-WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence
+WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence, visibilities.id
                             FROM visibilities
 								JOIN visibility_labels ON visibilities.id = visibility_labels.visibility_id
                             WHERE ((visibilities.id::text != ? AND
@@ -362,7 +405,7 @@ WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence
 										(SELECT visibility_id FROM visibility_labels WHERE (key::text = ? AND val::text IN (?, ?)))
 										INTERSECT
 										(SELECT visibility_id FROM visibility_labels WHERE (key::text = ? AND val::text != ?)))))
-                            ORDER BY visibilities.paging_sequence ASC
+                            ORDER BY visibilities.id ASC
                             LIMIT ?)
 SELECT visibilities.*,
        visibility_labels.id            "visibility_labels.id",
@@ -392,6 +435,63 @@ ORDER BY id ASC ;`)))
 				Expect(queryArgs[13]).Should(Equal("10"))
 			})
 		})
+		Context("when criteria are combined with logical operators", func() {
+			It("builds a valid query", func() {
+				fieldCriteria := query.ByAny(
+					query.ByField(query.EqualsOperator, "service_plan_id", "1"),
+					query.ByAll(
+						query.ByField(query.EqualsOperator, "service_plan_id", "2"),
+						query.ByField(query.InOperator, "platform_id", "3", "4"),
+					),
+				)
+				labelCriteria := query.ByNot(query.ByAny(
+					query.ByLabel(query.EqualsOperator, "left1", "right1"),
+					query.ByLabel(query.EqualsOperator, "left1", "right2"),
+				))
+
+				_, err := qb.NewQuery(entity).
+					WithCriteria(fieldCriteria, labelCriteria).
+					List(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence
+                            FROM visibilities
+                            WHERE ((visibilities.service_plan_id::text = ? OR
+                                    (visibilities.service_plan_id::text = ? AND visibilities.platform_id::text IN (?, ?))) AND
+                                   NOT ((visibilities.id IN (SELECT visibility_id FROM visibility_labels WHERE (key::text = ? AND val::text = ?)) OR
+                                         visibilities.id IN (SELECT visibility_id FROM visibility_labels WHERE (key::text = ? AND val::text = ?))))) )
+SELECT visibilities.*,
+       visibility_labels.id            "visibility_labels.id",
+       visibility_labels.key           "visibility_labels.key",
+       visibility_labels.val           "visibility_labels.val",
+       visibility_labels.created_at    "visibility_labels.created_at",
+       visibility_labels.updated_at    "visibility_labels.updated_at",
+       visibility_labels.visibility_id "visibility_labels.visibility_id"
+FROM visibilities
+	LEFT JOIN visibility_labels ON visibilities.id = visibility_labels.visibility_id
+WHERE visibilities.paging_sequence IN (SELECT matching_resources.paging_sequence FROM matching_resources)
+ORDER BY visibilities.paging_sequence ASC ;`)))
+				Expect(queryArgs).To(HaveLen(8))
+				Expect(queryArgs[0]).Should(Equal("1"))
+				Expect(queryArgs[1]).Should(Equal("2"))
+				Expect(queryArgs[2]).Should(Equal("3"))
+				Expect(queryArgs[3]).Should(Equal("4"))
+				Expect(queryArgs[4]).Should(Equal("left1"))
+				Expect(queryArgs[5]).Should(Equal("right1"))
+				Expect(queryArgs[6]).Should(Equal("left1"))
+				Expect(queryArgs[7]).Should(Equal("right2"))
+			})
+
+			Context("when nested field is missing", func() {
+				It("returns error", func() {
+					_, err := qb.NewQuery(entity).
+						WithCriteria(query.ByNot(query.ByField(query.EqualsOperator, "non-existing-field", "value"))).
+						List(ctx)
+					Expect(err).Should(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("unsupported field query key: non-existing-field"))
+				})
+			})
+		})
 	})
 
 	Describe("ListNoLabels", func() {
@@ -621,12 +721,12 @@ ORDER BY visibilities.paging_sequence ASC ;`)))
 					ListNoLabels(ctx)
 				Expect(err).ShouldNot(HaveOccurred())
 				Expect(executedQuery).Should(Equal(trim(`
-WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence
+WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence, visibilities.id
                             FROM visibilities
 							WHERE (visibilities.id::text != ? AND
                                     visibilities.service_plan_id::text NOT IN (?, ?, ?) AND
                                     (visibilities.platform_id::text = ? OR platform_id IS NULL))
-                            ORDER BY visibilities.paging_sequence ASC
+                            ORDER BY visibilities.id ASC
                             LIMIT ?)
 SELECT *
 FROM visibilities
@@ -1041,6 +1141,97 @@ LIMIT ?;`)))
 		})
 	})
 
+	Describe("CountGroups", func() {
+		var errNoRows = errors.New("no rows")
+
+		BeforeEach(func() {
+			db.QueryxContextStub = func(ctx context.Context, query string, args ...interface{}) (rows *sqlx.Rows, e error) {
+				executedQuery = query
+				queryArgs = args
+				return nil, errNoRows
+			}
+		})
+
+		AfterEach(func() {
+			db.QueryxContextStub = func(ctx context.Context, query string, args ...interface{}) (rows *sqlx.Rows, e error) {
+				executedQuery = query
+				queryArgs = args
+				return &sqlx.Rows{}, nil
+			}
+		})
+
+		Context("when grouping by fields and label keys", func() {
+			It("builds group by query joining the labels of each key", func() {
+				_, err := qb.NewQuery(entity).CountGroups(ctx, []string{"platform_id"}, []string{"env", "region"})
+				Expect(err).To(Equal(errNoRows))
+				Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT visibilities.id 
+							FROM visibilities ) 
+SELECT visibilities.platform_id, group_labels_0.val, group_labels_1.val, COUNT(DISTINCT visibilities.id) 
+FROM visibilities 
+	LEFT JOIN visibility_labels group_labels_0 ON visibilities.id = group_labels_0.visibility_id AND group_labels_0.key = ? 
+	LEFT JOIN visibility_labels group_labels_1 ON visibilities.id = group_labels_1.visibility_id AND group_labels_1.key = ? 
+WHERE visibilities.id IN 
+	(SELECT matching_resources.id FROM matching_resources) 
+GROUP BY 1, 2, 3 ORDER BY 4 DESC, 1, 2, 3;`)))
+				Expect(queryArgs).To(Equal([]interface{}{"env", "region"}))
+			})
+		})
+
+		Context("when field and label criteria are used", func() {
+			It("counts only the matching resources", func() {
+				_, err := qb.NewQuery(entity).
+					WithCriteria(
+						query.ByField(query.EqualsOperator, "service_plan_id", "plan"),
+						query.ByLabel(query.EqualsOperator, "labelKey", "labelValue")).
+					CountGroups(ctx, nil, []string{"env"})
+				Expect(err).To(Equal(errNoRows))
+				Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT visibilities.id 
+							FROM visibilities 
+							JOIN visibility_labels ON visibilities.id = visibility_labels.visibility_id 
+							WHERE (visibilities.service_plan_id::text = ? AND (key::text = ? AND val::text = ?))) 
+SELECT group_labels_0.val, COUNT(DISTINCT visibilities.id) 
+FROM visibilities 
+	LEFT JOIN visibility_labels group_labels_0 ON visibilities.id = group_labels_0.visibility_id AND group_labels_0.key = ? 
+WHERE visibilities.id IN 
+	(SELECT matching_resources.id FROM matching_resources) 
+GROUP BY 1 ORDER BY 2 DESC, 1;`)))
+				Expect(queryArgs).To(Equal([]interface{}{"plan", "labelKey", "labelValue", "env"}))
+			})
+		})
+
+		Context("when grouping by nothing", func() {
+			It("counts all matching resources", func() {
+				_, err := qb.NewQuery(entity).CountGroups(ctx, nil, nil)
+				Expect(err).To(Equal(errNoRows))
+				Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT visibilities.id 
+							FROM visibilities ) 
+SELECT COUNT(DISTINCT visibilities.id) 
+FROM visibilities 
+WHERE visibilities.id IN 
+	(SELECT matching_resources.id FROM matching_resources) ;`)))
+			})
+		})
+
+		Context("when grouping by unknown field", func() {
+			It("returns error", func() {
+				_, err := qb.NewQuery(entity).CountGroups(ctx, []string{"unknown"}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unsupported entity field for group by: unknown"))
+			})
+		})
+
+		Context("when limit criteria is used", func() {
+			It("returns error", func() {
+				_, err := qb.NewQuery(entity).WithCriteria(query.LimitResultBy(5)).CountGroups(ctx, []string{"platform_id"}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("limit is not supported when counting groups"))
+			})
+		})
+	})
+
 	Describe("Delete", func() {
 		Context("when entity does not have an associated label entity", func() {
 			It("returns error", func() {
@@ -27,19 +27,22 @@ import (
 )
 
 // ServiceBinding entity
+//
 //go:generate smgen storage ServiceBinding github.com/Peripli/service-manager/pkg/types
 type ServiceBinding struct {
 	BaseEntity
-	Name              string                 `db:"name"`
-	ServiceInstanceID string                 `db:"service_instance_id"`
-	SyslogDrainURL    sql.NullString         `db:"syslog_drain_url"`
-	RouteServiceURL   sql.NullString         `db:"route_service_url"`
-	VolumeMounts      sqlxtypes.NullJSONText `db:"volume_mounts"`
-	Endpoints         sqlxtypes.NullJSONText `db:"endpoints"`
-	Context           sqlxtypes.JSONText     `db:"context"`
-	BindResource      sqlxtypes.JSONText     `db:"bind_resource"`
-	Credentials       string                 `db:"credentials"`
-	Integrity         []byte                 `db:"integrity"`
+	Name                 string                 `db:"name"`
+	ServiceInstanceID    string                 `db:"service_instance_id"`
+	PredecessorBindingID sql.NullString         `db:"predecessor_binding_id"`
+	SyslogDrainURL       sql.NullString         `db:"syslog_drain_url"`
+	RouteServiceURL      sql.NullString         `db:"route_service_url"`
+	VolumeMounts         sqlxtypes.NullJSONText `db:"volume_mounts"`
+	Endpoints            sqlxtypes.NullJSONText `db:"endpoints"`
+	Context              sqlxtypes.JSONText     `db:"context"`
+	BindResource         sqlxtypes.JSONText     `db:"bind_resource"`
+	Credentials          string                 `db:"credentials"`
+	Metadata             sqlxtypes.NullJSONText `db:"metadata"`
+	Integrity            []byte                 `db:"integrity"`
 }
 
 func (sb *ServiceBinding) ToObject() (types.Object, error) {
@@ -52,16 +55,18 @@ func (sb *ServiceBinding) ToObject() (types.Object, error) {
 			PagingSequence: sb.PagingSequence,
 			Ready:          sb.Ready,
 		},
-		Name:              sb.Name,
-		ServiceInstanceID: sb.ServiceInstanceID,
-		SyslogDrainURL:    sb.SyslogDrainURL.String,
-		RouteServiceURL:   sb.RouteServiceURL.String,
-		VolumeMounts:      getJSONRawMessage(sb.VolumeMounts.JSONText),
-		Endpoints:         getJSONRawMessage(sb.Endpoints.JSONText),
-		Context:           getJSONRawMessage(sb.Context),
-		BindResource:      getJSONRawMessage(sb.BindResource),
-		Credentials:       getJSONRawMessageFromString(sb.Credentials),
-		Integrity:         sb.Integrity,
+		Name:                 sb.Name,
+		ServiceInstanceID:    sb.ServiceInstanceID,
+		PredecessorBindingID: sb.PredecessorBindingID.String,
+		SyslogDrainURL:       sb.SyslogDrainURL.String,
+		RouteServiceURL:      sb.RouteServiceURL.String,
+		VolumeMounts:         getJSONRawMessage(sb.VolumeMounts.JSONText),
+		Endpoints:            getJSONRawMessage(sb.Endpoints.JSONText),
+		Context:              getJSONRawMessage(sb.Context),
+		BindResource:         getJSONRawMessage(sb.BindResource),
+		Credentials:          getJSONRawMessageFromString(sb.Credentials),
+		Metadata:             getJSONRawMessage(sb.Metadata.JSONText),
+		Integrity:            sb.Integrity,
 	}, nil
 }
 
@@ -79,16 +84,18 @@ func (*ServiceBinding) FromObject(object types.Object) (storage.Entity, error) {
 			PagingSequence: serviceBinding.PagingSequence,
 			Ready:          serviceBinding.Ready,
 		},
-		Name:              serviceBinding.Name,
-		ServiceInstanceID: serviceBinding.ServiceInstanceID,
-		SyslogDrainURL:    toNullString(serviceBinding.SyslogDrainURL),
-		RouteServiceURL:   toNullString(serviceBinding.RouteServiceURL),
-		VolumeMounts:      getNullJSONText(serviceBinding.VolumeMounts),
-		Endpoints:         getNullJSONText(serviceBinding.Endpoints),
-		Context:           getJSONText(serviceBinding.Context),
-		BindResource:      getJSONText(serviceBinding.BindResource),
-		Credentials:       getStringFromJSONRawMessage(serviceBinding.Credentials),
-		Integrity:         serviceBinding.Integrity,
+		Name:                 serviceBinding.Name,
+		ServiceInstanceID:    serviceBinding.ServiceInstanceID,
+		PredecessorBindingID: toNullString(serviceBinding.PredecessorBindingID),
+		SyslogDrainURL:       toNullString(serviceBinding.SyslogDrainURL),
+		RouteServiceURL:      toNullString(serviceBinding.RouteServiceURL),
+		VolumeMounts:         getNullJSONText(serviceBinding.VolumeMounts),
+		Endpoints:            getNullJSONText(serviceBinding.Endpoints),
+		Context:              getJSONText(serviceBinding.Context),
+		BindResource:         getJSONText(serviceBinding.BindResource),
+		Credentials:          getStringFromJSONRawMessage(serviceBinding.Credentials),
+		Metadata:             getNullJSONText(serviceBinding.Metadata),
+		Integrity:            serviceBinding.Integrity,
 	}
 
 	return sb, nil
@@ -26,20 +26,22 @@ import (
 )
 
 // ServiceInstance entity
+//
 //go:generate smgen storage ServiceInstance github.com/Peripli/service-manager/pkg/types
 type ServiceInstance struct {
 	BaseEntity
-	Name                 string             `db:"name"`
-	ServicePlanID        string             `db:"service_plan_id"`
-	PlatformID           string             `db:"platform_id"`
-	ReferencedInstanceID sql.NullString     `db:"referenced_instance_id"`
-	DashboardURL         sql.NullString     `db:"dashboard_url"`
-	MaintenanceInfo      sqlxtypes.JSONText `db:"maintenance_info"`
-	Context              sqlxtypes.JSONText `db:"context"`
-	PreviousValues       sqlxtypes.JSONText `db:"previous_values"`
-	UpdateValues         sqlxtypes.JSONText `db:"update_values"`
-	Usable               bool               `db:"usable"`
-	Shared               sql.NullBool       `db:"shared"`
+	Name                 string                 `db:"name"`
+	ServicePlanID        string                 `db:"service_plan_id"`
+	PlatformID           string                 `db:"platform_id"`
+	ReferencedInstanceID sql.NullString         `db:"referenced_instance_id"`
+	DashboardURL         sql.NullString         `db:"dashboard_url"`
+	MaintenanceInfo      sqlxtypes.JSONText     `db:"maintenance_info"`
+	Context              sqlxtypes.JSONText     `db:"context"`
+	Metadata             sqlxtypes.NullJSONText `db:"metadata"`
+	PreviousValues       sqlxtypes.JSONText     `db:"previous_values"`
+	UpdateValues         sqlxtypes.JSONText     `db:"update_values"`
+	Usable               bool                   `db:"usable"`
+	Shared               sql.NullBool           `db:"shared"`
 }
 
 func (si *ServiceInstance) ToObject() (types.Object, error) {
@@ -65,6 +67,7 @@ func (si *ServiceInstance) ToObject() (types.Object, error) {
 		DashboardURL:         si.DashboardURL.String,
 		MaintenanceInfo:      getJSONRawMessage(si.MaintenanceInfo),
 		Context:              getJSONRawMessage(si.Context),
+		Metadata:             getJSONRawMessage(si.Metadata.JSONText),
 		PreviousValues:       getJSONRawMessage(si.PreviousValues),
 		UpdateValues:         updateValues,
 		Usable:               si.Usable,
@@ -98,6 +101,7 @@ func (*ServiceInstance) FromObject(object types.Object) (storage.Entity, error)
 		DashboardURL:         toNullString(serviceInstance.DashboardURL),
 		MaintenanceInfo:      getJSONText(serviceInstance.MaintenanceInfo),
 		Context:              getJSONText(serviceInstance.Context),
+		Metadata:             getNullJSONText(serviceInstance.Metadata),
 		PreviousValues:       getJSONText(serviceInstance.PreviousValues),
 		UpdateValues:         newStateBytes,
 		Usable:               serviceInstance.Usable,
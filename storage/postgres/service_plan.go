@@ -31,11 +31,12 @@ type ServicePlan struct {
 	Name        string `db:"name"`
 	Description string `db:"description"`
 
-	Free          bool         `db:"free"`
-	Bindable      sql.NullBool `db:"bindable"`
-	PlanUpdatable sql.NullBool `db:"plan_updateable"`
-	CatalogID     string       `db:"catalog_id"`
-	CatalogName   string       `db:"catalog_name"`
+	Free             bool         `db:"free"`
+	Bindable         sql.NullBool `db:"bindable"`
+	PlanUpdatable    sql.NullBool `db:"plan_updateable"`
+	BindingRotatable sql.NullBool `db:"binding_rotatable"`
+	CatalogID        string       `db:"catalog_id"`
+	CatalogName      string       `db:"catalog_name"`
 
 	Metadata               sqlxtypes.JSONText `db:"metadata"`
 	Schemas                sqlxtypes.JSONText `db:"schemas"`
@@ -61,6 +62,7 @@ func (sp *ServicePlan) ToObject() (types.Object, error) {
 		Free:                   &sp.Free,
 		Bindable:               toBoolPointer(sp.Bindable),
 		PlanUpdatable:          toBoolPointer(sp.PlanUpdatable),
+		BindingRotatable:       toBoolPointer(sp.BindingRotatable),
 		Metadata:               getJSONRawMessage(sp.Metadata),
 		Schemas:                getJSONRawMessage(sp.Schemas),
 		MaximumPollingDuration: sp.MaximumPollingDuration,
@@ -97,6 +99,7 @@ func (sp *ServicePlan) FromObject(object types.Object) (storage.Entity, error) {
 		Free:                   isFree(),
 		Bindable:               toNullBool(plan.Bindable),
 		PlanUpdatable:          toNullBool(plan.PlanUpdatable),
+		BindingRotatable:       toNullBool(plan.BindingRotatable),
 		CatalogID:              plan.CatalogID,
 		CatalogName:            plan.CatalogName,
 		Metadata:               getJSONText(plan.Metadata),
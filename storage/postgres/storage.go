@@ -48,13 +48,14 @@ const (
 type Storage struct {
 	ConnectFunc func(driver string, url string) (*sql.DB, error)
 
-	pgDB                  pgDB
-	db                    *sqlx.DB
-	queryBuilder          *QueryBuilder
-	state                 *storageState
-	layerOneEncryptionKey []byte
-	scheme                *scheme
-	mutex                 sync.Mutex
+	pgDB                          pgDB
+	db                            *sqlx.DB
+	queryBuilder                  *QueryBuilder
+	state                         *storageState
+	layerOneEncryptionKey         []byte
+	layerOnePreviousEncryptionKey []byte
+	scheme                        *scheme
+	mutex                         sync.Mutex
 }
 
 func (ps *Storage) Introduce(entity storage.Entity) {
@@ -111,6 +112,7 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 			storageCheckInterval: time.Second * 5,
 		}
 		ps.layerOneEncryptionKey = []byte(settings.EncryptionKey)
+		ps.layerOnePreviousEncryptionKey = []byte(settings.PreviousEncryptionKey)
 		ps.db.SetMaxIdleConns(settings.MaxIdleConnections)
 		ps.db.SetMaxOpenConns(settings.MaxOpenConnections)
 		ps.pgDB = ps.db
@@ -131,6 +133,8 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 		ps.scheme.introduce(&ServiceInstance{})
 		ps.scheme.introduce(&ServiceBinding{})
 		ps.scheme.introduce(&BrokerPlatformCredential{})
+		ps.scheme.introduce(&AuditEvent{})
+		ps.scheme.introduce(&TenantQuota{})
 	}
 
 	return nil
@@ -146,6 +150,12 @@ func (ps *Storage) Close() error {
 	return nil
 }
 
+// Stats returns the statistics of the database connection pool
+func (ps *Storage) Stats() sql.DBStats {
+	ps.checkOpen()
+	return ps.db.Stats()
+}
+
 func (ps *Storage) checkOpen() {
 	if ps.pgDB == nil {
 		log.D().Panicln("Storage is not yet open")
@@ -412,6 +422,14 @@ func (ps *Storage) CountLabelValues(ctx context.Context, objType types.ObjectTyp
 	return ps.queryBuilder.NewQuery(entity).WithCriteria(criteria...).CountLabelValues(ctx)
 }
 
+func (ps *Storage) CountGroups(ctx context.Context, objType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error) {
+	entity, err := ps.scheme.provide(objType)
+	if err != nil {
+		return nil, err
+	}
+	return ps.queryBuilder.NewQuery(entity).WithCriteria(criteria...).CountGroups(ctx, fields, labelKeys)
+}
+
 func (ps *Storage) DeleteReturning(ctx context.Context, objType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
 	entity, err := ps.scheme.provide(objType)
 	if err != nil {
@@ -544,11 +562,12 @@ func (ps *Storage) InTransaction(ctx context.Context, f func(ctx context.Context
 	}()
 
 	transactionalStorage := &Storage{
-		pgDB:                  tx,
-		db:                    ps.db,
-		queryBuilder:          NewQueryBuilder(tx),
-		scheme:                ps.scheme,
-		layerOneEncryptionKey: ps.layerOneEncryptionKey,
+		pgDB:                          tx,
+		db:                            ps.db,
+		queryBuilder:                  NewQueryBuilder(tx),
+		scheme:                        ps.scheme,
+		layerOneEncryptionKey:         ps.layerOneEncryptionKey,
+		layerOnePreviousEncryptionKey: ps.layerOnePreviousEncryptionKey,
 	}
 
 	if err = f(ctx, transactionalStorage); err != nil {
@@ -19,6 +19,7 @@ package postgres
 import (
 	"context"
 
+	"github.com/Peripli/service-manager/pkg/security"
 	"github.com/Peripli/service-manager/pkg/security/securityfakes"
 
 	"github.com/Peripli/service-manager/storage"
@@ -109,6 +110,8 @@ var _ = Describe("Postgres Storage", func() {
 						MigrationsURL:      "invalid",
 						EncryptionKey:      "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8",
 						Notification:       storage.DefaultNotificationSettings(),
+						KeyRotation:        storage.DefaultKeyRotationSettings(),
+						KeyProvider:        security.DefaultKeyProviderSettings(),
 						IntegrityProcessor: &securityfakes.FakeIntegrityProcessor{},
 					})
 					Expect(err).To(HaveOccurred())
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+)
+
+// TenantQuota entity
+//go:generate smgen storage TenantQuota github.com/Peripli/service-manager/pkg/types
+type TenantQuota struct {
+	BaseEntity
+	MaxInstances            sql.NullInt64      `db:"max_instances"`
+	MaxInstancesPerOffering sqlxtypes.JSONText `db:"max_instances_per_offering"`
+	MaxInstancesPerPlan     sqlxtypes.JSONText `db:"max_instances_per_plan"`
+	MaxBindingsPerInstance  sql.NullInt64      `db:"max_bindings_per_instance"`
+}
+
+func (e *TenantQuota) ToObject() (types.Object, error) {
+	var maxInstancesPerOffering, maxInstancesPerPlan map[string]int
+	if err := toJsonAsObject(e.MaxInstancesPerOffering, &maxInstancesPerOffering); err != nil {
+		return nil, err
+	}
+	if err := toJsonAsObject(e.MaxInstancesPerPlan, &maxInstancesPerPlan); err != nil {
+		return nil, err
+	}
+
+	return &types.TenantQuota{
+		Base: types.Base{
+			ID:             e.ID,
+			CreatedAt:      e.CreatedAt,
+			UpdatedAt:      e.UpdatedAt,
+			Labels:         map[string][]string{},
+			PagingSequence: e.PagingSequence,
+			Ready:          e.Ready,
+		},
+		MaxInstances:            toIntPointer(e.MaxInstances),
+		MaxInstancesPerOffering: maxInstancesPerOffering,
+		MaxInstancesPerPlan:     maxInstancesPerPlan,
+		MaxBindingsPerInstance:  toIntPointer(e.MaxBindingsPerInstance),
+	}, nil
+}
+
+func (*TenantQuota) FromObject(object types.Object) (storage.Entity, error) {
+	quota, ok := object.(*types.TenantQuota)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type TenantQuota")
+	}
+
+	maxInstancesPerOffering, err := json.Marshal(quota.MaxInstancesPerOffering)
+	if err != nil {
+		return nil, err
+	}
+	maxInstancesPerPlan, err := json.Marshal(quota.MaxInstancesPerPlan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TenantQuota{
+		BaseEntity: BaseEntity{
+			ID:             quota.ID,
+			CreatedAt:      quota.CreatedAt,
+			UpdatedAt:      quota.UpdatedAt,
+			PagingSequence: quota.PagingSequence,
+			Ready:          quota.Ready,
+		},
+		MaxInstances:            toNullInt64(quota.MaxInstances),
+		MaxInstancesPerOffering: getJSONText(maxInstancesPerOffering),
+		MaxInstancesPerPlan:     getJSONText(maxInstancesPerPlan),
+		MaxBindingsPerInstance:  toNullInt64(quota.MaxBindingsPerInstance),
+	}, nil
+}
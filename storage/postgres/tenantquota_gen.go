@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &TenantQuota{}
+
+const TenantQuotaTable = "tenant_quotas"
+
+func (*TenantQuota) LabelEntity() PostgresLabel {
+	return &TenantQuotaLabel{}
+}
+
+func (*TenantQuota) TableName() string {
+	return TenantQuotaTable
+}
+
+func (e *TenantQuota) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &TenantQuotaLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		TenantQuotaID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *TenantQuota) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*TenantQuota
+			TenantQuotaLabel `db:"tenant_quota_labels"`
+		}{}
+	}
+	result := &types.TenantQuotas{
+		TenantQuotas: make([]*types.TenantQuota, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type TenantQuotaLabel struct {
+	BaseLabelEntity
+	TenantQuotaID sql.NullString `db:"tenant_quota_id"`
+}
+
+func (el TenantQuotaLabel) LabelsTableName() string {
+	return "tenant_quota_labels"
+}
+
+func (el TenantQuotaLabel) ReferenceColumn() string {
+	return "tenant_quota_id"
+}
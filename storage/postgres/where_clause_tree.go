@@ -27,6 +27,7 @@ type logicalOperator string
 
 const (
 	AND       logicalOperator = "AND"
+	OR        logicalOperator = "OR"
 	INTERSECT logicalOperator = "INTERSECT"
 )
 
@@ -41,6 +42,12 @@ var defaultTreeSqlBuilder = &treeSqlBuilder{
 	},
 }
 
+var orTreeSqlBuilder = &treeSqlBuilder{
+	buildSQL: func(childrenSQL []string) string {
+		return fmt.Sprintf("(%s)", strings.Join(childrenSQL, fmt.Sprintf(" %s ", OR)))
+	},
+}
+
 // whereClauseTree represents an sql where clause as tree structure with AND/OR on the nodes
 type whereClauseTree struct {
 	criterion query.Criterion
@@ -49,6 +56,7 @@ type whereClauseTree struct {
 
 	children   []*whereClauseTree
 	sqlBuilder *treeSqlBuilder
+	negate     bool
 }
 
 func (t *whereClauseTree) isLeaf() bool {
@@ -92,6 +100,9 @@ func (t *whereClauseTree) compileSQL() (string, []interface{}) {
 		}
 		sql = t.sqlBuilder.buildSQL(childrenSQL)
 	}
+	if t.negate && len(sql) != 0 {
+		sql = fmt.Sprintf("NOT (%s)", sql)
+	}
 
 	return sql, queryParams
 }
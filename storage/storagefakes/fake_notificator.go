@@ -43,6 +43,16 @@ type FakeNotificator struct {
 	startReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StatsStub        func() storage.NotificatorStats
+	statsMutex       sync.RWMutex
+	statsArgsForCall []struct {
+	}
+	statsReturns struct {
+		result1 storage.NotificatorStats
+	}
+	statsReturnsOnCall map[int]struct {
+		result1 storage.NotificatorStats
+	}
 	UnregisterConsumerStub        func(storage.NotificationQueue) error
 	unregisterConsumerMutex       sync.RWMutex
 	unregisterConsumerArgsForCall []struct {
@@ -220,6 +230,59 @@ func (fake *FakeNotificator) StartReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeNotificator) Stats() storage.NotificatorStats {
+	fake.statsMutex.Lock()
+	ret, specificReturn := fake.statsReturnsOnCall[len(fake.statsArgsForCall)]
+	fake.statsArgsForCall = append(fake.statsArgsForCall, struct {
+	}{})
+	stub := fake.StatsStub
+	fakeReturns := fake.statsReturns
+	fake.recordInvocation("Stats", []interface{}{})
+	fake.statsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeNotificator) StatsCallCount() int {
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
+	return len(fake.statsArgsForCall)
+}
+
+func (fake *FakeNotificator) StatsCalls(stub func() storage.NotificatorStats) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = stub
+}
+
+func (fake *FakeNotificator) StatsReturns(result1 storage.NotificatorStats) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = nil
+	fake.statsReturns = struct {
+		result1 storage.NotificatorStats
+	}{result1}
+}
+
+func (fake *FakeNotificator) StatsReturnsOnCall(i int, result1 storage.NotificatorStats) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = nil
+	if fake.statsReturnsOnCall == nil {
+		fake.statsReturnsOnCall = make(map[int]struct {
+			result1 storage.NotificatorStats
+		})
+	}
+	fake.statsReturnsOnCall[i] = struct {
+		result1 storage.NotificatorStats
+	}{result1}
+}
+
 func (fake *FakeNotificator) UnregisterConsumer(arg1 storage.NotificationQueue) error {
 	fake.unregisterConsumerMutex.Lock()
 	ret, specificReturn := fake.unregisterConsumerReturnsOnCall[len(fake.unregisterConsumerArgsForCall)]
@@ -290,6 +353,8 @@ func (fake *FakeNotificator) Invocations() map[string][][]interface{} {
 	defer fake.registerFilterMutex.RUnlock()
 	fake.startMutex.RLock()
 	defer fake.startMutex.RUnlock()
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
 	fake.unregisterConsumerMutex.RLock()
 	defer fake.unregisterConsumerMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -36,6 +36,23 @@ type FakeStorage struct {
 		result1 int
 		result2 error
 	}
+	CountGroupsStub        func(context.Context, types.ObjectType, []string, []string, ...query.Criterion) ([]*types.GroupCount, error)
+	countGroupsMutex       sync.RWMutex
+	countGroupsArgsForCall []struct {
+		arg1 context.Context
+		arg2 types.ObjectType
+		arg3 []string
+		arg4 []string
+		arg5 []query.Criterion
+	}
+	countGroupsReturns struct {
+		result1 []*types.GroupCount
+		result2 error
+	}
+	countGroupsReturnsOnCall map[int]struct {
+		result1 []*types.GroupCount
+		result2 error
+	}
 	CountLabelValuesStub        func(context.Context, types.ObjectType, ...query.Criterion) (int, error)
 	countLabelValuesMutex       sync.RWMutex
 	countLabelValuesArgsForCall []struct {
@@ -372,6 +389,84 @@ func (fake *FakeStorage) CountReturnsOnCall(i int, result1 int, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeStorage) CountGroups(arg1 context.Context, arg2 types.ObjectType, arg3 []string, arg4 []string, arg5 ...query.Criterion) ([]*types.GroupCount, error) {
+	var arg3Copy []string
+	if arg3 != nil {
+		arg3Copy = make([]string, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	var arg4Copy []string
+	if arg4 != nil {
+		arg4Copy = make([]string, len(arg4))
+		copy(arg4Copy, arg4)
+	}
+	fake.countGroupsMutex.Lock()
+	ret, specificReturn := fake.countGroupsReturnsOnCall[len(fake.countGroupsArgsForCall)]
+	fake.countGroupsArgsForCall = append(fake.countGroupsArgsForCall, struct {
+		arg1 context.Context
+		arg2 types.ObjectType
+		arg3 []string
+		arg4 []string
+		arg5 []query.Criterion
+	}{arg1, arg2, arg3Copy, arg4Copy, arg5})
+	stub := fake.CountGroupsStub
+	fakeReturns := fake.countGroupsReturns
+	fake.recordInvocation("CountGroups", []interface{}{arg1, arg2, arg3Copy, arg4Copy, arg5})
+	fake.countGroupsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeStorage) CountGroupsCallCount() int {
+	fake.countGroupsMutex.RLock()
+	defer fake.countGroupsMutex.RUnlock()
+	return len(fake.countGroupsArgsForCall)
+}
+
+func (fake *FakeStorage) CountGroupsCalls(stub func(context.Context, types.ObjectType, []string, []string, ...query.Criterion) ([]*types.GroupCount, error)) {
+	fake.countGroupsMutex.Lock()
+	defer fake.countGroupsMutex.Unlock()
+	fake.CountGroupsStub = stub
+}
+
+func (fake *FakeStorage) CountGroupsArgsForCall(i int) (context.Context, types.ObjectType, []string, []string, []query.Criterion) {
+	fake.countGroupsMutex.RLock()
+	defer fake.countGroupsMutex.RUnlock()
+	argsForCall := fake.countGroupsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeStorage) CountGroupsReturns(result1 []*types.GroupCount, result2 error) {
+	fake.countGroupsMutex.Lock()
+	defer fake.countGroupsMutex.Unlock()
+	fake.CountGroupsStub = nil
+	fake.countGroupsReturns = struct {
+		result1 []*types.GroupCount
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorage) CountGroupsReturnsOnCall(i int, result1 []*types.GroupCount, result2 error) {
+	fake.countGroupsMutex.Lock()
+	defer fake.countGroupsMutex.Unlock()
+	fake.CountGroupsStub = nil
+	if fake.countGroupsReturnsOnCall == nil {
+		fake.countGroupsReturnsOnCall = make(map[int]struct {
+			result1 []*types.GroupCount
+			result2 error
+		})
+	}
+	fake.countGroupsReturnsOnCall[i] = struct {
+		result1 []*types.GroupCount
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeStorage) CountLabelValues(arg1 context.Context, arg2 types.ObjectType, arg3 ...query.Criterion) (int, error) {
 	fake.countLabelValuesMutex.Lock()
 	ret, specificReturn := fake.countLabelValuesReturnsOnCall[len(fake.countLabelValuesArgsForCall)]
@@ -1371,6 +1466,8 @@ func (fake *FakeStorage) Invocations() map[string][][]interface{} {
 	defer fake.closeMutex.RUnlock()
 	fake.countMutex.RLock()
 	defer fake.countMutex.RUnlock()
+	fake.countGroupsMutex.RLock()
+	defer fake.countGroupsMutex.RUnlock()
 	fake.countLabelValuesMutex.RLock()
 	defer fake.countLabelValuesMutex.RUnlock()
 	fake.createMutex.RLock()
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/tracing"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingDecorator decorates a repository to run each storage call in its own tracing span
+func TracingDecorator() TransactionalRepositoryDecorator {
+	return func(next TransactionalRepository) (TransactionalRepository, error) {
+		return NewTracingRepository(next), nil
+	}
+}
+
+// NewTracingRepository returns a new TransactionalTracingRepository wrapping the specified repository
+func NewTracingRepository(repository TransactionalRepository) *TransactionalTracingRepository {
+	return &TransactionalTracingRepository{
+		tracingRepository: &tracingRepository{
+			repository: repository,
+		},
+		repository: repository,
+	}
+}
+
+type tracingRepository struct {
+	repository Repository
+}
+
+// TransactionalTracingRepository is a TransactionalRepository which traces the storage calls and transactions
+type TransactionalTracingRepository struct {
+	*tracingRepository
+	repository TransactionalRepository
+}
+
+func (tr *tracingRepository) QueryForList(ctx context.Context, objectType types.ObjectType, queryName NamedQuery, queryParams map[string]interface{}) (types.ObjectList, error) {
+	ctx, span := startStorageSpan(ctx, "query", objectType)
+	objectList, err := tr.repository.QueryForList(ctx, objectType, queryName, queryParams)
+	endStorageSpan(span, err)
+	return objectList, err
+}
+
+func (tr *tracingRepository) Create(ctx context.Context, obj types.Object) (types.Object, error) {
+	ctx, span := startStorageSpan(ctx, "create", obj.GetType())
+	result, err := tr.repository.Create(ctx, obj)
+	endStorageSpan(span, err)
+	return result, err
+}
+
+func (tr *tracingRepository) Get(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+	ctx, span := startStorageSpan(ctx, "get", objectType)
+	obj, err := tr.repository.Get(ctx, objectType, criteria...)
+	endStorageSpan(span, err)
+	return obj, err
+}
+
+func (tr *tracingRepository) GetForUpdate(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+	ctx, span := startStorageSpan(ctx, "get for update", objectType)
+	obj, err := tr.repository.GetForUpdate(ctx, objectType, criteria...)
+	endStorageSpan(span, err)
+	return obj, err
+}
+
+func (tr *tracingRepository) List(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	ctx, span := startStorageSpan(ctx, "list", objectType)
+	objectList, err := tr.repository.List(ctx, objectType, criteria...)
+	endStorageSpan(span, err)
+	return objectList, err
+}
+
+func (tr *tracingRepository) ListNoLabels(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	ctx, span := startStorageSpan(ctx, "list", objectType)
+	objectList, err := tr.repository.ListNoLabels(ctx, objectType, criteria...)
+	endStorageSpan(span, err)
+	return objectList, err
+}
+
+func (tr *tracingRepository) Count(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+	ctx, span := startStorageSpan(ctx, "count", objectType)
+	count, err := tr.repository.Count(ctx, objectType, criteria...)
+	endStorageSpan(span, err)
+	return count, err
+}
+
+func (tr *tracingRepository) CountLabelValues(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+	ctx, span := startStorageSpan(ctx, "count label values", objectType)
+	count, err := tr.repository.CountLabelValues(ctx, objectType, criteria...)
+	endStorageSpan(span, err)
+	return count, err
+}
+
+func (tr *tracingRepository) CountGroups(ctx context.Context, objectType types.ObjectType, fields []string, labelKeys []string, criteria ...query.Criterion) ([]*types.GroupCount, error) {
+	ctx, span := startStorageSpan(ctx, "count groups", objectType)
+	groups, err := tr.repository.CountGroups(ctx, objectType, fields, labelKeys, criteria...)
+	endStorageSpan(span, err)
+	return groups, err
+}
+
+func (tr *tracingRepository) Update(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, criteria ...query.Criterion) (types.Object, error) {
+	ctx, span := startStorageSpan(ctx, "update", obj.GetType())
+	result, err := tr.repository.Update(ctx, obj, labelChanges, criteria...)
+	endStorageSpan(span, err)
+	return result, err
+}
+
+func (tr *tracingRepository) UpdateLabels(ctx context.Context, objectType types.ObjectType, objectID string, labelChanges types.LabelChanges, criteria ...query.Criterion) error {
+	ctx, span := startStorageSpan(ctx, "update labels", objectType)
+	err := tr.repository.UpdateLabels(ctx, objectType, objectID, labelChanges, criteria...)
+	endStorageSpan(span, err)
+	return err
+}
+
+func (tr *tracingRepository) DeleteReturning(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+	ctx, span := startStorageSpan(ctx, "delete", objectType)
+	objectList, err := tr.repository.DeleteReturning(ctx, objectType, criteria...)
+	endStorageSpan(span, err)
+	return objectList, err
+}
+
+func (tr *tracingRepository) Delete(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) error {
+	ctx, span := startStorageSpan(ctx, "delete", objectType)
+	err := tr.repository.Delete(ctx, objectType, criteria...)
+	endStorageSpan(span, err)
+	return err
+}
+
+func (tr *tracingRepository) GetEntities() []EntityMetadata {
+	return tr.repository.GetEntities()
+}
+
+// InTransaction runs the transaction in a span and wraps the repository passed in the transaction to trace the storage calls
+func (tr *TransactionalTracingRepository) InTransaction(ctx context.Context, f func(ctx context.Context, storage Repository) error) error {
+	ctx, span := tracing.StartSpan(ctx, "storage transaction")
+	err := tr.repository.InTransaction(ctx, func(ctx context.Context, storage Repository) error {
+		return f(ctx, &tracingRepository{
+			repository: storage,
+		})
+	})
+	endStorageSpan(span, err)
+	return err
+}
+
+func startStorageSpan(ctx context.Context, operation string, objectType types.ObjectType) (context.Context, trace.Span) {
+	return tracing.StartSpan(ctx, "storage "+operation+" "+objectType.String(), tracing.ResourceTypeKey.String(objectType.String()))
+}
+
+// endStorageSpan ends the span of a storage call. Objects which are not found are not considered a failure of the call.
+func endStorageSpan(span trace.Span, err error) {
+	if err == util.ErrNotFoundInStorage {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing Repository", func() {
+	var exporter *tracetest.InMemoryExporter
+	var fakeRepository *storagefakes.FakeStorage
+	var repository *storage.TransactionalTracingRepository
+
+	spanNames := func() []string {
+		var names []string
+		for _, span := range exporter.GetSpans() {
+			names = append(names, span.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		exporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.InTransactionStub = func(ctx context.Context, f func(ctx context.Context, storage storage.Repository) error) error {
+			return f(ctx, fakeRepository)
+		}
+		repository = storage.NewTracingRepository(fakeRepository)
+	})
+
+	AfterEach(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+
+	It("records a span for each storage call", func() {
+		_, err := repository.Count(context.TODO(), types.ServiceBrokerType)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(spanNames()).To(ConsistOf("storage count /v1/service_brokers"))
+	})
+
+	It("does not mark the span as failed when the object is not found", func() {
+		fakeRepository.GetReturns(nil, util.ErrNotFoundInStorage)
+		_, err := repository.Get(context.TODO(), types.ServiceBrokerType, query.ByField(query.EqualsOperator, "id", "id"))
+		Expect(err).To(Equal(util.ErrNotFoundInStorage))
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].StatusCode).To(Equal(codes.Unset))
+	})
+
+	It("traces the storage calls made in a transaction as children of the transaction span", func() {
+		err := repository.InTransaction(context.TODO(), func(ctx context.Context, storage storage.Repository) error {
+			_, err := storage.Count(ctx, types.PlatformType)
+			return err
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("storage count /v1/platforms"))
+		Expect(spans[1].Name).To(Equal("storage transaction"))
+		Expect(spans[0].Parent.SpanID()).To(Equal(spans[1].SpanContext.SpanID()))
+	})
+})
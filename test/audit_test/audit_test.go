@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+)
+
+func TestAudit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Audit Tests Suite")
+}
+
+var _ = Describe("Audit events", func() {
+	var ctx *common.TestContext
+
+	auditEventsOf := func(resourceID string) []interface{} {
+		return ctx.SMWithOAuth.GET(web.AuditEventsURL).
+			WithQuery("fieldQuery", "resource_id eq '"+resourceID+"'").
+			Expect().Status(http.StatusOK).JSON().Object().Value("items").Array().Raw()
+	}
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().WithEnvPreExtensions(func(set *pflag.FlagSet) {
+			Expect(set.Set("audit.enabled", "true")).ToNot(HaveOccurred())
+		}).Build()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	It("records the creation of a resource with the secured fields redacted", func() {
+		id := ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(common.Object{
+			"name": "audited-platform",
+			"type": "kubernetes",
+		}).Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+		events := auditEventsOf(id)
+		Expect(events).To(HaveLen(1))
+		event := events[0].(map[string]interface{})
+		Expect(event["resource"]).To(Equal(string(types.PlatformType)))
+		Expect(event["action"]).To(Equal(string(types.AuditCreate)))
+		Expect(event["outcome"]).To(Equal(string(types.AuditSuccess)))
+		Expect(event["actor"]).ToNot(BeEmpty())
+		Expect(event["correlation_id"]).ToNot(BeEmpty())
+
+		changes := event["changes"].(map[string]interface{})
+		Expect(changes["name"]).To(HaveKeyWithValue("after", "audited-platform"))
+		Expect(changes["credentials"]).To(HaveKeyWithValue("after", "[REDACTED]"))
+	})
+
+	It("records the changed fields of an updated resource", func() {
+		id := ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(common.Object{
+			"name": "audited-platform",
+			"type": "kubernetes",
+		}).Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/" + id).WithJSON(common.Object{
+			"description": "updated",
+		}).Expect().Status(http.StatusOK)
+
+		changes := ctx.SMWithOAuth.GET(web.AuditEventsURL).
+			WithQuery("fieldQuery", "resource_id eq '"+id+"' and action eq 'update'").
+			Expect().Status(http.StatusOK).JSON().Path("$.items[0].changes").Object()
+		changes.ContainsKey("description").NotContainsKey("name").NotContainsKey("credentials")
+		changes.Value("description").Object().ValueEqual("before", "").ValueEqual("after", "updated")
+	})
+
+	It("records the failed requests", func() {
+		ctx.SMWithOAuth.DELETE(web.PlatformsURL + "/missing-platform").Expect().Status(http.StatusNotFound)
+
+		events := auditEventsOf("missing-platform")
+		Expect(events).To(HaveLen(1))
+		event := events[0].(map[string]interface{})
+		Expect(event["action"]).To(Equal(string(types.AuditDelete)))
+		Expect(event["outcome"]).To(Equal(string(types.AuditFailure)))
+		Expect(event["status_code"]).To(BeNumerically("==", http.StatusNotFound))
+	})
+})
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authorization_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAuthorization(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Authorization Policies Tests Suite")
+}
+
+var _ = Describe("Authorization policies", func() {
+	var ctx *common.TestContext
+	var operator *common.SMExpect
+
+	newExpect := func(claims map[string]interface{}) *common.SMExpect {
+		token := ctx.Servers[common.OauthServer].(*common.OAuthServer).CreateToken(claims)
+		return &common.SMExpect{Expect: ctx.SM.Builder(func(req *httpexpect.Request) {
+			req.WithHeader("Authorization", "Bearer "+token)
+		})}
+	}
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().WithDefaultTokenClaims(map[string]interface{}{
+			"groups": []string{"admins"},
+		}).WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("authorization.enabled", true)
+			e.Set("authorization.roles", []interface{}{
+				map[string]interface{}{"role": "admin", "claim": "groups", "values": []string{"admins"}},
+				map[string]interface{}{"role": "operator", "claim": "groups", "values": []string{"operators"}},
+			})
+			e.Set("authorization.policies", []interface{}{
+				map[string]interface{}{
+					"role":         "admin",
+					"resources":    []string{"*"},
+					"methods":      []string{"*"},
+					"access_level": "global",
+				},
+				map[string]interface{}{
+					"role":         "operator",
+					"resources":    []string{web.PlatformsURL},
+					"methods":      []string{http.MethodGet, http.MethodPost, http.MethodPatch},
+					"label_query":  "region eq 'eu'",
+					"access_level": "global",
+				},
+			})
+		}).Build()
+
+		operator = newExpect(map[string]interface{}{
+			"groups": []string{"operators"},
+		})
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	It("leaves the requests of users without roles to the other authorizers", func() {
+		newExpect(map[string]interface{}{}).GET(web.PlatformsURL).Expect().Status(http.StatusOK)
+	})
+
+	It("denies the requests not allowed by the policies of the user roles", func() {
+		id := operator.POST(web.PlatformsURL).WithJSON(common.Object{
+			"name":   "eu-platform",
+			"type":   "kubernetes",
+			"labels": common.Object{"region": common.Array{"eu"}},
+		}).Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+		operator.PATCH(web.PlatformsURL + "/" + id).WithJSON(common.Object{
+			"labels": common.Array{common.Object{"op": "add", "key": "region", "values": common.Array{"us"}}},
+		}).Expect().Status(http.StatusForbidden).
+			JSON().Path("$.description").String().Contains("no policy of roles [operator] allows PATCH")
+	})
+
+	It("allows creating resources matching the label query of the policy", func() {
+		operator.POST(web.PlatformsURL).WithJSON(common.Object{
+			"name":   "eu-platform",
+			"type":   "kubernetes",
+			"labels": common.Object{"region": common.Array{"eu"}},
+		}).Expect().Status(http.StatusCreated)
+
+		operator.POST(web.PlatformsURL).WithJSON(common.Object{
+			"name":   "us-platform",
+			"type":   "kubernetes",
+			"labels": common.Object{"region": common.Array{"us"}},
+		}).Expect().Status(http.StatusForbidden)
+	})
+
+	It("lists only the resources matching the label query of the policy", func() {
+		operator.POST(web.PlatformsURL).WithJSON(common.Object{
+			"name":   "eu-platform",
+			"type":   "kubernetes",
+			"labels": common.Object{"region": common.Array{"eu"}},
+		}).Expect().Status(http.StatusCreated)
+		_, err := ctx.SMRepository.Create(context.Background(), &types.Platform{
+			Base: types.Base{
+				ID:        "us-platform",
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Labels:    types.Labels{"region": {"us"}},
+				Ready:     true,
+			},
+			Name: "us-platform",
+			Type: "kubernetes",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		var names []string
+		for _, platform := range operator.List(web.PlatformsURL).Iter() {
+			names = append(names, platform.Object().Value("name").String().Raw())
+		}
+		Expect(names).To(ConsistOf("eu-platform"))
+	})
+})
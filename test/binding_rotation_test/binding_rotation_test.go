@@ -0,0 +1,244 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package binding_rotation_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gavv/httpexpect"
+	"github.com/gofrs/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+func TestBindingRotation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Binding Rotation Tests Suite")
+}
+
+var _ = Describe("Binding rotation", func() {
+	var (
+		ctx                *common.TestContext
+		brokerServer       *common.BrokerServer
+		gracePeriod        time.Duration
+		nonRotatablePlanID string
+		instanceID         string
+		bindingID          string
+	)
+
+	newID := func() string {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		return UUID.String()
+	}
+
+	catalogPlan := func(rotatable bool) (string, string) {
+		catalogPlanID := newID()
+		plan, err := sjson.Set(common.GenerateTestPlanWithID(catalogPlanID), "binding_rotatable", rotatable)
+		Expect(err).ToNot(HaveOccurred())
+		return catalogPlanID, plan
+	}
+
+	planIDByCatalogID := func(catalogPlanID string) string {
+		return ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", catalogPlanID)).
+			First().Object().Value("id").String().Raw()
+	}
+
+	rotate := func(bindingID, async string) *httpexpect.Response {
+		return ctx.SMWithOAuthForTenant.POST(web.ServiceBindingsURL+"/"+bindingID+web.RotateURL).
+			WithQuery("async", async).
+			WithJSON(common.Object{}).
+			Expect()
+	}
+
+	brokerRequests := func(method, bindingID string) int {
+		count := 0
+		for _, req := range brokerServer.BindingEndpointRequests {
+			if req.Method == method && strings.HasSuffix(req.URL.Path, "/service_bindings/"+bindingID) {
+				count++
+			}
+		}
+		return count
+	}
+
+	JustBeforeEach(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("operations.binding_rotation_grace_period", gracePeriod)
+		}).Build()
+
+		rotatableCatalogPlanID, rotatablePlan := catalogPlan(true)
+		nonRotatableCatalogPlanID, nonRotatablePlan := catalogPlan(false)
+		catalog := common.NewEmptySBCatalog()
+		catalog.AddService(common.GenerateTestServiceWithPlans(rotatablePlan, nonRotatablePlan))
+
+		brokerUtils := ctx.RegisterBrokerWithCatalog(catalog)
+		brokerServer = brokerUtils.Broker.BrokerServer
+		brokerServer.ShouldRecordRequests(true)
+		common.CreateVisibilitiesForAllBrokerPlans(ctx.SMWithOAuth, brokerUtils.Broker.ID)
+
+		nonRotatablePlanID = planIDByCatalogID(nonRotatableCatalogPlanID)
+		instanceID = common.GetSMAAPProvisionInstanceFunc(ctx, "false", planIDByCatalogID(rotatableCatalogPlanID))()
+		bindingID = common.CreateBindingByInstanceID(ctx.SMWithOAuthForTenant, "false", http.StatusCreated, instanceID, "binding").
+			JSON().Object().Value("id").String().Raw()
+	})
+
+	BeforeEach(func() {
+		gracePeriod = 24 * time.Hour
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	Context("when rotating a binding synchronously", func() {
+		It("creates a successor binding with new credentials", func() {
+			successor := rotate(bindingID, "false").Status(http.StatusCreated).JSON().Object()
+
+			successor.Value("id").String().NotEqual(bindingID)
+			successor.Value("name").Equal("binding")
+			successor.Value("service_instance_id").Equal(instanceID)
+			successor.Value("predecessor_binding_id").Equal(bindingID)
+			successor.Path("$.credentials.user").Equal("user")
+			successor.Path("$.last_operation.description").Equal("binding rotation")
+
+			Expect(gjson.GetBytes(brokerServer.LastRequestBody, "predecessor_binding_id").String()).To(Equal(bindingID))
+			ctx.SMWithOAuthForTenant.GET(web.ServiceBindingsURL + "/" + bindingID).Expect().Status(http.StatusOK)
+		})
+	})
+
+	Context("when rotating a binding with parameters", func() {
+		It("creates the successor binding with the parameters of the rotated binding", func() {
+			brokerServer.BindingHandlerFunc(http.MethodGet, "", func(req *http.Request) (int, map[string]interface{}) {
+				return http.StatusOK, common.Object{
+					"credentials": common.Object{"user": "user"},
+					"parameters":  common.Object{"key": "value"},
+				}
+			})
+
+			rotate(bindingID, "false").Status(http.StatusCreated)
+
+			Expect(brokerRequests(http.MethodGet, bindingID)).To(Equal(1))
+			Expect(gjson.GetBytes(brokerServer.LastRequestBody, "parameters.key").String()).To(Equal("value"))
+		})
+	})
+
+	Context("when rotating a successor binding within the grace period", func() {
+		It("creates another successor binding with the same name", func() {
+			successorID := rotate(bindingID, "false").Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+			successor := rotate(successorID, "false").Status(http.StatusCreated).JSON().Object()
+
+			successor.Value("name").Equal("binding")
+			successor.Value("predecessor_binding_id").Equal(successorID)
+		})
+	})
+
+	Context("when rotating a binding asynchronously", func() {
+		It("schedules the creation of the successor binding", func() {
+			resp := rotate(bindingID, "true").Status(http.StatusAccepted)
+
+			successorID, _ := common.VerifyOperationExists(ctx, resp.Header("Location").Raw(), common.OperationExpectations{
+				Category:          types.CREATE,
+				State:             types.SUCCEEDED,
+				ResourceType:      types.ServiceBindingType,
+				Reschedulable:     false,
+				DeletionScheduled: false,
+			})
+
+			ctx.SMWithOAuthForTenant.GET(web.ServiceBindingsURL + "/" + successorID).Expect().Status(http.StatusOK).
+				JSON().Object().Value("predecessor_binding_id").Equal(bindingID)
+		})
+	})
+
+	Context("when the binding does not exist", func() {
+		It("returns 404", func() {
+			rotate(newID(), "false").Status(http.StatusNotFound)
+		})
+	})
+
+	Context("when the plan does not support binding rotation", func() {
+		It("returns 400", func() {
+			instanceID := ctx.SMWithOAuthForTenant.POST(web.ServiceInstancesURL).WithQuery("async", false).
+				WithJSON(common.Object{
+					"name":            "non-rotatable-instance",
+					"service_plan_id": nonRotatablePlanID,
+				}).
+				Expect().Status(http.StatusCreated).
+				JSON().Object().Value("id").String().Raw()
+			bindingID := common.CreateBindingByInstanceID(ctx.SMWithOAuthForTenant, "false", http.StatusCreated, instanceID, "binding").
+				JSON().Object().Value("id").String().Raw()
+
+			rotate(bindingID, "false").Status(http.StatusBadRequest)
+		})
+	})
+
+	Context("when the grace period of the rotated binding has not passed", func() {
+		It("keeps the rotated binding", func() {
+			rotate(bindingID, "false").Status(http.StatusCreated)
+
+			ctx.Maintainer.DeleteRotatedBindings()
+
+			Consistently(func() int {
+				return ctx.SMWithOAuthForTenant.GET(web.ServiceBindingsURL + "/" + bindingID).Expect().Raw().StatusCode
+			}, time.Second).Should(Equal(http.StatusOK))
+			Expect(brokerRequests(http.MethodDelete, bindingID)).To(BeZero())
+		})
+	})
+
+	Context("when the grace period of the rotated binding has passed", func() {
+		BeforeEach(func() {
+			gracePeriod = 0
+		})
+
+		It("deletes the rotated binding", func() {
+			successorID := rotate(bindingID, "false").Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+			ctx.Maintainer.DeleteRotatedBindings()
+
+			Eventually(func() int {
+				return ctx.SMWithOAuthForTenant.GET(web.ServiceBindingsURL + "/" + bindingID).Expect().Raw().StatusCode
+			}, 10*time.Second).Should(Equal(http.StatusNotFound))
+			Expect(brokerRequests(http.MethodDelete, bindingID)).To(Equal(1))
+			ctx.SMWithOAuthForTenant.GET(web.ServiceBindingsURL + "/" + successorID).Expect().Status(http.StatusOK)
+		})
+
+		It("deletes the rotated binding after the rotation operation is cleaned up", func() {
+			successorID := rotate(bindingID, "false").Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+			err := ctx.SMRepository.Delete(context.Background(), types.OperationType,
+				query.ByField(query.EqualsOperator, "resource_id", successorID))
+			Expect(err).ToNot(HaveOccurred())
+
+			ctx.Maintainer.DeleteRotatedBindings()
+
+			Eventually(func() int {
+				return ctx.SMWithOAuthForTenant.GET(web.ServiceBindingsURL + "/" + bindingID).Expect().Raw().StatusCode
+			}, 10*time.Second).Should(Equal(http.StatusNotFound))
+		})
+	})
+})
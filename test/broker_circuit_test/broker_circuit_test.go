@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package broker_circuit_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const openTimeout = time.Second
+
+func TestBrokerCircuit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Broker Circuit Tests Suite")
+}
+
+var _ = Describe("Broker circuit", func() {
+	var (
+		ctx              *common.TestContext
+		brokerID         string
+		brokerServer     *common.BrokerServer
+		lastOperationURL string
+	)
+
+	lastOperation := func() int {
+		return ctx.SMWithBasic.GET(lastOperationURL).Expect().Raw().StatusCode
+	}
+
+	serviceOfferingID := func() string {
+		return ctx.SMWithOAuth.ListWithQuery(web.ServiceOfferingsURL, fmt.Sprintf("fieldQuery=broker_id eq '%s'", brokerID)).
+			First().Object().Value("id").String().Raw()
+	}
+
+	brokerCircuit := func() string {
+		return ctx.SMWithOAuth.GET(web.ServiceBrokersURL + "/" + brokerID).Expect().
+			Status(http.StatusOK).JSON().Path("$.health.circuit").String().Raw()
+	}
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("operations.broker_circuit.failure_threshold", 2)
+			e.Set("operations.broker_circuit.open_timeout", openTimeout)
+		}).Build()
+
+		brokerUtils := ctx.RegisterBroker()
+		brokerID = brokerUtils.Broker.ID
+		brokerServer = brokerUtils.Broker.BrokerServer
+		lastOperationURL = fmt.Sprintf("%s/%s/v2/service_instances/instance-id/last_operation", web.OSBURL, brokerID)
+
+		brokerServer.ServiceInstanceLastOpHandler = func(rw http.ResponseWriter, req *http.Request) {
+			common.SetResponse(rw, http.StatusServiceUnavailable, common.Object{"description": "broker is down"})
+		}
+		Expect(lastOperation()).To(Equal(http.StatusServiceUnavailable))
+		Expect(lastOperation()).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	It("fails fast the requests to a broker which keeps failing", func() {
+		brokerServer.ResetCallHistory()
+
+		ctx.SMWithBasic.GET(lastOperationURL).Expect().
+			Status(http.StatusServiceUnavailable).
+			JSON().Object().Value("error").Equal("ServiceBrokerUnavailable")
+		Expect(brokerServer.ServiceInstanceLastOpEndpointRequests).To(BeEmpty())
+	})
+
+	It("fails fast the service instance operations towards the broker", func() {
+		common.CreateVisibilitiesForAllBrokerPlans(ctx.SMWithOAuth, brokerID)
+		planID := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=service_offering_id in ('%s')", serviceOfferingID())).
+			First().Object().Value("id").String().Raw()
+		brokerServer.ResetCallHistory()
+
+		ctx.SMWithOAuth.POST(web.ServiceInstancesURL).WithQuery("async", false).
+			WithJSON(common.Object{"name": "instance", "service_plan_id": planID, "maintenance_info": "{}"}).
+			Expect().Status(http.StatusBadGateway).
+			JSON().Object().Value("description").String().Contains("is unavailable")
+		Expect(brokerServer.ServiceInstanceEndpointRequests).To(BeEmpty())
+	})
+
+	It("reports the open circuit in the broker health", func() {
+		Expect(brokerCircuit()).To(Equal(string(operations.CircuitOpen)))
+	})
+
+	When("the broker recovers", func() {
+		It("closes the circuit after a successful probe request", func() {
+			brokerServer.ServiceInstanceLastOpHandler = func(rw http.ResponseWriter, req *http.Request) {
+				common.SetResponse(rw, http.StatusOK, common.Object{"state": "succeeded"})
+			}
+
+			Eventually(lastOperation, 3*openTimeout).Should(Equal(http.StatusOK))
+			Expect(brokerCircuit()).To(Equal(string(operations.CircuitClosed)))
+		})
+	})
+
+	When("the probe request fails", func() {
+		It("opens the circuit again", func() {
+			time.Sleep(openTimeout)
+			brokerServer.ResetCallHistory()
+
+			Expect(lastOperation()).To(Equal(http.StatusServiceUnavailable))
+			Expect(brokerServer.ServiceInstanceLastOpEndpointRequests).To(HaveLen(1))
+			Expect(lastOperation()).To(Equal(http.StatusServiceUnavailable))
+			Expect(brokerServer.ServiceInstanceLastOpEndpointRequests).To(HaveLen(1))
+			Expect(brokerCircuit()).To(Equal(string(operations.CircuitOpen)))
+		})
+	})
+})
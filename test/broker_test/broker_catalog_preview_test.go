@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package broker_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// describeCatalogPreviewTests describes the catalog preview tests. They run on the context of the generic broker
+// tests as all contexts share the same storage and a context of their own would clean up the generic test resources
+func describeCatalogPreviewTests(ctx *TestContext) bool {
+	return Context("catalog preview", func() {
+		var (
+			brokerID     string
+			brokerServer *BrokerServer
+			catalog      SBCatalog
+		)
+
+		previewURL := func(brokerID string) string {
+			return fmt.Sprintf("%s/%s%s", web.ServiceBrokersURL, brokerID, web.BrokerCatalogPreviewURL)
+		}
+
+		countPlansWithCatalogID := func(catalogID string) int {
+			count, err := ctx.SMRepository.Count(context.Background(), types.ServicePlanType, query.ByField(query.EqualsOperator, "catalog_id", catalogID))
+			Expect(err).ToNot(HaveOccurred())
+			return count
+		}
+
+		planIDForCatalogID := func(catalogID string) string {
+			plan, err := ctx.SMRepository.Get(context.Background(), types.ServicePlanType, query.ByField(query.EqualsOperator, "catalog_id", catalogID))
+			Expect(err).ToNot(HaveOccurred())
+			return plan.GetID()
+		}
+
+		BeforeEach(func() {
+			catalog = NewRandomSBCatalog()
+			brokerUtils := ctx.RegisterBrokerWithCatalog(catalog)
+			brokerID = brokerUtils.Broker.ID
+			brokerServer = brokerUtils.Broker.BrokerServer
+		})
+
+		AfterEach(func() {
+			ctx.SMWithOAuth.DELETE(web.ServiceBrokersURL + "/" + brokerID).Expect().Status(http.StatusOK)
+			brokerServer.Close()
+		})
+
+		Context("when the catalog of the broker has not changed", func() {
+			It("returns an empty diff", func() {
+				diff := ctx.SMWithOAuth.POST(previewURL(brokerID)).WithJSON(Object{}).Expect().Status(http.StatusOK).JSON().Object()
+
+				for _, changes := range []string{"service_offerings", "service_plans"} {
+					diff.Path(fmt.Sprintf("$.%s.added", changes)).Array().Empty()
+					diff.Path(fmt.Sprintf("$.%s.removed", changes)).Array().Empty()
+					diff.Path(fmt.Sprintf("$.%s.changed", changes)).Array().Empty()
+				}
+				diff.Value("blocked").Boolean().False()
+			})
+		})
+
+		Context("when the broker does not exist", func() {
+			It("returns 404", func() {
+				ctx.SMWithOAuth.POST(previewURL("missing")).WithJSON(Object{}).Expect().Status(http.StatusNotFound)
+			})
+		})
+
+		Context("when the request is made by a tenant", func() {
+			It("returns 404 for the brokers of other tenants", func() {
+				ctx.SMWithOAuthForTenant.POST(previewURL(brokerID)).WithJSON(Object{}).Expect().Status(http.StatusNotFound)
+			})
+
+			Context("when the broker belongs to the tenant", func() {
+				var (
+					tenantBrokerID     string
+					tenantBrokerServer *BrokerServer
+					removedPlanID      string
+					instanceID         string
+					tenantPlatformID   string
+					tenantVisibilityID string
+				)
+
+				BeforeEach(func() {
+					tenantCatalog := NewRandomSBCatalog()
+					brokerUtils := ctx.RegisterBrokerWithCatalogAndLabelsExpect(tenantCatalog, Object{}, ctx.SMWithOAuthForTenant, http.StatusCreated)
+					tenantBrokerID = brokerUtils.Broker.ID
+					tenantBrokerServer = brokerUtils.Broker.BrokerServer
+					removedPlanID = planIDForCatalogID(gjson.Get(string(tenantCatalog), "services.0.plans.0.id").String())
+
+					// the instance and the visibility of the test platform belong to no tenant
+					instanceID = CreateInstanceInPlatformForPlan(ctx, ctx.TestPlatform.ID, removedPlanID, false).ID
+					RegisterVisibilityForPlanAndPlatform(ctx.SMWithOAuth, removedPlanID, ctx.TestPlatform.ID)
+					tenantPlatformID = ctx.RegisterTenantPlatform().ID
+					tenantVisibilityID = RegisterVisibilityForPlanAndPlatform(ctx.SMWithOAuth, removedPlanID, tenantPlatformID)
+
+					newCatalog := tenantCatalog
+					newCatalog.RemovePlan(0, 0)
+					tenantBrokerServer.Catalog = newCatalog
+				})
+
+				AfterEach(func() {
+					Expect(DeleteInstance(ctx, instanceID, removedPlanID)).To(Succeed())
+					ctx.SMWithOAuth.DELETE(web.ServiceBrokersURL + "/" + tenantBrokerID).Expect().Status(http.StatusOK)
+					ctx.SMWithOAuth.DELETE(web.PlatformsURL + "/" + tenantPlatformID).Expect().Status(http.StatusOK)
+					tenantBrokerServer.Close()
+				})
+
+				It("returns only the instances and visibilities of the tenant", func() {
+					diff := ctx.SMWithOAuthForTenant.POST(previewURL(tenantBrokerID)).WithJSON(Object{}).
+						Expect().Status(http.StatusOK).JSON().Object()
+
+					diff.Path("$.service_plans.removed").Array().Length().Equal(1)
+					visibilities := diff.Value("dropped_visibilities").Array()
+					visibilities.Length().Equal(1)
+					visibilities.First().Object().Value("id").Equal(tenantVisibilityID)
+					diff.Value("blocking_instances").Array().Empty()
+					diff.Value("blocked").Boolean().True()
+				})
+			})
+		})
+
+		Context("when the catalog of the broker has changed", func() {
+			var (
+				removedPlanCatalogID string
+				removedPlanID        string
+				changedPlanCatalogID string
+				addedPlanCatalogID   string
+				instanceID           string
+				visibilityID         string
+			)
+
+			BeforeEach(func() {
+				removedPlanCatalogID = gjson.Get(string(catalog), "services.0.plans.0.id").String()
+				removedPlanID = planIDForCatalogID(removedPlanCatalogID)
+				changedPlanCatalogID = gjson.Get(string(catalog), "services.0.plans.1.id").String()
+
+				instanceID = CreateInstanceInPlatformForPlan(ctx, ctx.TestPlatform.ID, removedPlanID, false).ID
+				visibilityID = RegisterVisibilityForPlanAndPlatform(ctx.SMWithOAuth, removedPlanID, ctx.TestPlatform.ID)
+
+				newCatalog := catalog
+				changedCatalog, err := sjson.Set(string(newCatalog), "services.0.plans.1.description", "changed description")
+				Expect(err).ToNot(HaveOccurred())
+				newCatalog = SBCatalog(changedCatalog)
+				newCatalog.RemovePlan(0, 0)
+				addedPlan := GenerateFreeTestPlan()
+				addedPlanCatalogID = gjson.Get(addedPlan, "id").String()
+				newCatalog.AddPlanToService(addedPlan, 1)
+				brokerServer.Catalog = newCatalog
+			})
+
+			AfterEach(func() {
+				Expect(DeleteInstance(ctx, instanceID, removedPlanID)).To(Succeed())
+			})
+
+			It("returns the changes without applying them", func() {
+				diff := ctx.SMWithOAuth.POST(previewURL(brokerID)).WithJSON(Object{}).Expect().Status(http.StatusOK).JSON().Object()
+
+				diff.Path("$.service_offerings.changed").Array().Empty()
+
+				added := diff.Path("$.service_plans.added").Array()
+				added.Length().Equal(1)
+				added.First().Object().Value("catalog_id").Equal(addedPlanCatalogID)
+				added.First().Object().NotContainsKey("id")
+
+				removed := diff.Path("$.service_plans.removed").Array()
+				removed.Length().Equal(1)
+				removed.First().Object().Value("id").Equal(removedPlanID)
+
+				changed := diff.Path("$.service_plans.changed").Array()
+				changed.Length().Equal(1)
+				changed.First().Object().Value("catalog_id").Equal(changedPlanCatalogID)
+				changed.First().Object().Value("changed_fields").Array().ContainsOnly("description")
+
+				visibilities := diff.Value("dropped_visibilities").Array()
+				visibilities.Length().Equal(1)
+				visibilities.First().Object().Value("id").Equal(visibilityID)
+
+				instances := diff.Value("blocking_instances").Array()
+				instances.Length().Equal(1)
+				instances.First().Object().ValueEqual("id", instanceID).ValueEqual("reason", "plan_removed")
+				diff.Value("blocked").Boolean().True()
+
+				Expect(countPlansWithCatalogID(removedPlanCatalogID)).To(Equal(1))
+				Expect(countPlansWithCatalogID(addedPlanCatalogID)).To(Equal(0))
+				_, err := ctx.SMRepository.Get(context.Background(), types.VisibilityType, query.ByField(query.EqualsOperator, "id", visibilityID))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			When("a service offering is removed", func() {
+				BeforeEach(func() {
+					newCatalog := brokerServer.Catalog
+					newCatalog.RemoveService(0)
+					brokerServer.Catalog = newCatalog
+				})
+
+				It("returns the plans of the offering as removed", func() {
+					diff := ctx.SMWithOAuth.POST(previewURL(brokerID)).WithJSON(Object{}).Expect().Status(http.StatusOK).JSON().Object()
+
+					diff.Path("$.service_offerings.removed").Array().Length().Equal(1)
+					diff.Path("$.service_offerings.removed").Array().First().Object().
+						Value("catalog_id").Equal(gjson.Get(string(catalog), "services.0.id").String())
+					diff.Path("$.service_plans.removed").Array().Length().Equal(4)
+					diff.Path("$.service_plans.changed").Array().Empty()
+				})
+			})
+		})
+	})
+}
@@ -76,7 +76,7 @@ var _ = test.DescribeTestsFor(test.TestCase{
 	SupportsAsyncOperations:                true,
 	ResourceBlueprint:                      blueprint(true),
 	ResourceWithoutNullableFieldsBlueprint: blueprint(false),
-	ResourcePropertiesToIgnore:             []string{"last_operation"},
+	ResourcePropertiesToIgnore:             []string{"last_operation", "api_version"},
 	PatchResource:                          test.APIResourcePatch,
 	AdditionalTests: func(ctx *TestContext, t *test.TestCase) {
 		Context("additional non-generic tests", func() {
@@ -3041,6 +3041,8 @@ var _ = test.DescribeTestsFor(test.TestCase{
 				})
 			})
 		})
+
+		describeCatalogPreviewTests(ctx)
 	},
 })
 
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package catalog_refresh_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+)
+
+const refreshLabelKey = "catalog_refresh_interval"
+
+func TestCatalogRefresh(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Catalog Refresh Tests Suite")
+}
+
+var _ = Describe("Catalog refresh", func() {
+	var (
+		ctx          *common.TestContext
+		brokerID     string
+		brokerServer *common.BrokerServer
+		catalog      common.SBCatalog
+	)
+
+	plansCount := func(catalogID string) func() int {
+		return func() int {
+			return int(ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", catalogID)).Length().Raw())
+		}
+	}
+
+	refreshOperations := func() []*types.Operation {
+		list, err := ctx.SMRepository.List(context.Background(), types.OperationType,
+			query.ByField(query.EqualsOperator, "resource_id", brokerID),
+			query.ByField(query.EqualsOperator, "description", operations.CatalogRefreshDescription))
+		Expect(err).ToNot(HaveOccurred())
+		return list.(*types.Operations).Operations
+	}
+
+	labelBroker := func(interval string) {
+		ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).
+			WithJSON(common.Object{
+				"labels": []*types.LabelChange{{Operation: types.AddLabelOperation, Key: refreshLabelKey, Values: []string{interval}}},
+			}).
+			Expect().Status(http.StatusOK)
+	}
+
+	addPlan := func() string {
+		plan := common.GeneratePaidTestPlan()
+		catalog.AddPlanToService(plan, 0)
+		brokerServer.Catalog = catalog
+		return gjson.Get(plan, "id").String()
+	}
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("operations.catalog_refresh.check_interval", "100ms")
+		}).Build()
+
+		catalog = common.NewEmptySBCatalog()
+		catalog.AddService(common.GenerateTestServiceWithPlans(common.GeneratePaidTestPlan()))
+		brokerUtils := ctx.RegisterBrokerWithCatalog(catalog)
+		brokerID = brokerUtils.Broker.ID
+		brokerServer = brokerUtils.Broker.BrokerServer
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	When("the broker has a catalog refresh interval label", func() {
+		It("refreshes the broker catalog periodically", func() {
+			labelBroker("1s")
+			planID := addPlan()
+
+			Eventually(plansCount(planID), "5s").Should(Equal(1))
+			Expect(refreshOperations()).ToNot(BeEmpty())
+			Expect(refreshOperations()[0].Type).To(Equal(types.UPDATE))
+		})
+
+		It("keeps the changes made to the broker since the brokers were listed", func() {
+			labelBroker("1s")
+			ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).
+				WithJSON(common.Object{"description": "changed"}).
+				Expect().Status(http.StatusOK)
+			patchedAt := time.Now()
+
+			Eventually(func() bool {
+				for _, operation := range refreshOperations() {
+					if operation.State == types.SUCCEEDED && operation.CreatedAt.After(patchedAt) {
+						return true
+					}
+				}
+				return false
+			}, "5s").Should(BeTrue())
+			ctx.SMWithOAuth.GET(web.ServiceBrokersURL + "/" + brokerID).
+				Expect().Status(http.StatusOK).
+				JSON().Object().Value("description").Equal("changed")
+		})
+
+		When("the catalog refresh fails", func() {
+			It("records the failed refresh as a failed operation of the broker", func() {
+				labelBroker("1s")
+				brokerServer.CatalogHandler = func(rw http.ResponseWriter, req *http.Request) {
+					common.SetResponse(rw, http.StatusInternalServerError, common.Object{})
+				}
+
+				Eventually(func() types.OperationState {
+					operations := refreshOperations()
+					if len(operations) == 0 {
+						return ""
+					}
+					return operations[0].State
+				}, "5s").Should(Equal(types.FAILED))
+			})
+		})
+	})
+
+	When("the broker catalog refresh is disabled", func() {
+		It("does not refresh the broker catalog", func() {
+			labelBroker("0")
+			planID := addPlan()
+
+			Consistently(plansCount(planID), "1500ms").Should(Equal(0))
+			Expect(refreshOperations()).To(BeEmpty())
+		})
+	})
+})
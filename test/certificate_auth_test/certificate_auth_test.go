@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate_auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	certificateHeader = "X-Forwarded-Client-Cert"
+	platformID        = "certificate-platform"
+)
+
+func TestCertificateAuthentication(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Certificate Authentication Tests Suite")
+}
+
+var _ = Describe("Client certificate authentication", func() {
+	var (
+		ctx    *common.TestContext
+		dir    string
+		caKey  *ecdsa.PrivateKey
+		caCert *x509.Certificate
+	)
+
+	issue := func(commonName string) string {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: commonName},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		Expect(err).ToNot(HaveOccurred())
+		return url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	}
+
+	withCertificate := func(commonName string) *common.SMExpect {
+		certificate := issue(commonName)
+		return &common.SMExpect{Expect: ctx.SM.Builder(func(req *httpexpect.Request) {
+			req.WithHeader(certificateHeader, certificate)
+		})}
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "certificates")
+		Expect(err).ToNot(HaveOccurred())
+
+		caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "clients-ca"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+		Expect(err).ToNot(HaveOccurred())
+		caCert, err = x509.ParseCertificate(der)
+		Expect(err).ToNot(HaveOccurred())
+		caFile := filepath.Join(dir, "ca.pem")
+		Expect(ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+
+		ctx = common.NewTestContextBuilderWithSecurity().WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("clientcertificates.enabled", true)
+			e.Set("clientcertificates.ca_file", caFile)
+			e.Set("clientcertificates.header", certificateHeader)
+			e.Set("clientcertificates.trusted_proxies", []string{"127.0.0.1", "::1"})
+			e.Set("clientcertificates.mappings", []interface{}{
+				map[string]interface{}{"subject": "CN=operator", "client": "operator", "access_level": "global"},
+				map[string]interface{}{"subject": "CN=agent", "platform_id": platformID},
+			})
+		}).Build()
+
+		ctx.SMWithOAuth.POST(web.PlatformsURL).
+			WithJSON(common.MakePlatform(platformID, "certificate-platform", "kubernetes", "")).
+			Expect().Status(http.StatusCreated)
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("authenticates API clients with mapped certificates", func() {
+		withCertificate("operator").GET(web.PlatformsURL + "/" + platformID).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Value("id").Equal(platformID)
+	})
+
+	It("authenticates platforms with mapped certificates", func() {
+		withCertificate("agent").GET(web.ServiceOfferingsURL).
+			Expect().Status(http.StatusOK)
+	})
+
+	It("rejects certificates which are not mapped", func() {
+		withCertificate("unknown").GET(web.PlatformsURL).
+			Expect().Status(http.StatusUnauthorized)
+	})
+
+	It("rejects invalid certificates", func() {
+		ctx.SM.GET(web.PlatformsURL).
+			WithHeader(certificateHeader, "invalid").
+			Expect().Status(http.StatusUnauthorized)
+	})
+
+	It("keeps authenticating the requests without certificates with the other authenticators", func() {
+		ctx.SMWithOAuth.GET(web.PlatformsURL).
+			Expect().Status(http.StatusOK)
+		ctx.SMWithBasic.GET(web.ServiceOfferingsURL).
+			Expect().Status(http.StatusOK)
+	})
+})
@@ -627,6 +627,14 @@ func newSMServer(smEnv env.Environment, wg *sync.WaitGroup, fs []func(ctx contex
 	if err != nil {
 		panic(err)
 	}
+	err = smb.WebhookDeliverer.Start(ctx, wg)
+	if err != nil {
+		panic(err)
+	}
+	err = smb.CatalogRefresher.Start(ctx, wg)
+	if err != nil {
+		panic(err)
+	}
 
 	testServer := httptest.NewUnstartedServer(serviceManager.Server.Router)
 	if listener != nil {
@@ -634,7 +642,7 @@ func newSMServer(smEnv env.Environment, wg *sync.WaitGroup, fs []func(ctx contex
 		testServer.Listener = listener
 	}
 	testServer.Start()
-	scheduler := operations.NewScheduler(ctx, smb.Storage, cfg.Operations, 1000, wg)
+	scheduler := operations.NewScheduler(ctx, smb.Storage, cfg.Operations, nil, 1000, wg)
 	return &testSMServer{
 		cancel: cancel,
 		Server: testServer,
@@ -10,11 +10,15 @@ import (
 
 	"github.com/benjamintf1/unmarshalledmatchers"
 
+	"github.com/Peripli/service-manager/pkg/env"
 	"github.com/Peripli/service-manager/pkg/log"
 
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/web"
 
 	"github.com/Peripli/service-manager/test/common"
+	"github.com/spf13/pflag"
 
 	. "github.com/onsi/ginkgo"
 
@@ -34,7 +38,24 @@ var _ = Describe("Service Manager Config API", func() {
 	)
 
 	BeforeSuite(func() {
-		ctx = common.NewTestContextBuilder().Build()
+		ctx = common.NewTestContextBuilderWithSecurity().WithDefaultTokenClaims(map[string]interface{}{
+			"groups": []string{"admins"},
+		}).WithEnvPreExtensions(func(set *pflag.FlagSet) {
+			Expect(set.Set("storage.key_rotation.refresh_interval", "10ms")).To(Succeed())
+		}).WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("authorization.enabled", true)
+			e.Set("authorization.roles", []interface{}{
+				map[string]interface{}{"role": "admin", "claim": "groups", "values": []string{"admins"}},
+			})
+			e.Set("authorization.policies", []interface{}{
+				map[string]interface{}{
+					"role":         "admin",
+					"resources":    []string{"*"},
+					"methods":      []string{"*"},
+					"access_level": "global",
+				},
+			})
+		}).Build()
 	})
 
 	AfterSuite(func() {
@@ -219,4 +240,52 @@ var _ = Describe("Service Manager Config API", func() {
 			})
 		})
 	})
+
+	Describe("Key rotation API", func() {
+		Context("POST", func() {
+			It("rotates the encryption key and reports the progress", func() {
+				ctx.SMWithOAuth.GET(web.KeyRotationURL).
+					Expect().
+					Status(http.StatusNotFound)
+
+				platform := ctx.RegisterPlatform()
+
+				ctx.SMWithOAuth.POST(web.KeyRotationURL).
+					WithJSON(common.Object{}).
+					Expect().
+					Status(http.StatusAccepted).JSON().Object().ValueEqual("state", "in progress")
+
+				Eventually(func() string {
+					return ctx.SMWithOAuth.GET(web.KeyRotationURL).
+						Expect().
+						Status(http.StatusOK).JSON().Object().Value("state").String().Raw()
+				}).Should(Equal("succeeded"))
+
+				ctx.SMWithOAuth.GET(web.KeyRotationURL).
+					Expect().
+					Status(http.StatusOK).JSON().Object().
+					Value("reencrypted").Object().Value(string(types.PlatformType)).Number().Ge(1)
+
+				byID := query.ByField(query.EqualsOperator, "id", platform.ID)
+				object, err := ctx.SMRepository.Get(context.TODO(), types.PlatformType, byID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(object.(*types.Platform).Credentials.Basic.Password).To(Equal(platform.Credentials.Basic.Password))
+			})
+
+			It("returns 403 for users without global access", func() {
+				ctx.SMWithOAuthForTenant.POST(web.KeyRotationURL).
+					WithJSON(common.Object{}).
+					Expect().
+					Status(http.StatusForbidden)
+			})
+		})
+
+		Context("GET", func() {
+			It("returns 403 for users without global access", func() {
+				ctx.SMWithOAuthForTenant.GET(web.KeyRotationURL).
+					Expect().
+					Status(http.StatusForbidden)
+			})
+		})
+	})
 })
@@ -0,0 +1,258 @@
+package events_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/sm"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const settlePeriod = time.Second
+
+func TestEvents(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Events API Tests Suite")
+}
+
+var _ = Describe("events API", func() {
+	var (
+		ctx      *common.TestContext
+		revision int64
+	)
+
+	newID := func() string {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		return UUID.String()
+	}
+
+	BeforeSuite(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().
+			WithTenantTokenClaims(map[string]interface{}{
+				"cid": "tenancyClient",
+				"zid": "tenantID",
+			}).
+			WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+				e.Set("api.events_settle_period", settlePeriod)
+			}).
+			WithSMExtensions(func(ctx context.Context, smb *sm.ServiceManagerBuilder, e env.Environment) error {
+				_, err := smb.EnableMultitenancy("tenant", common.ExtractTenantFunc)
+				return err
+			}).
+			Build()
+	})
+
+	AfterSuite(func() {
+		ctx.Cleanup()
+	})
+
+	BeforeEach(func() {
+		// skip the events recorded so far by paging until the end of the feed
+		revision = 0
+		for {
+			page := ctx.SMWithOAuth.GET(web.EventsURL).
+				WithQuery("since", revision).
+				WithQuery("max_items", 200).
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object()
+			revision = int64(page.Value("revision").Number().Raw())
+			if len(page.Value("items").Array().Raw()) == 0 {
+				break
+			}
+		}
+	})
+
+	Context("when a broker is registered", func() {
+		var brokerID string
+
+		BeforeEach(func() {
+			brokerUtils := ctx.RegisterBroker()
+			brokerID = brokerUtils.Broker.ID
+			time.Sleep(settlePeriod)
+		})
+
+		AfterEach(func() {
+			ctx.CleanupBroker(brokerID)
+		})
+
+		It("should return the broker and plans creation events in revision order", func() {
+			items := ctx.SMWithOAuth.GET(web.EventsURL).
+				WithQuery("since", revision).
+				WithQuery("types", "service_brokers,service_plans").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().Value("items").Array()
+
+			items.NotEmpty()
+			previousRevision := revision
+			brokerEventFound := false
+			for _, item := range items.Iter() {
+				event := item.Object()
+				event.Value("resource").String().Match("^service_(brokers|plans)$")
+				currentRevision := int64(event.Value("revision").Number().Raw())
+				Expect(currentRevision).To(BeNumerically(">", previousRevision))
+				previousRevision = currentRevision
+				if event.Value("resource").String().Raw() == "service_brokers" &&
+					event.Path("$.payload.new.resource.id").String().Raw() == brokerID {
+					brokerEventFound = true
+					event.Value("type").String().Equal(string(types.CREATED))
+					event.Path("$.payload.new.resource").Object().NotContainsKey("credentials")
+				}
+			}
+			Expect(brokerEventFound).To(BeTrue())
+		})
+
+		It("should page through the events using since", func() {
+			firstPage := ctx.SMWithOAuth.GET(web.EventsURL).
+				WithQuery("since", revision).
+				WithQuery("max_items", 1).
+				Expect().
+				Status(http.StatusOK)
+			firstPage.Header("Link").NotEmpty()
+			page := firstPage.JSON().Object()
+			page.Value("items").Array().Length().Equal(1)
+			nextRevision := int64(page.Value("revision").Number().Raw())
+			Expect(nextRevision).To(BeNumerically(">", revision))
+
+			ctx.SMWithOAuth.GET(web.EventsURL).
+				WithQuery("since", nextRevision).
+				WithQuery("max_items", 1).
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().Value("items").Array().First().Object().
+				Value("revision").Number().Gt(float64(nextRevision))
+		})
+
+		Context("and the broker is deleted", func() {
+			It("should return a deletion event", func() {
+				ctx.SMWithOAuth.DELETE(web.ServiceBrokersURL + "/" + brokerID).
+					Expect().
+					Status(http.StatusOK)
+				time.Sleep(settlePeriod)
+
+				items := ctx.SMWithOAuth.GET(web.EventsURL).
+					WithQuery("since", revision).
+					WithQuery("types", "service_brokers").
+					Expect().
+					Status(http.StatusOK).
+					JSON().Object().Value("items").Array()
+				items.Last().Object().Value("type").String().Equal(string(types.DELETED))
+				items.Last().Object().Path("$.payload.old.resource.id").String().Equal(brokerID)
+			})
+		})
+	})
+
+	Context("when the changes have not settled yet", func() {
+		It("should not return them", func() {
+			brokerID := ctx.RegisterBroker().Broker.ID
+			defer ctx.CleanupBroker(brokerID)
+
+			ctx.SMWithOAuth.GET(web.EventsURL).
+				WithQuery("since", revision).
+				WithQuery("types", "service_brokers").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Path("$.items[*].payload.new.resource.id").Array().NotContains(brokerID)
+
+			time.Sleep(settlePeriod)
+			ctx.SMWithOAuth.GET(web.EventsURL).
+				WithQuery("since", revision).
+				WithQuery("types", "service_brokers").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Path("$.items[*].payload.new.resource.id").Array().Contains(brokerID)
+		})
+	})
+
+	Context("when a tenant creates a service instance", func() {
+		var (
+			tenant     string
+			instanceID string
+		)
+
+		instanceEvents := func(tenantSM *common.SMExpect) []interface{} {
+			return tenantSM.GET(web.EventsURL).
+				WithQuery("since", revision).
+				WithQuery("types", "service_instances").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Path("$.items[*].payload.new.resource.id").Array().Raw()
+		}
+
+		BeforeEach(func() {
+			catalogPlanID := newID()
+			catalog := common.NewEmptySBCatalog()
+			catalog.AddService(common.GenerateTestServiceWithPlansWithID(newID(), common.GenerateTestPlanWithID(catalogPlanID)))
+			brokerUtils := ctx.RegisterBrokerWithCatalog(catalog)
+			common.CreateVisibilitiesForAllBrokerPlans(ctx.SMWithOAuth, brokerUtils.Broker.ID)
+			planID := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", catalogPlanID)).
+				First().Object().Value("id").String().Raw()
+
+			tenant = newID()
+			instanceID = ctx.NewTenantExpect("tenancyClient", tenant).POST(web.ServiceInstancesURL).
+				WithQuery("async", false).
+				WithJSON(common.Object{
+					"name":             "instance-" + newID(),
+					"service_plan_id":  planID,
+					"maintenance_info": "{}",
+				}).
+				Expect().
+				Status(http.StatusCreated).
+				JSON().Object().Value("id").String().Raw()
+			time.Sleep(settlePeriod)
+		})
+
+		AfterEach(func() {
+			ctx.CleanupAdditionalResources()
+		})
+
+		It("should return its events to the users of the tenant", func() {
+			Expect(instanceEvents(ctx.NewTenantExpect("tenancyClient", tenant))).To(ContainElement(instanceID))
+		})
+
+		It("should not return its events to the users of other tenants", func() {
+			Expect(instanceEvents(ctx.NewTenantExpect("tenancyClient", newID()))).ToNot(ContainElement(instanceID))
+		})
+
+		It("should return its events to global users", func() {
+			Expect(instanceEvents(ctx.SMWithOAuth)).To(ContainElement(instanceID))
+		})
+	})
+
+	Context("when since is not a valid revision", func() {
+		It("should return 400", func() {
+			ctx.SMWithOAuth.GET(web.EventsURL).
+				WithQuery("since", "-1").
+				Expect().
+				Status(http.StatusBadRequest)
+		})
+	})
+
+	Context("when unsupported type is requested", func() {
+		It("should return 400", func() {
+			ctx.SMWithOAuth.GET(web.EventsURL).
+				WithQuery("types", "platforms").
+				Expect().
+				Status(http.StatusBadRequest)
+		})
+	})
+
+	Context("when no authentication is provided", func() {
+		It("should return 401", func() {
+			ctx.SM.GET(web.EventsURL).
+				Expect().
+				Status(http.StatusUnauthorized)
+		})
+	})
+})
@@ -0,0 +1,215 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package expand_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/sm"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gavv/httpexpect"
+	"github.com/gofrs/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func TestExpand(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fields and Expand Tests Suite")
+}
+
+var _ = Describe("Fields and expand", func() {
+	var (
+		ctx        *common.TestContext
+		brokerID   string
+		offeringID string
+		planID     string
+		instanceID string
+		bindingID  string
+	)
+
+	BeforeSuite(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().
+			WithTenantTokenClaims(map[string]interface{}{
+				"cid": "tenancyClient",
+				"zid": "tenantID",
+			}).
+			WithSMExtensions(func(ctx context.Context, smb *sm.ServiceManagerBuilder, e env.Environment) error {
+				_, err := smb.EnableMultitenancy("tenant", common.ExtractTenantFunc)
+				return err
+			}).
+			Build()
+
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		catalogPlanID := UUID.String()
+		catalog := common.NewEmptySBCatalog()
+		catalog.AddService(common.GenerateTestServiceWithPlans(common.GenerateTestPlanWithID(catalogPlanID)))
+
+		brokerUtils := ctx.RegisterBrokerWithCatalog(catalog)
+		brokerID = brokerUtils.Broker.ID
+		common.CreateVisibilitiesForAllBrokerPlans(ctx.SMWithOAuth, brokerID)
+
+		plan := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", catalogPlanID)).
+			First().Object()
+		planID = plan.Value("id").String().Raw()
+		offeringID = plan.Value("service_offering_id").String().Raw()
+
+		instanceID = common.GetSMAAPProvisionInstanceFunc(ctx, "false", planID)()
+		bindingID = common.CreateBindingByInstanceID(ctx.SMWithOAuthForTenant, "false", http.StatusCreated, instanceID, "binding").
+			JSON().Object().Value("id").String().Raw()
+	})
+
+	AfterSuite(func() {
+		ctx.Cleanup()
+	})
+
+	Context("when getting a resource", func() {
+		It("returns the expanded related resources", func() {
+			instance := ctx.SMWithOAuth.GET(web.ServiceInstancesURL+"/"+instanceID).
+				WithQuery("expand", "service_plan,service_offering,broker,platform").
+				Expect().Status(http.StatusOK).JSON().Object()
+
+			instance.Value("id").Equal(instanceID)
+			instance.Path("$.service_plan.id").Equal(planID)
+			instance.Path("$.service_offering.id").Equal(offeringID)
+			instance.Path("$.broker.id").Equal(brokerID)
+			instance.Path("$.platform.id").Equal(types.SMPlatform)
+		})
+
+		It("does not return the related resources which the tenant cannot access", func() {
+			instance := ctx.SMWithOAuthForTenant.GET(web.ServiceInstancesURL+"/"+instanceID).
+				WithQuery("expand", "service_plan,broker,platform").
+				Expect().Status(http.StatusOK).JSON().Object()
+
+			instance.Path("$.service_plan.id").Equal(planID)
+			instance.NotContainsKey("broker")
+			instance.NotContainsKey("platform")
+		})
+
+		It("sanitizes the expanded resources", func() {
+			instance := ctx.SMWithOAuth.GET(web.ServiceInstancesURL+"/"+instanceID).
+				WithQuery("expand", "broker,platform").
+				Expect().Status(http.StatusOK).JSON().Object()
+
+			instance.Value("broker").Object().NotContainsKey("credentials")
+			instance.Value("platform").Object().NotContainsKey("credentials")
+		})
+
+		It("returns only the requested fields", func() {
+			instance := ctx.SMWithOAuthForTenant.GET(web.ServiceInstancesURL+"/"+instanceID).
+				WithQuery("fields", "id,name,service_plan.name").
+				WithQuery("expand", "service_plan").
+				Expect().Status(http.StatusOK).JSON().Object()
+
+			instance.Keys().ContainsOnly("id", "name", "service_plan")
+			instance.Value("service_plan").Object().Keys().ContainsOnly("name")
+		})
+	})
+
+	Context("when the resources are requested by a kubernetes platform", func() {
+		var (
+			platformID     string
+			platformExpect *common.SMExpect
+			instance       *types.ServiceInstance
+		)
+
+		BeforeEach(func() {
+			platform := common.RegisterPlatformInSM(common.MakePlatform("", "k8s-platform", types.K8sPlatformType, ""), ctx.SMWithOAuth, map[string]string{})
+			platformID = platform.ID
+			platformExpect = &common.SMExpect{Expect: ctx.SM.Builder(func(req *httpexpect.Request) {
+				req.WithBasicAuth(platform.Credentials.Basic.Username, platform.Credentials.Basic.Password)
+			})}
+			_, instance = common.CreateInstanceInPlatform(ctx, platformID)
+		})
+
+		It("returns only the related resources visible to the platform", func() {
+			expandInstance := func() *httpexpect.Object {
+				return platformExpect.GET(web.ServiceInstancesURL+"/"+instance.ID).
+					WithQuery("expand", "service_plan,service_offering,broker").
+					Expect().Status(http.StatusOK).JSON().Object()
+			}
+
+			instance := expandInstance()
+			instance.NotContainsKey("service_plan")
+			instance.NotContainsKey("service_offering")
+			instance.NotContainsKey("broker")
+
+			common.RegisterVisibilityForPlanAndPlatform(ctx.SMWithOAuth, instance.Value("service_plan_id").String().Raw(), platformID)
+
+			instance = expandInstance()
+			instance.Path("$.service_plan.id").Equal(instance.Value("service_plan_id").Raw())
+			instance.ContainsKey("service_offering")
+			instance.ContainsKey("broker")
+		})
+	})
+
+	Context("when listing resources", func() {
+		It("returns the expanded related resources of each item", func() {
+			bindings := ctx.SMWithOAuth.ListWithQuery(web.ServiceBindingsURL, fmt.Sprintf("fieldQuery=id eq '%s'&expand=service_instance,service_plan,broker", bindingID))
+
+			bindings.Length().Equal(1)
+			binding := bindings.First().Object()
+			binding.Value("id").Equal(bindingID)
+			binding.Path("$.service_instance.id").Equal(instanceID)
+			binding.Path("$.service_plan.id").Equal(planID)
+			binding.Path("$.broker.id").Equal(brokerID)
+			binding.Value("broker").Object().NotContainsKey("credentials")
+		})
+
+		It("returns the related resources of the tenant for each item listed by the tenant", func() {
+			bindings := ctx.SMWithOAuthForTenant.GET(web.ServiceBindingsURL).
+				WithQuery("expand", "service_instance,broker").
+				Expect().Status(http.StatusOK).JSON().Object().Value("items").Array()
+
+			bindings.Length().Equal(1)
+			binding := bindings.First().Object()
+			binding.Value("id").Equal(bindingID)
+			binding.Path("$.service_instance.id").Equal(instanceID)
+			binding.NotContainsKey("broker")
+		})
+
+		It("returns only the requested fields of each item", func() {
+			page := ctx.SMWithOAuth.GET(web.ServicePlansURL).
+				WithQuery("fields", "id,service_offering.name").
+				WithQuery("expand", "service_offering").
+				Expect().Status(http.StatusOK).JSON().Object()
+
+			page.Value("num_items").Number().Gt(0)
+			for _, plan := range page.Value("items").Array().Iter() {
+				plan.Object().Keys().ContainsOnly("id", "service_offering")
+				plan.Object().Value("service_offering").Object().Keys().ContainsOnly("name")
+			}
+		})
+	})
+
+	DescribeTable("when the parameters are invalid",
+		func(path, param, value string) {
+			ctx.SMWithOAuth.GET(path).WithQuery(param, value).Expect().Status(http.StatusBadRequest)
+		},
+		Entry("expand of unrelated resource", web.ServiceBrokersURL, "expand", "platform"),
+		Entry("expand of unknown resource", web.ServiceInstancesURL, "expand", "unknown"),
+		Entry("invalid field", web.ServiceInstancesURL, "fields", "labels.*"),
+	)
+})
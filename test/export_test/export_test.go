@@ -0,0 +1,277 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestExport(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Export Tests Suite")
+}
+
+const exportKey = "bSDK3ac8TwOQBgUGjXDYbFvDUfEUhR7k"
+
+var _ = Describe("Export", func() {
+	var (
+		ctx          *common.TestContext
+		brokerID     string
+		platform     *types.Platform
+		planID       string
+		visibilityID string
+	)
+
+	exportArchive := func() map[string]interface{} {
+		return ctx.SMWithOAuth.POST(web.ExportURL).
+			WithJSON(common.Object{"key": exportKey}).
+			Expect().Status(http.StatusOK).JSON().Object().Raw()
+	}
+
+	importArchive := func(archive map[string]interface{}, mode string, dryRun bool, expectedStatus int) *httpexpect.Object {
+		req := ctx.SMWithOAuth.POST(web.ImportURL).
+			WithQuery(web.QueryParamMode, mode).
+			WithJSON(common.Object{"key": exportKey, "archive": archive})
+		if dryRun {
+			req = req.WithQuery(web.QueryParamDryRun, "true")
+		}
+		return req.Expect().Status(expectedStatus).JSON().Object()
+	}
+
+	// actions returns the import actions of the entities created by the test by their id in the archive
+	actions := func(report *httpexpect.Object) map[string]interface{} {
+		result := make(map[string]interface{})
+		for _, item := range report.Value("items").Array().Iter() {
+			id := item.Object().Value("id").String().Raw()
+			if id == brokerID || id == platform.ID || id == visibilityID {
+				item.Object().NotContainsKey("error")
+				result[id] = item.Object().Value("action").Raw()
+			}
+		}
+		return result
+	}
+
+	findByID := func(items *httpexpect.Array, id string) *httpexpect.Object {
+		for _, item := range items.Iter() {
+			if item.Object().Value("id").String().Raw() == id {
+				return item.Object()
+			}
+		}
+		Fail(fmt.Sprintf("item with id %s not found", id))
+		return nil
+	}
+
+	deleteEntities := func() {
+		ctx.SMWithOAuth.DELETE(web.ServiceBrokersURL + "/" + brokerID).Expect().Status(http.StatusOK)
+		ctx.SMWithOAuth.DELETE(web.PlatformsURL).WithQuery("fieldQuery", fmt.Sprintf("id eq '%s'", platform.ID)).
+			Expect().Status(http.StatusOK)
+	}
+
+	BeforeSuite(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().WithDefaultTokenClaims(map[string]interface{}{
+			"groups": []string{"admins"},
+		}).WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("authorization.enabled", true)
+			e.Set("authorization.roles", []interface{}{
+				map[string]interface{}{"role": "admin", "claim": "groups", "values": []string{"admins"}},
+			})
+			e.Set("authorization.policies", []interface{}{
+				map[string]interface{}{
+					"role":         "admin",
+					"resources":    []string{"*"},
+					"methods":      []string{"*"},
+					"access_level": "global",
+				},
+			})
+		}).Build()
+	})
+
+	AfterSuite(func() {
+		ctx.Cleanup()
+	})
+
+	BeforeEach(func() {
+		brokerID = ctx.RegisterBroker().Broker.ID
+		platform = ctx.RegisterPlatform()
+
+		offering := ctx.SMWithOAuth.ListWithQuery(web.ServiceOfferingsURL, fmt.Sprintf("fieldQuery=broker_id eq '%s'", brokerID)).First()
+		planID = ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL,
+			fmt.Sprintf("fieldQuery=service_offering_id eq '%s'", offering.Object().Value("id").String().Raw())).
+			First().Object().Value("id").String().Raw()
+		visibilityID = ctx.SMWithOAuth.POST(web.VisibilitiesURL).WithJSON(common.Object{
+			"service_plan_id": planID,
+			"platform_id":     platform.ID,
+			"labels":          common.Object{"organization_guid": common.Array{"org1"}},
+		}).Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+	})
+
+	AfterEach(func() {
+		ctx.CleanupAdditionalResources()
+	})
+
+	Describe("POST /v1/config/export", func() {
+		It("returns a signed archive of the registry entities", func() {
+			archive := ctx.SMWithOAuth.POST(web.ExportURL).
+				WithJSON(common.Object{"key": exportKey}).
+				Expect().Status(http.StatusOK).JSON().Object()
+
+			archive.Value("version").Equal(1)
+			archive.Value("signature").String().NotEmpty()
+			findByID(archive.Value("platforms").Array(), platform.ID)
+			broker := findByID(archive.Value("service_brokers").Array(), brokerID)
+			broker.Value("credentials").String().NotEmpty()
+			broker.NotContainsKey("catalog")
+			visibility := findByID(archive.Value("visibilities").Array(), visibilityID)
+			visibility.Value("service_broker_id").Equal(brokerID)
+			visibility.Value("platform_id").Equal(platform.ID)
+		})
+
+		It("returns 400 when the export key is too short", func() {
+			ctx.SMWithOAuth.POST(web.ExportURL).
+				WithJSON(common.Object{"key": "short"}).
+				Expect().Status(http.StatusBadRequest)
+		})
+
+		It("returns 401 for unauthenticated requests", func() {
+			ctx.SM.POST(web.ExportURL).
+				WithJSON(common.Object{"key": exportKey}).
+				Expect().Status(http.StatusUnauthorized)
+		})
+
+		It("returns 403 for users without global access", func() {
+			ctx.SMWithOAuthForTenant.POST(web.ExportURL).
+				WithJSON(common.Object{"key": exportKey}).
+				Expect().Status(http.StatusForbidden)
+		})
+	})
+
+	Describe("POST /v1/config/import", func() {
+		var archive map[string]interface{}
+
+		BeforeEach(func() {
+			archive = exportArchive()
+		})
+
+		It("returns 403 for users without global access", func() {
+			ctx.SMWithOAuthForTenant.POST(web.ImportURL).
+				WithJSON(common.Object{"key": exportKey, "archive": archive}).
+				Expect().Status(http.StatusForbidden)
+		})
+
+		It("returns 400 when the archive was modified", func() {
+			archive["service_brokers"].([]interface{})[0].(map[string]interface{})["broker_url"] = "http://attacker.example.com"
+			importArchive(archive, "skip", false, http.StatusBadRequest)
+		})
+
+		It("returns 400 when the conflict mode is not supported", func() {
+			importArchive(archive, "merge", false, http.StatusBadRequest)
+		})
+
+		It("reports the conflicts in dry-run mode", func() {
+			report := importArchive(archive, "fail", true, http.StatusOK)
+			report.Value("dry_run").Equal(true)
+			Expect(actions(report)).To(Equal(map[string]interface{}{
+				brokerID:     "conflict",
+				platform.ID:  "conflict",
+				visibilityID: "conflict",
+			}))
+		})
+
+		It("returns 409 with the report in fail mode when there are conflicts", func() {
+			resp := importArchive(archive, "fail", false, http.StatusConflict)
+			Expect(actions(resp.Value("report").Object())).To(HaveKeyWithValue(brokerID, "conflict"))
+		})
+
+		It("keeps the existing entities in skip mode", func() {
+			report := importArchive(archive, "skip", false, http.StatusOK)
+			Expect(actions(report)).To(Equal(map[string]interface{}{
+				brokerID:     "skip",
+				platform.ID:  "skip",
+				visibilityID: "skip",
+			}))
+		})
+
+		It("overwrites the labels of the existing entities in overwrite mode", func() {
+			ctx.SMWithOAuth.PATCH(web.VisibilitiesURL + "/" + visibilityID).WithJSON(common.Object{
+				"labels": []common.Object{{"op": "add", "key": "space_guid", "values": []string{"space1"}}},
+			}).Expect().Status(http.StatusOK)
+
+			report := importArchive(archive, "overwrite", false, http.StatusOK)
+			Expect(actions(report)).To(HaveKeyWithValue(visibilityID, "overwrite"))
+
+			labels := ctx.SMWithOAuth.GET(web.VisibilitiesURL + "/" + visibilityID).
+				Expect().Status(http.StatusOK).JSON().Object().Value("labels").Object()
+			labels.NotContainsKey("space_guid")
+			labels.Value("organization_guid").Array().Equal([]string{"org1"})
+		})
+
+		Context("when the entities were deleted", func() {
+			BeforeEach(func() {
+				deleteEntities()
+			})
+
+			It("recreates the broker with its catalog and the visibility of the new plan", func() {
+				report := importArchive(archive, "skip", false, http.StatusOK)
+				Expect(actions(report)).To(Equal(map[string]interface{}{
+					brokerID:     "create",
+					platform.ID:  "create",
+					visibilityID: "create",
+				}))
+
+				offerings := ctx.SMWithOAuth.ListWithQuery(web.ServiceOfferingsURL, fmt.Sprintf("fieldQuery=broker_id eq '%s'", brokerID))
+				offerings.NotEmpty()
+
+				visibility := ctx.SMWithOAuth.GET(web.VisibilitiesURL + "/" + visibilityID).
+					Expect().Status(http.StatusOK).JSON().Object()
+				visibility.Value("platform_id").Equal(platform.ID)
+				visibility.Value("labels").Object().Value("organization_guid").Array().Equal([]string{"org1"})
+				newPlanID := visibility.Value("service_plan_id").String().Raw()
+				Expect(newPlanID).ToNot(Equal(planID))
+				ctx.SMWithOAuth.GET(web.ServicePlansURL + "/" + newPlanID).Expect().Status(http.StatusOK)
+			})
+
+			It("keeps the credentials of the platforms", func() {
+				importArchive(archive, "skip", false, http.StatusOK)
+
+				imported, err := ctx.SMRepository.Get(context.Background(), types.PlatformType,
+					query.ByField(query.EqualsOperator, "id", platform.ID))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(imported.(*types.Platform).Credentials.Basic.Username).To(Equal(platform.Credentials.Basic.Username))
+				Expect(imported.(*types.Platform).Credentials.Basic.Password).To(Equal(platform.Credentials.Basic.Password))
+			})
+
+			It("does not import anything in dry-run mode", func() {
+				report := importArchive(archive, "skip", true, http.StatusOK)
+				Expect(actions(report)).To(HaveKeyWithValue(brokerID, "create"))
+
+				ctx.SMWithOAuth.GET(web.ServiceBrokersURL + "/" + brokerID).Expect().Status(http.StatusNotFound)
+			})
+		})
+	})
+})
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Metrics Tests Suite")
+}
+
+var _ = Describe("Metrics API", func() {
+	var ctx *common.TestContext
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	Context("when metrics are enabled", func() {
+		BeforeEach(func() {
+			ctx = common.NewTestContextBuilder().WithEnvPreExtensions(func(set *pflag.FlagSet) {
+				Expect(set.Set("metrics.enabled", "true")).ToNot(HaveOccurred())
+				Expect(set.Set("metrics.operations_refresh_interval", "0s")).ToNot(HaveOccurred())
+			}).Build()
+		})
+
+		It("is accessible without authentication", func() {
+			ctx.SM.GET(web.MetricsURL).Expect().
+				Status(http.StatusOK).
+				ContentType("text/plain")
+		})
+
+		It("exposes the handled requests by route and status", func() {
+			ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/missing").WithJSON(common.Object{}).Expect().Status(http.StatusNotFound)
+			ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/missing").WithJSON(common.Object{}).Expect().Status(http.StatusNotFound)
+			ctx.SMWithOAuth.GET(web.PlatformsURL).Expect().Status(http.StatusOK)
+
+			body := ctx.SM.GET(web.MetricsURL).Expect().Status(http.StatusOK).Body()
+			body.Contains(fmt.Sprintf(`sm_http_requests_total{method="PATCH",route="%s/{%s}",status="404"} 2`, web.PlatformsURL, web.PathParamResourceID))
+			body.Contains(fmt.Sprintf(`sm_http_requests_total{method="GET",route="%s",status="200"} 1`, web.PlatformsURL))
+			body.Contains(fmt.Sprintf(`sm_http_request_duration_seconds_count{method="PATCH",route="%s/{%s}",status="404"} 2`, web.PlatformsURL, web.PathParamResourceID))
+		})
+
+		It("exposes the stored operations by resource type, type and state", func() {
+			ctx.RegisterPlatform()
+
+			ctx.SM.GET(web.MetricsURL).Expect().
+				Status(http.StatusOK).
+				Body().Match(fmt.Sprintf(`sm_operations{resource_type="%s",state="succeeded",type="create"} [1-9]`, web.PlatformsURL))
+		})
+
+		It("exposes the worker pools utilisation", func() {
+			body := ctx.SM.GET(web.MetricsURL).Expect().Status(http.StatusOK).Body()
+			body.Contains(`sm_scheduler_workers{pool="maintainer"} 20`)
+			body.Contains(`sm_scheduler_busy_workers{pool="maintainer"} 0`)
+			body.Contains(fmt.Sprintf(`sm_scheduler_workers{pool="%s"}`, web.ServiceInstancesURL))
+		})
+
+		It("exposes the notification consumers", func() {
+			body := ctx.SM.GET(web.MetricsURL).Expect().Status(http.StatusOK).Body()
+			body.Contains("sm_notifications_connected_platforms 0")
+			body.Contains("sm_notifications_queued 0")
+		})
+	})
+
+	Context("when metrics are not enabled", func() {
+		BeforeEach(func() {
+			ctx = common.NewTestContextBuilder().Build()
+		})
+
+		It("does not expose the metrics endpoint", func() {
+			ctx.SM.GET(web.MetricsURL).Expect().Status(http.StatusNotFound)
+		})
+	})
+})
@@ -494,10 +494,14 @@ var _ = Describe("Notifications Suite", func() {
 			objectList, err := ctx.SMRepository.List(c, types.NotificationType, filters...)
 			Expect(err).ShouldNot(HaveOccurred())
 
-			notifications := objectList.(*types.Notifications)
-			notificatonIDs := make([]string, 0, len(notifications.Notifications))
-			for _, n := range notifications.Notifications {
+			// notifications for the Service Manager platform make up the events change feed
+			notifications := &types.Notifications{}
+			notificatonIDs := make([]string, 0, objectList.Len())
+			for _, n := range objectList.(*types.Notifications).Notifications {
 				notificatonIDs = append(notificatonIDs, n.GetID())
+				if n.PlatformID != types.SMPlatform {
+					notifications.Notifications = append(notifications.Notifications, n)
+				}
 			}
 
 			return notifications, notificatonIDs
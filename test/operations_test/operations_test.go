@@ -31,6 +31,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/sm"
 	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
 	"github.com/Peripli/service-manager/storage"
 	. "github.com/Peripli/service-manager/test/common"
@@ -59,7 +60,7 @@ var _ = test.DescribeTestsFor(test.TestCase{
 	ResourceBlueprint:                      blueprint,
 	ResourceWithoutNullableFieldsBlueprint: blueprint,
 	PatchResource:                          test.StorageResourcePatch,
-	ResourcePropertiesToIgnore:             []string{"transitive_resources", "context"},
+	ResourcePropertiesToIgnore:             []string{"transitive_resources", "context", "next_attempt_timestamp"},
 	AdditionalTests: func(ctx *TestContext, t *test.TestCase) {
 		Describe("Operations", func() {
 			var ctx *TestContext
@@ -199,7 +200,7 @@ var _ = test.DescribeTestsFor(test.TestCase{
 					ctx = NewTestContextBuilder().WithSMExtensions(func(ctx context.Context, smb *sm.ServiceManagerBuilder, e env.Environment) error {
 						testController := panicController{
 							operation: operation,
-							scheduler: operations.NewScheduler(ctx, smb.Storage, operations.DefaultSettings(), 10, &sync.WaitGroup{}),
+							scheduler: operations.NewScheduler(ctx, smb.Storage, operations.DefaultSettings(), nil, 10, &sync.WaitGroup{}),
 						}
 
 						smb.RegisterControllers(testController)
@@ -226,6 +227,45 @@ var _ = test.DescribeTestsFor(test.TestCase{
 				})
 			})
 
+			Context("Cancel", func() {
+				BeforeEach(func() {
+					ctx = NewTestContextBuilder().Build()
+				})
+
+				When("the operation completes after it was retrieved for cancellation", func() {
+					It("does not cancel it and does not schedule orphan mitigation", func() {
+						operation := &types.Operation{
+							Base: types.Base{
+								ID:        defaultOperationID,
+								CreatedAt: time.Now(),
+								UpdatedAt: time.Now(),
+								Labels:    make(map[string][]string),
+								Ready:     true,
+							},
+							Type:          types.CREATE,
+							State:         types.SUCCEEDED,
+							ResourceID:    "test-resource-id",
+							ResourceType:  types.ServiceInstanceType,
+							PlatformID:    types.SMPlatform,
+							CorrelationID: "test-correlation-id",
+						}
+						_, err := ctx.SMRepository.Create(context.Background(), operation)
+						Expect(err).ToNot(HaveOccurred())
+
+						retrievedOperation := *operation
+						retrievedOperation.State = types.IN_PROGRESS
+						err = ctx.SMScheduler.CancelOperation(context.Background(), &retrievedOperation)
+						Expect(err).To(HaveOccurred())
+						Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+						object, err := ctx.SMRepository.Get(context.Background(), types.OperationType, query.ByField(query.EqualsOperator, "id", operation.ID))
+						Expect(err).ToNot(HaveOccurred())
+						Expect(object.(*types.Operation).State).To(Equal(types.SUCCEEDED))
+						Expect(object.(*types.Operation).DeletionScheduled.IsZero()).To(BeTrue())
+					})
+				})
+			})
+
 			Context("Maintainer", func() {
 				const (
 					maintainerRetry           = 1 * time.Second
@@ -488,6 +528,89 @@ var _ = test.DescribeTestsFor(test.TestCase{
 					})
 				})
 
+				When("reschedulable operation is retried according to its retry policy", func() {
+					const maxAttempts = 2
+
+					var operation *types.Operation
+
+					getOperation := func() *types.Operation {
+						byID := query.ByField(query.EqualsOperator, "id", defaultOperationID)
+						object, err := ctx.SMRepository.Get(context.Background(), types.OperationType, byID)
+						Expect(err).To(BeNil())
+						return object.(*types.Operation)
+					}
+
+					BeforeEach(func() {
+						ctxBuilder.WithEnvPostExtensions(func(e env.Environment, servers map[string]FakeServer) {
+							e.Set("operations.retry_policy.max_attempts", maxAttempts)
+						})
+						ctx = ctxBuilder.Build()
+
+						// the operation needs a resource, otherwise it is cleaned up as resource-less
+						_, err := ctx.SMRepository.Create(context.Background(), &types.Platform{
+							Base: types.Base{ID: "test-resource-id"},
+						})
+						Expect(err).ToNot(HaveOccurred())
+
+						operation = &types.Operation{
+							Base: types.Base{
+								ID:        defaultOperationID,
+								CreatedAt: time.Now().Add(-5 * actionTimeout),
+								UpdatedAt: time.Now().Add(-5 * actionTimeout),
+								Labels:    make(map[string][]string),
+								Ready:     true,
+							},
+							Reschedule:    true,
+							Type:          types.DELETE,
+							State:         types.IN_PROGRESS,
+							ResourceID:    "test-resource-id",
+							ResourceType:  types.PlatformType,
+							PlatformID:    types.SMPlatform,
+							CorrelationID: "test-correlation-id",
+						}
+					})
+
+					When("maximum attempts are reached", func() {
+						It("marks the operation as failed", func() {
+							operation.Attempts = maxAttempts
+							_, err := ctx.SMRepository.Create(context.Background(), operation)
+							Expect(err).To(BeNil())
+
+							Eventually(func() types.OperationState {
+								return getOperation().State
+							}, actionTimeout*6).Should(Equal(types.FAILED))
+							Expect(string(getOperation().Errors)).To(ContainSubstring("exceeded the maximum number of attempts"))
+						})
+					})
+
+					When("next attempt is not yet due", func() {
+						It("does not reschedule the operation", func() {
+							operation.Attempts = 1
+							operation.NextAttemptTimestamp = time.Now().Add(time.Hour)
+							_, err := ctx.SMRepository.Create(context.Background(), operation)
+							Expect(err).To(BeNil())
+
+							Consistently(func() int {
+								return getOperation().Attempts
+							}, actionTimeout*3).Should(Equal(1))
+							Expect(getOperation().State).To(Equal(types.IN_PROGRESS))
+						})
+					})
+
+					When("next attempt is due", func() {
+						It("reschedules the operation and records the attempt", func() {
+							operation.Attempts = 1
+							operation.NextAttemptTimestamp = time.Now().Add(-time.Second)
+							_, err := ctx.SMRepository.Create(context.Background(), operation)
+							Expect(err).To(BeNil())
+
+							Eventually(func() int {
+								return getOperation().Attempts
+							}, actionTimeout*6).Should(Equal(2))
+						})
+					})
+				})
+
 				When("operation gets stuck in progress without being reschedulable", func() {
 					var operation *types.Operation
 
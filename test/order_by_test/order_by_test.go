@@ -0,0 +1,195 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package order_by_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func TestOrderBy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Order By Tests Suite")
+}
+
+var _ = Describe("List order", func() {
+	var (
+		ctx          *common.TestContext
+		platformType string
+	)
+
+	createPlatform := func(name string) {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		ctx.SMWithOAuth.POST(web.PlatformsURL).
+			WithJSON(common.MakePlatform(UUID.String(), platformType+"-"+name, platformType, "")).
+			Expect().Status(http.StatusCreated)
+	}
+
+	listPage := func(orderBy, token string, maxItems int) (names []string, nextToken string) {
+		req := ctx.SMWithOAuth.GET(web.PlatformsURL).
+			WithQuery("fieldQuery", fmt.Sprintf("type eq '%s'", platformType)).
+			WithQuery("max_items", maxItems)
+		if orderBy != "" {
+			req = req.WithQuery("orderBy", orderBy)
+		}
+		if token != "" {
+			req = req.WithQuery("token", token)
+		}
+		page := req.Expect().Status(http.StatusOK).JSON().Object()
+		for _, item := range page.Value("items").Array().Iter() {
+			names = append(names, strings.TrimPrefix(item.Object().Value("name").String().Raw(), platformType+"-"))
+		}
+		if token, found := page.Raw()["token"]; found {
+			nextToken = token.(string)
+		}
+		return names, nextToken
+	}
+
+	BeforeSuite(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().Build()
+	})
+
+	AfterSuite(func() {
+		ctx.Cleanup()
+	})
+
+	BeforeEach(func() {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		platformType = UUID.String()
+		for _, name := range []string{"b", "d", "a", "c"} {
+			createPlatform(name)
+		}
+	})
+
+	Context("when orderBy is not provided", func() {
+		It("lists the items in the order of their creation", func() {
+			names, _ := listPage("", "", 10)
+			Expect(names).To(Equal([]string{"b", "d", "a", "c"}))
+		})
+	})
+
+	Context("when ordering by a field", func() {
+		It("lists the items in the requested order", func() {
+			names, _ := listPage("name desc", "", 10)
+			Expect(names).To(Equal([]string{"d", "c", "b", "a"}))
+		})
+
+		It("lists the newest items first when ordering by creation time descending", func() {
+			names, _ := listPage("created_at desc", "", 10)
+			Expect(names).To(Equal([]string{"c", "a", "d", "b"}))
+		})
+	})
+
+	Context("when ordering by multiple fields", func() {
+		It("orders by the next field the items with equal values", func() {
+			names, _ := listPage("type,name desc", "", 10)
+			Expect(names).To(Equal([]string{"d", "c", "b", "a"}))
+		})
+	})
+
+	Context("when paging through the ordered items", func() {
+		It("returns each item exactly once even if items are created between the requests", func() {
+			names, token := listPage("name", "", 2)
+			Expect(names).To(Equal([]string{"a", "b"}))
+			Expect(token).ToNot(BeEmpty())
+
+			createPlatform("aa")
+
+			page, token := listPage("name", token, 2)
+			names = append(names, page...)
+			Expect(token).To(BeEmpty())
+			Expect(names).To(Equal([]string{"a", "b", "c", "d"}))
+		})
+
+		It("continues after the items with equal values of the ordered fields", func() {
+			var names []string
+			page, token := listPage("type", "", 1)
+			names = append(names, page...)
+			for token != "" {
+				page, token = listPage("type", token, 1)
+				names = append(names, page...)
+			}
+			Expect(names).To(Equal([]string{"b", "d", "a", "c"}))
+		})
+	})
+
+	Context("when the token was issued for another order", func() {
+		It("returns 400", func() {
+			_, token := listPage("name", "", 2)
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("orderBy", "name desc").
+				WithQuery("max_items", 2).
+				WithQuery("token", token).
+				Expect().Status(http.StatusBadRequest)
+		})
+	})
+
+	Context("when the token is a paging sequence issued before the tokens contained the order", func() {
+		var legacyToken string
+
+		BeforeEach(func() {
+			platforms, err := ctx.SMRepository.List(context.Background(), types.PlatformType,
+				query.ByField(query.EqualsOperator, "type", platformType),
+				query.OrderResultBy("paging_sequence", query.AscOrder))
+			Expect(err).ToNot(HaveOccurred())
+			pagingSequence := platforms.ItemAt(1).GetPagingSequence()
+			legacyToken = base64.StdEncoding.EncodeToString([]byte(strconv.FormatInt(pagingSequence, 10)))
+		})
+
+		It("continues after the item with the paging sequence for the default order", func() {
+			names, token := listPage("", legacyToken, 10)
+			Expect(names).To(Equal([]string{"a", "c"}))
+			Expect(token).To(BeEmpty())
+		})
+
+		It("returns 400 for another order", func() {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("orderBy", "name").
+				WithQuery("token", legacyToken).
+				Expect().Status(http.StatusBadRequest)
+		})
+	})
+
+	DescribeTable("when orderBy is invalid",
+		func(orderBy string) {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("orderBy", orderBy).
+				Expect().Status(http.StatusBadRequest)
+		},
+		Entry("unknown field", "unknown"),
+		Entry("label", "labels/env"),
+		Entry("nullable field", "description"),
+		Entry("unknown order type", "name up"),
+		Entry("field which is not part of the resource", "password"),
+	)
+})
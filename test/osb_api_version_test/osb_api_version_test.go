@@ -0,0 +1,269 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb_api_version_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+)
+
+func TestOSBAPIVersion(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OSB API Version Tests Suite")
+}
+
+var _ = Describe("OSB API version", func() {
+	var (
+		ctx              *common.TestContext
+		brokerID         string
+		brokerServer     *common.BrokerServer
+		osbURL           string
+		catalogServiceID string
+		catalogPlanID    string
+		planID           string
+	)
+
+	brokerAPIVersion := func() string {
+		return ctx.SMWithOAuth.GET(web.ServiceBrokersURL + "/" + brokerID).Expect().
+			Status(http.StatusOK).JSON().Object().Value("api_version").String().Raw()
+	}
+
+	supportAPIVersionsUpTo := func(version string) {
+		brokerServer.CatalogHandler = func(rw http.ResponseWriter, req *http.Request) {
+			if !osb.APIVersionAtLeast(version, req.Header.Get("X-Broker-API-Version")) {
+				common.SetResponse(rw, http.StatusPreconditionFailed, common.Object{"description": "unsupported version"})
+				return
+			}
+			common.SetResponse(rw, http.StatusOK, common.JSONToMap(string(brokerServer.Catalog)))
+		}
+		ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).WithJSON(common.Object{}).
+			Expect().Status(http.StatusOK)
+	}
+
+	newID := func() string {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		return UUID.String()
+	}
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().Build()
+
+		catalogPlanID = newID()
+		catalogServiceID = newID()
+		catalog := common.NewEmptySBCatalog()
+		catalog.AddService(common.GenerateTestServiceWithPlansWithID(catalogServiceID, common.GenerateTestPlanWithID(catalogPlanID)))
+
+		brokerUtils := ctx.RegisterBrokerWithCatalog(catalog)
+		brokerID = brokerUtils.Broker.ID
+		brokerServer = brokerUtils.Broker.BrokerServer
+		brokerServer.ShouldRecordRequests(true)
+		common.CreateVisibilitiesForAllBrokerPlans(ctx.SMWithOAuth, brokerID)
+		osbURL = web.OSBURL + "/" + brokerID
+
+		planID = ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", catalogPlanID)).
+			First().Object().Value("id").String().Raw()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	Context("when registering a broker", func() {
+		It("negotiates the latest OSB API version", func() {
+			Expect(brokerAPIVersion()).To(Equal(osb.LatestAPIVersion))
+		})
+
+		It("falls back to an older OSB API version if the broker does not support the newer ones", func() {
+			supportAPIVersionsUpTo(osb.APIVersion2_15)
+
+			Expect(brokerAPIVersion()).To(Equal(osb.APIVersion2_15))
+		})
+	})
+
+	Context("when proxying OSB requests", func() {
+		BeforeEach(func() {
+			supportAPIVersionsUpTo(osb.APIVersion2_15)
+		})
+
+		It("limits the OSB API version of the platform to the version negotiated with the broker", func() {
+			ctx.SMWithBasic.GET(osbURL+"/v2/service_instances/instance-id/last_operation").WithHeader("X-Broker-API-Version", osb.APIVersion2_17).
+				Expect().Status(http.StatusOK)
+
+			Expect(brokerServer.LastRequest.Header.Get("X-Broker-API-Version")).To(Equal(osb.APIVersion2_15))
+		})
+
+		It("keeps an older OSB API version of the platform", func() {
+			ctx.SMWithBasic.GET(osbURL+"/v2/service_instances/instance-id/last_operation").WithHeader("X-Broker-API-Version", osb.APIVersion2_14).
+				Expect().Status(http.StatusOK)
+
+			Expect(brokerServer.LastRequest.Header.Get("X-Broker-API-Version")).To(Equal(osb.APIVersion2_14))
+		})
+
+		It("removes the fields not supported by the OSB API version of the broker", func() {
+			supportAPIVersionsUpTo(osb.APIVersion2_14)
+			provisionRequest := common.JSONToMap(fmt.Sprintf(common.CFContext, catalogServiceID, catalogPlanID, "instance-name"))
+			provisionRequest["maintenance_info"] = common.Object{"version": "1.0.0"}
+
+			ctx.SMWithBasic.PUT(osbURL+"/v2/service_instances/"+newID()).WithHeader("X-Broker-API-Version", osb.APIVersion2_17).
+				WithJSON(provisionRequest).
+				Expect().Status(http.StatusCreated)
+
+			Expect(gjson.GetBytes(brokerServer.LastRequestBody, "service_id").String()).To(Equal(catalogServiceID))
+			Expect(gjson.GetBytes(brokerServer.LastRequestBody, "maintenance_info").Exists()).To(BeFalse())
+		})
+	})
+
+	Context("when provisioning and binding through the OSB API", func() {
+		It("stores the metadata and the predecessor binding returned by the broker", func() {
+			instanceID := newID()
+			bindingID := newID()
+			brokerServer.ServiceInstanceHandlerFunc(http.MethodPut, "provision", common.ParameterizedHandler(http.StatusCreated, common.Object{
+				"metadata": common.Object{"labels": common.Object{"key": "value"}},
+			}))
+			brokerServer.BindingHandlerFunc(http.MethodPut, "bind", common.ParameterizedHandler(http.StatusCreated, common.Object{
+				"credentials": common.Object{"user": "user"},
+				"metadata":    common.Object{"expires_at": "2026-10-17T17:00:00.0Z"},
+			}))
+
+			common.GetOsbProvisionFunc(ctx, instanceID, osbURL, catalogServiceID, catalogPlanID)()
+			bindRequest := common.JSONToMap(fmt.Sprintf(common.CFContext, catalogServiceID, catalogPlanID, "instance-name"))
+			bindRequest["predecessor_binding_id"] = "predecessor-id"
+			ctx.SMWithBasic.PUT(osbURL + "/v2/service_instances/" + instanceID + "/service_bindings/" + bindingID).
+				WithJSON(bindRequest).
+				Expect().Status(http.StatusCreated)
+
+			ctx.SMWithOAuth.GET(web.ServiceInstancesURL + "/" + instanceID).Expect().Status(http.StatusOK).
+				JSON().Path("$.metadata.labels.key").Equal("value")
+			binding := ctx.SMWithOAuth.GET(web.ServiceBindingsURL + "/" + bindingID).Expect().Status(http.StatusOK).JSON().Object()
+			binding.Value("predecessor_binding_id").Equal("predecessor-id")
+			binding.Path("$.metadata.expires_at").Equal("2026-10-17T17:00:00.0Z")
+		})
+	})
+
+	Context("when provisioning through the Service Manager API", func() {
+		It("sends the maintenance info to the broker and stores the metadata returned by it", func() {
+			brokerServer.ServiceInstanceHandlerFunc(http.MethodPut, "provision", common.ParameterizedHandler(http.StatusCreated, common.Object{
+				"metadata": common.Object{"labels": common.Object{"key": "value"}},
+			}))
+
+			instanceID := ctx.SMWithOAuthForTenant.POST(web.ServiceInstancesURL).WithQuery("async", false).
+				WithJSON(common.Object{
+					"name":             "test-instance",
+					"service_plan_id":  planID,
+					"maintenance_info": common.Object{"version": "1.0.0"},
+				}).
+				Expect().Status(http.StatusCreated).
+				JSON().Object().Value("id").String().Raw()
+
+			Expect(brokerServer.LastRequest.Header.Get("X-Broker-API-Version")).To(Equal(osb.LatestAPIVersion))
+			Expect(gjson.GetBytes(brokerServer.LastRequestBody, "maintenance_info.version").String()).To(Equal("1.0.0"))
+			ctx.SMWithOAuth.GET(web.ServiceInstancesURL + "/" + instanceID).Expect().Status(http.StatusOK).
+				JSON().Path("$.metadata.labels.key").Equal("value")
+		})
+
+		It("sends the requests without newer fields to brokers older than 2.16 with the OSB client library", func() {
+			supportAPIVersionsUpTo(osb.APIVersion2_15)
+
+			ctx.SMWithOAuthForTenant.POST(web.ServiceInstancesURL).WithQuery("async", false).
+				WithJSON(common.Object{
+					"name":            "test-instance",
+					"service_plan_id": planID,
+				}).
+				Expect().Status(http.StatusCreated)
+			Expect(brokerServer.LastRequest.Header.Get("X-Broker-API-Version")).To(Equal(osbc.LatestAPIVersion().HeaderValue()))
+
+			ctx.SMWithOAuthForTenant.POST(web.ServiceInstancesURL).WithQuery("async", false).
+				WithJSON(common.Object{
+					"name":             "test-instance-with-maintenance-info",
+					"service_plan_id":  planID,
+					"maintenance_info": common.Object{"version": "1.0.0"},
+				}).
+				Expect().Status(http.StatusCreated)
+			Expect(brokerServer.LastRequest.Header.Get("X-Broker-API-Version")).To(Equal(osb.APIVersion2_15))
+			Expect(gjson.GetBytes(brokerServer.LastRequestBody, "maintenance_info.version").String()).To(Equal("1.0.0"))
+		})
+
+		It("fails to send the maintenance info to a broker which does not support it", func() {
+			supportAPIVersionsUpTo(osb.APIVersion2_14)
+			brokerServer.ResetCallHistory()
+
+			ctx.SMWithOAuthForTenant.POST(web.ServiceInstancesURL).WithQuery("async", false).
+				WithJSON(common.Object{
+					"name":             "test-instance",
+					"service_plan_id":  planID,
+					"maintenance_info": common.Object{"version": "1.0.0"},
+				}).
+				Expect().Status(http.StatusBadRequest)
+			Expect(brokerServer.ServiceInstanceEndpointRequests).To(BeEmpty())
+		})
+	})
+
+	Context("when binding through the Service Manager API", func() {
+		var instanceID string
+
+		createBinding := func(predecessorBindingID string, expectedStatus int) common.Object {
+			body := ctx.SMWithOAuthForTenant.POST(web.ServiceBindingsURL).WithQuery("async", false).
+				WithJSON(common.Object{
+					"name":                   "successor-binding",
+					"service_instance_id":    instanceID,
+					"predecessor_binding_id": predecessorBindingID,
+				}).
+				Expect().Status(expectedStatus).Body().Raw()
+			var binding common.Object
+			Expect(json.Unmarshal([]byte(body), &binding)).To(Succeed())
+			return binding
+		}
+
+		BeforeEach(func() {
+			instanceID = common.GetSMAAPProvisionInstanceFunc(ctx, "false", planID)()
+		})
+
+		It("sends the predecessor binding to the broker", func() {
+			predecessorBindingID := common.CreateBindingByInstanceID(ctx.SMWithOAuthForTenant, "false", http.StatusCreated, instanceID, "predecessor-binding").
+				JSON().Object().Value("id").String().Raw()
+
+			binding := createBinding(predecessorBindingID, http.StatusCreated)
+
+			Expect(gjson.GetBytes(brokerServer.LastRequestBody, "predecessor_binding_id").String()).To(Equal(predecessorBindingID))
+			Expect(binding["predecessor_binding_id"]).To(Equal(predecessorBindingID))
+		})
+
+		It("fails if the predecessor binding does not exist", func() {
+			createBinding(newID(), http.StatusBadRequest)
+		})
+
+		It("fails if the broker does not support binding rotation", func() {
+			predecessorBindingID := common.CreateBindingByInstanceID(ctx.SMWithOAuthForTenant, "false", http.StatusCreated, instanceID, "predecessor-binding").
+				JSON().Object().Value("id").String().Raw()
+			supportAPIVersionsUpTo(osb.APIVersion2_16)
+
+			createBinding(predecessorBindingID, http.StatusBadRequest)
+		})
+	})
+})
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb_catalog_cache_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+)
+
+func TestOSBCatalogCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OSB Catalog Cache Tests Suite")
+}
+
+var _ = Describe("OSB catalog cache", func() {
+	var (
+		ctx        *common.TestContext
+		brokerID   string
+		catalogURL string
+		planID     string
+	)
+
+	catalogPlans := func() int {
+		body := ctx.SMWithBasic.GET(catalogURL).Expect().Status(http.StatusOK).Body().Raw()
+		return len(gjson.Get(body, "services.#.plans").Array())
+	}
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("api.osb_catalog_cache_enabled", true)
+		}).Build()
+
+		plan := common.GeneratePaidTestPlan()
+		catalog := common.NewEmptySBCatalog()
+		catalog.AddService(common.GenerateTestServiceWithPlans(plan, common.GeneratePaidTestPlan()))
+		brokerID = ctx.RegisterBrokerWithCatalog(catalog).Broker.ID
+		catalogURL = fmt.Sprintf("%s/%s/v2/catalog", web.OSBURL, brokerID)
+
+		catalogPlanID := gjson.Get(plan, "id").String()
+		planID = ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", catalogPlanID)).
+			First().Object().Value("id").String().Raw()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	It("returns Not Modified if the catalog matches the entity tag", func() {
+		etag := ctx.SMWithBasic.GET(catalogURL).Expect().Status(http.StatusOK).Header("ETag").NotEmpty().Raw()
+
+		ctx.SMWithBasic.GET(catalogURL).WithHeader("If-None-Match", etag).
+			Expect().Status(http.StatusNotModified).
+			Header("ETag").Equal(etag)
+	})
+
+	It("returns the catalog filtered by the visibilities created after it is cached", func() {
+		Expect(catalogPlans()).To(Equal(0))
+		Expect(catalogPlans()).To(Equal(0))
+
+		common.RegisterVisibilityForPlanAndPlatform(ctx.SMWithOAuth, planID, ctx.TestPlatform.ID)
+
+		Eventually(catalogPlans).Should(Equal(1))
+	})
+
+	It("returns a new entity tag when the visibilities of the platform change", func() {
+		etag := ctx.SMWithBasic.GET(catalogURL).Expect().Status(http.StatusOK).Header("ETag").Raw()
+
+		common.RegisterVisibilityForPlanAndPlatform(ctx.SMWithOAuth, planID, ctx.TestPlatform.ID)
+
+		Eventually(func() int {
+			return ctx.SMWithBasic.GET(catalogURL).WithHeader("If-None-Match", etag).Expect().Raw().StatusCode
+		}).Should(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,408 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package quota_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/sm"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gavv/httpexpect"
+	"github.com/gofrs/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestQuota(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tenant Quota Tests Suite")
+}
+
+var _ = Describe("Tenant quotas", func() {
+	var (
+		ctx              *common.TestContext
+		brokerServer     *common.BrokerServer
+		osbURL           string
+		catalogServiceID string
+		catalogPlanID    string
+		offeringID       string
+		planID           string
+		otherPlanID      string
+		tenant           string
+		tenantSM         *common.SMExpect
+	)
+
+	newID := func() string {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		return UUID.String()
+	}
+
+	quotaURL := func(tenant string) string {
+		return fmt.Sprintf("%s/%s%s", web.TenantURL, tenant, web.QuotasURL)
+	}
+
+	setQuota := func(quota common.Object) {
+		ctx.SMWithOAuth.PUT(quotaURL(tenant)).WithJSON(quota).Expect().Status(http.StatusOK)
+	}
+
+	createInstance := func(planID string) *httpexpect.Response {
+		return tenantSM.POST(web.ServiceInstancesURL).WithQuery("async", false).
+			WithJSON(common.Object{
+				"name":             "instance-" + newID(),
+				"service_plan_id":  planID,
+				"maintenance_info": "{}",
+			}).Expect()
+	}
+
+	createBinding := func(instanceID string) *httpexpect.Response {
+		return tenantSM.POST(web.ServiceBindingsURL).WithQuery("async", false).
+			WithJSON(common.Object{
+				"name":                "binding-" + newID(),
+				"service_instance_id": instanceID,
+			}).Expect()
+	}
+
+	osbProvision := func(instanceID string) *httpexpect.Response {
+		body := common.JSONToMap(fmt.Sprintf(common.CFContext, catalogServiceID, catalogPlanID, "instance-name"))
+		body["context"].(map[string]interface{})["tenant"] = tenant
+		return ctx.SMWithBasic.PUT(osbURL + "/v2/service_instances/" + instanceID).WithJSON(body).Expect()
+	}
+
+	osbBind := func(instanceID, bindingID string) *httpexpect.Response {
+		body := common.JSONToMap(fmt.Sprintf(common.CFContext, catalogServiceID, catalogPlanID, "instance-name"))
+		return ctx.SMWithBasic.PUT(osbURL + "/v2/service_instances/" + instanceID + "/service_bindings/" + bindingID).
+			WithJSON(body).Expect()
+	}
+
+	// createConcurrently sends two create requests at the same time, so that both pass the quota check before the
+	// broker creates the resources, and returns the status codes of the responses by resource id and a function
+	// returning the ids of the resources deleted at the broker since then
+	createConcurrently := func(handlerFunc func(method, op string, handler func(req *http.Request) (int, map[string]interface{})),
+		create func(id string) *httpexpect.Response) (map[string]int, func() []string) {
+		// recording the requests would serialize them at the broker
+		brokerServer.ShouldRecordRequests(false)
+		var arrived sync.WaitGroup
+		arrived.Add(2)
+		handlerFunc(http.MethodPut, http.MethodPut, func(req *http.Request) (int, map[string]interface{}) {
+			arrived.Done()
+			waited := make(chan struct{})
+			go func() {
+				arrived.Wait()
+				close(waited)
+			}()
+			select {
+			case <-waited:
+			case <-time.After(5 * time.Second):
+			}
+			return http.StatusCreated, common.Object{}
+		})
+		var mutex sync.Mutex
+		var deleted []string
+		handlerFunc(http.MethodDelete, http.MethodDelete, func(req *http.Request) (int, map[string]interface{}) {
+			mutex.Lock()
+			deleted = append(deleted, req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:])
+			mutex.Unlock()
+			return http.StatusOK, common.Object{}
+		})
+
+		statusCodes := make(map[string]int)
+		var created sync.WaitGroup
+		for _, id := range []string{newID(), newID()} {
+			created.Add(1)
+			go func(id string) {
+				defer GinkgoRecover()
+				defer created.Done()
+				statusCode := create(id).Raw().StatusCode
+				mutex.Lock()
+				statusCodes[id] = statusCode
+				mutex.Unlock()
+			}(id)
+		}
+		created.Wait()
+		return statusCodes, func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return append([]string{}, deleted...)
+		}
+	}
+
+	rejectedIDs := func(statusCodes map[string]int) []string {
+		var ids []string
+		for id, statusCode := range statusCodes {
+			if statusCode == http.StatusForbidden {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	BeforeSuite(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().
+			WithTenantTokenClaims(map[string]interface{}{
+				"cid": "tenancyClient",
+				"zid": "tenantID",
+			}).
+			WithSMExtensions(func(ctx context.Context, smb *sm.ServiceManagerBuilder, e env.Environment) error {
+				_, err := smb.EnableMultitenancy("tenant", common.ExtractTenantFunc)
+				return err
+			}).
+			Build()
+	})
+
+	AfterSuite(func() {
+		ctx.Cleanup()
+	})
+
+	BeforeEach(func() {
+		catalogServiceID = newID()
+		catalogPlanID = newID()
+		otherCatalogPlanID := newID()
+		catalog := common.NewEmptySBCatalog()
+		catalog.AddService(common.GenerateTestServiceWithPlansWithID(catalogServiceID,
+			common.GenerateTestPlanWithID(catalogPlanID), common.GenerateTestPlanWithID(otherCatalogPlanID)))
+		brokerUtils := ctx.RegisterBrokerWithCatalog(catalog)
+		brokerServer = brokerUtils.Broker.BrokerServer
+		brokerServer.ShouldRecordRequests(true)
+		common.CreateVisibilitiesForAllBrokerPlans(ctx.SMWithOAuth, brokerUtils.Broker.ID)
+		osbURL = web.OSBURL + "/" + brokerUtils.Broker.ID
+
+		plan := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", catalogPlanID)).
+			First().Object()
+		planID = plan.Value("id").String().Raw()
+		offeringID = plan.Value("service_offering_id").String().Raw()
+		otherPlanID = ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", otherCatalogPlanID)).
+			First().Object().Value("id").String().Raw()
+
+		tenant = newID()
+		tenantSM = ctx.NewTenantExpect("tenancyClient", tenant)
+	})
+
+	AfterEach(func() {
+		ctx.SMWithOAuth.DELETE(quotaURL(tenant)).Expect()
+		ctx.CleanupAdditionalResources()
+	})
+
+	Describe("GET /v1/tenants/{id}/quotas", func() {
+		It("returns the usage of a tenant without quota", func() {
+			instanceID := createInstance(planID).Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+			createBinding(instanceID).Status(http.StatusCreated)
+
+			resp := ctx.SMWithOAuth.GET(quotaURL(tenant)).Expect().Status(http.StatusOK).JSON().Object()
+			resp.Value("tenant_id").Equal(tenant)
+			resp.Value("quota").Null()
+			usage := resp.Value("usage").Object()
+			usage.Value("instances").Equal(1)
+			usage.Value("instances_per_plan").Object().Equal(common.Object{planID: 1})
+			usage.Value("instances_per_offering").Object().Equal(common.Object{offeringID: 1})
+			usage.Value("bindings_per_instance").Object().Equal(common.Object{instanceID: 1})
+		})
+
+		It("returns the quota of the tenant to its users", func() {
+			setQuota(common.Object{"max_instances": 5})
+
+			tenantSM.GET(quotaURL(tenant)).Expect().Status(http.StatusOK).
+				JSON().Path("$.quota.max_instances").Equal(5)
+		})
+
+		It("returns 404 for the quotas of other tenants to tenant users", func() {
+			tenantSM.GET(quotaURL(newID())).Expect().Status(http.StatusNotFound)
+		})
+	})
+
+	Describe("PUT /v1/tenants/{id}/quotas", func() {
+		It("replaces the quota of the tenant", func() {
+			setQuota(common.Object{"max_instances": 5, "max_bindings_per_instance": 2})
+			setQuota(common.Object{"max_instances_per_plan": common.Object{planID: 1}})
+
+			quota := ctx.SMWithOAuth.GET(quotaURL(tenant)).Expect().Status(http.StatusOK).JSON().Object().Value("quota").Object()
+			quota.NotContainsKey("max_instances")
+			quota.NotContainsKey("max_bindings_per_instance")
+			quota.Value("max_instances_per_plan").Object().Equal(common.Object{planID: 1})
+		})
+
+		It("returns 400 for negative limits", func() {
+			ctx.SMWithOAuth.PUT(quotaURL(tenant)).WithJSON(common.Object{"max_instances": -1}).
+				Expect().Status(http.StatusBadRequest)
+		})
+
+		It("returns 400 for limits of unknown plans", func() {
+			ctx.SMWithOAuth.PUT(quotaURL(tenant)).WithJSON(common.Object{"max_instances_per_plan": common.Object{"unknown": 1}}).
+				Expect().Status(http.StatusBadRequest)
+		})
+
+		It("returns 403 for tenant users", func() {
+			tenantSM.PUT(quotaURL(tenant)).WithJSON(common.Object{"max_instances": 100}).
+				Expect().Status(http.StatusForbidden)
+		})
+	})
+
+	Describe("DELETE /v1/tenants/{id}/quotas", func() {
+		It("removes the limits of the tenant", func() {
+			setQuota(common.Object{"max_instances": 0})
+			createInstance(planID).Status(http.StatusForbidden)
+
+			ctx.SMWithOAuth.DELETE(quotaURL(tenant)).Expect().Status(http.StatusOK)
+			createInstance(planID).Status(http.StatusCreated)
+		})
+
+		It("returns 404 when the tenant has no quota", func() {
+			ctx.SMWithOAuth.DELETE(quotaURL(tenant)).Expect().Status(http.StatusNotFound)
+		})
+	})
+
+	Context("when creating service instances through the Service Manager API", func() {
+		It("rejects instances exceeding the maximum instances of the tenant without calling the broker", func() {
+			setQuota(common.Object{"max_instances": 1})
+			createInstance(planID).Status(http.StatusCreated)
+			brokerServer.ResetCallHistory()
+
+			createInstance(otherPlanID).Status(http.StatusForbidden).
+				JSON().Object().Value("error").Equal("QuotaExceeded")
+			Expect(brokerServer.ServiceInstanceEndpointRequests).To(BeEmpty())
+		})
+
+		It("does not count the instances of other tenants", func() {
+			setQuota(common.Object{"max_instances": 1})
+			ctx.NewTenantExpect("tenancyClient", newID()).POST(web.ServiceInstancesURL).WithQuery("async", false).
+				WithJSON(common.Object{"name": "other-tenant-instance", "service_plan_id": planID, "maintenance_info": "{}"}).
+				Expect().Status(http.StatusCreated)
+
+			createInstance(planID).Status(http.StatusCreated)
+		})
+
+		It("rejects instances exceeding the maximum instances of the plan", func() {
+			setQuota(common.Object{"max_instances_per_plan": common.Object{planID: 1}})
+			createInstance(planID).Status(http.StatusCreated)
+
+			createInstance(planID).Status(http.StatusForbidden)
+			createInstance(otherPlanID).Status(http.StatusCreated)
+		})
+
+		It("rejects instances exceeding the maximum instances of the service offering", func() {
+			setQuota(common.Object{"max_instances_per_offering": common.Object{offeringID: 1}})
+			createInstance(planID).Status(http.StatusCreated)
+
+			createInstance(otherPlanID).Status(http.StatusForbidden)
+		})
+		It("orphan mitigates the instances rejected by concurrent requests at the broker", func() {
+			setQuota(common.Object{"max_instances": 1})
+
+			statusCodes, deleted := createConcurrently(brokerServer.ServiceInstanceHandlerFunc, func(string) *httpexpect.Response {
+				return createInstance(planID)
+			})
+
+			Expect(statusCodes).To(ConsistOf(http.StatusCreated, http.StatusForbidden))
+			instances := tenantSM.List(web.ServiceInstancesURL)
+			instances.Length().Equal(1)
+			Eventually(deleted, 10*time.Second).Should(HaveLen(1))
+			Expect(deleted()).ToNot(ContainElement(instances.First().Object().Value("id").String().Raw()))
+		})
+	})
+
+	Context("when creating service bindings through the Service Manager API", func() {
+		It("orphan mitigates the bindings rejected by concurrent requests at the broker", func() {
+			instanceID := createInstance(planID).Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+			setQuota(common.Object{"max_bindings_per_instance": 1})
+
+			statusCodes, deleted := createConcurrently(brokerServer.BindingHandlerFunc, func(string) *httpexpect.Response {
+				return createBinding(instanceID)
+			})
+
+			Expect(statusCodes).To(ConsistOf(http.StatusCreated, http.StatusForbidden))
+			bindings := ctx.SMWithOAuth.ListWithQuery(web.ServiceBindingsURL, fmt.Sprintf("fieldQuery=service_instance_id eq '%s'", instanceID))
+			bindings.Length().Equal(1)
+			Eventually(deleted, 10*time.Second).Should(HaveLen(1))
+			Expect(deleted()).ToNot(ContainElement(bindings.First().Object().Value("id").String().Raw()))
+		})
+
+		It("rejects bindings exceeding the maximum bindings of the instance", func() {
+			setQuota(common.Object{"max_bindings_per_instance": 1})
+			instanceID := createInstance(planID).Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+			otherInstanceID := createInstance(planID).Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+			createBinding(instanceID).Status(http.StatusCreated)
+
+			createBinding(instanceID).Status(http.StatusForbidden)
+			createBinding(otherInstanceID).Status(http.StatusCreated)
+		})
+	})
+
+	Context("when provisioning service instances through the OSB API", func() {
+		It("rejects instances exceeding the maximum instances of the tenant without calling the broker", func() {
+			setQuota(common.Object{"max_instances": 1})
+			osbProvision(newID()).Status(http.StatusCreated)
+			brokerServer.ResetCallHistory()
+
+			osbProvision(newID()).Status(http.StatusForbidden)
+			Expect(brokerServer.ServiceInstanceEndpointRequests).To(BeEmpty())
+
+			ctx.SMWithOAuth.GET(quotaURL(tenant)).Expect().Status(http.StatusOK).
+				JSON().Path("$.usage.instances").Equal(1)
+		})
+
+		It("counts the instances created through the Service Manager API", func() {
+			setQuota(common.Object{"max_instances": 1})
+			createInstance(planID).Status(http.StatusCreated)
+
+			osbProvision(newID()).Status(http.StatusForbidden)
+		})
+
+		It("does not reject repeated provision requests of existing instances for exceeding the quota", func() {
+			instanceID := newID()
+			setQuota(common.Object{"max_instances": 1})
+			osbProvision(instanceID).Status(http.StatusCreated)
+
+			Expect(osbProvision(instanceID).Raw().StatusCode).ToNot(Equal(http.StatusForbidden))
+		})
+
+		It("deprovisions the instances rejected by concurrent provision requests at the broker", func() {
+			setQuota(common.Object{"max_instances": 1})
+
+			statusCodes, deleted := createConcurrently(brokerServer.ServiceInstanceHandlerFunc, osbProvision)
+
+			Expect(statusCodes).To(ConsistOf(http.StatusCreated, http.StatusForbidden))
+			Expect(deleted()).To(ConsistOf(rejectedIDs(statusCodes)))
+			ctx.SMWithOAuth.GET(quotaURL(tenant)).Expect().Status(http.StatusOK).
+				JSON().Path("$.usage.instances").Equal(1)
+		})
+	})
+
+	Context("when binding service instances through the OSB API", func() {
+		It("unbinds the bindings rejected by concurrent bind requests at the broker", func() {
+			instanceID := newID()
+			osbProvision(instanceID).Status(http.StatusCreated)
+			setQuota(common.Object{"max_bindings_per_instance": 1})
+
+			statusCodes, deleted := createConcurrently(brokerServer.BindingHandlerFunc, func(bindingID string) *httpexpect.Response {
+				return osbBind(instanceID, bindingID)
+			})
+
+			Expect(statusCodes).To(ConsistOf(http.StatusCreated, http.StatusForbidden))
+			Expect(deleted()).To(ConsistOf(rejectedIDs(statusCodes)))
+			ctx.SMWithOAuth.ListWithQuery(web.ServiceBindingsURL, fmt.Sprintf("fieldQuery=service_instance_id eq '%s'", instanceID)).
+				Length().Equal(1)
+		})
+	})
+})
@@ -1299,6 +1299,61 @@ var _ = DescribeTestsFor(TestCase{
 										})
 									})
 
+									When("the operation is cancelled while polling", func() {
+										BeforeEach(func() {
+											brokerServer.BindingHandlerFunc(http.MethodPut, http.MethodPut+"1", ParameterizedHandler(http.StatusAccepted, Object{"async": true}))
+											brokerServer.BindingLastOpHandlerFunc(http.MethodPut+"1", ParameterizedHandler(http.StatusOK, Object{"state": "in progress"}))
+											brokerServer.BindingHandlerFunc(http.MethodDelete, http.MethodDelete+"1", ParameterizedHandler(http.StatusOK, Object{"async": false}))
+										})
+
+										It("marks the operation as cancelled and orphan mitigates the binding", func() {
+											resp := createBinding(ctx.SMWithOAuthForTenant, "true", http.StatusAccepted)
+											operationURL := resp.Header("Location").Raw()
+
+											bindingID, _ = VerifyOperationExists(ctx, operationURL, OperationExpectations{
+												Category:          types.CREATE,
+												State:             types.IN_PROGRESS,
+												ResourceType:      types.ServiceBindingType,
+												Reschedulable:     true,
+												DeletionScheduled: false,
+											})
+
+											ctx.SMWithOAuthForTenant.DELETE(operationURL).Expect().
+												Status(http.StatusOK).
+												JSON().Object().Value("state").Equal(string(types.CANCELLED))
+
+											VerifyOperationExists(ctx, operationURL, OperationExpectations{
+												Category:          types.CREATE,
+												State:             types.CANCELLED,
+												ResourceType:      types.ServiceBindingType,
+												Reschedulable:     false,
+												DeletionScheduled: false,
+												Error:             "operation was cancelled",
+											})
+
+											VerifyResourceDoesNotExist(ctx.SMWithOAuthForTenant, ResourceExpectations{
+												ID:   bindingID,
+												Type: types.ServiceBindingType,
+											})
+										})
+
+										It("does not allow cancelling the operation twice", func() {
+											resp := createBinding(ctx.SMWithOAuthForTenant, "true", http.StatusAccepted)
+											operationURL := resp.Header("Location").Raw()
+
+											VerifyOperationExists(ctx, operationURL, OperationExpectations{
+												Category:          types.CREATE,
+												State:             types.IN_PROGRESS,
+												ResourceType:      types.ServiceBindingType,
+												Reschedulable:     true,
+												DeletionScheduled: false,
+											})
+
+											ctx.SMWithOAuthForTenant.DELETE(operationURL).Expect().Status(http.StatusOK)
+											ctx.SMWithOAuthForTenant.DELETE(operationURL).Expect().Status(http.StatusUnprocessableEntity)
+										})
+									})
+
 									When("SM crashes while polling", func() {
 										var newSMCtx *TestContext
 										var isBound atomic.Value
@@ -38,6 +38,7 @@ import (
 	"github.com/Peripli/service-manager/api/healthcheck"
 	"github.com/Peripli/service-manager/pkg/sm"
 	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
 	"github.com/Peripli/service-manager/test/common"
 )
 
@@ -87,6 +88,7 @@ var _ = Describe("SM", func() {
 				env, err := env.DefaultLogger(context.TODO(), config.AddPFlags, common.SetTestFileLocation)
 				Expect(err).ToNot(HaveOccurred())
 				env.Set("api.token_issuer_url", oauthServer.URL())
+				env.Set("storage.type", storage.PostgresStorage)
 				env.Set("storage.uri", "invalid")
 
 				cfg, err := config.New(env)
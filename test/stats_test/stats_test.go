@@ -0,0 +1,209 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stats_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/sm"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func TestStats(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Stats Tests Suite")
+}
+
+var _ = Describe("Stats", func() {
+	var (
+		ctx          *common.TestContext
+		platformType string
+	)
+
+	createPlatform := func(name string, labels map[string][]string) {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		platform := common.MakePlatform(UUID.String(), platformType+"-"+name, platformType, "")
+		if labels != nil {
+			platform["labels"] = labels
+		}
+		ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).Expect().Status(http.StatusCreated)
+	}
+
+	stats := func(params map[string]string) []map[string]interface{} {
+		req := ctx.SMWithOAuth.GET(web.PlatformsURL+web.StatsURL).
+			WithQuery("fieldQuery", fmt.Sprintf("type eq '%s'", platformType))
+		for key, value := range params {
+			req = req.WithQuery(key, value)
+		}
+		page := req.Expect().Status(http.StatusOK).JSON().Object()
+		items := page.Value("items").Array()
+		page.Value("num_items").Equal(len(items.Iter()))
+
+		var groups []map[string]interface{}
+		for _, item := range items.Iter() {
+			groups = append(groups, item.Object().Raw())
+		}
+		return groups
+	}
+
+	BeforeSuite(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().
+			WithTenantTokenClaims(map[string]interface{}{
+				"cid": "tenancyClient",
+				"zid": "tenantID",
+			}).
+			WithSMExtensions(func(ctx context.Context, smb *sm.ServiceManagerBuilder, e env.Environment) error {
+				_, err := smb.EnableMultitenancy("tenant", common.ExtractTenantFunc)
+				return err
+			}).
+			Build()
+	})
+
+	AfterSuite(func() {
+		ctx.Cleanup()
+	})
+
+	BeforeEach(func() {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		platformType = UUID.String()
+		createPlatform("a", map[string][]string{"env": {"dev"}})
+		createPlatform("b", map[string][]string{"env": {"dev", "test"}})
+		createPlatform("c", map[string][]string{"env": {"prod"}})
+		createPlatform("d", nil)
+	})
+
+	Context("when no grouping is requested", func() {
+		It("returns the count of the matching resources", func() {
+			Expect(stats(nil)).To(Equal([]map[string]interface{}{
+				{"count": float64(4)},
+			}))
+		})
+	})
+
+	Context("when grouping by field", func() {
+		It("returns the count of the resources with each value of the field", func() {
+			Expect(stats(map[string]string{"groupBy": "type"})).To(Equal([]map[string]interface{}{
+				{"fields": map[string]interface{}{"type": platformType}, "count": float64(4)},
+			}))
+		})
+	})
+
+	Context("when grouping by label key", func() {
+		It("returns the count of the resources with each value of the label", func() {
+			Expect(stats(map[string]string{"groupByLabel": "env"})).To(Equal([]map[string]interface{}{
+				{"labels": map[string]interface{}{"env": "dev"}, "count": float64(2)},
+				{"labels": map[string]interface{}{"env": "prod"}, "count": float64(1)},
+				{"labels": map[string]interface{}{"env": "test"}, "count": float64(1)},
+				{"labels": map[string]interface{}{"env": nil}, "count": float64(1)},
+			}))
+		})
+	})
+
+	Context("when grouping by field and label key", func() {
+		It("returns the count of the resources with each combination of values", func() {
+			groups := stats(map[string]string{"groupBy": "type", "groupByLabel": "env"})
+			Expect(groups).To(HaveLen(4))
+			Expect(groups[0]).To(Equal(map[string]interface{}{
+				"fields": map[string]interface{}{"type": platformType},
+				"labels": map[string]interface{}{"env": "dev"},
+				"count":  float64(2),
+			}))
+		})
+	})
+
+	Context("when label query is used", func() {
+		It("counts only the matching resources", func() {
+			Expect(stats(map[string]string{"groupByLabel": "env", "labelQuery": "env in ('dev')"})).To(Equal([]map[string]interface{}{
+				{"labels": map[string]interface{}{"env": "dev"}, "count": float64(2)},
+				{"labels": map[string]interface{}{"env": "test"}, "count": float64(1)},
+			}))
+		})
+	})
+
+	DescribeTable("when the grouping is invalid",
+		func(param, value string) {
+			ctx.SMWithOAuth.GET(web.PlatformsURL+web.StatsURL).
+				WithQuery(param, value).
+				Expect().Status(http.StatusBadRequest)
+		},
+		Entry("unknown field", "groupBy", "unknown"),
+		Entry("field which is not part of the resource", "groupBy", "password"),
+		Entry("field which is not a single value", "groupBy", "labels"),
+		Entry("duplicate field", "groupBy", "type,type"),
+	)
+
+	Context("when the request is not authenticated", func() {
+		It("returns 401", func() {
+			ctx.SM.GET(web.PlatformsURL + web.StatsURL).
+				Expect().Status(http.StatusUnauthorized)
+		})
+	})
+
+	Context("when the request is made by a tenant", func() {
+		It("counts only the resources accessible by the tenant", func() {
+			ctx.SMWithOAuthForTenant.GET(web.PlatformsURL+web.StatsURL).
+				WithQuery("fieldQuery", fmt.Sprintf("type eq '%s'", platformType)).
+				Expect().Status(http.StatusOK).JSON().Object().
+				Value("num_items").Equal(0)
+		})
+	})
+
+	Context("when resources are scoped to a tenant", func() {
+		var planID string
+
+		BeforeEach(func() {
+			UUID, err := uuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			catalogPlanID := UUID.String()
+			catalog := common.NewEmptySBCatalog()
+			catalog.AddService(common.GenerateTestServiceWithPlans(common.GenerateTestPlanWithID(catalogPlanID)))
+			brokerID := ctx.RegisterBrokerWithCatalog(catalog).Broker.ID
+			common.CreateVisibilitiesForAllBrokerPlans(ctx.SMWithOAuth, brokerID)
+			planID = ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, fmt.Sprintf("fieldQuery=catalog_id eq '%s'", catalogPlanID)).
+				First().Object().Value("id").String().Raw()
+
+			common.GetSMAAPProvisionInstanceFunc(ctx, "false", planID)()
+			ctx.NewTenantExpect("tenancyClient", "otherTenantID").POST(web.ServiceInstancesURL).
+				WithQuery("async", "false").
+				WithJSON(common.Object{"name": "other-tenant-instance", "service_plan_id": planID, "maintenance_info": "{}"}).
+				Expect().Status(http.StatusCreated)
+		})
+
+		It("counts only the resources of the tenant", func() {
+			countInstances := func(sm *common.SMExpect) interface{} {
+				return sm.GET(web.ServiceInstancesURL+web.StatsURL).
+					WithQuery("fieldQuery", fmt.Sprintf("service_plan_id eq '%s'", planID)).
+					WithQuery("groupBy", "service_plan_id").
+					Expect().Status(http.StatusOK).JSON().Object().
+					Value("items").Array().First().Object().Value("count").Raw()
+			}
+			Expect(countInstances(ctx.SMWithOAuth)).To(Equal(float64(2)))
+			Expect(countInstances(ctx.SMWithOAuthForTenant)).To(Equal(float64(1)))
+		})
+	})
+})
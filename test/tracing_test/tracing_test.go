@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/tracing"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tracing Tests Suite")
+}
+
+const (
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	traceparent = "00-" + traceID + "-00f067aa0ba902b7-01"
+)
+
+var _ = Describe("Tracing", func() {
+	var ctx *common.TestContext
+	var exporter *tracetest.InMemoryExporter
+	var brokerServer *common.BrokerServer
+	var brokerJSON common.Object
+
+	spansNamed := func(name string) []*sdktrace.SpanSnapshot {
+		var spans []*sdktrace.SpanSnapshot
+		for _, span := range exporter.GetSpans() {
+			if span.Name == name {
+				spans = append(spans, span)
+			}
+		}
+		return spans
+	}
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilderWithSecurity().WithEnvPreExtensions(func(set *pflag.FlagSet) {
+			Expect(set.Set("tracing.enabled", "true")).ToNot(HaveOccurred())
+			Expect(set.Set("tracing.insecure", "true")).ToNot(HaveOccurred())
+		}).Build()
+
+		// the spans are recorded in memory instead of being exported to a collector
+		exporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+		brokerServer = common.NewBrokerServer()
+		name, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		brokerJSON = common.Object{
+			"name":       name.String(),
+			"broker_url": brokerServer.URL(),
+			"credentials": common.Object{
+				"basic": common.Object{
+					"username": brokerServer.Username,
+					"password": brokerServer.Password,
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		ctx.Cleanup()
+		brokerServer.Close()
+	})
+
+	It("continues the trace of the request and propagates it to the broker", func() {
+		ctx.SMWithOAuth.POST(web.ServiceBrokersURL).
+			WithHeader("traceparent", traceparent).
+			WithJSON(brokerJSON).
+			Expect().Status(http.StatusCreated)
+
+		serverSpans := spansNamed("POST " + web.ServiceBrokersURL)
+		Expect(serverSpans).To(HaveLen(1))
+		Expect(serverSpans[0].SpanContext.TraceID().String()).To(Equal(traceID))
+		Expect(serverSpans[0].SpanKind).To(Equal(trace.SpanKindServer))
+
+		for _, name := range []string{
+			"filter LoggingFilter",
+			"operation create " + web.ServiceBrokersURL,
+			"storage create " + web.ServiceBrokersURL,
+			"HTTP GET",
+		} {
+			spans := spansNamed(name)
+			Expect(spans).ToNot(BeEmpty(), name)
+			Expect(spans[0].SpanContext.TraceID().String()).To(Equal(traceID), name)
+		}
+
+		Expect(brokerServer.CatalogEndpointRequests).ToNot(BeEmpty())
+		Expect(brokerServer.CatalogEndpointRequests[0].Header.Get("traceparent")).To(ContainSubstring(traceID))
+	})
+
+	It("propagates the trace to the broker of a deprovisioned instance", func() {
+		brokerID := ctx.SMWithOAuth.POST(web.ServiceBrokersURL).
+			WithJSON(brokerJSON).
+			Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+		common.CreateVisibilitiesForAllBrokerPlans(ctx.SMWithOAuth, brokerID)
+		offeringID := ctx.SMWithOAuth.ListWithQuery(web.ServiceOfferingsURL, "fieldQuery=broker_id eq '"+brokerID+"'").
+			First().Object().Value("id").String().Raw()
+		planID := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, "fieldQuery=service_offering_id eq '"+offeringID+"'").
+			First().Object().Value("id").String().Raw()
+		instanceID := ctx.SMWithOAuth.POST(web.ServiceInstancesURL).
+			WithQuery("async", false).
+			WithJSON(common.Object{
+				"name":             "traced-instance",
+				"service_plan_id":  planID,
+				"maintenance_info": "{}",
+			}).
+			Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+		// the deprovision request is sent by the OSB client library
+		ctx.SMWithOAuth.DELETE(web.ServiceInstancesURL+"/"+instanceID).
+			WithQuery("async", false).
+			WithHeader("traceparent", traceparent).
+			Expect().Status(http.StatusOK)
+
+		brokerSpans := spansNamed("broker DeprovisionInstance")
+		Expect(brokerSpans).To(HaveLen(1))
+		Expect(brokerSpans[0].SpanContext.TraceID().String()).To(Equal(traceID))
+
+		requests := brokerServer.ServiceInstanceEndpointRequests
+		Expect(requests).ToNot(BeEmpty())
+		deprovisionRequest := requests[len(requests)-1]
+		Expect(deprovisionRequest.Method).To(Equal(http.MethodDelete))
+		Expect(deprovisionRequest.Header.Get("traceparent")).To(ContainSubstring(brokerSpans[0].SpanContext.SpanID().String()))
+	})
+
+	It("traces asynchronous operations in a new trace linked to the request trace", func() {
+		ctx.SMWithOAuth.POST(web.ServiceBrokersURL).
+			WithQuery("async", "true").
+			WithHeader("traceparent", traceparent).
+			WithJSON(brokerJSON).
+			Expect().Status(http.StatusAccepted)
+
+		operationSpanName := "operation create " + web.ServiceBrokersURL
+		Eventually(func() []*sdktrace.SpanSnapshot { return spansNamed(operationSpanName) }, "10s").Should(HaveLen(1))
+
+		operationSpan := spansNamed(operationSpanName)[0]
+		Expect(operationSpan.SpanContext.TraceID().String()).ToNot(Equal(traceID))
+		Expect(operationSpan.Links).To(HaveLen(1))
+		Expect(operationSpan.Links[0].TraceID().String()).To(Equal(traceID))
+
+		var correlationID string
+		for _, attribute := range operationSpan.Attributes {
+			if attribute.Key == tracing.CorrelationIDKey {
+				correlationID = attribute.Value.AsString()
+			}
+		}
+		Expect(correlationID).ToNot(BeEmpty())
+
+		Expect(brokerServer.CatalogEndpointRequests).ToNot(BeEmpty())
+		Expect(brokerServer.CatalogEndpointRequests[0].Header.Get("traceparent")).To(ContainSubstring(operationSpan.SpanContext.TraceID().String()))
+	})
+})
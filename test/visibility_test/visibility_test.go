@@ -17,6 +17,7 @@
 package visibility_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -757,6 +758,153 @@ var _ = test.DescribeTestsFor(test.TestCase{
 					})
 				})
 			})
+
+			Describe("POST batch", func() {
+				var platformID string
+
+				countPlatformNotifications := func() int {
+					count, err := ctx.SMRepository.Count(context.Background(), types.NotificationType,
+						query.ByField(query.EqualsOperator, "platform_id", platformID))
+					Expect(err).ToNot(HaveOccurred())
+					return count
+				}
+
+				BeforeEach(func() {
+					platformID = postVisibilityRequestWithLabels["platform_id"].(string)
+				})
+
+				Context("with invalid item", func() {
+					It("returns 400", func() {
+						ctx.SMWithOAuth.POST(web.VisibilitiesBatchURL).
+							WithJSON(common.Object{
+								"items": common.Array{
+									common.Object{"action": "delete"},
+								},
+							}).
+							Expect().
+							Status(http.StatusBadRequest).
+							JSON().Object().Value("description").String().Contains("batch item 0 is invalid")
+					})
+				})
+
+				Context("with no items", func() {
+					It("returns 400", func() {
+						ctx.SMWithOAuth.POST(web.VisibilitiesBatchURL).
+							WithJSON(common.Object{"items": common.Array{}}).
+							Expect().
+							Status(http.StatusBadRequest)
+					})
+				})
+
+				Context("when all items are valid", func() {
+					It("applies all items and returns their results", func() {
+						existingID := ctx.SMWithOAuth.POST(web.VisibilitiesURL).
+							WithJSON(postVisibilityRequestWithLabels).
+							Expect().
+							Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+						results := ctx.SMWithOAuth.POST(web.VisibilitiesBatchURL).
+							WithJSON(common.Object{
+								"items": common.Array{
+									common.Object{"action": "create", "visibility": postVisibilityRequestNoLabels},
+									common.Object{"action": "update", "id": existingID, "labels": common.Array{
+										common.Object{"op": "add", "key": "batch", "values": common.Array{"batch_value"}},
+									}},
+									common.Object{"action": "delete", "id": existingID},
+								},
+							}).
+							Expect().
+							Status(http.StatusOK).JSON().Object().Value("results").Array()
+
+						results.Length().Equal(3)
+						created := results.Element(0).Object()
+						created.Value("status_code").Equal(http.StatusCreated)
+						createdID := created.Path("$.visibility.id").String().NotEmpty().Raw()
+						results.Element(1).Object().Value("status_code").Equal(http.StatusOK)
+						results.Element(1).Object().Path("$.visibility.labels.batch").Array().Contains("batch_value")
+						results.Element(2).Object().Value("status_code").Equal(http.StatusOK)
+
+						ctx.SMWithOAuth.GET(web.VisibilitiesURL + "/" + createdID).
+							Expect().
+							Status(http.StatusOK).JSON().Object().Value("service_plan_id").Equal(existingPlanIDs[0])
+						ctx.SMWithOAuth.GET(web.VisibilitiesURL + "/" + existingID).
+							Expect().
+							Status(http.StatusNotFound)
+					})
+				})
+
+				Context("when an item fails", func() {
+					It("applies none of the items and returns the status of the failed item", func() {
+						results := ctx.SMWithOAuth.POST(web.VisibilitiesBatchURL).
+							WithJSON(common.Object{
+								"items": common.Array{
+									common.Object{"action": "create", "visibility": postVisibilityRequestNoLabels},
+									common.Object{"action": "delete", "id": "non-existing-id"},
+								},
+							}).
+							Expect().
+							Status(http.StatusNotFound).JSON().Object().Value("results").Array()
+
+						results.Length().Equal(2)
+						results.Element(0).Object().Value("status_code").Equal(http.StatusFailedDependency)
+						results.Element(1).Object().Value("status_code").Equal(http.StatusNotFound)
+
+						ctx.SMWithOAuth.List(web.VisibilitiesURL).Length().Equal(0)
+					})
+				})
+
+				Context("when notifications are coalesced", func() {
+					var items common.Array
+
+					BeforeEach(func() {
+						visibility := common.Object{
+							"id":              "batch-visibility-id",
+							"platform_id":     platformID,
+							"service_plan_id": existingPlanIDs[1],
+						}
+						items = common.Array{
+							common.Object{"action": "create", "visibility": visibility},
+							common.Object{"action": "update", "id": "batch-visibility-id", "labels": common.Array{
+								common.Object{"op": "add", "key": "batch", "values": common.Array{"batch_value"}},
+							}},
+							common.Object{"action": "delete", "id": "batch-visibility-id"},
+						}
+					})
+
+					It("creates no notifications for a visibility created and deleted in the batch", func() {
+						ctx.SMWithOAuth.POST(web.VisibilitiesBatchURL).
+							WithJSON(common.Object{"coalesce_notifications": true, "items": items}).
+							Expect().
+							Status(http.StatusOK)
+
+						Expect(countPlatformNotifications()).To(Equal(0))
+					})
+
+					It("creates a single notification for many changes of a visibility", func() {
+						ctx.SMWithOAuth.POST(web.VisibilitiesBatchURL).
+							WithJSON(common.Object{"coalesce_notifications": true, "items": items[:2]}).
+							Expect().
+							Status(http.StatusOK)
+
+						notifications, err := ctx.SMRepository.List(context.Background(), types.NotificationType,
+							query.ByField(query.EqualsOperator, "platform_id", platformID))
+						Expect(err).ToNot(HaveOccurred())
+						Expect(notifications.Len()).To(Equal(1))
+						notification := notifications.ItemAt(0).(*types.Notification)
+						Expect(notification.Type).To(Equal(types.CREATED))
+						Expect(string(notification.Payload)).To(ContainSubstring("batch_value"))
+					})
+
+					It("creates a notification for each change when coalescing is not requested", func() {
+						ctx.SMWithOAuth.POST(web.VisibilitiesBatchURL).
+							WithJSON(common.Object{"items": items}).
+							Expect().
+							Status(http.StatusOK)
+
+						Expect(countPlatformNotifications()).To(Equal(3))
+					})
+				})
+			})
 		})
 
 	},
@@ -0,0 +1,280 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhooks_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/webhooks"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+)
+
+func TestWebhooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Webhooks Tests Suite")
+}
+
+type deliveredNotification struct {
+	revision int64
+	body     []byte
+	valid    bool
+}
+
+// webhookServer records the notifications delivered to it and responds with the configured status
+type webhookServer struct {
+	*httptest.Server
+	secret string
+
+	mutex         sync.Mutex
+	status        int
+	notifications []deliveredNotification
+}
+
+func newWebhookServer(secret string) *webhookServer {
+	ws := &webhookServer{secret: secret, status: http.StatusOK}
+	ws.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ws.mutex.Lock()
+		defer ws.mutex.Unlock()
+		if ws.status == http.StatusOK {
+			revision, _ := strconv.ParseInt(req.Header.Get(webhooks.RevisionHeader), 10, 64)
+			ws.notifications = append(ws.notifications, deliveredNotification{
+				revision: revision,
+				body:     body,
+				valid:    webhooks.VerifySignature(ws.secret, req.Header.Get(webhooks.TimestampHeader), body, req.Header.Get(webhooks.SignatureHeader)),
+			})
+		}
+		rw.WriteHeader(ws.status)
+	}))
+	return ws
+}
+
+func (ws *webhookServer) setStatus(status int) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.status = status
+}
+
+func (ws *webhookServer) delivered() []deliveredNotification {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	return append([]deliveredNotification{}, ws.notifications...)
+}
+
+var _ = Describe("Webhooks", func() {
+	const (
+		secret            = "webhook-secret"
+		eventuallyTimeout = time.Second * 10
+	)
+
+	var (
+		ctx        *common.TestContext
+		server     *webhookServer
+		platformID string
+	)
+
+	createNotification := func() *types.Notification {
+		UUID, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		notification, err := ctx.SMRepository.Create(context.Background(), &types.Notification{
+			Base: types.Base{
+				ID:        UUID.String(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Ready:     true,
+			},
+			Resource:   types.VisibilityType,
+			Type:       types.CREATED,
+			PlatformID: platformID,
+			Payload:    []byte(`{"new":{"resource":{"id":"` + UUID.String() + `"}}}`),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return notification.(*types.Notification)
+	}
+
+	getPlatform := func() map[string]interface{} {
+		return ctx.SMWithOAuth.GET(web.PlatformsURL + "/" + platformID).
+			Expect().
+			Status(http.StatusOK).JSON().Object().Raw()
+	}
+
+	webhookStatus := func() map[string]interface{} {
+		status, _ := getPlatform()["webhook_status"].(map[string]interface{})
+		return status
+	}
+
+	BeforeSuite(func() {
+		ctx = common.NewTestContextBuilder().WithEnvPreExtensions(func(set *pflag.FlagSet) {
+			Expect(set.Set("webhooks.delivery_interval", "50ms")).To(Succeed())
+			Expect(set.Set("webhooks.min_backoff", "100ms")).To(Succeed())
+			Expect(set.Set("webhooks.max_backoff", "200ms")).To(Succeed())
+			Expect(set.Set("webhooks.settle_period", "100ms")).To(Succeed())
+		}).Build()
+	})
+
+	AfterSuite(func() {
+		ctx.Cleanup()
+	})
+
+	BeforeEach(func() {
+		server = newWebhookServer(secret)
+		platform := common.GenerateRandomPlatform()
+		platform["webhook"] = common.Object{"url": server.URL, "secret": secret}
+		platformID = ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+			Expect().
+			Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		ctx.CleanupAdditionalResources()
+	})
+
+	It("delivers signed notifications in revision order", func() {
+		first := createNotification()
+		second := createNotification()
+		third := createNotification()
+
+		Eventually(func() int {
+			return len(server.delivered())
+		}, eventuallyTimeout).Should(Equal(3))
+
+		delivered := server.delivered()
+		for i, notification := range []*types.Notification{first, second, third} {
+			Expect(delivered[i].revision).To(Equal(notification.Revision))
+			Expect(delivered[i].valid).To(BeTrue())
+			Expect(string(delivered[i].body)).To(ContainSubstring(notification.ID))
+		}
+
+		Eventually(func() interface{} {
+			return webhookStatus()["revision"]
+		}, eventuallyTimeout).Should(BeEquivalentTo(third.Revision))
+	})
+
+	It("retries failed deliveries with backoff and exposes the failures", func() {
+		server.setStatus(http.StatusInternalServerError)
+		notification := createNotification()
+
+		Eventually(func() interface{} {
+			return webhookStatus()["consecutive_failures"]
+		}, eventuallyTimeout).Should(BeNumerically(">=", 1))
+		status := webhookStatus()
+		Expect(status["last_error"]).To(ContainSubstring("500"))
+		Expect(status["next_attempt_at"]).ToNot(BeEmpty())
+		Expect(server.delivered()).To(BeEmpty())
+
+		server.setStatus(http.StatusOK)
+		Eventually(func() int {
+			return len(server.delivered())
+		}, eventuallyTimeout).Should(Equal(1))
+		Expect(server.delivered()[0].revision).To(Equal(notification.Revision))
+
+		Eventually(func() interface{} {
+			return webhookStatus()["consecutive_failures"]
+		}, eventuallyTimeout).Should(BeNil())
+	})
+
+	It("does not expose the webhook secret", func() {
+		webhook := getPlatform()["webhook"].(map[string]interface{})
+		Expect(webhook["url"]).To(Equal(server.URL))
+		Expect(webhook).ToNot(HaveKey("secret"))
+	})
+
+	It("keeps the webhook secret when only the url is updated", func() {
+		otherServer := newWebhookServer(secret)
+		defer otherServer.Close()
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/" + platformID).
+			WithJSON(common.Object{"webhook": common.Object{"url": otherServer.URL}}).
+			Expect().
+			Status(http.StatusOK)
+
+		createNotification()
+		Eventually(func() int {
+			return len(otherServer.delivered())
+		}, eventuallyTimeout).Should(Equal(1))
+		Expect(otherServer.delivered()[0].valid).To(BeTrue())
+	})
+
+	It("ignores webhook status provided in requests", func() {
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/" + platformID).
+			WithJSON(common.Object{"webhook_status": common.Object{"revision": 1000000}}).
+			Expect().
+			Status(http.StatusOK)
+
+		notification := createNotification()
+		Eventually(func() int {
+			return len(server.delivered())
+		}, eventuallyTimeout).Should(Equal(1))
+		Expect(server.delivered()[0].revision).To(Equal(notification.Revision))
+	})
+
+	It("does not deliver the notifications stored before the webhook was configured", func() {
+		platform := common.GenerateRandomPlatform()
+		platformID = ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+			Expect().
+			Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+		createNotification()
+
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/" + platformID).
+			WithJSON(common.Object{"webhook": common.Object{"url": server.URL, "secret": secret}}).
+			Expect().
+			Status(http.StatusOK)
+		notification := createNotification()
+
+		Eventually(func() int {
+			return len(server.delivered())
+		}, eventuallyTimeout).Should(BeNumerically(">=", 1))
+		Consistently(func() int {
+			return len(server.delivered())
+		}, time.Second).Should(Equal(1))
+		Expect(server.delivered()[0].revision).To(Equal(notification.Revision))
+	})
+
+	It("rejects webhooks with invalid url", func() {
+		platform := common.GenerateRandomPlatform()
+		platform["webhook"] = common.Object{"url": "not-a-url", "secret": secret}
+		ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	It("rejects webhooks without secret", func() {
+		platform := common.GenerateRandomPlatform()
+		platform["webhook"] = common.Object{"url": server.URL}
+		ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+})